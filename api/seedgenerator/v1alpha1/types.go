@@ -39,10 +39,33 @@ type SeedGenerator struct {
 	Status SeedGeneratorStatus `json:"status,omitempty"`
 }
 
+// MachineConfigDriftPolicy defines how the seed generation handles MachineConfig-managed files modified locally
+type MachineConfigDriftPolicy string
+
+// MachineConfigDriftPolicies defines the string values for the MachineConfigDriftPolicy field
+var MachineConfigDriftPolicies = struct {
+	Warn  MachineConfigDriftPolicy
+	Block MachineConfigDriftPolicy
+}{
+	Warn:  "Warn",
+	Block: "Block",
+}
+
 // SeedGeneratorSpec defines the desired state of SeedGenerator
 type SeedGeneratorSpec struct {
 	SeedImage   string `json:"seedImage,omitempty"`
 	RecertImage string `json:"recertImage,omitempty"`
+	// MachineConfigDriftPolicy defines whether the MachineConfig-managed files modified locally on the seed cluster
+	// are only listed in the status (Warn) or reject the seed generation (Block), as they would be baked into the seed
+	// +kubebuilder:validation:Enum=Warn;Block
+	// +kubebuilder:default=Warn
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="MachineConfig Drift Policy"
+	MachineConfigDriftPolicy MachineConfigDriftPolicy `json:"machineConfigDriftPolicy,omitempty"`
+	// SBOMFormat attaches a Software Bill of Materials of the seed packages and embedded images, in the given format,
+	// to the seed image in its registry. No SBOM is attached if unset
+	// +kubebuilder:validation:Enum=SPDX;CycloneDX
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="SBOM Format"
+	SBOMFormat string `json:"sbomFormat,omitempty"`
 }
 
 // SeedGeneratorStatus defines the observed state of SeedGenerator
@@ -53,6 +76,9 @@ type SeedGeneratorStatus struct {
 	CompletedAt        metav1.Time `json:"completedAt,omitempty"`
 	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Conditions"
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// MachineConfigDrift lists the MachineConfig-managed files modified locally on the seed cluster
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="MachineConfig Drift"
+	MachineConfigDrift []string `json:"machineConfigDrift,omitempty"`
 }
 
 //+kubebuilder:object:root=true
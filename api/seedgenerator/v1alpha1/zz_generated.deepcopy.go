@@ -111,6 +111,11 @@ func (in *SeedGeneratorStatus) DeepCopyInto(out *SeedGeneratorStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MachineConfigDrift != nil {
+		in, out := &in.MachineConfigDrift, &out.MachineConfigDrift
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeedGeneratorStatus.
@@ -92,6 +92,28 @@ type SeedReconfiguration struct {
 
 	// PullSecret is the secret to use when pulling images. Equivalent to install-config.yaml's pullSecret.
 	PullSecret string `json:"pull_secret,omitempty"`
+
+	// ClockSkewProtection protects the certificates regenerated by recert
+	// against a clock of the node running ahead when recert runs, as they
+	// would not be valid yet once the clock is corrected. When nil, recert
+	// runs right away with its defaults.
+	ClockSkewProtection *ClockSkewProtection `json:"clock_skew_protection,omitempty"`
+}
+
+// ClockSkewProtection configures how recert guards against a skewed clock of
+// the node. The durations are in the Go duration format, e.g. "90s" or "1h".
+type ClockSkewProtection struct {
+	// NotBeforeBackdate is how far in the past the certificates regenerated
+	// by recert become valid, covering a clock ahead by up to that much. When
+	// empty, recert keeps its default. It requires a recert supporting the
+	// not_before_backdate_seconds configuration.
+	NotBeforeBackdate string `json:"not_before_backdate,omitempty"`
+
+	// ClockSyncTimeout is how long to wait before running recert for the time
+	// synchronization service of the node to synchronize its clock. Recert is
+	// not run when the node has no time synchronization service or its clock
+	// is not synchronized by then. When empty, recert does not wait.
+	ClockSyncTimeout string `json:"clock_sync_timeout,omitempty"`
 }
 
 type KubeConfigCryptoRetention struct {
@@ -0,0 +1,78 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageBasedUpgradeReport states
+const (
+	ReportStateIdle       = "Idle"
+	ReportStateInProgress = "InProgress"
+	ReportStateCompleted  = "Completed"
+	ReportStateFailed     = "Failed"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=imagebasedupgradereports,scope=Namespaced,shortName=ibur
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.clusterName"
+// +kubebuilder:printcolumn:name="Stage",type="string",JSONPath=".status.stage"
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="ETA",type="date",JSONPath=".status.estimatedCompletionTime"
+// +kubebuilder:printcolumn:name="Last Error",type="string",JSONPath=".status.lastError"
+
+// ImageBasedUpgradeReport is a condensed view of the ImageBasedUpgrade status of a managed cluster. It is
+// created by the Lifecycle Agent on the hub, in the namespace of the managed cluster, so the upgrade progress of
+// a fleet can be followed from the hub without querying each cluster
+type ImageBasedUpgradeReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ImageBasedUpgradeReportStatus `json:"status,omitempty"`
+}
+
+// ImageBasedUpgradeReportStatus defines the reported state of the ImageBasedUpgrade of a managed cluster
+type ImageBasedUpgradeReportStatus struct {
+	ClusterName string `json:"clusterName,omitempty"`
+	// Stage is the stage in progress or, if none, the desired stage of the ImageBasedUpgrade
+	Stage ImageBasedUpgradeStage `json:"stage,omitempty"`
+	// State is one of Idle, InProgress, Completed or Failed
+	State         string `json:"state,omitempty"`
+	SeedImage     string `json:"seedImage,omitempty"`
+	TargetVersion string `json:"targetVersion,omitempty"`
+	Message       string `json:"message,omitempty"`
+	// LastError and LastErrorReason are the message and reason of the last stage failure
+	LastError       string `json:"lastError,omitempty"`
+	LastErrorReason string `json:"lastErrorReason,omitempty"`
+	// EstimatedCompletionTime is set while the progress of the stage in progress can be estimated
+	EstimatedCompletionTime *metav1.Time `json:"estimatedCompletionTime,omitempty"`
+	LastUpdateTime          metav1.Time  `json:"lastUpdateTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ImageBasedUpgradeReportList contains a list of ImageBasedUpgradeReport
+type ImageBasedUpgradeReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageBasedUpgradeReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageBasedUpgradeReport{}, &ImageBasedUpgradeReportList{})
+}
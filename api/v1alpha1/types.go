@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -64,20 +65,158 @@ type ImageBasedUpgradeSpec struct {
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Stage"
 	Stage ImageBasedUpgradeStage `json:"stage,omitempty"`
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Seed Image Reference"
-	SeedImageRef     SeedImageRef `json:"seedImageRef,omitempty"`
+	SeedImageRef SeedImageRef `json:"seedImageRef,omitempty"`
+	// AdditionalImages references a config map listing images to precache in addition to those of the precaching list,
+	// one image per line of each of its entries
 	AdditionalImages ConfigMapRef `json:"additionalImages,omitempty"`
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="OADP Content"
 	OADPContent []ConfigMapRef `json:"oadpContent,omitempty"`
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Extra Manifests"
 	ExtraManifests        []ConfigMapRef        `json:"extraManifests,omitempty"`
 	AutoRollbackOnFailure AutoRollbackOnFailure `json:"autoRollbackOnFailure,omitempty"`
+	PostPivotLogging      PostPivotLogging      `json:"postPivotLogging,omitempty"`
+	PivotGate             *PivotGate            `json:"pivotGate,omitempty"`
+	// VarDataMode selects how the seed /var data is stored in the new stateroot. Extracted, the default, extracts it
+	// during Prep. Deferred stores the compressed seed /var data, reflink-copied when supported, and extracts it at
+	// first boot of the new stateroot, saving disk space before pivot at the cost of a longer first boot
+	// +kubebuilder:validation:Enum=Extracted;Deferred
+	VarDataMode VarDataMode `json:"varDataMode,omitempty"`
+	// RegistryConfig references the registries configuration and CA certificates delivered for this upgrade, installed
+	// during Prep on the running host, for the seed image pull and the precaching, and in the new stateroot
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Registry Configuration"
+	RegistryConfig *RegistryConfigRef `json:"registryConfig,omitempty"`
+	// RegistryTLS overrides the TLS configuration of the named registries for the seed image pull and the precaching,
+	// for lab registries with self-signed certificates, without changing the configuration of the host
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Registry TLS"
+	RegistryTLS []RegistryTLS `json:"registryTLS,omitempty"`
+	// Precache configures the precaching of the images of the new release during Prep
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Precache"
+	Precache *PrecacheConfig `json:"precache,omitempty"`
+	// CleanupPolicy removes the seed images and the old stateroots left on the host by previous upgrades when the IBU
+	// goes back to Idle
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Cleanup Policy"
+	CleanupPolicy *CleanupPolicy `json:"cleanupPolicy,omitempty"`
 }
 
+// CleanupPolicy configures the garbage collection run when the IBU goes back to Idle, after the finalize or the abort
+// of an upgrade. Its failures are logged only and do not block the transition to Idle
+type CleanupPolicy struct {
+	// RemoveSeedImages removes the seed images left in the container storage of the host when going back to Idle: all
+	// of them on finalize, and all but the seed image of the spec, reused by a retried Prep, on abort. The seed images
+	// are kept by default
+	RemoveSeedImages bool `json:"removeSeedImages,omitempty"`
+	// StaterootMaxAgeDays deletes the stateroots not modified for this number of days, but those of the booted, staged
+	// or default deployment and the one preserved on failure. The unbooted stateroots are always deleted on finalize.
+	// Value <= 0 keeps them
+	StaterootMaxAgeDays int `json:"staterootMaxAgeDays,omitempty"`
+}
+
+// PrecacheImageListSource defines how the list of the images to precache is built
+type PrecacheImageListSource string
+
+const (
+	// PrecacheImageListSourceSeed precaches the images listed by the seed image, those of the seed cluster
+	PrecacheImageListSourceSeed PrecacheImageListSource = "Seed"
+	// PrecacheImageListSourceCluster precaches the images of the operators installed on this cluster
+	PrecacheImageListSourceCluster PrecacheImageListSource = "Cluster"
+	// PrecacheImageListSourceMerged precaches both the images listed by the seed image and those of the operators
+	// installed on this cluster
+	PrecacheImageListSourceMerged PrecacheImageListSource = "Merged"
+)
+
+// PrecacheConfig configures the precaching of the images during Prep
+type PrecacheConfig struct {
+	// ImageListSource selects the images to precache. Seed, the default, precaches the images listed by the seed
+	// image. Cluster precaches the images of the operators installed on this cluster, from their
+	// ClusterServiceVersions, and the recert image. Merged precaches both
+	// +kubebuilder:validation:Enum=Seed;Cluster;Merged
+	ImageListSource PrecacheImageListSource `json:"imageListSource,omitempty"`
+	// IncludeImages are regular expressions selecting the images to precache, matched against the image references,
+	// e.g. quay\.io/org/.*. When set, only the images matching one of them are precached
+	IncludeImages []string `json:"includeImages,omitempty"`
+	// ExcludeImages are regular expressions of the images not to precache, e.g. of the operators this cluster does
+	// not run, taking precedence over IncludeImages. The recert image run at first boot is always precached
+	ExcludeImages []string `json:"excludeImages,omitempty"`
+	// BestEffort completes Prep when some images fail to precache, e.g. an optional operator image missing from the
+	// mirror, with the PrecachePartial reason listing them, instead of failing Prep. The images not precached are
+	// pulled after the upgrade reboot instead
+	BestEffort bool `json:"bestEffort,omitempty"`
+	// NumConcurrentPulls is the number of images pulled in parallel by the precaching job, 10 by default
+	// +kubebuilder:validation:Minimum=1
+	NumConcurrentPulls int `json:"numConcurrentPulls,omitempty"`
+	// MaxMbps caps the download bandwidth of the precaching job, in megabits per second, shared by its concurrent
+	// pulls, so that precaching does not starve the traffic of the workloads sharing the uplink. Unlimited by default
+	// +kubebuilder:validation:Minimum=1
+	MaxMbps int `json:"maxMbps,omitempty"`
+	// TimeoutMinutes bounds the wait for the precaching to complete, in minutes, Prep failing with the PrecacheTimeout
+	// reason past it. Unlimited by default
+	// +kubebuilder:validation:Minimum=1
+	TimeoutMinutes int `json:"timeoutMinutes,omitempty"`
+	// StallMinutes fails Prep with the PrecacheStalled reason when the precaching processes no image for this number
+	// of minutes while neither blocked nor complete. Twice the time limit of an image pull by default, 60 minutes
+	// +kubebuilder:validation:Minimum=1
+	StallMinutes int `json:"stallMinutes,omitempty"`
+	// NicePriority is the niceness of the precaching job, from -20, the most favorable scheduling, to 19, the least
+	// favorable one, 0 by default
+	// +kubebuilder:validation:Minimum=-20
+	// +kubebuilder:validation:Maximum=19
+	NicePriority *int `json:"nicePriority,omitempty"`
+	// IoNiceClass is the I/O scheduling class of the precaching job, 0 for none, 1 for realtime, 2 for best-effort, the
+	// default, or 3 for idle
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=3
+	IoNiceClass *int `json:"ioNiceClass,omitempty"`
+	// IoNicePriority is the I/O priority of the precaching job in the realtime and best-effort classes, from 0, the
+	// highest priority, to 7, 4 by default
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=7
+	IoNicePriority *int `json:"ioNicePriority,omitempty"`
+	// Resources are the compute resources of the precaching job, whose requests default to 10m CPU and 512Mi memory,
+	// without limits. A default request above the limit set is lowered to the limit
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// VarDataMode defines how the seed /var data is stored in the new stateroot
+type VarDataMode string
+
+const (
+	VarDataModeExtracted VarDataMode = "Extracted"
+	VarDataModeDeferred  VarDataMode = "Deferred"
+)
+
 // SeedImageRef defines the seed image and OCP version for the upgrade
 type SeedImageRef struct {
-	Version       string         `json:"version,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Image is the seed image, referenced by tag or by digest, e.g. quay.io/org/seed@sha256:..., or an OCI archive or
+	// a dir transport directory pre-placed on the host, e.g. oci-archive:///var/tmp/seed.tar or dir:///var/tmp/seed
 	Image         string         `json:"image,omitempty"`
 	PullSecretRef *PullSecretRef `json:"pullSecretRef,omitempty"`
+	// ExpectedDigest is the digest the pulled seed image must have. It must match the digest of an image referenced
+	// by digest
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	ExpectedDigest string `json:"expectedDigest,omitempty"`
+	// InsecureSkipTLSVerify disables the verification of the certificate of the seed image registry, for the seed
+	// image pull and the precaching of the images of that registry
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+	// PullRetryPolicy retries the inspection and the pull of the seed image on failure. Unset retries 3 times with
+	// a backoff of 10 seconds
+	PullRetryPolicy *PullRetryPolicy `json:"pullRetryPolicy,omitempty"`
+	// ForcePull always pulls the seed image from its registry. Unset, the seed image already in the container storage
+	// with the digest of the seed image reference, or of its tag in the registry, is reused
+	ForcePull bool `json:"forcePull,omitempty"`
+}
+
+// PullRetryPolicy defines the retries of the registry operations on the seed image, with a backoff doubling after
+// each failed attempt
+type PullRetryPolicy struct {
+	// Attempts is the number of attempts, including the first one. Value <= 0 is treated as "use default" (3)
+	Attempts int `json:"attempts,omitempty"`
+	// BackoffSeconds is the wait before the first retry, in seconds, doubled before each following retry up to 5
+	// minutes. Value <= 0 is treated as "use default" (10)
+	BackoffSeconds int `json:"backoffSeconds,omitempty"`
+	// MaxDurationSeconds bounds the time spent on the attempts, in seconds, no retry being started past it. Value <= 0
+	// is treated as "no limit"
+	MaxDurationSeconds int `json:"maxDurationSeconds,omitempty"`
 }
 
 type AutoRollbackOnFailure struct {
@@ -87,6 +226,63 @@ type AutoRollbackOnFailure struct {
 	InitMonitorTimeoutSeconds    int  `json:"initMonitorTimeoutSeconds,omitempty"`    // LCA Init Monitor watchdog timeout, in seconds. Value <= 0 is treated as "use default" when writing config file in Prep stage
 }
 
+// PostPivotLogging configures additional outputs for the logs of the post-pivot service, which runs on the first boot
+// of the new stateroot, when its journal is often unreachable for remote debugging
+type PostPivotLogging struct {
+	// SerialConsole is the console device the logs are copied to, e.g. ttyS0
+	// +kubebuilder:validation:Pattern=`^tty[A-Za-z0-9]+$`
+	SerialConsole string `json:"serialConsole,omitempty"`
+	// PersistentLog copies the logs to /var/log/lca/post-pivot.log, which is rotated based on MaxSizeMB and MaxFiles
+	PersistentLog bool `json:"persistentLog,omitempty"`
+	// MaxSizeMB is the size in MB at which the persistent log is rotated. Value <= 0 is treated as "use default" (10)
+	MaxSizeMB int `json:"maxSizeMB,omitempty"`
+	// MaxFiles is the number of rotated persistent logs kept. Value <= 0 is treated as "use default" (3)
+	MaxFiles int `json:"maxFiles,omitempty"`
+}
+
+// PivotGate delays the reboot of the Upgrade stage until the node activity drops below the given thresholds, to
+// minimize the disruption of the workloads. Thresholds left unset are not checked
+type PivotGate struct {
+	// MaxCPUPercent is the node CPU utilization, in percent, at or below which the reboot can proceed
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MaxCPUPercent int `json:"maxCPUPercent,omitempty"`
+	// MaxNetworkKBps is the node network throughput, in KB/s received and transmitted, at or below which the reboot can proceed
+	// +kubebuilder:validation:Minimum=0
+	MaxNetworkKBps int `json:"maxNetworkKBps,omitempty"`
+	// WindowMinutes is the maintenance window, in minutes from the start of the Upgrade stage, in which a low-activity
+	// period is awaited. The Upgrade fails if activity stays high for the whole window. Value <= 0 waits indefinitely
+	WindowMinutes int `json:"windowMinutes,omitempty"`
+}
+
+// RegistryConfigRef defines a reference to a Secret or ConfigMap holding containers-registries.conf drop-ins, under
+// keys ending with .conf, and PEM encoded CA certificates, under keys ending with .crt or .pem
+type RegistryConfigRef struct {
+	// Kind is the kind of the referenced object, Secret by default
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind string `json:"kind,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// +required
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Required
+	// +required
+	Namespace string `json:"namespace"`
+}
+
+// RegistryTLS defines the TLS configuration of a registry, overriding the one of the host
+type RegistryTLS struct {
+	// Registry is the host of the registry, with its port if any, e.g. registry.lab.example.com:5000
+	// +kubebuilder:validation:Required
+	// +required
+	Registry string `json:"registry"`
+	// InsecureSkipTLSVerify disables the verification of the certificate of the registry
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+	// CABundle references a ConfigMap holding the PEM encoded CA certificates of the registry under the ca-bundle.crt key
+	CABundle *ConfigMapRef `json:"caBundle,omitempty"`
+}
+
 // ConfigMapRef defines a reference to a config map
 type ConfigMapRef struct {
 	// +kubebuilder:validation:Required
@@ -96,6 +292,10 @@ type ConfigMapRef struct {
 	// +kubebuilder:validation:Required
 	// +required
 	Namespace string `json:"namespace"`
+
+	// Optional skips the config map when it does not exist, in the extraManifests and oadpContent lists
+	// +optional
+	Optional bool `json:"optional,omitempty"`
 }
 
 // PullSecretRef defines a reference to a secret with credentials for pulling container images
@@ -114,6 +314,231 @@ type ImageBasedUpgradeStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Valid Next Stage"
 	ValidNextStages []ImageBasedUpgradeStage `json:"validNextStages,omitempty"`
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Rollback Plan"
+	RollbackPlan *RollbackPlan `json:"rollbackPlan,omitempty"`
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Preserved Artifacts"
+	PreservedArtifacts *PreservedArtifacts `json:"preservedArtifacts,omitempty"`
+	// SeedImageDigest is the digest of the seed image pulled by Prep. For a seed image reference to a manifest list, it
+	// is the digest of the image of the node architecture
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Seed Image Digest"
+	SeedImageDigest string `json:"seedImageDigest,omitempty"`
+	// SeedImageManifestListDigest is the digest of the manifest list the seed image was resolved from for the node
+	// architecture, when the seed image reference is a manifest list
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Seed Image Manifest List Digest"
+	SeedImageManifestListDigest string `json:"seedImageManifestListDigest,omitempty"`
+	// SeedImageInfo describes the seed image staged by Prep
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Seed Image Info"
+	SeedImageInfo *SeedImageInfo `json:"seedImageInfo,omitempty"`
+	// DeployedCommit is the ostree commit of the new stateroot deployed by Prep, the OS content the node pivots into
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Deployed Commit"
+	DeployedCommit *DeployedCommit `json:"deployedCommit,omitempty"`
+	// Preflight is the readiness summary of the upgrade, refreshed on demand with the lca.openshift.io/runPreflight
+	// annotation
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Preflight"
+	Preflight *PreflightStatus `json:"preflight,omitempty"`
+	// PrecacheAudit reports the precaching audited in the Idle stage on demand with the
+	// lca.openshift.io/precacheVerifyOnly annotation
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Precache Audit"
+	PrecacheAudit *PrecacheAudit `json:"precacheAudit,omitempty"`
+	// Deployments is the inventory of the ostree deployments and the stateroots of the host, refreshed on demand with
+	// the lca.openshift.io/reconcileNow annotation
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Deployments"
+	Deployments *DeploymentInventory `json:"deployments,omitempty"`
+	// PrecacheProgress is the progress of the precaching of the images by Prep
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Precache Progress"
+	PrecacheProgress *PrecacheProgress `json:"precacheProgress,omitempty"`
+	// SystemdUnits reports the systemd units and timers created by the admins of the node that Upgrade carried to the
+	// new stateroot, and those it skipped
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Systemd Units"
+	SystemdUnits *SystemdUnitsCarryOver `json:"systemdUnits,omitempty"`
+	// ValidationErrors lists every problem of the spec found when starting Prep, rather than only the first one
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Validation Errors"
+	ValidationErrors []string `json:"validationErrors,omitempty"`
+}
+
+// SystemdUnitsCarryOver lists the admin systemd units and timers of /etc/systemd/system carried to the new stateroot
+type SystemdUnitsCarryOver struct {
+	// Carried are the units copied to the new stateroot, with their drop-ins and enablement
+	Carried []string `json:"carried,omitempty"`
+	// Skipped are the units not carried, with the reason, e.g. not in the allowlist
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// PrecacheProgress reports the progress of the precaching job, as reported by the job after each image
+type PrecacheProgress struct {
+	// Total is the number of images to precache
+	Total int `json:"total"`
+	// Pulled is the number of images pulled, or that would be pulled when the images are only verified
+	Pulled int `json:"pulled"`
+	// Skipped is the number of images already in the container storage
+	Skipped int `json:"skipped"`
+	// Failed is the number of images that failed to be pulled, or are unavailable when the images are only verified
+	Failed int `json:"failed"`
+	// PulledBytes is the size of the images pulled, or the compressed size of the images that would be pulled when
+	// the images are only verified
+	PulledBytes int64 `json:"pulledBytes,omitempty"`
+	// Percent is the percentage of the images processed, pulled, skipped or failed
+	Percent int `json:"percent"`
+	// EstimatedCompletionTime is extrapolated from the rate at which the images have been processed so far, while
+	// the precaching job is active
+	EstimatedCompletionTime *metav1.Time `json:"estimatedCompletionTime,omitempty"`
+}
+
+// PreflightResult is the result of a preflight check
+type PreflightResult string
+
+const (
+	// PreflightPass is a check that found nothing preventing the upgrade
+	PreflightPass PreflightResult = "Pass"
+	// PreflightWarn is a check that found an issue to review, not preventing the upgrade
+	PreflightWarn PreflightResult = "Warn"
+	// PreflightFail is a check that found an issue the upgrade would fail on
+	PreflightFail PreflightResult = "Fail"
+)
+
+// PreflightStatus summarizes the readiness of the host and the cluster for the upgrade, from the checks Prep and
+// Upgrade run, to decide on go or no-go before starting Prep
+type PreflightStatus struct {
+	// Result is the worst result of the checks
+	// +kubebuilder:validation:Enum=Pass;Warn;Fail
+	Result PreflightResult `json:"result"`
+	// Score is the readiness score, from 0 to 100, the percentage of the checks passed, a warning counting for half
+	Score int `json:"score"`
+	// Checks are the results of the checks, in the order they ran
+	Checks []PreflightCheck `json:"checks,omitempty"`
+	// CompletedAt is the time the checks completed
+	CompletedAt metav1.Time `json:"completedAt"`
+}
+
+// PreflightCheck is the result of a preflight check
+type PreflightCheck struct {
+	// Name identifies the check, e.g. DiskSpace
+	Name string `json:"name"`
+	// +kubebuilder:validation:Enum=Pass;Warn;Fail
+	Result PreflightResult `json:"result"`
+	// Message describes the issue found or what was checked
+	Message string `json:"message,omitempty"`
+}
+
+// PrecacheAuditState is the state of a precaching audit
+type PrecacheAuditState string
+
+const (
+	// PrecacheAuditRunning is an audit whose precaching job checks the images
+	PrecacheAuditRunning PrecacheAuditState = "Running"
+	// PrecacheAuditCompleted is an audit whose report is in the summary
+	PrecacheAuditCompleted PrecacheAuditState = "Completed"
+	// PrecacheAuditFailed is an audit that could not check the images, the reason being in the summary
+	PrecacheAuditFailed PrecacheAuditState = "Failed"
+	// PrecacheAuditCanceled is an audit stopped by leaving the Idle stage
+	PrecacheAuditCanceled PrecacheAuditState = "Canceled"
+)
+
+// PrecacheAudit reports what the precaching of the seed image would pull, the images listed by the labels of the seed
+// image being only checked in their registry, neither the seed image nor any image being pulled
+type PrecacheAudit struct {
+	// SeedImage is the audited seed image
+	SeedImage string `json:"seedImage"`
+	// +kubebuilder:validation:Enum=Running;Completed;Failed;Canceled
+	State PrecacheAuditState `json:"state"`
+	// StartedAt is the time the audit started
+	StartedAt metav1.Time `json:"startedAt"`
+	// CompletedAt is the time the audit completed, failed or was canceled
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+	// Summary is the report of the audit, e.g. "total: 228 (would pull: 207, 21474836480 bytes, skipped: 20,
+	// unavailable: 1)", or the reason it failed
+	Summary string `json:"summary,omitempty"`
+	// Unavailable are the images unavailable in their registry, the precaching would fail to pull
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+// SeedImageInfo describes the seed image staged by Prep, from its labels and the seed cluster info it contains. The
+// values not recorded by the lca-cli that created the seed image are unset.
+type SeedImageInfo struct {
+	// OCPVersion is the OCP version of the seed cluster
+	OCPVersion string `json:"ocpVersion,omitempty"`
+	// BaseOSVersion is the RHCOS version of the seed cluster
+	BaseOSVersion string `json:"baseOSVersion,omitempty"`
+	// FormatVersion is the version of the seed image format
+	FormatVersion int `json:"formatVersion,omitempty"`
+	// FIPS is whether the seed cluster runs in FIPS mode
+	FIPS *bool `json:"fips,omitempty"`
+	// CreatedAt is the time the seed image was built
+	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
+}
+
+// DeployedCommit describes the ostree commit of the new stateroot deployed by Prep, from the metadata of the commit
+type DeployedCommit struct {
+	// Stateroot is the new stateroot
+	Stateroot string `json:"stateroot"`
+	// Deployment is the ostree deployment of the new stateroot, the checksum of the commit and its serial
+	Deployment string `json:"deployment,omitempty"`
+	// Checksum is the checksum of the deployed commit
+	Checksum string `json:"checksum"`
+	// Parent is the checksum of the parent of the deployed commit, if any
+	Parent string `json:"parent,omitempty"`
+	// Version is the version of the deployed commit, e.g. the RHCOS version of the seed
+	Version string `json:"version,omitempty"`
+	// CommittedAt is the time the commit was created
+	CommittedAt *metav1.Time `json:"committedAt,omitempty"`
+	// DeployedAt is the time Prep deployed the commit
+	DeployedAt *metav1.Time `json:"deployedAt,omitempty"`
+}
+
+// DeploymentInventory lists the ostree deployments and the stateroots of the host
+type DeploymentInventory struct {
+	// RefreshedAt is the time the inventory was taken
+	RefreshedAt metav1.Time `json:"refreshedAt"`
+	// Deployments are the ostree deployments, in boot order, the first one being the default
+	Deployments []OstreeDeployment `json:"deployments,omitempty"`
+	// Stateroots are the stateroots of the host, deployed or not
+	Stateroots []string `json:"stateroots,omitempty"`
+}
+
+// OstreeDeployment is an ostree deployment of the host
+type OstreeDeployment struct {
+	// Stateroot is the stateroot of the deployment
+	Stateroot string `json:"stateroot"`
+	// Checksum is the checksum of the deployed commit
+	Checksum string `json:"checksum"`
+	// Version is the version of the deployed commit, e.g. the RHCOS version
+	Version string `json:"version,omitempty"`
+	// Booted is set for the running deployment
+	Booted bool `json:"booted,omitempty"`
+	// Staged is set for the deployment staged for the next boot
+	Staged bool `json:"staged,omitempty"`
+}
+
+// PreservedArtifacts points at the artifacts of a failed Upgrade kept for postmortem analysis, instead of cleaned up,
+// when the upgrade was automatically rolled back with the lca.openshift.io/preserveOnFailure annotation set. They are
+// removed when the next Prep stage starts.
+type PreservedArtifacts struct {
+	// Stateroot is the failed stateroot, kept deployed
+	Stateroot string `json:"stateroot"`
+	// Path is the host directory holding the journal of the failed boot and a copy of the LCA workspace
+	Path string `json:"path"`
+	// Reason is the failure that triggered the rollback
+	Reason string `json:"reason,omitempty"`
+	// PreservedAt is the time of the failure
+	PreservedAt metav1.Time `json:"preservedAt"`
+}
+
+// RollbackPlan lists what the Rollback stage changes, computed before performing the rollback
+type RollbackPlan struct {
+	// Stateroot is the stateroot booted by the rollback
+	Stateroot string `json:"stateroot"`
+	// DeploymentIndex is the index of the deployment set as default for the next boot
+	DeploymentIndex int `json:"deploymentIndex"`
+	// Actions are the changes performed by the rollback, in order
+	Actions []string `json:"actions,omitempty"`
+	// NotRolledBack is the data kept as is by the rollback
+	NotRolledBack []string `json:"notRolledBack,omitempty"`
+	// ConfirmationRequired is set when the rollback risks losing data, until it is confirmed with the
+	// lca.openshift.io/confirmRollback annotation
+	ConfirmationRequired bool `json:"confirmationRequired,omitempty"`
+	// EtcdBackup describes the etcd backup taken before the pivot, checked against its integrity hash, for a disaster
+	// recovery should the rollback fail. Unset when no backup was taken
+	EtcdBackup string `json:"etcdBackup,omitempty"`
 }
 
 // +kubebuilder:object:root=true
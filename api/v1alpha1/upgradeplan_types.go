@@ -0,0 +1,131 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// UpgradePlanPhase defines the type for the UpgradePlan phase field
+type UpgradePlanPhase string
+
+// UpgradePlanPhases defines the string values for the UpgradePlan phases
+var UpgradePlanPhases = struct {
+	Pending            UpgradePlanPhase
+	Preparing          UpgradePlanPhase
+	WaitingForUpgrade  UpgradePlanPhase
+	Upgrading          UpgradePlanPhase
+	WaitingForFinalize UpgradePlanPhase
+	Finalizing         UpgradePlanPhase
+	Completed          UpgradePlanPhase
+	Failed             UpgradePlanPhase
+}{
+	Pending:            "Pending",
+	Preparing:          "Preparing",
+	WaitingForUpgrade:  "WaitingForUpgrade",
+	Upgrading:          "Upgrading",
+	WaitingForFinalize: "WaitingForFinalize",
+	Finalizing:         "Finalizing",
+	Completed:          "Completed",
+	Failed:             "Failed",
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=upgradeplans,scope=Cluster,shortName=upl
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Details",type="string",JSONPath=".status.message"
+// +kubebuilder:validation:XValidation:message="upgradeplan is a singleton, metadata.name must be 'upgrade'", rule="self.metadata.name == 'upgrade'"
+// +operator-sdk:csv:customresourcedefinitions:displayName="Upgrade Plan"
+// UpgradePlan drives the ImageBasedUpgrade through the Prep, Upgrade and, optionally, finalization stages, following
+// the declared maintenance window and health gates, so that no external orchestration is needed
+type UpgradePlan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="cannot modify spec, cr must be deleted and recreated"
+	Spec   UpgradePlanSpec   `json:"spec,omitempty"`
+	Status UpgradePlanStatus `json:"status,omitempty"`
+}
+
+// UpgradePlanSpec defines the desired upgrade and the policy used to drive it
+type UpgradePlanSpec struct {
+	// +kubebuilder:validation:Required
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Seed Image Reference"
+	SeedImageRef          SeedImageRef          `json:"seedImageRef"`
+	OADPContent           []ConfigMapRef        `json:"oadpContent,omitempty"`
+	ExtraManifests        []ConfigMapRef        `json:"extraManifests,omitempty"`
+	AutoRollbackOnFailure AutoRollbackOnFailure `json:"autoRollbackOnFailure,omitempty"`
+	// MaintenanceWindow restricts when the Upgrade stage, which reboots the node, can start. Prep starts immediately
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Maintenance Window"
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Health Gates"
+	HealthGates HealthGates `json:"healthGates,omitempty"`
+	// AutoFinalize moves the ImageBasedUpgrade back to Idle once the upgrade is completed and the health gates pass.
+	// Otherwise, the plan waits for the upgrade to be finalized or rolled back manually
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Auto Finalize"
+	AutoFinalize bool `json:"autoFinalize,omitempty"`
+}
+
+// MaintenanceWindow is a daily time window
+type MaintenanceWindow struct {
+	// Start is the time of the day the window opens, in UTC, formatted as HH:MM
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// DurationMinutes is how long the window stays open
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1440
+	DurationMinutes int `json:"durationMinutes"`
+}
+
+// HealthGates hold the stage transitions of the plan until the cluster is healthy, instead of failing them
+type HealthGates struct {
+	// BeforeUpgrade holds the Upgrade stage until the cluster health checks pass
+	BeforeUpgrade bool `json:"beforeUpgrade,omitempty"`
+	// BeforeFinalize holds the finalization until the cluster health checks pass
+	BeforeFinalize bool `json:"beforeFinalize,omitempty"`
+	// SoakMinutes is how long the upgrade must have been completed before it is finalized
+	// +kubebuilder:validation:Minimum=0
+	SoakMinutes int `json:"soakMinutes,omitempty"`
+}
+
+// UpgradePlanStatus defines the observed state of UpgradePlan
+type UpgradePlanStatus struct {
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Phase"
+	Phase UpgradePlanPhase `json:"phase,omitempty"`
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Message"
+	Message            string      `json:"message,omitempty"`
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	StartedAt          metav1.Time `json:"startedAt,omitempty"`
+	CompletedAt        metav1.Time `json:"completedAt,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// UpgradePlanList contains a list of UpgradePlan
+type UpgradePlanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []UpgradePlan `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&UpgradePlan{}, &UpgradePlanList{})
+}
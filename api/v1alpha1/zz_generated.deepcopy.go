@@ -21,7 +21,8 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -40,6 +41,21 @@ func (in *AutoRollbackOnFailure) DeepCopy() *AutoRollbackOnFailure {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CleanupPolicy) DeepCopyInto(out *CleanupPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CleanupPolicy.
+func (in *CleanupPolicy) DeepCopy() *CleanupPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CleanupPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigMapRef) DeepCopyInto(out *ConfigMapRef) {
 	*out = *in
@@ -55,6 +71,70 @@ func (in *ConfigMapRef) DeepCopy() *ConfigMapRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeployedCommit) DeepCopyInto(out *DeployedCommit) {
+	*out = *in
+	if in.CommittedAt != nil {
+		in, out := &in.CommittedAt, &out.CommittedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.DeployedAt != nil {
+		in, out := &in.DeployedAt, &out.DeployedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeployedCommit.
+func (in *DeployedCommit) DeepCopy() *DeployedCommit {
+	if in == nil {
+		return nil
+	}
+	out := new(DeployedCommit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentInventory) DeepCopyInto(out *DeploymentInventory) {
+	*out = *in
+	in.RefreshedAt.DeepCopyInto(&out.RefreshedAt)
+	if in.Deployments != nil {
+		in, out := &in.Deployments, &out.Deployments
+		*out = make([]OstreeDeployment, len(*in))
+		copy(*out, *in)
+	}
+	if in.Stateroots != nil {
+		in, out := &in.Stateroots, &out.Stateroots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentInventory.
+func (in *DeploymentInventory) DeepCopy() *DeploymentInventory {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentInventory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthGates) DeepCopyInto(out *HealthGates) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthGates.
+func (in *HealthGates) DeepCopy() *HealthGates {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthGates)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageBasedUpgrade) DeepCopyInto(out *ImageBasedUpgrade) {
 	*out = *in
@@ -114,6 +194,84 @@ func (in *ImageBasedUpgradeList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBasedUpgradeReport) DeepCopyInto(out *ImageBasedUpgradeReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBasedUpgradeReport.
+func (in *ImageBasedUpgradeReport) DeepCopy() *ImageBasedUpgradeReport {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBasedUpgradeReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageBasedUpgradeReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBasedUpgradeReportList) DeepCopyInto(out *ImageBasedUpgradeReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageBasedUpgradeReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBasedUpgradeReportList.
+func (in *ImageBasedUpgradeReportList) DeepCopy() *ImageBasedUpgradeReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBasedUpgradeReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageBasedUpgradeReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBasedUpgradeReportStatus) DeepCopyInto(out *ImageBasedUpgradeReportStatus) {
+	*out = *in
+	if in.EstimatedCompletionTime != nil {
+		in, out := &in.EstimatedCompletionTime, &out.EstimatedCompletionTime
+		*out = (*in).DeepCopy()
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBasedUpgradeReportStatus.
+func (in *ImageBasedUpgradeReportStatus) DeepCopy() *ImageBasedUpgradeReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBasedUpgradeReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageBasedUpgradeSpec) DeepCopyInto(out *ImageBasedUpgradeSpec) {
 	*out = *in
@@ -130,6 +288,34 @@ func (in *ImageBasedUpgradeSpec) DeepCopyInto(out *ImageBasedUpgradeSpec) {
 		copy(*out, *in)
 	}
 	out.AutoRollbackOnFailure = in.AutoRollbackOnFailure
+	out.PostPivotLogging = in.PostPivotLogging
+	if in.PivotGate != nil {
+		in, out := &in.PivotGate, &out.PivotGate
+		*out = new(PivotGate)
+		**out = **in
+	}
+	if in.RegistryConfig != nil {
+		in, out := &in.RegistryConfig, &out.RegistryConfig
+		*out = new(RegistryConfigRef)
+		**out = **in
+	}
+	if in.RegistryTLS != nil {
+		in, out := &in.RegistryTLS, &out.RegistryTLS
+		*out = make([]RegistryTLS, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Precache != nil {
+		in, out := &in.Precache, &out.Precache
+		*out = new(PrecacheConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CleanupPolicy != nil {
+		in, out := &in.CleanupPolicy, &out.CleanupPolicy
+		*out = new(CleanupPolicy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBasedUpgradeSpec.
@@ -149,7 +335,7 @@ func (in *ImageBasedUpgradeStatus) DeepCopyInto(out *ImageBasedUpgradeStatus) {
 	in.CompletedAt.DeepCopyInto(&out.CompletedAt)
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -159,6 +345,56 @@ func (in *ImageBasedUpgradeStatus) DeepCopyInto(out *ImageBasedUpgradeStatus) {
 		*out = make([]ImageBasedUpgradeStage, len(*in))
 		copy(*out, *in)
 	}
+	if in.RollbackPlan != nil {
+		in, out := &in.RollbackPlan, &out.RollbackPlan
+		*out = new(RollbackPlan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreservedArtifacts != nil {
+		in, out := &in.PreservedArtifacts, &out.PreservedArtifacts
+		*out = new(PreservedArtifacts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SeedImageInfo != nil {
+		in, out := &in.SeedImageInfo, &out.SeedImageInfo
+		*out = new(SeedImageInfo)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeployedCommit != nil {
+		in, out := &in.DeployedCommit, &out.DeployedCommit
+		*out = new(DeployedCommit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Preflight != nil {
+		in, out := &in.Preflight, &out.Preflight
+		*out = new(PreflightStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrecacheAudit != nil {
+		in, out := &in.PrecacheAudit, &out.PrecacheAudit
+		*out = new(PrecacheAudit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deployments != nil {
+		in, out := &in.Deployments, &out.Deployments
+		*out = new(DeploymentInventory)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrecacheProgress != nil {
+		in, out := &in.PrecacheProgress, &out.PrecacheProgress
+		*out = new(PrecacheProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SystemdUnits != nil {
+		in, out := &in.SystemdUnits, &out.SystemdUnits
+		*out = new(SystemdUnitsCarryOver)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ValidationErrors != nil {
+		in, out := &in.ValidationErrors, &out.ValidationErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBasedUpgradeStatus.
@@ -171,6 +407,222 @@ func (in *ImageBasedUpgradeStatus) DeepCopy() *ImageBasedUpgradeStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OstreeDeployment) DeepCopyInto(out *OstreeDeployment) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OstreeDeployment.
+func (in *OstreeDeployment) DeepCopy() *OstreeDeployment {
+	if in == nil {
+		return nil
+	}
+	out := new(OstreeDeployment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PivotGate) DeepCopyInto(out *PivotGate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PivotGate.
+func (in *PivotGate) DeepCopy() *PivotGate {
+	if in == nil {
+		return nil
+	}
+	out := new(PivotGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostPivotLogging) DeepCopyInto(out *PostPivotLogging) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostPivotLogging.
+func (in *PostPivotLogging) DeepCopy() *PostPivotLogging {
+	if in == nil {
+		return nil
+	}
+	out := new(PostPivotLogging)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightCheck) DeepCopyInto(out *PreflightCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightCheck.
+func (in *PreflightCheck) DeepCopy() *PreflightCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightStatus) DeepCopyInto(out *PreflightStatus) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]PreflightCheck, len(*in))
+		copy(*out, *in)
+	}
+	in.CompletedAt.DeepCopyInto(&out.CompletedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightStatus.
+func (in *PreflightStatus) DeepCopy() *PreflightStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreservedArtifacts) DeepCopyInto(out *PreservedArtifacts) {
+	*out = *in
+	in.PreservedAt.DeepCopyInto(&out.PreservedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreservedArtifacts.
+func (in *PreservedArtifacts) DeepCopy() *PreservedArtifacts {
+	if in == nil {
+		return nil
+	}
+	out := new(PreservedArtifacts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrecacheAudit) DeepCopyInto(out *PrecacheAudit) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Unavailable != nil {
+		in, out := &in.Unavailable, &out.Unavailable
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrecacheAudit.
+func (in *PrecacheAudit) DeepCopy() *PrecacheAudit {
+	if in == nil {
+		return nil
+	}
+	out := new(PrecacheAudit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrecacheConfig) DeepCopyInto(out *PrecacheConfig) {
+	*out = *in
+	if in.IncludeImages != nil {
+		in, out := &in.IncludeImages, &out.IncludeImages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeImages != nil {
+		in, out := &in.ExcludeImages, &out.ExcludeImages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NicePriority != nil {
+		in, out := &in.NicePriority, &out.NicePriority
+		*out = new(int)
+		**out = **in
+	}
+	if in.IoNiceClass != nil {
+		in, out := &in.IoNiceClass, &out.IoNiceClass
+		*out = new(int)
+		**out = **in
+	}
+	if in.IoNicePriority != nil {
+		in, out := &in.IoNicePriority, &out.IoNicePriority
+		*out = new(int)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrecacheConfig.
+func (in *PrecacheConfig) DeepCopy() *PrecacheConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PrecacheConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrecacheProgress) DeepCopyInto(out *PrecacheProgress) {
+	*out = *in
+	if in.EstimatedCompletionTime != nil {
+		in, out := &in.EstimatedCompletionTime, &out.EstimatedCompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrecacheProgress.
+func (in *PrecacheProgress) DeepCopy() *PrecacheProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(PrecacheProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PullRetryPolicy) DeepCopyInto(out *PullRetryPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullRetryPolicy.
+func (in *PullRetryPolicy) DeepCopy() *PullRetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PullRetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PullSecretRef) DeepCopyInto(out *PullSecretRef) {
 	*out = *in
@@ -186,6 +638,90 @@ func (in *PullSecretRef) DeepCopy() *PullSecretRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryConfigRef) DeepCopyInto(out *RegistryConfigRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryConfigRef.
+func (in *RegistryConfigRef) DeepCopy() *RegistryConfigRef {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryConfigRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryTLS) DeepCopyInto(out *RegistryTLS) {
+	*out = *in
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = new(ConfigMapRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryTLS.
+func (in *RegistryTLS) DeepCopy() *RegistryTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackPlan) DeepCopyInto(out *RollbackPlan) {
+	*out = *in
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NotRolledBack != nil {
+		in, out := &in.NotRolledBack, &out.NotRolledBack
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackPlan.
+func (in *RollbackPlan) DeepCopy() *RollbackPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SeedImageInfo) DeepCopyInto(out *SeedImageInfo) {
+	*out = *in
+	if in.FIPS != nil {
+		in, out := &in.FIPS, &out.FIPS
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeedImageInfo.
+func (in *SeedImageInfo) DeepCopy() *SeedImageInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(SeedImageInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SeedImageRef) DeepCopyInto(out *SeedImageRef) {
 	*out = *in
@@ -194,6 +730,11 @@ func (in *SeedImageRef) DeepCopyInto(out *SeedImageRef) {
 		*out = new(PullSecretRef)
 		**out = **in
 	}
+	if in.PullRetryPolicy != nil {
+		in, out := &in.PullRetryPolicy, &out.PullRetryPolicy
+		*out = new(PullRetryPolicy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeedImageRef.
@@ -205,3 +746,137 @@ func (in *SeedImageRef) DeepCopy() *SeedImageRef {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SystemdUnitsCarryOver) DeepCopyInto(out *SystemdUnitsCarryOver) {
+	*out = *in
+	if in.Carried != nil {
+		in, out := &in.Carried, &out.Carried
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Skipped != nil {
+		in, out := &in.Skipped, &out.Skipped
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SystemdUnitsCarryOver.
+func (in *SystemdUnitsCarryOver) DeepCopy() *SystemdUnitsCarryOver {
+	if in == nil {
+		return nil
+	}
+	out := new(SystemdUnitsCarryOver)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradePlan) DeepCopyInto(out *UpgradePlan) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradePlan.
+func (in *UpgradePlan) DeepCopy() *UpgradePlan {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradePlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UpgradePlan) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradePlanList) DeepCopyInto(out *UpgradePlanList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]UpgradePlan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradePlanList.
+func (in *UpgradePlanList) DeepCopy() *UpgradePlanList {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradePlanList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UpgradePlanList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradePlanSpec) DeepCopyInto(out *UpgradePlanSpec) {
+	*out = *in
+	in.SeedImageRef.DeepCopyInto(&out.SeedImageRef)
+	if in.OADPContent != nil {
+		in, out := &in.OADPContent, &out.OADPContent
+		*out = make([]ConfigMapRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraManifests != nil {
+		in, out := &in.ExtraManifests, &out.ExtraManifests
+		*out = make([]ConfigMapRef, len(*in))
+		copy(*out, *in)
+	}
+	out.AutoRollbackOnFailure = in.AutoRollbackOnFailure
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindow)
+		**out = **in
+	}
+	out.HealthGates = in.HealthGates
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradePlanSpec.
+func (in *UpgradePlanSpec) DeepCopy() *UpgradePlanSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradePlanSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradePlanStatus) DeepCopyInto(out *UpgradePlanStatus) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	in.CompletedAt.DeepCopyInto(&out.CompletedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradePlanStatus.
+func (in *UpgradePlanStatus) DeepCopy() *UpgradePlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradePlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -20,11 +20,23 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/backuprestore"
+	"github.com/openshift-kni/lifecycle-agent/internal/diagnostics"
+	"github.com/openshift-kni/lifecycle-agent/internal/extramanifest"
+	"github.com/openshift-kni/lifecycle-agent/internal/hubreport"
+	"github.com/openshift-kni/lifecycle-agent/internal/journal"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/internal/notification"
 	"github.com/openshift-kni/lifecycle-agent/internal/reboot"
+	"github.com/openshift-kni/lifecycle-agent/internal/registrytls"
+	"github.com/openshift-kni/lifecycle-agent/internal/seedimage"
+	"github.com/openshift-kni/lifecycle-agent/internal/systemdunits"
+	"github.com/openshift-kni/lifecycle-agent/internal/upgradesummary"
 
 	"github.com/go-logr/logr"
 	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
@@ -34,12 +46,14 @@ import (
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 	rpmostreeclient "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -67,7 +81,26 @@ type ImageBasedUpgradeReconciler struct {
 	Ops             ops.Ops
 	RebootClient    reboot.RebootIntf
 	PrepTask        *Task
-	Mux             *sync.Mutex
+	HubReporter     *hubreport.Reporter
+	Notifier        *notification.Notifier
+	UpgradeSummary  *upgradesummary.Recorder
+	StaterootLayout common.StaterootLayout
+	// StatusBatcher batches the ibu status updates only reporting progress, shared with the UpgradeHandler
+	StatusBatcher *utils.StatusBatcher
+	Mux           *sync.Mutex
+	// diagnostics is the diagnostics collection running in the background for a failed stage, if any
+	diagnostics *diagnosticsRun
+}
+
+// diagnosticsPollInterval is the interval at which a running diagnostics collection is checked for completion
+const diagnosticsPollInterval = 30 * time.Second
+
+// diagnosticsRun tracks a diagnostics collection, referenced in the failure condition of its stage once collected
+type diagnosticsRun struct {
+	stage lcav1alpha1.ImageBasedUpgradeStage
+	dir   string
+	done  chan struct{}
+	err   error
 }
 
 // Task contains objects for executing a group of serial tasks asynchronously
@@ -76,7 +109,23 @@ type Task struct {
 	Success  bool
 	Cancel   context.CancelFunc
 	Progress string
-	done     chan struct{}
+	// Blocked is set when the task cannot make progress until an external issue is solved, reported in Progress
+	Blocked bool
+	// SeedImageDigest is the digest of the seed image pulled by Prep, reported in the status
+	SeedImageDigest string
+	// SeedImageManifestListDigest is the digest of the manifest list the seed image was resolved from, if any,
+	// reported in the status
+	SeedImageManifestListDigest string
+	// SeedImageInfo describes the seed image pulled by Prep, reported in the status
+	SeedImageInfo *lcav1alpha1.SeedImageInfo
+	// DeployedCommit is the ostree commit deployed by Prep in the new stateroot, reported in the status
+	DeployedCommit *lcav1alpha1.DeployedCommit
+	// PrecacheProgress is the progress of the precaching job, reported in the status
+	PrecacheProgress *lcav1alpha1.PrecacheProgress
+	// PrecacheFailedImages are the images not pre-cached in best-effort mode, reported in the Prep completion
+	PrecacheFailedImages []string
+	Err                  error
+	done                 chan struct{}
 }
 
 // Reset Re-initialize the Task variables to initial values
@@ -85,6 +134,14 @@ func (c *Task) Reset() {
 	c.Success = false
 	c.Cancel = nil
 	c.Progress = ""
+	c.Blocked = false
+	c.SeedImageDigest = ""
+	c.SeedImageManifestListDigest = ""
+	c.SeedImageInfo = nil
+	c.DeployedCommit = nil
+	c.PrecacheProgress = nil
+	c.PrecacheFailedImages = nil
+	c.Err = nil
 	select {
 	case _, open := <-c.done:
 		if open {
@@ -120,6 +177,14 @@ func requeueWithLongInterval() ctrl.Result {
 	return requeueWithCustomInterval(5 * time.Minute)
 }
 
+// requeueNoLaterThan returns the result, requeued after the interval at the latest
+func requeueNoLaterThan(result ctrl.Result, interval time.Duration) ctrl.Result {
+	if !result.Requeue && (result.RequeueAfter == 0 || result.RequeueAfter > interval) {
+		result.RequeueAfter = interval
+	}
+	return result
+}
+
 //nolint:unused
 func requeueWithCustomInterval(interval time.Duration) ctrl.Result {
 	return ctrl.Result{RequeueAfter: interval}
@@ -177,13 +242,43 @@ func (r *ImageBasedUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Re
 
 	r.Log.Info("Loaded IBU", "name", req.NamespacedName, "version", ibu.GetResourceVersion(), "desired stage", ibu.Spec.Stage)
 
+	previousConditions := ibu.Status.DeepCopy().Conditions
+	defer r.recordStageTransitions(ibu, previousConditions)
+
+	if err = r.handleReconcileNow(ctx, ibu); err != nil {
+		return
+	}
+	if err = r.handleRunPreflight(ctx, ibu); err != nil {
+		return
+	}
+	if err = r.handlePrecacheAudit(ctx, ibu); err != nil {
+		return
+	}
+
 	var isAfterPivot bool
-	isAfterPivot, err = r.RPMOstreeClient.IsStaterootBooted(common.GetDesiredStaterootName(ibu))
+	isAfterPivot, err = r.RPMOstreeClient.IsStaterootBooted(ctx, r.StaterootLayout.DesiredName(ibu))
 	if err != nil {
 		return
 	}
 
 	if isTransitionRequested(ibu) {
+		// A stage started by the previous or the next version of the operator would run with mixed versions, the
+		// stages in progress are completed as they checkpoint their progress
+		var operatorUpgrade string
+		if operatorUpgrade, err = lcautils.GetOperatorUpgrade(ctx, r.Client, common.LcaNamespace); err != nil {
+			return
+		}
+		if operatorUpgrade != "" {
+			r.Log.Info("Deferring the stage transition until the operator upgrade completes", "upgrade", operatorUpgrade)
+			utils.SetWaitingForOperatorUpgrade(ibu, fmt.Sprintf(
+				"Waiting for the lifecycle-agent operator upgrade to complete before starting the %s stage: %s",
+				ibu.Spec.Stage, operatorUpgrade))
+			err = utils.UpdateIBUStatusBatched(ctx, r.Client, r.StatusBatcher, ibu)
+			nextReconcile = requeueWithShortInterval()
+			return
+		}
+		utils.ClearStatusCondition(&ibu.Status.Conditions, utils.ConditionTypes.WaitingForOperatorUpgrade)
+
 		// Update in progress condition to true and idle condition to false when transitioning to non idle stage
 		if validateStageTransition(ibu, isAfterPivot) {
 			// Validate the IBU spec if the transition is to prep stage
@@ -194,7 +289,7 @@ func (r *ImageBasedUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Re
 					return
 				}
 				if !isValid {
-					err = utils.UpdateIBUStatus(ctx, r.Client, ibu)
+					err = utils.UpdateIBUStatusBatched(ctx, r.Client, r.StatusBatcher, ibu)
 					return
 				}
 			}
@@ -204,19 +299,224 @@ func (r *ImageBasedUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		inProgressStage := utils.GetInProgressStage(ibu)
 		if inProgressStage != "" {
 			nextReconcile, err = r.handleStage(ctx, ibu, inProgressStage)
+			if inProgressStage != lcav1alpha1.Stages.Idle && utils.IsStageFailed(ibu, inProgressStage) {
+				r.collectDiagnostics(ibu, inProgressStage)
+			}
 			if err != nil {
-				_ = utils.UpdateIBUStatus(ctx, r.Client, ibu)
+				_ = utils.UpdateIBUStatusBatched(ctx, r.Client, r.StatusBatcher, ibu)
 				return
 			}
 			ibu.Status.ValidNextStages = getValidNextStageList(ibu, isAfterPivot)
 		}
 	}
 
+	r.reportDiagnostics(ibu)
+
 	// Update status
-	err = utils.UpdateIBUStatus(ctx, r.Client, ibu)
+	err = utils.UpdateIBUStatusBatched(ctx, r.Client, r.StatusBatcher, ibu)
+	if err == nil && r.StatusBatcher != nil && r.StatusBatcher.Pending() {
+		// Come back to write the progress batched, in case nothing else triggers a status update
+		nextReconcile = requeueNoLaterThan(nextReconcile, utils.GetStatusUpdateInterval(ibu))
+	}
+	if err == nil && isPrecacheAuditRunning(ibu) {
+		nextReconcile = requeueNoLaterThan(nextReconcile, precacheAuditPollInterval)
+	}
+	if err == nil && r.diagnostics != nil {
+		nextReconcile = requeueNoLaterThan(nextReconcile, diagnosticsPollInterval)
+	}
+	r.publishHubReport(ctx, ibu)
+	r.recordUpgradeSummary(ctx, ibu)
 	return
 }
 
+// recordUpgradeSummary records the ibu status in the summary of the last upgrade attempts, if enabled. Failures are
+// logged only, as the summary is informational
+func (r *ImageBasedUpgradeReconciler) recordUpgradeSummary(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) {
+	if r.UpgradeSummary == nil {
+		return
+	}
+
+	if err := r.UpgradeSummary.Record(ctx, ibu); err != nil {
+		r.Log.Error(err, "Failed to record the upgrade summary")
+	}
+}
+
+// publishHubReport mirrors the ibu status to the hub, if enabled. Failures are logged only, as the hub may be
+// unreachable during the upgrade
+func (r *ImageBasedUpgradeReconciler) publishHubReport(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) {
+	if r.HubReporter == nil {
+		return
+	}
+
+	var eta *time.Time
+	if r.PrepTask != nil && r.PrepTask.Active && utils.IsStageInProgress(ibu, lcav1alpha1.Stages.Prep) {
+		if status, err := r.Precache.QueryJobStatus(ctx); err == nil && status != nil {
+			eta = status.EstimatedCompletion(time.Now())
+		}
+	}
+
+	if err := r.HubReporter.Publish(ctx, ibu, eta); err != nil {
+		r.Log.Error(err, "Failed to publish ImageBasedUpgradeReport to hub")
+	}
+}
+
+// recordStageTransitions records an event for each stage started, completed or failed since the previous conditions,
+// and for each abort or finalize step. The events are also written to the host journal by the recorder
+func (r *ImageBasedUpgradeReconciler) recordStageTransitions(ibu *lcav1alpha1.ImageBasedUpgrade, previous []metav1.Condition) {
+	for _, stage := range []lcav1alpha1.ImageBasedUpgradeStage{
+		lcav1alpha1.Stages.Prep, lcav1alpha1.Stages.Upgrade, lcav1alpha1.Stages.Rollback} {
+		inProgress := utils.GetInProgressCondition(ibu, stage)
+		if inProgress != nil && inProgress.Status == metav1.ConditionTrue &&
+			!meta.IsStatusConditionTrue(previous, inProgress.Type) {
+			r.Recorder.Event(ibu, corev1.EventTypeNormal, fmt.Sprintf("%sStarted", stage), inProgress.Message)
+		}
+
+		completed := utils.GetCompletedCondition(ibu, stage)
+		if completed == nil || meta.IsStatusConditionPresentAndEqual(previous, completed.Type, completed.Status) {
+			continue
+		}
+		if completed.Status == metav1.ConditionTrue {
+			r.Recorder.Event(ibu, corev1.EventTypeNormal, fmt.Sprintf("%sCompleted", stage), completed.Message)
+		} else if inProgress != nil {
+			r.Recorder.Event(ibu, corev1.EventTypeWarning, fmt.Sprintf("%sFailed", stage), inProgress.Message)
+		}
+	}
+
+	idle := meta.FindStatusCondition(ibu.Status.Conditions, string(utils.ConditionTypes.Idle))
+	if idle == nil {
+		return
+	}
+	if previousIdle := meta.FindStatusCondition(previous, idle.Type); previousIdle != nil &&
+		previousIdle.Status == idle.Status && previousIdle.Reason == idle.Reason {
+		return
+	}
+	switch idle.Reason {
+	case string(utils.ConditionReasons.Idle), string(utils.ConditionReasons.Aborting), string(utils.ConditionReasons.Finalizing):
+		r.Recorder.Event(ibu, corev1.EventTypeNormal, idle.Reason, idle.Message)
+	case string(utils.ConditionReasons.AbortFailed), string(utils.ConditionReasons.FinalizeFailed):
+		r.Recorder.Event(ibu, corev1.EventTypeWarning, idle.Reason, idle.Message)
+	}
+}
+
+// handleReconcileNow looks for ReconcileNowAnnotation in the ibu CR. If present, it removes the annotation and
+// refreshes on demand the inventory of the deployments and stateroots of the node and, during Prep, the precaching
+// progress, instead of waiting for the next precaching job status poll
+func (r *ImageBasedUpgradeReconciler) handleReconcileNow(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	if _, ok := ibu.Annotations[utils.ReconcileNowAnnotation]; !ok {
+		return nil
+	}
+
+	r.Log.Info("Reconcile now requested, refreshing status")
+	delete(ibu.Annotations, utils.ReconcileNowAnnotation)
+	if err := r.Client.Update(ctx, ibu); err != nil {
+		return fmt.Errorf("failed to remove reconcile now annotation from ibu: %w", err)
+	}
+
+	if err := r.refreshDeploymentInventory(ctx, ibu); err != nil {
+		r.Log.Info("Unable to refresh deployments inventory", "error", err.Error())
+	}
+
+	if r.PrepTask != nil && r.PrepTask.Active && utils.IsStageInProgress(ibu, lcav1alpha1.Stages.Prep) {
+		status, err := r.Precache.QueryJobStatus(ctx)
+		if err != nil {
+			r.Log.Info("Unable to refresh precaching progress", "error", err.Error())
+			return nil
+		}
+		if status != nil {
+			r.updatePrecachingProgress(status)
+		}
+	}
+	return nil
+}
+
+// refreshDeploymentInventory records in the ibu status the ostree deployments of the node, as reported by
+// rpm-ostree, and the stateroots found on disk
+func (r *ImageBasedUpgradeReconciler) refreshDeploymentInventory(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	status, err := r.RPMOstreeClient.QueryStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to query status with rpmostree: %w", err)
+	}
+	files, err := osReadDir(getStaterootPath(r.StaterootLayout, ""))
+	if err != nil {
+		return fmt.Errorf("failed to list stateroots: %w", err)
+	}
+
+	inventory := &lcav1alpha1.DeploymentInventory{RefreshedAt: metav1.Now()}
+	for _, deployment := range status.Deployments {
+		inventory.Deployments = append(inventory.Deployments, lcav1alpha1.OstreeDeployment{
+			Stateroot: deployment.OSName,
+			Checksum:  deployment.Checksum,
+			Version:   deployment.Version,
+			Booted:    deployment.Booted,
+			Staged:    deployment.Staged,
+		})
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			inventory.Stateroots = append(inventory.Stateroots, file.Name())
+		}
+	}
+	ibu.Status.Deployments = inventory
+	return nil
+}
+
+// collectDiagnostics starts collecting diagnostics in the background for the failed stage, if enabled through
+// the CollectDiagnosticsAnnotation. A single collection runs at a time.
+func (r *ImageBasedUpgradeReconciler) collectDiagnostics(ibu *lcav1alpha1.ImageBasedUpgrade, stage lcav1alpha1.ImageBasedUpgradeStage) {
+	mode, exists := ibu.GetAnnotations()[utils.CollectDiagnosticsAnnotation]
+	if !exists {
+		return
+	}
+	if !diagnostics.IsValidMode(diagnostics.Mode(mode)) {
+		r.Log.Info("Ignoring invalid diagnostics mode", "annotation", utils.CollectDiagnosticsAnnotation, "value", mode)
+		return
+	}
+	if r.diagnostics != nil {
+		r.Log.Info("Skipping diagnostics collection, one is already running", "stage", r.diagnostics.stage)
+		return
+	}
+
+	run := &diagnosticsRun{
+		stage: stage,
+		dir:   filepath.Join(utils.IBUDiagnosticsPath, fmt.Sprintf("%s-%d", strings.ToLower(string(stage)), time.Now().Unix())),
+		done:  make(chan struct{}),
+	}
+	r.diagnostics = run
+
+	collector := diagnostics.NewCollector(r.Log, r.Executor)
+	gatherImage := ibu.GetAnnotations()[utils.MustGatherImageAnnotation]
+	go func() {
+		defer close(run.done)
+		run.err = collector.Collect(context.Background(), diagnostics.Mode(mode), run.dir, gatherImage)
+	}()
+}
+
+// reportDiagnostics references the diagnostics in the failure condition of their stage once collected, provided the
+// stage is still failed. A failed collection is logged only.
+func (r *ImageBasedUpgradeReconciler) reportDiagnostics(ibu *lcav1alpha1.ImageBasedUpgrade) {
+	run := r.diagnostics
+	if run == nil {
+		return
+	}
+	select {
+	case <-run.done:
+	default:
+		return
+	}
+	r.diagnostics = nil
+
+	if run.err != nil {
+		r.Log.Error(run.err, "Failed to collect diagnostics", "stage", run.stage)
+		return
+	}
+	condition := utils.GetInProgressCondition(ibu, run.stage)
+	if condition == nil || !utils.IsStageFailed(ibu, run.stage) {
+		r.Log.Info("Diagnostics collected after the failed stage was left", "stage", run.stage, "dir", run.dir)
+		return
+	}
+	condition.Message = fmt.Sprintf("%s. Diagnostics collected in %s", condition.Message, run.dir)
+}
+
 func getValidNextStageList(ibu *lcav1alpha1.ImageBasedUpgrade, isAfterPivot bool) []lcav1alpha1.ImageBasedUpgradeStage {
 	inProgressStage := utils.GetInProgressStage(ibu)
 	if inProgressStage == lcav1alpha1.Stages.Idle || inProgressStage == lcav1alpha1.Stages.Rollback || utils.IsStageFailed(ibu, lcav1alpha1.Stages.Rollback) {
@@ -330,34 +630,69 @@ func isAbortAllowed(ibu *lcav1alpha1.ImageBasedUpgrade, isAfterPivot bool) bool
 	return false
 }
 
+// isUpgradePreempted returns true if the Upgrade stage was stopped by a Rollback request
+func isUpgradePreempted(ibu *lcav1alpha1.ImageBasedUpgrade) bool {
+	condition := meta.FindStatusCondition(ibu.Status.Conditions, string(utils.ConditionTypes.UpgradeInProgress))
+	return condition != nil && condition.Reason == string(utils.ConditionReasons.Preempted)
+}
+
+// setUpgradePreempted stops the in progress Upgrade stage for the Rollback request. The upgrade handlers stop at
+// their next safe checkpoint, see UpgHandler.preemptedByRollback
+func setUpgradePreempted(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
+	utils.SetStatusCondition(&ibu.Status.Conditions,
+		utils.ConditionTypes.UpgradeInProgress,
+		utils.ConditionReasons.Preempted,
+		metav1.ConditionFalse,
+		msg,
+		ibu.Generation,
+	)
+	utils.SetStatusCondition(&ibu.Status.Conditions,
+		utils.ConditionTypes.UpgradeCompleted,
+		utils.ConditionReasons.Preempted,
+		metav1.ConditionFalse,
+		msg,
+		ibu.Generation,
+	)
+}
+
 // TODO unit test this function once the logic is stablized
 func validateStageTransition(ibu *lcav1alpha1.ImageBasedUpgrade, isAfterPivot bool) bool {
 	switch ibu.Spec.Stage {
 	case lcav1alpha1.Stages.Rollback:
 		if !isRollbackAllowed(ibu, isAfterPivot) {
+			msg := "Upgrade not started or already finalized"
+			if !isAfterPivot && (utils.IsStageInProgress(ibu, lcav1alpha1.Stages.Upgrade) || isUpgradePreempted(ibu)) {
+				// The upgrade is stopped before the pivot, there is nothing to roll back but it can be aborted
+				setUpgradePreempted(ibu, "Upgrade preempted by the Rollback request before the pivot, the original stateroot is still booted")
+				msg = "Nothing to roll back before the pivot, set the stage to Idle to abort the upgrade"
+			}
 			utils.SetStatusCondition(&ibu.Status.Conditions,
 				utils.ConditionTypes.RollbackInProgress,
 				utils.ConditionReasons.InvalidTransition,
 				metav1.ConditionFalse,
-				"Upgrade not started or already finalized",
+				msg,
 				ibu.Generation,
 			)
 			return false
 		}
-		utils.SetStatusCondition(&ibu.Status.Conditions,
-			utils.ConditionTypes.UpgradeInProgress,
-			utils.ConditionReasons.Failed,
-			metav1.ConditionFalse,
-			"Rollback requested",
-			ibu.Generation,
-		)
-		utils.SetStatusCondition(&ibu.Status.Conditions,
-			utils.ConditionTypes.UpgradeCompleted,
-			utils.ConditionReasons.Failed,
-			metav1.ConditionFalse,
-			"Rollback requested",
-			ibu.Generation,
-		)
+		if utils.IsStageInProgress(ibu, lcav1alpha1.Stages.Upgrade) {
+			setUpgradePreempted(ibu, "Upgrade preempted by the Rollback request")
+		} else {
+			utils.SetStatusCondition(&ibu.Status.Conditions,
+				utils.ConditionTypes.UpgradeInProgress,
+				utils.ConditionReasons.Failed,
+				metav1.ConditionFalse,
+				"Rollback requested",
+				ibu.Generation,
+			)
+			utils.SetStatusCondition(&ibu.Status.Conditions,
+				utils.ConditionTypes.UpgradeCompleted,
+				utils.ConditionReasons.Failed,
+				metav1.ConditionFalse,
+				"Rollback requested",
+				ibu.Generation,
+			)
+		}
 
 	case lcav1alpha1.Stages.Idle:
 		if isFinalizeAllowed(ibu) {
@@ -437,36 +772,148 @@ func validateStageTransition(ibu *lcav1alpha1.ImageBasedUpgrade, isAfterPivot bo
 	return true
 }
 
-// validateIBUSpec validates the IBU CR, returns true if the spec is valid, false otherwise
+// validateIBUSpec validates the IBU CR, returns true if the spec is valid, false otherwise. Every problem of the spec
+// is reported at once in the validationErrors status field, rather than only the first one, so that they can all be
+// fixed before the next Prep attempt
 func (r *ImageBasedUpgradeReconciler) validateIBUSpec(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (bool, error) {
 	r.Log.Info("Validating IBU spec")
 
+	invalid, err := r.collectIBUSpecErrors(ctx, ibu)
+	if err != nil {
+		return false, err
+	}
+	ibu.Status.ValidationErrors = nil
+	if len(invalid) == 0 {
+		return true, nil
+	}
+
+	for _, e := range invalid {
+		ibu.Status.ValidationErrors = append(ibu.Status.ValidationErrors, e.Error())
+	}
+	r.Log.Info("Invalid IBU spec", "validationErrors", ibu.Status.ValidationErrors)
+	// The condition reason is the one of the first problem, its message listing all of them
+	if len(invalid) == 1 {
+		utils.SetPrepStatusFailedWithError(ibu, invalid[0])
+	} else {
+		utils.SetPrepStatusFailedWithError(ibu, lcaerrors.Wrap(invalid[0], fmt.Errorf("%d problems in the spec: %s",
+			len(invalid), strings.Join(ibu.Status.ValidationErrors, "; "))))
+	}
+	return false, nil
+}
+
+// collectIBUSpecErrors returns the problems of the IBU spec, in the order they are checked. The error is only returned
+// when the validation itself fails, e.g. the API is not reachable
+func (r *ImageBasedUpgradeReconciler) collectIBUSpecErrors(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) ([]error, error) {
+	var invalid []error
+	invalidSpec := func(err error) {
+		invalid = append(invalid, lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, err))
+	}
+
+	// The stateroot name can only be suffixed with the digest of a seed image referenced by digest, as a tag may move
+	if ibu.GetAnnotations()[common.StaterootSeedDigestSuffixAnnotation] == "true" &&
+		common.ShortSeedDigest(ibu.Spec.SeedImageRef.Image) == "" {
+		invalidSpec(fmt.Errorf("the %s annotation requires a seed image referenced by digest: %s",
+			common.StaterootSeedDigestSuffixAnnotation, ibu.Spec.SeedImageRef.Image))
+	}
+
+	if _, err := utils.SkipSeedVersionCheck(ibu); err != nil {
+		invalidSpec(err)
+	}
+
+	if _, err := systemdunits.ParseAllowlist(ibu.GetAnnotations()[utils.SystemdUnitsAllowlistAnnotation]); err != nil {
+		invalidSpec(err)
+	}
+
+	if _, err := seedimage.ParseArchive(ibu.Spec.SeedImageRef.Image); err != nil {
+		invalidSpec(err)
+	}
+
+	if _, err := expectedSeedImageDigest(ibu.Spec.SeedImageRef); err != nil {
+		invalidSpec(err)
+	}
+
+	if err := registrytls.Validate(&ibu.Spec); err != nil {
+		invalidSpec(err)
+	}
+
+	if err := validatePrecacheMode(ibu); err != nil {
+		invalidSpec(err)
+	}
+
+	if ibu.Spec.Precache != nil {
+		if err := precache.ValidateResources(ibu.Spec.Precache.Resources); err != nil {
+			invalidSpec(err)
+		}
+		if _, err := getPrecacheFilter(ibu.Spec.Precache, ""); err != nil {
+			invalid = append(invalid, err)
+		}
+	}
+
+	if _, err := r.getAdditionalImages(ctx, ibu); err != nil {
+		if !isInvalidSpec(err) {
+			return nil, err
+		}
+		invalid = append(invalid, err)
+	}
+
+	var manifests []unstructured.Unstructured
+	validManifests := true
+	if len(ibu.Spec.ExtraManifests) != 0 {
+		var err error
+		manifests, err = extramanifest.GetExtraManifests(ctx, r.Client, ibu.Spec.ExtraManifests)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get extra manifests: %w", err)
+			}
+			invalid = append(invalid, extramanifest.NewEMFailedError(err.Error()))
+			validManifests = false
+		} else if err := extramanifest.ValidateMergeStrategies(manifests); err != nil {
+			invalid = append(invalid, err)
+			validManifests = false
+		}
+	}
+
 	// If OADP configmap is provided, validate the configmap and check if OADP operator is available
 	if len(ibu.Spec.OADPContent) != 0 {
+		validOadp := true
 		err := r.BackupRestore.ValidateOadpConfigmap(ctx, ibu.Spec.OADPContent)
 		if err != nil {
-			if backuprestore.IsBRFailedValidationError(err) {
-				utils.SetPrepStatusFailed(ibu, err.Error())
-				return false, nil
+			if !backuprestore.IsBRFailedValidationError(err) {
+				return nil, fmt.Errorf("failed to validate oadp configMap: %w", err)
 			}
-			return false, fmt.Errorf("failed to validate oadp configMap: %w", err)
+			invalid = append(invalid, err)
+			validOadp = false
 		}
 
 		err = r.BackupRestore.CheckOadpOperatorAvailability(ctx)
 		if err != nil {
-			if backuprestore.IsBRFailedValidationError(err) {
-				utils.SetPrepStatusFailed(ibu, err.Error())
-				return false, nil
+			if !backuprestore.IsBRFailedValidationError(err) {
+				return nil, fmt.Errorf("failed to check oadp operator availability: %w", err)
+			}
+			invalid = append(invalid, err)
+			validOadp = false
+		}
+
+		// Detect the extra manifests also restored by OADP, as both are applied after the pivot, only comparing valid
+		// contents
+		if validOadp && validManifests && len(manifests) != 0 {
+			preferExtraManifests := ibu.GetAnnotations()[utils.ExtraManifestConflictPolicyAnnotation] == utils.PreferExtraManifests
+			err = r.BackupRestore.ValidateExtraManifestConflicts(ctx, ibu.Spec.OADPContent, manifests, preferExtraManifests)
+			if err != nil {
+				if !backuprestore.IsBRFailedValidationError(err) {
+					return nil, fmt.Errorf("failed to validate extra manifests conflicts: %w", err)
+				}
+				invalid = append(invalid, err)
 			}
-			return false, fmt.Errorf("failed to check oadp operator availability: %w", err)
 		}
 	}
-	return true, nil
+	return invalid, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ImageBasedUpgradeReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	r.Recorder = mgr.GetEventRecorderFor("ImageBasedUpgrade")
+	r.Recorder = r.Notifier.NewEventRecorder(
+		journal.NewEventRecorder(mgr.GetEventRecorderFor("ImageBasedUpgrade"), r.Executor, r.Log.WithName("Journal")))
 
 	//nolint:wrapcheck
 	return ctrl.NewControllerManagedBy(mgr).
@@ -487,6 +934,27 @@ func (r *ImageBasedUpgradeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					return true
 				}
 
+				// trigger reconcile upon adding or updating ReconcileNowAnnotation
+				oldValue, oldReconcileNow := e.ObjectOld.GetAnnotations()[utils.ReconcileNowAnnotation]
+				newValue, newReconcileNow := e.ObjectNew.GetAnnotations()[utils.ReconcileNowAnnotation]
+				if newReconcileNow && (!oldReconcileNow || oldValue != newValue) {
+					return true
+				}
+
+				// trigger reconcile upon adding or updating RunPreflightAnnotation
+				oldValue, oldRunPreflight := e.ObjectOld.GetAnnotations()[utils.RunPreflightAnnotation]
+				newValue, newRunPreflight := e.ObjectNew.GetAnnotations()[utils.RunPreflightAnnotation]
+				if newRunPreflight && (!oldRunPreflight || oldValue != newValue) {
+					return true
+				}
+
+				// trigger reconcile upon changing the rollback dry-run or confirmation annotations
+				for _, annotation := range []string{utils.RollbackDryRunAnnotation, utils.ConfirmRollbackAnnotation} {
+					if e.ObjectOld.GetAnnotations()[annotation] != e.ObjectNew.GetAnnotations()[annotation] {
+						return true
+					}
+				}
+
 				return false
 			},
 			CreateFunc:  func(ce event.CreateEvent) bool { return true },
@@ -18,13 +18,17 @@ package controllers
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/go-logr/logr"
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
 	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
 	rpmostreeclient "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -32,6 +36,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -43,7 +48,9 @@ var (
 )
 
 func init() {
-	testscheme.AddKnownTypes(lcav1alpha1.GroupVersion, &lcav1alpha1.ImageBasedUpgrade{})
+	testscheme.AddKnownTypes(lcav1alpha1.GroupVersion, &lcav1alpha1.ImageBasedUpgrade{}, &lcav1alpha1.UpgradePlan{})
+	testscheme.AddKnownTypes(operatorsv1alpha1.SchemeGroupVersion, &operatorsv1alpha1.ClusterServiceVersion{},
+		&operatorsv1alpha1.ClusterServiceVersionList{})
 }
 
 func getFakeClientFromObjects(objs ...client.Object) (client.WithWatch, error) {
@@ -636,15 +643,15 @@ func TestValidateStageTransisions(t *testing.T) {
 			expectedConditions: []ExpectedCondition{
 				{
 					utils.ConditionTypes.UpgradeInProgress,
-					utils.ConditionReasons.Failed,
+					utils.ConditionReasons.Preempted,
 					metav1.ConditionFalse,
-					"Rollback requested",
+					"Upgrade preempted by the Rollback request",
 				},
 				{
 					utils.ConditionTypes.UpgradeCompleted,
-					utils.ConditionReasons.Failed,
+					utils.ConditionReasons.Preempted,
 					metav1.ConditionFalse,
-					"Rollback requested",
+					"Upgrade preempted by the Rollback request",
 				},
 				{
 					utils.ConditionTypes.RollbackInProgress,
@@ -655,6 +662,33 @@ func TestValidateStageTransisions(t *testing.T) {
 			},
 			afterPivot: true,
 		},
+		{
+			name:       "rollback when upgrade in progress before pivot",
+			stage:      lcav1alpha1.Stages.Rollback,
+			conditions: []Condition{{utils.ConditionTypes.UpgradeInProgress, metav1.ConditionTrue, ""}},
+			expected:   false,
+			expectedConditions: []ExpectedCondition{
+				{
+					utils.ConditionTypes.UpgradeInProgress,
+					utils.ConditionReasons.Preempted,
+					metav1.ConditionFalse,
+					"Upgrade preempted by the Rollback request before the pivot, the original stateroot is still booted",
+				},
+				{
+					utils.ConditionTypes.UpgradeCompleted,
+					utils.ConditionReasons.Preempted,
+					metav1.ConditionFalse,
+					"Upgrade preempted by the Rollback request before the pivot, the original stateroot is still booted",
+				},
+				{
+					utils.ConditionTypes.RollbackInProgress,
+					utils.ConditionReasons.InvalidTransition,
+					metav1.ConditionFalse,
+					"Nothing to roll back before the pivot, set the stage to Idle to abort the upgrade",
+				},
+			},
+			afterPivot: false,
+		},
 		{
 			name:       "rollback without upgrade in progress",
 			stage:      lcav1alpha1.Stages.Rollback,
@@ -706,6 +740,7 @@ func TestImageBasedUpgradeReconciler_Reconcile(t *testing.T) {
 	testcases := []struct {
 		name         string
 		ibu          client.Object
+		objs         []client.Object
 		request      reconcile.Request
 		validateFunc func(t *testing.T, result ctrl.Result, ibu *lcav1alpha1.ImageBasedUpgrade)
 	}{
@@ -732,11 +767,88 @@ func TestImageBasedUpgradeReconciler_Reconcile(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "idle IBU with reconcile now annotation",
+			ibu: &lcav1alpha1.ImageBasedUpgrade{
+				ObjectMeta: v1.ObjectMeta{
+					Name:        utils.IBUName,
+					Annotations: map[string]string{utils.ReconcileNowAnnotation: "1"},
+				},
+				Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+					Stage: lcav1alpha1.Stages.Idle,
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: utils.IBUName,
+				},
+			},
+			validateFunc: func(t *testing.T, result ctrl.Result, ibu *lcav1alpha1.ImageBasedUpgrade) {
+				_, annotationPresent := ibu.Annotations[utils.ReconcileNowAnnotation]
+				assert.False(t, annotationPresent)
+				if assert.NotNil(t, ibu.Status.Deployments) {
+					assert.Equal(t, []lcav1alpha1.OstreeDeployment{
+						{Stateroot: "rhcos", Checksum: "abc", Version: "4.15.0", Booted: true},
+						{Stateroot: "rhcos_4.16.0", Checksum: "def", Version: "4.16.0", Staged: true},
+					}, ibu.Status.Deployments.Deployments)
+					assert.Equal(t, []string{"rhcos", "rhcos_4.16.0"}, ibu.Status.Deployments.Stateroots)
+				}
+				idleCondition := meta.FindStatusCondition(ibu.Status.Conditions, string(utils.ConditionTypes.Idle))
+				assert.Equal(t, idleCondition.Status, metav1.ConditionTrue)
+			},
+		},
+		{
+			name: "prep deferred during operator upgrade",
+			ibu: &lcav1alpha1.ImageBasedUpgrade{
+				ObjectMeta: v1.ObjectMeta{
+					Name: utils.IBUName,
+				},
+				Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+					Stage: lcav1alpha1.Stages.Prep,
+				},
+			},
+			objs: []client.Object{
+				&operatorsv1alpha1.ClusterServiceVersion{
+					ObjectMeta: v1.ObjectMeta{Name: "lifecycle-agent.v4.16.1", Namespace: common.LcaNamespace},
+					Spec:       operatorsv1alpha1.ClusterServiceVersionSpec{Replaces: "lifecycle-agent.v4.16.0"},
+					Status:     operatorsv1alpha1.ClusterServiceVersionStatus{Phase: operatorsv1alpha1.CSVPhaseInstalling},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: utils.IBUName,
+				},
+			},
+			validateFunc: func(t *testing.T, result ctrl.Result, ibu *lcav1alpha1.ImageBasedUpgrade) {
+				assert.Equal(t, requeueWithShortInterval(), result)
+				waiting := meta.FindStatusCondition(ibu.Status.Conditions, string(utils.ConditionTypes.WaitingForOperatorUpgrade))
+				if assert.NotNil(t, waiting) {
+					assert.Equal(t, metav1.ConditionTrue, waiting.Status)
+					assert.Equal(t, "Waiting for the lifecycle-agent operator upgrade to complete before starting the Prep stage: "+
+						"lifecycle-agent.v4.16.1 replacing lifecycle-agent.v4.16.0 is Installing", waiting.Message)
+				}
+				assert.Nil(t, meta.FindStatusCondition(ibu.Status.Conditions, string(utils.ConditionTypes.PrepInProgress)))
+			},
+		},
+	}
+	staterootsDir := t.TempDir()
+	for _, stateroot := range []string{"rhcos", "rhcos_4.16.0"} {
+		if err := os.Mkdir(filepath.Join(staterootsDir, stateroot), 0o700); err != nil {
+			t.Fatal(err)
+		}
 	}
+	origGetStaterootPath, origReadDir := getStaterootPath, osReadDir
+	defer func() {
+		getStaterootPath, osReadDir = origGetStaterootPath, origReadDir
+	}()
+	getStaterootPath = func(layout common.StaterootLayout, stateroot string) string {
+		return filepath.Join(staterootsDir, stateroot)
+	}
+	osReadDir = os.ReadDir
 	for _, tc := range testcases {
 		t.TempDir()
 		t.Run(tc.name, func(t *testing.T) {
-			objs := []client.Object{tc.ibu}
+			objs := append([]client.Object{tc.ibu}, tc.objs...)
 			fakeClient, err := getFakeClientFromObjects(objs...)
 			if err != nil {
 				t.Errorf("error in creating fake client")
@@ -744,13 +856,19 @@ func TestImageBasedUpgradeReconciler_Reconcile(t *testing.T) {
 
 			ctrl := gomock.NewController(t)
 			mockClient := rpmostreeclient.NewMockIClient(ctrl)
-			mockClient.EXPECT().IsStaterootBooted("rhcos_").Return(false, nil)
+			mockClient.EXPECT().IsStaterootBooted(gomock.Any(), "rhcos_").Return(false, nil)
+			mockClient.EXPECT().QueryStatus(gomock.Any()).Return(&rpmostreeclient.Status{
+				Deployments: []rpmostreeclient.Deployment{
+					{OSName: "rhcos", Checksum: "abc", Version: "4.15.0", Booted: true},
+					{OSName: "rhcos_4.16.0", Checksum: "def", Version: "4.16.0", Staged: true},
+				}}, nil).AnyTimes()
 
 			r := &ImageBasedUpgradeReconciler{
 				Client:          fakeClient,
 				Log:             logr.Discard(),
 				Scheme:          fakeClient.Scheme(),
 				RPMOstreeClient: mockClient,
+				Recorder:        record.NewFakeRecorder(10),
 			}
 			result, err := r.Reconcile(context.TODO(), tc.request)
 			if err != nil {
@@ -889,3 +1007,102 @@ func Test_getValidNextStageList(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordStageTransitions(t *testing.T) {
+	ibu := &lcav1alpha1.ImageBasedUpgrade{ObjectMeta: v1.ObjectMeta{Name: utils.IBUName}}
+	utils.ResetStatusConditions(&ibu.Status.Conditions, ibu.Generation)
+	recorder := record.NewFakeRecorder(10)
+	r := &ImageBasedUpgradeReconciler{Log: logr.Discard(), Recorder: recorder}
+
+	// No transition
+	previous := ibu.Status.DeepCopy().Conditions
+	r.recordStageTransitions(ibu, previous)
+	assert.Empty(t, recorder.Events)
+
+	utils.SetPrepStatusInProgress(ibu, "Prep in progress")
+	r.recordStageTransitions(ibu, previous)
+	assert.Equal(t, "Normal PrepStarted Prep in progress", <-recorder.Events)
+
+	previous = ibu.Status.DeepCopy().Conditions
+	utils.SetPrepStatusFailed(ibu, "no space left")
+	r.recordStageTransitions(ibu, previous)
+	assert.Equal(t, "Warning PrepFailed no space left", <-recorder.Events)
+
+	previous = ibu.Status.DeepCopy().Conditions
+	utils.SetStatusCondition(&ibu.Status.Conditions, utils.ConditionTypes.Idle, utils.ConditionReasons.Aborting,
+		metav1.ConditionFalse, "Aborting", ibu.Generation)
+	r.recordStageTransitions(ibu, previous)
+	assert.Equal(t, "Normal Aborting Aborting", <-recorder.Events)
+	assert.Empty(t, recorder.Events)
+}
+
+func TestReportDiagnostics(t *testing.T) {
+	ibu := &lcav1alpha1.ImageBasedUpgrade{ObjectMeta: v1.ObjectMeta{Name: utils.IBUName}}
+	utils.ResetStatusConditions(&ibu.Status.Conditions, ibu.Generation)
+	utils.SetPrepStatusFailed(ibu, "no space left")
+	run := &diagnosticsRun{stage: lcav1alpha1.Stages.Prep, dir: "/var/lib/lca/diagnostics/prep-1", done: make(chan struct{})}
+	r := &ImageBasedUpgradeReconciler{Log: logr.Discard(), diagnostics: run}
+
+	// Not referenced while collecting
+	r.reportDiagnostics(ibu)
+	assert.Equal(t, "no space left", utils.GetInProgressCondition(ibu, lcav1alpha1.Stages.Prep).Message)
+	assert.Equal(t, run, r.diagnostics)
+
+	// Referenced once collected
+	close(run.done)
+	r.reportDiagnostics(ibu)
+	assert.Equal(t, "no space left. Diagnostics collected in /var/lib/lca/diagnostics/prep-1",
+		utils.GetInProgressCondition(ibu, lcav1alpha1.Stages.Prep).Message)
+	assert.Nil(t, r.diagnostics)
+
+	// Not referenced when the collection failed
+	utils.SetPrepStatusFailed(ibu, "no space left")
+	run = &diagnosticsRun{stage: lcav1alpha1.Stages.Prep, dir: "/var/lib/lca/diagnostics/prep-2", done: make(chan struct{}),
+		err: assert.AnError}
+	close(run.done)
+	r.diagnostics = run
+	r.reportDiagnostics(ibu)
+	assert.Equal(t, "no space left", utils.GetInProgressCondition(ibu, lcav1alpha1.Stages.Prep).Message)
+	assert.Nil(t, r.diagnostics)
+}
+
+func TestValidateIBUSpecReportsAllProblems(t *testing.T) {
+	fakeClient, err := getFakeClientFromObjects()
+	assert.NoError(t, err)
+	r := &ImageBasedUpgradeReconciler{Client: fakeClient, Log: logr.Discard()}
+
+	ibu := &lcav1alpha1.ImageBasedUpgrade{
+		ObjectMeta: v1.ObjectMeta{
+			Name: utils.IBUName,
+			Annotations: map[string]string{
+				common.StaterootSeedDigestSuffixAnnotation: "true",
+				utils.SystemdUnitsAllowlistAnnotation:      "site-[.timer",
+			},
+		},
+		Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+			Stage:            lcav1alpha1.Stages.Prep,
+			SeedImageRef:     lcav1alpha1.SeedImageRef{Image: "quay.io/xyz/seed:4.15.0", Version: "4.15.0"},
+			AdditionalImages: lcav1alpha1.ConfigMapRef{Name: "cnf-images", Namespace: common.LcaNamespace},
+		},
+	}
+	valid, err := r.validateIBUSpec(context.Background(), ibu)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	assert.Len(t, ibu.Status.ValidationErrors, 3)
+	assert.Contains(t, ibu.Status.ValidationErrors[0], "requires a seed image referenced by digest")
+	assert.Contains(t, ibu.Status.ValidationErrors[1], `invalid systemd unit pattern "site-[.timer"`)
+	assert.Contains(t, ibu.Status.ValidationErrors[2], "additional images configMap openshift-lifecycle-agent/cnf-images not found")
+
+	condition := meta.FindStatusCondition(ibu.Status.Conditions, string(utils.GetInProgressConditionType(lcav1alpha1.Stages.Prep)))
+	assert.NotNil(t, condition)
+	assert.Equal(t, string(utils.ConditionReasons.InvalidSpec), condition.Reason)
+	assert.Contains(t, condition.Message, "3 problems in the spec: ")
+
+	// The problems of a previous attempt are cleared once the spec is fixed
+	ibu.Annotations = nil
+	ibu.Spec.AdditionalImages = lcav1alpha1.ConfigMapRef{}
+	valid, err = r.validateIBUSpec(context.Background(), ibu)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	assert.Nil(t, ibu.Status.ValidationErrors)
+}
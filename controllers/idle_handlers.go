@@ -21,8 +21,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/registryconfig"
+	"github.com/openshift-kni/lifecycle-agent/internal/seedimage"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
@@ -33,6 +36,7 @@ import (
 var osStat = os.Stat
 var osReadDir = os.ReadDir
 var osRemoveAll = os.RemoveAll
+var gcClock = time.Now
 
 //nolint:unparam
 func (r *ImageBasedUpgradeReconciler) handleAbort(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, error) {
@@ -41,6 +45,14 @@ func (r *ImageBasedUpgradeReconciler) handleAbort(ctx context.Context, ibu *lcav
 	if successful, errMsg := r.cleanup(ctx, false, ibu); successful {
 		r.Log.Info("Finished handleAbort successfully")
 		utils.ResetStatusConditions(&ibu.Status.Conditions, ibu.Generation)
+		ibu.Status.RollbackPlan = nil
+		ibu.Status.SeedImageDigest = ""
+		ibu.Status.SeedImageManifestListDigest = ""
+		ibu.Status.SeedImageInfo = nil
+		ibu.Status.DeployedCommit = nil
+		ibu.Status.PrecacheProgress = nil
+		ibu.Status.SystemdUnits = nil
+		ibu.Status.ValidationErrors = nil
 		return doNotRequeue(), nil
 	} else {
 		utils.SetStatusCondition(&ibu.Status.Conditions,
@@ -97,6 +109,7 @@ func (r *ImageBasedUpgradeReconciler) handleFinalize(ctx context.Context, ibu *l
 	if successful, errMsg := r.cleanup(ctx, true, ibu); successful {
 		r.Log.Info("Finished handleFinalize successfully")
 		utils.ResetStatusConditions(&ibu.Status.Conditions, ibu.Generation)
+		ibu.Status.RollbackPlan = nil
 		return doNotRequeue(), nil
 	} else {
 		utils.SetStatusCondition(&ibu.Status.Conditions,
@@ -131,11 +144,17 @@ func (r *ImageBasedUpgradeReconciler) cleanup(
 		r.PrepTask.Cancel()
 		r.PrepTask.Reset()
 	}
-	if err := r.cleanupStateroots(allUnbootedStateroots, ibu); err != nil {
-		handleError(err, "failed to cleanup stateroots.")
-	}
-	if err := r.Precache.Cleanup(ctx); err != nil {
-		handleError(err, "failed to cleanup precaching resources.")
+	// the artifacts preserved for a postmortem are only released by the next Prep
+	if preserved := ibu.Status.PreservedArtifacts; preserved != nil {
+		r.Log.Info("Keeping the stateroot and precaching resources preserved on failure",
+			"stateroot", preserved.Stateroot, "path", preserved.Path)
+	} else {
+		if err := r.cleanupStateroots(ctx, allUnbootedStateroots, ibu); err != nil {
+			handleError(err, "failed to cleanup stateroots.")
+		}
+		if err := r.Precache.Cleanup(ctx); err != nil {
+			handleError(err, "failed to cleanup precaching resources.")
+		}
 	}
 
 	// only delete Backup CRs
@@ -150,17 +169,139 @@ func (r *ImageBasedUpgradeReconciler) cleanup(
 		handleError(err, "failed to cleanup ibu files.")
 	}
 
+	if err := r.removeRegistryConfig(ibu); err != nil {
+		handleError(err, "failed to remove registry configuration.")
+	}
+
+	r.collectGarbage(ctx, allUnbootedStateroots, ibu)
+
 	return successful, errorMessage
 }
 
+// collectGarbage removes the seed images and the old stateroots left on the host by previous upgrades, per the cleanup
+// policy of the IBU. Nothing is removed without it, as the seed images may be reused, e.g. for a re-run. Its failures
+// are logged only, not to block the transition to Idle on housekeeping.
+func (r *ImageBasedUpgradeReconciler) collectGarbage(ctx context.Context, finalize bool, ibu *lcav1alpha1.ImageBasedUpgrade) {
+	policy := ibu.Spec.CleanupPolicy
+	if policy == nil {
+		policy = &lcav1alpha1.CleanupPolicy{}
+	}
+
+	if policy.RemoveSeedImages {
+		var keep []string
+		if !finalize && ibu.Spec.SeedImageRef.Image != "" {
+			// reused by a retried Prep
+			keep = append(keep, seedimage.StorageName(ibu.Spec.SeedImageRef.Image))
+		}
+		removed, err := seedimage.Prune(ctx, r.Executor, keep...)
+		if len(removed) != 0 {
+			r.Log.Info("Removed seed images", "images", removed)
+		}
+		if err != nil {
+			r.Log.Error(err, "Failed to remove seed images")
+		}
+	}
+
+	if policy.StaterootMaxAgeDays > 0 {
+		deleted, err := r.cleanupOldStateroots(ctx, time.Duration(policy.StaterootMaxAgeDays)*24*time.Hour, ibu)
+		if len(deleted) != 0 {
+			r.Log.Info("Removed old stateroots", "stateroots", deleted)
+		}
+		if err != nil {
+			r.Log.Error(err, "Failed to remove old stateroots")
+		}
+	}
+}
+
+// cleanupOldStateroots removes the stateroots whose directory was not modified for maxAge, but those of the booted,
+// staged or default deployment and the one preserved on failure. It returns the removed stateroots.
+func (r *ImageBasedUpgradeReconciler) cleanupOldStateroots(ctx context.Context, maxAge time.Duration, ibu *lcav1alpha1.ImageBasedUpgrade) ([]string, error) {
+	status, err := r.RPMOstreeClient.QueryStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status with rpmostree: %w", err)
+	}
+	inUse := map[string]bool{}
+	for i, deployment := range status.Deployments {
+		if deployment.Booted || deployment.Staged || i == 0 {
+			inUse[deployment.OSName] = true
+		}
+	}
+	if preserved := ibu.Status.PreservedArtifacts; preserved != nil {
+		inUse[preserved.Stateroot] = true
+	}
+
+	files, err := osReadDir(getStaterootPath(r.StaterootLayout, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stateroots: %w", err)
+	}
+	var removed []string
+	var errs []error
+	for _, file := range files {
+		if !file.IsDir() || inUse[file.Name()] {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get the modification time of stateroot %s: %w", file.Name(), err))
+			continue
+		}
+		if gcClock().Sub(info.ModTime()) < maxAge {
+			continue
+		}
+		if err := r.cleanupUnbootedStateroot(ctx, file.Name()); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, file.Name())
+	}
+	return removed, errors.Join(errs...)
+}
+
 // cleanupStateroot cleans all unbooted stateroots or desired stateroot
 // depending on allUnbootedStateroots argument
-func (r *ImageBasedUpgradeReconciler) cleanupStateroots(
+func (r *ImageBasedUpgradeReconciler) cleanupStateroots(ctx context.Context,
 	allUnbootedStateroots bool, ibu *lcav1alpha1.ImageBasedUpgrade) error {
 	if allUnbootedStateroots {
-		return r.cleanupUnbootedStateroots()
+		return r.cleanupUnbootedStateroots(ctx)
+	}
+	return r.cleanupUnbootedStateroot(ctx, r.StaterootLayout.DesiredName(ibu))
+}
+
+// releasePreservedArtifacts removes the stateroot, precaching resources and postmortem artifacts preserved on an
+// Upgrade failure, before they are replaced by a new Prep
+func (r *ImageBasedUpgradeReconciler) releasePreservedArtifacts(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	preserved := ibu.Status.PreservedArtifacts
+	if preserved == nil {
+		return nil
+	}
+	r.Log.Info("Releasing the artifacts preserved on failure", "stateroot", preserved.Stateroot, "path", preserved.Path)
+	if err := r.cleanupUnbootedStateroot(ctx, preserved.Stateroot); err != nil {
+		return fmt.Errorf("failed to remove preserved stateroot %s: %w", preserved.Stateroot, err)
+	}
+	if err := r.Precache.Cleanup(ctx); err != nil {
+		return fmt.Errorf("failed to cleanup preserved precaching resources: %w", err)
+	}
+	if err := os.RemoveAll(common.PathOutsideChroot(preserved.Path)); err != nil {
+		return fmt.Errorf("removing %s failed: %w", preserved.Path, err)
+	}
+	ibu.Status.PreservedArtifacts = nil
+	return nil
+}
+
+// removeRegistryConfig removes the registries configuration installed on the host by Prep, unless the host runs the
+// upgraded stateroot, which keeps it
+func (r *ImageBasedUpgradeReconciler) removeRegistryConfig(ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	if utils.IsStageCompleted(ibu, lcav1alpha1.Stages.Upgrade) && !utils.IsStageCompleted(ibu, lcav1alpha1.Stages.Rollback) {
+		return nil
 	}
-	return r.cleanupUnbootedStateroot(common.GetDesiredStaterootName(ibu))
+	removedCACerts, err := registryconfig.Remove(common.PathOutsideChroot("/"))
+	if err != nil {
+		return fmt.Errorf("failed to remove registry config: %w", err)
+	}
+	if removedCACerts {
+		return registryconfig.UpdateCATrust(r.Executor)
+	}
+	return nil
 }
 
 func cleanupIBUFiles() error {
@@ -173,8 +314,8 @@ func cleanupIBUFiles() error {
 	return nil
 }
 
-func (r *ImageBasedUpgradeReconciler) cleanupUnbootedStateroots() error {
-	status, err := r.RPMOstreeClient.QueryStatus()
+func (r *ImageBasedUpgradeReconciler) cleanupUnbootedStateroots(ctx context.Context) error {
+	status, err := r.RPMOstreeClient.QueryStatus(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to query status with rpmostree: %w", err)
 	}
@@ -196,14 +337,14 @@ func (r *ImageBasedUpgradeReconciler) cleanupUnbootedStateroots() error {
 		if stateroot == bootedStateroot {
 			continue
 		}
-		if err := r.cleanupUnbootedStateroot(stateroot); err != nil {
+		if err := r.cleanupUnbootedStateroot(ctx, stateroot); err != nil {
 			r.Log.Error(err, "failed to remove stateroot", "stateroot", stateroot)
 			failures += 1
 		}
 	}
 
 	// remove stateroots that are not listed in rpm-ostree, e.g failed deployments
-	files, err := osReadDir(getStaterootPath(""))
+	files, err := osReadDir(getStaterootPath(r.StaterootLayout, ""))
 	if err != nil {
 		return fmt.Errorf("failed to list stateroots: %w", err)
 	}
@@ -212,7 +353,7 @@ func (r *ImageBasedUpgradeReconciler) cleanupUnbootedStateroots() error {
 			if fileInfo.Name() == bootedStateroot {
 				continue
 			}
-			err := osRemoveAll(getStaterootPath(fileInfo.Name()))
+			err := osRemoveAll(getStaterootPath(r.StaterootLayout, fileInfo.Name()))
 			if err != nil {
 				r.Log.Error(err, "failed to remove undeployed stateroot", "stateroot", fileInfo.Name())
 				failures += 1
@@ -226,8 +367,8 @@ func (r *ImageBasedUpgradeReconciler) cleanupUnbootedStateroots() error {
 	return fmt.Errorf("failed to remove %d stateroots", failures)
 }
 
-func (r *ImageBasedUpgradeReconciler) cleanupUnbootedStateroot(stateroot string) error {
-	status, err := r.RPMOstreeClient.QueryStatus()
+func (r *ImageBasedUpgradeReconciler) cleanupUnbootedStateroot(ctx context.Context, stateroot string) error {
+	status, err := r.RPMOstreeClient.QueryStatus(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to query status with rpmostree during stateroot cleanup: %w", err)
 	}
@@ -245,15 +386,15 @@ func (r *ImageBasedUpgradeReconciler) cleanupUnbootedStateroot(stateroot string)
 		indicesToUndeploy = append(indicesToUndeploy, i)
 	}
 	for _, idx := range indicesToUndeploy {
-		if err := r.OstreeClient.Undeploy(idx); err != nil {
+		if err := r.OstreeClient.Undeploy(ctx, idx); err != nil {
 			return fmt.Errorf("failed to undeploy %s with index %d: %w", stateroot, idx, err)
 		}
 	}
-	staterootPath := common.GetStaterootPath(stateroot)
+	staterootPath := r.StaterootLayout.Path(stateroot)
 	if _, err := osStat(common.PathOutsideChroot(staterootPath)); err != nil {
 		return nil
 	}
-	if _, err := r.Ops.RunBashInHostNamespace("unshare", "-m", "/bin/sh", "-c",
+	if _, err := r.Ops.RunBashInHostNamespace(ctx, "unshare", "-m", "/bin/sh", "-c",
 		fmt.Sprintf("\"mount -o remount,rw /sysroot && rm -rf %s\"", staterootPath)); err != nil {
 		return fmt.Errorf("removing stateroot %s failed: %w", stateroot, err)
 	}
@@ -20,7 +20,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
@@ -117,13 +119,13 @@ func TestImageBasedUpgradeReconciler_cleanupUnbootedStateroot(t *testing.T) {
 			executorMock := ops.NewMockExecute(ctrl)
 			mockOps := ops.NewMockOps(ctrl)
 
-			rpmostreeclientMock.EXPECT().QueryStatus().Return(&rpmostreeclient.Status{
+			rpmostreeclientMock.EXPECT().QueryStatus(gomock.Any()).Return(&rpmostreeclient.Status{
 				Deployments: tt.deployments}, nil)
 			for _, x := range tt.undeployIndices {
-				ostreeclientMock.EXPECT().Undeploy(x)
+				ostreeclientMock.EXPECT().Undeploy(gomock.Any(), x)
 			}
 			if tt.expectToRemove != "" {
-				mockOps.EXPECT().RunBashInHostNamespace("unshare", "-m", "/bin/sh", "-c",
+				mockOps.EXPECT().RunBashInHostNamespace(gomock.Any(), "unshare", "-m", "/bin/sh", "-c",
 					fmt.Sprintf("\"mount -o remount,rw /sysroot && rm -rf /ostree/deploy/%s\"",
 						tt.expectToRemove))
 			}
@@ -138,8 +140,8 @@ func TestImageBasedUpgradeReconciler_cleanupUnbootedStateroot(t *testing.T) {
 				return os.Stat(".")
 			}
 
-			if err := r.cleanupUnbootedStateroot(tt.input); (err != nil) != tt.wantErr {
-				t.Errorf("ImageBasedUpgradeReconciler.cleanupUnbootedStateroot() error = %v, wantErr %v", err, tt.wantErr)
+			if err := r.cleanupUnbootedStateroot(context.Background(), tt.input); (err != nil) != tt.wantErr {
+				t.Errorf("ImageBasedUpgradeReconciler.cleanupUnbootedStateroot(ctx) error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
@@ -275,15 +277,15 @@ func TestImageBasedUpgradeReconciler_cleanupUnbootedStateroots(t *testing.T) {
 			executorMock := ops.NewMockExecute(ctrl)
 			mockOps := ops.NewMockOps(ctrl)
 
-			rpmostreeclientMock.EXPECT().QueryStatus().Return(&rpmostreeclient.Status{
+			rpmostreeclientMock.EXPECT().QueryStatus(gomock.Any()).Return(&rpmostreeclient.Status{
 				Deployments: tt.deployments}, nil)
 			for _, x := range tt.undeployIndices {
-				ostreeclientMock.EXPECT().Undeploy(x)
+				ostreeclientMock.EXPECT().Undeploy(gomock.Any(), x)
 			}
 			for _, stateroot := range tt.staterootsToRemove {
-				rpmostreeclientMock.EXPECT().QueryStatus().Return(&rpmostreeclient.Status{
+				rpmostreeclientMock.EXPECT().QueryStatus(gomock.Any()).Return(&rpmostreeclient.Status{
 					Deployments: tt.deployments}, nil)
-				mockOps.EXPECT().RunBashInHostNamespace("unshare", "-m", "/bin/sh", "-c",
+				mockOps.EXPECT().RunBashInHostNamespace(gomock.Any(), "unshare", "-m", "/bin/sh", "-c",
 					fmt.Sprintf("\"mount -o remount,rw /sysroot && rm -rf /ostree/deploy/%s\"",
 						stateroot))
 			}
@@ -295,9 +297,87 @@ func TestImageBasedUpgradeReconciler_cleanupUnbootedStateroots(t *testing.T) {
 				Ops:             mockOps,
 			}
 
-			if err := r.cleanupUnbootedStateroots(); (err != nil) != tt.wantErr {
-				t.Errorf("ImageBasedUpgradeReconciler.cleanupUnbootedStateroots() error = %v, wantErr %v", err, tt.wantErr)
+			if err := r.cleanupUnbootedStateroots(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("ImageBasedUpgradeReconciler.cleanupUnbootedStateroots(ctx) error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestImageBasedUpgradeReconciler_collectGarbage(t *testing.T) {
+	origStat, origReadDir, origClock := osStat, osReadDir, gcClock
+	defer func() { osStat, osReadDir, gcClock = origStat, origReadDir, origClock }()
+
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	gcClock = func() time.Time { return now }
+	deployDir := t.TempDir()
+	for name, age := range map[string]time.Duration{
+		"rhcos_4.16.1": 60 * 24 * time.Hour,
+		"rhcos":        90 * 24 * time.Hour,
+		"rhcos_4.15.2": 40 * 24 * time.Hour,
+		"rhcos_4.15.9": 45 * 24 * time.Hour,
+		"rhcos_4.17.0": 10 * 24 * time.Hour,
+	} {
+		assert.NoError(t, os.Mkdir(filepath.Join(deployDir, name), 0o755))
+		assert.NoError(t, os.Chtimes(filepath.Join(deployDir, name), now.Add(-age), now.Add(-age)))
+	}
+	osReadDir = func(name string) ([]os.DirEntry, error) {
+		return os.ReadDir(deployDir)
+	}
+	osStat = func(name string) (os.FileInfo, error) {
+		return os.Stat(".")
+	}
+
+	ctrl := gomock.NewController(t)
+	ostreeclientMock := ostreeclient.NewMockIClient(ctrl)
+	rpmostreeclientMock := rpmostreeclient.NewMockIClient(ctrl)
+	executorMock := ops.NewMockExecute(ctrl)
+	mockOps := ops.NewMockOps(ctrl)
+	r := &ImageBasedUpgradeReconciler{
+		Log:             logr.Discard(),
+		RPMOstreeClient: rpmostreeclientMock,
+		Executor:        executorMock,
+		OstreeClient:    ostreeclientMock,
+		Ops:             mockOps,
+	}
+
+	// rhcos is the booted stateroot and rhcos_4.16.1 the default one, rhcos_4.15.9 is preserved on failure and
+	// rhcos_4.17.0 is too recent
+	rpmostreeclientMock.EXPECT().QueryStatus(gomock.Any()).Return(&rpmostreeclient.Status{Deployments: []rpmostreeclient.Deployment{
+		{OSName: "rhcos_4.16.1"},
+		{OSName: "rhcos", Booted: true},
+		{OSName: "rhcos_4.15.2"},
+	}}, nil).Times(2)
+	ostreeclientMock.EXPECT().Undeploy(gomock.Any(), 2).Return(nil)
+	mockOps.EXPECT().RunBashInHostNamespace(gomock.Any(), "unshare", "-m", "/bin/sh", "-c",
+		"\"mount -o remount,rw /sysroot && rm -rf /ostree/deploy/rhcos_4.15.2\"").Return("", nil)
+	// the seed image of the spec is kept on abort
+	executorMock.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "images", "--filter",
+		"label=com.openshift.lifecycle-agent.seed_format_version", "--format", "json").
+		Return(`[{"Id":"aaa","Names":["quay.io/org/seed:4.17.0"]},{"Id":"bbb","Names":["quay.io/org/seed:4.15.2"]}]`, nil)
+	executorMock.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "rmi", "bbb").Return("", nil)
+
+	ibu := &lcav1alpha1.ImageBasedUpgrade{
+		Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+			SeedImageRef:  lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed:4.17.0"},
+			CleanupPolicy: &lcav1alpha1.CleanupPolicy{RemoveSeedImages: true, StaterootMaxAgeDays: 30},
+		},
+		Status: lcav1alpha1.ImageBasedUpgradeStatus{
+			PreservedArtifacts: &lcav1alpha1.PreservedArtifacts{Stateroot: "rhcos_4.15.9"},
+		},
+	}
+	r.collectGarbage(context.Background(), false, ibu)
+
+	// All the seed images are removed on finalize
+	executorMock.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "images", "--filter",
+		"label=com.openshift.lifecycle-agent.seed_format_version", "--format", "json").
+		Return(`[{"Id":"aaa","Names":["quay.io/org/seed:4.17.0"]}]`, nil)
+	executorMock.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "rmi", "aaa").Return("", nil)
+	ibu.Spec.CleanupPolicy = &lcav1alpha1.CleanupPolicy{RemoveSeedImages: true}
+	r.collectGarbage(context.Background(), true, ibu)
+
+	// Nothing is removed without cleanup policy, on finalize or abort
+	ibu.Spec.CleanupPolicy = nil
+	r.collectGarbage(context.Background(), true, ibu)
+	r.collectGarbage(context.Background(), false, ibu)
+}
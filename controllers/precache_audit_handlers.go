@@ -0,0 +1,202 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/precache"
+)
+
+// precacheAuditPollInterval is the interval at which the precaching job of a running audit is checked
+const precacheAuditPollInterval = 30 * time.Second
+
+// precacheAuditDir holds the image list and the seed cluster info of the audited seed image, read from its labels
+var precacheAuditDir = filepath.Join(utils.IBUWorkspacePath, "precache-audit")
+
+// handlePrecacheAudit looks for PrecacheVerifyOnlyAnnotation in the ibu CR. If set to "true", it removes the
+// annotation and, in the Idle stage, starts the precaching job in verify only mode on the images listed by the labels
+// of the seed image, the seed image being inspected in its registry but not pulled. The audit is followed on the next
+// reconciles and reported in the status, not in the stage conditions, so that Prep can be started as usual. Leaving
+// the Idle stage cancels it.
+func (r *ImageBasedUpgradeReconciler) handlePrecacheAudit(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	if isPrecacheAuditRunning(ibu) {
+		if !isIdle(ibu) {
+			return r.cancelPrecacheAudit(ctx, ibu)
+		}
+		return r.pollPrecacheAudit(ctx, ibu)
+	}
+
+	if ibu.GetAnnotations()[utils.PrecacheVerifyOnlyAnnotation] != "true" {
+		return nil
+	}
+
+	delete(ibu.Annotations, utils.PrecacheVerifyOnlyAnnotation)
+	if err := r.Client.Update(ctx, ibu); err != nil {
+		return fmt.Errorf("failed to remove precache verify only annotation from ibu: %w", err)
+	}
+
+	if !isIdle(ibu) {
+		r.Log.Info("Skipping the precaching audit, it only runs in the Idle stage")
+		return nil
+	}
+
+	r.Log.Info("Starting the precaching audit", "seedImage", ibu.Spec.SeedImageRef.Image)
+	ibu.Status.PrecacheAudit = &lcav1alpha1.PrecacheAudit{
+		SeedImage: ibu.Spec.SeedImageRef.Image,
+		State:     lcav1alpha1.PrecacheAuditRunning,
+		StartedAt: metav1.Now(),
+	}
+	if err := r.startPrecacheAudit(ctx, ibu); err != nil {
+		r.Log.Error(err, "Failed to start the precaching audit")
+		finishPrecacheAudit(ibu.Status.PrecacheAudit, lcav1alpha1.PrecacheAuditFailed, err.Error())
+		r.cleanupPrecacheAudit(ctx)
+	}
+	return nil
+}
+
+// isIdle returns whether the ibu completed the Idle stage and no transition is requested
+func isIdle(ibu *lcav1alpha1.ImageBasedUpgrade) bool {
+	return ibu.Spec.Stage == lcav1alpha1.Stages.Idle && utils.IsStageCompleted(ibu, lcav1alpha1.Stages.Idle)
+}
+
+// isPrecacheAuditRunning returns whether the precaching job of an audit is to be followed
+func isPrecacheAuditRunning(ibu *lcav1alpha1.ImageBasedUpgrade) bool {
+	return ibu.Status.PrecacheAudit != nil && ibu.Status.PrecacheAudit.State == lcav1alpha1.PrecacheAuditRunning
+}
+
+// startPrecacheAudit creates the precaching job verifying the images listed by the labels of the seed image
+func (r *ImageBasedUpgradeReconciler) startPrecacheAudit(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	seedImage, _, err := r.preflightSeedImage(ctx, ibu)
+	if err != nil {
+		return err
+	}
+	imageList, clusterInfo, err := precacheAuditInputs(seedImage.labels)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(common.PathOutsideChroot(precacheAuditDir), 0o700); err != nil {
+		return fmt.Errorf("failed to create precaching audit dir: %w", err)
+	}
+	imageListFile := filepath.Join(precacheAuditDir, "image-list-file")
+	if err := os.WriteFile(common.PathOutsideChroot(imageListFile), []byte(imageList), 0o600); err != nil {
+		return fmt.Errorf("failed to write precaching audit image list: %w", err)
+	}
+	seedManifestFile := filepath.Join(precacheAuditDir, common.SeedClusterInfoFileName)
+	if err := os.WriteFile(common.PathOutsideChroot(seedManifestFile), []byte(clusterInfo), 0o600); err != nil {
+		return fmt.Errorf("failed to write precaching audit seed cluster info: %w", err)
+	}
+
+	registryTLS, err := r.renderRegistryTLS(ctx, ibu)
+	if err != nil {
+		return err
+	}
+	if _, err := r.launchPrecaching(ctx, imageListFile, seedManifestFile, ibu, registryTLS, true); err != nil {
+		return fmt.Errorf("failed to launch the precaching audit: %w", err)
+	}
+	return nil
+}
+
+// precacheAuditInputs returns the image list file content and the seed cluster info from the labels of the seed
+// image, set by the lca-cli versions supporting the precaching audit
+func precacheAuditInputs(labels map[string]string) (string, string, error) {
+	imageList, hasImageList := labels[common.SeedPrecacheListOCILabel]
+	clusterInfo, hasClusterInfo := labels[common.SeedClusterInfoOCILabel]
+	if !hasImageList || !hasClusterInfo {
+		return "", "", fmt.Errorf("the seed image is missing the %s and %s labels, regenerate it to audit its precaching",
+			common.SeedPrecacheListOCILabel, common.SeedClusterInfoOCILabel)
+	}
+	return strings.ReplaceAll(imageList, ",", "\n") + "\n", clusterInfo, nil
+}
+
+// pollPrecacheAudit reports the progress of the precaching job of the audit, and its report once complete
+func (r *ImageBasedUpgradeReconciler) pollPrecacheAudit(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	status, err := r.Precache.QueryJobStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to query the precaching audit job status: %w", err)
+	}
+
+	audit := ibu.Status.PrecacheAudit
+	switch {
+	case status == nil:
+		finishPrecacheAudit(audit, lcav1alpha1.PrecacheAuditFailed, "the precaching job of the audit was deleted")
+	case status.Status == precache.Active:
+		audit.Summary = status.Message
+		return nil
+	case status.Status == precache.Succeeded:
+		junit := ibu.GetAnnotations()[utils.PrecacheJUnitReportAnnotation] == "true"
+		if _, err := r.Precache.ExportReport(ctx, status, junit); err != nil {
+			r.Log.Error(err, "Failed to export the precaching audit report")
+		}
+		if len(status.Progress.FailedPullList) != 0 {
+			audit.Unavailable = append([]string{}, status.Progress.FailedPullList...)
+			sort.Strings(audit.Unavailable)
+		}
+		finishPrecacheAudit(audit, lcav1alpha1.PrecacheAuditCompleted, status.Message)
+	default:
+		finishPrecacheAudit(audit, lcav1alpha1.PrecacheAuditFailed,
+			fmt.Sprintf("the precaching job of the audit failed: %s", status.Message))
+	}
+	r.Log.Info("Precaching audit done", "state", audit.State, "summary", audit.Summary)
+	r.cleanupPrecacheAudit(ctx)
+	return nil
+}
+
+// cancelPrecacheAudit stops the precaching job of the audit, which would otherwise be taken for the one of Prep
+func (r *ImageBasedUpgradeReconciler) cancelPrecacheAudit(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	r.Log.Info("Canceling the precaching audit", "stage", ibu.Spec.Stage)
+	if err := r.Precache.Cleanup(ctx); err != nil {
+		return fmt.Errorf("failed to cancel the precaching audit: %w", err)
+	}
+	if err := os.RemoveAll(common.PathOutsideChroot(precacheAuditDir)); err != nil {
+		r.Log.Error(err, "Failed to delete the precaching audit dir")
+	}
+	finishPrecacheAudit(ibu.Status.PrecacheAudit, lcav1alpha1.PrecacheAuditCanceled,
+		fmt.Sprintf("canceled by the transition to the %s stage", ibu.Spec.Stage))
+	return nil
+}
+
+// cleanupPrecacheAudit deletes the precaching resources and the inputs of the audit. Failures are logged only, the
+// resources being deleted again by the cleanup of the stages
+func (r *ImageBasedUpgradeReconciler) cleanupPrecacheAudit(ctx context.Context) {
+	if err := r.Precache.Cleanup(ctx); err != nil {
+		r.Log.Error(err, "Failed to delete the precaching resources of the audit")
+	}
+	if err := os.RemoveAll(common.PathOutsideChroot(precacheAuditDir)); err != nil {
+		r.Log.Error(err, "Failed to delete the precaching audit dir")
+	}
+}
+
+// finishPrecacheAudit records the final state of the audit
+func finishPrecacheAudit(audit *lcav1alpha1.PrecacheAudit, state lcav1alpha1.PrecacheAuditState, summary string) {
+	now := metav1.Now()
+	audit.State = state
+	audit.Summary = summary
+	audit.CompletedAt = &now
+}
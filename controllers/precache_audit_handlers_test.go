@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/precache"
+)
+
+func TestPrecacheAuditInputs(t *testing.T) {
+	_, _, err := precacheAuditInputs(map[string]string{common.SeedPrecacheListOCILabel: "quay.io/a/b:1"})
+	assert.ErrorContains(t, err, "regenerate it to audit its precaching")
+
+	imageList, clusterInfo, err := precacheAuditInputs(map[string]string{
+		common.SeedPrecacheListOCILabel: "quay.io/a/b:1,quay.io/a/c@sha256:0123",
+		common.SeedClusterInfoOCILabel:  `{"seed_cluster_ocp_version":"4.14.9"}`,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "quay.io/a/b:1\nquay.io/a/c@sha256:0123\n", imageList)
+	assert.Equal(t, `{"seed_cluster_ocp_version":"4.14.9"}`, clusterInfo)
+}
+
+func TestImageBasedUpgradeReconciler_handlePrecacheAudit(t *testing.T) {
+	origAuditDir := precacheAuditDir
+	defer func() { precacheAuditDir = origAuditDir }()
+	precacheAuditDir = t.TempDir()
+
+	newIBU := func(stage lcav1alpha1.ImageBasedUpgradeStage, audit *lcav1alpha1.PrecacheAudit) *lcav1alpha1.ImageBasedUpgrade {
+		ibu := &lcav1alpha1.ImageBasedUpgrade{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        utils.IBUName,
+				Annotations: map[string]string{utils.PrecacheVerifyOnlyAnnotation: "true"},
+			},
+			Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+				Stage: stage,
+				SeedImageRef: lcav1alpha1.SeedImageRef{
+					Version: "4.14.9",
+					Image:   "oci-archive:///var/tmp/seed.tar",
+				},
+			},
+			Status: lcav1alpha1.ImageBasedUpgradeStatus{PrecacheAudit: audit},
+		}
+		if audit != nil {
+			ibu.Annotations = nil
+		}
+		utils.SetStatusCondition(&ibu.Status.Conditions, utils.ConditionTypes.Idle, utils.ConditionReasons.Idle,
+			metav1.ConditionTrue, "Idle", ibu.Generation)
+		return ibu
+	}
+	running := func() *lcav1alpha1.PrecacheAudit {
+		return &lcav1alpha1.PrecacheAudit{SeedImage: "quay.io/a/seed:1", State: lcav1alpha1.PrecacheAuditRunning}
+	}
+	newReconciler := func(objs ...client.Object) *ImageBasedUpgradeReconciler {
+		c, _ := getFakeClientFromObjects(objs...)
+		return &ImageBasedUpgradeReconciler{Client: c, Log: logr.Discard(),
+			Precache: &precache.PHandler{Client: c, Log: logr.Discard()}}
+	}
+
+	// The seed images loaded from an archive are not audited, without failing the Idle stage
+	ibu := newIBU(lcav1alpha1.Stages.Idle, nil)
+	r := newReconciler(ibu)
+	assert.NoError(t, r.handlePrecacheAudit(context.Background(), ibu))
+	assert.NotContains(t, ibu.Annotations, utils.PrecacheVerifyOnlyAnnotation)
+	assert.Equal(t, lcav1alpha1.PrecacheAuditFailed, ibu.Status.PrecacheAudit.State)
+	assert.Contains(t, ibu.Status.PrecacheAudit.Summary, "is only loaded by Prep")
+	assert.NotNil(t, ibu.Status.PrecacheAudit.CompletedAt)
+	assert.True(t, utils.IsStageCompleted(ibu, lcav1alpha1.Stages.Idle))
+	assert.False(t, isPrecacheAuditRunning(ibu))
+
+	// The audit only starts in the Idle stage
+	ibu = newIBU(lcav1alpha1.Stages.Prep, nil)
+	r = newReconciler(ibu)
+	assert.NoError(t, r.handlePrecacheAudit(context.Background(), ibu))
+	assert.NotContains(t, ibu.Annotations, utils.PrecacheVerifyOnlyAnnotation)
+	assert.Nil(t, ibu.Status.PrecacheAudit)
+
+	// A running audit is canceled by a transition, leaving the precaching job to Prep
+	ibu = newIBU(lcav1alpha1.Stages.Prep, running())
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: precache.LcaPrecacheJobName, Namespace: common.LcaNamespace},
+		Status: batchv1.JobStatus{Active: 1}}
+	r = newReconciler(ibu, job)
+	assert.NoError(t, r.handlePrecacheAudit(context.Background(), ibu))
+	assert.Equal(t, lcav1alpha1.PrecacheAuditCanceled, ibu.Status.PrecacheAudit.State)
+	assert.Equal(t, "canceled by the transition to the Prep stage", ibu.Status.PrecacheAudit.Summary)
+	status, err := r.Precache.QueryJobStatus(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+
+	// A running audit is followed until its job completes
+	ibu = newIBU(lcav1alpha1.Stages.Idle, running())
+	r = newReconciler(ibu, job.DeepCopy())
+	assert.NoError(t, r.handlePrecacheAudit(context.Background(), ibu))
+	assert.True(t, isPrecacheAuditRunning(ibu))
+
+	succeeded := job.DeepCopy()
+	succeeded.Status = batchv1.JobStatus{Succeeded: 1}
+	r = newReconciler(ibu, succeeded)
+	assert.NoError(t, r.handlePrecacheAudit(context.Background(), ibu))
+	assert.Equal(t, lcav1alpha1.PrecacheAuditCompleted, ibu.Status.PrecacheAudit.State)
+	status, err = r.Precache.QueryJobStatus(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+
+	// A deleted job fails the audit
+	ibu = newIBU(lcav1alpha1.Stages.Idle, running())
+	r = newReconciler(ibu)
+	assert.NoError(t, r.handlePrecacheAudit(context.Background(), ibu))
+	assert.Equal(t, lcav1alpha1.PrecacheAuditFailed, ibu.Status.PrecacheAudit.State)
+	assert.Equal(t, "the precaching job of the audit was deleted", ibu.Status.PrecacheAudit.Summary)
+}
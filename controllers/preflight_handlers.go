@@ -0,0 +1,209 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/bootcheck"
+	"github.com/openshift-kni/lifecycle-agent/internal/diskcheck"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/internal/preflight"
+	"github.com/openshift-kni/lifecycle-agent/internal/seedimage"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+// preflightClock is the time source of the preflight summary, overridden by tests
+var preflightClock = time.Now
+
+// handleRunPreflight looks for RunPreflightAnnotation in the ibu CR. If present, it removes the annotation and, in
+// the Idle stage, runs the preflight checks and publishes their summary in the status. The checks only read the
+// state of the host and the cluster, those of the other stages being taken by the stage in progress.
+func (r *ImageBasedUpgradeReconciler) handleRunPreflight(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	if _, ok := ibu.Annotations[utils.RunPreflightAnnotation]; !ok {
+		return nil
+	}
+
+	delete(ibu.Annotations, utils.RunPreflightAnnotation)
+	if err := r.Client.Update(ctx, ibu); err != nil {
+		return fmt.Errorf("failed to remove run preflight annotation from ibu: %w", err)
+	}
+
+	if !utils.IsStageCompleted(ibu, lcav1alpha1.Stages.Idle) {
+		r.Log.Info("Skipping the preflight checks, they only run in the Idle stage")
+		return nil
+	}
+
+	r.Log.Info("Running the preflight checks")
+	ibu.Status.Preflight = preflight.Run(ctx, r.Log, r.preflightChecks(ibu), preflightClock)
+	r.Log.Info("Preflight checks done", "result", ibu.Status.Preflight.Result, "score", ibu.Status.Preflight.Score)
+	return nil
+}
+
+// preflightChecks returns the checks of the Prep and Upgrade stages that can run before Prep: the seed image version,
+// its registry and architecture, the space for the seed image, the stateroot and the precaching, the boot partition,
+// the health of the cluster and its certificates
+func (r *ImageBasedUpgradeReconciler) preflightChecks(ibu *lcav1alpha1.ImageBasedUpgrade) []preflight.Check {
+	// the size of the seed image, only known once inspected in its registry or the container storage
+	var seedImage *seedImageSize
+
+	return []preflight.Check{
+		{Name: "SeedVersion", Run: func(ctx context.Context) (string, error) {
+			return r.preflightSeedVersion(ibu)
+		}},
+		{Name: "SeedImage", Run: func(ctx context.Context) (message string, err error) {
+			seedImage, message, err = r.preflightSeedImage(ctx, ibu)
+			return message, err
+		}},
+		{Name: "DiskSpace", Run: func(ctx context.Context) (string, error) {
+			if seedImage == nil {
+				return "", preflight.Warnf("the seed image size is unknown, the disk space is only checked by Prep")
+			}
+			if err := diskcheck.Check(r.Log, r.prepDiskRequirements(seedImage.pullSize, seedImage.size,
+				seedImage.labels)); err != nil {
+				return "", err //nolint:wrapcheck
+			}
+			return "enough space for the seed image, the stateroot and the precaching", nil
+		}},
+		{Name: "BootPartition", Run: func(ctx context.Context) (string, error) {
+			if err := bootcheck.Check(r.Log, r.Executor); err != nil {
+				return "", err //nolint:wrapcheck
+			}
+			if err := bootcheck.CheckBootDevices(r.Log, r.Executor); err != nil {
+				return "", err //nolint:wrapcheck
+			}
+			return "enough space for the boot entry of the new stateroot on all the boot devices", nil
+		}},
+		{Name: "ClusterHealth", Run: func(ctx context.Context) (string, error) {
+			if err := CheckHealth(r.Client, r.Log); err != nil {
+				return "", err
+			}
+			return "cluster is healthy", nil
+		}},
+		{Name: "Certificates", Run: func(ctx context.Context) (string, error) {
+			if err := CheckCertificates(ctx, r.Client, r.Log); err != nil {
+				return "", err
+			}
+			return "no pending CSR, kubelet certificates valid", nil
+		}},
+	}
+}
+
+// seedImageSize is the size of the seed image for the disk space check
+type seedImageSize struct {
+	// pullSize is 0 for a seed image already in the container storage
+	pullSize int64
+	size     int64
+	labels   map[string]string
+}
+
+// preflightSeedVersion checks the seed OCP version is higher than the one of the cluster, warning when the check is
+// skipped with the SkipSeedVersionCheckAnnotation
+func (r *ImageBasedUpgradeReconciler) preflightSeedVersion(ibu *lcav1alpha1.ImageBasedUpgrade) (string, error) {
+	skipCheck, err := utils.SkipSeedVersionCheck(ibu)
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+	err = r.validateSeedOcpVersion(ibu.Spec.SeedImageRef.Version, false)
+	if skipCheck && errors.Is(err, lcaerrors.ErrSeedIncompatible) {
+		return "", preflight.Warnf("%v, accepted with the %s annotation", err, utils.SkipSeedVersionCheckAnnotation)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("seed OCP version %s is higher than the cluster one", ibu.Spec.SeedImageRef.Version), nil
+}
+
+// preflightSeedImage checks the seed image is reachable in its registry, or already in the container storage, for the
+// architecture of the node, and returns its size. The seed images loaded from an archive or fetched from an artifact
+// source are only checked by Prep
+func (r *ImageBasedUpgradeReconciler) preflightSeedImage(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (
+	*seedImageSize, string, error) {
+	if archive, err := seedimage.ParseArchive(ibu.Spec.SeedImageRef.Image); err != nil {
+		return nil, "", err //nolint:wrapcheck
+	} else if archive != nil {
+		return nil, "", preflight.Warnf("the seed image archive %s is only loaded by Prep", archive.Path)
+	}
+	if sourceConfig, err := r.getArtifactSource(ctx, ibu); err != nil {
+		return nil, "", err
+	} else if sourceConfig != nil {
+		return nil, "", preflight.Warnf("the seed image is only fetched from the artifact source by Prep")
+	}
+
+	if local, err := r.reusableSeedImage(ctx, ibu.Spec.SeedImageRef, ""); err != nil {
+		return nil, "", err
+	} else if local != nil {
+		return &seedImageSize{size: local.Size, labels: local.Labels}, "seed image already in the container storage", nil
+	}
+
+	pullSecretFilename, removePullSecret, err := r.writeSeedImagePullSecret(ctx, ibu)
+	if err != nil {
+		return nil, "", err
+	}
+	defer removePullSecret()
+	registryTLS, err := r.renderRegistryTLS(ctx, ibu)
+	if err != nil {
+		return nil, "", err
+	}
+	clusterProxyEnv, err := lcautils.GetProxyEnv(ctx, r.Client)
+	if err != nil {
+		return nil, "", err //nolint:wrapcheck
+	}
+	executor := ops.WithEnv(r.Executor, seedImagePullProxyEnv(clusterProxyEnv)...)
+	nodeArchitecture, err := r.nodeArchitecture(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	candidates, err := r.seedImagePullCandidates(ctx, ibu.Spec.SeedImageRef.Image)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var remote *seedimage.RemoteImage
+	var errs []error
+	for _, candidate := range candidates {
+		if remote, err = seedimage.InspectRemote(ctx, executor, candidate, pullSecretFilename, nodeArchitecture,
+			registryTLS.Args(candidate)); err == nil {
+			break
+		}
+		errs = append(errs, err)
+	}
+	if remote == nil {
+		if err := r.checkSeedImagePlatform(ctx, executor, candidates, pullSecretFilename, nodeArchitecture,
+			registryTLS); err != nil {
+			return nil, "", err
+		}
+		return nil, "", lcaerrors.Wrap(lcaerrors.ErrSeedPull, errors.Join(errs...))
+	}
+	if err := checkSeedImageArchitecture(ibu.Spec.SeedImageRef.Image, remote.Architecture, nodeArchitecture); err != nil {
+		return nil, "", err
+	}
+
+	size := &seedImageSize{pullSize: remote.Size(), size: remote.Size(), labels: remote.Labels}
+	if local, err := r.reusableSeedImage(ctx, ibu.Spec.SeedImageRef, remote.Digest); err != nil {
+		return nil, "", err
+	} else if local != nil {
+		size.pullSize = 0
+	}
+	return size, fmt.Sprintf("seed image reachable for the %s architecture", nodeArchitecture), nil
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+func TestImageBasedUpgradeReconciler_handleRunPreflight(t *testing.T) {
+	origCheckHealth, origCheckCertificates, origClock := CheckHealth, CheckCertificates, preflightClock
+	defer func() {
+		CheckHealth, CheckCertificates, preflightClock = origCheckHealth, origCheckCertificates, origClock
+	}()
+
+	completedAt := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	preflightClock = func() time.Time { return completedAt }
+	CheckHealth = func(c client.Reader, l logr.Logger) error { return nil }
+	CheckCertificates = func(ctx context.Context, c client.Reader, l logr.Logger) error {
+		return errors.New("1 pending CSR")
+	}
+	testscheme.AddKnownTypes(configv1.GroupVersion, &configv1.ClusterVersion{})
+
+	newIBU := func(stage lcav1alpha1.ImageBasedUpgradeStage) *lcav1alpha1.ImageBasedUpgrade {
+		ibu := &lcav1alpha1.ImageBasedUpgrade{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        utils.IBUName,
+				Annotations: map[string]string{utils.RunPreflightAnnotation: "1"},
+			},
+			Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+				Stage: stage,
+				SeedImageRef: lcav1alpha1.SeedImageRef{
+					Version: "4.14.9",
+					Image:   "oci-archive:///var/tmp/seed.tar",
+				},
+			},
+		}
+		if stage == lcav1alpha1.Stages.Idle {
+			utils.SetStatusCondition(&ibu.Status.Conditions, utils.ConditionTypes.Idle, utils.ConditionReasons.Idle,
+				metav1.ConditionTrue, "Idle", ibu.Generation)
+		} else {
+			utils.SetStatusCondition(&ibu.Status.Conditions, utils.ConditionTypes.Idle, utils.ConditionReasons.InProgress,
+				metav1.ConditionFalse, "In progress", ibu.Generation)
+		}
+		return ibu
+	}
+	version := &configv1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "version"},
+		Status:     configv1.ClusterVersionStatus{Desired: configv1.Release{Version: "4.14.8"}},
+	}
+
+	// The boot partition check fails on the first command
+	executorMock := ops.NewMockExecute(gomock.NewController(t))
+	executorMock.EXPECT().Execute(gomock.Any(), gomock.Any()).Return("", errors.New("exit status 1")).AnyTimes()
+
+	ibu := newIBU(lcav1alpha1.Stages.Idle)
+	c, _ := getFakeClientFromObjects(ibu, version)
+	r := &ImageBasedUpgradeReconciler{Client: c, Log: logr.Discard(), Executor: executorMock}
+	assert.NoError(t, r.handleRunPreflight(context.Background(), ibu))
+	assert.NotContains(t, ibu.Annotations, utils.RunPreflightAnnotation)
+
+	preflight := ibu.Status.Preflight
+	assert.NotNil(t, preflight)
+	assert.Equal(t, lcav1alpha1.PreflightFail, preflight.Result)
+	// 2 passed, 2 warnings and 2 failures
+	assert.Equal(t, 50, preflight.Score)
+	assert.Equal(t, metav1.NewTime(completedAt), preflight.CompletedAt)
+	results := map[string]lcav1alpha1.PreflightResult{}
+	for _, check := range preflight.Checks {
+		results[check.Name] = check.Result
+	}
+	assert.Equal(t, map[string]lcav1alpha1.PreflightResult{
+		"SeedVersion":   lcav1alpha1.PreflightPass,
+		"SeedImage":     lcav1alpha1.PreflightWarn,
+		"DiskSpace":     lcav1alpha1.PreflightWarn,
+		"BootPartition": lcav1alpha1.PreflightFail,
+		"ClusterHealth": lcav1alpha1.PreflightPass,
+		"Certificates":  lcav1alpha1.PreflightFail,
+	}, results)
+	assert.Equal(t, lcav1alpha1.PreflightCheck{Name: "Certificates", Result: lcav1alpha1.PreflightFail,
+		Message: "1 pending CSR"}, preflight.Checks[5])
+
+	// The checks only run in the Idle stage
+	ibu = newIBU(lcav1alpha1.Stages.Prep)
+	c, _ = getFakeClientFromObjects(ibu, version)
+	r = &ImageBasedUpgradeReconciler{Client: c, Log: logr.Discard()}
+	assert.NoError(t, r.handleRunPreflight(context.Background(), ibu))
+	assert.NotContains(t, ibu.Annotations, utils.RunPreflightAnnotation)
+	assert.Nil(t, ibu.Status.Preflight)
+}
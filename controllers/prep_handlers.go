@@ -18,102 +18,612 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coreos/go-semver/semver"
 	configv1 "github.com/openshift/api/config/v1"
 	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
 	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/postpivot"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedclusterinfo"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedsbom"
 	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
 
+	"github.com/openshift-kni/lifecycle-agent/internal/artifactsource"
+	"github.com/openshift-kni/lifecycle-agent/internal/authfile"
+	"github.com/openshift-kni/lifecycle-agent/internal/bootcheck"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/common/collections"
+	"github.com/openshift-kni/lifecycle-agent/internal/diskcheck"
+	"github.com/openshift-kni/lifecycle-agent/internal/hostcompat"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/internal/nodeidentity"
+	"github.com/openshift-kni/lifecycle-agent/internal/podpolicy"
 	"github.com/openshift-kni/lifecycle-agent/internal/precache"
 	"github.com/openshift-kni/lifecycle-agent/internal/prep"
+	"github.com/openshift-kni/lifecycle-agent/internal/prepinputs"
+	"github.com/openshift-kni/lifecycle-agent/internal/registryconfig"
+	"github.com/openshift-kni/lifecycle-agent/internal/registrytls"
+	"github.com/openshift-kni/lifecycle-agent/internal/seedimage"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (r *ImageBasedUpgradeReconciler) getSeedImage(
-	ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) error {
-	// Use cluster wide pull-secret by default
-	pullSecretFilename := common.ImageRegistryAuthFile
-
-	if ibu.Spec.SeedImageRef.PullSecretRef != nil {
-		var pullSecret string
-		pullSecret, err := lcautils.GetSecretData(ctx, ibu.Spec.SeedImageRef.PullSecretRef.Name,
-			common.LcaNamespace, corev1.DockerConfigJsonKey, r.Client)
+	ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade, registryTLS *registrytls.Config) error {
+	pullSecretFilename, removePullSecret, err := r.writeSeedImagePullSecret(ctx, ibu)
+	if err != nil {
+		return err
+	}
+	defer removePullSecret()
+
+	sourceConfig, err := r.getArtifactSource(ctx, ibu)
+	if err != nil {
+		return lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, err)
+	}
+	// The seed image registry may only be reachable through the proxy, not configured for the podman and skopeo of
+	// the host
+	clusterProxyEnv, err := lcautils.GetProxyEnv(ctx, r.Client)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	executor := ops.WithEnv(r.Executor, seedImagePullProxyEnv(clusterProxyEnv)...)
+	nodeArchitecture, err := r.nodeArchitecture(ctx)
+	if err != nil {
+		return err
+	}
+
+	archive, err := seedimage.ParseArchive(ibu.Spec.SeedImageRef.Image)
+	if err != nil {
+		return lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, err)
+	}
+
+	// pulledFrom is the reference the seed image was pulled from, the one of a mirror or the seed image reference
+	pulledFrom := ibu.Spec.SeedImageRef.Image
+	if archive != nil {
+		if err := r.loadSeedImage(ctx, ibu.Spec.SeedImageRef.Image, archive); err != nil {
+			return err
+		}
+		// An archive has no registry to fetch the SBOM from
+		pulledFrom = ""
+	} else if sourceConfig != nil {
+		if err := r.fetchSeedImage(ctx, sourceConfig, ibu.Spec.SeedImageRef.Image, pullSecretFilename); err != nil {
+			return lcaerrors.WrapHostError(lcaerrors.ErrSeedPull, err)
+		}
+		// The size of the seed image is only known once fetched, check the space before the stateroot setup
+		local, err := seedimage.Inspect(ctx, r.Executor, ibu.Spec.SeedImageRef.Image)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		if err := r.checkDiskSpace(0, local.Size, local.Labels); err != nil {
+			return err
+		}
+	} else if local, err := r.reusableSeedImage(ctx, ibu.Spec.SeedImageRef, ""); err != nil {
+		return err
+	} else if local != nil {
+		// A seed image referenced by digest pulled by a previous Prep, e.g. before an abort, needs no registry
+		if err := r.checkDiskSpace(0, local.Size, local.Labels); err != nil {
+			return err
+		}
+	} else {
+		candidates, err := r.seedImagePullCandidates(ctx, ibu.Spec.SeedImageRef.Image)
 		if err != nil {
-			err = fmt.Errorf("failed to retrieve pull-secret from secret %s, err: %w", ibu.Spec.SeedImageRef.PullSecretRef.Name, err)
 			return err
 		}
+		retryPolicy := seedImageRetryPolicy(ibu.Spec.SeedImageRef.PullRetryPolicy)
+
+		// Check the space before the pull, not to fail halfway through the stateroot setup or the precaching. The
+		// seed image pull reports a registry unreachable with the right reason, the check is skipped then
+		var remote *seedimage.RemoteImage
+		_ = r.retrySeedImageOperation(ctx, retryPolicy, "inspection", func() error {
+			for _, candidate := range candidates {
+				if remote, err = seedimage.InspectRemote(ctx, executor, candidate, pullSecretFilename, nodeArchitecture,
+					registryTLS.Args(candidate)); err == nil {
+					return nil
+				}
+				r.Log.Error(err, "Failed to inspect the seed image in its registry", "image", candidate)
+			}
+			return err
+		})
+		if remote == nil {
+			// The inspection of a manifest list without an image for the node fails too, report it instead of
+			// failing to pull
+			if err := r.checkSeedImagePlatform(ctx, executor, candidates, pullSecretFilename, nodeArchitecture,
+				registryTLS); err != nil {
+				return err
+			}
+			r.Log.Info("Skipping the disk space check, the seed image size is unknown")
+		} else {
+			// Reject a seed image of another architecture before pulling it
+			if err := checkSeedImageArchitecture(ibu.Spec.SeedImageRef.Image, remote.Architecture, nodeArchitecture); err != nil {
+				return err
+			}
+			// A seed image referenced by tag is reused if the tag still refers to the image pulled before
+			if local, err = r.reusableSeedImage(ctx, ibu.Spec.SeedImageRef, remote.Digest); err != nil {
+				return err
+			}
+			pullSize := remote.Size()
+			if local != nil {
+				pullSize = 0
+			}
+			if err := r.checkDiskSpace(pullSize, remote.Size(), remote.Labels); err != nil {
+				return err
+			}
+		}
 
-		pullSecretFilename = filepath.Join(utils.IBUWorkspacePath, "seed-pull-secret")
-		if err = os.WriteFile(common.PathOutsideChroot(pullSecretFilename), []byte(pullSecret), 0o600); err != nil {
-			err = fmt.Errorf("failed to write seed image pull-secret to file %s, err: %w", pullSecretFilename, err)
+		if local != nil {
+			r.Log.Info("Skipping the seed image pull")
+		} else if err := r.retrySeedImageOperation(ctx, retryPolicy, "pull", func() error {
+			pulledFrom, err = r.pullSeedImage(ctx, executor, ibu.Spec.SeedImageRef.Image, candidates,
+				pullSecretFilename, nodeArchitecture, registryTLS)
 			return err
+		}); err != nil {
+			return lcaerrors.WrapHostError(lcaerrors.ErrSeedPull, err)
 		}
-		defer os.Remove(common.PathOutsideChroot(pullSecretFilename))
 	}
 
-	r.Log.Info("Pulling seed image")
-	if _, err := r.Executor.Execute("podman", "pull", "--authfile", pullSecretFilename, ibu.Spec.SeedImageRef.Image); err != nil {
-		return fmt.Errorf("failed to pull image: %w", err)
+	image, err := seedimage.Inspect(ctx, r.Executor, seedimage.StorageName(ibu.Spec.SeedImageRef.Image))
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	r.Log.Info("Checking seed image architecture", "seed", image.Architecture, "node", nodeArchitecture)
+	if err := checkSeedImageArchitecture(ibu.Spec.SeedImageRef.Image, image.Architecture, nodeArchitecture); err != nil {
+		return err
+	}
+
+	r.Log.Info("Checking seed image digest")
+	digest, err := checkSeedImageDigest(ibu.Spec.SeedImageRef, image)
+	if err != nil {
+		return err
+	}
+	r.PrepTask.SeedImageDigest = digest
+	if listDigest := image.ManifestListDigest(); listDigest != "" {
+		r.Log.Info("Seed image resolved from its manifest list for the node architecture", "architecture",
+			image.Architecture, "digest", digest, "manifestListDigest", listDigest)
+		r.PrepTask.SeedImageManifestListDigest = listDigest
+	}
+
+	// The SBOM of a previous seed image is stale
+	if err := r.deleteSeedSBOM(ctx); err != nil {
+		r.Log.Error(err, "Failed to delete the SBOM of the previous seed image")
+	}
+	if format := image.Labels[seedsbom.FormatOCILabel]; format != "" && pulledFrom != "" {
+		r.Log.Info("Fetching seed image SBOM", "format", format)
+		r.fetchSeedSBOM(ctx, executor, ibu, pulledFrom, image.Digest, pullSecretFilename, registryTLS)
 	}
 
 	r.Log.Info("Checking seed image compatibility")
-	if err := r.checkSeedImageCompatibility(ctx, ibu.Spec.SeedImageRef.Image); err != nil {
+	if err := checkSeedImageCompatibility(image, ibu.Spec.SeedImageRef.Image, len(clusterProxyEnv) != 0); err != nil {
 		return fmt.Errorf("checking seed image compatibility: %w", err)
 	}
+	r.PrepTask.SeedImageInfo = seedImageInfo(image)
 
 	return nil
 }
 
-// checkSeedImageCompatibility checks if the seed image is compatible with the
-// current version of the lifecycle-agent by inspecting the OCI image's labels
-// and checking if the specified format version equals the hard-coded one that
-// this version of the lifecycle agent expects. That format version is set by
-// the lca-cli during the image build process, and is only manually bumped by
-// developers when the image format changes in a way that is incompatible with
-// previous versions of the lifecycle-agent.
-func (r *ImageBasedUpgradeReconciler) checkSeedImageCompatibility(_ context.Context, seedImageRef string) error {
-	inspectArgs := []string{
-		"inspect",
-		"--format", "json",
-		seedImageRef,
+// writeSeedImagePullSecret writes the pull-secret of the seed image referenced by the IBU spec in a private tmpfs, and
+// returns the auth file to access the seed image with, the cluster wide pull-secret by default, and the function
+// removing the written file
+func (r *ImageBasedUpgradeReconciler) writeSeedImagePullSecret(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (
+	string, func(), error) {
+	if ibu.Spec.SeedImageRef.PullSecretRef == nil {
+		return common.ImageRegistryAuthFile, func() {}, nil
 	}
 
-	var inspect []struct {
-		Labels map[string]string `json:"Labels"`
+	pullSecret, err := lcautils.GetSecretData(ctx, ibu.Spec.SeedImageRef.PullSecretRef.Name,
+		common.LcaNamespace, corev1.DockerConfigJsonKey, r.Client)
+	if err != nil {
+		err = fmt.Errorf("failed to retrieve pull-secret from secret %s, err: %w", ibu.Spec.SeedImageRef.PullSecretRef.Name, err)
+		return "", nil, lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, err)
 	}
 
-	// TODO: use the context when execute supports it
-	if inspectRaw, err := r.Executor.Execute("podman", inspectArgs...); err != nil || inspectRaw == "" {
-		return fmt.Errorf("failed to inspect image: %w", err)
-	} else {
-		if err := json.Unmarshal([]byte(inspectRaw), &inspect); err != nil {
-			return fmt.Errorf("failed to unmarshal image inspect output: %w", err)
+	pullSecretFile, err := authfile.Create(r.Executor, "seed-pull-secret", []byte(pullSecret))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to write seed image pull-secret: %w", err)
+	}
+	return pullSecretFile.Path, func() {
+		if err := pullSecretFile.Remove(); err != nil {
+			r.Log.Error(err, "Failed to remove the seed image pull-secret")
+		}
+	}, nil
+}
+
+// seedImageInfo returns the description of the seed image from its labels and config. Its OCP version is only known
+// once the seed cluster info is extracted from the seed image.
+func seedImageInfo(image *seedimage.Image) *lcav1alpha1.SeedImageInfo {
+	info := &lcav1alpha1.SeedImageInfo{BaseOSVersion: image.Labels[common.SeedBaseOSVersionOCILabel]}
+	if formatVersion, err := strconv.Atoi(image.Labels[common.SeedFormatOCILabel]); err == nil {
+		info.FormatVersion = formatVersion
+	}
+	if fips, err := strconv.ParseBool(image.Labels[common.SeedFIPSOCILabel]); err == nil {
+		info.FIPS = &fips
+	}
+	if !image.Created.IsZero() {
+		info.CreatedAt = &metav1.Time{Time: image.Created}
+	}
+	return info
+}
+
+// checkSeedClusterInfo adds the OCP version of the seed cluster info extracted from the seed image to the seed image
+// description reported in the status, and checks the host configuration the seed stateroot depends on matches the one
+// of this host, which would otherwise only fail to boot after the pivot, and that the node keeps its name
+func (r *ImageBasedUpgradeReconciler) checkSeedClusterInfo(ctx context.Context, seedManifestFile string) error {
+	seedInfo, err := seedclusterinfo.ReadSeedClusterInfoFromFile(common.PathOutsideChroot(seedManifestFile))
+	if err != nil {
+		return fmt.Errorf("failed to read seed info: %w", err)
+	}
+	info := r.PrepTask.SeedImageInfo.DeepCopy()
+	if info == nil {
+		info = &lcav1alpha1.SeedImageInfo{}
+	}
+	info.OCPVersion = seedInfo.SeedClusterOCPVersion
+	r.PrepTask.SeedImageInfo = info
+
+	hostInfo, err := hostcompat.Gather(r.Executor)
+	if err != nil {
+		return fmt.Errorf("failed to get host info: %w", err)
+	}
+	if err := hostcompat.Check(seedInfo, hostInfo); err != nil {
+		return fmt.Errorf("seed image is incompatible with this host: %w", err)
+	}
+
+	node, err := lcautils.GetSNOMasterNode(ctx, r.Client)
+	if err != nil {
+		return fmt.Errorf("failed to get node: %w", err)
+	}
+	if err := nodeidentity.Check(node, seedInfo); err != nil {
+		return fmt.Errorf("node would not keep its name after the upgrade: %w", err)
+	}
+	return nil
+}
+
+// loadSeedImage loads the seed image from its archive pre-placed on the host into the container storage, checking the
+// space for the stateroot once its size is known
+func (r *ImageBasedUpgradeReconciler) loadSeedImage(ctx context.Context, image string, archive *seedimage.Archive) error {
+	if _, err := os.Stat(common.PathOutsideChroot(archive.Path)); err != nil {
+		return lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, fmt.Errorf("failed to find seed image %s on the host: %w", archive.Path, err))
+	}
+	r.Log.Info("Loading seed image from the host", "transport", archive.Transport, "path", archive.Path)
+	if err := seedimage.Load(ctx, r.Executor, image); err != nil {
+		return lcaerrors.WrapHostError(lcaerrors.ErrSeedPull, err)
+	}
+	local, err := seedimage.Inspect(ctx, r.Executor, seedimage.StorageName(image))
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	return r.checkDiskSpace(0, local.Size, local.Labels)
+}
+
+// seedImagePullCandidates returns the references to pull the seed image from, in order: its references in the mirrors
+// of its repository configured by the ImageTagMirrorSets, then the ImageDigestMirrorSets and
+// ImageContentSourcePolicies of the cluster, and the seed image reference itself. The registries configuration of the
+// host only applies the mirrors of the last two to the images referenced by digest, while mirroring tools push the
+// tags as well, so a seed image referenced by tag is tried on them first. A seed image referenced by digest is pulled
+// as is, the registries configuration of the host resolving its mirrors.
+func (r *ImageBasedUpgradeReconciler) seedImagePullCandidates(ctx context.Context, image string) ([]string, error) {
+	if lcautils.IsDigestReference(image) {
+		return []string{image}, nil
+	}
+	tagMirrors, err := lcautils.GetTagMirrors(ctx, r.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster image tag mirrors: %w", err)
+	}
+	digestMirrors, err := lcautils.GetImageMirrors(ctx, r.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster image mirrors: %w", err)
+	}
+	return collections.Union(lcautils.MirroredReferences(image, tagMirrors),
+		lcautils.MirroredReferences(image, digestMirrors), []string{image}), nil
+}
+
+// seedImageRetryPolicy returns the retry policy of the seed image registry operations from the IBU spec, with the
+// defaults of the unset values
+func seedImageRetryPolicy(spec *lcav1alpha1.PullRetryPolicy) seedimage.RetryPolicy {
+	if spec == nil {
+		return seedimage.NewRetryPolicy(0, 0, 0)
+	}
+	return seedimage.NewRetryPolicy(spec.Attempts, time.Duration(spec.BackoffSeconds)*time.Second,
+		time.Duration(spec.MaxDurationSeconds)*time.Second)
+}
+
+// retrySeedImageOperation runs a registry operation on the seed image with the retry policy, one attempt trying all
+// the pull references, and reports the retries in the Prep progress
+func (r *ImageBasedUpgradeReconciler) retrySeedImageOperation(ctx context.Context, policy seedimage.RetryPolicy,
+	operation string, fn func() error) error {
+	return policy.Retry(ctx, func(attempt int, err error, wait time.Duration) {
+		r.Log.Error(err, "Seed image registry operation failed, retrying", "operation", operation,
+			"attempt", attempt, "attempts", policy.Attempts, "backoff", wait)
+		r.PrepTask.Progress = fmt.Sprintf("Retrying seed image %s in %s (attempt %d of %d): %v",
+			operation, wait, attempt+1, policy.Attempts, err)
+	}, fn)
+}
+
+// nodeArchitecture returns the architecture of the SNO node, e.g. amd64 or arm64
+func (r *ImageBasedUpgradeReconciler) nodeArchitecture(ctx context.Context) (string, error) {
+	node, err := lcautils.GetSNOMasterNode(ctx, r.Client)
+	if err != nil {
+		return "", fmt.Errorf("failed to get node architecture: %w", err)
+	}
+	return node.Status.NodeInfo.Architecture, nil
+}
+
+// checkSeedImageArchitecture rejects a seed image of another architecture than the node, whose stateroot setup would
+// only fail later running its binaries. The check is skipped when either architecture is unknown.
+func checkSeedImageArchitecture(seedImageRef, seedArchitecture, nodeArchitecture string) error {
+	if seedArchitecture == "" || nodeArchitecture == "" || seedArchitecture == nodeArchitecture {
+		return nil
+	}
+	return lcaerrors.Wrap(lcaerrors.ErrSeedIncompatible, fmt.Errorf(
+		"seed image %s architecture %s does not match the node architecture %s, a seed image created from "+
+			"a cluster of the %s architecture is required", seedImageRef, seedArchitecture, nodeArchitecture, nodeArchitecture))
+}
+
+// seedImagePullProxyEnv returns the proxy environment of the seed image pull: the one of the cluster-wide proxy, or else
+// the one of the LCA, as set by the Subscription config of its operator
+func seedImagePullProxyEnv(clusterProxyEnv []string) []string {
+	if len(clusterProxyEnv) != 0 {
+		return clusterProxyEnv
+	}
+	var env []string
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY"} {
+		if value := os.Getenv(name); value != "" {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// checkSeedImagePlatform rejects a seed image manifest list without an image for the node architecture, checked on
+// the first of the candidate references that can be inspected. The other failures are left to the pull to report.
+func (r *ImageBasedUpgradeReconciler) checkSeedImagePlatform(ctx context.Context, executor ops.Execute,
+	candidates []string, authFile, nodeArchitecture string, registryTLS *registrytls.Config) error {
+	for _, candidate := range candidates {
+		err := seedimage.CheckPlatform(ctx, executor, candidate, authFile, nodeArchitecture, registryTLS.Args(candidate))
+		if errors.Is(err, seedimage.ErrNoPlatformImage) {
+			return lcaerrors.Wrap(lcaerrors.ErrSeedIncompatible, err)
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// pullSeedImage pulls the seed image from the first of the candidate references that succeeds, and returns it. The
+// seed image pulled from a mirror is named after the seed image reference, as if pulled from it. The image of a
+// manifest list is the one of the node architecture.
+func (r *ImageBasedUpgradeReconciler) pullSeedImage(ctx context.Context, executor ops.Execute, image string,
+	candidates []string, authFile, nodeArchitecture string, registryTLS *registrytls.Config) (string, error) {
+	var errs []error
+	for _, candidate := range candidates {
+		r.Log.Info("Pulling seed image", "image", candidate)
+		err := seedimage.PullWithProgress(ctx, executor, candidate, authFile, nodeArchitecture, registryTLS.Args(candidate),
+			func(progress seedimage.Progress) {
+				r.PrepTask.Progress = fmt.Sprintf("Pulling seed image: %s", progress)
+			})
+		if err == nil {
+			if candidate != image {
+				if err := seedimage.Rename(ctx, executor, candidate, image); err != nil {
+					return "", err //nolint:wrapcheck
+				}
+			}
+			return candidate, nil
+		}
+		if ctx.Err() != nil {
+			return "", err //nolint:wrapcheck
+		}
+		if len(candidates) > 1 {
+			r.Log.Error(err, "Failed to pull seed image, trying the next reference", "image", candidate)
+			err = fmt.Errorf("%s: %w", candidate, err)
 		}
+		errs = append(errs, err)
 	}
+	return "", errors.Join(errs...)
+}
+
+// reusableSeedImage returns the seed image in the container storage of the host if it has the digest the seed image
+// must have, expected by the seed image reference or else the given digest of the image in its registry, so that a
+// retried Prep does not pull it again. It returns nil if the image must be pulled: with forcePull, without a digest to
+// compare with, or if the container storage does not have the image, or with another digest.
+func (r *ImageBasedUpgradeReconciler) reusableSeedImage(ctx context.Context, seedImageRef lcav1alpha1.SeedImageRef,
+	remoteDigest string) (*seedimage.Image, error) {
+	if seedImageRef.ForcePull {
+		return nil, nil
+	}
+	digest, err := expectedSeedImageDigest(seedImageRef)
+	if err != nil {
+		return nil, lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, err)
+	}
+	if digest == "" {
+		digest = remoteDigest
+	}
+	if digest == "" {
+		return nil, nil
+	}
+	local, err := seedimage.Inspect(ctx, r.Executor, seedImageRef.Image)
+	if err != nil || !local.HasDigest(digest) {
+		return nil, nil //nolint:nilerr
+	}
+	r.Log.Info("Reusing the seed image already in the container storage", "image", seedImageRef.Image, "digest", digest)
+	return local, nil
+}
+
+// checkDiskSpace checks the host has the space for the seed image pull, the stateroot and the precaching, from the
+// size of the images to precache recorded by the seed creation in the seed image labels, if any
+func (r *ImageBasedUpgradeReconciler) checkDiskSpace(pullSize, staterootSize int64, labels map[string]string) error {
+	r.PrepTask.Progress = "Checking disk space"
+	if err := diskcheck.Check(r.Log, r.prepDiskRequirements(pullSize, staterootSize, labels)); err != nil {
+		return fmt.Errorf("disk space precheck failed: %w", err)
+	}
+	return nil
+}
+
+// prepDiskRequirements returns the space needed by Prep for the seed image pull, the stateroot and the precaching
+func (r *ImageBasedUpgradeReconciler) prepDiskRequirements(pullSize, staterootSize int64, labels map[string]string) []diskcheck.Requirement {
+	var precacheSize int64
+	if value, ok := labels[common.SeedPrecacheSizeOCILabel]; !ok {
+		r.Log.Info("Seed image does not record the size of its images to precache, checking the space for the seed image only")
+	} else if size, err := strconv.ParseInt(value, 10, 64); err != nil {
+		r.Log.Info("Ignoring the invalid size of the images to precache of the seed image", "value", value)
+	} else {
+		precacheSize = size
+	}
+	return diskcheck.PrepRequirements(pullSize, staterootSize, precacheSize)
+}
+
+// fetchSeedSBOM stores the SBOM attached to the seed image of the given digest in the seed SBOM ConfigMap, for
+// compliance scanning before the upgrade. The SBOM is not required by the upgrade, so failures are only logged
+func (r *ImageBasedUpgradeReconciler) fetchSeedSBOM(ctx context.Context, executor ops.Execute,
+	ibu *lcav1alpha1.ImageBasedUpgrade, pulledFrom, digest, authFile string, registryTLS *registrytls.Config) {
+	dir := filepath.Join(utils.IBUWorkspacePath, "seed-sbom")
+	if err := os.RemoveAll(common.PathOutsideChroot(dir)); err != nil {
+		r.Log.Error(err, "Failed to remove previous seed image SBOM")
+		return
+	}
+	defer os.RemoveAll(common.PathOutsideChroot(dir))
+
+	if err := seedimage.FetchSBOM(ctx, executor, pulledFrom, digest, authFile, registryTLS.SourceArgs(pulledFrom), dir); err != nil {
+		r.Log.Error(err, "Failed to fetch the seed image SBOM")
+		return
+	}
+	sbom, format, err := seedsbom.ReadArtifact(common.PathOutsideChroot(dir))
+	if err != nil {
+		r.Log.Error(err, "Failed to read the seed image SBOM")
+		return
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.SeedSBOMConfigMapName,
+			Namespace: common.LcaNamespace,
+			Annotations: map[string]string{
+				utils.SeedImageAnnotation:       ibu.Spec.SeedImageRef.Image,
+				utils.SeedImageDigestAnnotation: digest,
+			},
+		},
+		Data: map[string]string{seedsbom.FileName(format): string(sbom)},
+	}
+	if err := r.Client.Create(ctx, cm); err != nil {
+		r.Log.Error(err, "Failed to store the seed image SBOM", "configmap", utils.SeedSBOMConfigMapName)
+		return
+	}
+	r.Log.Info("Stored the seed image SBOM", "configmap", utils.SeedSBOMConfigMapName, "format", format,
+		"size", len(sbom))
+}
+
+// deleteSeedSBOM deletes the seed SBOM ConfigMap
+func (r *ImageBasedUpgradeReconciler) deleteSeedSBOM(ctx context.Context) error {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: utils.SeedSBOMConfigMapName, Namespace: common.LcaNamespace}}
+	if err := r.Client.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete configmap %s: %w", utils.SeedSBOMConfigMapName, err)
+	}
+	return nil
+}
+
+// expectedSeedImageDigest returns the digest the seed image must have, from its reference by digest or the expected
+// digest of the spec, which must match, or an empty string if none
+func expectedSeedImageDigest(seedImageRef lcav1alpha1.SeedImageRef) (string, error) {
+	digest, err := seedimage.ReferenceDigest(seedImageRef.Image)
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+	if digest == "" {
+		return seedImageRef.ExpectedDigest, nil
+	}
+	if seedImageRef.ExpectedDigest != "" && seedImageRef.ExpectedDigest != digest {
+		return "", fmt.Errorf("the expected digest %s differs from the digest of seed image %s",
+			seedImageRef.ExpectedDigest, seedImageRef.Image)
+	}
+	return digest, nil
+}
+
+// checkSeedImageDigest checks that the pulled seed image has the expected digest, if any, either its own or the one of
+// the manifest list it was pulled from, and returns the digest recorded for the seed image, its own one
+func checkSeedImageDigest(seedImageRef lcav1alpha1.SeedImageRef, image *seedimage.Image) (string, error) {
+	expected, err := expectedSeedImageDigest(seedImageRef)
+	if err != nil {
+		return "", lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, err)
+	}
+	if expected != "" && !image.HasDigest(expected) {
+		return "", lcaerrors.Wrap(lcaerrors.ErrSeedDigest, fmt.Errorf("seed image %s has digest %s, expected %s",
+			seedImageRef.Image, image.Digest, expected))
+	}
+	return image.Digest, nil
+}
+
+// getArtifactSource returns the artifact source configured for the IBU by annotation, or cluster wide, or nil if none
+func (r *ImageBasedUpgradeReconciler) getArtifactSource(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (*artifactsource.Config, error) {
+	name := artifactsource.DefaultConfigMapName
+	if value, ok := ibu.GetAnnotations()[utils.ArtifactSourceAnnotation]; ok && value != "" {
+		name = value
+	}
+	config, err := artifactsource.LoadConfig(ctx, r.Client, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load artifact source: %w", err)
+	}
+	return config, nil
+}
+
+// fetchSeedImage downloads the seed image from the artifact source and copies it to the container storage under its
+// reference, as if it were pulled from its registry
+func (r *ImageBasedUpgradeReconciler) fetchSeedImage(ctx context.Context, sourceConfig *artifactsource.Config, image,
+	pullSecretFilename string) error {
+	credentials, err := sourceConfig.LoadCredentials(ctx, r.Client)
+	if err != nil {
+		return fmt.Errorf("failed to load artifact source credentials: %w", err)
+	}
+	source, err := artifactsource.New(sourceConfig, credentials)
+	if err != nil {
+		return fmt.Errorf("failed to create artifact source: %w", err)
+	}
+
+	r.Log.Info("Fetching seed image from artifact source", "type", source.Type())
+	artifact, err := source.Fetch(ctx, image, common.PathOutsideChroot(artifactsource.Dir))
+	if err != nil {
+		return fmt.Errorf("failed to fetch seed image from %s artifact source: %w", source.Type(), err)
+	}
+	defer func() {
+		if err := artifact.Remove(); err != nil {
+			r.Log.Error(err, "failed to remove seed image artifact")
+		}
+	}()
 
-	if len(inspect) != 1 {
-		return fmt.Errorf("expected 1 image inspect result, got %d", len(inspect))
+	args := artifactsource.CopyArgs(artifact.TransportReference(common.Host), image, pullSecretFilename)
+	if _, err := r.Executor.ExecuteWithContext(ctx, "skopeo", args...); err != nil {
+		return fmt.Errorf("failed to copy seed image from %s artifact source: %w", source.Type(), err)
 	}
+	return nil
+}
 
-	seedFormatLabelValue, ok := inspect[0].Labels[common.SeedFormatOCILabel]
+// checkSeedImageCompatibility checks if the seed image is compatible with the
+// current version of the lifecycle-agent by inspecting the OCI image's labels
+// and checking if the specified format version equals the hard-coded one that
+// this version of the lifecycle agent expects. That format version is set by
+// the lca-cli during the image build process, and is only manually bumped by
+// developers when the image format changes in a way that is incompatible with
+// previous versions of the lifecycle-agent.
+func checkSeedImageCompatibility(image *seedimage.Image, seedImageRef string, hasProxy bool) error {
+	seedFormatLabelValue, ok := image.Labels[common.SeedFormatOCILabel]
 	if !ok {
-		return fmt.Errorf(
+		return lcaerrors.Wrap(lcaerrors.ErrSeedIncompatible, fmt.Errorf(
 			"seed image %s is missing the %s label, please build a new image using the latest version of the lca-cli",
-			seedImageRef, common.SeedFormatOCILabel)
+			seedImageRef, common.SeedFormatOCILabel))
 	}
 
 	// Hard equal since we don't have backwards compatibility guarantees yet.
@@ -121,15 +631,31 @@ func (r *ImageBasedUpgradeReconciler) checkSeedImageCompatibility(_ context.Cont
 	// handle older seed formats and in that case we'll look at the version
 	// number and do the right thing.
 	if seedFormatLabelValue != fmt.Sprintf("%d", common.SeedFormatVersion) {
-		return fmt.Errorf("seed image format version mismatch: expected %d, got %s",
-			common.SeedFormatVersion, seedFormatLabelValue)
+		return lcaerrors.Wrap(lcaerrors.ErrSeedIncompatible, fmt.Errorf("seed image format version mismatch: expected %d, got %s",
+			common.SeedFormatVersion, seedFormatLabelValue))
+	}
+
+	// The proxy is part of the machine configuration rendered in the seed stateroot, a cluster with or without a proxy
+	// unlike the seed cluster would have MCO roll out its own configuration after the upgrade, with an extra reboot
+	if value, ok := image.Labels[common.SeedHasProxyOCILabel]; ok {
+		seedHasProxy, err := strconv.ParseBool(value)
+		if err != nil {
+			return lcaerrors.Wrap(lcaerrors.ErrSeedIncompatible, fmt.Errorf("invalid %s label %q of seed image %s",
+				common.SeedHasProxyOCILabel, value, seedImageRef))
+		}
+		if seedHasProxy != hasProxy {
+			return lcaerrors.Wrap(lcaerrors.ErrSeedIncompatible, fmt.Errorf(
+				"seed image proxy configuration mismatch: seed cluster has a cluster-wide proxy: %t, this cluster: %t",
+				seedHasProxy, hasProxy))
+		}
 	}
 
 	return nil
 }
 
-// validateSeedOcpVersion rejects upgrade request if seed image version is not higher than current cluster (target) OCP version
-func (r *ImageBasedUpgradeReconciler) validateSeedOcpVersion(seedOcpVersion string) error {
+// validateSeedOcpVersion rejects upgrade request if seed image version is not higher than current cluster (target) OCP version,
+// unless skipCheck acknowledges a same version reinstall or a downgrade
+func (r *ImageBasedUpgradeReconciler) validateSeedOcpVersion(seedOcpVersion string, skipCheck bool) error {
 	// get target OCP version
 	targetClusterVersion := &configv1.ClusterVersion{}
 	if err := r.Get(context.Background(), types.NamespacedName{Name: "version"}, targetClusterVersion); err != nil {
@@ -149,7 +675,13 @@ func (r *ImageBasedUpgradeReconciler) validateSeedOcpVersion(seedOcpVersion stri
 
 	// compare versions
 	if seedSemVer.Compare(*targetSemVer) <= 0 {
-		return fmt.Errorf("seed OCP version (%s) must be higher than current OCP version (%s)", seedOcpVersion, targetOCP)
+		if skipCheck {
+			r.Log.Info("Skipping the seed OCP version check, the seed OCP version is not higher than the current one",
+				"seed", seedOcpVersion, "target", targetOCP, "annotation", utils.SkipSeedVersionCheckAnnotation)
+			return nil
+		}
+		return lcaerrors.Wrap(lcaerrors.ErrSeedIncompatible,
+			fmt.Errorf("seed OCP version (%s) must be higher than current OCP version (%s)", seedOcpVersion, targetOCP))
 	}
 
 	r.Log.Info("OCP versions are validated", "seed", seedOcpVersion, "target", targetOCP)
@@ -179,33 +711,273 @@ func (r *ImageBasedUpgradeReconciler) getPodEnvVars(ctx context.Context) (envVar
 	return
 }
 
-func (r *ImageBasedUpgradeReconciler) launchPrecaching(ctx context.Context, imageListFile string, ibu *lcav1alpha1.ImageBasedUpgrade) (bool, error) {
+// registriesConfRoot is the root of the registries configuration of the host
+var registriesConfRoot = common.PathOutsideChroot("/")
+
+// getClusterMirrors returns the mirrors of this cluster, from its ImageDigestMirrorSets and
+// ImageContentSourcePolicies, then from the registries configuration of the host, which also has the mirrors not
+// declared by mirror sets, e.g. the ones of the registry config of the upgrade
+func (r *ImageBasedUpgradeReconciler) getClusterMirrors(ctx context.Context) ([]lcautils.ImageMirror, error) {
+	clusterMirrors, err := lcautils.GetImageMirrors(ctx, r.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster image mirrors: %w", err)
+	}
+	hostMirrors, err := registryconfig.ReadMirrors(registriesConfRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host image mirrors: %w", err)
+	}
+	return append(clusterMirrors, hostMirrors...), nil
+}
+
+// readPrecachingList reads the images to precache, rewriting the repositories the seed cluster pulled from through a
+// mirror, and keeps those selected by the filter. Seeds recording their mirror configuration are rewritten per
+// repository, older seeds per image, by replacing the seed release registry for the images this cluster does not
+// mirror.
+func (r *ImageBasedUpgradeReconciler) readPrecachingList(ctx context.Context, imageListFile, clusterRegistry string,
+	seedInfo *seedclusterinfo.SeedClusterInfo, filter *prep.PrecacheFilter) ([]string, error) {
+	clusterMirrors, err := r.getClusterMirrors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	imageList, err := prep.ReadPrecachingList(imageListFile, "", "", false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pre-caching image file: %s, %w", common.PathOutsideChroot(imageListFile), err)
+	}
+
+	if len(seedInfo.ImageMirrors) > 0 {
+		rewrites := lcautils.BuildMirrorRewriteMap(seedInfo.ImageMirrors, clusterMirrors)
+		r.Log.Info("Rewriting seed mirrored repositories", "rewrites", rewrites)
+		for i := range imageList {
+			imageList[i] = lcautils.RewriteImageRepository(imageList[i], rewrites)
+		}
+	} else {
+		var overridden int
+		for i := range imageList {
+			image, err := lcautils.ResolveSeedImage(imageList[i], clusterRegistry, seedInfo.ReleaseRegistry, clusterMirrors)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve the registry of %s: %w", imageList[i], err)
+			}
+			if image != imageList[i] {
+				overridden++
+			}
+			imageList[i] = image
+		}
+		r.Log.Info("Resolved the seed release registry per image", "seedRegistry", seedInfo.ReleaseRegistry,
+			"clusterRegistry", clusterRegistry, "overridden", overridden, "images", len(imageList))
+	}
+	// The filter applies to the references pulled, as without mirrors
+	imageList, _ = filter.Apply(imageList)
+	return imageList, nil
+}
+
+// +kubebuilder:rbac:groups=config.openshift.io,resources=imagetagmirrorsets,verbs=get;list;watch
+
+// ResolveImageDigest helper func to resolve the digest of an image reference by tag in its registry
+var ResolveImageDigest = func(ctx context.Context, executor ops.Execute, image string) (string, error) {
+	output, err := executor.ExecuteWithContext(ctx, "skopeo", "inspect", "--retry-times", "3", "--authfile", common.ImageRegistryAuthFile,
+		"--format", "{{.Digest}}", "docker://"+image)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect %s: %w", image, err)
+	}
+	digest := strings.TrimSpace(output)
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", fmt.Errorf("unexpected digest %q of %s", digest, image)
+	}
+	return digest, nil
+}
+
+// applyDigestOnlyMirrorPolicy rewrites the images to pre-cache and the recert image run at first boot that are
+// referenced by tag, when their repository is under a digest-only mirror policy of this cluster, to references by
+// digest resolved through the mirrors. Prep fails listing the references that cannot be resolved, which would fail
+// to pull under the policy.
+func (r *ImageBasedUpgradeReconciler) applyDigestOnlyMirrorPolicy(ctx context.Context, imageList []string,
+	seedInfo *seedclusterinfo.SeedClusterInfo, seedManifestFile string) ([]string, error) {
+	clusterMirrors, err := lcautils.GetImageMirrors(ctx, r.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster image mirrors: %w", err)
+	}
+	tagMirrorSources, err := lcautils.GetTagMirrorSources(ctx, r.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster image tag mirrors: %w", err)
+	}
+	digestOnly := lcautils.DigestOnlyMirrors(clusterMirrors, tagMirrorSources)
+	if len(digestOnly) == 0 {
+		return imageList, nil
+	}
+	r.Log.Info("Resolving the references by tag under the digest-only mirror policy", "mirrors", digestOnly)
+
+	var unresolved []string
+	resolve := func(image string) string {
+		if lcautils.IsDigestReference(image) {
+			return image
+		}
+		imageMirror := lcautils.MatchImageMirror(image, digestOnly)
+		if imageMirror == nil {
+			return image
+		}
+		for _, mirror := range imageMirror.Mirrors {
+			mirrored := lcautils.RewriteImageRepository(image, map[string]string{imageMirror.Source: mirror})
+			digest, err := ResolveImageDigest(ctx, r.Executor, mirrored)
+			if err != nil {
+				r.Log.Info("Unable to resolve image digest through mirror", "image", image, "mirror", mirror, "error", err.Error())
+				continue
+			}
+			return lcautils.ImageRepository(image) + "@" + digest
+		}
+		unresolved = append(unresolved, image)
+		return image
+	}
+
+	resolved := make([]string, len(imageList))
+	for i, image := range imageList {
+		resolved[i] = resolve(image)
+	}
+	if seedInfo.RecertImagePullSpec != "" {
+		if recertImage := resolve(seedInfo.RecertImagePullSpec); recertImage != seedInfo.RecertImagePullSpec {
+			r.Log.Info("Rewriting the recert image by digest", "image", recertImage)
+			seedInfo.RecertImagePullSpec = recertImage
+			if err := lcautils.MarshalToFile(seedInfo, common.PathOutsideChroot(seedManifestFile)); err != nil {
+				return nil, fmt.Errorf("failed to write seed info with recert image by digest: %w", err)
+			}
+		}
+	}
+	if len(unresolved) > 0 {
+		return nil, lcaerrors.Wrap(lcaerrors.ErrSeedIncompatible,
+			fmt.Errorf("%d image references by tag cannot be resolved to a digest and would fail to pull under the digest-only mirror policy: %s",
+				len(unresolved), strings.Join(unresolved, ", ")))
+	}
+	return resolved, nil
+}
+
+// launchPrecaching creates the precaching job for the images not yet pre-cached. It returns false if no job was
+// needed because all images were already pre-cached by a previous attempt. With verifyOnly, the job only checks all
+// the images in their registry, leaving the record of the pre-cached images untouched.
+func (r *ImageBasedUpgradeReconciler) launchPrecaching(ctx context.Context, imageListFile, seedManifestFile string,
+	ibu *lcav1alpha1.ImageBasedUpgrade, registryTLS *registrytls.Config, verifyOnly bool) (bool, error) {
 	clusterRegistry, err := lcautils.GetReleaseRegistry(ctx, r.Client)
 	if err != nil {
 		return false, fmt.Errorf("failed to get cluster registry: %w", err)
 	}
-	seedInfo, err := seedclusterinfo.ReadSeedClusterInfoFromFile(
-		common.PathOutsideChroot(getSeedManifestPath(common.GetDesiredStaterootName(ibu))))
+	seedInfo, err := seedclusterinfo.ReadSeedClusterInfoFromFile(common.PathOutsideChroot(seedManifestFile))
 	if err != nil {
 		return false, fmt.Errorf("failed to read seed info: %w", err)
 	}
-	shouldOverrideRegistry, err := lcautils.ShouldOverrideSeedRegistry(ctx, r.Client, seedInfo.MirrorRegistryConfigured, seedInfo.ReleaseRegistry)
+	filter, err := getPrecacheFilter(ibu.Spec.Precache, seedInfo.RecertImagePullSpec)
 	if err != nil {
-		return false, fmt.Errorf("failed to check ShouldOverrideSeedRegistry %w", err)
+		return false, err
 	}
-
-	imageList, err := prep.ReadPrecachingList(imageListFile, clusterRegistry, seedInfo.ReleaseRegistry, shouldOverrideRegistry)
+	if filter != nil {
+		r.Log.Info("Filtering the precaching list", "include", ibu.Spec.Precache.IncludeImages,
+			"exclude", ibu.Spec.Precache.ExcludeImages)
+	}
+	imageList, err := r.readPrecachingList(ctx, imageListFile, clusterRegistry, seedInfo, filter)
 	if err != nil {
-		return false, fmt.Errorf("failed to read pre-caching image file: %s, %w", common.PathOutsideChroot(imageListFile), err)
+		return false, err
 	}
-
-	envVars, err := r.getPodEnvVars(ctx)
+	if source := precache.ImageListSource(ibu); source != lcav1alpha1.PrecacheImageListSourceSeed {
+		seedImages := len(imageList)
+		if imageList, err = precache.BuildImageList(ctx, r.Client, source, imageList, seedInfo.RecertImagePullSpec); err != nil {
+			return false, fmt.Errorf("failed to build the precaching list: %w", err)
+		}
+		var removed []string
+		if imageList, removed = filter.Apply(imageList); len(removed) != 0 {
+			r.Log.Info("Filtered out operator images from the precaching list", "images", removed)
+		}
+		r.Log.Info("Built the precaching list", "source", source, "seedImages", seedImages, "images", len(imageList))
+	}
+	additionalImages, err := r.getAdditionalImages(ctx, ibu)
+	if err != nil {
+		return false, err
+	}
+	if len(additionalImages) != 0 {
+		// The additional images are listed explicitly, the filter of the precaching list does not apply to them
+		r.Log.Info("Adding the additional images to the precaching list", "configMap", ibu.Spec.AdditionalImages.Name,
+			"images", len(additionalImages))
+		imageList = collections.Union(imageList, additionalImages)
+	}
+	imageList, err = r.applyDigestOnlyMirrorPolicy(ctx, imageList, seedInfo, seedManifestFile)
 	if err != nil {
-		return false, fmt.Errorf("failed to get pod env vars: %w", err)
+		return false, err
+	}
+	// Rewriting the repositories or resolving the tags can make images of the seed image list the same
+	if unique := collections.Unique(imageList); len(unique) < len(imageList) {
+		r.Log.Info("Removed duplicate images from the precaching list", "duplicates", len(imageList)-len(unique))
+		imageList = unique
+	}
+
+	// The report of a previous precaching is stale
+	if err := r.Precache.DeleteReport(ctx); err != nil {
+		return false, fmt.Errorf("failed to delete previous precaching report: %w", err)
+	}
+
+	// Skip images already pre-cached by a previous attempt for this seed image and image list
+	if !verifyOnly {
+		remaining, err := precache.PrepareCache(ctx, r.Executor, common.PathOutsideChroot(precache.CacheFile),
+			ibu.Spec.SeedImageRef.Image, r.PrepTask.SeedImageDigest, imageList)
+		if err != nil {
+			return false, fmt.Errorf("failed to prepare precache record: %w", err)
+		}
+		if len(remaining) == 0 {
+			r.Log.Info("All images were pre-cached by a previous attempt, skipping precaching job", "total", len(imageList))
+			return false, nil
+		}
+		if len(remaining) < len(imageList) {
+			r.Log.Info("Resuming precaching from a previous attempt", "cached", len(imageList)-len(remaining),
+				"remaining", len(remaining))
+		}
+		imageList = remaining
+	}
+
+	// The manager process already runs with its env vars, only the precaching job needs them
+	inProcess := isPrecacheInProcess(ibu)
+	var envVars []corev1.EnvVar
+	if !inProcess {
+		if envVars, err = r.getPodEnvVars(ctx); err != nil {
+			return false, fmt.Errorf("failed to get pod env vars: %w", err)
+		}
 	}
 
 	// Create pre-cache config using default values
-	config := precache.NewConfig(imageList, envVars)
+	var configArgs []any
+	if inProcess {
+		configArgs = append(configArgs, "InProcess", true)
+	}
+	sourceConfig, err := r.getArtifactSource(ctx, ibu)
+	if err != nil {
+		return false, lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, err)
+	}
+	if sourceConfig != nil {
+		configArgs = append(configArgs, "ArtifactSource", sourceConfig)
+	}
+	if ibu.GetAnnotations()[utils.RestrictPrecacheEgressAnnotation] == "true" {
+		allowedHosts, err := r.getPrecacheEgressAllowedHosts(ctx, ibu)
+		if err != nil {
+			return false, err
+		}
+		if sourceConfig != nil {
+			allowedHosts = lcautils.AppendToListIfNotExists(allowedHosts, sourceConfig.Host())
+		}
+		configArgs = append(configArgs, "RestrictEgress", true, "AllowedEgressHosts", allowedHosts)
+	}
+	configArgs = append(configArgs, r.getPrecachePullLimits(ibu)...)
+	configArgs = append(configArgs, getPrecacheJobSettings(ibu.Spec.Precache)...)
+	if ibu.GetAnnotations()[utils.PrecachePartialPullsAnnotation] == "true" {
+		configArgs = append(configArgs, "PartialPulls", true)
+	}
+	if verifyOnly {
+		configArgs = append(configArgs, "VerifyOnly", true)
+	}
+	if registryTLS != nil {
+		configArgs = append(configArgs, "RegistryTLS", registryTLS)
+	}
+	podPolicy, err := podpolicy.Load(ctx, r.Client)
+	if err != nil {
+		return false, err //nolint:wrapcheck
+	}
+	if podPolicy != nil {
+		configArgs = append(configArgs, "PodPolicy", podPolicy)
+	}
+	config := precache.NewConfig(imageList, envVars, configArgs...)
 	err = r.Precache.CreateJob(ctx, config)
 	if err != nil {
 		return false, fmt.Errorf("failed to create precaching job: %w", err)
@@ -214,6 +986,156 @@ func (r *ImageBasedUpgradeReconciler) launchPrecaching(ctx context.Context, imag
 	return true, nil
 }
 
+// getAdditionalImages returns the images listed in the additional images config map of the IBU, if any, such as the
+// images of the workloads to precache along with the platform images. A missing config map or an invalid image list
+// is an invalid spec.
+func (r *ImageBasedUpgradeReconciler) getAdditionalImages(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) ([]string, error) {
+	ref := ibu.Spec.AdditionalImages
+	if ref.Name == "" {
+		return nil, nil
+	}
+	cm, err := common.GetConfigMap(ctx, r.Client, ref)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return nil, lcaerrors.Wrap(lcaerrors.ErrInvalidSpec,
+				fmt.Errorf("additional images configMap %s/%s not found", ref.Namespace, ref.Name))
+		}
+		return nil, fmt.Errorf("failed to get additional images: %w", err)
+	}
+	images, err := precache.ParseImageList(cm.Data)
+	if err != nil {
+		return nil, lcaerrors.Wrap(lcaerrors.ErrInvalidSpec,
+			fmt.Errorf("invalid additional images configMap %s/%s: %w", ref.Namespace, ref.Name, err))
+	}
+	return images, nil
+}
+
+// isInvalidSpec tells whether the error is classified as an invalid spec
+func isInvalidSpec(err error) bool {
+	return errors.Is(err, lcaerrors.ErrInvalidSpec)
+}
+
+// getPrecacheFilter returns the filter of the images to precache set in the IBU spec, if any, always keeping the
+// recert image run at first boot
+func getPrecacheFilter(spec *lcav1alpha1.PrecacheConfig, recertImage string) (*prep.PrecacheFilter, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	filter, err := prep.NewPrecacheFilter(spec.IncludeImages, spec.ExcludeImages, recertImage)
+	if err != nil {
+		return nil, lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, err)
+	}
+	return filter, nil
+}
+
+// isPrecacheInProcess tells whether the images are pre-cached in the manager process instead of a precaching job
+func isPrecacheInProcess(ibu *lcav1alpha1.ImageBasedUpgrade) bool {
+	return ibu.GetAnnotations()[utils.PrecacheModeAnnotation] == utils.PrecacheModeInProcess
+}
+
+// validatePrecacheMode checks the precaching mode set by the PrecacheModeAnnotation. The options only applying to the
+// pod of the precaching job are rejected when precaching in the manager process, rather than silently ignored.
+func validatePrecacheMode(ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	mode, ok := ibu.GetAnnotations()[utils.PrecacheModeAnnotation]
+	if !ok || mode == utils.PrecacheModeJob {
+		return nil
+	}
+	if mode != utils.PrecacheModeInProcess {
+		return fmt.Errorf("invalid %s annotation %q, expected %s or %s", utils.PrecacheModeAnnotation, mode,
+			utils.PrecacheModeJob, utils.PrecacheModeInProcess)
+	}
+
+	var jobOnly []string
+	if ibu.GetAnnotations()[utils.RestrictPrecacheEgressAnnotation] == "true" {
+		jobOnly = append(jobOnly, utils.RestrictPrecacheEgressAnnotation)
+	}
+	if ibu.GetAnnotations()[utils.PrecachePartialPullsAnnotation] == "true" {
+		jobOnly = append(jobOnly, utils.PrecachePartialPullsAnnotation)
+	}
+	if ibu.Spec.Precache != nil && ibu.Spec.Precache.Resources != nil {
+		jobOnly = append(jobOnly, "spec.precache.resources")
+	}
+	if len(jobOnly) != 0 {
+		return fmt.Errorf("%s only apply to the precaching job, not when precaching in the manager process with the %s annotation",
+			strings.Join(jobOnly, ", "), utils.PrecacheModeAnnotation)
+	}
+	return nil
+}
+
+// getPrecachePullLimits returns the precaching config arguments of the pull timeout and oversized image threshold
+// annotations. Invalid values are ignored in favor of the defaults.
+func (r *ImageBasedUpgradeReconciler) getPrecachePullLimits(ibu *lcav1alpha1.ImageBasedUpgrade) []any {
+	var configArgs []any
+	if value, ok := ibu.GetAnnotations()[utils.PrecachePullTimeoutAnnotation]; ok {
+		if timeout, err := time.ParseDuration(value); err == nil {
+			configArgs = append(configArgs, "PullTimeout", timeout)
+		} else {
+			r.Log.Info("Ignoring invalid precaching pull timeout", "annotation", utils.PrecachePullTimeoutAnnotation, "value", value)
+		}
+	}
+	if value, ok := ibu.GetAnnotations()[utils.PrecacheOversizedImageThresholdAnnotation]; ok {
+		if threshold, err := resource.ParseQuantity(value); err == nil {
+			configArgs = append(configArgs, "OversizedImageThreshold", threshold.Value())
+		} else {
+			r.Log.Info("Ignoring invalid precaching oversized image threshold", "annotation", utils.PrecacheOversizedImageThresholdAnnotation, "value", value)
+		}
+	}
+	return configArgs
+}
+
+// getPrecacheJobSettings returns the precaching config arguments of the concurrency, the niceness and the compute
+// resources of the precaching job set in the IBU spec, the unset ones keeping their defaults
+func getPrecacheJobSettings(spec *lcav1alpha1.PrecacheConfig) []any {
+	if spec == nil {
+		return nil
+	}
+	var configArgs []any
+	if spec.NumConcurrentPulls != 0 {
+		configArgs = append(configArgs, "NumConcurrentPulls", spec.NumConcurrentPulls)
+	}
+	if spec.MaxMbps != 0 {
+		configArgs = append(configArgs, "MaxMbps", spec.MaxMbps)
+	}
+	if spec.NicePriority != nil {
+		configArgs = append(configArgs, "NicePriority", *spec.NicePriority)
+	}
+	if spec.IoNiceClass != nil {
+		configArgs = append(configArgs, "IoNiceClass", *spec.IoNiceClass)
+	}
+	if spec.IoNicePriority != nil {
+		configArgs = append(configArgs, "IoNicePriority", *spec.IoNicePriority)
+	}
+	if spec.Resources != nil {
+		configArgs = append(configArgs, "Resources", spec.Resources)
+	}
+	if spec.BestEffort {
+		configArgs = append(configArgs, "BestEffort", true)
+	}
+	return configArgs
+}
+
+// getPrecacheEgressAllowedHosts returns the mirror registries of the cluster, which the host may pull the images
+// from, and the hosts listed in the PrecacheEgressAllowedHostsAnnotation
+func (r *ImageBasedUpgradeReconciler) getPrecacheEgressAllowedHosts(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) ([]string, error) {
+	mirrors, err := lcautils.GetImageMirrors(ctx, r.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster image mirrors: %w", err)
+	}
+
+	hosts := &collections.OrderedSet[string]{}
+	for _, mirror := range mirrors {
+		for _, m := range mirror.Mirrors {
+			hosts.Add(lcautils.ExtractRegistryFromImage(m))
+		}
+	}
+	for _, host := range strings.Split(ibu.GetAnnotations()[utils.PrecacheEgressAllowedHostsAnnotation], ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts.Add(host)
+		}
+	}
+	return hosts.Items(), nil
+}
+
 func (r *ImageBasedUpgradeReconciler) queryPrecachingStatus(ctx context.Context) (status *precache.Status, err error) {
 	status, err = r.Precache.QueryJobStatus(ctx)
 	if err != nil {
@@ -227,9 +1149,27 @@ func (r *ImageBasedUpgradeReconciler) queryPrecachingStatus(ctx context.Context)
 	}
 
 	if status.Status == precache.Failed {
+		var reasons []string
+		if status.Blocked != "" {
+			reasons = append(reasons, status.Blocked)
+		}
+		if len(status.FailedImages) != 0 {
+			reasons = append(reasons, precache.FailedImagesSummary(status.FailedImages))
+		}
+		if len(reasons) != 0 {
+			return status, fmt.Errorf("%w: %s", precache.ErrFailed, strings.Join(reasons, "; "))
+		}
 		return status, precache.ErrFailed
 	}
 
+	if status.Status == precache.Succeeded && len(status.FailedImages) != 0 {
+		// Only in best-effort mode, the failed pulls are reported by the Prep completion
+		for _, image := range status.FailedImages {
+			r.Log.Info("Image not pre-cached after retries", "image", image.Image, "attempts", image.Attempts,
+				"error", image.Error)
+		}
+	}
+
 	var logMsg string
 	switch {
 	case status.Status == precache.Active:
@@ -248,12 +1188,13 @@ func (r *ImageBasedUpgradeReconciler) queryPrecachingStatus(ctx context.Context)
 }
 
 func (r *ImageBasedUpgradeReconciler) SetupStateroot(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade, imageListFile string) error {
-	if err := prep.SetupStateroot(r.Log, r.Ops, r.OstreeClient, r.RPMOstreeClient, ibu.Spec.SeedImageRef.Image,
-		ibu.Spec.SeedImageRef.Version, imageListFile, false); err != nil {
-		return fmt.Errorf("failed to setup stateroot: %w", err)
+	if err := prep.SetupStateroot(ctx, r.Log, r.Ops, r.OstreeClient, r.RPMOstreeClient, r.StaterootLayout, r.StaterootLayout.DesiredName(ibu),
+		seedimage.StorageName(ibu.Spec.SeedImageRef.Image), ibu.Spec.SeedImageRef.Version, imageListFile, false,
+		ibu.Spec.VarDataMode == lcav1alpha1.VarDataModeDeferred); err != nil {
+		return lcaerrors.WrapHostError(lcaerrors.ErrOstreeDeploy, fmt.Errorf("failed to setup stateroot: %w", err))
 	}
 
-	if err := r.RPMOstreeClient.RpmOstreeCleanup(); err != nil {
+	if err := r.RPMOstreeClient.RpmOstreeCleanup(ctx); err != nil {
 		return fmt.Errorf("failed rpm-ostree cleanup -b: %w", err)
 	}
 
@@ -261,32 +1202,295 @@ func (r *ImageBasedUpgradeReconciler) SetupStateroot(ctx context.Context, ibu *l
 		return fmt.Errorf("failed to write auto-rollback config: %w", err)
 	}
 
-	if err := lcautils.BackupKubeconfigCrypto(ctx, r.Client, common.GetStaterootCertsDir(ibu)); err != nil {
+	if err := postpivot.WriteLoggingConfigFile(ibu.Spec.PostPivotLogging,
+		r.StaterootLayout.Path(r.StaterootLayout.DesiredName(ibu))); err != nil {
+		return fmt.Errorf("failed to write post-pivot logging config: %w", err)
+	}
+
+	if err := lcautils.BackupKubeconfigCrypto(ctx, r.Client, r.StaterootLayout.CertsDir(ibu), func(msg string) {
+		r.PrepTask.Progress = msg
+	}); err != nil {
 		return fmt.Errorf("failed to backup cerificaties: %w", err)
 	}
 
 	return nil
 }
 
-func (r *ImageBasedUpgradeReconciler) verifyPrecachingCompleteFunc(retries int, interval time.Duration) wait.ConditionWithContextFunc {
+// recordDeployedCommit queries ostree for the metadata of the commit deployed in the new stateroot, reported in the
+// status and recorded in the upgrade journal. It is informational only, a failure is logged and does not fail Prep
+func (r *ImageBasedUpgradeReconciler) recordDeployedCommit(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) {
+	deployedCommit, err := r.getDeployedCommit(ctx, r.StaterootLayout.DesiredName(ibu))
+	if err != nil {
+		r.Log.Error(err, "Failed to get the metadata of the deployed commit")
+		return
+	}
+	r.PrepTask.DeployedCommit = deployedCommit
+
+	msg := fmt.Sprintf("Deployed commit %s in stateroot %s", deployedCommit.Checksum, deployedCommit.Stateroot)
+	if deployedCommit.Version != "" {
+		msg = fmt.Sprintf("%s, version %s", msg, deployedCommit.Version)
+	}
+	if deployedCommit.Parent != "" {
+		msg = fmt.Sprintf("%s, parent %s", msg, deployedCommit.Parent)
+	}
+	if deployedCommit.CommittedAt != nil {
+		msg = fmt.Sprintf("%s, committed at %s", msg, deployedCommit.CommittedAt.UTC().Format(time.RFC3339))
+	}
+	r.Log.Info(msg)
+	r.Recorder.Event(ibu, corev1.EventTypeNormal, "StaterootDeployed", msg)
+}
+
+// getDeployedCommit returns the ostree commit deployed in the stateroot, with its metadata
+func (r *ImageBasedUpgradeReconciler) getDeployedCommit(ctx context.Context, stateroot string) (*lcav1alpha1.DeployedCommit, error) {
+	deployment, err := r.OstreeClient.GetDeployment(ctx, stateroot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the deployment of stateroot %s: %w", stateroot, err)
+	}
+	if deployment == "" {
+		return nil, fmt.Errorf("no deployment found for stateroot %s", stateroot)
+	}
+
+	// The deployment is the checksum of the commit and its serial, e.g. 9455b993...b722.0
+	checksum, _, _ := strings.Cut(deployment, ".")
+	metadata, err := r.OstreeClient.GetCommitMetadata(ctx, checksum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the metadata of commit %s: %w", checksum, err)
+	}
+
+	deployedAt := metav1.Now()
+	committedAt := metav1.NewTime(metadata.Timestamp)
+	return &lcav1alpha1.DeployedCommit{
+		Stateroot:   stateroot,
+		Deployment:  deployment,
+		Checksum:    metadata.Checksum,
+		Parent:      metadata.Parent,
+		Version:     metadata.Version,
+		CommittedAt: &committedAt,
+		DeployedAt:  &deployedAt,
+	}, nil
+}
+
+// installRegistryConfig installs the registries configuration referenced by the IBU on the running host, replacing
+// the one of a previous Prep. It returns nil when the IBU references none
+func (r *ImageBasedUpgradeReconciler) installRegistryConfig(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (*registryconfig.Config, error) {
+	if ibu.Spec.RegistryConfig == nil {
+		return nil, nil
+	}
+	config, err := registryconfig.Load(ctx, r.Client, ibu.Spec.RegistryConfig)
+	if err != nil {
+		return nil, lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, fmt.Errorf("failed to load registry config: %w", err))
+	}
+	r.Log.Info("Installing the registry configuration on the host", "registryConfig", ibu.Spec.RegistryConfig.Name,
+		"dropIns", len(config.DropIns), "caCerts", len(config.CACerts))
+	if err := config.Install(common.PathOutsideChroot("/")); err != nil {
+		return nil, fmt.Errorf("failed to install registry config on the host: %w", err)
+	}
+	if err := registryconfig.UpdateCATrust(r.Executor); err != nil {
+		return nil, fmt.Errorf("failed to install registry config on the host: %w", err)
+	}
+	return config, nil
+}
+
+// renderRegistryTLS renders the TLS overrides of the registries in the workspace, for the seed image pull and the
+// precaching, leaving the containers configuration of the host untouched
+func (r *ImageBasedUpgradeReconciler) renderRegistryTLS(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (*registrytls.Config, error) {
+	config, err := registrytls.Render(ctx, r.Client, &ibu.Spec, common.PathOutsideChroot("/"))
+	if err != nil {
+		return nil, lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, fmt.Errorf("failed to render registry TLS config: %w", err))
+	}
+	if config != nil {
+		r.Log.Info("Overriding the TLS configuration of registries", "registries", len(config.Registries))
+	}
+	return config, nil
+}
+
+// installStaterootRegistryConfig installs the registries configuration in the new stateroot, whose CA bundle is
+// extracted from the seed and only updated by the post-pivot with the CA bundle of the running host when the cluster
+// has a user CA bundle
+func (r *ImageBasedUpgradeReconciler) installStaterootRegistryConfig(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade,
+	config *registryconfig.Config) error {
+	if config == nil {
+		return nil
+	}
+	deploymentDir, err := r.OstreeClient.GetDeploymentDir(ctx, r.StaterootLayout.DesiredName(ibu))
+	if err != nil {
+		return fmt.Errorf("failed to get deployment dir: %w", err)
+	}
+	root := common.PathOutsideChroot(deploymentDir)
+	if err := config.Install(root); err != nil {
+		return fmt.Errorf("failed to install registry config in the new stateroot: %w", err)
+	}
+	if err := config.AppendCABundle(filepath.Join(root, common.CABundleFilePath)); err != nil {
+		return fmt.Errorf("failed to install registry config in the new stateroot: %w", err)
+	}
+	return nil
+}
+
+// exportPrecachingReport exports the final precaching report, successful or not, for CI pipelines. The export is
+// best effort, failures are only logged
+func (r *ImageBasedUpgradeReconciler) exportPrecachingReport(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) {
+	status, err := r.Precache.QueryJobStatus(ctx)
+	if err != nil || status == nil {
+		r.Log.Info("Unable to get the final precaching status, not exporting the precaching report")
+		return
+	}
+	junit := ibu.GetAnnotations()[utils.PrecacheJUnitReportAnnotation] == "true"
+	if _, err := r.Precache.ExportReport(ctx, status, junit); err != nil {
+		r.Log.Error(err, "Failed to export the precaching report")
+	}
+}
+
+// updatePrecachingProgress reports the precaching job progress, or the reason it is blocked, in the Prep task
+func (r *ImageBasedUpgradeReconciler) updatePrecachingProgress(status *precache.Status) {
+	var eta *time.Time
+	if status.Message != "" {
+		eta = status.EstimatedCompletion(time.Now())
+		r.PrepTask.PrecacheProgress = precacheProgress(&status.Progress, eta)
+	}
+
+	wasBlocked := r.PrepTask.Blocked
+	r.PrepTask.Blocked = status.Blocked != "" && status.Status != precache.Succeeded
+	switch {
+	case r.PrepTask.Blocked:
+		r.PrepTask.Progress = fmt.Sprintf("Precaching blocked: %s", status.Blocked)
+	case status.Recreated != "":
+		r.PrepTask.Progress = fmt.Sprintf("Precaching job %s", status.Recreated)
+	case status.Message != "":
+		r.PrepTask.Progress = precachingProgressMessage(&status.Progress, eta, status.Message)
+	case wasBlocked:
+		r.PrepTask.Progress = "Waiting for precaching job to complete"
+	}
+}
+
+// precacheProgress returns the structured progress of the precaching job reported in the status
+func precacheProgress(progress *precache.Progress, eta *time.Time) *lcav1alpha1.PrecacheProgress {
+	p := &lcav1alpha1.PrecacheProgress{
+		Total:       progress.Total,
+		Pulled:      progress.Pulled,
+		Skipped:     progress.Skipped,
+		Failed:      progress.Failed,
+		PulledBytes: progress.PulledBytes,
+		Percent:     progress.Percent(),
+	}
+	if progress.VerifyOnly {
+		p.Pulled = progress.WouldPull
+		p.PulledBytes = progress.WouldPullBytes
+	}
+	if eta != nil {
+		p.EstimatedCompletionTime = &metav1.Time{Time: eta.UTC().Truncate(time.Second)}
+	}
+	return p
+}
+
+// precachingProgressMessage renders the precaching job progress for the Prep condition, e.g.
+// "Precaching: 143/311 images (46%), estimated completion at 2024-01-19T06:40:00Z; total: 311 (pulled: ...)"
+func precachingProgressMessage(progress *precache.Progress, eta *time.Time, summary string) string {
+	msg := fmt.Sprintf("Precaching: %d/%d images (%d%%)", progress.Processed(), progress.Total, progress.Percent())
+	if eta != nil {
+		msg += fmt.Sprintf(", estimated completion at %s", eta.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s; %s", msg, summary)
+}
+
+// getPrecacheWaitLimits returns the timeout and the stall window of the wait for the precaching to complete, from the
+// IBU spec. The stall window defaults to twice the time limit of an image pull, so that a single slow pull, which
+// times out and fails on its own, is not taken for a stall
+func getPrecacheWaitLimits(ibu *lcav1alpha1.ImageBasedUpgrade) (timeout, stall time.Duration) {
+	if spec := ibu.Spec.Precache; spec != nil {
+		timeout = time.Duration(spec.TimeoutMinutes) * time.Minute
+		stall = time.Duration(spec.StallMinutes) * time.Minute
+	}
+	if stall <= 0 {
+		pullTimeout := precache.DefaultPullTimeout
+		if value, ok := ibu.GetAnnotations()[utils.PrecachePullTimeoutAnnotation]; ok {
+			if parsed, err := time.ParseDuration(value); err == nil && parsed >= time.Second {
+				pullTimeout = parsed
+			}
+		}
+		stall = 2 * pullTimeout
+	}
+	return timeout, stall
+}
+
+// precacheWatchdog fails the wait for the precaching once past its timeout, or when the precaching processes no
+// image for the stall window while neither blocked nor complete. The time the precaching job is blocked, e.g. by a
+// ResourceQuota, only counts against the timeout, as the job recovers once the cause is solved.
+type precacheWatchdog struct {
+	timeout time.Duration
+	stall   time.Duration
+	start   time.Time
+	// lastProgress is the last time the processed images or the pulled bytes changed, or the job was blocked
+	lastProgress time.Time
+	processed    int
+	pulledBytes  int64
+}
+
+func newPrecacheWatchdog(timeout, stall time.Duration, now time.Time) *precacheWatchdog {
+	return &precacheWatchdog{timeout: timeout, stall: stall, start: now, lastProgress: now}
+}
+
+// check returns an error classified with the PrecacheTimeout or the PrecacheStalled reason when the precaching
+// exceeds its limits, given its status at the time, nil if it could not be queried
+func (w *precacheWatchdog) check(status *precache.Status, now time.Time) error {
+	var progress string
+	if status != nil {
+		progress = fmt.Sprintf(", %d/%d images processed", status.Progress.Processed(), status.Progress.Total)
+	}
+	if w.timeout > 0 && now.Sub(w.start) > w.timeout {
+		return lcaerrors.Wrap(lcaerrors.ErrPrecacheTimeout,
+			fmt.Errorf("precaching did not complete within %s%s", w.timeout, progress))
+	}
+	if status == nil {
+		return nil
+	}
+
+	processed := status.Progress.Processed()
+	if status.Blocked != "" || processed != w.processed || status.Progress.PulledBytes != w.pulledBytes {
+		w.lastProgress, w.processed, w.pulledBytes = now, processed, status.Progress.PulledBytes
+		return nil
+	}
+	if w.stall > 0 && now.Sub(w.lastProgress) > w.stall {
+		return lcaerrors.Wrap(lcaerrors.ErrPrecacheStalled,
+			fmt.Errorf("precaching made no progress for %s%s", w.stall, progress))
+	}
+	return nil
+}
+
+func (r *ImageBasedUpgradeReconciler) verifyPrecachingCompleteFunc(retries int, interval time.Duration,
+	watchdog *precacheWatchdog) wait.ConditionWithContextFunc {
 	return func(ctx context.Context) (bool, error) {
 		r.Log.Info("Querying pre-caching job for completion...")
 		for retry := 0; retry < retries; retry++ {
 			status, err := r.queryPrecachingStatus(ctx)
+			if err == nil && status == nil {
+				// precaching job deleted or precaching interrupted by a manager restart - recreate it
+				if status, err = r.Precache.RecreateJob(ctx); err != nil {
+					r.Log.Info("Failed to recreate precaching job", "error", err.Error())
+				}
+			}
 			if err != nil && errors.Is(err, precache.ErrFailed) {
 				// precaching job failed - exit immediately
 				return false, err
 			} else if status != nil {
-				if status.Message != "" {
-					r.PrepTask.Progress = fmt.Sprintf("Precaching progress: %s", status.Message)
-				}
+				r.updatePrecachingProgress(status)
 				if status.Status == precache.Succeeded {
 					// precaching job succeeded
 					return true, nil
-				} else if status.Status == precache.Active {
+				}
+				if err := watchdog.check(status, time.Now()); err != nil {
+					// precaching job past its timeout or stalled
+					return false, err
+				}
+				if status.Blocked != "" {
+					// precaching job blocked, e.g. by a ResourceQuota or node pressure - wait for it to be solved
+					return false, nil
+				}
+				if status.Status == precache.Active {
 					// precaching job still in-progress
 					return false, nil
 				}
+			} else if err := watchdog.check(nil, time.Now()); err != nil {
+				return false, err
 			}
 			// retry after interval
 			time.Sleep(interval)
@@ -308,20 +1512,45 @@ func (r *ImageBasedUpgradeReconciler) prepStageWorker(ctx context.Context, ibu *
 
 	errGroup.Go(func() error {
 		var ok bool
+		var registryConfig *registryconfig.Config
+		var registryTLS *registrytls.Config
 		imageListFile := filepath.Join(utils.IBUWorkspacePath, "image-list-file")
+		seedManifestFile := getSeedManifestPath(r.StaterootLayout, r.StaterootLayout.DesiredName(ibu))
 
 		// check spec against this cluster's version and possibly exit early
-		if err := r.validateSeedOcpVersion(ibu.Spec.SeedImageRef.Version); err != nil {
+		skipVersionCheck, err := utils.SkipSeedVersionCheck(ibu)
+		if err != nil {
+			return lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, err)
+		}
+		if err := r.validateSeedOcpVersion(ibu.Spec.SeedImageRef.Version, skipVersionCheck); err != nil {
 			return fmt.Errorf("failed to validate seed image OCP version in spec: %w", err)
 		}
 
+		// check the boot partition can take the new deployment, as ostree only fails at the end of the stateroot setup
+		r.PrepTask.Progress = "Checking boot partition"
+		if err := bootcheck.Check(r.Log, r.Executor); err != nil {
+			return fmt.Errorf("boot partition precheck failed: %w", err)
+		}
+		if err := bootcheck.CheckBootDevices(r.Log, r.Executor); err != nil {
+			return fmt.Errorf("boot devices precheck failed: %w", err)
+		}
+
+		// Install the registries configuration of the upgrade first, for the seed image pull and the precaching
+		r.PrepTask.Progress = "Installing registry configuration"
+		if registryConfig, err = r.installRegistryConfig(derivedCtx, ibu); err != nil {
+			return err
+		}
+		if registryTLS, err = r.renderRegistryTLS(derivedCtx, ibu); err != nil {
+			return err
+		}
+
 		// Pull seed image
 		select {
 		case <-derivedCtx.Done():
 			return fmt.Errorf("context canceled before pulling seed image: %w", derivedCtx.Err())
 		default:
 			r.PrepTask.Progress = "Pulling seed image"
-			if err = r.getSeedImage(derivedCtx, ibu); err != nil {
+			if err = r.getSeedImage(derivedCtx, ibu, registryTLS); err != nil {
 				return fmt.Errorf("failed to pull seed image: %w", err)
 			}
 			r.Log.Info("Successfully pulled seed image")
@@ -337,6 +1566,20 @@ func (r *ImageBasedUpgradeReconciler) prepStageWorker(ctx context.Context, ibu *
 			if err = r.SetupStateroot(derivedCtx, ibu, imageListFile); err != nil {
 				return fmt.Errorf("failed to setup stateroot with prep stage worker: %w", err)
 			}
+			r.recordDeployedCommit(derivedCtx, ibu)
+			// The boot entry of the new deployment must have reached all the devices under /boot
+			if _, err = r.Executor.ExecuteWithContext(derivedCtx, "sync", "--file-system", "/boot"); err != nil {
+				return fmt.Errorf("failed to sync /boot: %w", err)
+			}
+			if err = bootcheck.CheckBootDevices(r.Log, r.Executor); err != nil {
+				return fmt.Errorf("boot devices check after the stateroot setup failed: %w", err)
+			}
+			if err = r.installStaterootRegistryConfig(derivedCtx, ibu, registryConfig); err != nil {
+				return err
+			}
+			if err = r.checkSeedClusterInfo(derivedCtx, seedManifestFile); err != nil {
+				return err
+			}
 			r.Log.Info("Successfully setup stateroot")
 			r.PrepTask.Progress = "Successfully setup stateroot"
 		}
@@ -347,22 +1590,30 @@ func (r *ImageBasedUpgradeReconciler) prepStageWorker(ctx context.Context, ibu *
 			return fmt.Errorf("context canceled before creating precaching job: %w", derivedCtx.Err())
 		default:
 			r.PrepTask.Progress = "Creating precaching job"
-			ok, err = r.launchPrecaching(derivedCtx, imageListFile, ibu)
+			ok, err = r.launchPrecaching(derivedCtx, imageListFile, seedManifestFile, ibu, registryTLS, false)
 			if err != nil {
-				return fmt.Errorf("failed to launch pre-caching phase: %w", err)
+				return lcaerrors.Wrap(lcaerrors.ErrPrecache, fmt.Errorf("failed to launch pre-caching phase: %w", err))
 			}
-			if !ok {
-				return fmt.Errorf("failed to create precaching job")
+			if ok {
+				r.Log.Info("Successfully created precaching job")
+				r.PrepTask.Progress = "Successfully created precaching job"
 			}
-			r.Log.Info("Successfully created precaching job")
-			r.PrepTask.Progress = "Successfully created precaching job"
 		}
 
 		// Wait for precaching job to complete
-		r.PrepTask.Progress = "Waiting for precaching job to complete"
-		interval := 30 * time.Second
-		if err = wait.PollUntilContextCancel(derivedCtx, interval, false, r.verifyPrecachingCompleteFunc(5, interval)); err != nil {
-			return fmt.Errorf("failed to precache images: %w", err)
+		if ok {
+			r.PrepTask.Progress = "Waiting for precaching job to complete"
+			interval := 30 * time.Second
+			timeout, stall := getPrecacheWaitLimits(ibu)
+			r.Log.Info("Waiting for precaching to complete", "timeout", timeout, "stallWindow", stall)
+			err = wait.PollUntilContextCancel(derivedCtx, interval, false,
+				r.verifyPrecachingCompleteFunc(5, interval, newPrecacheWatchdog(timeout, stall, time.Now())))
+			if derivedCtx.Err() == nil {
+				r.exportPrecachingReport(ctx, ibu)
+			}
+			if err != nil {
+				return lcaerrors.Wrap(lcaerrors.ErrPrecache, fmt.Errorf("failed to precache images: %w", err))
+			}
 		}
 
 		// Fetch final precaching job report summary
@@ -370,6 +1621,27 @@ func (r *ImageBasedUpgradeReconciler) prepStageWorker(ctx context.Context, ibu *
 		status, err := r.Precache.QueryJobStatus(ctx)
 		if err == nil && status != nil && status.Message != "" {
 			r.Log.Info(msg, "summary", status.Message)
+			if len(status.Progress.OversizedImages) != 0 {
+				r.Log.Info("Oversized images pre-cached, consider including them in the seed image",
+					"images", status.Progress.OversizedImages)
+			}
+		}
+		// The precaching job only succeeds with failed pulls in best-effort mode
+		if err == nil && status != nil && len(status.Progress.FailedPullList) != 0 {
+			failed := append([]string{}, status.Progress.FailedPullList...)
+			sort.Strings(failed)
+			r.Log.Info("Images not pre-cached in best-effort mode, pulled after the upgrade reboot instead", "images", failed)
+			r.PrepTask.PrecacheFailedImages = failed
+			msg = fmt.Sprintf("Prep completed with %d images not pre-cached, pulled after the upgrade reboot instead: %s",
+				len(failed), strings.Join(failed, ", "))
+		}
+		// Record the inputs consumed by Prep, to detect their changes before the upgrade
+		inputs, err := computePrepInputs(ctx, r.Client, r.StaterootLayout, ibu)
+		if err != nil {
+			return fmt.Errorf("failed to compute prep inputs: %w", err)
+		}
+		if err := inputs.Persist(prepInputsFile); err != nil {
+			return fmt.Errorf("failed to record prep inputs: %w", err)
 		}
 		r.PrepTask.Progress = msg
 
@@ -407,13 +1679,22 @@ func (r *ImageBasedUpgradeReconciler) handlePrep(ctx context.Context, ibu *lcav1
 
 	switch {
 	case !r.PrepTask.Active:
+		if err := r.releasePreservedArtifacts(ctx, ibu); err != nil {
+			utils.SetPrepStatusFailedWithError(ibu, err)
+			return doNotRequeue(), nil
+		}
 		r.PrepTask.done = make(chan struct{})
 		r.PrepTask.Active = true
 		r.PrepTask.Success = false
 		r.PrepTask.Progress = "Prep stage initialized"
+		ibu.Status.SeedImageDigest = ""
+		ibu.Status.SeedImageManifestListDigest = ""
+		ibu.Status.SeedImageInfo = nil
+		ibu.Status.DeployedCommit = nil
+		ibu.Status.PrecacheProgress = nil
+		ibu.Status.SystemdUnits = nil
 		go func() {
-			err = r.prepStageWorker(ctx, ibu)
-			close(r.PrepTask.done)
+			err := r.prepStageWorker(ctx, ibu)
 			if err != nil {
 				r.Log.Error(err, "Prep stage failed with error")
 				r.PrepTask.Success = false
@@ -421,22 +1702,43 @@ func (r *ImageBasedUpgradeReconciler) handlePrep(ctx context.Context, ibu *lcav1
 				r.Log.Info("Prep stage completed successfully!")
 				r.PrepTask.Success = true
 			}
+			r.PrepTask.Err = err
+			close(r.PrepTask.done)
 		}()
 		utils.SetPrepStatusInProgress(ibu, r.PrepTask.Progress)
 		result = requeueWithShortInterval()
 	case r.PrepTask.Active:
+		if r.PrepTask.SeedImageDigest != "" {
+			ibu.Status.SeedImageDigest = r.PrepTask.SeedImageDigest
+			ibu.Status.SeedImageManifestListDigest = r.PrepTask.SeedImageManifestListDigest
+		}
+		if r.PrepTask.SeedImageInfo != nil {
+			ibu.Status.SeedImageInfo = r.PrepTask.SeedImageInfo.DeepCopy()
+		}
+		if r.PrepTask.DeployedCommit != nil {
+			ibu.Status.DeployedCommit = r.PrepTask.DeployedCommit.DeepCopy()
+		}
+		if r.PrepTask.PrecacheProgress != nil {
+			ibu.Status.PrecacheProgress = r.PrepTask.PrecacheProgress.DeepCopy()
+		}
 		select {
 		case <-r.PrepTask.done:
-			if r.PrepTask.Success {
+			if r.PrepTask.Success && len(r.PrepTask.PrecacheFailedImages) != 0 {
+				utils.SetPrepStatusCompletedWithReason(ibu, utils.ConditionReasons.PrecachePartial, r.PrepTask.Progress)
+			} else if r.PrepTask.Success {
 				utils.SetPrepStatusCompleted(ibu, r.PrepTask.Progress)
 			} else {
-				utils.SetPrepStatusFailed(ibu, r.PrepTask.Progress)
+				utils.SetPrepStatusFailedWithError(ibu, r.PrepTask.Err)
 			}
 			// Reset Task values
 			r.PrepTask.Reset()
 			result = doNotRequeue()
 		default:
-			utils.SetPrepStatusInProgress(ibu, r.PrepTask.Progress)
+			if r.PrepTask.Blocked {
+				utils.SetPrepStatusPrecacheBlocked(ibu, r.PrepTask.Progress)
+			} else {
+				utils.SetPrepStatusInProgress(ibu, r.PrepTask.Progress)
+			}
 			result = requeueWithShortInterval()
 		}
 	}
@@ -444,9 +1746,26 @@ func (r *ImageBasedUpgradeReconciler) handlePrep(ctx context.Context, ibu *lcav1
 	return
 }
 
-func getSeedManifestPath(osname string) string {
+// prepInputsFile records the hashes of the inputs consumed by Prep
+var prepInputsFile = common.PathOutsideChroot(prepinputs.InputsFile)
+
+// getStaterootSeedDigest helper func to call GetStaterootSeedDigest
+var getStaterootSeedDigest = prep.GetStaterootSeedDigest
+
+// computePrepInputs returns the hashes of the inputs consumed by Prep, with the digest of the seed image the
+// stateroot was set up from
+func computePrepInputs(ctx context.Context, c client.Client, layout common.StaterootLayout,
+	ibu *lcav1alpha1.ImageBasedUpgrade) (prepinputs.Hashes, error) {
+	seedDigest, err := getStaterootSeedDigest(layout, layout.DesiredName(ibu))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the seed image digest of the stateroot: %w", err)
+	}
+	return prepinputs.Compute(ctx, c, ibu, seedDigest)
+}
+
+func getSeedManifestPath(layout common.StaterootLayout, osname string) string {
 	return filepath.Join(
-		common.GetStaterootPath(osname),
+		layout.Path(osname),
 		filepath.Join(common.SeedDataDir, common.SeedClusterInfoFileName),
 	)
 }
@@ -1,14 +1,38 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
 	"github.com/go-logr/logr"
 	configv1 "github.com/openshift/api/config/v1"
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
-	"testing"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/artifactsource"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
+	"github.com/openshift-kni/lifecycle-agent/internal/precache"
+	"github.com/openshift-kni/lifecycle-agent/internal/seedimage"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedclusterinfo"
+	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
 )
 
 func TestImageBasedUpgradeReconciler_validateSeedOcpVersion(t *testing.T) {
@@ -28,6 +52,7 @@ func TestImageBasedUpgradeReconciler_validateSeedOcpVersion(t *testing.T) {
 
 	type args struct {
 		seedOcpVersion string
+		skipCheck      bool
 	}
 	tests := []struct {
 		name       string
@@ -52,6 +77,16 @@ func TestImageBasedUpgradeReconciler_validateSeedOcpVersion(t *testing.T) {
 			wantErr:    assert.Error,
 			wantErrMsg: "seed OCP version (4.14.7-rc.1) must be higher than current OCP version (4.14.8)",
 		},
+		{
+			name:    "when seed OCP is equal to target cluster and the check is skipped",
+			args:    args{seedOcpVersion: "4.14.8", skipCheck: true},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "when seed OCP is less than target cluster and the check is skipped",
+			args:    args{seedOcpVersion: "4.13.20", skipCheck: true},
+			wantErr: assert.NoError,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -61,7 +96,7 @@ func TestImageBasedUpgradeReconciler_validateSeedOcpVersion(t *testing.T) {
 				Log:    logr.Logger{},
 			}
 
-			err := r.validateSeedOcpVersion(tt.args.seedOcpVersion)
+			err := r.validateSeedOcpVersion(tt.args.seedOcpVersion, tt.args.skipCheck)
 			tt.wantErr(t, err, fmt.Sprintf("validateSeedOcpVersion(%v)", tt.args.seedOcpVersion))
 			if err != nil {
 				assert.Equal(t, tt.wantErrMsg, err.Error())
@@ -69,3 +104,587 @@ func TestImageBasedUpgradeReconciler_validateSeedOcpVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestImageBasedUpgradeReconciler_fetchSeedImage(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: artifactsource.DefaultConfigMapName, Namespace: common.LcaNamespace},
+			Data:       map[string]string{artifactsource.TypeKey: artifactsource.TypeRegistry, artifactsource.RegistryKey: "nexus.example.com/cache"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "site-cache", Namespace: common.LcaNamespace},
+			Data:       map[string]string{artifactsource.TypeKey: artifactsource.TypeRegistry, artifactsource.RegistryKey: "artifactory.example.com"},
+		},
+	).Build()
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	r := &ImageBasedUpgradeReconciler{Client: c, Log: logr.Discard(), Executor: mockExec}
+
+	ibu := &lcav1alpha1.ImageBasedUpgrade{}
+	sourceConfig, err := r.getArtifactSource(context.Background(), ibu)
+	assert.NoError(t, err)
+	assert.Equal(t, "nexus.example.com/cache", sourceConfig.Registry)
+
+	ibu.SetAnnotations(map[string]string{utils.ArtifactSourceAnnotation: "site-cache"})
+	sourceConfig, err = r.getArtifactSource(context.Background(), ibu)
+	assert.NoError(t, err)
+	assert.Equal(t, "artifactory.example.com", sourceConfig.Registry)
+
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "skopeo", "copy", "--retry-times", "3", "--src-authfile", common.ImageRegistryAuthFile,
+		"docker://artifactory.example.com/quay.io/org/seed:4.15.0", "containers-storage:quay.io/org/seed:4.15.0").Return("", nil)
+	assert.NoError(t, r.fetchSeedImage(context.Background(), sourceConfig, "quay.io/org/seed:4.15.0", common.ImageRegistryAuthFile))
+
+	ibu.SetAnnotations(map[string]string{utils.ArtifactSourceAnnotation: "missing"})
+	_, err = r.getArtifactSource(context.Background(), ibu)
+	assert.ErrorContains(t, err, "failed to get artifact source configmap missing")
+}
+
+func TestImageBasedUpgradeReconciler_pullSeedImage(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NoError(t, configv1.AddToScheme(testScheme))
+	assert.NoError(t, operatorv1alpha1.AddToScheme(testScheme))
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		&configv1.ImageDigestMirrorSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "digest-mirrors"},
+			Spec: configv1.ImageDigestMirrorSetSpec{ImageDigestMirrors: []configv1.ImageDigestMirrors{
+				{Source: "quay.io/org", Mirrors: []configv1.ImageMirror{"mirror.example.com/org", "backup.example.com/org"}},
+			}},
+		},
+		&configv1.ImageTagMirrorSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "tag-mirrors"},
+			Spec: configv1.ImageTagMirrorSetSpec{ImageTagMirrors: []configv1.ImageTagMirrors{
+				{Source: "quay.io/org/seed", Mirrors: []configv1.ImageMirror{"backup.example.com/org/seed"}},
+			}},
+		},
+	).Build()
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	r := &ImageBasedUpgradeReconciler{Client: c, Log: logr.Discard(), Executor: mockExec, PrepTask: &Task{}}
+
+	candidates, err := r.seedImagePullCandidates(context.Background(), "quay.io/org/seed:4.15.0")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"backup.example.com/org/seed:4.15.0", "mirror.example.com/org/seed:4.15.0",
+		"quay.io/org/seed:4.15.0"}, candidates)
+	candidates, err = r.seedImagePullCandidates(context.Background(), "quay.io/org/seed@sha256:1234")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"quay.io/org/seed@sha256:1234"}, candidates)
+
+	// The first mirror fails, the seed image pulled from the second one is named after the seed image reference
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "skopeo", gomock.Any()).Return("", errors.New("unreachable")).AnyTimes()
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "pull", "--authfile", "/auth.json", "--arch", "arm64",
+		"backup.example.com/org/seed:4.15.0").Return("", errors.New("manifest unknown"))
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "pull", "--authfile", "/auth.json", "--arch", "arm64",
+		"mirror.example.com/org/seed:4.15.0").Return("", nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "tag", "mirror.example.com/org/seed:4.15.0",
+		"quay.io/org/seed:4.15.0").Return("", nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "untag", "quay.io/org/seed:4.15.0",
+		"mirror.example.com/org/seed:4.15.0").Return("", nil)
+	pulledFrom, err := r.pullSeedImage(context.Background(), mockExec, "quay.io/org/seed:4.15.0", []string{
+		"backup.example.com/org/seed:4.15.0", "mirror.example.com/org/seed:4.15.0", "quay.io/org/seed:4.15.0"}, "/auth.json",
+		"arm64", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "mirror.example.com/org/seed:4.15.0", pulledFrom)
+
+	// All the references failing, the errors of all are reported
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "pull", "--authfile", "/auth.json",
+		"mirror.example.com/org/seed:4.15.0").Return("", errors.New("manifest unknown"))
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "pull", "--authfile", "/auth.json",
+		"quay.io/org/seed:4.15.0").Return("", errors.New("unauthorized"))
+	_, err = r.pullSeedImage(context.Background(), mockExec, "quay.io/org/seed:4.15.0", []string{
+		"mirror.example.com/org/seed:4.15.0", "quay.io/org/seed:4.15.0"}, "/auth.json", "", nil)
+	assert.ErrorContains(t, err, "mirror.example.com/org/seed:4.15.0: failed to pull image: manifest unknown")
+	assert.ErrorContains(t, err, "quay.io/org/seed:4.15.0: failed to pull image: unauthorized")
+}
+
+func TestImageBasedUpgradeReconciler_readPrecachingList(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NoError(t, configv1.AddToScheme(testScheme))
+	assert.NoError(t, operatorv1alpha1.AddToScheme(testScheme))
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		&configv1.ImageDigestMirrorSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "digest-mirrors"},
+			Spec: configv1.ImageDigestMirrorSetSpec{ImageDigestMirrors: []configv1.ImageDigestMirrors{
+				{Source: "seed-mirror.io:5000/ocp/art-dev", Mirrors: []configv1.ImageMirror{"mirror.local/ocp/art-dev"}},
+			}},
+		},
+	).Build()
+	r := &ImageBasedUpgradeReconciler{Client: c, Log: logr.Discard(), PrepTask: &Task{}}
+
+	// The mirrors of the registries configuration of the host apply as well
+	registriesConfRoot = t.TempDir()
+	dropInDir := filepath.Join(registriesConfRoot, "etc/containers/registries.conf.d")
+	assert.NoError(t, os.MkdirAll(dropInDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dropInDir, "98-lca-ibu-mirrors.conf"), []byte(`[[registry]]
+location = "seed-mirror.io:5000/ocp/operators"
+[[registry.mirror]]
+location = "mirror.local/ocp/operators"
+`), 0o644))
+
+	imageListFile := filepath.Join(t.TempDir(), "images.txt")
+	assert.NoError(t, os.WriteFile(imageListFile, []byte(strings.Join([]string{
+		"seed-mirror.io:5000/ocp/art-dev@sha256:1234",
+		"seed-mirror.io:5000/ocp/operators@sha256:5678",
+		"seed-mirror.io:5000/ocp/release@sha256:9abc",
+		"registry.redhat.io/rhel9/support-tools@sha256:def0",
+	}, "\n")+"\n"), 0o644))
+
+	// Only the image not mirrored by this cluster is moved to its release registry
+	imageList, err := r.readPrecachingList(context.Background(), imageListFile, "quay.io",
+		&seedclusterinfo.SeedClusterInfo{ReleaseRegistry: "seed-mirror.io:5000", MirrorRegistryConfigured: true}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"seed-mirror.io:5000/ocp/art-dev@sha256:1234",
+		"seed-mirror.io:5000/ocp/operators@sha256:5678",
+		"quay.io/ocp/release@sha256:9abc",
+		"registry.redhat.io/rhel9/support-tools@sha256:def0",
+	}, imageList)
+}
+
+func TestImageBasedUpgradeReconciler_checkSeedImagePlatform(t *testing.T) {
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	r := &ImageBasedUpgradeReconciler{Log: logr.Discard(), Executor: mockExec, PrepTask: &Task{}}
+	inspectRaw := func(image string) *gomock.Call {
+		return mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "skopeo", "inspect", "--raw", "--retry-times", "3",
+			"--authfile", "/auth.json", "docker://"+image)
+	}
+	candidates := []string{"mirror.example.com/org/seed:4.16.0", "quay.io/org/seed:4.16.0"}
+	manifestList := `{"manifests":[{"digest":"sha256:1234","platform":{"architecture":"amd64","os":"linux"}}]}`
+
+	// The unreachable mirror is skipped
+	inspectRaw(candidates[0]).Return("", errors.New("unreachable")).Times(2)
+	inspectRaw(candidates[1]).Return(manifestList, nil).Times(2)
+	err := r.checkSeedImagePlatform(context.Background(), mockExec, candidates, "/auth.json", "arm64", nil)
+	assert.ErrorContains(t, err, "manifest list quay.io/org/seed:4.16.0 has no image for linux/arm64, only for linux/amd64")
+	assert.Equal(t, utils.ConditionReasons.SeedIncompatible, utils.ReasonForError(err))
+	assert.NoError(t, r.checkSeedImagePlatform(context.Background(), mockExec, candidates, "/auth.json", "amd64", nil))
+}
+
+func TestImageBasedUpgradeReconciler_reusableSeedImage(t *testing.T) {
+	const (
+		digest      = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		otherDigest = "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	)
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	r := &ImageBasedUpgradeReconciler{Log: logr.Discard(), Executor: mockExec, PrepTask: &Task{}}
+	inspect := func(image string) *gomock.Call {
+		return mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "inspect", "--format", "json", image)
+	}
+
+	// Referenced by digest, the seed image in the container storage is reused without its registry
+	byDigest := lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed@" + digest}
+	inspect(byDigest.Image).Return(`[{"Digest":"`+digest+`","Size":4096}]`, nil)
+	local, err := r.reusableSeedImage(context.Background(), byDigest, "")
+	assert.NoError(t, err)
+	assert.Equal(t, &seedimage.Image{Digest: digest, Size: 4096}, local)
+
+	// Not in the container storage
+	inspect(byDigest.Image).Return("", errors.New("image not known"))
+	local, err = r.reusableSeedImage(context.Background(), byDigest, "")
+	assert.NoError(t, err)
+	assert.Nil(t, local)
+
+	// Referenced by tag, the tag in the registry must still refer to the seed image in the container storage
+	byTag := lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed:4.15.0"}
+	local, err = r.reusableSeedImage(context.Background(), byTag, "")
+	assert.NoError(t, err)
+	assert.Nil(t, local)
+	inspect(byTag.Image).Return(`[{"Digest":"`+otherDigest+`"}]`, nil)
+	local, err = r.reusableSeedImage(context.Background(), byTag, digest)
+	assert.NoError(t, err)
+	assert.Nil(t, local)
+	inspect(byTag.Image).Return(`[{"Digest":"`+otherDigest+`","RepoDigests":["quay.io/org/seed@`+digest+`"]}]`, nil)
+	local, err = r.reusableSeedImage(context.Background(), byTag, digest)
+	assert.NoError(t, err)
+	assert.NotNil(t, local)
+
+	// The expected digest takes precedence over the one in the registry
+	byTag.ExpectedDigest = otherDigest
+	inspect(byTag.Image).Return(`[{"Digest":"`+digest+`"}]`, nil)
+	local, err = r.reusableSeedImage(context.Background(), byTag, digest)
+	assert.NoError(t, err)
+	assert.Nil(t, local)
+
+	// Always pulled with forcePull
+	byDigest.ForcePull = true
+	local, err = r.reusableSeedImage(context.Background(), byDigest, "")
+	assert.NoError(t, err)
+	assert.Nil(t, local)
+
+	byDigest = lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed@" + digest, ExpectedDigest: otherDigest}
+	_, err = r.reusableSeedImage(context.Background(), byDigest, "")
+	assert.ErrorContains(t, err, "the expected digest")
+}
+
+func TestSeedImagePullProxyEnv(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "http://lca-proxy:3128")
+	t.Setenv("NO_PROXY", ".cluster.local")
+
+	// The cluster-wide proxy has precedence over the one of the LCA
+	testScheme := runtime.NewScheme()
+	assert.NoError(t, configv1.AddToScheme(testScheme))
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(&configv1.Proxy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status:     configv1.ProxyStatus{HTTPSProxy: "http://proxy:3128"},
+	}).Build()
+	clusterProxyEnv, err := lcautils.GetProxyEnv(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"HTTPS_PROXY=http://proxy:3128"}, seedImagePullProxyEnv(clusterProxyEnv))
+
+	clusterProxyEnv, err = lcautils.GetProxyEnv(context.Background(), fake.NewClientBuilder().WithScheme(testScheme).Build())
+	assert.NoError(t, err)
+	assert.Empty(t, clusterProxyEnv)
+	assert.Equal(t, []string{"HTTPS_PROXY=http://lca-proxy:3128", "NO_PROXY=.cluster.local"},
+		seedImagePullProxyEnv(clusterProxyEnv))
+}
+
+func TestSeedImageRetryPolicy(t *testing.T) {
+	assert.Equal(t, seedimage.RetryPolicy{Attempts: 3, Backoff: 10 * time.Second}, seedImageRetryPolicy(nil))
+	assert.Equal(t, seedimage.RetryPolicy{Attempts: 5, Backoff: 10 * time.Second, MaxDuration: 10 * time.Minute},
+		seedImageRetryPolicy(&lcav1alpha1.PullRetryPolicy{Attempts: 5, MaxDurationSeconds: 600}))
+}
+
+func TestCheckSeedImageArchitecture(t *testing.T) {
+	assert.NoError(t, checkSeedImageArchitecture("quay.io/org/seed:4.15.0", "arm64", "arm64"))
+	// Unknown architectures are not checked
+	assert.NoError(t, checkSeedImageArchitecture("quay.io/org/seed:4.15.0", "", "arm64"))
+	assert.NoError(t, checkSeedImageArchitecture("quay.io/org/seed:4.15.0", "amd64", ""))
+
+	err := checkSeedImageArchitecture("quay.io/org/seed:4.15.0", "amd64", "arm64")
+	assert.ErrorContains(t, err, "seed image quay.io/org/seed:4.15.0 architecture amd64 does not match the node "+
+		"architecture arm64, a seed image created from a cluster of the arm64 architecture is required")
+	assert.Equal(t, utils.ConditionReasons.SeedIncompatible, utils.ReasonForError(err))
+}
+
+func TestCheckSeedImageCompatibility(t *testing.T) {
+	formatLabel := fmt.Sprintf("%d", common.SeedFormatVersion)
+	testCases := []struct {
+		name        string
+		labels      map[string]string
+		hasProxy    bool
+		expectedErr string
+	}{
+		{
+			name:   "seed without proxy label",
+			labels: map[string]string{common.SeedFormatOCILabel: formatLabel},
+		},
+		{
+			name:     "both with proxy",
+			labels:   map[string]string{common.SeedFormatOCILabel: formatLabel, common.SeedHasProxyOCILabel: "true"},
+			hasProxy: true,
+		},
+		{
+			name:        "seed with proxy",
+			labels:      map[string]string{common.SeedFormatOCILabel: formatLabel, common.SeedHasProxyOCILabel: "true"},
+			expectedErr: "seed image proxy configuration mismatch: seed cluster has a cluster-wide proxy: true, this cluster: false",
+		},
+		{
+			name:        "cluster with proxy",
+			labels:      map[string]string{common.SeedFormatOCILabel: formatLabel, common.SeedHasProxyOCILabel: "false"},
+			hasProxy:    true,
+			expectedErr: "seed image proxy configuration mismatch: seed cluster has a cluster-wide proxy: false, this cluster: true",
+		},
+		{
+			name:        "format mismatch",
+			labels:      map[string]string{common.SeedFormatOCILabel: "1"},
+			expectedErr: "seed image format version mismatch",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkSeedImageCompatibility(&seedimage.Image{Labels: tc.labels}, "quay.io/org/seed:4.15.0", tc.hasProxy)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.expectedErr)
+			assert.Equal(t, utils.ConditionReasons.SeedIncompatible, utils.ReasonForError(err))
+		})
+	}
+}
+
+func TestSeedImageInfo(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fips := true
+	assert.Equal(t, &lcav1alpha1.SeedImageInfo{BaseOSVersion: "415.92.202402201450-0", FormatVersion: 3, FIPS: &fips,
+		CreatedAt: &metav1.Time{Time: created}},
+		seedImageInfo(&seedimage.Image{Created: created, Labels: map[string]string{
+			common.SeedFormatOCILabel:        "3",
+			common.SeedBaseOSVersionOCILabel: "415.92.202402201450-0",
+			common.SeedFIPSOCILabel:          "true",
+		}}))
+
+	// The seed images created by older lca-cli versions do not record the base OS version nor the FIPS mode
+	assert.Equal(t, &lcav1alpha1.SeedImageInfo{FormatVersion: 3},
+		seedImageInfo(&seedimage.Image{Labels: map[string]string{common.SeedFormatOCILabel: "3"}}))
+}
+
+func TestGetDeployedCommit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ostreeclientMock := ostreeclient.NewMockIClient(ctrl)
+	r := &ImageBasedUpgradeReconciler{Log: logr.Discard(), OstreeClient: ostreeclientMock}
+
+	committed := time.Date(2024, 3, 6, 16, 41, 12, 0, time.UTC)
+	ostreeclientMock.EXPECT().GetDeployment(gomock.Any(), "rhcos_4.15.2").Return("8ef186bc.0", nil)
+	ostreeclientMock.EXPECT().GetCommitMetadata(gomock.Any(), "8ef186bc").Return(&ostreeclient.CommitMetadata{
+		Checksum: "8ef186bc", Parent: "9455b993", Version: "415.92.202403061641-0", Timestamp: committed}, nil)
+	deployedCommit, err := r.getDeployedCommit(context.Background(), "rhcos_4.15.2")
+	assert.NoError(t, err)
+	assert.NotNil(t, deployedCommit.DeployedAt)
+	deployedCommit.DeployedAt = nil
+	assert.Equal(t, &lcav1alpha1.DeployedCommit{Stateroot: "rhcos_4.15.2", Deployment: "8ef186bc.0", Checksum: "8ef186bc",
+		Parent: "9455b993", Version: "415.92.202403061641-0", CommittedAt: &metav1.Time{Time: committed}}, deployedCommit)
+
+	// The stateroot is not deployed
+	ostreeclientMock.EXPECT().GetDeployment(gomock.Any(), "rhcos_4.15.2").Return("", nil)
+	_, err = r.getDeployedCommit(context.Background(), "rhcos_4.15.2")
+	assert.ErrorContains(t, err, "no deployment found for stateroot rhcos_4.15.2")
+
+	ostreeclientMock.EXPECT().GetDeployment(gomock.Any(), "rhcos_4.15.2").Return("8ef186bc.0", nil)
+	ostreeclientMock.EXPECT().GetCommitMetadata(gomock.Any(), "8ef186bc").Return(nil, errors.New("ostree failed"))
+	_, err = r.getDeployedCommit(context.Background(), "rhcos_4.15.2")
+	assert.ErrorContains(t, err, "failed to get the metadata of commit 8ef186bc: ostree failed")
+}
+
+func TestCheckSeedImageDigest(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	otherDigest := "sha256:" + strings.Repeat("cd", 32)
+	listDigest := "sha256:" + strings.Repeat("ef", 32)
+	image := &seedimage.Image{Digest: digest, RepoDigests: []string{"quay.io/org/seed@" + digest, "quay.io/org/seed@" + listDigest}}
+	testCases := []struct {
+		name           string
+		seedImageRef   lcav1alpha1.SeedImageRef
+		expectedDigest string
+		expectedReason utils.ConditionReason
+	}{
+		{
+			name:           "referenced by tag",
+			seedImageRef:   lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed:4.15.0"},
+			expectedDigest: digest,
+		},
+		{
+			name:           "referenced by digest",
+			seedImageRef:   lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed@" + digest},
+			expectedDigest: digest,
+		},
+		{
+			name:           "referenced by tag with the expected digest",
+			seedImageRef:   lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed:4.15.0", ExpectedDigest: digest},
+			expectedDigest: digest,
+		},
+		{
+			name:           "resolved from the expected manifest list digest",
+			seedImageRef:   lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed:4.15.0", ExpectedDigest: listDigest},
+			expectedDigest: digest,
+		},
+		{
+			name:           "referenced by tag with another expected digest",
+			seedImageRef:   lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed:4.15.0", ExpectedDigest: otherDigest},
+			expectedReason: utils.ConditionReasons.SeedDigestMismatch,
+		},
+		{
+			name:           "referenced by digest with another expected digest",
+			seedImageRef:   lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed@" + digest, ExpectedDigest: otherDigest},
+			expectedReason: utils.ConditionReasons.InvalidSpec,
+		},
+		{
+			name:           "referenced by invalid digest",
+			seedImageRef:   lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed@sha256:1234"},
+			expectedReason: utils.ConditionReasons.InvalidSpec,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			digest, err := checkSeedImageDigest(tc.seedImageRef, image)
+			if tc.expectedReason != "" {
+				assert.Error(t, err)
+				assert.Equal(t, tc.expectedReason, utils.ReasonForError(err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedDigest, digest)
+		})
+	}
+}
+
+func TestGetPrecacheJobSettings(t *testing.T) {
+	assert.Empty(t, getPrecacheJobSettings(nil))
+	assert.Empty(t, getPrecacheJobSettings(&lcav1alpha1.PrecacheConfig{ImageListSource: lcav1alpha1.PrecacheImageListSourceSeed}))
+
+	nicePriority, ioNiceClass, ioNicePriority := 10, 0, 7
+	resources := &corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}}
+	assert.Equal(t, []any{"NumConcurrentPulls", 2, "MaxMbps", 100, "NicePriority", 10, "IoNiceClass", 0, "IoNicePriority", 7,
+		"Resources", resources, "BestEffort", true}, getPrecacheJobSettings(&lcav1alpha1.PrecacheConfig{
+		NumConcurrentPulls: 2,
+		MaxMbps:            100,
+		NicePriority:       &nicePriority,
+		IoNiceClass:        &ioNiceClass,
+		IoNicePriority:     &ioNicePriority,
+		Resources:          resources,
+		BestEffort:         true,
+	}))
+}
+
+func TestGetPrecacheWaitLimits(t *testing.T) {
+	ibu := &lcav1alpha1.ImageBasedUpgrade{}
+	timeout, stall := getPrecacheWaitLimits(ibu)
+	assert.Equal(t, time.Duration(0), timeout)
+	assert.Equal(t, 2*precache.DefaultPullTimeout, stall)
+
+	// The default stall window follows the pull timeout annotation
+	ibu.SetAnnotations(map[string]string{utils.PrecachePullTimeoutAnnotation: "45m"})
+	_, stall = getPrecacheWaitLimits(ibu)
+	assert.Equal(t, 90*time.Minute, stall)
+	ibu.SetAnnotations(map[string]string{utils.PrecachePullTimeoutAnnotation: "invalid"})
+	_, stall = getPrecacheWaitLimits(ibu)
+	assert.Equal(t, 2*precache.DefaultPullTimeout, stall)
+
+	ibu.Spec.Precache = &lcav1alpha1.PrecacheConfig{TimeoutMinutes: 120, StallMinutes: 20}
+	timeout, stall = getPrecacheWaitLimits(ibu)
+	assert.Equal(t, 2*time.Hour, timeout)
+	assert.Equal(t, 20*time.Minute, stall)
+}
+
+func TestPrecacheWatchdog(t *testing.T) {
+	start := time.Now()
+	status := func(pulled int, pulledBytes int64, blocked string) *precache.Status {
+		return &precache.Status{Status: precache.Active, Blocked: blocked,
+			Progress: precache.Progress{Total: 10, Pulled: pulled, PulledBytes: pulledBytes}}
+	}
+
+	watchdog := newPrecacheWatchdog(0, 10*time.Minute, start)
+	assert.NoError(t, watchdog.check(status(1, 100, ""), start.Add(time.Minute)))
+	assert.NoError(t, watchdog.check(status(1, 100, ""), start.Add(10*time.Minute)))
+	// A new image processed restarts the stall window
+	assert.NoError(t, watchdog.check(status(2, 200, ""), start.Add(11*time.Minute)))
+	assert.NoError(t, watchdog.check(status(2, 200, ""), start.Add(20*time.Minute)))
+	// The time blocked does not count as a stall
+	assert.NoError(t, watchdog.check(status(2, 200, "0/1 nodes are available"), start.Add(30*time.Minute)))
+	assert.NoError(t, watchdog.check(status(2, 200, ""), start.Add(39*time.Minute)))
+	// The status could not be queried
+	assert.NoError(t, watchdog.check(nil, start.Add(40*time.Minute)))
+	err := watchdog.check(status(2, 200, ""), start.Add(41*time.Minute))
+	assert.True(t, errors.Is(err, lcaerrors.ErrPrecacheStalled))
+	assert.EqualError(t, err, "precaching made no progress for 10m0s, 2/10 images processed")
+	// The classification is kept by the Prep failure
+	assert.Equal(t, utils.ConditionReasons.PrecacheStalled,
+		utils.ReasonForError(lcaerrors.Wrap(lcaerrors.ErrPrecache, fmt.Errorf("failed to precache images: %w", err))))
+
+	// The timeout applies whatever the progress
+	watchdog = newPrecacheWatchdog(time.Hour, 0, start)
+	assert.NoError(t, watchdog.check(status(1, 100, "0/1 nodes are available"), start.Add(time.Hour)))
+	err = watchdog.check(status(1, 100, "0/1 nodes are available"), start.Add(61*time.Minute))
+	assert.True(t, errors.Is(err, lcaerrors.ErrPrecacheTimeout))
+	assert.EqualError(t, err, "precaching did not complete within 1h0m0s, 1/10 images processed")
+	err = watchdog.check(nil, start.Add(2*time.Hour))
+	assert.EqualError(t, err, "precaching did not complete within 1h0m0s")
+}
+
+func TestGetPrecacheFilter(t *testing.T) {
+	filter, err := getPrecacheFilter(nil, "quay.io/edge-infrastructure/recert:v0")
+	assert.NoError(t, err)
+	assert.Nil(t, filter)
+
+	filter, err = getPrecacheFilter(&lcav1alpha1.PrecacheConfig{ExcludeImages: []string{"/odf4/", "recert"}},
+		"quay.io/edge-infrastructure/recert:v0")
+	assert.NoError(t, err)
+	assert.False(t, filter.Match("registry.redhat.io/odf4/odf-operator-bundle@sha256:bbb"))
+	assert.True(t, filter.Match("quay.io/edge-infrastructure/recert:v0"))
+
+	_, err = getPrecacheFilter(&lcav1alpha1.PrecacheConfig{IncludeImages: []string{"[a-"}}, "")
+	assert.ErrorIs(t, err, lcaerrors.ErrInvalidSpec)
+}
+
+func TestValidatePrecacheMode(t *testing.T) {
+	ibu := &lcav1alpha1.ImageBasedUpgrade{}
+	assert.NoError(t, validatePrecacheMode(ibu))
+	assert.False(t, isPrecacheInProcess(ibu))
+
+	ibu.SetAnnotations(map[string]string{utils.PrecacheModeAnnotation: utils.PrecacheModeInProcess})
+	assert.NoError(t, validatePrecacheMode(ibu))
+	assert.True(t, isPrecacheInProcess(ibu))
+
+	ibu.SetAnnotations(map[string]string{utils.PrecacheModeAnnotation: "Pod"})
+	assert.ErrorContains(t, validatePrecacheMode(ibu), `invalid lca.openshift.io/precacheMode annotation "Pod"`)
+
+	// The options of the job pod do not apply in the manager process
+	ibu.SetAnnotations(map[string]string{
+		utils.PrecacheModeAnnotation:           utils.PrecacheModeInProcess,
+		utils.RestrictPrecacheEgressAnnotation: "true",
+	})
+	ibu.Spec.Precache = &lcav1alpha1.PrecacheConfig{Resources: &corev1.ResourceRequirements{}}
+	assert.ErrorContains(t, validatePrecacheMode(ibu),
+		"lca.openshift.io/restrictPrecacheEgress, spec.precache.resources only apply to the precaching job")
+
+	ibu.SetAnnotations(map[string]string{
+		utils.PrecacheModeAnnotation:           utils.PrecacheModeJob,
+		utils.RestrictPrecacheEgressAnnotation: "true",
+	})
+	assert.NoError(t, validatePrecacheMode(ibu))
+}
+
+func TestGetAdditionalImages(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cnf-images", Namespace: "openshift-lifecycle-agent"},
+			Data: map[string]string{
+				"du.txt":    "# vDU images\nregistry.example.com/du/l1:v2\n\nregistry.example.com/du/l2:v2\n",
+				"cu.txt":    "registry.example.com/cu/cu:v1\nregistry.example.com/du/l1:v2\n",
+				"empty.txt": "",
+			},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "openshift-lifecycle-agent"},
+			Data:       map[string]string{"images": "registry.example.com/du/l1:v2 registry.example.com/du/l2:v2"},
+		},
+	).Build()
+	r := &ImageBasedUpgradeReconciler{Client: c, Log: logr.Discard()}
+	ibu := &lcav1alpha1.ImageBasedUpgrade{}
+
+	images, err := r.getAdditionalImages(context.Background(), ibu)
+	assert.NoError(t, err)
+	assert.Empty(t, images)
+
+	ibu.Spec.AdditionalImages = lcav1alpha1.ConfigMapRef{Name: "cnf-images", Namespace: "openshift-lifecycle-agent"}
+	images, err = r.getAdditionalImages(context.Background(), ibu)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"registry.example.com/cu/cu:v1", "registry.example.com/du/l1:v2",
+		"registry.example.com/du/l2:v2"}, images)
+
+	ibu.Spec.AdditionalImages.Name = "invalid"
+	_, err = r.getAdditionalImages(context.Background(), ibu)
+	assert.ErrorIs(t, err, lcaerrors.ErrInvalidSpec)
+	assert.ErrorContains(t, err, "expecting one image per line")
+
+	ibu.Spec.AdditionalImages.Name = "missing"
+	_, err = r.getAdditionalImages(context.Background(), ibu)
+	assert.True(t, isInvalidSpec(err))
+	assert.ErrorContains(t, err, "additional images configMap openshift-lifecycle-agent/missing not found")
+}
+
+func TestUpdatePrecachingProgress(t *testing.T) {
+	eta := time.Date(2024, 1, 19, 6, 40, 0, 500, time.UTC)
+	progress := &precache.Progress{Total: 311, Pulled: 120, Skipped: 20, Failed: 3, PulledBytes: 4 << 30}
+	assert.Equal(t, "Precaching: 143/311 images (45%), estimated completion at 2024-01-19T06:40:00Z; total: 311 (pulled: 120, skipped: 20, failed: 3)",
+		precachingProgressMessage(progress, &eta, progress.Summary()))
+	assert.Equal(t, &lcav1alpha1.PrecacheProgress{Total: 311, Pulled: 120, Skipped: 20, Failed: 3, PulledBytes: 4 << 30,
+		Percent: 45, EstimatedCompletionTime: &metav1.Time{Time: eta.Truncate(time.Second)}}, precacheProgress(progress, &eta))
+
+	// The images that would be pulled are reported as pulled when only verified
+	verified := &precache.Progress{Total: 4, Skipped: 1, WouldPull: 2, WouldPullBytes: 3000, VerifyOnly: true}
+	assert.Equal(t, &lcav1alpha1.PrecacheProgress{Total: 4, Pulled: 2, Skipped: 1, PulledBytes: 3000, Percent: 75},
+		precacheProgress(verified, nil))
+
+	// No estimate once the job completed
+	r := &ImageBasedUpgradeReconciler{Log: logr.Discard(), PrepTask: &Task{}}
+	r.updatePrecachingProgress(&precache.Status{Status: precache.Succeeded, Progress: precache.Progress{Total: 2, Pulled: 2},
+		Message: "total: 2 (pulled: 2, skipped: 0, failed: 0)", StartTime: eta.Add(-time.Hour)})
+	assert.Equal(t, "Precaching: 2/2 images (100%); total: 2 (pulled: 2, skipped: 0, failed: 0)", r.PrepTask.Progress)
+	assert.Equal(t, &lcav1alpha1.PrecacheProgress{Total: 2, Pulled: 2, Percent: 100}, r.PrepTask.PrecacheProgress)
+
+	// The progress is only known once reported by the job
+	r = &ImageBasedUpgradeReconciler{Log: logr.Discard(), PrepTask: &Task{Progress: "Precaching started"}}
+	r.updatePrecachingProgress(&precache.Status{Status: precache.Active})
+	assert.Equal(t, "Precaching started", r.PrepTask.Progress)
+	assert.Nil(t, r.PrepTask.PrecacheProgress)
+}
@@ -18,12 +18,23 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/backuprestore"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/etcdbackup"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
 	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
@@ -33,32 +44,55 @@ import (
 func (r *ImageBasedUpgradeReconciler) startRollback(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, error) {
 	utils.SetRollbackStatusInProgress(ibu, "Initiating rollback")
 
-	stateroot, err := r.RPMOstreeClient.GetUnbootedStaterootName()
+	stateroot, err := r.RPMOstreeClient.GetUnbootedStaterootName(ctx)
 	if err != nil {
-		utils.SetRollbackStatusFailed(ibu, err.Error())
+		utils.SetRollbackStatusFailedWithError(ibu, err)
 		return doNotRequeue(), nil
 	}
 
-	if err := r.Ops.RemountSysroot(); err != nil {
-		utils.SetRollbackStatusFailed(ibu, err.Error())
+	if err := r.Ops.RemountSysroot(ctx); err != nil {
+		utils.SetRollbackStatusFailedWithError(ibu, err)
 		return doNotRequeue(), nil
 	}
 
 	r.Log.Info("Finding unbooted deployment")
-	deploymentIndex, err := r.RPMOstreeClient.GetUnbootedDeploymentIndex()
+	deploymentIndex, err := r.RPMOstreeClient.GetUnbootedDeploymentIndex(ctx)
 	if err != nil {
-		utils.SetRollbackStatusFailed(ibu, err.Error())
+		utils.SetRollbackStatusFailedWithError(ibu, err)
 		return doNotRequeue(), nil
 	}
 
 	// Set the new default deployment
 	r.Log.Info("Checking for set-default feature")
+	setDefaultEnabled := r.OstreeClient.IsOstreeAdminSetDefaultFeatureEnabled(ctx)
 
-	if r.OstreeClient.IsOstreeAdminSetDefaultFeatureEnabled() {
+	// Publish what the rollback changes before performing it
+	plan, err := r.computeRollbackPlan(ctx, ibu, stateroot, deploymentIndex, setDefaultEnabled)
+	if err != nil {
+		utils.SetRollbackStatusInProgress(ibu, fmt.Sprintf("Retrying rollback plan: %s", err))
+		return requeueWithError(fmt.Errorf("failed to compute rollback plan: %w", err))
+	}
+	ibu.Status.RollbackPlan = plan
+	if ibu.GetAnnotations()[utils.RollbackDryRunAnnotation] == "true" {
+		msg := fmt.Sprintf("Rollback dry-run, the plan is in the status. Remove the %s annotation to roll back",
+			utils.RollbackDryRunAnnotation)
+		r.Log.Info(msg)
+		utils.SetRollbackStatusInProgress(ibu, msg)
+		return doNotRequeue(), nil
+	}
+	if plan.ConfirmationRequired {
+		msg := fmt.Sprintf("Rollback awaiting confirmation, the following data is not rolled back: %s. Set the %s annotation to \"true\" to roll back",
+			strings.Join(plan.NotRolledBack, "; "), utils.ConfirmRollbackAnnotation)
+		r.Log.Info(msg)
+		utils.SetRollbackStatusInProgress(ibu, msg)
+		return doNotRequeue(), nil
+	}
+
+	if setDefaultEnabled {
 		r.Log.Info("set-default feature available")
 
-		if err = r.OstreeClient.SetDefaultDeployment(deploymentIndex); err != nil {
-			utils.SetRollbackStatusFailed(ibu, err.Error())
+		if err = r.OstreeClient.SetDefaultDeployment(ctx, deploymentIndex); err != nil {
+			utils.SetRollbackStatusFailedWithError(ibu, lcaerrors.Wrap(lcaerrors.ErrOstreeDeploy, err))
 			return doNotRequeue(), nil
 		}
 	} else {
@@ -75,29 +109,101 @@ func (r *ImageBasedUpgradeReconciler) startRollback(ctx context.Context, ibu *lc
 
 	// Update in-progress message
 	utils.SetRollbackStatusInProgress(ibu, "Completing rollback")
-	_ = utils.UpdateIBUStatus(ctx, r.Client, ibu)
+	_ = utils.UpdateIBUStatusBatched(ctx, r.Client, r.StatusBatcher, ibu)
 
 	// Save the CR for post-reboot restore
 	r.Log.Info("Save the IBU CR to the old state root before pivot")
-	filePath := common.PathOutsideChroot(filepath.Join(common.GetStaterootPath(stateroot), utils.IBUFilePath))
+	filePath := common.PathOutsideChroot(filepath.Join(r.StaterootLayout.Path(stateroot), utils.IBUFilePath))
 	if err := lcautils.MarshalToFile(ibu, filePath); err != nil {
-		utils.SetRollbackStatusFailed(ibu, err.Error())
+		utils.SetRollbackStatusFailedWithError(ibu, err)
+		return doNotRequeue(), nil
+	}
+	summaryPath := common.PathOutsideChroot(filepath.Join(r.StaterootLayout.Path(stateroot), utils.UpgradeSummaryFile))
+	if err := lcautils.CopyFileIfExists(common.PathOutsideChroot(utils.UpgradeSummaryFile), summaryPath); err != nil {
+		utils.SetRollbackStatusFailedWithError(ibu, err)
 		return doNotRequeue(), nil
 	}
 
 	// Write an event to indicate reboot attempt
 	r.Recorder.Event(ibu, corev1.EventTypeNormal, "Reboot", "System will now reboot for rollback")
-	err = r.RebootClient.RebootToNewStateRoot("rollback")
+	err = r.RebootClient.RebootToNewStateRoot(ctx, "rollback")
 	if err != nil {
 		//todo: abort handler? e.g delete desired stateroot
 		r.Log.Error(err, "")
-		utils.SetUpgradeStatusFailed(ibu, err.Error())
+		utils.SetUpgradeStatusFailedWithError(ibu, lcaerrors.Wrap(lcaerrors.ErrReboot, err))
 		return doNotRequeue(), nil
 	}
 
 	return doNotRequeue(), nil
 }
 
+// computeRollbackPlan lists what the rollback to the stateroot changes, and the data it does not roll back. The
+// rollback requires a confirmation when OADP restored persistent volume data after the pivot, as that data is kept
+// as is by the rollback and may not be usable by the workloads of the original release.
+func (r *ImageBasedUpgradeReconciler) computeRollbackPlan(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade,
+	stateroot string, deploymentIndex int, setDefaultEnabled bool) (*lcav1alpha1.RollbackPlan, error) {
+	plan := &lcav1alpha1.RollbackPlan{Stateroot: stateroot, DeploymentIndex: deploymentIndex}
+
+	switch {
+	case setDefaultEnabled:
+		plan.Actions = append(plan.Actions, fmt.Sprintf("Set deployment %d of stateroot %s as the default deployment", deploymentIndex, stateroot))
+	case deploymentIndex != 0:
+		plan.Actions = append(plan.Actions, fmt.Sprintf("Wait for deployment %d of stateroot %s to be manually set as the default deployment", deploymentIndex, stateroot))
+	}
+	plan.Actions = append(plan.Actions,
+		fmt.Sprintf("Save the IBU CR in stateroot %s", stateroot),
+		fmt.Sprintf("Reboot to stateroot %s, restoring its kubelet, CRI-O and etcd with the cluster state as of the pivot", stateroot),
+		fmt.Sprintf("Restore the cluster certificates of stateroot %s, the kubelet certificates expired since the pivot being renewed through CSRs", stateroot),
+	)
+
+	plan.NotRolledBack = append(plan.NotRolledBack,
+		"Persistent volume data written since the pivot",
+		"Data of the partitions shared between the stateroots, e.g. /var/lib/containers on a separate partition",
+	)
+	restores := &velerov1.RestoreList{}
+	if err := r.Client.List(ctx, restores, client.InNamespace(backuprestore.OadpNs)); err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("failed to list OADP restores: %w", err)
+	}
+	for i := range restores.Items {
+		restore := &restores.Items[i]
+		if restoresVolumeData(restore) {
+			plan.NotRolledBack = append(plan.NotRolledBack,
+				fmt.Sprintf("Persistent volume data restored by Restore %s/%s", restore.Namespace, restore.Name))
+			plan.ConfirmationRequired = ibu.GetAnnotations()[utils.ConfirmRollbackAnnotation] != "true"
+		}
+	}
+
+	// The etcd backup taken before the pivot, if enabled, is the last resort should the rollback fail
+	metadata, err := VerifyEtcdBackup(etcdbackup.BackupDir)
+	switch {
+	case err == nil:
+		plan.EtcdBackup = fmt.Sprintf("Snapshot %s/%s taken at %s, verified", etcdbackup.BackupDir, metadata.Snapshot,
+			metadata.CreatedAt.Format(time.RFC3339))
+	case !errors.Is(err, os.ErrNotExist):
+		r.Log.Error(err, "Etcd backup taken before pivot is not usable")
+		plan.EtcdBackup = fmt.Sprintf("Not usable: %s", err)
+	}
+
+	return plan, nil
+}
+
+// restoresVolumeData returns whether the restore brings back persistent volume data
+func restoresVolumeData(restore *velerov1.Restore) bool {
+	if restore.Spec.RestorePVs != nil && !*restore.Spec.RestorePVs {
+		return false
+	}
+	if len(restore.Spec.IncludedResources) == 0 {
+		return true
+	}
+	for _, resource := range restore.Spec.IncludedResources {
+		switch strings.ToLower(resource) {
+		case "*", "persistentvolumes", "persistentvolumeclaims", "pv", "pvc":
+			return true
+		}
+	}
+	return false
+}
+
 //nolint:unparam
 func (r *ImageBasedUpgradeReconciler) finishRollback(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, error) {
 	utils.SetRollbackStatusCompleted(ibu)
@@ -107,10 +213,10 @@ func (r *ImageBasedUpgradeReconciler) finishRollback(ctx context.Context, ibu *l
 
 //nolint:unparam
 func (r *ImageBasedUpgradeReconciler) handleRollback(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, error) {
-	origStaterootBooted, err := r.RebootClient.IsOrigStaterootBooted(ibu)
+	origStaterootBooted, err := r.RebootClient.IsOrigStaterootBooted(ctx, ibu)
 	if err != nil {
 		//todo: abort handler? e.g delete desired stateroot
-		utils.SetRollbackStatusFailed(ibu, err.Error())
+		utils.SetRollbackStatusFailedWithError(ibu, err)
 		return doNotRequeue(), nil
 	}
 
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/backuprestore"
+	"github.com/openshift-kni/lifecycle-agent/internal/etcdbackup"
+	"github.com/stretchr/testify/assert"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestComputeRollbackPlan(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, velerov1.AddToScheme(s))
+
+	falseValue := false
+	testcases := []struct {
+		name                 string
+		restores             []client.Object
+		annotations          map[string]string
+		setDefaultEnabled    bool
+		expectedActions      int
+		expectedNotRolled    int
+		confirmationRequired bool
+	}{
+		{
+			name:              "no restores",
+			setDefaultEnabled: true,
+			expectedActions:   4,
+			expectedNotRolled: 2,
+		},
+		{
+			name: "restore of all resources",
+			restores: []client.Object{
+				&velerov1.Restore{ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: backuprestore.OadpNs}},
+			},
+			setDefaultEnabled:    true,
+			expectedActions:      4,
+			expectedNotRolled:    3,
+			confirmationRequired: true,
+		},
+		{
+			name: "restore of all resources confirmed",
+			restores: []client.Object{
+				&velerov1.Restore{ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: backuprestore.OadpNs}},
+			},
+			annotations:       map[string]string{utils.ConfirmRollbackAnnotation: "true"},
+			setDefaultEnabled: true,
+			expectedActions:   4,
+			expectedNotRolled: 3,
+		},
+		{
+			name: "restore without volumes",
+			restores: []client.Object{
+				&velerov1.Restore{
+					ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: backuprestore.OadpNs},
+					Spec:       velerov1.RestoreSpec{RestorePVs: &falseValue},
+				},
+				&velerov1.Restore{
+					ObjectMeta: metav1.ObjectMeta{Name: "configs", Namespace: backuprestore.OadpNs},
+					Spec:       velerov1.RestoreSpec{IncludedResources: []string{"configmaps", "secrets"}},
+				},
+			},
+			setDefaultEnabled: true,
+			expectedActions:   4,
+			expectedNotRolled: 2,
+		},
+		{
+			name: "restore of volume claims without set-default",
+			restores: []client.Object{
+				&velerov1.Restore{
+					ObjectMeta: metav1.ObjectMeta{Name: "pvcs", Namespace: backuprestore.OadpNs},
+					Spec:       velerov1.RestoreSpec{IncludedResources: []string{"PersistentVolumeClaims"}},
+				},
+			},
+			expectedActions:      4,
+			expectedNotRolled:    3,
+			confirmationRequired: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &ImageBasedUpgradeReconciler{
+				Client: fake.NewClientBuilder().WithScheme(s).WithObjects(tc.restores...).Build(),
+				Log:    logr.Discard(),
+			}
+			ibu := &lcav1alpha1.ImageBasedUpgrade{
+				ObjectMeta: metav1.ObjectMeta{Name: utils.IBUName, Annotations: tc.annotations},
+			}
+
+			plan, err := r.computeRollbackPlan(context.Background(), ibu, "rhcos_4.15.0", 1, tc.setDefaultEnabled)
+			assert.NoError(t, err)
+			assert.Equal(t, "rhcos_4.15.0", plan.Stateroot)
+			assert.Equal(t, 1, plan.DeploymentIndex)
+			assert.Len(t, plan.Actions, tc.expectedActions)
+			assert.Len(t, plan.NotRolledBack, tc.expectedNotRolled)
+			assert.Equal(t, tc.confirmationRequired, plan.ConfirmationRequired)
+			assert.Empty(t, plan.EtcdBackup)
+		})
+	}
+}
+
+func TestComputeRollbackPlanEtcdBackup(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, velerov1.AddToScheme(s))
+	r := &ImageBasedUpgradeReconciler{
+		Client: fake.NewClientBuilder().WithScheme(s).Build(),
+		Log:    logr.Discard(),
+	}
+	ibu := &lcav1alpha1.ImageBasedUpgrade{ObjectMeta: metav1.ObjectMeta{Name: utils.IBUName}}
+
+	origVerifyEtcdBackup := VerifyEtcdBackup
+	defer func() {
+		VerifyEtcdBackup = origVerifyEtcdBackup
+	}()
+
+	VerifyEtcdBackup = func(dir string) (*etcdbackup.Metadata, error) {
+		return &etcdbackup.Metadata{Snapshot: "snapshot_2024-01-10_120000.db",
+			CreatedAt: time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)}, nil
+	}
+	plan, err := r.computeRollbackPlan(context.Background(), ibu, "rhcos_4.15.0", 0, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "Snapshot /var/lib/containers/lca/etcd-backup/snapshot_2024-01-10_120000.db taken at "+
+		"2024-01-10T12:00:00Z, verified", plan.EtcdBackup)
+
+	VerifyEtcdBackup = func(dir string) (*etcdbackup.Metadata, error) {
+		return nil, errors.New("etcd snapshot snapshot_2024-01-10_120000.db is corrupted, integrity hash mismatch")
+	}
+	plan, err = r.computeRollbackPlan(context.Background(), ibu, "rhcos_4.15.0", 0, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "Not usable: etcd snapshot snapshot_2024-01-10_120000.db is corrupted, integrity hash mismatch",
+		plan.EtcdBackup)
+}
@@ -30,11 +30,13 @@ import (
 	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
 	"github.com/openshift-kni/lifecycle-agent/internal/healthcheck"
+	"github.com/openshift-kni/lifecycle-agent/internal/mcodrift"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 	commonUtils "github.com/openshift-kni/lifecycle-agent/utils"
 	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
 
 	"github.com/go-logr/logr"
+	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -480,6 +482,9 @@ func (r *SeedGeneratorReconciler) launchLCACli(seedgen *seedgenv1alpha1.SeedGene
 	if skipRecert {
 		lcaCliCmdArgs = append(lcaCliCmdArgs, "--skip-recert-validation")
 	}
+	if seedgen.Spec.SBOMFormat != "" {
+		lcaCliCmdArgs = append(lcaCliCmdArgs, "--sbom-format", strings.ToLower(seedgen.Spec.SBOMFormat))
+	}
 
 	// In order to have the lca-cli container both survive the LCA pod shutdown and have continued network access
 	// after all other pods are shutdown, we're using systemd-run to launch it as a transient service-unit
@@ -560,6 +565,32 @@ func (r *SeedGeneratorReconciler) validateSystem(ctx context.Context) (msg strin
 	return
 }
 
+// checkMachineConfigDrift lists the MachineConfig-managed files modified locally in the status, as they would be
+// baked into the seed and propagated to every upgraded cluster, and rejects the seed generation with the Block policy
+func (r *SeedGeneratorReconciler) checkMachineConfigDrift(seedgen *seedgenv1alpha1.SeedGenerator) (msg string) {
+	block := seedgen.Spec.MachineConfigDriftPolicy == seedgenv1alpha1.MachineConfigDriftPolicies.Block
+
+	drifts, err := mcodrift.Detect(common.Host)
+	if err != nil {
+		r.Log.Error(err, "Unable to check for MachineConfig-managed files modified locally")
+		if block {
+			msg = fmt.Sprintf("Rejected due to failure checking for MachineConfig drift: %s", err)
+		}
+		return
+	}
+
+	seedgen.Status.MachineConfigDrift = lo.Map(drifts, func(d mcodrift.Drift, _ int) string { return d.String() })
+	if len(drifts) == 0 {
+		return
+	}
+	r.Log.Info("Found MachineConfig-managed files modified locally", "files", seedgen.Status.MachineConfigDrift)
+	if block {
+		msg = fmt.Sprintf("Rejected due to MachineConfig-managed files modified locally: %s",
+			strings.Join(seedgen.Status.MachineConfigDrift, ", "))
+	}
+	return
+}
+
 func (r *SeedGeneratorReconciler) restoreSeedgenCRIfNeeded(ctx context.Context, seedgen *seedgenv1alpha1.SeedGenerator) error {
 	r.Log.Info("Restoring seedgen CR in DB")
 
@@ -878,6 +909,16 @@ func (r *SeedGeneratorReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 
 	if firstReconcile(seedgen) {
+		if rejection := r.checkMachineConfigDrift(seedgen); len(rejection) > 0 {
+			setSeedGenStatusFailed(seedgen, rejection)
+			r.Log.Info(fmt.Sprintf("Seed generation rejected: %s", rejection))
+
+			if err = r.updateStatus(ctx, seedgen); err != nil {
+				r.Log.Error(err, "Failed to update status")
+			}
+			return
+		}
+
 		setSeedGenStatusInProgress(seedgen)
 		if err = r.updateStatus(ctx, seedgen); err != nil {
 			rc = fmt.Errorf("failed to update status: %w", err)
@@ -0,0 +1,130 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	seedgenv1alpha1 "github.com/openshift-kni/lifecycle-agent/api/seedgenerator/v1alpha1"
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+//+kubebuilder:webhook:path=/validate-lca-openshift-io-v1alpha1-seedgenerator,mutating=false,failurePolicy=ignore,sideEffects=None,groups=lca.openshift.io,resources=seedgenerators,verbs=create,versions=v1alpha1,name=vseedgenerator.lca.openshift.io,admissionReviewVersions=v1,timeoutSeconds=30
+
+// seedGenPushCheckTimeout bounds the push credentials check against the destination registry
+const seedGenPushCheckTimeout = 20 * time.Second
+
+// SeedGeneratorValidator rejects the SeedGenerator CRs that would only fail after the seed build: an invalid
+// destination reference, push credentials rejected by the destination registry, or a cluster in the middle of an IBU.
+// The webhook ignores its failures, as LCA itself restores the CR while stopped for the seed generation, and the
+// controller still validates the system before generating the seed.
+type SeedGeneratorValidator struct {
+	client.Reader
+	Log        logr.Logger
+	HTTPClient *http.Client
+	// ServiceAccount is the username of LCA, whose restores of the CR are not validated
+	ServiceAccount string
+}
+
+// SetupWebhookWithManager registers the validating webhook of the SeedGenerator CR with the manager
+func (v *SeedGeneratorValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&seedgenv1alpha1.SeedGenerator{}).
+		WithValidator(v).
+		Complete(); err != nil {
+		return fmt.Errorf("failed to setup SeedGenerator webhook: %w", err)
+	}
+	return nil
+}
+
+// ValidateCreate validates the destination and credentials of the seed image, and that no IBU is in progress
+func (v *SeedGeneratorValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	seedgen, ok := obj.(*seedgenv1alpha1.SeedGenerator)
+	if !ok {
+		return nil, fmt.Errorf("expected a SeedGenerator but got a %T", obj)
+	}
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.UserInfo.Username == v.ServiceAccount {
+		return nil, nil
+	}
+
+	if err := lcautils.ValidateImageReference(seedgen.Spec.SeedImage); err != nil {
+		return nil, fmt.Errorf("invalid spec.seedImage: %w", err)
+	}
+	if seedgen.Spec.RecertImage != "" {
+		if err := lcautils.ValidateImageReference(seedgen.Spec.RecertImage); err != nil {
+			return nil, fmt.Errorf("invalid spec.recertImage: %w", err)
+		}
+	}
+
+	ibu := &lcav1alpha1.ImageBasedUpgrade{}
+	if err := v.Get(ctx, types.NamespacedName{Name: utils.IBUName}, ibu); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get IBU CR: %w", err)
+		}
+	} else if ibu.Spec.Stage != "" && ibu.Spec.Stage != lcav1alpha1.Stages.Idle {
+		return nil, fmt.Errorf("an image based upgrade is in progress in stage %s, the seed cannot be generated until it is back to Idle",
+			ibu.Spec.Stage)
+	}
+
+	secret := &corev1.Secret{}
+	if err := v.Get(ctx, types.NamespacedName{Name: utils.SeedGenSecretName, Namespace: common.LcaNamespace}, secret); err != nil {
+		return nil, fmt.Errorf("could not access secret %s in %s: %w", utils.SeedGenSecretName, common.LcaNamespace, err)
+	}
+	seedAuth, exists := secret.Data["seedAuth"]
+	if !exists {
+		return nil, fmt.Errorf("could not find seedAuth in %s secret", utils.SeedGenSecretName)
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, seedGenPushCheckTimeout)
+	defer cancel()
+	if err := lcautils.CheckPushAccess(checkCtx, v.HTTPClient, seedgen.Spec.SeedImage, seedAuth); err != nil {
+		if errors.Is(err, lcautils.ErrPushDenied) {
+			return nil, fmt.Errorf("seedAuth of the %s secret cannot push %s: %w", utils.SeedGenSecretName,
+				seedgen.Spec.SeedImage, err)
+		}
+		// the registry may only be reachable through a proxy or CA unknown to the webhook, the push decides then
+		v.Log.Info("Unable to check the push credentials of the seed image", "image", seedgen.Spec.SeedImage, "error", err.Error())
+		return admission.Warnings{fmt.Sprintf("unable to check the push credentials for %s: %s", seedgen.Spec.SeedImage, err)}, nil
+	}
+	return nil, nil
+}
+
+// ValidateUpdate accepts the updates, as the spec is immutable
+func (v *SeedGeneratorValidator) ValidateUpdate(_ context.Context, _, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateDelete accepts the deletions
+func (v *SeedGeneratorValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	seedgenv1alpha1 "github.com/openshift-kni/lifecycle-agent/api/seedgenerator/v1alpha1"
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+func TestSeedGeneratorValidator_ValidateCreate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	seedAuth := func(password string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: utils.SeedGenSecretName, Namespace: common.LcaNamespace},
+			Data: map[string][]byte{"seedAuth": []byte(`{"auths":{"` + registry + `":{"username":"user","password":"` +
+				password + `"}}}`)},
+		}
+	}
+	ibu := func(stage lcav1alpha1.ImageBasedUpgradeStage) *lcav1alpha1.ImageBasedUpgrade {
+		return &lcav1alpha1.ImageBasedUpgrade{
+			ObjectMeta: metav1.ObjectMeta{Name: utils.IBUName},
+			Spec:       lcav1alpha1.ImageBasedUpgradeSpec{Stage: stage},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		seedImage string
+		objects   []client.Object
+		username  string
+		wantErr   string
+	}{
+		{
+			name:      "valid",
+			seedImage: registry + "/org/seed:4.15.0",
+			objects:   []client.Object{ibu(lcav1alpha1.Stages.Idle), seedAuth("secret")},
+		},
+		{
+			name:      "invalid reference",
+			seedImage: "seed:4.15.0",
+			objects:   []client.Object{ibu(lcav1alpha1.Stages.Idle), seedAuth("secret")},
+			wantErr:   "invalid spec.seedImage",
+		},
+		{
+			name:      "ibu in progress",
+			seedImage: registry + "/org/seed:4.15.0",
+			objects:   []client.Object{ibu(lcav1alpha1.Stages.Prep), seedAuth("secret")},
+			wantErr:   "an image based upgrade is in progress in stage Prep",
+		},
+		{
+			name:      "missing secret",
+			seedImage: registry + "/org/seed:4.15.0",
+			objects:   []client.Object{ibu(lcav1alpha1.Stages.Idle)},
+			wantErr:   "could not access secret seedgen",
+		},
+		{
+			name:      "push denied",
+			seedImage: registry + "/org/seed:4.15.0",
+			objects:   []client.Object{ibu(lcav1alpha1.Stages.Idle), seedAuth("wrong")},
+			wantErr:   "seedAuth of the seedgen secret cannot push",
+		},
+		{
+			name:      "restored by lca",
+			seedImage: registry + "/org/seed:4.15.0",
+			username:  "system:serviceaccount:openshift-lifecycle-agent:lifecycle-agent-controller-manager",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := getFakeClientFromObjects(tt.objects...)
+			assert.NoError(t, err)
+			v := &SeedGeneratorValidator{
+				Reader:         c,
+				Log:            logr.Discard(),
+				HTTPClient:     server.Client(),
+				ServiceAccount: "system:serviceaccount:openshift-lifecycle-agent:lifecycle-agent-controller-manager",
+			}
+			ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: tt.username}},
+			})
+			seedgen := &seedgenv1alpha1.SeedGenerator{
+				ObjectMeta: metav1.ObjectMeta{Name: utils.SeedGenName},
+				Spec:       seedgenv1alpha1.SeedGeneratorSpec{SeedImage: tt.seedImage},
+			}
+			warnings, err := v.ValidateCreate(ctx, seedgen)
+			assert.Empty(t, warnings)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -22,21 +22,33 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/openshift-kni/lifecycle-agent/api/seedreconfig"
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
 	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
 	"github.com/openshift-kni/lifecycle-agent/internal/backuprestore"
+	"github.com/openshift-kni/lifecycle-agent/internal/bootmetrics"
 	"github.com/openshift-kni/lifecycle-agent/internal/clusterconfig"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/etcdbackup"
 	"github.com/openshift-kni/lifecycle-agent/internal/extramanifest"
 	"github.com/openshift-kni/lifecycle-agent/internal/healthcheck"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/internal/nodeactivity"
 	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
+	"github.com/openshift-kni/lifecycle-agent/internal/precache"
+	"github.com/openshift-kni/lifecycle-agent/internal/prepinputs"
 	"github.com/openshift-kni/lifecycle-agent/internal/reboot"
+	"github.com/openshift-kni/lifecycle-agent/internal/systemdunits"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 	rpmostreeclient "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedpackages"
 	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -62,6 +74,12 @@ type (
 		RPMOstreeClient rpmostreeclient.IClient
 		OstreeClient    ostreeclient.IClient
 		RebootClient    reboot.RebootIntf
+		StaterootLayout common.StaterootLayout
+		StatusBatcher   *utils.StatusBatcher
+
+		// activitySampler and pivotGateErrors measure the node activity across the requeues of the pivot gate
+		activitySampler nodeactivity.Sampler
+		pivotGateErrors int
 	}
 )
 
@@ -71,11 +89,11 @@ const TargetOcpVersionLabel = "lca.openshift.io/target-ocp-version"
 func (r *ImageBasedUpgradeReconciler) handleUpgrade(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, error) {
 	r.Log.Info("Starting handleUpgrade")
 
-	origStaterootBooted, err := r.RebootClient.IsOrigStaterootBooted(ibu)
+	origStaterootBooted, err := r.RebootClient.IsOrigStaterootBooted(ctx, ibu)
 
 	if err != nil {
 		//todo: abort handler? e.g delete desired stateroot
-		utils.SetUpgradeStatusFailed(ibu, err.Error())
+		utils.SetUpgradeStatusFailedWithError(ibu, err)
 		return doNotRequeue(), nil
 	}
 
@@ -100,7 +118,7 @@ func (r *ImageBasedUpgradeReconciler) handleUpgrade(ctx context.Context, ibu *lc
 func (u *UpgHandler) resetProgressMessage(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) {
 	// Clear any error status that may have been set
 	utils.SetUpgradeStatusInProgress(ibu, "In progress")
-	_ = utils.UpdateIBUStatus(ctx, u.Client, ibu)
+	_ = utils.UpdateIBUStatusBatched(ctx, u.Client, u.StatusBatcher, ibu)
 }
 
 // prePivot executes all the pre-upgrade steps and initiates a cluster reboot.
@@ -113,6 +131,22 @@ func (u *UpgHandler) PrePivot(ctx context.Context, ibu *lcav1alpha1.ImageBasedUp
 		u.resetProgressMessage(ctx, ibu)
 	}
 
+	u.Log.Info("Checking certificates before pivot")
+	if err := CheckCertificates(ctx, u.Client, u.Log); err != nil {
+		// Block the upgrade until the certificates are ready, the condition message carries the remediation hint
+		u.Log.Info("Upgrade blocked", "reason", err.Error())
+		utils.SetUpgradeStatusInProgress(ibu, fmt.Sprintf("Upgrade blocked: %s", err))
+		return requeueWithMediumInterval(), nil
+	}
+
+	if ctrlResult, blocked := u.checkPrepInputs(ctx, ibu); blocked {
+		return ctrlResult, nil
+	}
+
+	if ctrlResult, stopped := u.checkPrecachedImages(ctx, ibu); stopped {
+		return ctrlResult, nil
+	}
+
 	// backup with OADP
 	u.Log.Info("Handling backups with OADP operator")
 	ctrlResult, err := u.HandleBackup(ctx, ibu)
@@ -121,7 +155,7 @@ func (u *UpgHandler) PrePivot(ctx context.Context, ibu *lcav1alpha1.ImageBasedUp
 			backuprestore.IsBRFailedValidationError(err) ||
 			backuprestore.IsBRFailedError(err) {
 
-			utils.SetUpgradeStatusFailed(ibu, err.Error())
+			utils.SetUpgradeStatusFailedWithError(ibu, err)
 			return doNotRequeue(), nil
 		}
 		return requeueWithError(fmt.Errorf("error while handling backup: %w", err))
@@ -131,19 +165,25 @@ func (u *UpgHandler) PrePivot(ctx context.Context, ibu *lcav1alpha1.ImageBasedUp
 		return ctrlResult, nil
 	}
 
+	if ibu.Spec.PivotGate != nil {
+		if ctrlResult, blocked := u.checkPivotGate(ctx, ibu); blocked {
+			return ctrlResult, nil
+		}
+	}
+
 	u.Log.Info("Remounting sysroot")
-	if err := u.Ops.RemountSysroot(); err != nil {
+	if err := u.Ops.RemountSysroot(ctx); err != nil {
 		return requeueWithError(fmt.Errorf("error while remounting sysroot: %w", err))
 	}
 
-	stateroot := common.GetDesiredStaterootName(ibu)
-	staterootPath := getStaterootPath(stateroot)
-	staterootVarPath := getStaterootVarPath(stateroot)
+	stateroot := u.StaterootLayout.DesiredName(ibu)
+	staterootPath := getStaterootPath(u.StaterootLayout, stateroot)
+	staterootVarPath := getStaterootVarPath(u.StaterootLayout, stateroot)
 
 	u.Log.Info("Writing OadpConfiguration CRs into new stateroot")
 	if err := u.BackupRestore.ExportOadpConfigurationToDir(ctx, staterootVarPath, backuprestore.OadpNs); err != nil {
 		if backuprestore.IsBRFailedError(err) {
-			utils.SetUpgradeStatusFailed(ibu, err.Error())
+			utils.SetUpgradeStatusFailedWithError(ibu, err)
 			return doNotRequeue(), nil
 		}
 		return requeueWithError(fmt.Errorf("error while exporting OADP configuration: %w", err))
@@ -152,7 +192,7 @@ func (u *UpgHandler) PrePivot(ctx context.Context, ibu *lcav1alpha1.ImageBasedUp
 	u.Log.Info("Writing Restore CRs into new stateroot")
 	if err := u.BackupRestore.ExportRestoresToDir(ctx, ibu.Spec.OADPContent, staterootVarPath); err != nil {
 		if backuprestore.IsBRFailedValidationError(err) {
-			utils.SetUpgradeStatusFailed(ibu, err.Error())
+			utils.SetUpgradeStatusFailedWithError(ibu, err)
 			return doNotRequeue(), nil
 		}
 		return requeueWithError(fmt.Errorf("error while exporting restores: %w", err))
@@ -171,16 +211,50 @@ func (u *UpgHandler) PrePivot(ctx context.Context, ibu *lcav1alpha1.ImageBasedUp
 		return requeueWithError(fmt.Errorf("error while exporting extra manifests: %w", err))
 	}
 
+	// Report the progress of the backups in the Upgrade condition, and which items failed if any
+	progress := func(msg string) {
+		utils.SetUpgradeStatusInProgress(ibu, msg)
+		_ = utils.UpdateIBUStatusBatched(ctx, u.Client, u.StatusBatcher, ibu)
+	}
+
 	u.Log.Info("Writing cluster-configuration into new stateroot")
-	if err := u.ClusterConfig.FetchClusterConfig(ctx, staterootVarPath); err != nil {
+	if err := u.ClusterConfig.FetchClusterConfig(ctx, staterootVarPath, clockSkewProtection(ibu), progress); err != nil {
+		utils.SetUpgradeStatusInProgress(ibu, fmt.Sprintf("Retrying backup: %s", err))
 		return requeueWithError(fmt.Errorf("error while fetching cluster configuration: %w", err))
 	}
 
 	u.Log.Info("Writing lvm-configuration into new stateroot")
-	if err := u.ClusterConfig.FetchLvmConfig(ctx, staterootVarPath); err != nil {
+	if err := u.ClusterConfig.FetchLvmConfig(ctx, staterootVarPath, progress); err != nil {
+		utils.SetUpgradeStatusInProgress(ibu, fmt.Sprintf("Retrying backup: %s", err))
 		return requeueWithError(fmt.Errorf("error while fetching LVM configuration: %w", err))
 	}
 
+	u.Log.Info("Writing cluster-wide proxy configuration into new stateroot")
+	deploymentDir, err := u.OstreeClient.GetDeploymentDir(ctx, stateroot)
+	if err != nil {
+		return requeueWithError(fmt.Errorf("failed to get deployment dir for stateroot %s: %w", stateroot, err))
+	}
+	if err := u.ClusterConfig.RenderProxyConfig(ctx, common.PathOutsideChroot(deploymentDir)); err != nil {
+		return requeueWithError(fmt.Errorf("error while rendering proxy configuration: %w", err))
+	}
+
+	u.Log.Info("Carrying the admin systemd units into new stateroot")
+	if err := u.carrySystemdUnits(ibu, common.PathOutsideChroot(deploymentDir)); err != nil {
+		if isInvalidSpec(err) {
+			utils.SetUpgradeStatusFailedWithError(ibu, err)
+			return doNotRequeue(), nil
+		}
+		return requeueWithError(fmt.Errorf("error while carrying the admin systemd units: %w", err))
+	}
+
+	if ibu.GetAnnotations()[utils.EtcdBackupAnnotation] == utils.EtcdBackupEnabled {
+		u.Log.Info("Taking etcd backup before pivot")
+		if _, err := BackupEtcd(u.Log, u.Executor); err != nil {
+			utils.SetUpgradeStatusFailedWithError(ibu, fmt.Errorf("etcd backup before pivot failed: %w", err))
+			return doNotRequeue(), nil
+		}
+	}
+
 	// Clear any error status that may have been previously set
 	u.resetProgressMessage(ctx, ibu)
 
@@ -196,34 +270,155 @@ func (u *UpgHandler) PrePivot(ctx context.Context, ibu *lcav1alpha1.ImageBasedUp
 		return requeueWithError(fmt.Errorf("error while saving IBU CR to the new state root: %w", err))
 	}
 
+	if err := lcautils.CopyFileIfExists(common.PathOutsideChroot(utils.UpgradeSummaryFile), filepath.Join(staterootPath, utils.UpgradeSummaryFile)); err != nil {
+		return requeueWithError(fmt.Errorf("error while saving the upgrade summary to the new state root: %w", err))
+	}
+
+	if err := exportUpgradePlan(ctx, u.Client, staterootPath); err != nil {
+		return requeueWithError(fmt.Errorf("error while saving UpgradePlan CR to the new state root: %w", err))
+	}
+
 	u.Log.Info("Save a copy of the IBU in the current stateroot for rollback")
 	if err := exportForUncontrolledRollback(ibu); err != nil {
 		return requeueWithError(fmt.Errorf("error while exporting for uncontrolled rollback: %w", err))
 	}
 
+	// Last safe checkpoint, the node boots the new stateroot from then on
+	if u.preemptedByRollback(ctx, ibu, "before pivot") {
+		return requeueImmediately(), nil
+	}
+
 	// Set the new default deployment
-	if u.OstreeClient.IsOstreeAdminSetDefaultFeatureEnabled() {
-		deploymentIndex, err := u.RPMOstreeClient.GetDeploymentIndex(stateroot)
+	if u.OstreeClient.IsOstreeAdminSetDefaultFeatureEnabled(ctx) {
+		deploymentIndex, err := u.RPMOstreeClient.GetDeploymentIndex(ctx, stateroot)
 		if err != nil {
 			return requeueWithError(fmt.Errorf("failed to get deployment index for stateroot %s: %w", stateroot, err))
 		}
-		if err := u.OstreeClient.SetDefaultDeployment(deploymentIndex); err != nil {
+		if err := u.OstreeClient.SetDefaultDeployment(ctx, deploymentIndex); err != nil {
 			return requeueWithError(fmt.Errorf("failed to set default deployment at index %d: %w", deploymentIndex, err))
 		}
 	}
 
 	// Write an event to indicate reboot attempt
 	u.Recorder.Event(ibu, v1.EventTypeNormal, "Reboot", "System will now reboot for upgrade")
-	err = u.RebootClient.RebootToNewStateRoot("upgrade")
+	err = u.RebootClient.RebootToNewStateRoot(ctx, "upgrade")
 	if err != nil {
 		//todo: abort handler? e.g delete desired stateroot
 		u.Log.Error(err, "")
-		utils.SetUpgradeStatusFailed(ibu, err.Error())
+		utils.SetUpgradeStatusFailedWithError(ibu, lcaerrors.Wrap(lcaerrors.ErrReboot, err))
 		return doNotRequeue(), nil
 	}
 	return doNotRequeue(), nil
 }
 
+// checkPrepInputs detects the inputs changed since Prep, validating the changed OADP and extra manifests ConfigMaps
+// and pull secret again as Prep did. It returns true if the upgrade is blocked by changes that cannot be validated,
+// or by a seed image different from the one the stateroot was set up from.
+func (u *UpgHandler) checkPrepInputs(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, bool) {
+	recorded, err := prepinputs.Load(prepInputsFile)
+	if err != nil || recorded == nil {
+		// Prep was completed without recording its inputs, e.g. by a previous version of the operator
+		u.Log.Info("Skipping prep inputs drift detection", "error", err)
+		return ctrl.Result{}, false
+	}
+
+	current, err := computePrepInputs(ctx, u.Client, u.StaterootLayout, ibu)
+	if err != nil {
+		u.Log.Info("Upgrade blocked", "reason", err.Error())
+		utils.SetUpgradeStatusInProgress(ibu, fmt.Sprintf("Upgrade blocked: %s", err))
+		return requeueWithShortInterval(), true
+	}
+
+	changed := prepinputs.Changed(recorded, current)
+	var unresolved []string
+	for _, input := range changed {
+		if err := u.validatePrepInput(ctx, ibu, input); err != nil {
+			unresolved = append(unresolved, fmt.Sprintf("%s: %s", input, err))
+		}
+	}
+	if len(unresolved) != 0 {
+		msg := fmt.Sprintf("Upgrade blocked, inputs changed since Prep: %s", strings.Join(unresolved, "; "))
+		u.Log.Info(msg)
+		utils.SetUpgradeStatusDriftDetected(ibu, msg)
+		return requeueWithMediumInterval(), true
+	}
+
+	if len(changed) != 0 {
+		msg := fmt.Sprintf("Inputs changed since Prep validated again: %s", strings.Join(changed, ", "))
+		u.Log.Info(msg)
+		u.Recorder.Event(ibu, v1.EventTypeNormal, "PrepInputsChanged", msg)
+		if err := current.Persist(prepInputsFile); err != nil {
+			u.Log.Error(err, "failed to update prep inputs record")
+		}
+	}
+	if prog := utils.GetInProgressCondition(ibu, lcav1alpha1.Stages.Upgrade); prog != nil &&
+		prog.Reason == string(utils.ConditionReasons.DriftDetected) {
+		u.resetProgressMessage(ctx, ibu)
+	}
+	return ctrl.Result{}, false
+}
+
+// precacheRecordFile records the images pre-cached by Prep, with their digest
+var precacheRecordFile = common.PathOutsideChroot(precache.CacheFile)
+
+// checkPrecachedImages verifies that the images pre-cached by Prep are still in the container storage shared with the
+// new stateroot, e.g. not removed by the image garbage collection of the kubelet since, failing the upgrade before the
+// pivot otherwise rather than pulling them after the reboot. It returns true if the upgrade is stopped, blocked when
+// the images cannot be listed.
+func (u *UpgHandler) checkPrecachedImages(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, bool) {
+	bestEffort := ibu.Spec.Precache != nil && ibu.Spec.Precache.BestEffort
+	missing, err := precache.VerifyImages(ctx, u.Executor, precacheRecordFile, ibu.Spec.SeedImageRef.Image, bestEffort)
+	if err != nil {
+		u.Log.Info("Upgrade blocked", "reason", err.Error())
+		utils.SetUpgradeStatusInProgress(ibu, fmt.Sprintf("Upgrade blocked: failed to verify the precached images: %s", err))
+		return requeueWithShortInterval(), true
+	}
+	if len(missing) != 0 {
+		err := lcaerrors.Wrap(lcaerrors.ErrPrecacheMissing, fmt.Errorf(
+			"%d precached images are missing from the container storage, abort and run Prep again: %s",
+			len(missing), strings.Join(missing, ", ")))
+		u.Log.Error(err, "Precached images verification failed")
+		utils.SetUpgradeStatusFailedWithError(ibu, err)
+		return doNotRequeue(), true
+	}
+	return ctrl.Result{}, false
+}
+
+// validatePrepInput runs the Prep validation of the changed input again
+func (u *UpgHandler) validatePrepInput(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade, input string) error {
+	switch input {
+	case prepinputs.SeedImage:
+		return fmt.Errorf("the stateroot was set up from another seed image, revert the seedImageRef or abort and run Prep again")
+	case prepinputs.OADPContent, prepinputs.ExtraManifests:
+		if len(ibu.Spec.OADPContent) != 0 {
+			if err := u.BackupRestore.ValidateOadpConfigmap(ctx, ibu.Spec.OADPContent); err != nil {
+				return fmt.Errorf("invalid oadp configmaps: %w", err)
+			}
+		}
+		if len(ibu.Spec.ExtraManifests) != 0 {
+			manifests, err := extramanifest.GetExtraManifests(ctx, u.Client, ibu.Spec.ExtraManifests)
+			if err != nil {
+				return fmt.Errorf("invalid extra manifests: %w", err)
+			}
+			if len(ibu.Spec.OADPContent) != 0 {
+				preferExtraManifests := ibu.GetAnnotations()[utils.ExtraManifestConflictPolicyAnnotation] == utils.PreferExtraManifests
+				if err := u.BackupRestore.ValidateExtraManifestConflicts(ctx, ibu.Spec.OADPContent, manifests, preferExtraManifests); err != nil {
+					return fmt.Errorf("conflicting extra manifests: %w", err)
+				}
+			}
+		}
+	case prepinputs.PullSecret:
+		pullSecret, err := lcautils.GetSecretData(ctx, common.PullSecretName, common.OpenshiftConfigNamespace, v1.DockerConfigJsonKey, u.Client)
+		if err != nil {
+			return fmt.Errorf("failed to get the cluster pull secret: %w", err)
+		}
+		if err := lcautils.ValidatePullSecret([]byte(pullSecret)); err != nil {
+			return fmt.Errorf("invalid cluster pull secret: %w", err)
+		}
+	}
+	return nil
+}
+
 // exportForUncontrolledRollback Save a copy of the IBU in the current stateroot in case of uncontrolled rollback, with Upgrade set to failed
 var ibuPreStaterootPath = common.PathOutsideChroot(utils.IBUFilePath)
 
@@ -236,18 +431,137 @@ func exportForUncontrolledRollback(ibu *lcav1alpha1.ImageBasedUpgrade) error {
 	return nil
 }
 
-var getStaterootPath = func(stateroot string) string {
-	return common.PathOutsideChroot(common.GetStaterootPath(stateroot))
+var getStaterootPath = func(layout common.StaterootLayout, stateroot string) string {
+	return common.PathOutsideChroot(layout.Path(stateroot))
 }
 
-var getStaterootVarPath = func(stateroot string) string {
-	return common.PathOutsideChroot(filepath.Join(common.GetStaterootPath(stateroot), "/var"))
+var getStaterootVarPath = func(layout common.StaterootLayout, stateroot string) string {
+	return common.PathOutsideChroot(filepath.Join(layout.Path(stateroot), "/var"))
 }
 
 // CheckHealth helper func to call HealthChecks
 var CheckHealth = healthcheck.HealthChecks
 
-func (u *UpgHandler) autoRollbackIfEnabled(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
+// CheckPullSecret helper func to call verifyPullSecretReplaced
+var CheckPullSecret = verifyPullSecretReplaced
+
+// verifyPullSecretReplaced checks that no placeholder credentials of the seed image remain in the cluster pull
+// secret, nor in the pull secret of the node rendered from it by MCO
+func verifyPullSecretReplaced(ctx context.Context, c client.Client) error {
+	pullSecret, err := lcautils.GetSecretData(ctx, common.PullSecretName, common.OpenshiftConfigNamespace, v1.DockerConfigJsonKey, c)
+	if err != nil {
+		return fmt.Errorf("failed to get the cluster pull secret: %w", err)
+	}
+	if lcautils.HasPlaceholderCredentials([]byte(pullSecret)) {
+		return fmt.Errorf("the cluster pull secret %s/%s still holds the placeholder credentials of the seed image",
+			common.OpenshiftConfigNamespace, common.PullSecretName)
+	}
+
+	nodePullSecret, err := os.ReadFile(common.PathOutsideChroot(common.ImageRegistryAuthFile))
+	if err != nil {
+		return fmt.Errorf("failed to read the node pull secret: %w", err)
+	}
+	if lcautils.HasPlaceholderCredentials(nodePullSecret) {
+		return fmt.Errorf("the node pull secret %s still holds the placeholder credentials of the seed image",
+			common.ImageRegistryAuthFile)
+	}
+	return nil
+}
+
+// CheckNetwork helper func to wait for the network to be ready after the pivot
+var CheckNetwork = healthcheck.NetworkReady
+
+// CollectBootMetrics helper func to measure the first boot after the pivot
+var CollectBootMetrics = bootmetrics.Collect
+
+// CheckKlusterlet helper func to wait for the klusterlet of an ACM managed cluster to reconnect to the hub
+var CheckKlusterlet = healthcheck.KlusterletConnected
+
+// CheckCertificates helper func to call CertificatesReady
+var CheckCertificates = healthcheck.CertificatesReady
+
+// BackupEtcd helper func to take the etcd backup before pivot
+var BackupEtcd = func(log logr.Logger, executor ops.Execute) (*etcdbackup.Metadata, error) {
+	return etcdbackup.NewBackuper(log, executor).Backup() //nolint:wrapcheck
+}
+
+// VerifyEtcdBackup helper func to check the etcd backup taken before pivot
+var VerifyEtcdBackup = etcdbackup.Verify
+
+// MeasureNodeActivity helper func to call Sampler.Next
+var MeasureNodeActivity = func(sampler *nodeactivity.Sampler) (*nodeactivity.Usage, error) {
+	return sampler.Next()
+}
+
+// maxPivotGateErrors is the number of consecutive failures to measure the node activity after which the upgrade fails
+const maxPivotGateErrors = 5
+
+// CarrySystemdUnits helper func to call systemdunits.Carry
+var CarrySystemdUnits = systemdunits.Carry
+
+// carrySystemdUnits carries the systemd units and timers created by the admins of the node, allowed by the
+// lca.openshift.io/systemdUnitsAllowlist annotation, to the new stateroot, and reports the units carried and skipped
+// in the status
+func (u *UpgHandler) carrySystemdUnits(ibu *lcav1alpha1.ImageBasedUpgrade, deploymentDir string) error {
+	allowlist, err := systemdunits.ParseAllowlist(ibu.GetAnnotations()[utils.SystemdUnitsAllowlistAnnotation])
+	if err != nil {
+		return lcaerrors.Wrap(lcaerrors.ErrInvalidSpec, err)
+	}
+	report, err := CarrySystemdUnits(common.Host, deploymentDir, allowlist)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	u.Log.Info("Carried the admin systemd units", "carried", report.Carried, "skipped", report.Skipped)
+	ibu.Status.SystemdUnits = nil
+	if len(report.Carried) != 0 || len(report.Skipped) != 0 {
+		ibu.Status.SystemdUnits = &lcav1alpha1.SystemdUnitsCarryOver{Carried: report.Carried, Skipped: report.Skipped}
+	}
+	return nil
+}
+
+// checkPivotGate holds the reboot until the node activity is below the pivot gate thresholds. The activity is measured
+// across requeues, not to block the reconcile. The upgrade fails if no low-activity period is found within the window,
+// counted from the start of the Upgrade stage, or if the activity cannot be measured maxPivotGateErrors times in a row
+func (u *UpgHandler) checkPivotGate(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, bool) {
+	gate := ibu.Spec.PivotGate
+
+	u.Log.Info("Measuring node activity before pivot")
+	usage, err := MeasureNodeActivity(&u.activitySampler)
+	if err != nil {
+		u.pivotGateErrors++
+		if u.pivotGateErrors >= maxPivotGateErrors {
+			u.pivotGateErrors = 0
+			utils.SetUpgradeStatusFailedWithError(ibu, lcaerrors.Wrap(lcaerrors.ErrPivotGate,
+				fmt.Errorf("failed to measure node activity %d times in a row: %w", maxPivotGateErrors, err)))
+			return doNotRequeue(), true
+		}
+	} else {
+		u.pivotGateErrors = 0
+		if usage == nil {
+			utils.SetUpgradeStatusInProgress(ibu, "Measuring node activity before reboot")
+			return requeueWithCustomInterval(nodeactivity.SampleInterval), true
+		}
+		if err = nodeactivity.CheckLowActivity(usage, gate.MaxCPUPercent, gate.MaxNetworkKBps); err == nil {
+			u.Log.Info("Node activity is low, proceeding with pivot", "usage", usage.String())
+			return ctrl.Result{}, false
+		}
+	}
+
+	if gate.WindowMinutes > 0 {
+		window := time.Duration(gate.WindowMinutes) * time.Minute
+		if prog := utils.GetInProgressCondition(ibu, lcav1alpha1.Stages.Upgrade); prog != nil && time.Since(prog.LastTransitionTime.Time) > window {
+			utils.SetUpgradeStatusFailedWithError(ibu, lcaerrors.Wrap(lcaerrors.ErrPivotGate,
+				fmt.Errorf("no low-activity period found within the %d minutes pivot window: %w", gate.WindowMinutes, err)))
+			return doNotRequeue(), true
+		}
+	}
+
+	u.Log.Info("Waiting for low node activity before pivot", "reason", err.Error())
+	utils.SetUpgradeStatusInProgress(ibu, fmt.Sprintf("Waiting for low node activity before reboot: %s", strings.ReplaceAll(err.Error(), "\n", ", ")))
+	return requeueWithShortInterval(), true
+}
+
+func (u *UpgHandler) autoRollbackIfEnabled(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
 	// Check whether auto-rollback is desired
 	if ibu.Spec.AutoRollbackOnFailure.DisabledForUpgradeCompletion {
 		// Auto-rollback is not enabled, so do nothing
@@ -255,8 +569,15 @@ func (u *UpgHandler) autoRollbackIfEnabled(ibu *lcav1alpha1.ImageBasedUpgrade, m
 	}
 
 	u.Log.Info("Automatically rolling back due to failure")
+	u.Recorder.Event(ibu, v1.EventTypeWarning, "AutoRollback", msg)
 
-	if err := u.RebootClient.InitiateRollback(msg); err != nil {
+	if ibu.GetAnnotations()[utils.PreserveOnFailureAnnotation] == "true" {
+		if err := u.preserveFailureArtifacts(ctx, ibu, msg); err != nil {
+			u.Log.Error(err, "Unable to preserve the artifacts of the failure, rolling back without them")
+		}
+	}
+
+	if err := u.RebootClient.InitiateRollback(ctx, msg); err != nil {
 		u.Log.Info(fmt.Sprintf("Unable to auto rollback: %s", err))
 		return
 	}
@@ -265,6 +586,58 @@ func (u *UpgHandler) autoRollbackIfEnabled(ibu *lcav1alpha1.ImageBasedUpgrade, m
 	return
 }
 
+// preserveFailureArtifacts saves the journal of the failed boot and the workspace into the original stateroot, and
+// points the saved IBU CR at them. The failed stateroot and the precaching job are then kept by the cleanup of the
+// Idle stage, until the next Prep stage releases them
+func (u *UpgHandler) preserveFailureArtifacts(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade, msg string) error {
+	stateroot, err := u.RPMOstreeClient.GetUnbootedStaterootName(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to determine the original stateroot: %w", err)
+	}
+	if err := u.Ops.RemountSysroot(ctx); err != nil {
+		return fmt.Errorf("failed to remount sysroot: %w", err)
+	}
+
+	preservedAt := metav1.Now()
+	dir := filepath.Join(utils.PostmortemDir, preservedAt.UTC().Format("20060102-150405"))
+	// The commands run in the host namespace while the files are written through the host mount
+	hostDir := filepath.Join(u.StaterootLayout.Path(stateroot), dir)
+	localDir := filepath.Join(getStaterootPath(u.StaterootLayout, stateroot), dir)
+	if err := os.MkdirAll(localDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create postmortem dir %s: %w", localDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(localDir, "reason"), []byte(msg+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to write the failure reason: %w", err)
+	}
+	if _, err := u.Ops.RunBashInHostNamespace(ctx, "journalctl", "-b", "0", "--no-pager", "-o", "short-iso",
+		">", filepath.Join(hostDir, "journal.log")); err != nil {
+		return fmt.Errorf("failed to save the journal of the failed boot: %w", err)
+	}
+	if _, err := os.Stat(common.PathOutsideChroot(utils.IBUWorkspacePath)); err == nil {
+		if _, err := u.Ops.RunBashInHostNamespace(ctx, "cp", "-a", utils.IBUWorkspacePath,
+			filepath.Join(hostDir, "workspace")); err != nil {
+			return fmt.Errorf("failed to save the workspace: %w", err)
+		}
+	}
+
+	filePath := filepath.Join(getStaterootPath(u.StaterootLayout, stateroot), utils.IBUFilePath)
+	savedIbu := &lcav1alpha1.ImageBasedUpgrade{}
+	if err := lcautils.ReadYamlOrJSONFile(filePath, savedIbu); err != nil {
+		return fmt.Errorf("failed to read saved IBU CR from %s: %w", filePath, err)
+	}
+	savedIbu.Status.PreservedArtifacts = &lcav1alpha1.PreservedArtifacts{
+		Stateroot:   u.StaterootLayout.DesiredName(ibu),
+		Path:        dir,
+		Reason:      msg,
+		PreservedAt: preservedAt,
+	}
+	if err := lcautils.MarshalToFile(savedIbu, filePath); err != nil {
+		return fmt.Errorf("failed to save updated IBU CR to %s: %w", filePath, err)
+	}
+	u.Log.Info("Preserved the artifacts of the failure", "stateroot", stateroot, "path", dir)
+	return nil
+}
+
 // postPivot executes all the post-upgrade steps after the cluster is rebooted to the new stateroot.
 //
 // Note: All decisions, including reconciles and failures, should be made within this function.
@@ -273,17 +646,40 @@ func (u *UpgHandler) PostPivot(ctx context.Context, ibu *lcav1alpha1.ImageBasedU
 	u.Log.Info("Starting health check for different components")
 	err := CheckHealth(u.Client, u.Log)
 	if err != nil {
-		utils.SetUpgradeStatusFailed(ibu, err.Error())
-		u.autoRollbackIfEnabled(ibu, fmt.Sprintf("Rollback due to health check failure: %s", err))
+		utils.SetUpgradeStatusFailedWithError(ibu, lcaerrors.Wrap(lcaerrors.ErrHealthCheck, err))
+		u.autoRollbackIfEnabled(ctx, ibu, fmt.Sprintf("Rollback due to health check failure: %s", err))
+		return doNotRequeue(), nil
+	}
+	u.recordBootMetrics(ctx, ibu)
+
+	// Once MCO is stable, the placeholder pull secret of the seed must have been replaced everywhere
+	u.Log.Info("Checking that the pull secret of the seed image was replaced")
+	if err := CheckPullSecret(ctx, u.Client); err != nil {
+		utils.SetUpgradeStatusFailedWithError(ibu, lcaerrors.Wrap(lcaerrors.ErrRegistryAuth, err))
+		u.autoRollbackIfEnabled(ctx, ibu, fmt.Sprintf("Rollback due to pull secret failure: %s", err))
 		return doNotRequeue(), nil
 	}
 
+	// Applying the manifests or restores early races the network initialization, failing with confusing errors
+	if timeout := utils.GetNetworkReadinessTimeout(ibu); timeout > 0 {
+		u.Log.Info("Waiting for the network to be ready", "timeout", timeout)
+		if err := CheckNetwork(u.Client, u.Log, timeout); err != nil {
+			utils.SetUpgradeStatusFailedWithError(ibu, lcaerrors.Wrap(lcaerrors.ErrHealthCheck, err))
+			u.autoRollbackIfEnabled(ctx, ibu, fmt.Sprintf("Rollback due to network readiness failure: %s", err))
+			return doNotRequeue(), nil
+		}
+	}
+
+	if u.preemptedByRollback(ctx, ibu, "before applying the extra manifests") {
+		return requeueImmediately(), nil
+	}
+
 	// Applying extra manifests
 	err = u.ExtraManifest.ApplyExtraManifests(ctx, common.PathOutsideChroot(extramanifest.PolicyManifestPath))
 	if err != nil {
 		if extramanifest.IsEMFailedError(err) {
-			utils.SetUpgradeStatusFailed(ibu, err.Error())
-			u.autoRollbackIfEnabled(ibu, fmt.Sprintf("Rollback due to failure applying policy extra-manifests: %s", err))
+			utils.SetUpgradeStatusFailedWithError(ibu, err)
+			u.autoRollbackIfEnabled(ctx, ibu, fmt.Sprintf("Rollback due to failure applying policy extra-manifests: %s", err))
 			return doNotRequeue(), nil
 		}
 		return requeueWithError(fmt.Errorf("error while applying policy extra manifests: %w", err))
@@ -292,19 +688,23 @@ func (u *UpgHandler) PostPivot(ctx context.Context, ibu *lcav1alpha1.ImageBasedU
 	err = u.ExtraManifest.ApplyExtraManifests(ctx, common.PathOutsideChroot(extramanifest.ExtraManifestPath))
 	if err != nil {
 		if extramanifest.IsEMFailedError(err) {
-			utils.SetUpgradeStatusFailed(ibu, err.Error())
-			u.autoRollbackIfEnabled(ibu, fmt.Sprintf("Rollback due to failure applying extra-manifests: %s", err))
+			utils.SetUpgradeStatusFailedWithError(ibu, err)
+			u.autoRollbackIfEnabled(ctx, ibu, fmt.Sprintf("Rollback due to failure applying extra-manifests: %s", err))
 			return doNotRequeue(), nil
 		}
 		return requeueWithError(fmt.Errorf("error while applying extra manifests: %w", err))
 	}
 
+	if u.preemptedByRollback(ctx, ibu, "before restoring the OADP backups") {
+		return requeueImmediately(), nil
+	}
+
 	// Recovering OADP configuration
 	err = u.BackupRestore.RestoreOadpConfigurations(ctx)
 	if err != nil {
 		if backuprestore.IsBRStorageBackendUnavailableError(err) {
-			utils.SetUpgradeStatusFailed(ibu, err.Error())
-			u.autoRollbackIfEnabled(ibu, fmt.Sprintf("Rollback due to backup storage failure: %s", err))
+			utils.SetUpgradeStatusFailedWithError(ibu, err)
+			u.autoRollbackIfEnabled(ctx, ibu, fmt.Sprintf("Rollback due to backup storage failure: %s", err))
 			return doNotRequeue(), nil
 		}
 		return requeueWithError(fmt.Errorf("error while restoring OADP configuration: %w", err))
@@ -315,8 +715,8 @@ func (u *UpgHandler) PostPivot(ctx context.Context, ibu *lcav1alpha1.ImageBasedU
 	if err != nil {
 		// Restore failed
 		if backuprestore.IsBRFailedError(err) {
-			utils.SetUpgradeStatusFailed(ibu, err.Error())
-			u.autoRollbackIfEnabled(ibu, fmt.Sprintf("Rollback due to restore failure: %s", err))
+			utils.SetUpgradeStatusFailedWithError(ibu, err)
+			u.autoRollbackIfEnabled(ctx, ibu, fmt.Sprintf("Rollback due to restore failure: %s", err))
 			return doNotRequeue(), nil
 		}
 		return requeueWithError(fmt.Errorf("error while handling restore: %w", err))
@@ -326,16 +726,128 @@ func (u *UpgHandler) PostPivot(ctx context.Context, ibu *lcav1alpha1.ImageBasedU
 		return result, nil
 	}
 
-	if err := u.RebootClient.DisableInitMonitor(); err != nil {
+	if u.preemptedByRollback(ctx, ibu, "before completing the upgrade") {
+		return requeueImmediately(), nil
+	}
+
+	// An upgrade breaking the connection to the hub is operationally failed, even if the cluster is healthy
+	u.Log.Info("Checking the klusterlet connection to the hub")
+	if err := CheckKlusterlet(u.Client, u.Log); err != nil {
+		utils.SetUpgradeStatusFailedWithError(ibu, lcaerrors.Wrap(lcaerrors.ErrHealthCheck, err))
+		u.autoRollbackIfEnabled(ctx, ibu, fmt.Sprintf("Rollback due to klusterlet connection failure: %s", err))
+		return doNotRequeue(), nil
+	}
+
+	if err := clusterconfig.RemoveProxyConfig(); err != nil {
+		// Don't fail the upgrade on failure here, just log it
+		u.Log.Error(err, "unable to remove the proxy configuration rendered before pivot")
+	}
+
+	if err := u.RebootClient.DisableInitMonitor(ctx); err != nil {
 		// Don't fail the upgrade on failure here, just log it
 		u.Log.Error(err, "unable to disable LCA init monitor")
 	}
 
 	u.Log.Info("Done handleUpgrade")
-	utils.SetUpgradeStatusCompleted(ibu)
+	utils.SetUpgradeStatusCompleted(ibu, u.upgradeCompletedMessage())
 	return doNotRequeue(), nil
 }
 
+// IsRollbackRequested helper func to check the stage requested in the latest IBU
+var IsRollbackRequested = func(ctx context.Context, c client.Reader, ibu *lcav1alpha1.ImageBasedUpgrade) (bool, error) {
+	latest := &lcav1alpha1.ImageBasedUpgrade{}
+	if err := c.Get(ctx, types.NamespacedName{Name: ibu.Name}, latest); err != nil {
+		return false, fmt.Errorf("failed to get IBU %s: %w", ibu.Name, err)
+	}
+	return latest.Spec.Stage == lcav1alpha1.Stages.Rollback, nil
+}
+
+// preemptedByRollback returns true if a Rollback was requested since the reconcile started, so that the upgrade
+// stops at this safe checkpoint and the next reconcile transitions to the Rollback stage instead of running the
+// remaining steps. The check is best effort, the upgrade goes on if the IBU cannot be read
+func (u *UpgHandler) preemptedByRollback(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade, checkpoint string) bool {
+	requested, err := IsRollbackRequested(ctx, u.Client, ibu)
+	if err != nil {
+		u.Log.Error(err, "unable to check for a Rollback request", "checkpoint", checkpoint)
+		return false
+	}
+	if !requested {
+		return false
+	}
+	u.Log.Info("Upgrade preempted by the Rollback request", "checkpoint", checkpoint)
+	u.Recorder.Event(ibu, v1.EventTypeNormal, "UpgradePreempted",
+		fmt.Sprintf("Upgrade stopped %s for the Rollback request", checkpoint))
+	return true
+}
+
+// recordBootMetrics measures the first boot after the pivot once the cluster is healthy, for the upgrade summary.
+// Failures are logged only, as the metrics are informational
+func (u *UpgHandler) recordBootMetrics(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) {
+	metricsFile := common.PathOutsideChroot(utils.BootMetricsFile)
+	recorded, err := bootmetrics.Load(metricsFile)
+	if err != nil {
+		u.Log.Error(err, "unable to read the recorded boot metrics")
+	}
+	if upgrade := utils.GetInProgressCondition(ibu, lcav1alpha1.Stages.Upgrade); recorded != nil && upgrade != nil &&
+		!recorded.BootTime.Before(&upgrade.LastTransitionTime) {
+		// the first boot of this upgrade was already measured
+		return
+	}
+
+	metrics, err := CollectBootMetrics(ctx, u.Client, u.Executor, u.Log)
+	if err != nil {
+		u.Log.Error(err, "unable to measure the first boot after pivot")
+		return
+	}
+	u.Log.Info("First boot after pivot", "startupSeconds", metrics.StartupSeconds,
+		"timeToAPISeconds", metrics.TimeToAPISeconds,
+		"timeToClusterOperatorsAvailableSeconds", metrics.TimeToClusterOperatorsAvailableSeconds)
+	if err := metrics.Persist(metricsFile); err != nil {
+		u.Log.Error(err, "unable to record the boot metrics")
+	}
+}
+
+// upgradeCompletedMessage reports the package drift between the seed and the booted system found after the pivot
+func (u *UpgHandler) upgradeCompletedMessage() string {
+	msg := "Upgrade completed"
+	reportFile := common.PathOutsideChroot(common.PackageReportFile)
+	if _, err := os.Stat(reportFile); err != nil {
+		// seeds generated without a package manifest have no report
+		return msg
+	}
+	report, err := seedpackages.ReadReport(reportFile)
+	if err != nil {
+		u.Log.Error(err, "unable to read the package report")
+		return msg
+	}
+	u.Log.Info("Package report", "summary", report.Summary())
+	if !report.HasDrift() {
+		return msg
+	}
+	return fmt.Sprintf("%s. The booted system packages differ from the seed (%d added, %d removed, %d changed), see %s",
+		msg, len(report.Added), len(report.Removed), len(report.Changed), common.PackageReportFile)
+}
+
+// clockSkewProtection returns the clock skew protection of recert set by the RecertNotBeforeBackdateAnnotation and
+// the RecertClockSyncTimeoutAnnotation, nil if neither is set. Invalid values are ignored
+func clockSkewProtection(ibu *lcav1alpha1.ImageBasedUpgrade) *seedreconfig.ClockSkewProtection {
+	protection := &seedreconfig.ClockSkewProtection{}
+	if value, ok := ibu.GetAnnotations()[utils.RecertNotBeforeBackdateAnnotation]; ok {
+		if backdate, err := time.ParseDuration(value); err == nil && backdate > 0 {
+			protection.NotBeforeBackdate = backdate.String()
+		}
+	}
+	if value, ok := ibu.GetAnnotations()[utils.RecertClockSyncTimeoutAnnotation]; ok {
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			protection.ClockSyncTimeout = timeout.String()
+		}
+	}
+	if *protection == (seedreconfig.ClockSkewProtection{}) {
+		return nil
+	}
+	return protection
+}
+
 // HandleBackup manages backup flow and returns with possible requeue
 func (u *UpgHandler) HandleBackup(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, error) {
 	sortedBackupGroups, err := u.BackupRestore.GetSortedBackupsFromConfigmap(ctx, ibu.Spec.OADPContent)
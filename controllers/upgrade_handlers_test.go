@@ -7,23 +7,34 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/openshift-kni/lifecycle-agent/api/seedreconfig"
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
 	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
 	"github.com/openshift-kni/lifecycle-agent/internal/backuprestore"
 	mock_backuprestore "github.com/openshift-kni/lifecycle-agent/internal/backuprestore/mocks"
+	"github.com/openshift-kni/lifecycle-agent/internal/bootmetrics"
 	mock_clusterconfig "github.com/openshift-kni/lifecycle-agent/internal/clusterconfig/mocks"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/etcdbackup"
 	"github.com/openshift-kni/lifecycle-agent/internal/extramanifest"
 	mock_extramanifest "github.com/openshift-kni/lifecycle-agent/internal/extramanifest/mocks"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/internal/nodeactivity"
 	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
+	"github.com/openshift-kni/lifecycle-agent/internal/precache"
+	"github.com/openshift-kni/lifecycle-agent/internal/prepinputs"
 	"github.com/openshift-kni/lifecycle-agent/internal/reboot"
+	"github.com/openshift-kni/lifecycle-agent/internal/systemdunits"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 	rpmostreeclient "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
+	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
 	"github.com/stretchr/testify/assert"
 	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
 	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
 	controllerruntime "sigs.k8s.io/controller-runtime"
@@ -326,6 +337,10 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 	tests := []struct {
 		name                                            string
 		args                                            args
+		checkCertificatesReturn                         func() error
+		measureNodeActivityReturn                       func() (*nodeactivity.Usage, error)
+		pivotGateErrors                                 int
+		backupEtcdReturn                                func() error
 		getSortedBackupsFromConfigmapReturn             func() ([][]*velerov1.Backup, error)
 		getStartOrTrackBackupReturn                     func() (*backuprestore.BackupTracker, error)
 		remountSysrootReturn                            func() error
@@ -335,6 +350,7 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 		extractAndExportManifestFromPoliciesToDirReturn func() error
 		fetchClusterConfigReturn                        func() error
 		fetchLvmConfigReturn                            func() error
+		renderProxyConfigReturn                         func() error
 		exportIBUCRNew                                  bool
 		exportIBUCROrig                                 bool
 		rebootToNewStateRootReturn                      func() error
@@ -343,6 +359,145 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 		wantErr                                         assert.ErrorAssertionFunc
 		wantConditions                                  []metav1.Condition
 	}{
+		{
+			name: "certificates not ready blocks upgrade",
+			args: args{
+				ibu: lcav1alpha1.ImageBasedUpgrade{},
+			},
+			checkCertificatesReturn: func() error {
+				return fmt.Errorf("found 1 pending CSRs (csr-abc)")
+			},
+			want:    requeueWithMediumInterval(),
+			wantErr: assert.NoError,
+			wantConditions: []metav1.Condition{
+				{
+					Type:    string(utils.ConditionTypes.UpgradeInProgress),
+					Reason:  string(utils.ConditionReasons.InProgress),
+					Status:  metav1.ConditionTrue,
+					Message: "Upgrade blocked: found 1 pending CSRs (csr-abc)",
+				},
+			},
+		},
+		{
+			name: "high node activity holds the pivot",
+			args: args{
+				ibu: lcav1alpha1.ImageBasedUpgrade{
+					Spec: lcav1alpha1.ImageBasedUpgradeSpec{PivotGate: &lcav1alpha1.PivotGate{MaxCPUPercent: 20, WindowMinutes: 60}},
+				},
+			},
+			getSortedBackupsFromConfigmapReturn: func() ([][]*velerov1.Backup, error) {
+				return [][]*velerov1.Backup{}, nil
+			},
+			measureNodeActivityReturn: func() (*nodeactivity.Usage, error) {
+				return &nodeactivity.Usage{CPUPercent: 75}, nil
+			},
+			want:    requeueWithShortInterval(),
+			wantErr: assert.NoError,
+			wantConditions: []metav1.Condition{
+				{
+					Type:    string(utils.ConditionTypes.UpgradeInProgress),
+					Reason:  string(utils.ConditionReasons.InProgress),
+					Status:  metav1.ConditionTrue,
+					Message: "Waiting for low node activity before reboot: cpu utilization 75.0% is above 20%",
+				},
+			},
+		},
+		{
+			name: "first node activity sample holds the pivot for the sample interval",
+			args: args{
+				ibu: lcav1alpha1.ImageBasedUpgrade{
+					Spec: lcav1alpha1.ImageBasedUpgradeSpec{PivotGate: &lcav1alpha1.PivotGate{MaxCPUPercent: 20}},
+				},
+			},
+			getSortedBackupsFromConfigmapReturn: func() ([][]*velerov1.Backup, error) {
+				return [][]*velerov1.Backup{}, nil
+			},
+			measureNodeActivityReturn: func() (*nodeactivity.Usage, error) {
+				return nil, nil
+			},
+			want:    requeueWithCustomInterval(nodeactivity.SampleInterval),
+			wantErr: assert.NoError,
+			wantConditions: []metav1.Condition{
+				{
+					Type:    string(utils.ConditionTypes.UpgradeInProgress),
+					Reason:  string(utils.ConditionReasons.InProgress),
+					Status:  metav1.ConditionTrue,
+					Message: "Measuring node activity before reboot",
+				},
+			},
+		},
+		{
+			name: "node activity measurement failing repeatedly fails upgrade without window",
+			args: args{
+				ibu: lcav1alpha1.ImageBasedUpgrade{
+					Spec: lcav1alpha1.ImageBasedUpgradeSpec{PivotGate: &lcav1alpha1.PivotGate{MaxCPUPercent: 20}},
+				},
+			},
+			getSortedBackupsFromConfigmapReturn: func() ([][]*velerov1.Backup, error) {
+				return [][]*velerov1.Backup{}, nil
+			},
+			measureNodeActivityReturn: func() (*nodeactivity.Usage, error) {
+				return nil, errors.New("failed to open /proc/stat")
+			},
+			pivotGateErrors: maxPivotGateErrors - 1,
+			want:            doNotRequeue(),
+			wantErr:         assert.NoError,
+			wantConditions: []metav1.Condition{
+				{
+					Type:    string(utils.ConditionTypes.UpgradeCompleted),
+					Reason:  string(utils.ConditionReasons.PivotGateFailed),
+					Status:  metav1.ConditionFalse,
+					Message: "Upgrade failed",
+				},
+				{
+					Type:    string(utils.ConditionTypes.UpgradeInProgress),
+					Reason:  string(utils.ConditionReasons.PivotGateFailed),
+					Status:  metav1.ConditionFalse,
+					Message: "failed to measure node activity 5 times in a row: failed to open /proc/stat",
+				},
+			},
+		},
+		{
+			name: "no low node activity within the pivot window fails upgrade",
+			args: args{
+				ibu: lcav1alpha1.ImageBasedUpgrade{
+					Spec: lcav1alpha1.ImageBasedUpgradeSpec{PivotGate: &lcav1alpha1.PivotGate{MaxNetworkKBps: 100, WindowMinutes: 60}},
+					Status: lcav1alpha1.ImageBasedUpgradeStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:               string(utils.ConditionTypes.UpgradeInProgress),
+								Reason:             string(utils.ConditionReasons.InProgress),
+								Status:             metav1.ConditionTrue,
+								Message:            "In progress",
+								LastTransitionTime: metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+							},
+						},
+					},
+				},
+			},
+			getSortedBackupsFromConfigmapReturn: func() ([][]*velerov1.Backup, error) {
+				return [][]*velerov1.Backup{}, nil
+			},
+			measureNodeActivityReturn: func() (*nodeactivity.Usage, error) {
+				return &nodeactivity.Usage{NetworkKBps: 500}, nil
+			},
+			want:    doNotRequeue(),
+			wantErr: assert.NoError,
+			wantConditions: []metav1.Condition{
+				{
+					Type:    string(utils.ConditionTypes.UpgradeCompleted),
+					Reason:  string(utils.ConditionReasons.PivotGateFailed),
+					Status:  metav1.ConditionFalse,
+					Message: "Upgrade failed",
+				},
+				{
+					Type:    string(utils.ConditionTypes.UpgradeInProgress),
+					Reason:  string(utils.ConditionReasons.PivotGateFailed),
+					Status:  metav1.ConditionFalse,
+					Message: "no low-activity period found within the 60 minutes pivot window: network throughput 500.0 KB/s is above 100 KB/s",
+				},
+			},
+		},
 		{
 			name: "backup failed request no requeue",
 			args: args{
@@ -356,13 +511,13 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 			wantConditions: []metav1.Condition{
 				{
 					Type:    string(utils.ConditionTypes.UpgradeCompleted),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.BackupFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "Upgrade failed",
 				},
 				{
 					Type:    string(utils.ConditionTypes.UpgradeInProgress),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.BackupFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "error while getting sorted backups from configmap: this is a test - error",
 				},
@@ -382,13 +537,13 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 			wantConditions: []metav1.Condition{
 				{
 					Type:    string(utils.ConditionTypes.UpgradeCompleted),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.InvalidSpec),
 					Status:  metav1.ConditionFalse,
 					Message: "Upgrade failed",
 				},
 				{
 					Type:    string(utils.ConditionTypes.UpgradeInProgress),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.InvalidSpec),
 					Status:  metav1.ConditionFalse,
 					Message: "error while getting sorted backups from configmap: this is a test - FailedValidation",
 				},
@@ -408,13 +563,13 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 			wantConditions: []metav1.Condition{
 				{
 					Type:    string(utils.ConditionTypes.UpgradeCompleted),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.InvalidSpec),
 					Status:  metav1.ConditionFalse,
 					Message: "Upgrade failed",
 				},
 				{
 					Type:    string(utils.ConditionTypes.UpgradeInProgress),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.InvalidSpec),
 					Status:  metav1.ConditionFalse,
 					Message: "error while getting sorted backups from configmap: this is a test - NotFound",
 				},
@@ -463,13 +618,13 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 			wantConditions: []metav1.Condition{
 				{
 					Type:    string(utils.ConditionTypes.UpgradeCompleted),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.BackupFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "Upgrade failed",
 				},
 				{
 					Type:    string(utils.ConditionTypes.UpgradeInProgress),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.BackupFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "this is a test - NotFound stop reconcile",
 				},
@@ -523,13 +678,13 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 			wantConditions: []metav1.Condition{
 				{
 					Type:    string(utils.ConditionTypes.UpgradeCompleted),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.InvalidSpec),
 					Status:  metav1.ConditionFalse,
 					Message: "Upgrade failed",
 				},
 				{
 					Type:    string(utils.ConditionTypes.UpgradeInProgress),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.InvalidSpec),
 					Status:  metav1.ConditionFalse,
 					Message: "ExportRestoresToDir validation failed",
 				},
@@ -622,7 +777,107 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 				return nil
 			},
 			fetchClusterConfigReturn: func() error {
-				return fmt.Errorf("any error")
+				return &lcautils.BackupItemsError{What: "cluster configuration", Total: 6,
+					Failed: []string{"proxy"}, Errs: []error{fmt.Errorf("failed to get proxy")}}
+			},
+			want:    doNotRequeue(),
+			wantErr: assert.Error,
+			wantConditions: []metav1.Condition{
+				{
+					Type:    string(utils.ConditionTypes.UpgradeInProgress),
+					Reason:  string(utils.ConditionReasons.InProgress),
+					Status:  metav1.ConditionTrue,
+					Message: "Retrying backup: failed to backup 1 of 6 cluster configuration items: proxy: failed to get proxy",
+				},
+			},
+		},
+		{
+			name: "etcd backup failure fails upgrade",
+			args: args{
+				ibu: lcav1alpha1.ImageBasedUpgrade{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{utils.EtcdBackupAnnotation: utils.EtcdBackupEnabled},
+					},
+				},
+			},
+			getSortedBackupsFromConfigmapReturn: func() ([][]*velerov1.Backup, error) {
+				return nil, nil
+			},
+			remountSysrootReturn: func() error {
+				return nil
+			},
+			exportOadpConfigurationToDirReturn: func() error {
+				return nil
+			},
+			exportRestoresToDirReturn: func() error {
+				return nil
+			},
+			extractAndExportManifestFromPoliciesToDirReturn: func() error {
+				return nil
+			},
+			exportExtraManifestToDirReturn: func() error {
+				return nil
+			},
+			fetchClusterConfigReturn: func() error {
+				return nil
+			},
+			fetchLvmConfigReturn: func() error {
+				return nil
+			},
+			renderProxyConfigReturn: func() error {
+				return nil
+			},
+			backupEtcdReturn: func() error {
+				return lcaerrors.Wrap(lcaerrors.ErrInsufficientSpace, fmt.Errorf("etcd backup requires 2048 bytes available"))
+			},
+			want:    doNotRequeue(),
+			wantErr: assert.NoError,
+			wantConditions: []metav1.Condition{
+				{
+					Type:    string(utils.ConditionTypes.UpgradeCompleted),
+					Reason:  string(utils.ConditionReasons.InsufficientSpace),
+					Status:  metav1.ConditionFalse,
+					Message: "Upgrade failed",
+				},
+				{
+					Type:    string(utils.ConditionTypes.UpgradeInProgress),
+					Reason:  string(utils.ConditionReasons.InsufficientSpace),
+					Status:  metav1.ConditionFalse,
+					Message: "etcd backup before pivot failed: etcd backup requires 2048 bytes available",
+				},
+			},
+		},
+		{
+			name: "Rendering the proxy configuration fails",
+			args: args{
+				ibu: lcav1alpha1.ImageBasedUpgrade{},
+			},
+			getSortedBackupsFromConfigmapReturn: func() ([][]*velerov1.Backup, error) {
+				return nil, nil
+			},
+			remountSysrootReturn: func() error {
+				return nil
+			},
+			exportOadpConfigurationToDirReturn: func() error {
+				return nil
+			},
+			exportRestoresToDirReturn: func() error {
+				return nil
+			},
+			extractAndExportManifestFromPoliciesToDirReturn: func() error {
+				return nil
+			},
+			exportExtraManifestToDirReturn: func() error {
+				return nil
+			},
+			fetchClusterConfigReturn: func() error {
+				return nil
+			},
+			fetchLvmConfigReturn: func() error {
+				return nil
+			},
+			renderProxyConfigReturn: func() error {
+				return fmt.Errorf("read-only file system")
 			},
 			want:    doNotRequeue(),
 			wantErr: assert.Error,
@@ -664,6 +919,9 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 			fetchLvmConfigReturn: func() error {
 				return nil
 			},
+			renderProxyConfigReturn: func() error {
+				return nil
+			},
 			exportIBUCRNew:  true,
 			exportIBUCROrig: true,
 			isOstreeAdminSetDefaultFeatureEnabledReturn: BoolPointer(false),
@@ -675,13 +933,13 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 			wantConditions: []metav1.Condition{
 				{
 					Type:    string(utils.ConditionTypes.UpgradeCompleted),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.RebootFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "Upgrade failed",
 				},
 				{
 					Type:    string(utils.ConditionTypes.UpgradeInProgress),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.RebootFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "reboot failed",
 				},
@@ -700,7 +958,7 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 				mockBackuprestore.EXPECT().ExportOadpConfigurationToDir(gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.exportOadpConfigurationToDirReturn()).Times(1)
 			}
 			if tt.remountSysrootReturn != nil {
-				mockOps.EXPECT().RemountSysroot().Return(tt.remountSysrootReturn())
+				mockOps.EXPECT().RemountSysroot(gomock.Any()).Return(tt.remountSysrootReturn())
 			}
 			if tt.exportRestoresToDirReturn != nil {
 				mockBackuprestore.EXPECT().ExportRestoresToDir(gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.exportRestoresToDirReturn()).Times(1)
@@ -712,10 +970,14 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 				mockExtramanifest.EXPECT().ExportExtraManifestToDir(gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.exportExtraManifestToDirReturn()).Times(1)
 			}
 			if tt.fetchClusterConfigReturn != nil {
-				mockClusterconfig.EXPECT().FetchClusterConfig(gomock.Any(), gomock.Any()).Return(tt.fetchClusterConfigReturn()).Times(1)
+				mockClusterconfig.EXPECT().FetchClusterConfig(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.fetchClusterConfigReturn()).Times(1)
 			}
 			if tt.fetchLvmConfigReturn != nil {
-				mockClusterconfig.EXPECT().FetchLvmConfig(gomock.Any(), gomock.Any()).Return(tt.fetchLvmConfigReturn()).Times(1)
+				mockClusterconfig.EXPECT().FetchLvmConfig(gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.fetchLvmConfigReturn()).Times(1)
+			}
+			if tt.renderProxyConfigReturn != nil {
+				ostreeclientMock.EXPECT().GetDeploymentDir(gomock.Any(), gomock.Any()).Return("/ostree/deploy/rhcos_4.15.0/deploy/abc.0", nil).Times(1)
+				mockClusterconfig.EXPECT().RenderProxyConfig(gomock.Any(), gomock.Any()).Return(tt.renderProxyConfigReturn()).Times(1)
 			}
 			ibuTempDirNew := t.TempDir()
 			if tt.exportIBUCRNew {
@@ -723,7 +985,7 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 				defer func() {
 					getStaterootVarPath = origGetStaterootVarPath
 				}()
-				getStaterootVarPath = func(stateroot string) string {
+				getStaterootVarPath = func(layout common.StaterootLayout, stateroot string) string {
 					_ = os.MkdirAll(filepath.Join(ibuTempDirNew, "/opt"), 0777)
 					return ibuTempDirNew
 				}
@@ -732,7 +994,7 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 				defer func() {
 					getStaterootPath = origGetStaterootPath
 				}()
-				getStaterootPath = func(stateroot string) string {
+				getStaterootPath = func(layout common.StaterootLayout, stateroot string) string {
 					_ = os.MkdirAll(filepath.Join(ibuTempDirNew, common.LCAConfigDir), 0777)
 					file, _ := os.OpenFile(filepath.Join(ibuTempDirNew, utils.IBUFilePath), os.O_CREATE, 0777)
 					file.Close()
@@ -752,13 +1014,49 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 				ibuPreStaterootPath = filepath.Join(ibuTempDirOrig, utils.IBUFilePath)
 			}
 			if tt.isOstreeAdminSetDefaultFeatureEnabledReturn != nil {
-				ostreeclientMock.EXPECT().IsOstreeAdminSetDefaultFeatureEnabled().Return(*tt.isOstreeAdminSetDefaultFeatureEnabledReturn).Times(1)
+				ostreeclientMock.EXPECT().IsOstreeAdminSetDefaultFeatureEnabled(gomock.Any()).Return(*tt.isOstreeAdminSetDefaultFeatureEnabledReturn).Times(1)
 			}
 			if tt.rebootToNewStateRootReturn != nil {
-				mockRebootClient.EXPECT().RebootToNewStateRoot(gomock.Any()).Return(tt.rebootToNewStateRootReturn()).Times(1)
+				mockRebootClient.EXPECT().RebootToNewStateRoot(gomock.Any(), gomock.Any()).Return(tt.rebootToNewStateRootReturn()).Times(1)
 			}
+			origCheckCertificates := CheckCertificates
+			defer func() {
+				CheckCertificates = origCheckCertificates
+			}()
+			CheckCertificates = func(ctx context.Context, c client.Reader, l logr.Logger) error {
+				if tt.checkCertificatesReturn != nil {
+					return tt.checkCertificatesReturn()
+				}
+				return nil
+			}
+			origMeasureNodeActivity := MeasureNodeActivity
+			defer func() {
+				MeasureNodeActivity = origMeasureNodeActivity
+			}()
+			MeasureNodeActivity = func(sampler *nodeactivity.Sampler) (*nodeactivity.Usage, error) {
+				return tt.measureNodeActivityReturn()
+			}
+			origBackupEtcd := BackupEtcd
+			defer func() {
+				BackupEtcd = origBackupEtcd
+			}()
+			BackupEtcd = func(log logr.Logger, executor ops.Execute) (*etcdbackup.Metadata, error) {
+				if tt.backupEtcdReturn != nil {
+					return nil, tt.backupEtcdReturn()
+				}
+				return &etcdbackup.Metadata{}, nil
+			}
+			origCarrySystemdUnits := CarrySystemdUnits
+			defer func() {
+				CarrySystemdUnits = origCarrySystemdUnits
+			}()
+			CarrySystemdUnits = func(root, deploymentDir string, allowlist []string) (*systemdunits.Report, error) {
+				return &systemdunits.Report{}, nil
+			}
+			fakeClient, err := getFakeClientFromObjects()
+			assert.NoError(t, err)
 			uh := &UpgHandler{
-				Client:          nil,
+				Client:          fakeClient,
 				Log:             logr.Logger{},
 				BackupRestore:   mockBackuprestore,
 				ExtraManifest:   mockExtramanifest,
@@ -769,6 +1067,7 @@ func TestImageBasedUpgradeReconciler_prePivot(t *testing.T) {
 				RPMOstreeClient: mockRpmostreeclient,
 				OstreeClient:    ostreeclientMock,
 				RebootClient:    mockRebootClient,
+				pivotGateErrors: tt.pivotGateErrors,
 			}
 
 			got, err := uh.PrePivot(context.Background(), &tt.args.ibu)
@@ -837,6 +1136,10 @@ func TestImageBasedUpgradeReconciler_postPivot(t *testing.T) {
 		want                              controllerruntime.Result
 		wantErr                           assert.ErrorAssertionFunc
 		checkHealthReturn                 func(c client.Reader, l logr.Logger) error
+		checkKlusterletReturn             func(c client.Reader, l logr.Logger) error
+		rollbackRequested                 bool
+		checkNetworkReturn                func() error
+		checkPullSecretReturn             func() error
 		applyExtraManifestsReturn         func() error
 		applyPolicyManifestsReturn        func() error
 		restoreOadpConfigurationsReturn   func() error
@@ -858,19 +1161,57 @@ func TestImageBasedUpgradeReconciler_postPivot(t *testing.T) {
 			wantConditions: []metav1.Condition{
 				{
 					Type:    string(utils.ConditionTypes.UpgradeCompleted),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.HealthCheckFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "Upgrade failed",
 				},
 				{
 					Type:    string(utils.ConditionTypes.UpgradeInProgress),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.HealthCheckFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "any error from hc",
 				},
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name: "placeholder pull secret of the seed remains",
+			args: args{ibu: &lcav1alpha1.ImageBasedUpgrade{}},
+			checkHealthReturn: func(c client.Reader, l logr.Logger) error {
+				return nil
+			},
+			checkPullSecretReturn: func() error {
+				return fmt.Errorf("placeholder pull secret")
+			},
+			initiateRollbackReturn: func() error {
+				return nil
+			},
+			wantConditions: []metav1.Condition{
+				{
+					Type:    string(utils.ConditionTypes.UpgradeCompleted),
+					Reason:  string(utils.ConditionReasons.RegistryAuthFailed),
+					Status:  metav1.ConditionFalse,
+					Message: "Upgrade failed",
+				},
+				{
+					Type:    string(utils.ConditionTypes.UpgradeInProgress),
+					Reason:  string(utils.ConditionReasons.RegistryAuthFailed),
+					Status:  metav1.ConditionFalse,
+					Message: "placeholder pull secret",
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "preempted by a rollback request",
+			args: args{ibu: &lcav1alpha1.ImageBasedUpgrade{}},
+			checkHealthReturn: func(c client.Reader, l logr.Logger) error {
+				return nil
+			},
+			rollbackRequested: true,
+			want:              requeueImmediately(),
+			wantErr:           assert.NoError,
+		},
 		{
 			name: "extraManifests return error",
 			args: args{ibu: &lcav1alpha1.ImageBasedUpgrade{}},
@@ -889,13 +1230,13 @@ func TestImageBasedUpgradeReconciler_postPivot(t *testing.T) {
 			wantConditions: []metav1.Condition{
 				{
 					Type:    string(utils.ConditionTypes.UpgradeCompleted),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.ExtraManifestsFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "Upgrade failed",
 				},
 				{
 					Type:    string(utils.ConditionTypes.UpgradeInProgress),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.ExtraManifestsFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "Test error EM",
 				},
@@ -923,13 +1264,13 @@ func TestImageBasedUpgradeReconciler_postPivot(t *testing.T) {
 			wantConditions: []metav1.Condition{
 				{
 					Type:    string(utils.ConditionTypes.UpgradeCompleted),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.StorageBackendUnavailable),
 					Status:  metav1.ConditionFalse,
 					Message: "Upgrade failed",
 				},
 				{
 					Type:    string(utils.ConditionTypes.UpgradeInProgress),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.StorageBackendUnavailable),
 					Status:  metav1.ConditionFalse,
 					Message: "error RestoreOadpConfigurations",
 				},
@@ -963,19 +1304,87 @@ func TestImageBasedUpgradeReconciler_postPivot(t *testing.T) {
 			wantConditions: []metav1.Condition{
 				{
 					Type:    string(utils.ConditionTypes.UpgradeCompleted),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.RestoreFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "Upgrade failed",
 				},
 				{
 					Type:    string(utils.ConditionTypes.UpgradeInProgress),
-					Reason:  string(utils.ConditionReasons.Failed),
+					Reason:  string(utils.ConditionReasons.RestoreFailed),
 					Status:  metav1.ConditionFalse,
 					Message: "Failed restore CRs: name-failed",
 				},
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name: "network not ready after pivot",
+			args: args{ibu: &lcav1alpha1.ImageBasedUpgrade{}},
+			checkHealthReturn: func(c client.Reader, l logr.Logger) error {
+				return nil
+			},
+			checkNetworkReturn: func() error {
+				return fmt.Errorf("network not ready after 10m0s: api-int.example.com not resolvable")
+			},
+			initiateRollbackReturn: func() error {
+				return nil
+			},
+			wantConditions: []metav1.Condition{
+				{
+					Type:    string(utils.ConditionTypes.UpgradeCompleted),
+					Reason:  string(utils.ConditionReasons.HealthCheckFailed),
+					Status:  metav1.ConditionFalse,
+					Message: "Upgrade failed",
+				},
+				{
+					Type:    string(utils.ConditionTypes.UpgradeInProgress),
+					Reason:  string(utils.ConditionReasons.HealthCheckFailed),
+					Status:  metav1.ConditionFalse,
+					Message: "network not ready after 10m0s: api-int.example.com not resolvable",
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "klusterlet does not reconnect to the hub",
+			args: args{ibu: &lcav1alpha1.ImageBasedUpgrade{}},
+			checkHealthReturn: func(c client.Reader, l logr.Logger) error {
+				return nil
+			},
+			checkKlusterletReturn: func(c client.Reader, l logr.Logger) error {
+				return fmt.Errorf("klusterlet not connected")
+			},
+			applyPolicyManifestsReturn: func() error {
+				return nil
+			},
+			applyExtraManifestsReturn: func() error {
+				return nil
+			},
+			restoreOadpConfigurationsReturn: func() error {
+				return nil
+			},
+			loadRestoresFromOadpRestoreReturn: func() ([][]*velerov1.Restore, error) {
+				return nil, nil
+			},
+			initiateRollbackReturn: func() error {
+				return nil
+			},
+			wantConditions: []metav1.Condition{
+				{
+					Type:    string(utils.ConditionTypes.UpgradeCompleted),
+					Reason:  string(utils.ConditionReasons.HealthCheckFailed),
+					Status:  metav1.ConditionFalse,
+					Message: "Upgrade failed",
+				},
+				{
+					Type:    string(utils.ConditionTypes.UpgradeInProgress),
+					Reason:  string(utils.ConditionReasons.HealthCheckFailed),
+					Status:  metav1.ConditionFalse,
+					Message: "klusterlet not connected",
+				},
+			},
+			wantErr: assert.NoError,
+		},
 		{
 			name: "upgrade completed",
 			args: args{ibu: &lcav1alpha1.ImageBasedUpgrade{}},
@@ -1023,6 +1432,7 @@ func TestImageBasedUpgradeReconciler_postPivot(t *testing.T) {
 				BackupRestore: mockBackuprestore,
 				ExtraManifest: mockExtramanifest,
 				RebootClient:  mockRebootClient,
+				Recorder:      record.NewFakeRecorder(10),
 			}
 
 			oldHC := CheckHealth
@@ -1032,6 +1442,53 @@ func TestImageBasedUpgradeReconciler_postPivot(t *testing.T) {
 
 			CheckHealth = tt.checkHealthReturn
 
+			oldCheckKlusterlet := CheckKlusterlet
+			defer func() {
+				CheckKlusterlet = oldCheckKlusterlet
+			}()
+			CheckKlusterlet = func(c client.Reader, l logr.Logger) error { return nil }
+			if tt.checkKlusterletReturn != nil {
+				CheckKlusterlet = tt.checkKlusterletReturn
+			}
+
+			oldIsRollbackRequested := IsRollbackRequested
+			defer func() {
+				IsRollbackRequested = oldIsRollbackRequested
+			}()
+			IsRollbackRequested = func(ctx context.Context, c client.Reader, ibu *lcav1alpha1.ImageBasedUpgrade) (bool, error) {
+				return tt.rollbackRequested, nil
+			}
+
+			oldCheckNetwork := CheckNetwork
+			defer func() {
+				CheckNetwork = oldCheckNetwork
+			}()
+			CheckNetwork = func(c client.Reader, l logr.Logger, timeout time.Duration) error {
+				if tt.checkNetworkReturn != nil {
+					return tt.checkNetworkReturn()
+				}
+				return nil
+			}
+
+			oldCollectBootMetrics := CollectBootMetrics
+			defer func() {
+				CollectBootMetrics = oldCollectBootMetrics
+			}()
+			CollectBootMetrics = func(ctx context.Context, c client.Reader, executor ops.Execute, l logr.Logger) (*bootmetrics.Metrics, error) {
+				return nil, errors.New("boot metrics not measured in tests")
+			}
+
+			oldCheckPullSecret := CheckPullSecret
+			defer func() {
+				CheckPullSecret = oldCheckPullSecret
+			}()
+			CheckPullSecret = func(ctx context.Context, c client.Client) error {
+				if tt.checkPullSecretReturn != nil {
+					return tt.checkPullSecretReturn()
+				}
+				return nil
+			}
+
 			if tt.applyPolicyManifestsReturn != nil {
 				mockExtramanifest.EXPECT().ApplyExtraManifests(gomock.Any(), common.PathOutsideChroot(extramanifest.PolicyManifestPath)).Return(tt.applyPolicyManifestsReturn()).Times(1)
 			}
@@ -1048,10 +1505,10 @@ func TestImageBasedUpgradeReconciler_postPivot(t *testing.T) {
 				mockBackuprestore.EXPECT().StartOrTrackRestore(gomock.Any(), gomock.Any()).Return(tt.startOrTrackRestoreReturn()).Times(1)
 			}
 			if tt.initiateRollbackReturn != nil {
-				mockRebootClient.EXPECT().InitiateRollback(gomock.Any()).Return(tt.initiateRollbackReturn()).Times(1)
+				mockRebootClient.EXPECT().InitiateRollback(gomock.Any(), gomock.Any()).Return(tt.initiateRollbackReturn()).Times(1)
 			}
 			if tt.disableInitMonitorReturn != nil {
-				mockRebootClient.EXPECT().DisableInitMonitor().Return(tt.disableInitMonitorReturn()).Times(1)
+				mockRebootClient.EXPECT().DisableInitMonitor(gomock.Any()).Return(tt.disableInitMonitorReturn()).Times(1)
 			}
 
 			got, err := uh.PostPivot(tt.args.ctx, tt.args.ibu)
@@ -1070,3 +1527,198 @@ func TestImageBasedUpgradeReconciler_postPivot(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckPrepInputs(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+	mockBackuprestore := mock_backuprestore.NewMockBackuperRestorer(mockController)
+
+	origPrepInputsFile := prepInputsFile
+	origGetStaterootSeedDigest := getStaterootSeedDigest
+	defer func() {
+		prepInputsFile = origPrepInputsFile
+		getStaterootSeedDigest = origGetStaterootSeedDigest
+	}()
+	prepInputsFile = filepath.Join(t.TempDir(), "prep_inputs.json")
+	getStaterootSeedDigest = func(layout common.StaterootLayout, osname string) (string, error) {
+		return "sha256:0123", nil
+	}
+
+	pullSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: common.PullSecretName, Namespace: common.OpenshiftConfigNamespace},
+		Data:       map[string][]byte{v1.DockerConfigJsonKey: []byte(`{"auths":{"quay.io":{"auth":"dXNlcjpwYXNz"}}}`)},
+	}
+	oadpConfigMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "oadp-cm", Namespace: "openshift-adp"},
+		Data:       map[string]string{"backup.yaml": "v1"},
+	}
+	fakeClient, err := getFakeClientFromObjects(pullSecret, oadpConfigMap)
+	assert.NoError(t, err)
+
+	ibu := &lcav1alpha1.ImageBasedUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: utils.IBUName},
+		Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+			SeedImageRef: lcav1alpha1.SeedImageRef{Image: "quay.io/seed:4.16.0", Version: "4.16.0"},
+			OADPContent:  []lcav1alpha1.ConfigMapRef{{Name: "oadp-cm", Namespace: "openshift-adp"}},
+		},
+	}
+	recorded, err := computePrepInputs(context.Background(), fakeClient, common.StaterootLayout{}, ibu)
+	assert.NoError(t, err)
+	assert.NoError(t, recorded.Persist(prepInputsFile))
+
+	uh := &UpgHandler{
+		Client:        fakeClient,
+		Log:           logr.Discard(),
+		BackupRestore: mockBackuprestore,
+		Recorder:      record.NewFakeRecorder(1),
+	}
+
+	// unchanged inputs
+	_, blocked := uh.checkPrepInputs(context.Background(), ibu)
+	assert.False(t, blocked)
+
+	// changed oadp configmap, validated again
+	oadpConfigMap.Data["backup.yaml"] = "v2"
+	assert.NoError(t, fakeClient.Update(context.Background(), oadpConfigMap))
+	mockBackuprestore.EXPECT().ValidateOadpConfigmap(gomock.Any(), ibu.Spec.OADPContent).Return(nil).Times(1)
+	_, blocked = uh.checkPrepInputs(context.Background(), ibu)
+	assert.False(t, blocked)
+	updated, err := prepinputs.Load(prepInputsFile)
+	assert.NoError(t, err)
+	assert.NotEqual(t, recorded[prepinputs.OADPContent], updated[prepinputs.OADPContent])
+
+	// changed seed image, blocking the upgrade
+	ibu.Spec.SeedImageRef.Image = "quay.io/seed:4.16.1"
+	result, blocked := uh.checkPrepInputs(context.Background(), ibu)
+	assert.True(t, blocked)
+	assert.Equal(t, requeueWithMediumInterval(), result)
+	prog := utils.GetInProgressCondition(ibu, lcav1alpha1.Stages.Upgrade)
+	assert.NotNil(t, prog)
+	assert.Equal(t, string(utils.ConditionReasons.DriftDetected), prog.Reason)
+	assert.Contains(t, prog.Message, prepinputs.SeedImage)
+}
+
+func TestCheckPrecachedImages(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+	mockExec := ops.NewMockExecute(mockController)
+
+	origPrecacheRecordFile := precacheRecordFile
+	defer func() {
+		precacheRecordFile = origPrecacheRecordFile
+	}()
+	precacheRecordFile = filepath.Join(t.TempDir(), "precache_cache.json")
+
+	ibu := &lcav1alpha1.ImageBasedUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: utils.IBUName},
+		Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+			SeedImageRef: lcav1alpha1.SeedImageRef{Image: "quay.io/seed:4.16.0", Version: "4.16.0"},
+		},
+	}
+	uh := &UpgHandler{Log: logr.Discard(), Executor: mockExec}
+
+	// no record of the precached images
+	_, stopped := uh.checkPrecachedImages(context.Background(), ibu)
+	assert.False(t, stopped)
+
+	cache := &precache.Cache{SeedImage: ibu.Spec.SeedImageRef.Image,
+		Images:    map[string]string{"quay.io/a:1": "sha256:aaa", "quay.io/b:1": "sha256:bbb"},
+		ImageList: []string{"quay.io/a:1", "quay.io/b:1"}}
+	assert.NoError(t, cache.Persist(precacheRecordFile))
+	listImages := func() *gomock.Call {
+		return mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "images", "--format", "json")
+	}
+
+	// the images are listed
+	listImages().Return(`[{"Names":["quay.io/a:1"],"Digest":"sha256:aaa"},{"Names":["quay.io/b:1"],"Digest":"sha256:bbb"}]`, nil)
+	_, stopped = uh.checkPrecachedImages(context.Background(), ibu)
+	assert.False(t, stopped)
+
+	// the images cannot be listed, blocking the upgrade
+	listImages().Return("", errors.New("podman failed"))
+	result, stopped := uh.checkPrecachedImages(context.Background(), ibu)
+	assert.True(t, stopped)
+	assert.Equal(t, requeueWithShortInterval(), result)
+
+	// an image was removed since Prep, failing the upgrade
+	listImages().Return(`[{"Names":["quay.io/a:1"],"Digest":"sha256:aaa"}]`, nil)
+	result, stopped = uh.checkPrecachedImages(context.Background(), ibu)
+	assert.True(t, stopped)
+	assert.Equal(t, doNotRequeue(), result)
+	prog := utils.GetInProgressCondition(ibu, lcav1alpha1.Stages.Upgrade)
+	assert.NotNil(t, prog)
+	assert.Equal(t, string(utils.ConditionReasons.PrecacheMissing), prog.Reason)
+	assert.Contains(t, prog.Message, "quay.io/b:1 (not found)")
+}
+
+func TestUpgHandler_preserveFailureArtifacts(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+	mockOps := ops.NewMockOps(mockController)
+	mockRpmostreeclient := rpmostreeclient.NewMockIClient(mockController)
+
+	staterootDir := t.TempDir()
+	origGetStaterootPath := getStaterootPath
+	defer func() {
+		getStaterootPath = origGetStaterootPath
+	}()
+	getStaterootPath = func(layout common.StaterootLayout, stateroot string) string {
+		return staterootDir
+	}
+	savedIbu := &lcav1alpha1.ImageBasedUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: utils.IBUName},
+		Spec:       lcav1alpha1.ImageBasedUpgradeSpec{Stage: lcav1alpha1.Stages.Upgrade},
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Join(staterootDir, common.LCAConfigDir), 0o700))
+	assert.NoError(t, lcautils.MarshalToFile(savedIbu, filepath.Join(staterootDir, utils.IBUFilePath)))
+
+	ibu := savedIbu.DeepCopy()
+	ibu.Spec.SeedImageRef.Version = "4.15.0"
+	layout := common.StaterootLayout{}
+	mockRpmostreeclient.EXPECT().GetUnbootedStaterootName(gomock.Any()).Return("rhcos", nil).Times(1)
+	mockOps.EXPECT().RemountSysroot(gomock.Any()).Return(nil).Times(1)
+	mockOps.EXPECT().RunBashInHostNamespace(gomock.Any(), "journalctl", "-b", "0", "--no-pager", "-o", "short-iso", ">", gomock.Any()).
+		Return("", nil).Times(1)
+
+	uh := &UpgHandler{
+		Log:             logr.Logger{},
+		Ops:             mockOps,
+		RPMOstreeClient: mockRpmostreeclient,
+		StaterootLayout: layout,
+	}
+	assert.NoError(t, uh.preserveFailureArtifacts(context.Background(), ibu, "Rollback due to health check failure"))
+
+	updated := &lcav1alpha1.ImageBasedUpgrade{}
+	assert.NoError(t, lcautils.ReadYamlOrJSONFile(filepath.Join(staterootDir, utils.IBUFilePath), updated))
+	preserved := updated.Status.PreservedArtifacts
+	if assert.NotNil(t, preserved) {
+		assert.Equal(t, layout.DesiredName(ibu), preserved.Stateroot)
+		assert.Equal(t, "Rollback due to health check failure", preserved.Reason)
+		assert.Equal(t, utils.PostmortemDir, filepath.Dir(preserved.Path))
+		reason, err := os.ReadFile(filepath.Join(staterootDir, preserved.Path, "reason"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Rollback due to health check failure\n", string(reason))
+	}
+}
+
+func TestClockSkewProtection(t *testing.T) {
+	ibu := &lcav1alpha1.ImageBasedUpgrade{}
+	assert.Nil(t, clockSkewProtection(ibu))
+
+	ibu.SetAnnotations(map[string]string{utils.RecertClockSyncTimeoutAnnotation: "invalid"})
+	assert.Nil(t, clockSkewProtection(ibu))
+
+	ibu.SetAnnotations(map[string]string{utils.RecertClockSyncTimeoutAnnotation: "0s"})
+	assert.Nil(t, clockSkewProtection(ibu))
+
+	ibu.SetAnnotations(map[string]string{utils.RecertClockSyncTimeoutAnnotation: "5m"})
+	assert.Equal(t, &seedreconfig.ClockSkewProtection{ClockSyncTimeout: "5m0s"}, clockSkewProtection(ibu))
+
+	ibu.SetAnnotations(map[string]string{utils.RecertNotBeforeBackdateAnnotation: "60m",
+		utils.RecertClockSyncTimeoutAnnotation: "invalid"})
+	assert.Equal(t, &seedreconfig.ClockSkewProtection{NotBeforeBackdate: "1h0m0s"}, clockSkewProtection(ibu))
+
+	ibu.SetAnnotations(map[string]string{utils.RecertNotBeforeBackdateAnnotation: "0s",
+		utils.RecertClockSyncTimeoutAnnotation: "5m"})
+	assert.Equal(t, &seedreconfig.ClockSkewProtection{ClockSyncTimeout: "5m0s"}, clockSkewProtection(ibu))
+}
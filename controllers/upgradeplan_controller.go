@@ -0,0 +1,356 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+// UpgradePlanReconciler reconciles an UpgradePlan object, moving the IBU CR through the Prep, Upgrade and Idle
+// stages as the plan policy allows. The IBU controller performs the stages, as when they are set by the user
+type UpgradePlanReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// planClock is the time source of the maintenance window and soak time checks
+var planClock = time.Now
+
+//+kubebuilder:rbac:groups=lca.openshift.io,resources=upgradeplans,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=lca.openshift.io,resources=upgradeplans/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=lca.openshift.io,resources=imagebasedupgrades,verbs=get;list;watch;update;patch
+
+// Reconcile drives the IBU CR one step further along the plan
+func (r *UpgradePlanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	plan := &lcav1alpha1.UpgradePlan{}
+	if err := r.Get(ctx, req.NamespacedName, plan); err != nil {
+		if errors.IsNotFound(err) {
+			return doNotRequeue(), nil
+		}
+		return requeueWithError(fmt.Errorf("failed to get UpgradePlan: %w", err))
+	}
+	if plan.Name != utils.UpgradePlanName {
+		r.Log.Info("Ignoring UpgradePlan with unexpected name", "name", plan.Name)
+		return doNotRequeue(), nil
+	}
+	if plan.Status.Phase == lcav1alpha1.UpgradePlanPhases.Completed || plan.Status.Phase == lcav1alpha1.UpgradePlanPhases.Failed {
+		return doNotRequeue(), nil
+	}
+
+	ibu := &lcav1alpha1.ImageBasedUpgrade{}
+	if err := r.Get(ctx, types.NamespacedName{Name: utils.IBUName}, ibu); err != nil {
+		if errors.IsNotFound(err) {
+			return requeueWithShortInterval(), nil
+		}
+		return requeueWithError(fmt.Errorf("failed to get ImageBasedUpgrade: %w", err))
+	}
+
+	status := plan.Status.DeepCopy()
+	result, err := r.drive(ctx, plan, ibu)
+	if !equality.Semantic.DeepEqual(status, &plan.Status) {
+		if updateErr := r.updateStatus(ctx, plan); updateErr != nil {
+			return requeueWithError(updateErr)
+		}
+	}
+	return result, err
+}
+
+func (r *UpgradePlanReconciler) drive(ctx context.Context, plan *lcav1alpha1.UpgradePlan, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, error) {
+	switch plan.Status.Phase {
+	case lcav1alpha1.UpgradePlanPhases.Preparing, lcav1alpha1.UpgradePlanPhases.WaitingForUpgrade:
+		return r.handlePrep(ctx, plan, ibu)
+	case lcav1alpha1.UpgradePlanPhases.Upgrading, lcav1alpha1.UpgradePlanPhases.WaitingForFinalize:
+		return r.handleUpgrade(ctx, plan, ibu)
+	case lcav1alpha1.UpgradePlanPhases.Finalizing:
+		return r.handleFinalize(plan, ibu), nil
+	default:
+		return r.start(ctx, plan, ibu)
+	}
+}
+
+// start copies the plan into the IBU CR and moves it to Prep, once the IBU is Idle
+func (r *UpgradePlanReconciler) start(ctx context.Context, plan *lcav1alpha1.UpgradePlan, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, error) {
+	if ibu.Spec.Stage == lcav1alpha1.Stages.Prep && equality.Semantic.DeepEqual(ibu.Spec.SeedImageRef, plan.Spec.SeedImageRef) {
+		// The IBU was moved to Prep but the plan status update was lost
+		setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Preparing, "Prep in progress")
+		return requeueImmediately(), nil
+	}
+	if ibu.Spec.Stage != lcav1alpha1.Stages.Idle || !utils.IsStageCompleted(ibu, lcav1alpha1.Stages.Idle) {
+		setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Pending, "Waiting for the ImageBasedUpgrade to be Idle")
+		return requeueWithMediumInterval(), nil
+	}
+
+	ibu.Spec.SeedImageRef = plan.Spec.SeedImageRef
+	ibu.Spec.OADPContent = plan.Spec.OADPContent
+	ibu.Spec.ExtraManifests = plan.Spec.ExtraManifests
+	ibu.Spec.AutoRollbackOnFailure = plan.Spec.AutoRollbackOnFailure
+	if err := r.setStage(ctx, ibu, lcav1alpha1.Stages.Prep); err != nil {
+		return requeueWithError(err)
+	}
+	plan.Status.StartedAt = metav1.Now()
+	setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Preparing, "Prep in progress")
+	r.event(plan, corev1.EventTypeNormal, "Prep", fmt.Sprintf("Started the Prep stage for seed image %s", plan.Spec.SeedImageRef.Image))
+	return doNotRequeue(), nil
+}
+
+// handlePrep moves the IBU CR to Upgrade once Prep is completed and the maintenance window and health gate allow it
+func (r *UpgradePlanReconciler) handlePrep(ctx context.Context, plan *lcav1alpha1.UpgradePlan, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, error) {
+	switch {
+	case ibu.Spec.Stage == lcav1alpha1.Stages.Upgrade:
+		// The IBU was moved to Upgrade but the plan status update was lost
+		setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Upgrading, "Upgrade in progress")
+		return requeueImmediately(), nil
+	case ibu.Spec.Stage != lcav1alpha1.Stages.Prep:
+		return r.fail(plan, fmt.Sprintf("The ImageBasedUpgrade was moved to %s outside of the plan", ibu.Spec.Stage)), nil
+	case utils.IsStageFailed(ibu, lcav1alpha1.Stages.Prep):
+		return r.fail(plan, fmt.Sprintf("Prep failed: %s", stageFailureMessage(ibu, lcav1alpha1.Stages.Prep))), nil
+	case !utils.IsStageCompleted(ibu, lcav1alpha1.Stages.Prep):
+		setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Preparing, "Prep in progress")
+		return doNotRequeue(), nil
+	}
+
+	if wait, msg := r.upgradeGate(plan); wait > 0 {
+		setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.WaitingForUpgrade, msg)
+		return requeueWithCustomInterval(wait), nil
+	}
+
+	if err := r.setStage(ctx, ibu, lcav1alpha1.Stages.Upgrade); err != nil {
+		return requeueWithError(err)
+	}
+	setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Upgrading, "Upgrade in progress")
+	r.event(plan, corev1.EventTypeNormal, "Upgrade", "Started the Upgrade stage")
+	return doNotRequeue(), nil
+}
+
+// upgradeGate returns how long to wait before starting the Upgrade stage, with the reason, or zero
+func (r *UpgradePlanReconciler) upgradeGate(plan *lcav1alpha1.UpgradePlan) (time.Duration, string) {
+	if window := plan.Spec.MaintenanceWindow; window != nil {
+		open, wait, err := maintenanceWindowStatus(window, planClock())
+		if err != nil {
+			return requeueWithLongInterval().RequeueAfter, fmt.Sprintf("Invalid maintenance window: %s", err)
+		}
+		if !open {
+			return wait, fmt.Sprintf("Prep completed, waiting for the maintenance window opening at %s UTC", window.Start)
+		}
+	}
+	if plan.Spec.HealthGates.BeforeUpgrade {
+		if err := CheckHealth(r.Client, r.Log); err != nil {
+			return requeueWithMediumInterval().RequeueAfter, fmt.Sprintf("Prep completed, waiting for the cluster to be healthy: %s", err)
+		}
+	}
+	return 0, ""
+}
+
+// handleUpgrade moves the IBU CR to Idle, finalizing the upgrade, once it is completed and the health gates pass,
+// if the plan auto-finalizes
+func (r *UpgradePlanReconciler) handleUpgrade(ctx context.Context, plan *lcav1alpha1.UpgradePlan, ibu *lcav1alpha1.ImageBasedUpgrade) (ctrl.Result, error) {
+	switch {
+	case ibu.Spec.Stage == lcav1alpha1.Stages.Idle:
+		// Finalized by the user, or the plan status update was lost
+		setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Finalizing, "Finalizing the upgrade")
+		return requeueImmediately(), nil
+	case ibu.Spec.Stage == lcav1alpha1.Stages.Rollback:
+		return r.fail(plan, "The upgrade was rolled back"), nil
+	case ibu.Spec.Stage != lcav1alpha1.Stages.Upgrade:
+		return r.fail(plan, fmt.Sprintf("The ImageBasedUpgrade was moved to %s outside of the plan", ibu.Spec.Stage)), nil
+	case utils.IsStageFailed(ibu, lcav1alpha1.Stages.Upgrade):
+		return r.fail(plan, fmt.Sprintf("Upgrade failed: %s", stageFailureMessage(ibu, lcav1alpha1.Stages.Upgrade))), nil
+	case !utils.IsStageCompleted(ibu, lcav1alpha1.Stages.Upgrade):
+		setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Upgrading, "Upgrade in progress")
+		return doNotRequeue(), nil
+	}
+
+	if !plan.Spec.AutoFinalize {
+		setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.WaitingForFinalize,
+			"Upgrade completed, waiting for the ImageBasedUpgrade to be finalized or rolled back")
+		return doNotRequeue(), nil
+	}
+
+	completedAt := utils.GetCompletedCondition(ibu, lcav1alpha1.Stages.Upgrade).LastTransitionTime.Time
+	soakUntil := completedAt.Add(time.Duration(plan.Spec.HealthGates.SoakMinutes) * time.Minute)
+	if wait := soakUntil.Sub(planClock()); wait > 0 {
+		setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.WaitingForFinalize,
+			fmt.Sprintf("Upgrade completed, finalizing after %s", soakUntil.UTC().Format(time.RFC3339)))
+		return requeueWithCustomInterval(wait), nil
+	}
+	if plan.Spec.HealthGates.BeforeFinalize {
+		if err := CheckHealth(r.Client, r.Log); err != nil {
+			setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.WaitingForFinalize,
+				fmt.Sprintf("Upgrade completed, waiting for the cluster to be healthy: %s", err))
+			return requeueWithMediumInterval(), nil
+		}
+	}
+
+	if err := r.setStage(ctx, ibu, lcav1alpha1.Stages.Idle); err != nil {
+		return requeueWithError(err)
+	}
+	setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Finalizing, "Finalizing the upgrade")
+	r.event(plan, corev1.EventTypeNormal, "Finalize", "Finalizing the upgrade")
+	return doNotRequeue(), nil
+}
+
+// handleFinalize completes the plan once the IBU CR is back to Idle
+func (r *UpgradePlanReconciler) handleFinalize(plan *lcav1alpha1.UpgradePlan, ibu *lcav1alpha1.ImageBasedUpgrade) ctrl.Result {
+	if ibu.Spec.Stage != lcav1alpha1.Stages.Idle {
+		return r.fail(plan, fmt.Sprintf("The ImageBasedUpgrade was moved to %s outside of the plan", ibu.Spec.Stage))
+	}
+	if !utils.IsStageCompleted(ibu, lcav1alpha1.Stages.Idle) {
+		msg := "Finalizing the upgrade"
+		if condition := utils.GetCompletedCondition(ibu, lcav1alpha1.Stages.Idle); condition != nil && condition.Message != "" {
+			msg = fmt.Sprintf("%s: %s", msg, condition.Message)
+		}
+		setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Finalizing, msg)
+		return doNotRequeue()
+	}
+
+	plan.Status.CompletedAt = metav1.Now()
+	setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Completed, "Upgrade completed and finalized")
+	r.event(plan, corev1.EventTypeNormal, "Completed", "Upgrade completed and finalized")
+	return doNotRequeue()
+}
+
+func (r *UpgradePlanReconciler) fail(plan *lcav1alpha1.UpgradePlan, msg string) ctrl.Result {
+	r.Log.Info("UpgradePlan failed", "reason", msg)
+	plan.Status.CompletedAt = metav1.Now()
+	setPlanPhase(plan, lcav1alpha1.UpgradePlanPhases.Failed, msg)
+	r.event(plan, corev1.EventTypeWarning, "Failed", msg)
+	return doNotRequeue()
+}
+
+func (r *UpgradePlanReconciler) event(plan *lcav1alpha1.UpgradePlan, eventType, reason, msg string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(plan, eventType, reason, msg)
+	}
+}
+
+func (r *UpgradePlanReconciler) setStage(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade, stage lcav1alpha1.ImageBasedUpgradeStage) error {
+	r.Log.Info("Moving the ImageBasedUpgrade to the next stage", "stage", stage)
+	ibu.Spec.Stage = stage
+	if err := r.Update(ctx, ibu); err != nil {
+		return fmt.Errorf("failed to move the ImageBasedUpgrade to %s: %w", stage, err)
+	}
+	return nil
+}
+
+func (r *UpgradePlanReconciler) updateStatus(ctx context.Context, plan *lcav1alpha1.UpgradePlan) error {
+	plan.Status.ObservedGeneration = plan.ObjectMeta.Generation
+	err := common.RetryOnConflictOrRetriable(retry.DefaultRetry, func() error {
+		return r.Status().Update(ctx, plan) //nolint:wrapcheck
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update UpgradePlan status: %w", err)
+	}
+	return nil
+}
+
+func setPlanPhase(plan *lcav1alpha1.UpgradePlan, phase lcav1alpha1.UpgradePlanPhase, msg string) {
+	plan.Status.Phase = phase
+	plan.Status.Message = msg
+}
+
+// stageFailureMessage returns the message of the in progress condition of a failed stage, which holds the error
+func stageFailureMessage(ibu *lcav1alpha1.ImageBasedUpgrade, stage lcav1alpha1.ImageBasedUpgradeStage) string {
+	if condition := utils.GetInProgressCondition(ibu, stage); condition != nil {
+		return condition.Message
+	}
+	return fmt.Sprintf("%s failed", stage)
+}
+
+// maintenanceWindowStatus tells whether the daily maintenance window is open at the given time and, if not, how long
+// until it opens
+func maintenanceWindowStatus(window *lcav1alpha1.MaintenanceWindow, at time.Time) (bool, time.Duration, error) {
+	start, err := time.Parse("15:04", window.Start)
+	if err != nil {
+		return false, 0, fmt.Errorf("invalid start %q: %w", window.Start, err)
+	}
+	at = at.UTC()
+	duration := time.Duration(window.DurationMinutes) * time.Minute
+	todayStart := time.Date(at.Year(), at.Month(), at.Day(), start.Hour(), start.Minute(), 0, 0, time.UTC)
+	// a window opened yesterday may still be open
+	for _, opening := range []time.Time{todayStart.AddDate(0, 0, -1), todayStart} {
+		if !at.Before(opening) && at.Before(opening.Add(duration)) {
+			return true, 0, nil
+		}
+	}
+	if at.Before(todayStart) {
+		return false, todayStart.Sub(at), nil
+	}
+	return false, todayStart.AddDate(0, 0, 1).Sub(at), nil
+}
+
+// exportUpgradePlan saves the UpgradePlan CR, if any, to the new stateroot, for the plan to be restored after the pivot
+func exportUpgradePlan(ctx context.Context, c client.Client, staterootPath string) error {
+	plan := &lcav1alpha1.UpgradePlan{}
+	if err := c.Get(ctx, types.NamespacedName{Name: utils.UpgradePlanName}, plan); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get UpgradePlan: %w", err)
+	}
+	filePath := filepath.Join(staterootPath, utils.UpgradePlanFilePath)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(filePath), err)
+	}
+	if err := lcautils.MarshalToFile(plan, filePath); err != nil {
+		return fmt.Errorf("failed to save UpgradePlan CR to the new state root: %w", err)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *UpgradePlanReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("UpgradePlan")
+
+	//nolint:wrapcheck
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&lcav1alpha1.UpgradePlan{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// Every change of the IBU CR may let the plan move forward
+		Watches(&lcav1alpha1.ImageBasedUpgrade{}, handler.EnqueueRequestsFromMapFunc(
+			func(_ context.Context, _ client.Object) []reconcile.Request {
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: utils.UpgradePlanName}}}
+			})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+)
+
+func TestMaintenanceWindowStatus(t *testing.T) {
+	window := &lcav1alpha1.MaintenanceWindow{Start: "22:00", DurationMinutes: 240}
+	day := func(hour, minute int) time.Time { return time.Date(2024, 1, 10, hour, minute, 0, 0, time.UTC) }
+
+	testcases := []struct {
+		name         string
+		at           time.Time
+		expectedOpen bool
+		expectedWait time.Duration
+	}{
+		{name: "open", at: day(23, 0), expectedOpen: true},
+		{name: "open since yesterday", at: day(1, 30), expectedOpen: true},
+		{name: "closed before opening", at: day(20, 0), expectedWait: 2 * time.Hour},
+		{name: "closed after the window of yesterday", at: day(2, 0), expectedWait: 20 * time.Hour},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			open, wait, err := maintenanceWindowStatus(window, tc.at)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOpen, open)
+			assert.Equal(t, tc.expectedWait, wait)
+		})
+	}
+}
+
+func TestUpgradePlanReconcile(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	oldPlanClock := planClock
+	planClock = func() time.Time { return now }
+	defer func() { planClock = oldPlanClock }()
+
+	condition := func(conditionType utils.ConditionType, status metav1.ConditionStatus, msg string) metav1.Condition {
+		return metav1.Condition{Type: string(conditionType), Status: status, Message: msg, LastTransitionTime: metav1.NewTime(now.Add(-time.Hour))}
+	}
+	idle := []metav1.Condition{condition(utils.ConditionTypes.Idle, metav1.ConditionTrue, "Idle")}
+	prepCompleted := []metav1.Condition{condition(utils.ConditionTypes.PrepCompleted, metav1.ConditionTrue, "Prep completed")}
+	prepFailed := []metav1.Condition{
+		condition(utils.ConditionTypes.PrepCompleted, metav1.ConditionFalse, "Prep failed"),
+		condition(utils.ConditionTypes.PrepInProgress, metav1.ConditionFalse, "seed image not found"),
+	}
+	upgradeCompleted := []metav1.Condition{condition(utils.ConditionTypes.UpgradeCompleted, metav1.ConditionTrue, "Upgrade completed")}
+
+	testcases := []struct {
+		name            string
+		spec            lcav1alpha1.UpgradePlanSpec
+		phase           lcav1alpha1.UpgradePlanPhase
+		stage           lcav1alpha1.ImageBasedUpgradeStage
+		conditions      []metav1.Condition
+		unhealthy       bool
+		expectedPhase   lcav1alpha1.UpgradePlanPhase
+		expectedStage   lcav1alpha1.ImageBasedUpgradeStage
+		expectedMessage string
+		expectedRequeue time.Duration
+	}{
+		{
+			name:          "starts Prep when Idle",
+			stage:         lcav1alpha1.Stages.Idle,
+			conditions:    idle,
+			expectedPhase: lcav1alpha1.UpgradePlanPhases.Preparing,
+			expectedStage: lcav1alpha1.Stages.Prep,
+		},
+		{
+			name:            "waits for the IBU to be Idle",
+			stage:           lcav1alpha1.Stages.Rollback,
+			expectedPhase:   lcav1alpha1.UpgradePlanPhases.Pending,
+			expectedStage:   lcav1alpha1.Stages.Rollback,
+			expectedRequeue: time.Minute,
+		},
+		{
+			name:            "fails with Prep",
+			phase:           lcav1alpha1.UpgradePlanPhases.Preparing,
+			stage:           lcav1alpha1.Stages.Prep,
+			conditions:      prepFailed,
+			expectedPhase:   lcav1alpha1.UpgradePlanPhases.Failed,
+			expectedStage:   lcav1alpha1.Stages.Prep,
+			expectedMessage: "Prep failed: seed image not found",
+		},
+		{
+			name:          "starts Upgrade once Prep completed",
+			phase:         lcav1alpha1.UpgradePlanPhases.Preparing,
+			stage:         lcav1alpha1.Stages.Prep,
+			conditions:    prepCompleted,
+			expectedPhase: lcav1alpha1.UpgradePlanPhases.Upgrading,
+			expectedStage: lcav1alpha1.Stages.Upgrade,
+		},
+		{
+			name:            "waits for the maintenance window",
+			spec:            lcav1alpha1.UpgradePlanSpec{MaintenanceWindow: &lcav1alpha1.MaintenanceWindow{Start: "14:00", DurationMinutes: 60}},
+			phase:           lcav1alpha1.UpgradePlanPhases.Preparing,
+			stage:           lcav1alpha1.Stages.Prep,
+			conditions:      prepCompleted,
+			expectedPhase:   lcav1alpha1.UpgradePlanPhases.WaitingForUpgrade,
+			expectedStage:   lcav1alpha1.Stages.Prep,
+			expectedMessage: "Prep completed, waiting for the maintenance window opening at 14:00 UTC",
+			expectedRequeue: 2 * time.Hour,
+		},
+		{
+			name:            "waits for the cluster to be healthy before Upgrade",
+			spec:            lcav1alpha1.UpgradePlanSpec{HealthGates: lcav1alpha1.HealthGates{BeforeUpgrade: true}},
+			phase:           lcav1alpha1.UpgradePlanPhases.WaitingForUpgrade,
+			stage:           lcav1alpha1.Stages.Prep,
+			conditions:      prepCompleted,
+			unhealthy:       true,
+			expectedPhase:   lcav1alpha1.UpgradePlanPhases.WaitingForUpgrade,
+			expectedStage:   lcav1alpha1.Stages.Prep,
+			expectedMessage: "Prep completed, waiting for the cluster to be healthy: unhealthy",
+			expectedRequeue: time.Minute,
+		},
+		{
+			name:          "waits for the user to finalize",
+			phase:         lcav1alpha1.UpgradePlanPhases.Upgrading,
+			stage:         lcav1alpha1.Stages.Upgrade,
+			conditions:    upgradeCompleted,
+			expectedPhase: lcav1alpha1.UpgradePlanPhases.WaitingForFinalize,
+			expectedStage: lcav1alpha1.Stages.Upgrade,
+		},
+		{
+			name:            "soaks before finalizing",
+			spec:            lcav1alpha1.UpgradePlanSpec{AutoFinalize: true, HealthGates: lcav1alpha1.HealthGates{SoakMinutes: 90}},
+			phase:           lcav1alpha1.UpgradePlanPhases.Upgrading,
+			stage:           lcav1alpha1.Stages.Upgrade,
+			conditions:      upgradeCompleted,
+			expectedPhase:   lcav1alpha1.UpgradePlanPhases.WaitingForFinalize,
+			expectedStage:   lcav1alpha1.Stages.Upgrade,
+			expectedRequeue: 30 * time.Minute,
+		},
+		{
+			name:          "finalizes after soaking",
+			spec:          lcav1alpha1.UpgradePlanSpec{AutoFinalize: true, HealthGates: lcav1alpha1.HealthGates{SoakMinutes: 30, BeforeFinalize: true}},
+			phase:         lcav1alpha1.UpgradePlanPhases.WaitingForFinalize,
+			stage:         lcav1alpha1.Stages.Upgrade,
+			conditions:    upgradeCompleted,
+			expectedPhase: lcav1alpha1.UpgradePlanPhases.Finalizing,
+			expectedStage: lcav1alpha1.Stages.Idle,
+		},
+		{
+			name:            "fails when rolled back",
+			phase:           lcav1alpha1.UpgradePlanPhases.WaitingForFinalize,
+			stage:           lcav1alpha1.Stages.Rollback,
+			expectedPhase:   lcav1alpha1.UpgradePlanPhases.Failed,
+			expectedStage:   lcav1alpha1.Stages.Rollback,
+			expectedMessage: "The upgrade was rolled back",
+		},
+		{
+			name:          "completes once Idle",
+			phase:         lcav1alpha1.UpgradePlanPhases.Finalizing,
+			stage:         lcav1alpha1.Stages.Idle,
+			conditions:    idle,
+			expectedPhase: lcav1alpha1.UpgradePlanPhases.Completed,
+			expectedStage: lcav1alpha1.Stages.Idle,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			oldCheckHealth := CheckHealth
+			CheckHealth = func(c client.Reader, l logr.Logger) error {
+				if tc.unhealthy {
+					return errors.New("unhealthy")
+				}
+				return nil
+			}
+			defer func() { CheckHealth = oldCheckHealth }()
+
+			tc.spec.SeedImageRef = lcav1alpha1.SeedImageRef{Image: "quay.io/seed:4.15", Version: "4.15.0"}
+			plan := &lcav1alpha1.UpgradePlan{
+				ObjectMeta: metav1.ObjectMeta{Name: utils.UpgradePlanName},
+				Spec:       tc.spec,
+				Status:     lcav1alpha1.UpgradePlanStatus{Phase: tc.phase},
+			}
+			ibu := &lcav1alpha1.ImageBasedUpgrade{
+				ObjectMeta: metav1.ObjectMeta{Name: utils.IBUName},
+				Spec:       lcav1alpha1.ImageBasedUpgradeSpec{Stage: tc.stage},
+				Status:     lcav1alpha1.ImageBasedUpgradeStatus{Conditions: tc.conditions},
+			}
+			c, err := getFakeClientFromObjects(plan, ibu)
+			assert.NoError(t, err)
+
+			r := &UpgradePlanReconciler{Client: c, Log: logr.Discard(), Scheme: testscheme}
+			result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: utils.UpgradePlanName}})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRequeue, result.RequeueAfter)
+
+			assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: utils.UpgradePlanName}, plan))
+			assert.Equal(t, tc.expectedPhase, plan.Status.Phase)
+			if tc.expectedMessage != "" {
+				assert.Equal(t, tc.expectedMessage, plan.Status.Message)
+			}
+			assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: utils.IBUName}, ibu))
+			assert.Equal(t, tc.expectedStage, ibu.Spec.Stage)
+			if tc.expectedStage == lcav1alpha1.Stages.Prep && tc.phase == "" {
+				assert.Equal(t, tc.spec.SeedImageRef, ibu.Spec.SeedImageRef)
+			}
+		})
+	}
+}
@@ -2,13 +2,18 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
 
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,6 +33,8 @@ var ConditionTypes = struct {
 	RollbackCompleted  ConditionType
 	SeedGenInProgress  ConditionType
 	SeedGenCompleted   ConditionType
+
+	WaitingForOperatorUpgrade ConditionType
 }{
 	Idle:               "Idle",
 	PrepInProgress:     "PrepInProgress",
@@ -38,6 +45,8 @@ var ConditionTypes = struct {
 	RollbackCompleted:  "RollbackCompleted",
 	SeedGenInProgress:  "SeedGenInProgress",
 	SeedGenCompleted:   "SeedGenCompleted",
+
+	WaitingForOperatorUpgrade: "WaitingForOperatorUpgrade",
 }
 
 var SeedGenConditionTypes = struct {
@@ -68,6 +77,33 @@ var ConditionReasons = struct {
 	FinalizeCompleted ConditionReason
 	FinalizeFailed    ConditionReason
 	InvalidTransition ConditionReason
+	Preempted         ConditionReason
+
+	InsufficientSpace         ConditionReason
+	SeedIncompatible          ConditionReason
+	SeedPullFailed            ConditionReason
+	SeedDigestMismatch        ConditionReason
+	RegistryAuthFailed        ConditionReason
+	OstreeDeployFailed        ConditionReason
+	PrecacheFailed            ConditionReason
+	PrecacheBlocked           ConditionReason
+	PrecachePartial           ConditionReason
+	PrecacheMissing           ConditionReason
+	PrecacheTimeout           ConditionReason
+	PrecacheStalled           ConditionReason
+	DriftDetected             ConditionReason
+	InvalidSpec               ConditionReason
+	BackupFailed              ConditionReason
+	RestoreFailed             ConditionReason
+	StorageBackendUnavailable ConditionReason
+	ExtraManifestsFailed      ConditionReason
+	HealthCheckFailed         ConditionReason
+	RebootFailed              ConditionReason
+	PivotGateFailed           ConditionReason
+	FIPSMismatch              ConditionReason
+	SecureBootMismatch        ConditionReason
+	BootDeviceLayoutMismatch  ConditionReason
+	NodeNameMismatch          ConditionReason
 }{
 	Idle:              "Idle",
 	Completed:         "Completed",
@@ -81,6 +117,72 @@ var ConditionReasons = struct {
 	FinalizeCompleted: "FinalizeCompleted",
 	FinalizeFailed:    "FinalizeFailed",
 	InvalidTransition: "InvalidTransition",
+	Preempted:         "Preempted",
+
+	InsufficientSpace:         "InsufficientSpace",
+	SeedIncompatible:          "SeedIncompatible",
+	SeedPullFailed:            "SeedPullFailed",
+	SeedDigestMismatch:        "SeedDigestMismatch",
+	RegistryAuthFailed:        "RegistryAuthFailed",
+	OstreeDeployFailed:        "OstreeDeployFailed",
+	PrecacheFailed:            "PrecacheFailed",
+	PrecacheBlocked:           "PrecacheBlocked",
+	PrecachePartial:           "PrecachePartial",
+	PrecacheMissing:           "PrecacheMissing",
+	PrecacheTimeout:           "PrecacheTimeout",
+	PrecacheStalled:           "PrecacheStalled",
+	DriftDetected:             "DriftDetected",
+	InvalidSpec:               "InvalidSpec",
+	BackupFailed:              "BackupFailed",
+	RestoreFailed:             "RestoreFailed",
+	StorageBackendUnavailable: "StorageBackendUnavailable",
+	ExtraManifestsFailed:      "ExtraManifestsFailed",
+	HealthCheckFailed:         "HealthCheckFailed",
+	RebootFailed:              "RebootFailed",
+	PivotGateFailed:           "PivotGateFailed",
+	FIPSMismatch:              "FIPSMismatch",
+	SecureBootMismatch:        "SecureBootMismatch",
+	BootDeviceLayoutMismatch:  "BootDeviceLayoutMismatch",
+	NodeNameMismatch:          "NodeNameMismatch",
+}
+
+// errorReasons maps each lcaerrors sentinel to its condition reason
+var errorReasons = []struct {
+	err    error
+	reason ConditionReason
+}{
+	{lcaerrors.ErrInsufficientSpace, ConditionReasons.InsufficientSpace},
+	{lcaerrors.ErrSeedIncompatible, ConditionReasons.SeedIncompatible},
+	{lcaerrors.ErrSeedPull, ConditionReasons.SeedPullFailed},
+	{lcaerrors.ErrSeedDigest, ConditionReasons.SeedDigestMismatch},
+	{lcaerrors.ErrRegistryAuth, ConditionReasons.RegistryAuthFailed},
+	{lcaerrors.ErrOstreeDeploy, ConditionReasons.OstreeDeployFailed},
+	{lcaerrors.ErrPrecache, ConditionReasons.PrecacheFailed},
+	{lcaerrors.ErrPrecacheMissing, ConditionReasons.PrecacheMissing},
+	{lcaerrors.ErrPrecacheTimeout, ConditionReasons.PrecacheTimeout},
+	{lcaerrors.ErrPrecacheStalled, ConditionReasons.PrecacheStalled},
+	{lcaerrors.ErrInvalidSpec, ConditionReasons.InvalidSpec},
+	{lcaerrors.ErrBackup, ConditionReasons.BackupFailed},
+	{lcaerrors.ErrRestore, ConditionReasons.RestoreFailed},
+	{lcaerrors.ErrStorageBackend, ConditionReasons.StorageBackendUnavailable},
+	{lcaerrors.ErrExtraManifests, ConditionReasons.ExtraManifestsFailed},
+	{lcaerrors.ErrHealthCheck, ConditionReasons.HealthCheckFailed},
+	{lcaerrors.ErrReboot, ConditionReasons.RebootFailed},
+	{lcaerrors.ErrPivotGate, ConditionReasons.PivotGateFailed},
+	{lcaerrors.ErrFIPSMismatch, ConditionReasons.FIPSMismatch},
+	{lcaerrors.ErrSecureBootMismatch, ConditionReasons.SecureBootMismatch},
+	{lcaerrors.ErrBootDeviceLayoutMismatch, ConditionReasons.BootDeviceLayoutMismatch},
+	{lcaerrors.ErrNodeNameMismatch, ConditionReasons.NodeNameMismatch},
+}
+
+// ReasonForError returns the condition reason matching the error classification, or Failed for unclassified errors
+func ReasonForError(err error) ConditionReason {
+	for _, errorReason := range errorReasons {
+		if errors.Is(err, errorReason.err) {
+			return errorReason.reason
+		}
+	}
+	return ConditionReasons.Failed
 }
 
 var SeedGenConditionReasons = struct {
@@ -262,22 +364,32 @@ func GetPreviousStage(stage lcav1alpha1.ImageBasedUpgradeStage) lcav1alpha1.Imag
 	return ""
 }
 
-// SetUpgradeStatusFailed updates the upgrade status to failed with message
-func SetUpgradeStatusFailed(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
+func setStageStatusFailed(ibu *lcav1alpha1.ImageBasedUpgrade, stage lcav1alpha1.ImageBasedUpgradeStage, reason ConditionReason, msg string) {
 	SetStatusCondition(&ibu.Status.Conditions,
-		GetCompletedConditionType(lcav1alpha1.Stages.Upgrade),
-		ConditionReasons.Failed,
+		GetCompletedConditionType(stage),
+		reason,
 		metav1.ConditionFalse,
-		"Upgrade failed",
+		fmt.Sprintf("%s failed", stage),
 		ibu.Generation)
 	SetStatusCondition(&ibu.Status.Conditions,
-		GetInProgressConditionType(lcav1alpha1.Stages.Upgrade),
-		ConditionReasons.Failed,
+		GetInProgressConditionType(stage),
+		reason,
 		metav1.ConditionFalse,
 		msg,
 		ibu.Generation)
 }
 
+// SetUpgradeStatusFailed updates the upgrade status to failed with message
+func SetUpgradeStatusFailed(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
+	setStageStatusFailed(ibu, lcav1alpha1.Stages.Upgrade, ConditionReasons.Failed, msg)
+}
+
+// SetUpgradeStatusFailedWithError updates the upgrade status to failed with the error message,
+// using the condition reason matching the error classification
+func SetUpgradeStatusFailedWithError(ibu *lcav1alpha1.ImageBasedUpgrade, err error) {
+	setStageStatusFailed(ibu, lcav1alpha1.Stages.Upgrade, ReasonForError(err), err.Error())
+}
+
 // SetUpgradeStatusInProgress updates the upgrade status to in progress with message
 func SetUpgradeStatusInProgress(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
 	SetStatusCondition(&ibu.Status.Conditions,
@@ -288,8 +400,19 @@ func SetUpgradeStatusInProgress(ibu *lcav1alpha1.ImageBasedUpgrade, msg string)
 		ibu.Generation)
 }
 
-// SetUpgradeStatusCompleted updates the upgrade status to completed
-func SetUpgradeStatusCompleted(ibu *lcav1alpha1.ImageBasedUpgrade) {
+// SetUpgradeStatusDriftDetected updates the upgrade status to in progress, with the inputs changed since Prep that
+// block the upgrade as message
+func SetUpgradeStatusDriftDetected(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
+	SetStatusCondition(&ibu.Status.Conditions,
+		GetInProgressConditionType(lcav1alpha1.Stages.Upgrade),
+		ConditionReasons.DriftDetected,
+		metav1.ConditionTrue,
+		msg,
+		ibu.Generation)
+}
+
+// SetUpgradeStatusCompleted updates the upgrade status to completed with message
+func SetUpgradeStatusCompleted(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
 	SetStatusCondition(&ibu.Status.Conditions,
 		GetInProgressConditionType(lcav1alpha1.Stages.Upgrade),
 		ConditionReasons.Completed,
@@ -300,7 +423,18 @@ func SetUpgradeStatusCompleted(ibu *lcav1alpha1.ImageBasedUpgrade) {
 		GetCompletedConditionType(lcav1alpha1.Stages.Upgrade),
 		ConditionReasons.Completed,
 		metav1.ConditionTrue,
-		"Upgrade completed",
+		msg,
+		ibu.Generation)
+}
+
+// SetWaitingForOperatorUpgrade records that the stage transition is deferred until the upgrade of the LCA operator
+// completes
+func SetWaitingForOperatorUpgrade(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
+	SetStatusCondition(&ibu.Status.Conditions,
+		ConditionTypes.WaitingForOperatorUpgrade,
+		ConditionReasons.InProgress,
+		metav1.ConditionTrue,
+		msg,
 		ibu.Generation)
 }
 
@@ -314,24 +448,36 @@ func SetPrepStatusInProgress(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
 		ibu.Generation)
 }
 
-// SetPrepStatusFailed updates the prep status to failed with message
-func SetPrepStatusFailed(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
-	SetStatusCondition(&ibu.Status.Conditions,
-		GetCompletedConditionType(lcav1alpha1.Stages.Prep),
-		ConditionReasons.Failed,
-		metav1.ConditionFalse,
-		"Prep failed",
-		ibu.Generation)
+// SetPrepStatusPrecacheBlocked updates the prep status to in progress, with the reason the precaching job is
+// blocked as message
+func SetPrepStatusPrecacheBlocked(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
 	SetStatusCondition(&ibu.Status.Conditions,
 		GetInProgressConditionType(lcav1alpha1.Stages.Prep),
-		ConditionReasons.Failed,
-		metav1.ConditionFalse,
+		ConditionReasons.PrecacheBlocked,
+		metav1.ConditionTrue,
 		msg,
 		ibu.Generation)
 }
 
+// SetPrepStatusFailed updates the prep status to failed with message
+func SetPrepStatusFailed(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
+	setStageStatusFailed(ibu, lcav1alpha1.Stages.Prep, ConditionReasons.Failed, msg)
+}
+
+// SetPrepStatusFailedWithError updates the prep status to failed with the error message,
+// using the condition reason matching the error classification
+func SetPrepStatusFailedWithError(ibu *lcav1alpha1.ImageBasedUpgrade, err error) {
+	setStageStatusFailed(ibu, lcav1alpha1.Stages.Prep, ReasonForError(err), err.Error())
+}
+
 // SetPrepStatusCompleted updates the prep status to completed
 func SetPrepStatusCompleted(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
+	SetPrepStatusCompletedWithReason(ibu, ConditionReasons.Completed, msg)
+}
+
+// SetPrepStatusCompletedWithReason updates the prep status to completed, with a reason warning about the outcome of
+// the stage, e.g. PrecachePartial
+func SetPrepStatusCompletedWithReason(ibu *lcav1alpha1.ImageBasedUpgrade, reason ConditionReason, msg string) {
 	SetStatusCondition(&ibu.Status.Conditions,
 		GetInProgressConditionType(lcav1alpha1.Stages.Prep),
 		ConditionReasons.Completed,
@@ -340,7 +486,7 @@ func SetPrepStatusCompleted(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
 		ibu.Generation)
 	SetStatusCondition(&ibu.Status.Conditions,
 		GetCompletedConditionType(lcav1alpha1.Stages.Prep),
-		ConditionReasons.Completed,
+		reason,
 		metav1.ConditionTrue,
 		msg,
 		ibu.Generation)
@@ -348,18 +494,13 @@ func SetPrepStatusCompleted(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
 
 // SetRollbackStatusFailed updates the Rollback status to failed with message
 func SetRollbackStatusFailed(ibu *lcav1alpha1.ImageBasedUpgrade, msg string) {
-	SetStatusCondition(&ibu.Status.Conditions,
-		GetCompletedConditionType(lcav1alpha1.Stages.Rollback),
-		ConditionReasons.Failed,
-		metav1.ConditionFalse,
-		"Rollback failed",
-		ibu.Generation)
-	SetStatusCondition(&ibu.Status.Conditions,
-		GetInProgressConditionType(lcav1alpha1.Stages.Rollback),
-		ConditionReasons.Failed,
-		metav1.ConditionFalse,
-		msg,
-		ibu.Generation)
+	setStageStatusFailed(ibu, lcav1alpha1.Stages.Rollback, ConditionReasons.Failed, msg)
+}
+
+// SetRollbackStatusFailedWithError updates the Rollback status to failed with the error message,
+// using the condition reason matching the error classification
+func SetRollbackStatusFailedWithError(ibu *lcav1alpha1.ImageBasedUpgrade, err error) {
+	setStageStatusFailed(ibu, lcav1alpha1.Stages.Rollback, ReasonForError(err), err.Error())
 }
 
 // SetRollbackStatusInProgress updates the Rollback status to in progress with message
@@ -388,7 +529,14 @@ func SetRollbackStatusCompleted(ibu *lcav1alpha1.ImageBasedUpgrade) {
 		ibu.Generation)
 }
 
+// UpdateIBUStatus writes the ibu status right away, unless it changes nothing
 func UpdateIBUStatus(ctx context.Context, c client.Client, ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	return UpdateIBUStatusBatched(ctx, c, nil, ibu)
+}
+
+// UpdateIBUStatusBatched writes the ibu status, unless it changes nothing, batching the updates only reporting progress
+// with the batcher if not nil. The last progress batched is written before any other change, so that it is not lost
+func UpdateIBUStatusBatched(ctx context.Context, c client.Client, batcher *StatusBatcher, ibu *lcav1alpha1.ImageBasedUpgrade) error {
 	if c == nil {
 		// In UT code
 		return nil
@@ -403,6 +551,41 @@ func UpdateIBUStatus(ctx context.Context, c client.Client, ibu *lcav1alpha1.Imag
 			condition.ObservedGeneration = ibu.ObjectMeta.Generation
 		}
 	}
+
+	// Skip the updates that change nothing, and batch the ones only reporting progress
+	now := statusClock()
+	current := &lcav1alpha1.ImageBasedUpgrade{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(ibu), current); err == nil {
+		if equality.Semantic.DeepEqual(current.Status, ibu.Status) {
+			return nil
+		}
+		if batcher != nil {
+			if IsProgressOnlyChange(&current.Status, &ibu.Status) {
+				if !batcher.Due(GetStatusUpdateInterval(ibu), now) {
+					batcher.batch(ibu.Status.DeepCopy())
+					return nil
+				}
+			} else if pending := batcher.takePending(); pending != nil && IsProgressOnlyChange(&current.Status, pending) {
+				flush := ibu.DeepCopy()
+				flush.Status = *pending
+				if err := writeIBUStatus(ctx, c, flush); err != nil {
+					return err
+				}
+				ibu.ResourceVersion = flush.ResourceVersion
+			}
+		}
+	}
+
+	if err := writeIBUStatus(ctx, c, ibu); err != nil {
+		return err
+	}
+	if batcher != nil {
+		batcher.Written(now)
+	}
+	return nil
+}
+
+func writeIBUStatus(ctx context.Context, c client.Client, ibu *lcav1alpha1.ImageBasedUpgrade) error {
 	err := common.RetryOnConflictOrRetriable(retry.DefaultRetry, func() error {
 		return c.Status().Update(ctx, ibu) //nolint:wrapcheck
 	})
@@ -410,6 +593,106 @@ func UpdateIBUStatus(ctx context.Context, c client.Client, ibu *lcav1alpha1.Imag
 	if err != nil {
 		return fmt.Errorf("failed to update IBU status: %w", err)
 	}
-
 	return nil
 }
+
+// statusClock returns the current time, overridden in tests
+var statusClock = time.Now
+
+// StatusBatcher rate limits the updates only reporting progress, which change every few seconds, to reduce the
+// watch traffic on the API servers of large fleets. Any other change is written right away. The zero value is ready to use
+type StatusBatcher struct {
+	mu      sync.Mutex
+	last    time.Time
+	pending *lcav1alpha1.ImageBasedUpgradeStatus
+}
+
+// Due tells whether an update only reporting progress can be written at the given time
+func (b *StatusBatcher) Due(interval time.Duration, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Sub(b.last) >= interval
+}
+
+// Written records an update written at the given time
+func (b *StatusBatcher) Written(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.last = now
+	b.pending = nil
+}
+
+// Pending tells whether an ibu status only reporting progress was batched and not written yet
+func (b *StatusBatcher) Pending() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pending != nil
+}
+
+// batch records the last ibu status batched
+func (b *StatusBatcher) batch(status *lcav1alpha1.ImageBasedUpgradeStatus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = status
+}
+
+// takePending returns and forgets the last ibu status batched, nil if none
+func (b *StatusBatcher) takePending() *lcav1alpha1.ImageBasedUpgradeStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pending := b.pending
+	b.pending = nil
+	return pending
+}
+
+// GetStatusUpdateInterval returns the minimum interval between two updates only reporting progress, set by the
+// StatusUpdateIntervalAnnotation. Invalid values are ignored in favor of DefaultStatusUpdateInterval
+func GetStatusUpdateInterval(ibu *lcav1alpha1.ImageBasedUpgrade) time.Duration {
+	if value, ok := ibu.GetAnnotations()[StatusUpdateIntervalAnnotation]; ok {
+		if interval, err := time.ParseDuration(value); err == nil && interval >= 0 {
+			return interval
+		}
+	}
+	return DefaultStatusUpdateInterval
+}
+
+// GetNetworkReadinessTimeout returns how long to wait for the network to be ready after the pivot, set by the
+// NetworkReadinessTimeoutAnnotation. Invalid values are ignored in favor of DefaultNetworkReadinessTimeout
+func GetNetworkReadinessTimeout(ibu *lcav1alpha1.ImageBasedUpgrade) time.Duration {
+	if value, ok := ibu.GetAnnotations()[NetworkReadinessTimeoutAnnotation]; ok {
+		if timeout, err := time.ParseDuration(value); err == nil && timeout >= 0 {
+			return timeout
+		}
+	}
+	return DefaultNetworkReadinessTimeout
+}
+
+// SkipSeedVersionCheck returns whether the SkipSeedVersionCheckAnnotation acknowledges skipping the check that the
+// seed OCP version is higher than the one of the cluster. Any other value than the acknowledgement is an error
+func SkipSeedVersionCheck(ibu *lcav1alpha1.ImageBasedUpgrade) (bool, error) {
+	value, ok := ibu.GetAnnotations()[SkipSeedVersionCheckAnnotation]
+	if !ok {
+		return false, nil
+	}
+	if value != SkipSeedVersionCheckAcknowledgement {
+		return false, fmt.Errorf("the %s annotation must be set to %q to acknowledge that a same version reinstall or a "+
+			"downgrade is unsupported, found %q", SkipSeedVersionCheckAnnotation, SkipSeedVersionCheckAcknowledgement, value)
+	}
+	return true, nil
+}
+
+// IsProgressOnlyChange tells whether the new status only differs from the old one by the messages of the conditions
+// of the stages in progress, which report the progress of the stage, and by the precaching progress
+func IsProgressOnlyChange(old, new *lcav1alpha1.ImageBasedUpgradeStatus) bool {
+	oldCopy, newCopy := old.DeepCopy(), new.DeepCopy()
+	for _, status := range []*lcav1alpha1.ImageBasedUpgradeStatus{oldCopy, newCopy} {
+		status.PrecacheProgress = nil
+		for i := range status.Conditions {
+			condition := &status.Conditions[i]
+			if condition.Status == metav1.ConditionTrue && condition.Reason == string(ConditionReasons.InProgress) {
+				condition.Message = ""
+			}
+		}
+	}
+	return equality.Semantic.DeepEqual(oldCopy, newCopy)
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+)
+
+func TestUpdateIBUStatusBatching(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	oldStatusClock := statusClock
+	statusClock = func() time.Time { return now }
+	defer func() { statusClock = oldStatusClock }()
+	batcher := &StatusBatcher{}
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, lcav1alpha1.AddToScheme(scheme))
+	ibu := &lcav1alpha1.ImageBasedUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: IBUName},
+		Spec:       lcav1alpha1.ImageBasedUpgradeSpec{Stage: lcav1alpha1.Stages.Prep},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ibu).WithStatusSubresource(ibu).Build()
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(ibu), ibu))
+
+	stored := func() string {
+		current := &lcav1alpha1.ImageBasedUpgrade{}
+		assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(ibu), current))
+		if condition := GetInProgressCondition(current, lcav1alpha1.Stages.Prep); condition != nil {
+			return condition.Message
+		}
+		if condition := GetCompletedCondition(current, lcav1alpha1.Stages.Prep); condition != nil {
+			return condition.Message
+		}
+		return ""
+	}
+
+	// Starting the stage is a semantic change
+	SetPrepStatusInProgress(ibu, "Precaching 1/10")
+	assert.NoError(t, UpdateIBUStatusBatched(context.Background(), c, batcher, ibu))
+	assert.Equal(t, "Precaching 1/10", stored())
+
+	// The progress is batched within the interval
+	now = now.Add(5 * time.Second)
+	SetPrepStatusInProgress(ibu, "Precaching 2/10")
+	ibu.Status.PrecacheProgress = &lcav1alpha1.PrecacheProgress{Total: 10, Pulled: 2, Percent: 20}
+	assert.NoError(t, UpdateIBUStatusBatched(context.Background(), c, batcher, ibu))
+	assert.Equal(t, "Precaching 1/10", stored())
+	assert.True(t, batcher.Pending())
+
+	now = now.Add(DefaultStatusUpdateInterval)
+	SetPrepStatusInProgress(ibu, "Precaching 3/10")
+	assert.NoError(t, UpdateIBUStatusBatched(context.Background(), c, batcher, ibu))
+	assert.Equal(t, "Precaching 3/10", stored())
+	assert.False(t, batcher.Pending())
+
+	// Other changes are written right away, after the progress batched
+	now = now.Add(5 * time.Second)
+	SetPrepStatusInProgress(ibu, "Precaching 10/10")
+	assert.NoError(t, UpdateIBUStatusBatched(context.Background(), c, batcher, ibu))
+	assert.Equal(t, "Precaching 3/10", stored())
+	before := &lcav1alpha1.ImageBasedUpgrade{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(ibu), before))
+
+	SetPrepStatusCompleted(ibu, "Prep completed")
+	assert.NoError(t, UpdateIBUStatusBatched(context.Background(), c, batcher, ibu))
+	assert.Equal(t, "Prep completed", stored())
+	assert.False(t, batcher.Pending())
+	after := &lcav1alpha1.ImageBasedUpgrade{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(ibu), after))
+	beforeVersion, _ := strconv.Atoi(before.ResourceVersion)
+	afterVersion, _ := strconv.Atoi(after.ResourceVersion)
+	assert.Equal(t, beforeVersion+2, afterVersion, "the progress batched is written before the completion")
+
+	// Without a batcher, the progress is written right away
+	SetPrepStatusInProgress(ibu, "Precaching 1/10")
+	assert.NoError(t, UpdateIBUStatus(context.Background(), c, ibu))
+	assert.Equal(t, "Precaching 1/10", stored())
+}
+
+func TestSkipSeedVersionCheck(t *testing.T) {
+	ibu := &lcav1alpha1.ImageBasedUpgrade{}
+	skip, err := SkipSeedVersionCheck(ibu)
+	assert.NoError(t, err)
+	assert.False(t, skip)
+
+	ibu.SetAnnotations(map[string]string{SkipSeedVersionCheckAnnotation: SkipSeedVersionCheckAcknowledgement})
+	skip, err = SkipSeedVersionCheck(ibu)
+	assert.NoError(t, err)
+	assert.True(t, skip)
+
+	ibu.SetAnnotations(map[string]string{SkipSeedVersionCheckAnnotation: "true"})
+	_, err = SkipSeedVersionCheck(ibu)
+	assert.ErrorContains(t, err, `must be set to "I-understand-this-is-unsupported"`)
+}
+
+func TestSetPrepStatusCompletedWithReason(t *testing.T) {
+	ibu := &lcav1alpha1.ImageBasedUpgrade{}
+	SetPrepStatusInProgress(ibu, "Precaching")
+	SetPrepStatusCompletedWithReason(ibu, ConditionReasons.PrecachePartial,
+		"Prep completed with 1 images not pre-cached, pulled after the upgrade reboot instead: quay.io/org/optional:v1")
+
+	assert.True(t, IsStageCompleted(ibu, lcav1alpha1.Stages.Prep))
+	completed := GetCompletedCondition(ibu, lcav1alpha1.Stages.Prep)
+	assert.Equal(t, string(ConditionReasons.PrecachePartial), completed.Reason)
+	assert.Contains(t, completed.Message, "quay.io/org/optional:v1")
+	inProgress := GetInProgressCondition(ibu, lcav1alpha1.Stages.Prep)
+	assert.Equal(t, metav1.ConditionFalse, inProgress.Status)
+	assert.Equal(t, string(ConditionReasons.Completed), inProgress.Reason)
+}
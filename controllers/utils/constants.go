@@ -2,6 +2,7 @@ package utils
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
 )
@@ -12,8 +13,118 @@ const (
 	IBUName     string = "upgrade"
 	IBUFilePath string = common.LCAConfigDir + "/ibu.json"
 
+	// UpgradePlanName defines the valid name of the UpgradePlan CR, which drives the IBU CR
+	UpgradePlanName     string = "upgrade"
+	UpgradePlanFilePath string = common.LCAConfigDir + "/upgradeplan.json"
+
+	// UpgradeSummaryFile persists the summary of the last upgrade attempts, published in a ConfigMap
+	UpgradeSummaryFile string = common.LCAConfigDir + "/upgrade_summary.json"
+	// BootMetricsFile persists the metrics of the first boot after the pivot, attached to the upgrade summary
+	BootMetricsFile string = common.LCAConfigDir + "/boot_metrics.json"
+
 	ManualCleanupAnnotation string = "lca.openshift.io/manualCleanupDone"
 
+	// ReconcileNowAnnotation forces an immediate reconcile and status refresh when added or updated,
+	// and is removed once handled
+	ReconcileNowAnnotation string = "lca.openshift.io/reconcileNow"
+
+	// RunPreflightAnnotation runs the preflight checks of the upgrade in the Idle stage and publishes their summary in
+	// the status when added or updated, and is removed once handled
+	RunPreflightAnnotation string = "lca.openshift.io/runPreflight"
+
+	// RollbackDryRunAnnotation makes the Rollback stage only publish its plan in the status, when set to "true"
+	RollbackDryRunAnnotation string = "lca.openshift.io/rollbackDryRun"
+	// ConfirmRollbackAnnotation confirms a rollback that risks losing data, when set to "true"
+	ConfirmRollbackAnnotation string = "lca.openshift.io/confirmRollback"
+
+	// CollectDiagnosticsAnnotation enables diagnostics collection on Prep, Upgrade or Rollback failure,
+	// with a value of "sosreport" or "must-gather"
+	CollectDiagnosticsAnnotation string = "lca.openshift.io/collectDiagnosticsOnFailure"
+	// MustGatherImageAnnotation overrides the gather image used for must-gather diagnostics
+	MustGatherImageAnnotation string = "lca.openshift.io/mustGatherImage"
+	// IBUDiagnosticsPath holds the diagnostics collected on stage failure, outside the workspace so that they are kept
+	// on abort or finalize
+	IBUDiagnosticsPath string = common.LCAConfigDir + "/diagnostics"
+
+	// PreserveOnFailureAnnotation keeps the failed stateroot, its logs and the workspace on an Upgrade auto-rollback
+	// for a postmortem, until the next Prep, when set to "true"
+	PreserveOnFailureAnnotation string = "lca.openshift.io/preserveOnFailure"
+	// PostmortemDir holds the artifacts preserved on an Upgrade auto-rollback, one directory per failure
+	PostmortemDir string = common.LCAConfigDir + "/postmortem"
+
+	// RestrictPrecacheEgressAnnotation restricts the egress of the precaching job to the image registries, mirror
+	// registries and proxies with a NetworkPolicy, when set to "true"
+	RestrictPrecacheEgressAnnotation string = "lca.openshift.io/restrictPrecacheEgress"
+	// PrecacheEgressAllowedHostsAnnotation is a comma-separated list of additional host[:port] the precaching job
+	// is allowed to reach when its egress is restricted, e.g. the CDNs serving the registry blobs
+	PrecacheEgressAllowedHostsAnnotation string = "lca.openshift.io/precacheEgressAllowedHosts"
+	// PrecachePullTimeoutAnnotation overrides the time limit of each image pull of the precaching job, e.g. "45m"
+	PrecachePullTimeoutAnnotation string = "lca.openshift.io/precachePullTimeout"
+	// PrecacheOversizedImageThresholdAnnotation overrides the size above which a pre-cached image is reported as
+	// oversized, e.g. "4Gi"
+	PrecacheOversizedImageThresholdAnnotation string = "lca.openshift.io/precacheOversizedImageThreshold"
+	// PrecachePartialPullsAnnotation enables the partial pulls of the zstd:chunked layers by the precaching job,
+	// when set to "true"
+	PrecachePartialPullsAnnotation string = "lca.openshift.io/precachePartialPulls"
+	// PrecacheVerifyOnlyAnnotation audits the precaching in the Idle stage when set to "true", the precaching job only
+	// checking in their registry the images listed by the labels of the seed image. It is removed once the audit starts
+	PrecacheVerifyOnlyAnnotation string = "lca.openshift.io/precacheVerifyOnly"
+	// PrecacheModeAnnotation pre-caches the images in the manager process instead of a precaching job when set to
+	// PrecacheModeInProcess, so that precaching does not depend on scheduling the job nor pulling its image
+	PrecacheModeAnnotation string = "lca.openshift.io/precacheMode"
+	PrecacheModeJob        string = "Job"
+	PrecacheModeInProcess  string = "InProcess"
+	// PrecacheJUnitReportAnnotation adds a JUnit XML rendering of the precaching report, with a test case per image,
+	// when set to "true"
+	PrecacheJUnitReportAnnotation string = "lca.openshift.io/precacheJUnitReport"
+	// ArtifactSourceAnnotation names the ConfigMap in the LCA namespace configuring the artifact source the seed image
+	// and the images to pre-cache are downloaded from, instead of the cluster wide lca-artifact-source ConfigMap
+	ArtifactSourceAnnotation string = "lca.openshift.io/artifactSource"
+
+	// SeedSBOMConfigMapName is the ConfigMap in the LCA namespace storing the SBOM attached to the seed image, fetched
+	// by Prep for compliance scanning
+	SeedSBOMConfigMapName string = "lca-seed-sbom"
+	// SeedImageAnnotation and SeedImageDigestAnnotation name the seed image of the SBOM ConfigMap
+	SeedImageAnnotation       string = "lca.openshift.io/seedImage"
+	SeedImageDigestAnnotation string = "lca.openshift.io/seedImageDigest"
+
+	// StatusUpdateIntervalAnnotation sets the minimum interval between two status updates only reporting progress,
+	// of the IBU and of the hub report, e.g. "1m". "0s" writes every update
+	StatusUpdateIntervalAnnotation string = "lca.openshift.io/statusUpdateInterval"
+	DefaultStatusUpdateInterval           = 15 * time.Second
+
+	// NetworkReadinessTimeoutAnnotation sets how long the Upgrade stage waits after the pivot for the network to be
+	// ready before applying the extra manifests and restores, e.g. "20m". "0s" disables the wait
+	NetworkReadinessTimeoutAnnotation string = "lca.openshift.io/networkReadinessTimeout"
+	DefaultNetworkReadinessTimeout           = 10 * time.Minute
+
+	// RecertNotBeforeBackdateAnnotation sets how far in the past the certificates regenerated by recert after the
+	// pivot become valid, e.g. "1h", so that they are valid even if the clock of the node was ahead when recert ran.
+	// It requires the recert image of the seed to support the not_before_backdate_seconds configuration
+	RecertNotBeforeBackdateAnnotation string = "lca.openshift.io/recertNotBeforeBackdate"
+	// RecertClockSyncTimeoutAnnotation sets how long to wait after the pivot for the clock of the node to be
+	// synchronized before running recert, e.g. "5m". Recert fails if it is not synchronized by then
+	RecertClockSyncTimeoutAnnotation string = "lca.openshift.io/recertClockSyncTimeout"
+
+	// EtcdBackupAnnotation enables the etcd backup taken right before the Upgrade pivot when set to "Enabled"
+	EtcdBackupAnnotation string = "lca.openshift.io/etcdBackupBeforePivot"
+	EtcdBackupEnabled    string = "Enabled"
+
+	// ExtraManifestConflictPolicyAnnotation sets the precedence between the extra manifests and the resources
+	// restored by OADP. Prep fails on any conflict unless set to "PreferExtraManifests"
+	ExtraManifestConflictPolicyAnnotation string = "lca.openshift.io/extraManifestConflictPolicy"
+	PreferExtraManifests                  string = "PreferExtraManifests"
+
+	// SystemdUnitsAllowlistAnnotation lists the comma separated shell patterns of the systemd units and timers
+	// created by the admins of the node that Upgrade carries to the new stateroot, e.g. "site-*.timer,site-*.service"
+	SystemdUnitsAllowlistAnnotation string = "lca.openshift.io/systemdUnitsAllowlist"
+
+	// SkipSeedVersionCheckAnnotation lets Prep accept a seed image of the same or an older OCP version than the
+	// cluster, for the reinstalls and the downgrade tests of lab environments, when set to
+	// SkipSeedVersionCheckAcknowledgement. Neither is supported in production
+	SkipSeedVersionCheckAnnotation      string = "lca.openshift.io/skipSeedVersionCheck"
+	SkipSeedVersionCheckAcknowledgement string = "I-understand-this-is-unsupported"
+
 	// SeedGenName defines the valid name of the CR for the controller to reconcile
 	SeedGenName          string = "seedimage"
 	SeedGenSecretName    string = "seedgen"
@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 
@@ -89,7 +90,7 @@ func createIso() error {
 		return err
 	}
 	isoCreator := installationiso.NewInstallationIso(log, op, workDir)
-	if err = isoCreator.Create(seedImage, seedVersion, authFile, pullSecretFile, sshPublicKeyFile, lcaImage, rhcosLiveIso, installationDisk); err != nil {
+	if err = isoCreator.Create(context.Background(), seedImage, seedVersion, authFile, pullSecretFile, sshPublicKeyFile, lcaImage, rhcosLiveIso, installationDisk); err != nil {
 		err = fmt.Errorf("failed to create installation ISO: %w", err)
 		log.Errorf(err.Error())
 		return err
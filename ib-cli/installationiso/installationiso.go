@@ -1,6 +1,7 @@
 package installationiso
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"io"
@@ -53,20 +54,20 @@ const (
 	coreosInstallerImage      = "quay.io/coreos/coreos-installer:latest"
 )
 
-func (r *InstallationIso) Create(seedImage, seedVersion, authFile, pullSecretFile, sshPublicKeyPath, lcaImage, rhcosLiveIsoUrl, installationDisk string) error {
+func (r *InstallationIso) Create(ctx context.Context, seedImage, seedVersion, authFile, pullSecretFile, sshPublicKeyPath, lcaImage, rhcosLiveIsoUrl, installationDisk string) error {
 	r.log.Info("Creating IBI installation ISO")
 	err := r.validate()
 	if err != nil {
 		return err
 	}
-	err = r.createIgnitionFile(seedImage, seedVersion, authFile, pullSecretFile, sshPublicKeyPath, lcaImage, installationDisk)
+	err = r.createIgnitionFile(ctx, seedImage, seedVersion, authFile, pullSecretFile, sshPublicKeyPath, lcaImage, installationDisk)
 	if err != nil {
 		return err
 	}
 	if err := r.downloadLiveIso(rhcosLiveIsoUrl); err != nil {
 		return err
 	}
-	if err := r.embedIgnitionToIso(); err != nil {
+	if err := r.embedIgnitionToIso(ctx); err != nil {
 		return err
 	}
 	r.log.Infof("installation ISO created at: %s", path.Join(r.workDir, ibiIsoFileName))
@@ -82,16 +83,16 @@ func (r *InstallationIso) validate() error {
 	return nil
 }
 
-func (r *InstallationIso) createIgnitionFile(seedImage, seedVersion, authFile, pullSecretFile, sshPublicKeyPath, lcaImage, installationDisk string) error {
+func (r *InstallationIso) createIgnitionFile(ctx context.Context, seedImage, seedVersion, authFile, pullSecretFile, sshPublicKeyPath, lcaImage, installationDisk string) error {
 	r.log.Info("Generating Ignition Config")
 	err := r.renderButaneConfig(seedImage, seedVersion, authFile, pullSecretFile, sshPublicKeyPath, lcaImage, installationDisk)
 	if err != nil {
 		return err
 	}
-	return r.renderIgnitionFile()
+	return r.renderIgnitionFile(ctx)
 }
 
-func (r *InstallationIso) renderIgnitionFile() error {
+func (r *InstallationIso) renderIgnitionFile(ctx context.Context) error {
 	ibiIsoPath := path.Join(r.workDir, ibiIgnitionFileName)
 	if _, err := os.Stat(ibiIsoPath); err == nil {
 		r.log.Infof("ignition file exists (%s), deleting it", ibiIsoPath)
@@ -109,7 +110,7 @@ func (r *InstallationIso) renderIgnitionFile() error {
 		"-d", "/data",
 		path.Join("/data", butaneConfigFile),
 	}
-	ignitionContent, err := r.ops.RunInHostNamespace(command, args...)
+	ignitionContent, err := r.ops.RunInHostNamespace(ctx, command, args...)
 	if err != nil {
 		return fmt.Errorf("failed to render ignition from config: %w", err)
 	}
@@ -122,7 +123,7 @@ func (r *InstallationIso) renderIgnitionFile() error {
 	return nil
 }
 
-func (r *InstallationIso) embedIgnitionToIso() error {
+func (r *InstallationIso) embedIgnitionToIso(ctx context.Context) error {
 	ibiIsoPath := path.Join(r.workDir, ibiIsoFileName)
 	if _, err := os.Stat(ibiIsoPath); err == nil {
 		r.log.Infof("ibi ISO exists (%s), deleting it", ibiIsoPath)
@@ -141,7 +142,7 @@ func (r *InstallationIso) embedIgnitionToIso() error {
 		path.Join("/data", rhcosIsoFileName),
 	}
 
-	if _, err := r.ops.RunInHostNamespace(command, args...); err != nil {
+	if _, err := r.ops.RunInHostNamespace(ctx, command, args...); err != nil {
 		return fmt.Errorf("failed to embed ign with args %s: %w", args, err)
 	}
 	return nil
@@ -1,6 +1,7 @@
 package installationiso
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -141,7 +142,7 @@ func TestInstallationIso(t *testing.T) {
 				psFilePath = psFile.Name()
 			}
 			if tc.pullSecretExists && tc.authFileExists && tc.sshPublicKeyExists {
-				mockOps.EXPECT().RunInHostNamespace("podman", "run",
+				mockOps.EXPECT().RunInHostNamespace(gomock.Any(), "podman", "run",
 					"-v", fmt.Sprintf("%s:/data:rw,Z", tmpDir),
 					"--rm",
 					"quay.io/coreos/butane:release",
@@ -149,7 +150,7 @@ func TestInstallationIso(t *testing.T) {
 					"-d", "/data",
 					path.Join("/data", butaneConfigFile)).Return("", tc.renderCommandReturn).Times(1)
 				if tc.liveIsoUrlSuccess {
-					mockOps.EXPECT().RunInHostNamespace("podman", "run",
+					mockOps.EXPECT().RunInHostNamespace(gomock.Any(), "podman", "run",
 						"-v", fmt.Sprintf("%s:/data:rw,Z", tmpDir),
 						coreosInstallerImage,
 						"iso", "ignition", "embed",
@@ -167,7 +168,7 @@ func TestInstallationIso(t *testing.T) {
 				defer server.Close()
 			}
 			installationIso := NewInstallationIso(log, mockOps, tmpDir)
-			err := installationIso.Create(seedImage, seedVersion, authFilePath, psFilePath, sshPublicKeyPath, lcaImage, rhcosLiveIsoUrl, installationDisk)
+			err := installationIso.Create(context.Background(), seedImage, seedVersion, authFilePath, psFilePath, sshPublicKeyPath, lcaImage, rhcosLiveIsoUrl, installationDisk)
 			if tc.expectedError == "" {
 				assert.Equal(t, err, nil)
 			} else {
@@ -0,0 +1,391 @@
+package artifactsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+const (
+	// Dir is the workspace directory of the archives downloaded from an HTTP or S3 source, removed once copied
+	Dir = utils.IBUWorkspacePath + "/artifacts"
+
+	// DefaultConfigMapName is the ConfigMap in the LCA namespace configuring the artifact source of every IBU, unless
+	// an IBU names another one with the artifact source annotation
+	DefaultConfigMapName = "lca-artifact-source"
+
+	// ConfigMap keys of the artifact source configuration
+	TypeKey              = "type"
+	RegistryKey          = "registry"
+	URLKey               = "url"
+	EndpointKey          = "endpoint"
+	BucketKey            = "bucket"
+	RegionKey            = "region"
+	PrefixKey            = "prefix"
+	CredentialsSecretKey = "credentialsSecret"
+
+	// Keys of the credentials secret: basic auth for HTTP, access keys for S3. A registry source uses the pull secret.
+	UsernameKey        = "username"
+	PasswordKey        = "password"
+	AccessKeyIDKey     = "aws_access_key_id"
+	SecretAccessKeyKey = "aws_secret_access_key"
+
+	// EnvArtifactSource holds the JSON artifact source configuration passed to the precaching job, and the env vars
+	// below its credentials
+	EnvArtifactSource   = "ARTIFACT_SOURCE"
+	EnvUsername         = "ARTIFACT_SOURCE_USERNAME"
+	EnvPassword         = "ARTIFACT_SOURCE_PASSWORD"
+	EnvAccessKeyID      = "ARTIFACT_SOURCE_AWS_ACCESS_KEY_ID"
+	EnvSecretAccessKey  = "ARTIFACT_SOURCE_AWS_SECRET_ACCESS_KEY"
+	defaultS3Region     = "us-east-1"
+	archiveExtension    = ".tar"
+	containersStorage   = "containers-storage:"
+	dockerTransport     = "docker://"
+	ociArchiveTransport = "oci-archive:"
+)
+
+// Types of artifact source
+const (
+	// TypeRegistry pulls the images from an OCI registry cache, such as a Nexus or Artifactory proxy, under Registry
+	TypeRegistry = "registry"
+	// TypeHTTP downloads the OCI archives of the images from an HTTP server, under URL
+	TypeHTTP = "http"
+	// TypeS3 downloads the OCI archives of the images from an S3 bucket
+	TypeS3 = "s3"
+)
+
+// Config is the configuration of an artifact source, from which the seed image and the images to pre-cache are
+// downloaded instead of their registry, without repurposing the mirror configuration of the cluster
+type Config struct {
+	Type string `json:"type"`
+	// Registry is the registry and path under which the images are found by their full reference,
+	// e.g. nexus.example.com:8443/cache/quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64
+	Registry string `json:"registry,omitempty"`
+	// URL is the base URL of the HTTP server
+	URL string `json:"url,omitempty"`
+	// Endpoint, Bucket and Region locate the S3 bucket, accessed path-style
+	Endpoint string `json:"endpoint,omitempty"`
+	Bucket   string `json:"bucket,omitempty"`
+	Region   string `json:"region,omitempty"`
+	// Prefix is prepended to the archive paths, for HTTP and S3 sources
+	Prefix string `json:"prefix,omitempty"`
+	// CredentialsSecret is the secret in the LCA namespace holding the credentials of the HTTP or S3 source
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+// Credentials of the HTTP or S3 source
+type Credentials struct {
+	Username        string
+	Password        string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Validate checks the configuration has the fields required by its type
+func (c *Config) Validate() error {
+	switch c.Type {
+	case TypeRegistry:
+		if c.Registry == "" {
+			return fmt.Errorf("%s artifact source requires %s", c.Type, RegistryKey)
+		}
+	case TypeHTTP:
+		if _, err := url.ParseRequestURI(c.URL); err != nil {
+			return fmt.Errorf("%s artifact source requires a valid %s: %w", c.Type, URLKey, err)
+		}
+	case TypeS3:
+		if _, err := url.ParseRequestURI(c.Endpoint); err != nil {
+			return fmt.Errorf("%s artifact source requires a valid %s: %w", c.Type, EndpointKey, err)
+		}
+		if c.Bucket == "" {
+			return fmt.Errorf("%s artifact source requires %s", c.Type, BucketKey)
+		}
+	default:
+		return fmt.Errorf("unknown artifact source type %q, expected one of %s, %s, %s", c.Type, TypeRegistry, TypeHTTP, TypeS3)
+	}
+	return nil
+}
+
+// Host returns the host the artifacts are downloaded from, to allow it in the egress of the precaching job
+func (c *Config) Host() string {
+	switch c.Type {
+	case TypeRegistry:
+		return strings.SplitN(c.Registry, "/", 2)[0]
+	case TypeHTTP:
+		if u, err := url.Parse(c.URL); err == nil {
+			return u.Host
+		}
+	case TypeS3:
+		if u, err := url.Parse(c.Endpoint); err == nil {
+			return u.Host
+		}
+	}
+	return ""
+}
+
+// LoadConfig reads the artifact source configuration from the named ConfigMap in the LCA namespace. It returns nil if
+// the name is the DefaultConfigMapName and the ConfigMap does not exist, as no artifact source is configured.
+func LoadConfig(ctx context.Context, c client.Reader, name string) (*Config, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: common.LcaNamespace}, cm); err != nil {
+		if k8serrors.IsNotFound(err) && name == DefaultConfigMapName {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get artifact source configmap %s: %w", name, err)
+	}
+	config := &Config{
+		Type:              cm.Data[TypeKey],
+		Registry:          strings.TrimSuffix(cm.Data[RegistryKey], "/"),
+		URL:               strings.TrimSuffix(cm.Data[URLKey], "/"),
+		Endpoint:          strings.TrimSuffix(cm.Data[EndpointKey], "/"),
+		Bucket:            cm.Data[BucketKey],
+		Region:            cm.Data[RegionKey],
+		Prefix:            strings.Trim(cm.Data[PrefixKey], "/"),
+		CredentialsSecret: cm.Data[CredentialsSecretKey],
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid artifact source configmap %s: %w", name, err)
+	}
+	return config, nil
+}
+
+// LoadCredentials reads the credentials of the source from its secret, if any
+func (c *Config) LoadCredentials(ctx context.Context, reader client.Reader) (*Credentials, error) {
+	credentials := &Credentials{}
+	if c.CredentialsSecret == "" {
+		return credentials, nil
+	}
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: c.CredentialsSecret, Namespace: common.LcaNamespace}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get artifact source credentials secret %s: %w", c.CredentialsSecret, err)
+	}
+	credentials.Username = string(secret.Data[UsernameKey])
+	credentials.Password = string(secret.Data[PasswordKey])
+	credentials.AccessKeyID = string(secret.Data[AccessKeyIDKey])
+	credentials.SecretAccessKey = string(secret.Data[SecretAccessKeyKey])
+	return credentials, nil
+}
+
+// EnvVars returns the env vars passing the configuration and the credentials of the source to the precaching job,
+// the credentials referencing their secret
+func (c *Config) EnvVars() ([]corev1.EnvVar, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal artifact source config: %w", err)
+	}
+	envVars := []corev1.EnvVar{{Name: EnvArtifactSource, Value: string(data)}}
+	if c.CredentialsSecret == "" {
+		return envVars, nil
+	}
+	optional := true
+	for _, credential := range [][2]string{
+		{EnvUsername, UsernameKey},
+		{EnvPassword, PasswordKey},
+		{EnvAccessKeyID, AccessKeyIDKey},
+		{EnvSecretAccessKey, SecretAccessKeyKey},
+	} {
+		envVars = append(envVars, corev1.EnvVar{
+			Name: credential[0],
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: c.CredentialsSecret},
+				Key:                  credential[1],
+				Optional:             &optional,
+			}},
+		})
+	}
+	return envVars, nil
+}
+
+// FromEnv returns the artifact source configuration and credentials passed to the precaching job, or nil if none
+func FromEnv() (*Config, *Credentials, error) {
+	value := os.Getenv(EnvArtifactSource)
+	if value == "" {
+		return nil, nil, nil
+	}
+	config := &Config{}
+	if err := json.Unmarshal([]byte(value), config); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", EnvArtifactSource, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return config, &Credentials{
+		Username:        os.Getenv(EnvUsername),
+		Password:        os.Getenv(EnvPassword),
+		AccessKeyID:     os.Getenv(EnvAccessKeyID),
+		SecretAccessKey: os.Getenv(EnvSecretAccessKey),
+	}, nil
+}
+
+// Source fetches the artifacts of an image based upgrade
+type Source interface {
+	// Type returns the type of the source
+	Type() string
+	// Fetch makes the image available for copying to the container storage, downloading its archive into dir if the
+	// source is not a registry
+	Fetch(ctx context.Context, image, dir string) (*Artifact, error)
+}
+
+// Artifact is an image fetched from an artifact source
+type Artifact struct {
+	// Reference is the reference of the image in a registry source
+	Reference string
+	// Archive is the path of the OCI archive of the image downloaded from an HTTP or S3 source
+	Archive string
+}
+
+// TransportReference returns the reference to copy the artifact from, with the path of its archive under root
+// trimmed, for a copy run chroot in root
+func (a *Artifact) TransportReference(root string) string {
+	if a.Archive == "" {
+		return dockerTransport + a.Reference
+	}
+	return ociArchiveTransport + strings.TrimPrefix(a.Archive, root)
+}
+
+// Remove deletes the downloaded archive of the artifact, if any
+func (a *Artifact) Remove() error {
+	if a.Archive == "" {
+		return nil
+	}
+	if err := os.Remove(a.Archive); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove artifact archive %s: %w", a.Archive, err)
+	}
+	return nil
+}
+
+// CopyArgs returns the skopeo arguments copying the artifact to the container storage under the reference of the
+// image, so it is found by its original name without any mirror configuration
+func CopyArgs(transportReference, image, authFile string) []string {
+	args := []string{"copy", "--retry-times", "3"}
+	if strings.HasPrefix(transportReference, dockerTransport) && authFile != "" {
+		args = append(args, "--src-authfile", authFile)
+	}
+	return append(args, transportReference, containersStorage+image)
+}
+
+// New returns the source of the configuration
+func New(config *Config, credentials *Credentials) (Source, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if credentials == nil {
+		credentials = &Credentials{}
+	}
+	switch config.Type {
+	case TypeRegistry:
+		return &registrySource{registry: config.Registry}, nil
+	case TypeHTTP:
+		return &httpSource{url: config.URL, prefix: config.Prefix, credentials: credentials, client: http.DefaultClient}, nil
+	default:
+		region := config.Region
+		if region == "" {
+			region = defaultS3Region
+		}
+		return &s3Source{endpoint: config.Endpoint, bucket: config.Bucket, region: region, prefix: config.Prefix,
+			credentials: credentials, client: http.DefaultClient}, nil
+	}
+}
+
+// ArchivePath returns the path of the OCI archive of the image in an HTTP or S3 source: its repository followed by
+// its tag, or its digest with the colon replaced by a dash, e.g. quay.io/openshift-release-dev/ocp-release/4.15.0.tar
+// or quay.io/openshift-release-dev/ocp-v4.0-art-dev/sha256-1234.tar
+func ArchivePath(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i] + "/" + strings.Replace(image[i+1:], ":", "-", 1) + archiveExtension
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i] + "/" + image[i+1:] + archiveExtension
+	}
+	return image + "/latest" + archiveExtension
+}
+
+type registrySource struct {
+	registry string
+}
+
+func (s *registrySource) Type() string {
+	return TypeRegistry
+}
+
+func (s *registrySource) Fetch(_ context.Context, image, _ string) (*Artifact, error) {
+	return &Artifact{Reference: s.registry + "/" + image}, nil
+}
+
+type httpSource struct {
+	url         string
+	prefix      string
+	credentials *Credentials
+	client      *http.Client
+}
+
+func (s *httpSource) Type() string {
+	return TypeHTTP
+}
+
+func (s *httpSource) Fetch(ctx context.Context, image, dir string) (*Artifact, error) {
+	archiveURL := s.url + "/" + joinPrefix(s.prefix, ArchivePath(image))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", archiveURL, err)
+	}
+	if s.credentials.Username != "" {
+		req.SetBasicAuth(s.credentials.Username, s.credentials.Password)
+	}
+	return download(s.client, req, image, dir)
+}
+
+func joinPrefix(prefix, path string) string {
+	if prefix == "" {
+		return path
+	}
+	return prefix + "/" + path
+}
+
+// download saves the response body of the request as the archive of the image in dir
+func download(httpClient *http.Client, req *http.Request, image, dir string) (*Artifact, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", req.URL.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", req.URL.Redacted(), resp.Status)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory %s: %w", dir, err)
+	}
+	archive := filepath.Join(dir, strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(image)+archiveExtension)
+	file, err := os.OpenFile(archive, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifact archive %s: %w", archive, err)
+	}
+	artifact := &Artifact{Archive: archive}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		_ = artifact.Remove()
+		return nil, fmt.Errorf("failed to download %s: %w", req.URL.Redacted(), err)
+	}
+	if err := file.Close(); err != nil {
+		_ = artifact.Remove()
+		return nil, fmt.Errorf("failed to write artifact archive %s: %w", archive, err)
+	}
+	return artifact, nil
+}
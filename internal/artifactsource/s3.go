@@ -0,0 +1,114 @@
+package artifactsource
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3Service        = "s3"
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3DateFormat     = "20060102T150405Z"
+	s3EmptyBodyHash  = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3ContentHeader  = "X-Amz-Content-Sha256"
+	s3DateHeader     = "X-Amz-Date"
+	s3AuthorizedDate = 8
+)
+
+// now returns the signing time of the S3 requests, overridden in tests
+var now = time.Now
+
+type s3Source struct {
+	endpoint    string
+	bucket      string
+	region      string
+	prefix      string
+	credentials *Credentials
+	client      *http.Client
+}
+
+func (s *s3Source) Type() string {
+	return TypeS3
+}
+
+func (s *s3Source) Fetch(ctx context.Context, image, dir string) (*Artifact, error) {
+	objectURL := s.endpoint + "/" + s.bucket + "/" + escapePath(joinPrefix(s.prefix, ArchivePath(image)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", objectURL, err)
+	}
+	if s.credentials.AccessKeyID != "" {
+		signS3Request(req, s.credentials, s.region, now().UTC())
+	}
+	return download(s.client, req, image, dir)
+}
+
+// escapePath escapes each segment of the object key
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// signS3Request signs the bodiless request with AWS Signature Version 4, the authentication of the S3 API
+func signS3Request(req *http.Request, credentials *Credentials, region string, t time.Time) {
+	amzDate := t.Format(s3DateFormat)
+	req.Header.Set(s3DateHeader, amzDate)
+	req.Header.Set(s3ContentHeader, s3EmptyBodyHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3EmptyBodyHash,
+	}, "\n")
+	scope := strings.Join([]string{amzDate[:s3AuthorizedDate], region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range []string{amzDate[:s3AuthorizedDate], region, s3Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
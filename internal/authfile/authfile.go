@@ -0,0 +1,75 @@
+// Package authfile stores the registry credentials of an operation, e.g. the pull-secret of the seed image, in a tmpfs
+// mounted for the operation instead of a file of the workspace on the persistent disk. The tmpfs is mounted in the
+// mount namespace of the LCA container, where the host commands run chrooted, so the credentials are neither written
+// to the disk nor visible from the mount namespace of the host, and are gone with the unmount at the end of the
+// operation, or with the mount namespace of the LCA container if it dies before.
+package authfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+const (
+	// fileName is the name of the auth file in its tmpfs
+	fileName = "auth.json"
+	// mountOptions bound the tmpfs to the size of a pull-secret and make it accessible to root only
+	mountOptions = "size=1m,mode=0700,nosuid,nodev,noexec"
+)
+
+// baseDir holds the mount points of the auth files, on the runtime tmpfs of the host
+var baseDir = "/run/lca-auth"
+
+// AuthFile is an auth file in a tmpfs mounted for an operation
+type AuthFile struct {
+	// Path is the path of the auth file on the host, for the --authfile option of the host podman and skopeo
+	Path string
+
+	executor   ops.Execute
+	mountPoint string
+}
+
+// Create mounts a tmpfs for the operation and writes the credentials to an auth file in it. The auth file must be
+// removed at the end of the operation.
+func Create(executor ops.Execute, operation string, data []byte) (*AuthFile, error) {
+	if err := os.MkdirAll(common.PathOutsideChroot(baseDir), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", baseDir, err)
+	}
+	dir, err := os.MkdirTemp(common.PathOutsideChroot(baseDir), operation+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the mount point of the %s auth file: %w", operation, err)
+	}
+	a := &AuthFile{
+		executor:   executor,
+		mountPoint: filepath.Join(baseDir, filepath.Base(dir)),
+	}
+	a.Path = filepath.Join(a.mountPoint, fileName)
+
+	if _, err := executor.Execute("mount", "-t", "tmpfs", "-o", mountOptions, "tmpfs", a.mountPoint); err != nil {
+		_ = os.Remove(dir)
+		return nil, fmt.Errorf("failed to mount the tmpfs of the %s auth file: %w", operation, err)
+	}
+	if err := os.WriteFile(common.PathOutsideChroot(a.Path), data, 0o600); err != nil {
+		err = fmt.Errorf("failed to write the %s auth file: %w", operation, err)
+		if removeErr := a.Remove(); removeErr != nil {
+			return nil, fmt.Errorf("%w, and to remove it: %w", err, removeErr)
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// Remove unmounts the tmpfs of the auth file, discarding the credentials, and removes its mount point
+func (a *AuthFile) Remove() error {
+	if _, err := a.executor.Execute("umount", a.mountPoint); err != nil {
+		return fmt.Errorf("failed to unmount the tmpfs of the auth file %s: %w", a.Path, err)
+	}
+	if err := os.Remove(common.PathOutsideChroot(a.mountPoint)); err != nil {
+		return fmt.Errorf("failed to remove the mount point of the auth file %s: %w", a.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package authfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+func TestCreate(t *testing.T) {
+	origBaseDir := baseDir
+	defer func() { baseDir = origBaseDir }()
+	baseDir = filepath.Join(t.TempDir(), "lca-auth")
+
+	executorMock := ops.NewMockExecute(gomock.NewController(t))
+
+	// The auth file is written in the mounted tmpfs, and its tmpfs unmounted on removal
+	var mountPoint string
+	executorMock.EXPECT().Execute("mount", "-t", "tmpfs", "-o", mountOptions, "tmpfs", gomock.Any()).DoAndReturn(
+		func(command string, args ...string) (string, error) {
+			mountPoint = args[len(args)-1]
+			return "", nil
+		})
+	a, err := Create(executorMock, "seed-pull-secret", []byte(`{"auths":{}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(mountPoint, "auth.json"), a.Path)
+	assert.Equal(t, baseDir, filepath.Dir(mountPoint))
+	data, err := os.ReadFile(a.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"auths":{}}`, string(data))
+	info, err := os.Stat(a.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	executorMock.EXPECT().Execute("umount", mountPoint).DoAndReturn(func(command string, args ...string) (string, error) {
+		// the tmpfs content goes with the unmount
+		return "", os.Remove(a.Path)
+	})
+	assert.NoError(t, a.Remove())
+	assert.NoDirExists(t, mountPoint)
+
+	// No auth file without its tmpfs
+	executorMock.EXPECT().Execute("mount", gomock.Any()).Return("", errors.New("exit status 32"))
+	_, err = Create(executorMock, "seed-pull-secret", []byte(`{"auths":{}}`))
+	assert.ErrorContains(t, err, "failed to mount the tmpfs of the seed-pull-secret auth file")
+	entries, err := os.ReadDir(baseDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/common/collections"
 	"github.com/openshift-kni/lifecycle-agent/utils"
 
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
@@ -163,7 +164,7 @@ func getObjsFromAnnotations(backup *velerov1.Backup) ([]ObjMetadata, error) {
 		if k != applyLabelAnn || v == "" {
 			continue
 		}
-		objStrings := common.RemoveDuplicates[string](strings.Split(v, ","))
+		objStrings := collections.Unique(strings.Split(v, ","))
 		for _, objString := range objStrings {
 			objStringSplitted := strings.Split(objString, "/")
 			if len(objStringSplitted) < 3 || len(objStringSplitted) > 5 {
@@ -25,6 +25,7 @@ import (
 	"github.com/go-logr/logr"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
 
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
 
@@ -88,6 +89,7 @@ type BackuperRestorer interface {
 	RestoreOadpConfigurations(ctx context.Context) error
 	StartOrTrackBackup(ctx context.Context, backups []*velerov1.Backup) (*BackupTracker, error)
 	StartOrTrackRestore(ctx context.Context, restores []*velerov1.Restore) (*RestoreTracker, error)
+	ValidateExtraManifestConflicts(ctx context.Context, content []lcav1alpha1.ConfigMapRef, manifests []unstructured.Unstructured, preferExtraManifests bool) error
 	ValidateOadpConfigmap(ctx context.Context, content []lcav1alpha1.ConfigMapRef) error
 }
 
@@ -117,6 +119,21 @@ func (e *BRStatusError) Error() string {
 	return fmt.Sprintf(e.ErrMessage)
 }
 
+// Is classifies the error with the matching lcaerrors sentinel
+func (e *BRStatusError) Is(target error) bool {
+	switch target {
+	case lcaerrors.ErrInvalidSpec:
+		return e.Reason == "NotFound" || e.Reason == "FailedValidation"
+	case lcaerrors.ErrBackup:
+		return e.Reason == "Failed" && (e.Type == "Backup" || e.Type == "OADP")
+	case lcaerrors.ErrRestore:
+		return e.Reason == "Failed" && e.Type == "Restore"
+	case lcaerrors.ErrStorageBackend:
+		return e.Reason == "Unavailable"
+	}
+	return false
+}
+
 func NewBRNotFoundError(msg string) *BRStatusError {
 	return &BRStatusError{
 		Type:       "configmap",
@@ -0,0 +1,241 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/lcenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backuprestore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+// ManifestConflict is an extra manifest resource also included in an OADP backup, and therefore
+// written twice after the pivot: first by the extra manifests, then by the OADP restore
+type ManifestConflict struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	Backup           string
+}
+
+func (c ManifestConflict) String() string {
+	kind := c.GroupVersionKind.Kind
+	if c.GroupVersionKind.Group != "" {
+		kind += "." + c.GroupVersionKind.Group
+	}
+	if c.Namespace != "" {
+		return fmt.Sprintf("%s %s/%s (backup %s)", kind, c.Namespace, c.Name, c.Backup)
+	}
+	return fmt.Sprintf("%s %s (backup %s)", kind, c.Name, c.Backup)
+}
+
+// ValidateExtraManifestConflicts fails the validation if any of the extra manifests is also included in
+// an OADP backup, unless preferExtraManifests is set.
+//
+// The extra manifests are applied before the OADP restores after the pivot, and Velero does not overwrite
+// resources that already exist unless the restore sets existingResourcePolicy to update. When
+// preferExtraManifests is set the extra manifests therefore win, and a restore with the update policy
+// including a conflicting resource is rejected as it would silently revert the extra manifest.
+func (h *BRHandler) ValidateExtraManifestConflicts(ctx context.Context, content []lcav1alpha1.ConfigMapRef,
+	manifests []unstructured.Unstructured, preferExtraManifests bool) error {
+	configmaps, err := common.GetConfigMaps(ctx, h.Client, content)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return NewBRFailedValidationError("OADP", fmt.Sprintf("OADP configmap not found, error: %s. Please create the configmap.", err.Error()))
+		}
+		return fmt.Errorf("failed to get oadp configMaps: %w", err)
+	}
+	backups, err := h.extractBackupFromConfigmaps(ctx, configmaps)
+	if err != nil {
+		return err
+	}
+
+	conflicts, err := h.findManifestConflicts(manifests, backups)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	h.Log.Info("Extra manifests included in OADP backups", "conflicts", joinConflicts(conflicts))
+
+	if !preferExtraManifests {
+		errMsg := fmt.Sprintf("Extra manifests conflict with resources restored by OADP: %s. "+
+			"Remove them from either the extra manifests or the OADP backups, or let the extra manifests take precedence "+
+			"with the PreferExtraManifests conflict policy", joinConflicts(conflicts))
+		h.Log.Error(nil, errMsg)
+		return NewBRFailedValidationError("OADP", errMsg)
+	}
+
+	restores, err := h.extractRestoreFromConfigmaps(ctx, configmaps)
+	if err != nil {
+		return err
+	}
+	var overwritten []ManifestConflict
+	for _, conflict := range conflicts {
+		conflict := conflict
+		if lo.ContainsBy(restores, func(restore *velerov1.Restore) bool {
+			return restore.Spec.BackupName == conflict.Backup && restore.Spec.ExistingResourcePolicy == velerov1.PolicyTypeUpdate
+		}) {
+			overwritten = append(overwritten, conflict)
+		}
+	}
+	if len(overwritten) != 0 {
+		errMsg := fmt.Sprintf("Extra manifests cannot take precedence over resources restored with the %s existingResourcePolicy: %s",
+			velerov1.PolicyTypeUpdate, joinConflicts(overwritten))
+		h.Log.Error(nil, errMsg)
+		return NewBRFailedValidationError("OADP", errMsg)
+	}
+	return nil
+}
+
+func joinConflicts(conflicts []ManifestConflict) string {
+	return strings.Join(lo.Map(conflicts, func(c ManifestConflict, _ int) string { return c.String() }), ", ")
+}
+
+// findManifestConflicts returns the manifests within the scope of the backups
+func (h *BRHandler) findManifestConflicts(manifests []unstructured.Unstructured, backups []*velerov1.Backup) ([]ManifestConflict, error) {
+	var conflicts []ManifestConflict
+	for _, manifest := range manifests {
+		gvk := manifest.GroupVersionKind()
+		// The resources of the CRDs installed by the extra manifests are not known yet, fall back to the usual naming
+		gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+		if mapping, err := h.Client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err == nil {
+			gvr = mapping.Resource
+		}
+
+		for _, backup := range backups {
+			included, err := isIncludedInBackup(&manifest, gvr, backup) //nolint:gosec
+			if err != nil {
+				return nil, err
+			}
+			if included {
+				conflicts = append(conflicts, ManifestConflict{
+					GroupVersionKind: gvk,
+					Namespace:        manifest.GetNamespace(),
+					Name:             manifest.GetName(),
+					Backup:           backup.GetName(),
+				})
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// isIncludedInBackup tells whether the backup includes the object, following the Velero filtering rules.
+// The label selectors are evaluated against the labels of the given object.
+func isIncludedInBackup(obj *unstructured.Unstructured, gvr schema.GroupVersionResource, backup *velerov1.Backup) (bool, error) {
+	objs, err := getObjsFromAnnotations(backup)
+	if err != nil {
+		return false, NewBRFailedValidationError("OADP", err.Error())
+	}
+	if len(objs) != 0 {
+		// Lifecycle agent restricts the backup to the labeled objects of the apply-label annotation
+		return lo.ContainsBy(objs, func(o ObjMetadata) bool {
+			return o.Group == gvr.Group && o.Resource == gvr.Resource && o.Namespace == obj.GetNamespace() && o.Name == obj.GetName()
+		}), nil
+	}
+
+	if obj.GetNamespace() != "" {
+		if !matchesFilter(backup.Spec.IncludedNamespaces, backup.Spec.ExcludedNamespaces, obj.GetNamespace()) ||
+			!isNamespaceScopedIncluded(backup, obj.GetKind(), gvr) {
+			return false, nil
+		}
+	} else if !isClusterScopedIncluded(backup, obj.GetKind(), gvr) {
+		return false, nil
+	}
+
+	return matchesLabelSelectors(backup, obj.GetLabels())
+}
+
+func isNamespaceScopedIncluded(backup *velerov1.Backup, kind string, gvr schema.GroupVersionResource) bool {
+	spec := backup.Spec
+	if len(spec.IncludedNamespaceScopedResources) != 0 || len(spec.ExcludedNamespaceScopedResources) != 0 {
+		return matchesResourceFilter(spec.IncludedNamespaceScopedResources, spec.ExcludedNamespaceScopedResources, kind, gvr)
+	}
+	return matchesResourceFilter(spec.IncludedResources, spec.ExcludedResources, kind, gvr)
+}
+
+func isClusterScopedIncluded(backup *velerov1.Backup, kind string, gvr schema.GroupVersionResource) bool {
+	spec := backup.Spec
+	if len(spec.IncludedClusterScopedResources) != 0 || len(spec.ExcludedClusterScopedResources) != 0 {
+		return len(spec.IncludedClusterScopedResources) != 0 &&
+			matchesResourceFilter(spec.IncludedClusterScopedResources, spec.ExcludedClusterScopedResources, kind, gvr)
+	}
+	if !matchesResourceFilter(spec.IncludedResources, spec.ExcludedResources, kind, gvr) {
+		return false
+	}
+	if spec.IncludeClusterResources != nil {
+		return *spec.IncludeClusterResources
+	}
+	// By default, Velero only backs up the cluster-scoped resources when backing up all namespaces
+	return len(spec.IncludedNamespaces) == 0 || lo.Contains(spec.IncludedNamespaces, "*")
+}
+
+func matchesFilter(included, excluded []string, value string) bool {
+	if lo.Contains(excluded, value) {
+		return false
+	}
+	return len(included) == 0 || lo.Contains(included, "*") || lo.Contains(included, value)
+}
+
+// matchesResourceFilter matches a resource against Velero resource filters, which may use the plural
+// resource name, qualified or not by the group, or the kind
+func matchesResourceFilter(included, excluded []string, kind string, gvr schema.GroupVersionResource) bool {
+	names := []string{gvr.Resource, strings.ToLower(kind)}
+	if gvr.Group != "" {
+		names = append(names, gvr.Resource+"."+gvr.Group)
+	}
+	matches := func(filter []string) bool {
+		return lo.ContainsBy(filter, func(f string) bool { return lo.Contains(names, strings.ToLower(f)) })
+	}
+	if matches(excluded) || lo.Contains(excluded, "*") {
+		return false
+	}
+	return len(included) == 0 || lo.Contains(included, "*") || matches(included)
+}
+
+func matchesLabelSelectors(backup *velerov1.Backup, objLabels map[string]string) (bool, error) {
+	selectors := backup.Spec.OrLabelSelectors
+	if backup.Spec.LabelSelector != nil {
+		selectors = []*metav1.LabelSelector{backup.Spec.LabelSelector}
+	}
+	if len(selectors) == 0 {
+		return true, nil
+	}
+	for _, s := range selectors {
+		selector, err := metav1.LabelSelectorAsSelector(s)
+		if err != nil {
+			return false, NewBRFailedValidationError("OADP", fmt.Sprintf("invalid label selector in backup %s: %s", backup.GetName(), err.Error()))
+		}
+		if selector.Matches(labels.Set(objLabels)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
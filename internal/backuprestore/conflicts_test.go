@@ -0,0 +1,150 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/lcenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backuprestore
+
+import (
+	"context"
+	"testing"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/yaml"
+)
+
+func TestValidateExtraManifestConflicts(t *testing.T) {
+	manifests := []unstructured.Unstructured{
+		*newUnstructured("v1", "ConfigMap", "openshift-test", "conflicting"),
+		*newUnstructured("v1", "ConfigMap", "other", "not-in-namespace"),
+		*newUnstructured("apps/v1", "Deployment", "openshift-test", "not-in-resources"),
+		*newUnstructured("rbac.authorization.k8s.io/v1", "ClusterRole", "", "cluster-scoped"),
+	}
+
+	testcases := []struct {
+		name                 string
+		policy               velerov1.PolicyType
+		preferExtraManifests bool
+		expectedErr          string
+	}{
+		{
+			name:        "conflict fails by default",
+			expectedErr: "Extra manifests conflict with resources restored by OADP: ConfigMap openshift-test/conflicting (backup backup1)",
+		},
+		{
+			name:                 "extra manifests take precedence",
+			preferExtraManifests: true,
+		},
+		{
+			name:                 "extra manifests cannot take precedence over the update policy",
+			policy:               velerov1.PolicyTypeUpdate,
+			preferExtraManifests: true,
+			expectedErr:          "Extra manifests cannot take precedence over resources restored with the update existingResourcePolicy: ConfigMap openshift-test/conflicting (backup backup1)",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			backup := fakeBackupCr("backup1", "1", "configmaps")
+			restore := fakeRestoreCr("restore1", "1", "backup1")
+			restore.Spec.ExistingResourcePolicy = tc.policy
+			backupBytes, _ := yaml.Marshal(backup)
+			restoreBytes, _ := yaml.Marshal(restore)
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "oadp-cm", Namespace: oadpNs},
+				Data:       map[string]string{"backup": string(backupBytes), "restore": string(restoreBytes)},
+			}
+
+			fakeClient, err := getFakeClientFromObjects(cm)
+			assert.NoError(t, err)
+			handler := &BRHandler{Client: fakeClient, Log: ctrl.Log.WithName("BackupRestore")}
+
+			err = handler.ValidateExtraManifestConflicts(context.Background(),
+				[]lcav1alpha1.ConfigMapRef{{Name: "oadp-cm", Namespace: oadpNs}}, manifests, tc.preferExtraManifests)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, IsBRFailedValidationError(err))
+			assert.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestIsIncludedInBackup(t *testing.T) {
+	deployment := newUnstructuredWithLabel("apps/v1", "Deployment", "openshift-test", "app", "app", "test")
+	deploymentGvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	testcases := []struct {
+		name     string
+		spec     velerov1.BackupSpec
+		ann      string
+		expected bool
+	}{
+		{
+			name:     "all namespaces and resources",
+			expected: true,
+		},
+		{
+			name:     "resource qualified by its group",
+			spec:     velerov1.BackupSpec{IncludedNamespaces: []string{"*"}, IncludedResources: []string{"deployments.apps"}},
+			expected: true,
+		},
+		{
+			name: "excluded namespace",
+			spec: velerov1.BackupSpec{ExcludedNamespaces: []string{"openshift-test"}},
+		},
+		{
+			name: "excluded resource",
+			spec: velerov1.BackupSpec{ExcludedNamespaceScopedResources: []string{"deployments"}},
+		},
+		{
+			name:     "matching label selector",
+			spec:     velerov1.BackupSpec{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}},
+			expected: true,
+		},
+		{
+			name: "not matching label selectors",
+			spec: velerov1.BackupSpec{OrLabelSelectors: []*metav1.LabelSelector{{MatchLabels: map[string]string{"app": "other"}}}},
+		},
+		{
+			name:     "object of the apply-label annotation",
+			ann:      "apps/v1/deployments/openshift-test/app",
+			expected: true,
+		},
+		{
+			name: "object not in the apply-label annotation",
+			ann:  "apps/v1/deployments/openshift-test/other",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			backup := &velerov1.Backup{Spec: tc.spec}
+			if tc.ann != "" {
+				backup.SetAnnotations(map[string]string{applyLabelAnn: tc.ann})
+			}
+			included, err := isIncludedInBackup(deployment, deploymentGvr, backup)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, included)
+		})
+	}
+}
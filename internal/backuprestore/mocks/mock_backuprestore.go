@@ -16,6 +16,7 @@ import (
 	backuprestore "github.com/openshift-kni/lifecycle-agent/internal/backuprestore"
 	v1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
 	gomock "go.uber.org/mock/gomock"
+	unstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 //go:generate mockgen -source ../common.go -destination mock_backuprestore.go -write_generate_directive
@@ -174,6 +175,20 @@ func (mr *MockBackuperRestorerMockRecorder) StartOrTrackRestore(ctx, restores an
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartOrTrackRestore", reflect.TypeOf((*MockBackuperRestorer)(nil).StartOrTrackRestore), ctx, restores)
 }
 
+// ValidateExtraManifestConflicts mocks base method.
+func (m *MockBackuperRestorer) ValidateExtraManifestConflicts(ctx context.Context, content []v1alpha1.ConfigMapRef, manifests []unstructured.Unstructured, preferExtraManifests bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateExtraManifestConflicts", ctx, content, manifests, preferExtraManifests)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateExtraManifestConflicts indicates an expected call of ValidateExtraManifestConflicts.
+func (mr *MockBackuperRestorerMockRecorder) ValidateExtraManifestConflicts(ctx, content, manifests, preferExtraManifests any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateExtraManifestConflicts", reflect.TypeOf((*MockBackuperRestorer)(nil).ValidateExtraManifestConflicts), ctx, content, manifests, preferExtraManifests)
+}
+
 // ValidateOadpConfigmap mocks base method.
 func (m *MockBackuperRestorer) ValidateOadpConfigmap(ctx context.Context, content []v1alpha1.ConfigMapRef) error {
 	m.ctrl.T.Helper()
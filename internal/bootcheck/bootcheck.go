@@ -0,0 +1,202 @@
+// Package bootcheck verifies that the boot partition can take the boot entry of a new ostree deployment before
+// the Prep stage deploys the new stateroot, as a full /boot or an inconsistent bootloader only fails the ostree
+// deployment late, with a cryptic error.
+package bootcheck
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+const (
+	// headroomPercent is added to the size of the largest boot directory for the boot loader entries and config
+	headroomPercent = 10
+)
+
+var (
+	bootDir        = "/boot"
+	bootupctlPath  = "/usr/bin/bootupctl"
+	availableSpace = common.AvailableSpace
+)
+
+// bootupdStatus is the part of the "bootupctl status --json" output used to detect interrupted updates
+type bootupdStatus struct {
+	Components map[string]struct {
+		Interrupted json.RawMessage `json:"interrupted"`
+	} `json:"components"`
+}
+
+// Check verifies the boot partition has space for the kernel and initramfs of another deployment,
+// that no boot loader entry references a missing file, and that no bootupd update was interrupted.
+// All the issues found are reported together.
+func Check(log logr.Logger, hostCommandsExecutor ops.Execute) error {
+	log.Info("Checking boot partition")
+	var errs []error
+	for _, check := range []func() error{
+		checkSpace,
+		checkEntries,
+		func() error { return checkBootupd(hostCommandsExecutor) },
+	} {
+		if err := check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		log.Info("Boot partition checks passed")
+	}
+	return errors.Join(errs...)
+}
+
+// checkSpace requires the space of the largest kernel and initramfs directory of the existing deployments
+func checkSpace() error {
+	ostreeBootDir := filepath.Join(common.PathOutsideChroot(bootDir), "ostree")
+	dirs, err := os.ReadDir(ostreeBootDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", ostreeBootDir, err)
+	}
+
+	var largest int64
+	for _, dir := range dirs {
+		// the boot.N entries are symlinks to the deployments, only the <osname>-<checksum> directories hold files
+		if !dir.IsDir() {
+			continue
+		}
+		size, err := dirSize(filepath.Join(ostreeBootDir, dir.Name()))
+		if err != nil {
+			return err
+		}
+		if size > largest {
+			largest = size
+		}
+	}
+
+	available, err := availableSpace(common.PathOutsideChroot(bootDir))
+	if err != nil {
+		return err
+	}
+	if required := largest + largest*headroomPercent/100; available < required {
+		return lcaerrors.Wrap(lcaerrors.ErrInsufficientSpace,
+			fmt.Errorf("%s requires %d bytes available for the boot entry of the new deployment, found %d", bootDir, required, available))
+	}
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute the size of %s: %w", dir, err)
+	}
+	return size, nil
+}
+
+// checkEntries looks for stale boot loader entries, whose kernel or initramfs is missing from the boot partition
+func checkEntries() error {
+	entries, err := filepath.Glob(filepath.Join(common.PathOutsideChroot(bootDir), "loader", "entries", "*.conf"))
+	if err != nil {
+		return fmt.Errorf("failed to list boot loader entries: %w", err)
+	}
+	sort.Strings(entries)
+
+	var stale []string
+	for _, entry := range entries {
+		missing, err := missingEntryFiles(entry)
+		if err != nil {
+			return err
+		}
+		if len(missing) != 0 {
+			stale = append(stale, fmt.Sprintf("%s (missing %s)", filepath.Base(entry), strings.Join(missing, ", ")))
+		}
+	}
+	if len(stale) != 0 {
+		return lcaerrors.Wrap(lcaerrors.ErrOstreeDeploy,
+			fmt.Errorf("stale boot loader entries found: %s. Clean up the deployments with rpm-ostree cleanup -r or ostree admin undeploy", strings.Join(stale, "; ")))
+	}
+	return nil
+}
+
+// missingEntryFiles returns the linux and initrd paths of a boot loader entry that do not exist in the boot partition
+func missingEntryFiles(entry string) ([]string, error) {
+	f, err := os.Open(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open boot loader entry: %w", err)
+	}
+	defer f.Close()
+
+	var missing []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || (fields[0] != "linux" && fields[0] != "initrd") {
+			continue
+		}
+		// the paths are relative to the boot partition
+		for _, path := range fields[1:] {
+			if _, err := os.Stat(filepath.Join(common.PathOutsideChroot(bootDir), path)); os.IsNotExist(err) {
+				missing = append(missing, path)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read boot loader entry %s: %w", entry, err)
+	}
+	return missing, nil
+}
+
+// checkBootupd fails if a bootloader update was interrupted, the bootloader being then in an unknown state
+func checkBootupd(hostCommandsExecutor ops.Execute) error {
+	if _, err := os.Stat(common.PathOutsideChroot(bootupctlPath)); err != nil {
+		// bootupd is not used on this host
+		return nil
+	}
+
+	output, err := hostCommandsExecutor.Execute("bootupctl", "status", "--json")
+	if err != nil {
+		return lcaerrors.WrapHostError(lcaerrors.ErrOstreeDeploy, fmt.Errorf("failed to get bootupd status: %w", err))
+	}
+	status := &bootupdStatus{}
+	if err := json.Unmarshal([]byte(output), status); err != nil {
+		return fmt.Errorf("failed to parse bootupd status: %w", err)
+	}
+
+	var interrupted []string
+	for name, component := range status.Components {
+		if len(component.Interrupted) != 0 && string(component.Interrupted) != "null" {
+			interrupted = append(interrupted, name)
+		}
+	}
+	if len(interrupted) != 0 {
+		sort.Strings(interrupted)
+		return lcaerrors.Wrap(lcaerrors.ErrOstreeDeploy,
+			fmt.Errorf("bootloader update interrupted for %s. Complete it with bootupctl update", strings.Join(interrupted, ", ")))
+	}
+	return nil
+}
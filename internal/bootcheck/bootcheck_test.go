@@ -0,0 +1,97 @@
+package bootcheck
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+const entry = `title Red Hat Enterprise Linux CoreOS (ostree:0)
+version 1
+linux /ostree/rhcos-abc/vmlinuz
+initrd /ostree/rhcos-abc/initramfs.img
+options ostree=/ostree/boot.1/rhcos/abc/0
+`
+
+func TestCheck(t *testing.T) {
+	testcases := []struct {
+		name          string
+		available     int64
+		missingKernel bool
+		bootupd       string
+		expectedErr   []error
+		expectedMsg   string
+	}{
+		{
+			name:      "boot partition ready",
+			available: 1 << 20,
+			bootupd:   `{"components":{"EFI":{"installed":{"version":"1"},"interrupted":null}}}`,
+		},
+		{
+			name:        "insufficient space",
+			available:   1000,
+			expectedErr: []error{lcaerrors.ErrInsufficientSpace},
+			expectedMsg: "/boot requires 1100 bytes available",
+		},
+		{
+			name:          "stale entry and interrupted bootloader update",
+			available:     1 << 20,
+			missingKernel: true,
+			bootupd:       `{"components":{"EFI":{"interrupted":{"version":"2"}}}}`,
+			expectedErr:   []error{lcaerrors.ErrOstreeDeploy},
+			expectedMsg:   "ostree-1.conf (missing /ostree/rhcos-abc/vmlinuz)",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockExec := ops.NewMockExecute(ctrl)
+			tmp := t.TempDir()
+
+			origBootDir, origBootupctlPath, origAvailableSpace := bootDir, bootupctlPath, availableSpace
+			defer func() {
+				bootDir, bootupctlPath, availableSpace = origBootDir, origBootupctlPath, origAvailableSpace
+			}()
+			bootDir = filepath.Join(tmp, "boot")
+			bootupctlPath = filepath.Join(tmp, "bootupctl")
+			availableSpace = func(string) (int64, error) { return tc.available, nil }
+
+			kernelDir := filepath.Join(bootDir, "ostree", "rhcos-abc")
+			assert.NoError(t, os.MkdirAll(kernelDir, 0o700))
+			assert.NoError(t, os.WriteFile(filepath.Join(kernelDir, "initramfs.img"), make([]byte, 600), 0o600))
+			if !tc.missingKernel {
+				assert.NoError(t, os.WriteFile(filepath.Join(kernelDir, "vmlinuz"), make([]byte, 400), 0o600))
+			}
+			assert.NoError(t, os.Symlink("rhcos-abc", filepath.Join(bootDir, "ostree", "boot.1")))
+			assert.NoError(t, os.MkdirAll(filepath.Join(bootDir, "loader", "entries"), 0o700))
+			assert.NoError(t, os.WriteFile(filepath.Join(bootDir, "loader", "entries", "ostree-1.conf"), []byte(entry), 0o600))
+
+			if tc.bootupd != "" {
+				assert.NoError(t, os.WriteFile(bootupctlPath, nil, 0o600))
+				mockExec.EXPECT().Execute("bootupctl", "status", "--json").Return(tc.bootupd, nil)
+			}
+
+			err := Check(logr.Discard(), mockExec)
+			if len(tc.expectedErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			for _, expected := range tc.expectedErr {
+				assert.True(t, errors.Is(err, expected))
+			}
+			assert.ErrorContains(t, err, tc.expectedMsg)
+			if tc.bootupd != "" {
+				assert.ErrorContains(t, err, "bootloader update interrupted for EFI")
+			}
+		})
+	}
+}
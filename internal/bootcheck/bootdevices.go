@@ -0,0 +1,163 @@
+package bootcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+// SingleBootDevice is the layout of a /boot on a plain partition, see BootDeviceLayout
+const SingleBootDevice = "single"
+
+var sysBlockDir = "/sys/block"
+
+// blockDevice is a device of the "lsblk --inverse --json" output, whose children are the devices it is built on
+type blockDevice struct {
+	KName    string        `json:"kname"`
+	Type     string        `json:"type"`
+	Children []blockDevice `json:"children,omitempty"`
+}
+
+// CheckBootDevices verifies that all the devices under /boot take the writes of the new deployment: the members of a
+// software RAID1 /boot, which a degraded or resyncing array would leave stale, and the paths of a multipath /boot. It is
+// run before the stateroot setup, and again once deployed, so that an array degraded in between fails Prep instead of
+// leaving one member without the boot entry of the new deployment.
+func CheckBootDevices(log logr.Logger, hostCommandsExecutor ops.Execute) error {
+	devices, err := listBootDevices(hostCommandsExecutor)
+	if err != nil {
+		return err
+	}
+
+	var issues []string
+	var walk func(device blockDevice)
+	walk = func(device blockDevice) {
+		switch {
+		case strings.HasPrefix(device.Type, "raid"):
+			log.Info("Boot partition is on a software RAID", "device", device.KName, "level", device.Type,
+				"members", kernelNames(device.Children))
+			issues = append(issues, checkRAID(device)...)
+		case device.Type == "mpath":
+			log.Info("Boot partition is on a multipath device", "device", device.KName, "paths", kernelNames(device.Children))
+			issues = append(issues, checkMultipath(device)...)
+		}
+		for _, child := range device.Children {
+			walk(child)
+		}
+	}
+	for _, device := range devices {
+		walk(device)
+	}
+	if len(issues) != 0 {
+		sort.Strings(issues)
+		return lcaerrors.Wrap(lcaerrors.ErrOstreeDeploy, fmt.Errorf("boot devices not ready for a new deployment: %s",
+			strings.Join(issues, "; ")))
+	}
+	return nil
+}
+
+// BootDeviceLayout returns the layout of the devices under /boot: the types of the software RAID and multipath devices
+// it is on, e.g. raid1 or mpath, joined with a plus sign, or single for a plain partition. The configuration of the seed
+// stateroot, such as the RAID arrays assembled by the initramfs, is only valid on a host of the same layout.
+func BootDeviceLayout(hostCommandsExecutor ops.Execute) (string, error) {
+	devices, err := listBootDevices(hostCommandsExecutor)
+	if err != nil {
+		return "", err
+	}
+	types := map[string]bool{}
+	var walk func(device blockDevice)
+	walk = func(device blockDevice) {
+		if strings.HasPrefix(device.Type, "raid") || device.Type == "mpath" {
+			types[device.Type] = true
+		}
+		for _, child := range device.Children {
+			walk(child)
+		}
+	}
+	for _, device := range devices {
+		walk(device)
+	}
+	if len(types) == 0 {
+		return SingleBootDevice, nil
+	}
+	layout := make([]string, 0, len(types))
+	for deviceType := range types {
+		layout = append(layout, deviceType)
+	}
+	sort.Strings(layout)
+	return strings.Join(layout, "+"), nil
+}
+
+// listBootDevices returns the device of /boot, with the devices it is built on as children
+func listBootDevices(hostCommandsExecutor ops.Execute) ([]blockDevice, error) {
+	source, err := hostCommandsExecutor.Execute("findmnt", "--noheadings", "--output", "SOURCE", "--target", bootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the device of %s: %w", bootDir, err)
+	}
+	output, err := hostCommandsExecutor.Execute("lsblk", "--json", "--inverse", "--output", "KNAME,TYPE", source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the devices of %s: %w", bootDir, err)
+	}
+	devices := struct {
+		BlockDevices []blockDevice `json:"blockdevices"`
+	}{}
+	if err := json.Unmarshal([]byte(output), &devices); err != nil {
+		return nil, fmt.Errorf("failed to parse lsblk output: %w", err)
+	}
+	return devices.BlockDevices, nil
+}
+
+// checkRAID reports a degraded array, missing members, or an array not idle, whose members would not all get the new
+// deployment
+func checkRAID(device blockDevice) []string {
+	var issues []string
+	if degraded := readSysBlock(device.KName, "md", "degraded"); degraded != "" && degraded != "0" {
+		issues = append(issues, fmt.Sprintf("RAID %s is degraded, %s member(s) missing from %s, restore it with mdadm --add",
+			device.KName, degraded, strings.Join(kernelNames(device.Children), ", ")))
+	}
+	if action := readSysBlock(device.KName, "md", "sync_action"); action != "" && action != "idle" {
+		issues = append(issues, fmt.Sprintf("RAID %s is running a %s, wait for it to complete", device.KName, action))
+	}
+	return issues
+}
+
+// checkMultipath reports the paths of a multipath device that are not running
+func checkMultipath(device blockDevice) []string {
+	var failed []string
+	for _, path := range device.Children {
+		if state := readSysBlock(path.KName, "device", "state"); state != "" && state != "running" {
+			failed = append(failed, fmt.Sprintf("%s (%s)", path.KName, state))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("multipath %s has failed paths %s, restore them before the upgrade",
+		device.KName, strings.Join(failed, ", "))}
+}
+
+// readSysBlock returns the trimmed content of a sysfs attribute of a block device, or an empty string if unreadable
+func readSysBlock(kname string, attribute ...string) string {
+	path := filepath.Join(append([]string{common.PathOutsideChroot(sysBlockDir), kname}, attribute...)...)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+func kernelNames(devices []blockDevice) []string {
+	names := make([]string, 0, len(devices))
+	for _, device := range devices {
+		names = append(names, device.KName)
+	}
+	return names
+}
@@ -0,0 +1,103 @@
+package bootcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+const (
+	raidLsblk = `{"blockdevices":[{"kname":"md126","type":"raid1","children":[` +
+		`{"kname":"sda3","type":"part","children":[{"kname":"sda","type":"disk"}]},` +
+		`{"kname":"sdb3","type":"part","children":[{"kname":"sdb","type":"disk"}]}]}]}`
+	multipathLsblk = `{"blockdevices":[{"kname":"dm-3","type":"part","children":[{"kname":"dm-0","type":"mpath","children":[` +
+		`{"kname":"sda","type":"disk"},{"kname":"sdb","type":"disk"}]}]}]}`
+)
+
+func TestCheckBootDevices(t *testing.T) {
+	testcases := []struct {
+		name        string
+		source      string
+		lsblk       string
+		sysfs       map[string]string
+		expectedMsg string
+	}{
+		{
+			name:   "partition",
+			source: "/dev/sda3",
+			lsblk:  `{"blockdevices":[{"kname":"sda3","type":"part","children":[{"kname":"sda","type":"disk"}]}]}`,
+		},
+		{
+			name:   "healthy RAID1",
+			source: "/dev/md126",
+			lsblk:  raidLsblk,
+			sysfs:  map[string]string{"md126/md/degraded": "0\n", "md126/md/sync_action": "idle\n"},
+		},
+		{
+			name:        "degraded RAID1",
+			source:      "/dev/md126",
+			lsblk:       raidLsblk,
+			sysfs:       map[string]string{"md126/md/degraded": "1\n", "md126/md/sync_action": "recover\n"},
+			expectedMsg: "boot devices not ready for a new deployment: RAID md126 is degraded, 1 member(s) missing from sda3, sdb3, restore it with mdadm --add; RAID md126 is running a recover, wait for it to complete",
+		},
+		{
+			name:   "healthy multipath",
+			source: "/dev/mapper/mpatha3",
+			lsblk:  multipathLsblk,
+			sysfs:  map[string]string{"sda/device/state": "running\n", "sdb/device/state": "running\n"},
+		},
+		{
+			name:        "multipath with a failed path",
+			source:      "/dev/mapper/mpatha3",
+			lsblk:       multipathLsblk,
+			sysfs:       map[string]string{"sda/device/state": "running\n", "sdb/device/state": "offline\n"},
+			expectedMsg: "boot devices not ready for a new deployment: multipath dm-0 has failed paths sdb (offline), restore them before the upgrade",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockExec := ops.NewMockExecute(gomock.NewController(t))
+			origSysBlockDir := sysBlockDir
+			defer func() { sysBlockDir = origSysBlockDir }()
+			sysBlockDir = t.TempDir()
+			for attribute, content := range tc.sysfs {
+				path := filepath.Join(sysBlockDir, attribute)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+				assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			}
+			mockExec.EXPECT().Execute("findmnt", "--noheadings", "--output", "SOURCE", "--target", "/boot").Return(tc.source, nil)
+			mockExec.EXPECT().Execute("lsblk", "--json", "--inverse", "--output", "KNAME,TYPE", tc.source).Return(tc.lsblk, nil)
+
+			err := CheckBootDevices(logr.Discard(), mockExec)
+			if tc.expectedMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, lcaerrors.ErrOstreeDeploy)
+			assert.EqualError(t, err, tc.expectedMsg)
+		})
+	}
+}
+
+func TestBootDeviceLayout(t *testing.T) {
+	for lsblk, expected := range map[string]string{
+		`{"blockdevices":[{"kname":"sda3","type":"part","children":[{"kname":"sda","type":"disk"}]}]}`: SingleBootDevice,
+		raidLsblk:      "raid1",
+		multipathLsblk: "mpath",
+	} {
+		mockExec := ops.NewMockExecute(gomock.NewController(t))
+		mockExec.EXPECT().Execute("findmnt", "--noheadings", "--output", "SOURCE", "--target", "/boot").Return("/dev/boot", nil)
+		mockExec.EXPECT().Execute("lsblk", "--json", "--inverse", "--output", "KNAME,TYPE", "/dev/boot").Return(lsblk, nil)
+		layout, err := BootDeviceLayout(mockExec)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, layout)
+	}
+}
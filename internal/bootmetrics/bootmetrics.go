@@ -0,0 +1,249 @@
+// Package bootmetrics measures the first boot after the pivot to the new stateroot, for the regression tracking of
+// the upgrade downtime across seed versions.
+package bootmetrics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+// +kubebuilder:rbac:groups=config.openshift.io,resources=clusteroperators,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+const (
+	// ProcStatFile holds the boot time of the node
+	ProcStatFile = "/proc/stat"
+	// SlowestUnits is the number of units reported with the longest initialization
+	SlowestUnits = 10
+
+	kubeAPIServerNamespace  = "openshift-kube-apiserver"
+	kubeAPIServerLabel      = "app"
+	kubeAPIServerLabelValue = "openshift-kube-apiserver"
+)
+
+// procStatFile is ProcStatFile, overridden in tests
+var procStatFile = ProcStatFile
+
+// startupPhase matches the phases of the systemd-analyze time output, e.g. "1min 2.345s (userspace)"
+var startupPhase = regexp.MustCompile(`([0-9][^+=(]*?)\s*\((firmware|loader|kernel|initrd|userspace)\)`)
+
+// Metrics are the metrics of the first boot after the pivot
+type Metrics struct {
+	BootTime metav1.Time `json:"bootTime"`
+	// Startup is the time spent in each boot phase reported by systemd-analyze, in seconds: firmware, loader, kernel,
+	// initrd and userspace
+	Startup map[string]float64 `json:"startup,omitempty"`
+	// StartupSeconds is the total boot time reported by systemd-analyze
+	StartupSeconds float64 `json:"startupSeconds,omitempty"`
+	// SlowestUnits are the units with the longest initialization, the slowest first
+	SlowestUnits []UnitTime `json:"slowestUnits,omitempty"`
+	// TimeToAPISeconds is the time from the boot until the kube-apiserver is ready
+	TimeToAPISeconds int64 `json:"timeToAPISeconds"`
+	// TimeToClusterOperatorsAvailableSeconds is the time from the boot until all the cluster operators are available
+	TimeToClusterOperatorsAvailableSeconds int64 `json:"timeToClusterOperatorsAvailableSeconds"`
+}
+
+// UnitTime is the initialization time of a systemd unit
+type UnitTime struct {
+	Unit    string  `json:"unit"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Collect measures the boot. It is called once the cluster is healthy: the readiness of the kube-apiserver and the
+// availability of the cluster operators are timed from their conditions, or from now when the conditions predate the
+// boot. The systemd-analyze data is best effort, as the boot may not be finished for systemd.
+func Collect(ctx context.Context, c client.Reader, executor ops.Execute, l logr.Logger) (*Metrics, error) {
+	bootTime, err := readBootTime(procStatFile)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	metrics := &Metrics{BootTime: metav1.NewTime(bootTime)}
+
+	if output, err := executor.ExecuteWithContext(ctx, "systemd-analyze", "time"); err != nil {
+		l.Info("Unable to get the boot time from systemd-analyze", "error", err.Error())
+	} else {
+		metrics.Startup, metrics.StartupSeconds = parseStartup(output)
+	}
+	if output, err := executor.ExecuteWithContext(ctx, "systemd-analyze", "blame", "--no-pager"); err != nil {
+		l.Info("Unable to get the unit initialization times from systemd-analyze", "error", err.Error())
+	} else {
+		metrics.SlowestUnits = parseBlame(output, SlowestUnits)
+	}
+
+	apiReady, err := kubeAPIServerReadyTime(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	metrics.TimeToAPISeconds = secondsSinceBoot(bootTime, apiReady, now)
+
+	available, err := clusterOperatorsAvailableTime(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	metrics.TimeToClusterOperatorsAvailableSeconds = secondsSinceBoot(bootTime, available, now)
+
+	return metrics, nil
+}
+
+// secondsSinceBoot returns the seconds from the boot to t, or to now if t predates the boot
+func secondsSinceBoot(bootTime, t, now time.Time) int64 {
+	if t.Before(bootTime) {
+		t = now
+	}
+	return int64(t.Sub(bootTime).Seconds())
+}
+
+// readBootTime reads the boot time of the node from the btime line of /proc/stat
+func readBootTime(statFile string) (time.Time, error) {
+	file, err := os.Open(statFile)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to open %s: %w", statFile, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != "btime" {
+			continue
+		}
+		seconds, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse btime of %s: %w", statFile, err)
+		}
+		return time.Unix(seconds, 0), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, fmt.Errorf("failed to read %s: %w", statFile, err)
+	}
+	return time.Time{}, fmt.Errorf("no btime in %s", statFile)
+}
+
+// parseDuration parses a systemd time span, e.g. "1min 2.345s" or "345ms"
+func parseDuration(span string) (time.Duration, error) {
+	span = strings.ReplaceAll(strings.ReplaceAll(span, "min", "m"), " ", "")
+	d, err := time.ParseDuration(span)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse time span %q: %w", span, err)
+	}
+	return d, nil
+}
+
+// parseStartup parses the phases of the systemd-analyze time output, returning them with their total in seconds
+func parseStartup(output string) (map[string]float64, float64) {
+	phases := map[string]float64{}
+	var total float64
+	for _, match := range startupPhase.FindAllStringSubmatch(output, -1) {
+		d, err := parseDuration(match[1])
+		if err != nil {
+			continue
+		}
+		phases[match[2]] = d.Seconds()
+		total += d.Seconds()
+	}
+	if len(phases) == 0 {
+		return nil, 0
+	}
+	return phases, total
+}
+
+// parseBlame parses the systemd-analyze blame output, the slowest unit first, returning the first count units
+func parseBlame(output string, count int) []UnitTime {
+	var units []UnitTime
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		d, err := parseDuration(strings.Join(fields[:len(fields)-1], " "))
+		if err != nil {
+			continue
+		}
+		units = append(units, UnitTime{Unit: fields[len(fields)-1], Seconds: d.Seconds()})
+		if len(units) == count {
+			break
+		}
+	}
+	return units
+}
+
+// kubeAPIServerReadyTime returns the latest time a kube-apiserver pod became ready
+func kubeAPIServerReadyTime(ctx context.Context, c client.Reader) (time.Time, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(kubeAPIServerNamespace),
+		client.MatchingLabels{kubeAPIServerLabel: kubeAPIServerLabelValue}); err != nil {
+		return time.Time{}, fmt.Errorf("failed to list the kube-apiserver pods: %w", err)
+	}
+	var ready time.Time
+	for _, pod := range pods.Items {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue &&
+				condition.LastTransitionTime.After(ready) {
+				ready = condition.LastTransitionTime.Time
+			}
+		}
+	}
+	return ready, nil
+}
+
+// clusterOperatorsAvailableTime returns the latest time a cluster operator became available
+func clusterOperatorsAvailableTime(ctx context.Context, c client.Reader) (time.Time, error) {
+	operators := &configv1.ClusterOperatorList{}
+	if err := c.List(ctx, operators); err != nil {
+		return time.Time{}, fmt.Errorf("failed to list the cluster operators: %w", err)
+	}
+	var available time.Time
+	for _, operator := range operators.Items {
+		for _, condition := range operator.Status.Conditions {
+			if condition.Type == configv1.OperatorAvailable && condition.Status == configv1.ConditionTrue &&
+				condition.LastTransitionTime.After(available) {
+				available = condition.LastTransitionTime.Time
+			}
+		}
+	}
+	return available, nil
+}
+
+// Load reads the persisted boot metrics, returning nil if none were persisted
+func Load(filename string) (*Metrics, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read boot metrics: %w", err)
+	}
+	metrics := &Metrics{}
+	if err := json.Unmarshal(data, metrics); err != nil {
+		return nil, fmt.Errorf("failed to parse boot metrics: %w", err)
+	}
+	return metrics, nil
+}
+
+// Persist writes the boot metrics to the file
+func (m *Metrics) Persist(filename string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal boot metrics: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write boot metrics: %w", err)
+	}
+	return nil
+}
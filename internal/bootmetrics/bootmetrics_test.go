@@ -0,0 +1,101 @@
+package bootmetrics
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+const (
+	systemdAnalyzeTime = `Startup finished in 1.234s (firmware) + 2.5s (loader) + 1.5s (kernel) + 3.25s (initrd) + 1min 2.5s (userspace) = 1min 10.984s
+multi-user.target reached after 1min 2.1s in userspace`
+	systemdAnalyzeBlame = `    1min 2.345s crio-wipe.service
+         30.5s kubelet.service
+        1.200s NetworkManager-wait-online.service
+         345ms ostree-finalize-staged.service
+`
+)
+
+func TestParse(t *testing.T) {
+	phases, total := parseStartup(systemdAnalyzeTime)
+	assert.Equal(t, map[string]float64{"firmware": 1.234, "loader": 2.5, "kernel": 1.5, "initrd": 3.25, "userspace": 62.5}, phases)
+	assert.InDelta(t, 70.984, total, 0.0001)
+
+	phases, total = parseStartup("Bootup is not yet finished (org.freedesktop.systemd1.Manager.FinishTimestampMonotonic=0).")
+	assert.Nil(t, phases)
+	assert.Zero(t, total)
+
+	assert.Equal(t, []UnitTime{{Unit: "crio-wipe.service", Seconds: 62.345}, {Unit: "kubelet.service", Seconds: 30.5},
+		{Unit: "NetworkManager-wait-online.service", Seconds: 1.2}}, parseBlame(systemdAnalyzeBlame, 3))
+}
+
+func TestCollect(t *testing.T) {
+	bootTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	procStatFile = filepath.Join(t.TempDir(), "stat")
+	defer func() { procStatFile = ProcStatFile }()
+	assert.NoError(t, os.WriteFile(procStatFile,
+		[]byte("cpu  1 2 3 4\nbtime "+strconv.FormatInt(bootTime.Unix(), 10)+"\nprocesses 42\n"), 0o600))
+
+	s := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(s)
+	_ = configv1.AddToScheme(s)
+	ready := func(t time.Time) []corev1.PodCondition {
+		return []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(t)}}
+	}
+	available := func(t time.Time) []configv1.ClusterOperatorStatusCondition {
+		return []configv1.ClusterOperatorStatusCondition{
+			{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue, LastTransitionTime: metav1.NewTime(t)}}
+	}
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-apiserver-sno", Namespace: kubeAPIServerNamespace,
+				Labels: map[string]string{kubeAPIServerLabel: kubeAPIServerLabelValue}},
+			Status: corev1.PodStatus{Conditions: ready(bootTime.Add(5 * time.Minute))},
+		},
+		&configv1.ClusterOperator{
+			ObjectMeta: metav1.ObjectMeta{Name: "etcd"},
+			Status:     configv1.ClusterOperatorStatus{Conditions: available(bootTime.Add(-24 * time.Hour))},
+		},
+		&configv1.ClusterOperator{
+			ObjectMeta: metav1.ObjectMeta{Name: "network"},
+			Status:     configv1.ClusterOperatorStatus{Conditions: available(bootTime.Add(20 * time.Minute))},
+		},
+	).Build()
+
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "systemd-analyze", "time").Return(systemdAnalyzeTime, nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "systemd-analyze", "blame", "--no-pager").Return("", errors.New("failed"))
+
+	metrics, err := Collect(context.Background(), c, mockExec, logr.Discard())
+	assert.NoError(t, err)
+	assert.True(t, metrics.BootTime.Time.Equal(bootTime))
+	assert.InDelta(t, 70.984, metrics.StartupSeconds, 0.0001)
+	assert.Nil(t, metrics.SlowestUnits)
+	assert.Equal(t, int64(300), metrics.TimeToAPISeconds)
+	assert.Equal(t, int64(1200), metrics.TimeToClusterOperatorsAvailableSeconds)
+
+	file := filepath.Join(t.TempDir(), "boot_metrics.json")
+	loaded, err := Load(file)
+	assert.NoError(t, err)
+	assert.Nil(t, loaded)
+	assert.NoError(t, metrics.Persist(file))
+	loaded, err = Load(file)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.TimeToAPISeconds, loaded.TimeToAPISeconds)
+}
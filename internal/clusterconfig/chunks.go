@@ -0,0 +1,183 @@
+package clusterconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const (
+	// chunkedBundleLabel selects the ConfigMaps and Secrets of the openshift-config namespace holding a chunk of a
+	// bundle too large for a single object, with the name of the bundle as value
+	chunkedBundleLabel = "lca.openshift.io/chunked-bundle"
+	// chunkIndexAnnotation is the position of the chunk in the bundle, starting at 0
+	chunkIndexAnnotation = "lca.openshift.io/chunk-index"
+	// chunkCountAnnotation is the number of chunks of the bundle
+	chunkCountAnnotation = "lca.openshift.io/chunk-count"
+	// chunkDataKey is the ConfigMap or Secret key holding the chunk
+	chunkDataKey = "chunk"
+
+	// chunkedCABundle is a PEM bundle added to the trusted CAs of the host
+	chunkedCABundle = "ca-bundle"
+	// chunkedRegistriesConf is a containers-registries.conf drop-in
+	chunkedRegistriesConf = "registries-conf"
+)
+
+// chunkedBundleFiles are the cluster configuration files of the supported chunked bundles
+var chunkedBundleFiles = map[string]string{
+	chunkedCABundle:       common.ChunkedCABundleFileName,
+	chunkedRegistriesConf: common.ChunkedRegistriesConfFileName,
+}
+
+// chunk is a part of a chunked bundle
+type chunk struct {
+	source string
+	index  int
+	count  int
+	data   []byte
+}
+
+// fetchChunkedBundles reassembles the bundles split across ConfigMaps and Secrets into the cluster configuration, to
+// be installed in the new stateroot. The chunks are carried over as manifests, so that they are kept by the cluster.
+func (r *UpgradeClusterConfigGather) fetchChunkedBundles(ctx context.Context, manifestsDir, clusterConfigPath string) error {
+	r.Log.Info("Fetching chunked bundles")
+	chunks := map[string][]chunk{}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.Client.List(ctx, configMaps, client.InNamespace(common.OpenshiftConfigNamespace),
+		client.HasLabels{chunkedBundleLabel}); err != nil {
+		return fmt.Errorf("failed to list chunked bundle configmaps: %w", err)
+	}
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
+		c, err := newChunk("configmap "+cm.Name, cm.GetAnnotations(), []byte(cm.Data[chunkDataKey]))
+		if err != nil {
+			return err
+		}
+		bundle := cm.GetLabels()[chunkedBundleLabel]
+		chunks[bundle] = append(chunks[bundle], c)
+		if err := r.writeChunkManifest(cm, filepath.Join(manifestsDir, fmt.Sprintf("chunk-configmap-%s.json", cm.Name))); err != nil {
+			return err
+		}
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := r.Client.List(ctx, secrets, client.InNamespace(common.OpenshiftConfigNamespace),
+		client.HasLabels{chunkedBundleLabel}); err != nil {
+		return fmt.Errorf("failed to list chunked bundle secrets: %w", err)
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		c, err := newChunk("secret "+secret.Name, secret.GetAnnotations(), secret.Data[chunkDataKey])
+		if err != nil {
+			return err
+		}
+		bundle := secret.GetLabels()[chunkedBundleLabel]
+		chunks[bundle] = append(chunks[bundle], c)
+		if err := r.writeChunkManifest(secret, filepath.Join(manifestsDir, fmt.Sprintf("chunk-secret-%s.json", secret.Name))); err != nil {
+			return err
+		}
+	}
+
+	bundles := make([]string, 0, len(chunks))
+	for bundle := range chunks {
+		bundles = append(bundles, bundle)
+	}
+	sort.Strings(bundles)
+	for _, bundle := range bundles {
+		fileName, ok := chunkedBundleFiles[bundle]
+		if !ok {
+			return fmt.Errorf("unsupported chunked bundle %q, must be %q or %q", bundle, chunkedCABundle, chunkedRegistriesConf)
+		}
+		data, err := reassembleChunks(bundle, chunks[bundle])
+		if err != nil {
+			return err
+		}
+		if bundle == chunkedCABundle {
+			if err := utils.ValidateCABundle(data); err != nil {
+				return fmt.Errorf("invalid chunked bundle %s: %w", bundle, err)
+			}
+		}
+
+		filePath := filepath.Join(clusterConfigPath, fileName)
+		r.Log.Info("Writing reassembled chunked bundle", "bundle", bundle, "chunks", len(chunks[bundle]), "path", filePath)
+		if err := os.WriteFile(filePath, data, 0o600); err != nil {
+			return fmt.Errorf("failed to write chunked bundle %s to %s: %w", bundle, filePath, err)
+		}
+	}
+
+	return nil
+}
+
+// writeChunkManifest writes the chunk object as a manifest, keeping its chunk annotations
+func (r *UpgradeClusterConfigGather) writeChunkManifest(o client.Object, filePath string) error {
+	meta := r.cleanObjectMetadata(o)
+	meta.Annotations = map[string]string{
+		chunkIndexAnnotation: o.GetAnnotations()[chunkIndexAnnotation],
+		chunkCountAnnotation: o.GetAnnotations()[chunkCountAnnotation],
+	}
+
+	var manifest runtime.Object
+	switch obj := o.(type) {
+	case *corev1.ConfigMap:
+		manifest = &corev1.ConfigMap{ObjectMeta: meta, Data: obj.Data, BinaryData: obj.BinaryData}
+	case *corev1.Secret:
+		manifest = &corev1.Secret{ObjectMeta: meta, Type: obj.Type, Data: obj.Data}
+	default:
+		return fmt.Errorf("unsupported chunk object %T", o)
+	}
+	typeMeta, err := r.typeMetaForObject(manifest)
+	if err != nil {
+		return err
+	}
+	manifest.GetObjectKind().SetGroupVersionKind(typeMeta.GroupVersionKind())
+
+	if err := utils.MarshalToFile(manifest, filePath); err != nil {
+		return fmt.Errorf("failed to write chunk to %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// newChunk reads the position of the chunk in its bundle from the annotations of its object
+func newChunk(source string, annotations map[string]string, data []byte) (chunk, error) {
+	index, err := strconv.Atoi(annotations[chunkIndexAnnotation])
+	if err != nil {
+		return chunk{}, fmt.Errorf("invalid %s annotation of chunk %s: %w", chunkIndexAnnotation, source, err)
+	}
+	count, err := strconv.Atoi(annotations[chunkCountAnnotation])
+	if err != nil {
+		return chunk{}, fmt.Errorf("invalid %s annotation of chunk %s: %w", chunkCountAnnotation, source, err)
+	}
+	if len(data) == 0 {
+		return chunk{}, fmt.Errorf("chunk %s has no %s key", source, chunkDataKey)
+	}
+	return chunk{source: source, index: index, count: count, data: data}, nil
+}
+
+// reassembleChunks concatenates the chunks of the bundle in order, checking that none is missing or duplicated
+func reassembleChunks(bundle string, chunks []chunk) ([]byte, error) {
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].index < chunks[j].index })
+
+	var data bytes.Buffer
+	for i, c := range chunks {
+		if c.count != len(chunks) {
+			return nil, fmt.Errorf("chunked bundle %s has %d chunks, but chunk %s expects %d", bundle, len(chunks), c.source, c.count)
+		}
+		if c.index != i {
+			return nil, fmt.Errorf("chunked bundle %s is missing chunk %d, or has duplicated chunks", bundle, i)
+		}
+		data.Write(c.data)
+	}
+	return data.Bytes(), nil
+}
@@ -0,0 +1,147 @@
+package clusterconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/testutils"
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+func chunkMeta(name, bundle string, index, count int) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: common.OpenshiftConfigNamespace,
+		Labels:    map[string]string{chunkedBundleLabel: bundle},
+		Annotations: map[string]string{
+			chunkIndexAnnotation: strconv.Itoa(index),
+			chunkCountAnnotation: strconv.Itoa(count),
+		},
+	}
+}
+
+func TestFetchChunkedBundles(t *testing.T) {
+	caBundle := append(testutils.CertificatePEM(t, "test-ca"), testutils.CertificatePEM(t, "test-ca")...)
+	third := len(caBundle) / 3
+	caChunks := []string{string(caBundle[:third]), string(caBundle[third : 2*third]), string(caBundle[2*third:])}
+
+	testcases := []struct {
+		name                   string
+		objects                []client.Object
+		expectedCABundle       string
+		expectedRegistriesConf string
+		expectedErr            string
+	}{
+		{
+			name: "No chunked bundle",
+		},
+		{
+			name: "CA bundle chunked across configmaps and secrets",
+			objects: []client.Object{
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("ca-2", chunkedCABundle, 2, 3), Data: map[string]string{chunkDataKey: caChunks[2]}},
+				&corev1.Secret{ObjectMeta: chunkMeta("ca-1", chunkedCABundle, 1, 3), Data: map[string][]byte{chunkDataKey: []byte(caChunks[1])}},
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("ca-0", chunkedCABundle, 0, 3), Data: map[string]string{chunkDataKey: caChunks[0]}},
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("registries-0", chunkedRegistriesConf, 0, 2), Data: map[string]string{chunkDataKey: "[[registry]]\n"}},
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("registries-1", chunkedRegistriesConf, 1, 2), Data: map[string]string{chunkDataKey: "location = \"quay.io\"\n"}},
+			},
+			expectedCABundle:       string(caBundle),
+			expectedRegistriesConf: "[[registry]]\nlocation = \"quay.io\"\n",
+		},
+		{
+			name: "Missing chunk",
+			objects: []client.Object{
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("ca-0", chunkedCABundle, 0, 3), Data: map[string]string{chunkDataKey: caChunks[0]}},
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("ca-2", chunkedCABundle, 2, 3), Data: map[string]string{chunkDataKey: caChunks[2]}},
+			},
+			expectedErr: "chunked bundle ca-bundle has 2 chunks, but chunk configmap ca-0 expects 3",
+		},
+		{
+			name: "Duplicated chunk",
+			objects: []client.Object{
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("ca-0", chunkedCABundle, 0, 2), Data: map[string]string{chunkDataKey: caChunks[0]}},
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("ca-0-bis", chunkedCABundle, 0, 2), Data: map[string]string{chunkDataKey: caChunks[0]}},
+			},
+			expectedErr: "chunked bundle ca-bundle is missing chunk 1, or has duplicated chunks",
+		},
+		{
+			name: "Invalid CA bundle",
+			objects: []client.Object{
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("ca-0", chunkedCABundle, 0, 2), Data: map[string]string{chunkDataKey: caChunks[0]}},
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("ca-1", chunkedCABundle, 1, 2), Data: map[string]string{chunkDataKey: caChunks[2]}},
+			},
+			expectedErr: "invalid chunked bundle ca-bundle",
+		},
+		{
+			name: "Missing chunk annotation",
+			objects: []client.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "ca-0", Namespace: common.OpenshiftConfigNamespace,
+						Labels: map[string]string{chunkedBundleLabel: chunkedCABundle}},
+					Data: map[string]string{chunkDataKey: caChunks[0]},
+				},
+			},
+			expectedErr: "invalid lca.openshift.io/chunk-index annotation of chunk configmap ca-0",
+		},
+		{
+			name: "Unsupported bundle",
+			objects: []client.Object{
+				&corev1.ConfigMap{ObjectMeta: chunkMeta("other-0", "other", 0, 1), Data: map[string]string{chunkDataKey: "data"}},
+			},
+			expectedErr: `unsupported chunked bundle "other"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			clusterConfigPath := t.TempDir()
+			manifestsDir := filepath.Join(clusterConfigPath, manifestDir)
+			assert.NoError(t, os.MkdirAll(manifestsDir, 0o700))
+			fakeClient, err := getFakeClientFromObjects(tc.objects...)
+			assert.NoError(t, err)
+
+			ucc := &UpgradeClusterConfigGather{Client: fakeClient, Log: logr.Discard(), Scheme: testscheme}
+			err = ucc.fetchChunkedBundles(context.Background(), manifestsDir, clusterConfigPath)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			for fileName, expected := range map[string]string{
+				common.ChunkedCABundleFileName:       tc.expectedCABundle,
+				common.ChunkedRegistriesConfFileName: tc.expectedRegistriesConf,
+			} {
+				content, err := os.ReadFile(filepath.Join(clusterConfigPath, fileName))
+				if expected == "" {
+					assert.True(t, os.IsNotExist(err))
+					continue
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, expected, string(content))
+			}
+
+			// The chunks are carried over as manifests, with their chunk annotations
+			for _, obj := range tc.objects {
+				kind := "configmap"
+				if _, ok := obj.(*corev1.Secret); ok {
+					kind = "secret"
+				}
+				manifest := &corev1.ConfigMap{}
+				assert.NoError(t, utils.ReadYamlOrJSONFile(filepath.Join(manifestsDir, "chunk-"+kind+"-"+obj.GetName()+".json"), manifest))
+				assert.Equal(t, obj.GetName(), manifest.Name)
+				assert.Equal(t, obj.GetAnnotations()[chunkIndexAnnotation], manifest.Annotations[chunkIndexAnnotation])
+				assert.Equal(t, obj.GetLabels(), manifest.Labels)
+			}
+		})
+	}
+}
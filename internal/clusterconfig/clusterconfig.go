@@ -56,8 +56,10 @@ var (
 )
 
 type UpgradeClusterConfigGatherer interface {
-	FetchClusterConfig(ctx context.Context, ostreeVarDir string) error
-	FetchLvmConfig(ctx context.Context, ostreeVarDir string) error
+	FetchClusterConfig(ctx context.Context, ostreeVarDir string, clockSkewProtection *seedreconfig.ClockSkewProtection,
+		progress utils.ProgressFunc) error
+	FetchLvmConfig(ctx context.Context, ostreeVarDir string, progress utils.ProgressFunc) error
+	RenderProxyConfig(ctx context.Context, deploymentDir string) error
 }
 
 // UpgradeClusterConfigGather Gather ClusterConfig attributes from the kube-api
@@ -68,8 +70,10 @@ type UpgradeClusterConfigGather struct {
 }
 
 // FetchClusterConfig collects the current cluster's configuration and write it as JSON files into
-// given filesystem directory.
-func (r *UpgradeClusterConfigGather) FetchClusterConfig(ctx context.Context, ostreeVarDir string) error {
+// given filesystem directory, along with the clock skew protection of recert in the seed reconfiguration. All items
+// are fetched even if one fails, and the error lists every failed item.
+func (r *UpgradeClusterConfigGather) FetchClusterConfig(ctx context.Context, ostreeVarDir string,
+	clockSkewProtection *seedreconfig.ClockSkewProtection, progress utils.ProgressFunc) error {
 	r.Log.Info("Fetching cluster configuration")
 
 	clusterConfigPath, err := r.configDir(ostreeVarDir)
@@ -78,24 +82,17 @@ func (r *UpgradeClusterConfigGather) FetchClusterConfig(ctx context.Context, ost
 	}
 	manifestsDir := filepath.Join(clusterConfigPath, manifestDir)
 
-	if err := r.fetchProxy(ctx, manifestsDir); err != nil {
-		return err
-	}
-	if err := r.fetchIDMS(ctx, manifestsDir); err != nil {
-		return err
-	}
-
-	if err := r.fetchClusterInfo(ctx, clusterConfigPath); err != nil {
-		return err
+	items := []utils.BackupItem{
+		{Name: "proxy", Backup: func() error { return r.fetchProxy(ctx, manifestsDir) }},
+		{Name: "image digest mirror sets", Backup: func() error { return r.fetchIDMS(ctx, manifestsDir) }},
+		{Name: "cluster info", Backup: func() error { return r.fetchClusterInfo(ctx, clusterConfigPath, clockSkewProtection) }},
+		{Name: "CA bundle", Backup: func() error { return r.fetchCABundle(ctx, manifestsDir, clusterConfigPath) }},
+		{Name: "chunked bundles", Backup: func() error { return r.fetchChunkedBundles(ctx, manifestsDir, clusterConfigPath) }},
+		{Name: "image content source policies", Backup: func() error { return r.fetchICSPs(ctx, manifestsDir) }},
+		{Name: "network configuration", Backup: func() error { return r.fetchNetworkConfig(ostreeVarDir) }},
 	}
-	if err := r.fetchCABundle(ctx, manifestsDir, clusterConfigPath); err != nil {
-		return err
-	}
-	if err := r.fetchICSPs(ctx, manifestsDir); err != nil {
-		return err
-	}
-	if err := r.fetchNetworkConfig(ostreeVarDir); err != nil {
-		return err
+	if err := utils.RunBackupItems("cluster configuration", items, progress); err != nil {
+		return err //nolint:wrapcheck
 	}
 
 	r.Log.Info("Successfully fetched cluster configuration")
@@ -195,7 +192,8 @@ func SeedReconfigurationFromClusterInfo(clusterInfo *utils.ClusterInfo,
 	}
 }
 
-func (r *UpgradeClusterConfigGather) fetchClusterInfo(ctx context.Context, clusterConfigPath string) error {
+func (r *UpgradeClusterConfigGather) fetchClusterInfo(ctx context.Context, clusterConfigPath string,
+	clockSkewProtection *seedreconfig.ClockSkewProtection) error {
 	r.Log.Info("Fetching ClusterInfo")
 
 	clusterInfo, err := utils.GetClusterInfo(ctx, r.Client)
@@ -234,6 +232,7 @@ func (r *UpgradeClusterConfigGather) fetchClusterInfo(ctx context.Context, clust
 		pullSecret,
 		kubeadminPasswordHash,
 	)
+	seedReconfiguration.ClockSkewProtection = clockSkewProtection
 
 	filePath := filepath.Join(clusterConfigPath, common.SeedReconfigurationFileName)
 	r.Log.Info("Writing ClusterInfo to file", "path", filePath)
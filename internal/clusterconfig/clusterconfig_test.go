@@ -506,7 +506,7 @@ func TestClusterConfig(t *testing.T) {
 				t.Errorf("failed to create seed manifest, error: %v", err)
 			}
 
-			err = ucc.FetchClusterConfig(context.TODO(), tmpDir)
+			err = ucc.FetchClusterConfig(context.TODO(), tmpDir, nil, nil)
 			if !tc.expectedErr && err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
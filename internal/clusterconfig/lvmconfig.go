@@ -23,10 +23,18 @@ import (
 	"path/filepath"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/utils"
 	cp "github.com/otiai10/copy"
 )
 
-func (r *UpgradeClusterConfigGather) FetchLvmConfig(ctx context.Context, ostreeDir string) error {
+// FetchLvmConfig copies the node lvm devices file, if any, into the given filesystem directory
+func (r *UpgradeClusterConfigGather) FetchLvmConfig(ctx context.Context, ostreeDir string, progress utils.ProgressFunc) error {
+	return utils.RunBackupItems("lvm configuration", []utils.BackupItem{ //nolint:wrapcheck
+		{Name: "lvm devices", Backup: func() error { return r.fetchLvmDevices(ostreeDir) }},
+	}, progress)
+}
+
+func (r *UpgradeClusterConfigGather) fetchLvmDevices(ostreeDir string) error {
 	r.Log.Info("Fetching node lvm files")
 	lvmConfigPath := filepath.Join(ostreeDir, common.OptOpenshift, common.LvmConfigDir)
 	if err := os.MkdirAll(lvmConfigPath, 0o700); err != nil {
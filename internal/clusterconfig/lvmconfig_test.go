@@ -70,7 +70,7 @@ func TestFetchLvmConfig(t *testing.T) {
 				Log: logr.Discard(),
 			}
 
-			err := ucc.FetchLvmConfig(context.Background(), tmpDir)
+			err := ucc.FetchLvmConfig(context.Background(), tmpDir, nil)
 			if err != nil {
 				if tc.expectedErr {
 					assert.Error(t, err)
@@ -12,6 +12,8 @@ import (
 	context "context"
 	reflect "reflect"
 
+	seedreconfig "github.com/openshift-kni/lifecycle-agent/api/seedreconfig"
+	utils "github.com/openshift-kni/lifecycle-agent/utils"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -41,29 +43,43 @@ func (m *MockUpgradeClusterConfigGatherer) EXPECT() *MockUpgradeClusterConfigGat
 }
 
 // FetchClusterConfig mocks base method.
-func (m *MockUpgradeClusterConfigGatherer) FetchClusterConfig(ctx context.Context, ostreeVarDir string) error {
+func (m *MockUpgradeClusterConfigGatherer) FetchClusterConfig(ctx context.Context, ostreeVarDir string, clockSkewProtection *seedreconfig.ClockSkewProtection, progress utils.ProgressFunc) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FetchClusterConfig", ctx, ostreeVarDir)
+	ret := m.ctrl.Call(m, "FetchClusterConfig", ctx, ostreeVarDir, clockSkewProtection, progress)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // FetchClusterConfig indicates an expected call of FetchClusterConfig.
-func (mr *MockUpgradeClusterConfigGathererMockRecorder) FetchClusterConfig(ctx, ostreeVarDir any) *gomock.Call {
+func (mr *MockUpgradeClusterConfigGathererMockRecorder) FetchClusterConfig(ctx, ostreeVarDir, clockSkewProtection, progress any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchClusterConfig", reflect.TypeOf((*MockUpgradeClusterConfigGatherer)(nil).FetchClusterConfig), ctx, ostreeVarDir)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchClusterConfig", reflect.TypeOf((*MockUpgradeClusterConfigGatherer)(nil).FetchClusterConfig), ctx, ostreeVarDir, clockSkewProtection, progress)
 }
 
 // FetchLvmConfig mocks base method.
-func (m *MockUpgradeClusterConfigGatherer) FetchLvmConfig(ctx context.Context, ostreeVarDir string) error {
+func (m *MockUpgradeClusterConfigGatherer) FetchLvmConfig(ctx context.Context, ostreeVarDir string, progress utils.ProgressFunc) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FetchLvmConfig", ctx, ostreeVarDir)
+	ret := m.ctrl.Call(m, "FetchLvmConfig", ctx, ostreeVarDir, progress)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // FetchLvmConfig indicates an expected call of FetchLvmConfig.
-func (mr *MockUpgradeClusterConfigGathererMockRecorder) FetchLvmConfig(ctx, ostreeVarDir any) *gomock.Call {
+func (mr *MockUpgradeClusterConfigGathererMockRecorder) FetchLvmConfig(ctx, ostreeVarDir, progress any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchLvmConfig", reflect.TypeOf((*MockUpgradeClusterConfigGatherer)(nil).FetchLvmConfig), ctx, ostreeVarDir)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchLvmConfig", reflect.TypeOf((*MockUpgradeClusterConfigGatherer)(nil).FetchLvmConfig), ctx, ostreeVarDir, progress)
+}
+
+// RenderProxyConfig mocks base method.
+func (m *MockUpgradeClusterConfigGatherer) RenderProxyConfig(ctx context.Context, deploymentDir string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenderProxyConfig", ctx, deploymentDir)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenderProxyConfig indicates an expected call of RenderProxyConfig.
+func (mr *MockUpgradeClusterConfigGathererMockRecorder) RenderProxyConfig(ctx, deploymentDir any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenderProxyConfig", reflect.TypeOf((*MockUpgradeClusterConfigGatherer)(nil).RenderProxyConfig), ctx, deploymentDir)
 }
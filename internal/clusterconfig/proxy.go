@@ -0,0 +1,84 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const (
+	// systemdProxyConfFile sets the proxy in the environment of every systemd unit, crio included
+	systemdProxyConfFile = "/etc/systemd/system.conf.d/99-lca-proxy.conf"
+	// containersProxyConfFile sets the proxy in the environment of podman
+	containersProxyConfFile = "/etc/containers/containers.conf.d/99-lca-proxy.conf"
+)
+
+// RenderProxyConfig writes the cluster-wide proxy into the etc of the given deployment, so that the image pulls
+// done during the first boot of the new stateroot honor the proxy before MCO renders its own configuration.
+// Nothing is written when the cluster has no proxy
+func (r *UpgradeClusterConfigGather) RenderProxyConfig(ctx context.Context, deploymentDir string) error {
+	proxy := v1.Proxy{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: proxyName}, &proxy); err != nil {
+		return fmt.Errorf("failed to get proxy: %w", err)
+	}
+
+	env := utils.ProxyEnv(&proxy)
+	if len(env) == 0 {
+		r.Log.Info("No cluster-wide proxy configured, skipping the proxy configuration of the new stateroot")
+		return nil
+	}
+
+	quoted := make([]string, 0, len(env))
+	for _, e := range env {
+		quoted = append(quoted, fmt.Sprintf("%q", e))
+	}
+	files := map[string]string{
+		systemdProxyConfFile:    fmt.Sprintf("[Manager]\nDefaultEnvironment=%s\n", strings.Join(quoted, " ")),
+		containersProxyConfFile: fmt.Sprintf("[engine]\nenv = [%s]\n", strings.Join(quoted, ", ")),
+	}
+	for file, content := range files {
+		filePath := filepath.Join(deploymentDir, file)
+		r.Log.Info("Writing proxy configuration into new stateroot", "path", filePath)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			return fmt.Errorf("failed to make dir for %s: %w", filePath, err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil { //nolint:gosec
+			return fmt.Errorf("failed to write proxy configuration to %s: %w", filePath, err)
+		}
+	}
+	return nil
+}
+
+// RemoveProxyConfig removes the proxy configuration written by RenderProxyConfig from the host. Once the upgrade
+// is completed MCO owns the proxy configuration, and the leftover files would shadow later changes of the proxy
+func RemoveProxyConfig() error {
+	for _, file := range []string{systemdProxyConfFile, containersProxyConfFile} {
+		if err := os.Remove(filepath.Join(hostPath, file)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove proxy configuration %s: %w", file, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	ocpV1 "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRenderProxyConfig(t *testing.T) {
+	testcases := []struct {
+		name            string
+		proxy           *ocpV1.Proxy
+		expectedSystemd string
+		expectedEngine  string
+	}{
+		{
+			name:  "No proxy",
+			proxy: &ocpV1.Proxy{ObjectMeta: metav1.ObjectMeta{Name: proxyName}},
+		},
+		{
+			name: "Proxy from status",
+			proxy: &ocpV1.Proxy{
+				ObjectMeta: metav1.ObjectMeta{Name: proxyName},
+				Spec:       ocpV1.ProxySpec{HTTPProxy: "http://spec:3128", NoProxy: "example.com"},
+				Status: ocpV1.ProxyStatus{
+					HTTPProxy:  "http://proxy:3128",
+					HTTPSProxy: "http://proxy:3129",
+					NoProxy:    ".cluster.local,example.com",
+				},
+			},
+			expectedSystemd: "[Manager]\nDefaultEnvironment=\"HTTP_PROXY=http://proxy:3128\" \"HTTPS_PROXY=http://proxy:3129\" \"NO_PROXY=.cluster.local,example.com\"\n",
+			expectedEngine:  "[engine]\nenv = [\"HTTP_PROXY=http://proxy:3128\", \"HTTPS_PROXY=http://proxy:3129\", \"NO_PROXY=.cluster.local,example.com\"]\n",
+		},
+		{
+			name: "Proxy from spec",
+			proxy: &ocpV1.Proxy{
+				ObjectMeta: metav1.ObjectMeta{Name: proxyName},
+				Spec:       ocpV1.ProxySpec{HTTPSProxy: "http://spec:3128"},
+			},
+			expectedSystemd: "[Manager]\nDefaultEnvironment=\"HTTPS_PROXY=http://spec:3128\"\n",
+			expectedEngine:  "[engine]\nenv = [\"HTTPS_PROXY=http://spec:3128\"]\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			deploymentDir := t.TempDir()
+			fakeClient, err := getFakeClientFromObjects(tc.proxy)
+			assert.NoError(t, err)
+
+			ucc := &UpgradeClusterConfigGather{Client: fakeClient, Log: logr.Discard()}
+			assert.NoError(t, ucc.RenderProxyConfig(context.Background(), deploymentDir))
+
+			for file, expected := range map[string]string{systemdProxyConfFile: tc.expectedSystemd, containersProxyConfFile: tc.expectedEngine} {
+				content, err := os.ReadFile(filepath.Join(deploymentDir, file))
+				if expected == "" {
+					assert.True(t, os.IsNotExist(err))
+					continue
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, expected, string(content))
+			}
+
+			// The rendered files are removed from the host once the upgrade is completed
+			hostPath = deploymentDir
+			assert.NoError(t, RemoveProxyConfig())
+			for _, file := range []string{systemdProxyConfFile, containersProxyConfFile} {
+				_, err := os.Stat(filepath.Join(deploymentDir, file))
+				assert.True(t, os.IsNotExist(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Package collections provides generic helpers for the lists of images, hosts and resource references handled by the
+// LCA. All the helpers preserve the order of their input, keeping the first occurrence of repeated items, so that
+// the resources, jobs and reports built from their output are deterministic.
+package collections
+
+// OrderedSet is a set keeping its items in the order they were first added. The zero value is an empty set.
+type OrderedSet[T comparable] struct {
+	items []T
+	index map[T]struct{}
+}
+
+// NewOrderedSet returns a set of the given items
+func NewOrderedSet[T comparable](items ...T) *OrderedSet[T] {
+	s := &OrderedSet[T]{}
+	s.Add(items...)
+	return s
+}
+
+// Add adds the items not in the set yet, at its end, and returns the number of items added
+func (s *OrderedSet[T]) Add(items ...T) int {
+	if s.index == nil {
+		s.index = make(map[T]struct{}, len(items))
+	}
+	added := 0
+	for _, item := range items {
+		if _, found := s.index[item]; found {
+			continue
+		}
+		s.index[item] = struct{}{}
+		s.items = append(s.items, item)
+		added++
+	}
+	return added
+}
+
+// Remove removes the item from the set, keeping the order of the others, and returns whether it was in the set
+func (s *OrderedSet[T]) Remove(item T) bool {
+	if _, found := s.index[item]; !found {
+		return false
+	}
+	delete(s.index, item)
+	for i := range s.items {
+		if s.items[i] == item {
+			s.items = append(s.items[:i], s.items[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Contains returns whether the item is in the set
+func (s *OrderedSet[T]) Contains(item T) bool {
+	_, found := s.index[item]
+	return found
+}
+
+// Len returns the number of items in the set
+func (s *OrderedSet[T]) Len() int {
+	return len(s.items)
+}
+
+// Items returns a copy of the items of the set, in the order they were added. It is never nil.
+func (s *OrderedSet[T]) Items() []T {
+	return append(make([]T, 0, len(s.items)), s.items...)
+}
+
+// Unique returns the items of the list without duplicates, in the order of their first occurrence. It is never nil.
+func Unique[T comparable](list []T) []T {
+	return NewOrderedSet(list...).Items()
+}
+
+// Union returns the items of all the lists without duplicates, in the order of their first occurrence
+func Union[T comparable](lists ...[]T) []T {
+	s := &OrderedSet[T]{}
+	for _, list := range lists {
+		s.Add(list...)
+	}
+	return s.Items()
+}
+
+// Difference returns the items of a that are not in b, without duplicates, in the order of a
+func Difference[T comparable](a, b []T) []T {
+	exclude := NewOrderedSet(b...)
+	return filter(a, func(item T) bool { return !exclude.Contains(item) })
+}
+
+// Intersect returns the items of a that are also in b, without duplicates, in the order of a
+func Intersect[T comparable](a, b []T) []T {
+	include := NewOrderedSet(b...)
+	return filter(a, include.Contains)
+}
+
+func filter[T comparable](list []T, keep func(T) bool) []T {
+	s := &OrderedSet[T]{}
+	for _, item := range list {
+		if keep(item) {
+			s.Add(item)
+		}
+	}
+	return s.Items()
+}
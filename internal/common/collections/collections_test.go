@@ -0,0 +1,60 @@
+package collections
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedSet(t *testing.T) {
+	var s OrderedSet[string]
+	assert.Equal(t, 0, s.Len())
+	assert.False(t, s.Contains("a"))
+	assert.False(t, s.Remove("a"))
+	assert.Equal(t, []string{}, s.Items())
+
+	assert.Equal(t, 3, s.Add("c", "a", "c", "b"))
+	assert.Equal(t, 0, s.Add("a"))
+	assert.Equal(t, []string{"c", "a", "b"}, s.Items())
+	assert.True(t, s.Contains("b"))
+
+	assert.True(t, s.Remove("a"))
+	assert.False(t, s.Contains("a"))
+	assert.Equal(t, []string{"c", "b"}, s.Items())
+	// Re-adding an item moves it to the end
+	assert.Equal(t, 1, s.Add("a"))
+	assert.Equal(t, []string{"c", "b", "a"}, s.Items())
+
+	// The items are a copy
+	items := s.Items()
+	items[0] = "z"
+	assert.Equal(t, []string{"c", "b", "a"}, s.Items())
+	assert.Equal(t, 3, NewOrderedSet("c", "b", "a").Len())
+}
+
+func TestUnique(t *testing.T) {
+	assert.Equal(t, []int{3, 1, 2}, Unique([]int{3, 1, 1, 2, 3}))
+	assert.Equal(t, []int{}, Unique([]int{}))
+	assert.Equal(t, []int{}, Unique[int](nil))
+
+	type ref struct{ name, namespace string }
+	assert.Equal(t, []ref{{"a", "ns1"}, {"a", "ns2"}},
+		Unique([]ref{{"a", "ns1"}, {"a", "ns2"}, {"a", "ns1"}}))
+}
+
+func TestSetOperations(t *testing.T) {
+	a := []string{"quay.io/a", "quay.io/b", "quay.io/c", "quay.io/b"}
+	b := []string{"quay.io/c", "quay.io/d", "quay.io/a"}
+
+	assert.Equal(t, []string{"quay.io/a", "quay.io/b", "quay.io/c", "quay.io/d"}, Union(a, b))
+	assert.Equal(t, []string{"quay.io/b"}, Difference(a, b))
+	assert.Equal(t, []string{"quay.io/d"}, Difference(b, a))
+	// The order is the order of the first list
+	assert.Equal(t, []string{"quay.io/a", "quay.io/c"}, Intersect(a, b))
+	assert.Equal(t, []string{"quay.io/c", "quay.io/a"}, Intersect(b, a))
+
+	assert.Equal(t, []string{}, Difference(nil, b))
+	assert.Equal(t, []string{"quay.io/a", "quay.io/b", "quay.io/c"}, Difference(a, nil))
+	assert.Equal(t, []string{}, Intersect(a, nil))
+	assert.Equal(t, []string{}, Union[string]())
+}
@@ -23,13 +23,21 @@ const (
 	BackupCertsDir  = "/var/tmp/backupCertsDir"
 	BackupChecksDir = "/var/tmp/checks"
 
+	// SeedProvenanceFile records the source cluster of the last created seed image, outside the backup dir
+	SeedProvenanceFile = "/var/tmp/seed-provenance.json"
+
+	// StaterootSeedDigestSuffixAnnotation suffixes the name of the stateroot with the short digest of the seed image of
+	// the IBU when set to "true", for the seed images referenced by digest
+	StaterootSeedDigestSuffixAnnotation = "lca.openshift.io/staterootSeedDigestSuffix"
+
 	// Workload partitioning annotation key and value
 	WorkloadManagementAnnotationKey   = "target.workload.openshift.io/management"
 	WorkloadManagementAnnotationValue = `{"effect": "PreferredDuringScheduling"}`
 
 	// ImageRegistryAuthFile is the pull secret. Written by the machine-config-operator
 	ImageRegistryAuthFile = "/var/lib/kubelet/config.json"
-	KubeconfigFile        = "/etc/kubernetes/static-pod-resources/kube-apiserver-certs/secrets/node-kubeconfigs/lb-ext.kubeconfig"
+	NodeKubeconfigsDir    = "/etc/kubernetes/static-pod-resources/kube-apiserver-certs/secrets/node-kubeconfigs"
+	KubeconfigFile        = NodeKubeconfigsDir + "/lb-ext.kubeconfig"
 
 	DefaultRecertImage     = "quay.io/edge-infrastructure/recert:v0"
 	EtcdStaticPodFile      = "/etc/kubernetes/manifests/etcd-pod.yaml"
@@ -52,11 +60,23 @@ const (
 	LvmConfigDir                      = "lvm-configuration"
 	LvmDevicesPath                    = "/etc/lvm/devices/system.devices"
 	CABundleFilePath                  = "/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem"
+	// ChunkedCABundleFileName and ChunkedRegistriesConfFileName are the bundles reassembled from chunks in the cluster
+	// configuration, installed in ChunkedCABundleAnchorFile and ChunkedRegistriesConfFile of the new stateroot
+	ChunkedCABundleFileName       = "chunked-ca-bundle.pem"
+	ChunkedRegistriesConfFileName = "chunked-registries.conf"
+	ChunkedCABundleAnchorFile     = "/etc/pki/ca-trust/source/anchors/lca-chunked-ca-bundle.pem"
+	ChunkedRegistriesConfFile     = "/etc/containers/registries.conf.d/99-lca-chunked.conf"
 
 	LCAConfigDir                                    = "/var/lib/lca"
 	IBUAutoRollbackConfigFile                       = LCAConfigDir + "/autorollback_config.json"
 	IBUAutoRollbackInitMonitorTimeoutDefaultSeconds = 1800
+	IBUPostPivotLoggingConfigFile                   = LCAConfigDir + "/postpivot_logging_config.json"
+	PostPivotLogFile                                = "/var/log/lca/post-pivot.log"
+	PackageReportFile                               = LCAConfigDir + "/package-report.json"
+	ValidatedKubeconfigsDir                         = LCAConfigDir + "/kubeconfigs"
 	IBUInitMonitorService                           = "lca-init-monitor.service"
+	DeferredSeedVarDataFile                         = "/var/.lca-seed-var.tgz"
+	SeedVarDataService                              = "lca-seed-var.service"
 	IBUInitMonitorServiceFile                       = "/etc/systemd/system/" + IBUInitMonitorService
 
 	LcaNamespace = "openshift-lifecycle-agent"
@@ -76,6 +96,19 @@ const (
 	// Bump this every time the seed format changes in a backwards incompatible way
 	SeedFormatVersion  = 3
 	SeedFormatOCILabel = "com.openshift.lifecycle-agent.seed_format_version"
+	// SeedPrecacheSizeOCILabel is the size in bytes of the images listed by the seed image for precaching, in the
+	// container storage of the seed cluster
+	SeedPrecacheSizeOCILabel = "com.openshift.lifecycle-agent.seed_precache_size"
+	// SeedHasProxyOCILabel is whether the seed cluster has a cluster-wide proxy, true or false
+	SeedHasProxyOCILabel = "com.openshift.lifecycle-agent.seed_has_proxy"
+	// SeedBaseOSVersionOCILabel is the RHCOS version of the seed cluster
+	SeedBaseOSVersionOCILabel = "com.openshift.lifecycle-agent.seed_base_os_version"
+	// SeedFIPSOCILabel is whether the seed cluster runs in FIPS mode, true or false
+	SeedFIPSOCILabel = "com.openshift.lifecycle-agent.seed_fips"
+	// SeedPrecacheListOCILabel is the comma-separated list of the images listed by the seed image for precaching
+	SeedPrecacheListOCILabel = "com.openshift.lifecycle-agent.seed_precache_list"
+	// SeedClusterInfoOCILabel is the seed cluster info of the seed image, in compact JSON
+	SeedClusterInfoOCILabel = "com.openshift.lifecycle-agent.seed_cluster_info"
 
 	PullSecretName           = "pull-secret"
 	PullSecretEmptyData      = "{\"auths\":{\"registry.connect.redhat.com\":{\"username\":\"empty\",\"password\":\"empty\",\"auth\":\"ZW1wdHk6ZW1wdHk=\",\"email\":\"\"}}}" //nolint:gosec
@@ -0,0 +1,126 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+)
+
+const (
+	// StaterootLayoutV1 is the layout of the stateroots set up from the seed formats 1 to 3
+	StaterootLayoutV1 = 1
+
+	// seedDigestSuffixLength is the number of hex characters of the seed digest suffixing the stateroot names
+	seedDigestSuffixLength = 12
+)
+
+// staterootLayoutVersions maps each supported seed format version to the layout of the stateroots set up from it
+var staterootLayoutVersions = map[int]int{
+	1: StaterootLayoutV1,
+	2: StaterootLayoutV1,
+	3: StaterootLayoutV1,
+}
+
+// StaterootLayout computes the names and paths of the stateroots set up from seed images. The layout is versioned
+// by the seed format, so that a new seed format can change where its stateroot lives without breaking the path
+// assumptions of the stateroots set up from older seeds. The zero value is the layout of the current seed format.
+type StaterootLayout struct {
+	version int
+}
+
+// NewStaterootLayout returns the layout of the stateroots set up from seed images of the given format version
+func NewStaterootLayout(seedFormatVersion int) (StaterootLayout, error) {
+	version, ok := staterootLayoutVersions[seedFormatVersion]
+	if !ok {
+		return StaterootLayout{}, fmt.Errorf("no stateroot layout for seed format version %d", seedFormatVersion)
+	}
+	return StaterootLayout{version: version}, nil
+}
+
+// CurrentStaterootLayout returns the layout of the stateroots set up from seed images of the current format version
+func CurrentStaterootLayout() StaterootLayout {
+	return StaterootLayout{version: staterootLayoutVersions[SeedFormatVersion]}
+}
+
+// Version returns the version of the layout
+func (l StaterootLayout) Version() int {
+	if l.version == 0 {
+		return staterootLayoutVersions[SeedFormatVersion]
+	}
+	return l.version
+}
+
+// Name returns the name of the stateroot set up from a seed image of the given OCP version, suffixed with the short
+// seed digest when one is given, so that different seed images of the same OCP version get different stateroots
+func (l StaterootLayout) Name(seedImageVersion, seedDigest string) string {
+	name := fmt.Sprintf("rhcos_%s", strings.ReplaceAll(seedImageVersion, "-", "_"))
+	if seedDigest != "" {
+		name = fmt.Sprintf("%s_%s", name, seedDigest)
+	}
+	return name
+}
+
+// DesiredName returns the name of the stateroot set up from the seed image of the IBU, suffixed with the short digest
+// of the seed image when the IBU has the StaterootSeedDigestSuffixAnnotation
+func (l StaterootLayout) DesiredName(ibu *v1alpha1.ImageBasedUpgrade) string {
+	var seedDigest string
+	if ibu.GetAnnotations()[StaterootSeedDigestSuffixAnnotation] == "true" {
+		seedDigest = ShortSeedDigest(ibu.Spec.SeedImageRef.Image)
+	}
+	return l.Name(ibu.Spec.SeedImageRef.Version, seedDigest)
+}
+
+// ShortSeedDigest returns the first hex characters of the digest of a seed image referenced by digest, e.g.
+// quay.io/seed@sha256:..., or an empty string for a seed image referenced by tag, whose digest may change
+func ShortSeedDigest(seedImage string) string {
+	_, digest, found := strings.Cut(seedImage, "@")
+	if !found {
+		return ""
+	}
+	_, hex, found := strings.Cut(digest, ":")
+	if !found || len(hex) < seedDigestSuffixLength {
+		return ""
+	}
+	return hex[:seedDigestSuffixLength]
+}
+
+// Path returns the path of the stateroot with the given name
+func (l StaterootLayout) Path(osname string) string {
+	return fmt.Sprintf("%s/ostree/deploy/%s", OstreeDeployPathPrefix, osname)
+}
+
+// DeploymentDir returns the path of the given deployment of the stateroot
+func (l StaterootLayout) DeploymentDir(osname, deployment string) string {
+	return filepath.Join(l.Path(osname), "deploy", deployment)
+}
+
+// OptOpenshift returns the path to the `/opt/openshift` directory
+// in a given stateroot. Note that since `/opt` in ostree systems is actually a
+// symlink to `/var/opt`, and the `/var` directory of a stateroot is outside
+// the stateroot deployment, we need to access it in this odd manner.
+func (l StaterootLayout) OptOpenshift(staterootPath string) string {
+	return filepath.Join(staterootPath, "var", OptOpenshift)
+}
+
+// CertsDir returns the host path of the kubeconfig crypto backup in the stateroot set up from the seed image of the IBU
+func (l StaterootLayout) CertsDir(ibu *v1alpha1.ImageBasedUpgrade) string {
+	return PathOutsideChroot(filepath.Join(l.OptOpenshift(l.Path(l.DesiredName(ibu))), KubeconfigCryptoDir))
+}
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+)
+
+func TestStaterootLayout(t *testing.T) {
+	layout, err := NewStaterootLayout(SeedFormatVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentStaterootLayout(), layout)
+	assert.Equal(t, StaterootLayoutV1, layout.Version())
+	assert.Equal(t, layout.Version(), StaterootLayout{}.Version())
+
+	_, err = NewStaterootLayout(SeedFormatVersion + 1)
+	assert.Error(t, err)
+
+	ibu := &v1alpha1.ImageBasedUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: "upgrade"},
+		Spec:       v1alpha1.ImageBasedUpgradeSpec{SeedImageRef: v1alpha1.SeedImageRef{Version: "4.16.0-rc.1"}},
+	}
+	assert.Equal(t, "rhcos_4.16.0_rc.1", layout.DesiredName(ibu))
+	assert.Equal(t, "/ostree/deploy/rhcos_4.16.0_rc.1", layout.Path(layout.DesiredName(ibu)))
+	assert.Equal(t, "/ostree/deploy/rhcos_4.16.0_rc.1/deploy/abc.0", layout.DeploymentDir("rhcos_4.16.0_rc.1", "abc.0"))
+	assert.Equal(t, "/ostree/deploy/rhcos_4.16.0_rc.1/var/opt/openshift", layout.OptOpenshift("/ostree/deploy/rhcos_4.16.0_rc.1"))
+}
+
+func TestStaterootLayoutSeedDigestSuffix(t *testing.T) {
+	layout := CurrentStaterootLayout()
+	ibu := &v1alpha1.ImageBasedUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: "upgrade"},
+		Spec: v1alpha1.ImageBasedUpgradeSpec{SeedImageRef: v1alpha1.SeedImageRef{
+			Version: "4.16.0",
+			Image:   "quay.io/seed@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		}},
+	}
+	assert.Equal(t, "rhcos_4.16.0", layout.DesiredName(ibu))
+
+	ibu.SetAnnotations(map[string]string{StaterootSeedDigestSuffixAnnotation: "true"})
+	assert.Equal(t, "rhcos_4.16.0_0123456789ab", layout.DesiredName(ibu))
+	assert.Equal(t, layout.Name("4.16.0", "0123456789ab"), layout.DesiredName(ibu))
+
+	// a seed image referenced by tag has no digest suffix
+	ibu.Spec.SeedImageRef.Image = "quay.io/seed:4.16.0"
+	assert.Equal(t, "rhcos_4.16.0", layout.DesiredName(ibu))
+	assert.Equal(t, "", ShortSeedDigest("quay.io/seed@sha256:0123"))
+}
@@ -18,14 +18,15 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	cp "github.com/otiai10/copy"
+	"golang.org/x/sys/unix"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -40,6 +41,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/internal/common/collections"
 )
 
 // TODO: Need a better way to change this but will require relatively big refactoring
@@ -59,31 +61,54 @@ func GetConfigMap(ctx context.Context, c client.Client, configMap v1alpha1.Confi
 	return cm, nil
 }
 
-// GetConfigMaps retrieves a collection of configmaps from cluster
+// GetConfigMaps retrieves a collection of configmaps from cluster, once each, in the order they are listed. The
+// optional configmaps are skipped when missing, unless also listed as required. All the configmaps that cannot be
+// retrieved are reported together, the missing ones last so that a transient failure of another one takes precedence
+// over a missing one for the callers checking whether the error is a not found error.
 func GetConfigMaps(ctx context.Context, c client.Client, configMaps []v1alpha1.ConfigMapRef) ([]corev1.ConfigMap, error) {
-	var cms []corev1.ConfigMap
-	var cmSet = map[v1alpha1.ConfigMapRef]bool{}
-	var uniqueCms []v1alpha1.ConfigMapRef
-
-	// Remove duplicate configmaps
+	refs := &collections.OrderedSet[types.NamespacedName]{}
+	optional := map[types.NamespacedName]bool{}
 	for _, cm := range configMaps {
-		if _, found := cmSet[cm]; !found {
-			cmSet[cm] = true
-			uniqueCms = append(uniqueCms, cm)
+		key := types.NamespacedName{Name: cm.Name, Namespace: cm.Namespace}
+		if refs.Add(key) == 1 {
+			optional[key] = cm.Optional
+		} else if !cm.Optional {
+			optional[key] = false
 		}
 	}
 
-	for _, cm := range uniqueCms {
-		existingCm, err := GetConfigMap(ctx, c, cm)
-		if err != nil {
-			return nil, err
+	var cms []corev1.ConfigMap
+	var failed, missing []error
+	for _, key := range refs.Items() {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, key, cm); err != nil {
+			switch {
+			case apierrors.IsNotFound(err) && optional[key]:
+				continue
+			case apierrors.IsNotFound(err):
+				missing = append(missing, fmt.Errorf("failed to get configMap %s: %w", key, err))
+			default:
+				failed = append(failed, fmt.Errorf("failed to get configMap %s: %w", key, err))
+			}
+			continue
 		}
-		cms = append(cms, *existingCm)
+		cms = append(cms, *cm)
+	}
+	if len(failed)+len(missing) != 0 {
+		return nil, errors.Join(append(failed, missing...)...)
 	}
-
 	return cms, nil
 }
 
+// AvailableSpace returns the space available to unprivileged users on the filesystem holding path
+func AvailableSpace(path string) (int64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to stat filesystem of %s: %w", path, err)
+	}
+	return int64(stat.Bavail) * stat.Bsize, nil //nolint:gosec
+}
+
 // PathOutsideChroot returns filepath with host fs
 func PathOutsideChroot(filename string) string {
 	if _, err := os.Stat(Host); err != nil {
@@ -99,18 +124,6 @@ func CopyOutsideChroot(src, dest string) error {
 	return nil
 }
 
-func GetStaterootPath(osname string) string {
-	return fmt.Sprintf("%s/ostree/deploy/%s", OstreeDeployPathPrefix, osname)
-}
-
-// GetStaterootOptOpenshift returns the path to the `/opt/openshift` directory
-// in a given stateroot. Note that since `/opt` in ostree systems is actually a
-// symlink to `/var/opt`, and the `/var` directory of a stateroot is outside
-// the stateroot deployment, we need to access it in this odd manner.
-func GetStaterootOptOpenshift(staterootPath string) string {
-	return filepath.Join(staterootPath, "var", OptOpenshift)
-}
-
 // FuncTimer check execution time
 func FuncTimer(start time.Time, name string, r logr.Logger) {
 	elapsed := time.Since(start)
@@ -154,27 +167,8 @@ func RetryOnConflictOrRetriable(backoff wait.Backoff, fn func() error) error {
 	return retry.OnError(backoff, isConflictOrRetriable, fn) //nolint:wrapcheck
 }
 
-func GetDesiredStaterootName(ibu *v1alpha1.ImageBasedUpgrade) string {
-	return GetStaterootName(ibu.Spec.SeedImageRef.Version)
-}
-
-func GetStaterootCertsDir(ibu *v1alpha1.ImageBasedUpgrade) string {
-	return PathOutsideChroot(filepath.Join(GetStaterootOptOpenshift(GetStaterootPath(GetDesiredStaterootName(ibu))), KubeconfigCryptoDir))
-}
-
-func GetStaterootName(seedImageVersion string) string {
-	return fmt.Sprintf("rhcos_%s", strings.ReplaceAll(seedImageVersion, "-", "_"))
-}
-
+// RemoveDuplicates returns the items of the list without duplicates, in the order of their first occurrence.
+// Refer to the collections package for the other operations on lists.
 func RemoveDuplicates[T comparable](list []T) []T {
-	result := []T{}
-	mp := make(map[T]bool)
-	for _, item := range list {
-		if _, present := mp[item]; present {
-			continue
-		}
-		result = append(result, item)
-		mp[item] = true
-	}
-	return result
+	return collections.Unique(list)
 }
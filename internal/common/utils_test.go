@@ -1,9 +1,16 @@
 package common
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
 )
 
 func TestRemoveDuplicates(t *testing.T) {
@@ -19,3 +26,33 @@ func TestRemoveDuplicates(t *testing.T) {
 	resStr := RemoveDuplicates[string](strs)
 	assert.Equal(t, []string{"a/b/c/d", "a/b/c"}, resStr)
 }
+
+func TestGetConfigMaps(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "oadp-1", Namespace: "openshift-adp"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "oadp-2", Namespace: "openshift-adp"}},
+	).Build()
+
+	cms, err := GetConfigMaps(context.Background(), c, []v1alpha1.ConfigMapRef{
+		{Name: "oadp-2", Namespace: "openshift-adp"},
+		{Name: "site-specific", Namespace: "openshift-adp", Optional: true},
+		{Name: "oadp-1", Namespace: "openshift-adp"},
+		{Name: "oadp-2", Namespace: "openshift-adp"},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, cms, 2) {
+		assert.Equal(t, "oadp-2", cms[0].Name)
+		assert.Equal(t, "oadp-1", cms[1].Name)
+	}
+
+	// All the missing configmaps are reported, an optional one listed as required too included
+	_, err = GetConfigMaps(context.Background(), c, []v1alpha1.ConfigMapRef{
+		{Name: "missing-1", Namespace: "openshift-adp"},
+		{Name: "oadp-1", Namespace: "openshift-adp"},
+		{Name: "missing-2", Namespace: "openshift-adp", Optional: true},
+		{Name: "missing-2", Namespace: "openshift-adp"},
+	})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.ErrorContains(t, err, "failed to get configMap openshift-adp/missing-1")
+	assert.ErrorContains(t, err, "failed to get configMap openshift-adp/missing-2")
+}
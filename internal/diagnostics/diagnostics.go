@@ -0,0 +1,221 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+// Mode is the type of diagnostics data to collect on stage failure
+type Mode string
+
+const (
+	// ModeSosreport collects a host sosreport
+	ModeSosreport Mode = "sosreport"
+	// ModeMustGather collects a cluster must-gather, using the default gather image unless one is provided
+	ModeMustGather Mode = "must-gather"
+)
+
+const (
+	// DefaultTimeout bounds the time spent collecting diagnostics
+	DefaultTimeout = 10 * time.Minute
+	// DefaultMaxSize is the maximum size in bytes of the collected diagnostics kept on the host
+	DefaultMaxSize int64 = 1 << 30
+	// DefaultRetention is the number of diagnostics collections kept on the host, the oldest ones being removed first
+	DefaultRetention = 3
+)
+
+// sizeCheckInterval is the interval at which the size of the diagnostics is checked while collecting
+var sizeCheckInterval = 5 * time.Second
+
+// Collector collects diagnostics data on the host for support escalation
+type Collector struct {
+	log                  logr.Logger
+	hostCommandsExecutor ops.Execute
+	timeout              time.Duration
+	maxSize              int64
+	retention            int
+}
+
+// NewCollector creates a diagnostics Collector with the default time, size and retention limits
+func NewCollector(log logr.Logger, hostCommandsExecutor ops.Execute) *Collector {
+	return &Collector{
+		log:                  log,
+		hostCommandsExecutor: hostCommandsExecutor,
+		timeout:              DefaultTimeout,
+		maxSize:              DefaultMaxSize,
+		retention:            DefaultRetention,
+	}
+}
+
+// IsValidMode returns true if the mode is a supported diagnostics mode
+func IsValidMode(mode Mode) bool {
+	return mode == ModeSosreport || mode == ModeMustGather
+}
+
+// Collect gathers diagnostics data of the given mode into destDir, a host path. The older collections found next to
+// destDir are removed beyond the retention limit, and the collection is stopped and removed as soon as it exceeds the
+// size limit, so it never fills up the host filesystem.
+func (c *Collector) Collect(ctx context.Context, mode Mode, destDir, gatherImage string) error {
+	if !IsValidMode(mode) {
+		return fmt.Errorf("unsupported diagnostics mode %q", mode)
+	}
+
+	c.prune(destDir)
+	if err := os.MkdirAll(common.PathOutsideChroot(destDir), 0o700); err != nil {
+		return fmt.Errorf("failed to create diagnostics dir %s: %w", destDir, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	exceededSize := c.watchSize(ctx, cancel, destDir)
+
+	c.log.Info("Collecting diagnostics", "mode", mode, "dir", destDir)
+	err := c.run(ctx, mode, destDir, gatherImage)
+	cancel()
+	size := exceededSize()
+	if size == 0 && err == nil {
+		if size, err = dirSize(common.PathOutsideChroot(destDir)); err != nil {
+			return fmt.Errorf("failed to get size of diagnostics dir %s: %w", destDir, err)
+		}
+	}
+	if size > c.maxSize {
+		c.remove(destDir)
+		return fmt.Errorf("diagnostics size %d exceeds limit of %d bytes, removed", size, c.maxSize)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.log.Info("Diagnostics collected", "mode", mode, "dir", destDir, "size", size)
+	return nil
+}
+
+// run runs the commands collecting the diagnostics of the given mode into destDir
+func (c *Collector) run(ctx context.Context, mode Mode, destDir, gatherImage string) error {
+	timeout := strconv.Itoa(int(c.timeout.Seconds()))
+	switch mode {
+	case ModeSosreport:
+		if _, err := c.hostCommandsExecutor.ExecuteWithContext(ctx, "timeout", timeout, "sos", "report", "--batch",
+			"--quiet", "--tmp-dir", destDir); err != nil {
+			return fmt.Errorf("failed to collect sosreport: %w", err)
+		}
+	case ModeMustGather:
+		args := []string{timeout, "oc", "--kubeconfig", common.KubeconfigFile, "adm", "must-gather", "--dest-dir",
+			filepath.Join(destDir, "must-gather")}
+		if gatherImage != "" {
+			args = append(args, "--image", gatherImage)
+		}
+		if _, err := c.hostCommandsExecutor.ExecuteWithContext(ctx, "timeout", args...); err != nil {
+			return fmt.Errorf("failed to collect must-gather: %w", err)
+		}
+		if _, err := c.hostCommandsExecutor.ExecuteWithContext(ctx, "tar", "czf",
+			filepath.Join(destDir, "must-gather.tar.gz"), "-C", destDir, "must-gather", "--remove-files"); err != nil {
+			return fmt.Errorf("failed to archive must-gather: %w", err)
+		}
+	}
+	return nil
+}
+
+// watchSize checks the size of dir until ctx is done, canceling it once the size limit is exceeded. The returned
+// function waits for the check to stop, and returns the exceeding size, or 0.
+func (c *Collector) watchSize(ctx context.Context, cancel context.CancelFunc, dir string) func() int64 {
+	var exceeded int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(sizeCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				size, err := dirSize(common.PathOutsideChroot(dir))
+				if err == nil && size > c.maxSize {
+					c.log.Info("Stopping the diagnostics collection, size limit exceeded", "dir", dir, "size", size)
+					exceeded = size
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return func() int64 {
+		<-done
+		return exceeded
+	}
+}
+
+// prune removes the oldest collections next to destDir, keeping room for destDir within the retention limit
+func (c *Collector) prune(destDir string) {
+	parent := filepath.Dir(destDir)
+	entries, err := os.ReadDir(common.PathOutsideChroot(parent))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.log.Error(err, "Failed to list the diagnostics collections", "dir", parent)
+		}
+		return
+	}
+
+	type collection struct {
+		path    string
+		modTime time.Time
+	}
+	var collections []collection
+	for _, entry := range entries {
+		path := filepath.Join(parent, entry.Name())
+		if !entry.IsDir() || path == filepath.Clean(destDir) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		collections = append(collections, collection{path: path, modTime: info.ModTime()})
+	}
+	sort.Slice(collections, func(i, j int) bool { return collections[i].modTime.After(collections[j].modTime) })
+
+	for i := c.retention - 1; i >= 0 && i < len(collections); i++ {
+		c.log.Info("Removing old diagnostics beyond the retention limit", "dir", collections[i].path)
+		c.remove(collections[i].path)
+	}
+}
+
+func (c *Collector) remove(dir string) {
+	if err := os.RemoveAll(common.PathOutsideChroot(dir)); err != nil {
+		c.log.Error(err, "Failed to remove diagnostics", "dir", dir)
+	}
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to walk %s: %w", dir, err)
+	}
+	return size, nil
+}
@@ -0,0 +1,140 @@
+package diagnostics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+func TestCollect(t *testing.T) {
+	testCases := []struct {
+		name          string
+		mode          Mode
+		gatherImage   string
+		reportSize    int
+		maxSize       int64
+		expectedCalls func(mockExec *ops.MockExecute, destDir string)
+		expectedError bool
+		expectRemoved bool
+	}{
+		{
+			name:          "invalid mode",
+			mode:          "invalid",
+			expectedCalls: func(mockExec *ops.MockExecute, destDir string) {},
+			expectedError: true,
+		},
+		{
+			name:       "sosreport within size limit",
+			mode:       ModeSosreport,
+			reportSize: 10,
+			maxSize:    100,
+			expectedCalls: func(mockExec *ops.MockExecute, destDir string) {
+				mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "timeout", "600", "sos", "report", "--batch", "--quiet",
+					"--tmp-dir", destDir).Return("", nil)
+			},
+		},
+		{
+			name:       "sosreport exceeding size limit is removed",
+			mode:       ModeSosreport,
+			reportSize: 200,
+			maxSize:    100,
+			expectedCalls: func(mockExec *ops.MockExecute, destDir string) {
+				mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "timeout", "600", "sos", "report", "--batch", "--quiet",
+					"--tmp-dir", destDir).Return("", nil)
+			},
+			expectedError: true,
+			expectRemoved: true,
+		},
+		{
+			name:    "sosreport exceeding size limit while collecting is stopped and removed",
+			mode:    ModeSosreport,
+			maxSize: 100,
+			expectedCalls: func(mockExec *ops.MockExecute, destDir string) {
+				mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "timeout", "600", "sos", "report", "--batch", "--quiet",
+					"--tmp-dir", destDir).DoAndReturn(func(ctx context.Context, _ string, _ ...string) (string, error) {
+					if err := os.WriteFile(filepath.Join(destDir, "report.tar.xz"), make([]byte, 200), 0o600); err != nil {
+						return "", err
+					}
+					<-ctx.Done()
+					return "", ctx.Err()
+				})
+			},
+			expectedError: true,
+			expectRemoved: true,
+		},
+		{
+			name:        "must-gather with custom image",
+			mode:        ModeMustGather,
+			gatherImage: "quay.io/lca/must-gather:latest",
+			maxSize:     100,
+			expectedCalls: func(mockExec *ops.MockExecute, destDir string) {
+				mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "timeout", "600", "oc", "--kubeconfig", gomock.Any(), "adm", "must-gather",
+					"--dest-dir", filepath.Join(destDir, "must-gather"), "--image", "quay.io/lca/must-gather:latest").Return("", nil)
+				mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "tar", "czf", filepath.Join(destDir, "must-gather.tar.gz"),
+					"-C", destDir, "must-gather", "--remove-files").Return("", nil)
+			},
+		},
+	}
+
+	origInterval := sizeCheckInterval
+	defer func() { sizeCheckInterval = origInterval }()
+	sizeCheckInterval = 10 * time.Millisecond
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockExec := ops.NewMockExecute(ctrl)
+			destDir := filepath.Join(t.TempDir(), "diagnostics")
+			tc.expectedCalls(mockExec, destDir)
+
+			if tc.reportSize > 0 {
+				assert.NoError(t, os.MkdirAll(destDir, 0o700))
+				assert.NoError(t, os.WriteFile(filepath.Join(destDir, "report.tar.xz"), make([]byte, tc.reportSize), 0o600))
+			}
+
+			collector := NewCollector(logr.Discard(), mockExec)
+			collector.maxSize = tc.maxSize
+			err := collector.Collect(context.Background(), tc.mode, destDir, tc.gatherImage)
+			if tc.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tc.expectRemoved {
+				_, err := os.Stat(destDir)
+				assert.True(t, os.IsNotExist(err))
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	parent := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"prep-1", "prep-2", "upgrade-3", "rollback-4"} {
+		dir := filepath.Join(parent, name)
+		assert.NoError(t, os.Mkdir(dir, 0o700))
+		modTime := now.Add(time.Duration(i) * time.Minute)
+		assert.NoError(t, os.Chtimes(dir, modTime, modTime))
+	}
+
+	collector := NewCollector(logr.Discard(), nil)
+	collector.prune(filepath.Join(parent, "prep-5"))
+
+	entries, err := os.ReadDir(parent)
+	assert.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"rollback-4", "upgrade-3"}, names)
+}
@@ -0,0 +1,103 @@
+// Package diskcheck verifies that the host has the space the Prep stage needs for the seed image, the new stateroot
+// and the precached images before the seed image pull, as a full disk otherwise only fails Prep halfway through, with
+// a partially deployed stateroot or precache to clean up.
+package diskcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+)
+
+const (
+	// ContainerStoragePath holds the seed image and the precached images
+	ContainerStoragePath = "/var/lib/containers"
+	// StaterootPath holds the ostree repository and the deployments of the new stateroot
+	StaterootPath = "/sysroot"
+
+	// headroomPercent is added to the estimates, for the pull downloads and the metadata not accounted for
+	headroomPercent = 10
+)
+
+var (
+	availableSpace = common.AvailableSpace
+	deviceOf       = func(path string) (uint64, error) {
+		var stat unix.Stat_t
+		if err := unix.Stat(path, &stat); err != nil {
+			return 0, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		return stat.Dev, nil
+	}
+)
+
+// Requirement is the space needed under a path of the host
+type Requirement struct {
+	Path string
+	// Bytes is the estimated space needed, without headroom
+	Bytes int64
+	// Purpose describes what the space is needed for, in the error reporting a shortage
+	Purpose string
+}
+
+// PrepRequirements returns the space needed by the Prep stage for the seed image pull, the stateroot extracted from
+// the seed image and the images it lists for precaching. A seed image already in the container storage needs no pull.
+func PrepRequirements(pullSize, staterootSize, precacheSize int64) []Requirement {
+	return []Requirement{
+		{Path: ContainerStoragePath, Bytes: pullSize, Purpose: "seed image"},
+		{Path: StaterootPath, Bytes: staterootSize, Purpose: "stateroot"},
+		{Path: ContainerStoragePath, Bytes: precacheSize, Purpose: "precached images"},
+	}
+}
+
+// Check verifies the host has the space of the requirements, summing those of the paths on the same filesystem, as
+// /var and /sysroot usually are
+func Check(log logr.Logger, requirements []Requirement) error {
+	type filesystem struct {
+		path     string
+		required int64
+		purposes []string
+	}
+	filesystems := map[uint64]*filesystem{}
+	for _, requirement := range requirements {
+		if requirement.Bytes <= 0 {
+			continue
+		}
+		hostPath := common.PathOutsideChroot(requirement.Path)
+		device, err := deviceOf(hostPath)
+		if err != nil {
+			return err
+		}
+		fs, ok := filesystems[device]
+		if !ok {
+			fs = &filesystem{path: requirement.Path}
+			filesystems[device] = fs
+		}
+		fs.required += requirement.Bytes + requirement.Bytes*headroomPercent/100
+		fs.purposes = append(fs.purposes, requirement.Purpose)
+	}
+
+	var shortages []string
+	for _, fs := range filesystems {
+		available, err := availableSpace(common.PathOutsideChroot(fs.path))
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		log.Info("Checking disk space", "path", fs.path, "required", fs.required, "available", available,
+			"for", strings.Join(fs.purposes, ", "))
+		if available < fs.required {
+			shortages = append(shortages, fmt.Sprintf("%s requires %d bytes available for the %s, found %d",
+				fs.path, fs.required, strings.Join(fs.purposes, ", "), available))
+		}
+	}
+	if len(shortages) != 0 {
+		sort.Strings(shortages)
+		return lcaerrors.Wrap(lcaerrors.ErrInsufficientSpace, fmt.Errorf("%s", strings.Join(shortages, "; ")))
+	}
+	return nil
+}
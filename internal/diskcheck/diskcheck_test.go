@@ -0,0 +1,84 @@
+package diskcheck
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+)
+
+func TestCheck(t *testing.T) {
+	testcases := []struct {
+		name        string
+		devices     map[string]uint64
+		available   map[string]int64
+		expectedErr error
+		expectedMsg string
+	}{
+		{
+			name:      "shared filesystem with space",
+			devices:   map[string]uint64{ContainerStoragePath: 1, StaterootPath: 1},
+			available: map[string]int64{ContainerStoragePath: 5500},
+		},
+		{
+			name:        "shared filesystem without space",
+			devices:     map[string]uint64{ContainerStoragePath: 1, StaterootPath: 1},
+			available:   map[string]int64{ContainerStoragePath: 5000},
+			expectedErr: lcaerrors.ErrInsufficientSpace,
+			expectedMsg: "/var/lib/containers requires 5500 bytes available for the seed image, stateroot, precached images, found 5000",
+		},
+		{
+			name:      "separate filesystems with space",
+			devices:   map[string]uint64{ContainerStoragePath: 1, StaterootPath: 2},
+			available: map[string]int64{ContainerStoragePath: 4400, StaterootPath: 1100},
+		},
+		{
+			name:        "separate filesystems, stateroot without space",
+			devices:     map[string]uint64{ContainerStoragePath: 1, StaterootPath: 2},
+			available:   map[string]int64{ContainerStoragePath: 4400, StaterootPath: 1000},
+			expectedErr: lcaerrors.ErrInsufficientSpace,
+			expectedMsg: "/sysroot requires 1100 bytes available for the stateroot, found 1000",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			origAvailableSpace, origDeviceOf := availableSpace, deviceOf
+			defer func() { availableSpace, deviceOf = origAvailableSpace, origDeviceOf }()
+			deviceOf = func(path string) (uint64, error) {
+				for p, device := range tc.devices {
+					if strings.HasSuffix(path, p) {
+						return device, nil
+					}
+				}
+				return 0, errors.New("unexpected path " + path)
+			}
+			availableSpace = func(path string) (int64, error) {
+				for p, available := range tc.available {
+					if strings.HasSuffix(path, p) {
+						return available, nil
+					}
+				}
+				return 0, errors.New("unexpected path " + path)
+			}
+
+			err := Check(logr.Discard(), PrepRequirements(1000, 1000, 3000))
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.ErrorContains(t, err, tc.expectedMsg)
+		})
+	}
+
+	// Unknown sizes are not checked
+	origDeviceOf := deviceOf
+	defer func() { deviceOf = origDeviceOf }()
+	deviceOf = func(path string) (uint64, error) { return 0, errors.New("unexpected stat of " + path) }
+	assert.NoError(t, Check(logr.Discard(), PrepRequirements(0, 0, 0)))
+}
@@ -0,0 +1,184 @@
+// Package etcdbackup takes an etcd snapshot right before the Upgrade pivot and stores it in the container storage,
+// which is shared between the stateroots, so it remains available to a rollback or a disaster recovery regardless
+// of the OADP configuration.
+package etcdbackup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const (
+	// BackupDir is the host directory holding the etcd backup, in the container storage shared between stateroots
+	BackupDir = "/var/lib/containers/lca/etcd-backup"
+	// MetadataFile describes the etcd backup stored in BackupDir
+	MetadataFile = "backup.json"
+
+	// DefaultTimeout bounds the time spent taking the etcd backup
+	DefaultTimeout = 5 * time.Minute
+
+	// clusterBackupScript is the OCP etcd backup script, saving an etcd snapshot and the static pod resources
+	clusterBackupScript = "/usr/local/bin/cluster-backup.sh"
+	snapshotPattern     = "snapshot_*.db"
+	// timeoutExitCode is the exit code of the timeout command when the time limit is reached
+	timeoutExitCode = 124
+	// etcd appends the sha256 of the snapshot to its end, the snapshot itself being a multiple of 512 bytes
+	snapshotAlignment = 512
+)
+
+// Metadata describes an etcd backup
+type Metadata struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Snapshot  string    `json:"snapshot"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+}
+
+var etcdDBFile = "/var/lib/etcd/member/snap/db"
+
+var availableSpace = common.AvailableSpace
+
+// Backuper takes etcd backups on the host
+type Backuper struct {
+	log                  logr.Logger
+	hostCommandsExecutor ops.Execute
+	dir                  string
+	timeout              time.Duration
+}
+
+// NewBackuper creates an etcd Backuper storing the backup in BackupDir, with the default time limit
+func NewBackuper(log logr.Logger, hostCommandsExecutor ops.Execute) *Backuper {
+	return &Backuper{
+		log:                  log,
+		hostCommandsExecutor: hostCommandsExecutor,
+		dir:                  BackupDir,
+		timeout:              DefaultTimeout,
+	}
+}
+
+// Backup takes an etcd backup, replacing the previous one only once the new snapshot is verified.
+// It fails with an insufficient space error if the filesystem cannot hold twice the etcd database size.
+func (b *Backuper) Backup() (*Metadata, error) {
+	dbInfo, err := os.Stat(common.PathOutsideChroot(etcdDBFile))
+	if err != nil {
+		return nil, lcaerrors.Wrap(lcaerrors.ErrBackup, fmt.Errorf("failed to stat etcd database: %w", err))
+	}
+
+	tmpDir := b.dir + ".tmp"
+	if err := os.RemoveAll(common.PathOutsideChroot(tmpDir)); err != nil {
+		return nil, fmt.Errorf("failed to remove previous etcd backup attempt %s: %w", tmpDir, err)
+	}
+	if err := os.MkdirAll(common.PathOutsideChroot(tmpDir), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create etcd backup dir %s: %w", tmpDir, err)
+	}
+	defer os.RemoveAll(common.PathOutsideChroot(tmpDir))
+
+	// The snapshot is about the size of the database, keep room for the static pod resources and some headroom
+	available, err := availableSpace(common.PathOutsideChroot(tmpDir))
+	if err != nil {
+		return nil, err
+	}
+	if required := 2 * dbInfo.Size(); available < required {
+		return nil, lcaerrors.Wrap(lcaerrors.ErrInsufficientSpace,
+			fmt.Errorf("etcd backup requires %d bytes available in %s, found %d", required, filepath.Dir(b.dir), available))
+	}
+
+	b.log.Info("Taking etcd backup", "dir", b.dir, "timeout", b.timeout)
+	if _, err := b.hostCommandsExecutor.Execute("timeout", strconv.Itoa(int(b.timeout.Seconds())), clusterBackupScript, tmpDir); err != nil {
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == timeoutExitCode {
+			return nil, lcaerrors.Wrap(lcaerrors.ErrBackup, fmt.Errorf("etcd backup timed out after %s", b.timeout))
+		}
+		return nil, lcaerrors.WrapHostError(lcaerrors.ErrBackup, fmt.Errorf("failed to take etcd backup: %w", err))
+	}
+
+	snapshots, err := filepath.Glob(filepath.Join(common.PathOutsideChroot(tmpDir), snapshotPattern))
+	if err != nil || len(snapshots) != 1 {
+		return nil, lcaerrors.Wrap(lcaerrors.ErrBackup, fmt.Errorf("expected one etcd snapshot in %s, found %d", tmpDir, len(snapshots)))
+	}
+	metadata, err := verifySnapshot(snapshots[0])
+	if err != nil {
+		return nil, lcaerrors.Wrap(lcaerrors.ErrBackup, err)
+	}
+	metadata.CreatedAt = time.Now().UTC()
+	if err := utils.MarshalToFile(metadata, filepath.Join(common.PathOutsideChroot(tmpDir), MetadataFile)); err != nil {
+		return nil, fmt.Errorf("failed to write etcd backup metadata: %w", err)
+	}
+
+	if err := os.RemoveAll(common.PathOutsideChroot(b.dir)); err != nil {
+		return nil, fmt.Errorf("failed to remove previous etcd backup %s: %w", b.dir, err)
+	}
+	if err := os.Rename(common.PathOutsideChroot(tmpDir), common.PathOutsideChroot(b.dir)); err != nil {
+		return nil, fmt.Errorf("failed to move etcd backup to %s: %w", b.dir, err)
+	}
+	b.log.Info("Etcd backup completed", "snapshot", metadata.Snapshot, "size", metadata.Size)
+	return metadata, nil
+}
+
+// Verify checks the etcd backup in the given host directory against its metadata and the snapshot integrity hash
+func Verify(dir string) (*Metadata, error) {
+	expected := &Metadata{}
+	if err := utils.ReadYamlOrJSONFile(filepath.Join(common.PathOutsideChroot(dir), MetadataFile), expected); err != nil {
+		return nil, fmt.Errorf("failed to read etcd backup metadata: %w", err)
+	}
+	actual, err := verifySnapshot(filepath.Join(common.PathOutsideChroot(dir), expected.Snapshot))
+	if err != nil {
+		return nil, err
+	}
+	if actual.Size != expected.Size || actual.SHA256 != expected.SHA256 {
+		return nil, fmt.Errorf("etcd snapshot %s does not match its metadata: size %d, sha256 %s", expected.Snapshot, actual.Size, actual.SHA256)
+	}
+	return expected, nil
+}
+
+// verifySnapshot checks the sha256 appended by etcd to the snapshot, and returns the snapshot metadata
+func verifySnapshot(path string) (*Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open etcd snapshot: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat etcd snapshot: %w", err)
+	}
+	size := info.Size()
+	if size%snapshotAlignment != sha256.Size {
+		return nil, fmt.Errorf("etcd snapshot %s has no integrity hash, size %d", filepath.Base(path), size)
+	}
+
+	content, file := sha256.New(), sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(content, file), f, size-sha256.Size); err != nil {
+		return nil, fmt.Errorf("failed to read etcd snapshot: %w", err)
+	}
+	appended := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(f, appended); err != nil {
+		return nil, fmt.Errorf("failed to read etcd snapshot hash: %w", err)
+	}
+	if !bytes.Equal(content.Sum(nil), appended) {
+		return nil, fmt.Errorf("etcd snapshot %s is corrupted, integrity hash mismatch", filepath.Base(path))
+	}
+	_, _ = file.Write(appended)
+
+	return &Metadata{
+		Snapshot: filepath.Base(path),
+		Size:     size,
+		SHA256:   hex.EncodeToString(file.Sum(nil)),
+	}, nil
+}
@@ -0,0 +1,130 @@
+package etcdbackup
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+// writeSnapshot writes a fake etcd snapshot of 512 bytes followed by its sha256, as saved by etcd
+func writeSnapshot(t *testing.T, path string, corrupt bool) {
+	content := make([]byte, 512)
+	copy(content, "etcd")
+	sum := sha256.Sum256(content)
+	if corrupt {
+		content[0] = 'x'
+	}
+	assert.NoError(t, os.WriteFile(path, append(content, sum[:]...), 0o600))
+}
+
+func TestBackup(t *testing.T) {
+	testcases := []struct {
+		name          string
+		available     int64
+		execute       func(dir string) error
+		expectExecute bool
+		expectedErr   error
+	}{
+		{
+			name:      "backup taken and verified",
+			available: 1 << 30,
+			execute: func(dir string) error {
+				writeSnapshot(t, filepath.Join(dir, "snapshot_2024-01-01_000000.db"), false)
+				return os.WriteFile(filepath.Join(dir, "static_kuberesources_2024-01-01_000000.tar.gz"), []byte("resources"), 0o600)
+			},
+			expectExecute: true,
+		},
+		{
+			name:        "insufficient space",
+			available:   100,
+			expectedErr: lcaerrors.ErrInsufficientSpace,
+		},
+		{
+			name:      "corrupted snapshot",
+			available: 1 << 30,
+			execute: func(dir string) error {
+				writeSnapshot(t, filepath.Join(dir, "snapshot_2024-01-01_000000.db"), true)
+				return nil
+			},
+			expectExecute: true,
+			expectedErr:   lcaerrors.ErrBackup,
+		},
+		{
+			name:      "backup timed out",
+			available: 1 << 30,
+			execute: func(dir string) error {
+				return fmt.Errorf(": %w", exec.Command("sh", "-c", "exit 124").Run())
+			},
+			expectExecute: true,
+			expectedErr:   lcaerrors.ErrBackup,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockExec := ops.NewMockExecute(ctrl)
+			tmp := t.TempDir()
+
+			origEtcdDBFile, origAvailableSpace := etcdDBFile, availableSpace
+			defer func() {
+				etcdDBFile, availableSpace = origEtcdDBFile, origAvailableSpace
+			}()
+			etcdDBFile = filepath.Join(tmp, "db")
+			assert.NoError(t, os.WriteFile(etcdDBFile, make([]byte, 1024), 0o600))
+			availableSpace = func(string) (int64, error) { return tc.available, nil }
+
+			b := NewBackuper(logr.Discard(), mockExec)
+			b.dir = filepath.Join(tmp, "etcd-backup")
+			if tc.expectExecute {
+				mockExec.EXPECT().Execute("timeout", "300", clusterBackupScript, b.dir+".tmp").
+					DoAndReturn(func(_ string, args ...string) (string, error) {
+						return "", tc.execute(args[2])
+					})
+			}
+
+			metadata, err := b.Backup()
+			_, statErr := os.Stat(b.dir + ".tmp")
+			assert.True(t, os.IsNotExist(statErr))
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "snapshot_2024-01-01_000000.db", metadata.Snapshot)
+			assert.Equal(t, int64(544), metadata.Size)
+			assert.FileExists(t, filepath.Join(b.dir, "static_kuberesources_2024-01-01_000000.tar.gz"))
+
+			verified, err := Verify(b.dir)
+			assert.NoError(t, err)
+			assert.Equal(t, metadata.SHA256, verified.SHA256)
+
+			// tampering with the snapshot is detected
+			writeSnapshot(t, filepath.Join(b.dir, metadata.Snapshot), true)
+			_, err = Verify(b.dir)
+			assert.ErrorContains(t, err, "integrity hash mismatch")
+		})
+	}
+}
+
+func TestVerifySnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	assert.NoError(t, os.WriteFile(path, make([]byte, 512), 0o600))
+	_, err := verifySnapshot(path)
+	assert.ErrorContains(t, err, "has no integrity hash")
+
+	writeSnapshot(t, path, false)
+	metadata, err := verifySnapshot(path)
+	assert.NoError(t, err)
+	assert.Len(t, metadata.SHA256, 64)
+}
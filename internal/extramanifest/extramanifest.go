@@ -29,10 +29,11 @@ import (
 	"github.com/go-logr/logr"
 	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
 	"github.com/openshift-kni/lifecycle-agent/utils"
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/yaml"
@@ -66,6 +67,11 @@ func (e *EMStatusError) Error() string {
 	return fmt.Sprintf(e.ErrMessage)
 }
 
+// Is classifies the error with the matching lcaerrors sentinel
+func (e *EMStatusError) Is(target error) bool {
+	return target == lcaerrors.ErrExtraManifests && e.Reason == "Failed"
+}
+
 func NewEMFailedError(msg string) *EMStatusError {
 	return &EMStatusError{
 		Reason:     "Failed",
@@ -101,36 +107,67 @@ func (h *EMHandler) ExportExtraManifestToDir(ctx context.Context, extraManifestC
 	}
 
 	for i, cm := range configmaps {
-		for _, value := range cm.Data {
-			decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewBufferString(value), 4096)
-			for {
-				manifest := unstructured.Unstructured{}
-				err := decoder.Decode(&manifest)
-				if err != nil {
-					if errors.Is(err, io.EOF) {
-						// Reach the end of the data, exit the loop
-						break
-					}
-					return fmt.Errorf("failed to decode manifest: %w", err)
-				}
-				// In case it contains the UID and ResourceVersion, remove them
-				manifest.SetUID("")
-				manifest.SetResourceVersion("")
-
-				fileName := strconv.Itoa(i) + "_" + manifest.GetName() + "_" + manifest.GetNamespace() + ".yaml"
-				filePath := filepath.Join(toDir, ExtraManifestPath, fileName)
-				err = utils.MarshalToYamlFile(&manifest, filePath)
-				if err != nil {
-					return fmt.Errorf("failed to marshal manifest %s to yaml: %w", manifest.GetName(), err)
-				}
-				h.Log.Info("Exported manifest to file", "path", filePath)
+		manifests, err := decodeManifests(cm)
+		if err != nil {
+			return err
+		}
+		for _, manifest := range manifests {
+			manifest := manifest
+			fileName := strconv.Itoa(i) + "_" + manifest.GetName() + "_" + manifest.GetNamespace() + ".yaml"
+			filePath := filepath.Join(toDir, ExtraManifestPath, fileName)
+			err = utils.MarshalToYamlFile(&manifest, filePath)
+			if err != nil {
+				return fmt.Errorf("failed to marshal manifest %s to yaml: %w", manifest.GetName(), err)
 			}
+			h.Log.Info("Exported manifest to file", "path", filePath)
 		}
 	}
 
 	return nil
 }
 
+// GetExtraManifests returns the manifests of the extra manifests configmaps
+func GetExtraManifests(ctx context.Context, c client.Client, extraManifestCMs []lcav1alpha1.ConfigMapRef) ([]unstructured.Unstructured, error) {
+	configmaps, err := common.GetConfigMaps(ctx, c, extraManifestCMs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extraManifest configMaps: %w", err)
+	}
+
+	var result []unstructured.Unstructured
+	for _, cm := range configmaps {
+		manifests, err := decodeManifests(cm)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, manifests...)
+	}
+	return result, nil
+}
+
+// decodeManifests decodes the manifests of a configmap, without their UID and ResourceVersion
+func decodeManifests(cm corev1.ConfigMap) ([]unstructured.Unstructured, error) {
+	var manifests []unstructured.Unstructured
+	for _, value := range cm.Data {
+		decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewBufferString(value), 4096)
+		for {
+			manifest := unstructured.Unstructured{}
+			err := decoder.Decode(&manifest)
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					// Reach the end of the data, exit the loop
+					break
+				}
+				return nil, fmt.Errorf("failed to decode manifest: %w", err)
+			}
+			// In case it contains the UID and ResourceVersion, remove them
+			manifest.SetUID("")
+			manifest.SetResourceVersion("")
+			manifests = append(manifests, manifest)
+		}
+	}
+	return manifests, nil
+}
+
 // ExtractAndExportManifestFromPoliciesToDir extracts CR specs from policies. It matches policies and/or CRs by labels.
 func (h *EMHandler) ExtractAndExportManifestFromPoliciesToDir(ctx context.Context, policyLabels, objectLabels map[string]string, toDir string) error {
 	crd := &apiextensionsv1.CustomResourceDefinition{}
@@ -208,40 +245,9 @@ func (h *EMHandler) ApplyExtraManifests(ctx context.Context, fromDir string) err
 		}
 
 		h.Log.Info("Applying manifest from file", "path", manifestYamlPath)
-		// Check if the resource exists
-		existingManifest := &unstructured.Unstructured{}
-		existingManifest.SetGroupVersionKind(manifest.GroupVersionKind())
 		resource := c.Resource(mapping.Resource).Namespace(manifest.GetNamespace())
-		existingManifest, err = resource.Get(ctx, manifest.GetName(), metav1.GetOptions{})
-		if err != nil {
-			if !k8serrors.IsNotFound(err) {
-				return fmt.Errorf("failed to get extramanifest called %s: %w", manifest.GetName(), err)
-			}
-			// Create if it doesn't exist
-			if _, err := resource.Create(ctx, manifest, metav1.CreateOptions{}); err != nil {
-				// Capture both invalid syntax and webhook validation errors
-				if k8serrors.IsInvalid(err) || k8serrors.IsBadRequest(err) {
-					errMsg := fmt.Sprintf("Failed to create manifest %s %s: %s",
-						manifest.GetKind(), manifest.GetName(), err.Error())
-					h.Log.Error(nil, errMsg)
-					return NewEMFailedError(errMsg)
-				}
-				return fmt.Errorf("failed to create extramanifest called %s: %w", manifest.GetName(), err)
-			}
-			h.Log.Info("Created manifest", "manifest", manifest.GetName())
-		} else {
-			manifest.SetResourceVersion(existingManifest.GetResourceVersion())
-			if _, err := resource.Update(ctx, manifest, metav1.UpdateOptions{}); err != nil {
-				// Capture both invalid syntax and webhook validation errors
-				if k8serrors.IsInvalid(err) || k8serrors.IsBadRequest(err) {
-					errMsg := fmt.Sprintf("Failed to update manifest %s %s: %s",
-						manifest.GetKind(), manifest.GetName(), err.Error())
-					h.Log.Error(nil, errMsg)
-					return NewEMFailedError(errMsg)
-				}
-				return fmt.Errorf("failed to update manifest %s: %w", manifest.GetName(), err)
-			}
-			h.Log.Info("Updated manifest", "manifest", manifest.GetName())
+		if err := h.applyManifest(ctx, resource, manifest); err != nil {
+			return err
 		}
 	}
 
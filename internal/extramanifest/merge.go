@@ -0,0 +1,151 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extramanifest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	// MergeStrategyAnnotation selects, per extra manifest, how it is applied to a resource that already exists after
+	// the pivot, as configured by the seed
+	MergeStrategyAnnotation = "lca.openshift.io/merge-strategy"
+	// MergeStrategyTargetWins, the default, replaces the resource with the extra manifest
+	MergeStrategyTargetWins = "target-wins"
+	// MergeStrategySeedWins keeps the resource as configured by the seed, the extra manifest only creating it when missing
+	MergeStrategySeedWins = "seed-wins"
+	// MergeStrategyStrategicMerge merges the extra manifest into the resource, with a strategic merge patch for the
+	// built-in kinds and a JSON merge patch for the custom resources
+	MergeStrategyStrategicMerge = "strategic-merge"
+)
+
+var mergeStrategies = []string{MergeStrategyTargetWins, MergeStrategySeedWins, MergeStrategyStrategicMerge}
+
+// mergeStrategy returns the merge strategy of the manifest, target-wins when not set
+func mergeStrategy(manifest *unstructured.Unstructured) (string, error) {
+	strategy, found := manifest.GetAnnotations()[MergeStrategyAnnotation]
+	if !found {
+		return MergeStrategyTargetWins, nil
+	}
+	for _, s := range mergeStrategies {
+		if strategy == s {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("invalid %s annotation %q of %s %s, valid values are: %s", MergeStrategyAnnotation,
+		strategy, manifest.GetKind(), manifest.GetName(), strings.Join(mergeStrategies, ", "))
+}
+
+// ValidateMergeStrategies checks the merge strategy annotation of the extra manifests
+func ValidateMergeStrategies(manifests []unstructured.Unstructured) error {
+	var invalid []string
+	for i := range manifests {
+		if _, err := mergeStrategy(&manifests[i]); err != nil {
+			invalid = append(invalid, err.Error())
+		}
+	}
+	if len(invalid) != 0 {
+		return NewEMFailedError(strings.Join(invalid, "; "))
+	}
+	return nil
+}
+
+// applyManifest creates the resource of the manifest, or applies the manifest to the existing resource according to
+// its merge strategy. The merge strategy annotation is not set on the resource.
+func (h *EMHandler) applyManifest(ctx context.Context, resource dynamic.ResourceInterface, manifest *unstructured.Unstructured) error {
+	strategy, err := mergeStrategy(manifest)
+	if err != nil {
+		return NewEMFailedError(err.Error())
+	}
+	if annotations := manifest.GetAnnotations(); annotations != nil {
+		delete(annotations, MergeStrategyAnnotation)
+		manifest.SetAnnotations(annotations)
+	}
+
+	existingManifest, err := resource.Get(ctx, manifest.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get extramanifest called %s: %w", manifest.GetName(), err)
+		}
+		// Create if it doesn't exist
+		if _, err := resource.Create(ctx, manifest, metav1.CreateOptions{}); err != nil {
+			// Capture both invalid syntax and webhook validation errors
+			if k8serrors.IsInvalid(err) || k8serrors.IsBadRequest(err) {
+				errMsg := fmt.Sprintf("Failed to create manifest %s %s: %s",
+					manifest.GetKind(), manifest.GetName(), err.Error())
+				h.Log.Error(nil, errMsg)
+				return NewEMFailedError(errMsg)
+			}
+			return fmt.Errorf("failed to create extramanifest called %s: %w", manifest.GetName(), err)
+		}
+		h.Log.Info("Created manifest", "manifest", manifest.GetName())
+		return nil
+	}
+
+	switch strategy {
+	case MergeStrategySeedWins:
+		h.Log.Info("Kept the existing resource configured by the seed", "manifest", manifest.GetName(),
+			"mergeStrategy", strategy)
+		return nil
+	case MergeStrategyStrategicMerge:
+		patchType := types.MergePatchType
+		if scheme.Scheme.Recognizes(manifest.GroupVersionKind()) {
+			patchType = types.StrategicMergePatchType
+		}
+		patch, err := json.Marshal(manifest.Object)
+		if err != nil {
+			return fmt.Errorf("failed to marshal manifest %s: %w", manifest.GetName(), err)
+		}
+		_, err = resource.Patch(ctx, manifest.GetName(), patchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			// Capture both invalid syntax and webhook validation errors
+			if k8serrors.IsInvalid(err) || k8serrors.IsBadRequest(err) {
+				errMsg := fmt.Sprintf("Failed to merge manifest %s %s: %s",
+					manifest.GetKind(), manifest.GetName(), err.Error())
+				h.Log.Error(nil, errMsg)
+				return NewEMFailedError(errMsg)
+			}
+			return fmt.Errorf("failed to merge manifest %s: %w", manifest.GetName(), err)
+		}
+		h.Log.Info("Merged manifest", "manifest", manifest.GetName(), "patchType", patchType)
+		return nil
+	}
+
+	manifest.SetResourceVersion(existingManifest.GetResourceVersion())
+	if _, err := resource.Update(ctx, manifest, metav1.UpdateOptions{}); err != nil {
+		// Capture both invalid syntax and webhook validation errors
+		if k8serrors.IsInvalid(err) || k8serrors.IsBadRequest(err) {
+			errMsg := fmt.Sprintf("Failed to update manifest %s %s: %s",
+				manifest.GetKind(), manifest.GetName(), err.Error())
+			h.Log.Error(nil, errMsg)
+			return NewEMFailedError(errMsg)
+		}
+		return fmt.Errorf("failed to update manifest %s: %w", manifest.GetName(), err)
+	}
+	h.Log.Info("Updated manifest", "manifest", manifest.GetName())
+	return nil
+}
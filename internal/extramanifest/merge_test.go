@@ -0,0 +1,105 @@
+package extramanifest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+var sriovNetworkGVR = schema.GroupVersionResource{Group: "sriovnetwork.openshift.io", Version: "v1", Resource: "sriovnetworks"}
+
+func newSriovNetwork(name, strategy string, spec map[string]any) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "sriovnetwork.openshift.io/v1",
+		"kind":       "SriovNetwork",
+		"metadata":   map[string]any{"name": name, "namespace": "openshift-sriov-network-operator"},
+		"spec":       spec,
+	}}
+	if strategy != "" {
+		u.SetAnnotations(map[string]string{MergeStrategyAnnotation: strategy})
+	}
+	return u
+}
+
+func TestValidateMergeStrategies(t *testing.T) {
+	assert.NoError(t, ValidateMergeStrategies([]unstructured.Unstructured{
+		*newSriovNetwork("default", "", nil),
+		*newSriovNetwork("seed", MergeStrategySeedWins, nil),
+		*newSriovNetwork("merge", MergeStrategyStrategicMerge, nil),
+	}))
+
+	err := ValidateMergeStrategies([]unstructured.Unstructured{*newSriovNetwork("sriov-nw-mh", "last-wins", nil)})
+	assert.True(t, IsEMFailedError(err))
+	assert.EqualError(t, err, `invalid lca.openshift.io/merge-strategy annotation "last-wins" of SriovNetwork sriov-nw-mh, `+
+		`valid values are: target-wins, seed-wins, strategic-merge`)
+}
+
+func TestApplyManifestMergeStrategies(t *testing.T) {
+	seedSpec := map[string]any{"resourceName": "mh", "vlan": int64(100)}
+	targetSpec := map[string]any{"resourceName": "mh", "spoofChk": "off"}
+
+	testcases := []struct {
+		name         string
+		strategy     string
+		expectedSpec map[string]any
+	}{
+		{
+			name:         "target wins by default",
+			expectedSpec: targetSpec,
+		},
+		{
+			name:         "target wins",
+			strategy:     MergeStrategyTargetWins,
+			expectedSpec: targetSpec,
+		},
+		{
+			name:         "seed wins",
+			strategy:     MergeStrategySeedWins,
+			expectedSpec: seedSpec,
+		},
+		{
+			name:         "strategic merge",
+			strategy:     MergeStrategyStrategicMerge,
+			expectedSpec: map[string]any{"resourceName": "mh", "vlan": int64(100), "spoofChk": "off"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{sriovNetworkGVR: "SriovNetworkList"},
+				newSriovNetwork("sriov-nw-mh", "", seedSpec))
+			resource := c.Resource(sriovNetworkGVR).Namespace("openshift-sriov-network-operator")
+			h := &EMHandler{Log: logr.Discard()}
+
+			assert.NoError(t, h.applyManifest(context.Background(), resource, newSriovNetwork("sriov-nw-mh", tc.strategy, targetSpec)))
+			actual, err := resource.Get(context.Background(), "sriov-nw-mh", metav1.GetOptions{})
+			assert.NoError(t, err)
+			spec, _, _ := unstructured.NestedMap(actual.Object, "spec")
+			assert.Equal(t, tc.expectedSpec, spec)
+			assert.NotContains(t, actual.GetAnnotations(), MergeStrategyAnnotation)
+
+			// The missing resources are created whatever the merge strategy
+			assert.NoError(t, h.applyManifest(context.Background(), resource, newSriovNetwork("sriov-nw-fh", tc.strategy, targetSpec)))
+			actual, err = resource.Get(context.Background(), "sriov-nw-fh", metav1.GetOptions{})
+			assert.NoError(t, err)
+			spec, _, _ = unstructured.NestedMap(actual.Object, "spec")
+			assert.Equal(t, targetSpec, spec)
+			assert.NotContains(t, actual.GetAnnotations(), MergeStrategyAnnotation)
+		})
+	}
+
+	h := &EMHandler{Log: logr.Discard()}
+	c := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{sriovNetworkGVR: "SriovNetworkList"})
+	err := h.applyManifest(context.Background(), c.Resource(sriovNetworkGVR).Namespace("openshift-sriov-network-operator"),
+		newSriovNetwork("sriov-nw-mh", "merge", targetSpec))
+	assert.True(t, IsEMFailedError(err))
+}
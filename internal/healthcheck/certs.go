@@ -0,0 +1,108 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+// +kubebuilder:rbac:groups=certificates.k8s.io,resources=certificatesigningrequests,verbs=list;watch
+
+const (
+	KubeletClientCertFile  = "/var/lib/kubelet/pki/kubelet-client-current.pem"
+	KubeletServingCertFile = "/var/lib/kubelet/pki/kubelet-server-current.pem"
+
+	// CertExpiryThreshold is the minimum remaining validity of the kubelet certificates to allow the pivot
+	CertExpiryThreshold = 24 * time.Hour
+)
+
+// CertificatesReady verifies there are no pending CSRs and that the kubelet client and serving certificates are
+// not about to expire, since recert and the post-pivot CSR handling do not cope with a certificate rotation in progress.
+// The returned error includes a remediation hint.
+func CertificatesReady(ctx context.Context, c client.Reader, l logr.Logger) error {
+	var errs error
+
+	if err := noPendingCSRs(ctx, c, l); err != nil {
+		errs = errors.Join(errs, err)
+	}
+
+	for _, certFile := range []string{KubeletClientCertFile, KubeletServingCertFile} {
+		if err := certNotExpiring(common.PathOutsideChroot(certFile), CertExpiryThreshold); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%w. Wait for the kubelet to rotate its certificates and for the "+
+				"resulting CSRs to be approved before starting the upgrade", err))
+		}
+	}
+
+	if errs != nil {
+		return fmt.Errorf("certificates are not ready for upgrade: %w", errs)
+	}
+
+	l.Info("Certificates are ready")
+	return nil
+}
+
+func noPendingCSRs(ctx context.Context, c client.Reader, l logr.Logger) error {
+	csrList := &certificatesv1.CertificateSigningRequestList{}
+	if err := c.List(ctx, csrList); err != nil {
+		return fmt.Errorf("failed to list CSRs: %w", err)
+	}
+
+	var pending []string
+	for _, csr := range csrList.Items {
+		if isCSRPending(&csr) {
+			pending = append(pending, csr.Name)
+		}
+	}
+
+	if len(pending) > 0 {
+		l.Info("Found pending CSRs", "csrs", pending)
+		return fmt.Errorf("found %d pending CSRs (%s). Approve or deny them with 'oc adm certificate approve|deny <name>' "+
+			"before starting the upgrade", len(pending), strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+func isCSRPending(csr *certificatesv1.CertificateSigningRequest) bool {
+	for _, condition := range csr.Status.Conditions {
+		switch condition.Type {
+		case certificatesv1.CertificateApproved, certificatesv1.CertificateDenied, certificatesv1.CertificateFailed:
+			return false
+		}
+	}
+	return true
+}
+
+func certNotExpiring(certFile string, threshold time.Duration) error {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate %s: %w", certFile, err)
+	}
+
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse certificate %s: %w", certFile, err)
+		}
+		if time.Until(cert.NotAfter) < threshold {
+			return fmt.Errorf("certificate %s expires at %s, in less than %s", certFile,
+				cert.NotAfter.UTC().Format(time.RFC3339), threshold)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("no certificate found in %s", certFile)
+}
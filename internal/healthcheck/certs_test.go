@@ -0,0 +1,121 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func writeCert(t *testing.T, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "system:node:test"},
+		NotBefore:    notAfter.Add(-30 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	certFile := filepath.Join(t.TempDir(), "kubelet-current.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	return certFile
+}
+
+func Test_certNotExpiring(t *testing.T) {
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "valid for a year",
+			notAfter: time.Now().Add(365 * 24 * time.Hour),
+		},
+		{
+			name:     "expires within the threshold",
+			notAfter: time.Now().Add(time.Hour),
+			wantErr:  true,
+		},
+		{
+			name:     "already expired",
+			notAfter: time.Now().Add(-time.Hour),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := certNotExpiring(writeCert(t, tt.notAfter), CertExpiryThreshold)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Error(t, certNotExpiring(filepath.Join(t.TempDir(), "missing.pem"), CertExpiryThreshold))
+}
+
+func Test_noPendingCSRs(t *testing.T) {
+	csr := func(name string, conditions ...certificatesv1.RequestConditionType) *certificatesv1.CertificateSigningRequest {
+		c := &certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		for _, condition := range conditions {
+			c.Status.Conditions = append(c.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{Type: condition})
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		wantErr bool
+	}{
+		{
+			name: "no CSRs",
+		},
+		{
+			name: "approved and denied CSRs",
+			objects: []runtime.Object{
+				csr("csr-approved", certificatesv1.CertificateApproved),
+				csr("csr-denied", certificatesv1.CertificateDenied),
+			},
+		},
+		{
+			name: "pending CSR",
+			objects: []runtime.Object{
+				csr("csr-approved", certificatesv1.CertificateApproved),
+				csr("csr-pending"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(tt.objects...).Build()
+			err := noPendingCSRs(context.Background(), c, logr.Discard())
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "csr-pending")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -1,12 +1,17 @@
 package healthcheck
 
 import (
+	"context"
+	"errors"
+	"strings"
+
 	"github.com/go-logr/logr"
 	configv1 "github.com/openshift/api/config/v1"
 	mcv1 "github.com/openshift/api/machineconfiguration/v1"
 	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -29,6 +34,7 @@ func init() {
 	s.AddKnownTypes(operatorsv1alpha1.SchemeGroupVersion, &operatorsv1alpha1.ClusterServiceVersion{})
 	s.AddKnownTypes(configv1.GroupVersion, &configv1.Infrastructure{})
 	s.AddKnownTypes(v1.SchemeGroupVersion, &v1.Node{})
+	s.AddKnownTypes(configv1.GroupVersion, &configv1.Network{})
 }
 
 func Test_nodesReady(t *testing.T) {
@@ -653,3 +659,163 @@ func TestHealthChecks(t *testing.T) {
 		})
 	}
 }
+
+func Test_klusterletConnected(t *testing.T) {
+	oldPoll := pollTimeout
+	defer func() {
+		pollTimeout = oldPoll
+	}()
+	pollTimeout = 1 * time.Microsecond
+
+	klusterlet := func(conditions ...interface{}) client.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(klusterletGVK)
+		obj.SetName(KlusterletName)
+		_ = unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions")
+		return obj
+	}
+	condition := func(conditionType, status string) interface{} {
+		return map[string]interface{}{"type": conditionType, "status": status}
+	}
+
+	tests := []struct {
+		name    string
+		objects []client.Object
+		wantErr bool
+	}{
+		{
+			name:    "not managed by ACM",
+			wantErr: false,
+		},
+		{
+			name: "connected to the hub",
+			objects: []client.Object{klusterlet(
+				condition(klusterletConditionAvailable, "True"),
+				condition(klusterletConditionHubConnectionDegraded, "False"),
+			)},
+			wantErr: false,
+		},
+		{
+			name: "hub connection degraded",
+			objects: []client.Object{klusterlet(
+				condition(klusterletConditionAvailable, "True"),
+				condition(klusterletConditionHubConnectionDegraded, "True"),
+			)},
+			wantErr: true,
+		},
+		{
+			name:    "no conditions reported yet",
+			objects: []client.Object{klusterlet()},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(tt.objects...).Build()
+			if err := KlusterletConnected(c, logr.Discard()); (err != nil) != tt.wantErr {
+				t.Errorf("KlusterletConnected() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_networkReady(t *testing.T) {
+	oldDialNode, oldLookupHost := dialNode, lookupHost
+	defer func() {
+		dialNode, lookupHost = oldDialNode, oldLookupHost
+	}()
+	dialNode = func(ctx context.Context, address string) error {
+		if address != "192.168.1.10:6443" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		if host != "api-int.sno.example.com" {
+			return nil, errors.New("no such host")
+		}
+		return []string{"192.168.1.10"}, nil
+	}
+
+	network := &configv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status:     configv1.NetworkStatus{NetworkType: ovnKubernetesNetworkType},
+	}
+	infra := func(apiIntURL string) client.Object {
+		return &configv1.Infrastructure{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Status:     configv1.InfrastructureStatus{APIServerInternalURL: apiIntURL},
+		}
+	}
+	node := func(ip string) client.Object {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "sno"},
+			Status:     v1.NodeStatus{Addresses: []v1.NodeAddress{{Type: v1.NodeInternalIP, Address: ip}}},
+		}
+	}
+	ovnPod := func(ready bool) client.Object {
+		status := v1.ConditionFalse
+		if ready {
+			status = v1.ConditionTrue
+		}
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "ovnkube-node-abcde", Namespace: ovnKubernetesNamespace,
+				Labels: map[string]string{ovnKubeNodeLabel: ovnKubeNodeLabelValue}},
+			Status: v1.PodStatus{
+				Phase:             v1.PodRunning,
+				Conditions:        []v1.PodCondition{{Type: v1.PodReady, Status: status}},
+				ContainerStatuses: []v1.ContainerStatus{{Name: "ovnkube-controller", Ready: ready}, {Name: "ovn-controller", Ready: true}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		objects []client.Object
+		wantErr []string
+	}{
+		{
+			name:    "network ready",
+			objects: []client.Object{network, infra("https://api-int.sno.example.com:6443"), node("192.168.1.10"), ovnPod(true)},
+		},
+		{
+			name:    "not OVNKubernetes",
+			objects: []client.Object{&configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}, infra("https://api-int.sno.example.com:6443"), node("192.168.1.10")},
+		},
+		{
+			name:    "every check failing",
+			objects: []client.Object{network, infra("https://api-int.other.example.com:6443"), node("192.168.1.11"), ovnPod(false)},
+			wantErr: []string{
+				"network not ready after",
+				"ovnkube-node pods not ready: ovnkube-node-abcde (phase Running, containers not ready: ovnkube-controller)",
+				"node sno InternalIP 192.168.1.11 unreachable: connection refused",
+				"api-int.other.example.com not resolvable: no such host",
+			},
+		},
+		{
+			name:    "ovnkube-node pods not created yet",
+			objects: []client.Object{network, infra("https://api-int.sno.example.com:6443"), node("192.168.1.10")},
+			wantErr: []string{"no ovnkube-node pod found in openshift-ovn-kubernetes"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(tt.objects...).Build()
+			err := NetworkReady(c, logr.Discard(), time.Microsecond)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("NetworkReady() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("NetworkReady() expected error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("NetworkReady() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=operator.open-cluster-management.io,resources=klusterlets,verbs=get
+
+const (
+	// KlusterletName is the Klusterlet CR deployed on the clusters managed by ACM
+	KlusterletName = "klusterlet"
+
+	// Both conditions are reported by the local klusterlet agent: Available once the registration agent runs,
+	// HubConnectionDegraded while it cannot reach the hub or its ManagedCluster is not accepted and joined
+	klusterletConditionAvailable             = "Available"
+	klusterletConditionHubConnectionDegraded = "HubConnectionDegraded"
+)
+
+var klusterletGVK = schema.GroupVersionKind{Group: "operator.open-cluster-management.io", Version: "v1", Kind: "Klusterlet"}
+
+// KlusterletConnected waits for the klusterlet of an ACM managed cluster to be connected to the hub, where its
+// ManagedCluster then reports Available. The check passes right away when the cluster is not managed by ACM.
+func KlusterletConnected(c client.Reader, l logr.Logger) error {
+	l.Info("Waiting for the klusterlet to be connected to the hub")
+	err := wait.PollUntilContextTimeout(context.Background(), pollInterval, pollTimeout, true, isKlusterletConnected(c, l))
+	if err != nil {
+		return fmt.Errorf("failed to wait for the klusterlet to be connected to the hub: %w", err)
+	}
+
+	return nil
+}
+
+func isKlusterletConnected(c client.Reader, l logr.Logger) wait.ConditionWithContextFunc {
+	return func(ctx context.Context) (bool, error) {
+		klusterlet := &unstructured.Unstructured{}
+		klusterlet.SetGroupVersionKind(klusterletGVK)
+		if err := c.Get(ctx, types.NamespacedName{Name: KlusterletName}, klusterlet); err != nil {
+			if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				l.Info("Skipping klusterlet check, the cluster is not managed by ACM")
+				return true, nil
+			}
+			l.Error(err, "failed to get klusterlet")
+			return false, nil
+		}
+
+		conditions, _, err := unstructured.NestedSlice(klusterlet.Object, "status", "conditions")
+		if err != nil {
+			l.Error(err, "failed to get klusterlet conditions")
+			return false, nil
+		}
+		if !getUnstructuredCondition(conditions, klusterletConditionAvailable, "True") {
+			l.Info("Klusterlet not available yet")
+			return false, nil
+		}
+		if !getUnstructuredCondition(conditions, klusterletConditionHubConnectionDegraded, "False") {
+			l.Info("Klusterlet not connected to the hub yet")
+			return false, nil
+		}
+
+		l.Info("Klusterlet is connected to the hub")
+		return true, nil
+	}
+}
+
+// getUnstructuredCondition returns whether the condition of the given type has the given status
+func getUnstructuredCondition(conditions []interface{}, conditionType, status string) bool {
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != conditionType {
+			continue
+		}
+		return condition["status"] == status
+	}
+
+	return false
+}
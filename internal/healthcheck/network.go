@@ -0,0 +1,178 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=config.openshift.io,resources=networks,verbs=get
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+const (
+	ovnKubernetesNetworkType = "OVNKubernetes"
+	ovnKubernetesNamespace   = "openshift-ovn-kubernetes"
+	ovnKubeNodeLabel         = "app"
+	ovnKubeNodeLabelValue    = "ovnkube-node"
+	kubeAPIServerPort        = "6443"
+	networkCheckTimeout      = 5 * time.Second
+)
+
+// dialNode connects to the address of the node, to check it is reachable
+var dialNode = func(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, networkCheckTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	return conn.Close() //nolint:wrapcheck
+}
+
+// lookupHost resolves the host name
+var lookupHost = func(ctx context.Context, host string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, networkCheckTimeout)
+	defer cancel()
+	return net.DefaultResolver.LookupHost(ctx, host) //nolint:wrapcheck
+}
+
+// NetworkReady waits up to timeout for the network of the node to be initialized after the pivot: the ovnkube-node
+// pods ready when the cluster network is OVNKubernetes, the InternalIP of the node reachable and the internal API
+// name resolvable. On timeout, the error reports every check still failing.
+func NetworkReady(c client.Reader, l logr.Logger, timeout time.Duration) error {
+	l.Info("Waiting for the network to be ready")
+	var failures []string
+	err := wait.PollUntilContextTimeout(context.Background(), pollInterval, timeout, true,
+		func(ctx context.Context) (bool, error) {
+			failures = networkFailures(ctx, c)
+			if len(failures) > 0 {
+				l.Info("Network not ready yet", "failures", failures)
+				return false, nil
+			}
+			l.Info("Network is ready")
+			return true, nil
+		})
+	if err != nil {
+		if len(failures) == 0 {
+			return fmt.Errorf("failed to wait for the network to be ready: %w", err)
+		}
+		return fmt.Errorf("network not ready after %s: %s", timeout, strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// networkFailures runs every network check, returning the failed ones
+func networkFailures(ctx context.Context, c client.Reader) []string {
+	var failures []string
+	for _, check := range []func(context.Context, client.Reader) error{ovnKubeNodeReady, nodeIPReachable, apiIntResolvable} {
+		if err := check(ctx, c); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	return failures
+}
+
+// ovnKubeNodeReady checks the ovnkube-node pods are ready, when the cluster network is OVNKubernetes
+func ovnKubeNodeReady(ctx context.Context, c client.Reader) error {
+	network := &configv1.Network{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "cluster"}, network); err != nil {
+		return fmt.Errorf("failed to get the cluster network config: %w", err)
+	}
+	if network.Status.NetworkType != ovnKubernetesNetworkType {
+		return nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(ovnKubernetesNamespace),
+		client.MatchingLabels{ovnKubeNodeLabel: ovnKubeNodeLabelValue}); err != nil {
+		return fmt.Errorf("failed to list the ovnkube-node pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no ovnkube-node pod found in %s", ovnKubernetesNamespace)
+	}
+
+	var notReady []string
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if isPodReady(pod) {
+			continue
+		}
+		var containers []string
+		for _, status := range pod.Status.ContainerStatuses {
+			if !status.Ready {
+				containers = append(containers, status.Name)
+			}
+		}
+		notReady = append(notReady, fmt.Sprintf("%s (phase %s, containers not ready: %s)",
+			pod.Name, pod.Status.Phase, strings.Join(containers, ",")))
+	}
+	if len(notReady) > 0 {
+		return fmt.Errorf("ovnkube-node pods not ready: %s", strings.Join(notReady, ", "))
+	}
+	return nil
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeIPReachable checks the kube-apiserver is reachable on the InternalIP of the nodes
+func nodeIPReachable(ctx context.Context, c client.Reader) error {
+	nodes := &corev1.NodeList{}
+	if err := c.List(ctx, nodes); err != nil {
+		return fmt.Errorf("failed to list the nodes: %w", err)
+	}
+
+	var unreachable []string
+	for _, node := range nodes.Items {
+		found := false
+		for _, address := range node.Status.Addresses {
+			if address.Type != corev1.NodeInternalIP {
+				continue
+			}
+			found = true
+			if err := dialNode(ctx, net.JoinHostPort(address.Address, kubeAPIServerPort)); err != nil {
+				unreachable = append(unreachable, fmt.Sprintf("%s InternalIP %s unreachable: %s", node.Name, address.Address, err))
+			}
+		}
+		if !found {
+			unreachable = append(unreachable, fmt.Sprintf("%s has no InternalIP", node.Name))
+		}
+	}
+	if len(unreachable) > 0 {
+		return fmt.Errorf("node %s", strings.Join(unreachable, ", "))
+	}
+	return nil
+}
+
+// apiIntResolvable checks the host name of the internal API server URL resolves
+func apiIntResolvable(ctx context.Context, c client.Reader) error {
+	infra := &configv1.Infrastructure{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "cluster"}, infra); err != nil {
+		return fmt.Errorf("failed to get the cluster infrastructure: %w", err)
+	}
+	apiURL, err := url.Parse(infra.Status.APIServerInternalURL)
+	if err != nil || apiURL.Hostname() == "" {
+		return fmt.Errorf("invalid internal API server URL %q", infra.Status.APIServerInternalURL)
+	}
+	if _, err := lookupHost(ctx, apiURL.Hostname()); err != nil {
+		return fmt.Errorf("%s not resolvable: %w", apiURL.Hostname(), err)
+	}
+	return nil
+}
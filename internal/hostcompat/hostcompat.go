@@ -0,0 +1,93 @@
+// Package hostcompat checks that the stateroot of a seed image is compatible with the host it is deployed on, from the
+// host configuration recorded in the seed cluster info, so that Prep fails instead of the first boot of the new
+// stateroot after the pivot.
+package hostcompat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/bootcheck"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedclusterinfo"
+)
+
+const (
+	fipsEnabledFile = "/proc/sys/crypto/fips_enabled"
+	// secureBootVar is the EFI variable of the secure boot state, its fifth byte, after the 4 bytes of attributes
+	secureBootVar = "/sys/firmware/efi/efivars/SecureBoot-8be4df61-93ca-11d2-aa0d-00e098032b8c"
+)
+
+// HostInfo is the configuration of a host a stateroot depends on
+type HostInfo struct {
+	FIPS             bool
+	SecureBoot       bool
+	BootDeviceLayout string
+}
+
+// Gather returns the configuration of the host the commands are executed on
+func Gather(hostCommandsExecutor ops.Execute) (*HostInfo, error) {
+	fips, err := FIPSEnabled(hostCommandsExecutor)
+	if err != nil {
+		return nil, err
+	}
+	secureBoot, err := SecureBootEnabled(hostCommandsExecutor)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := bootcheck.BootDeviceLayout(hostCommandsExecutor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the boot device layout: %w", err)
+	}
+	return &HostInfo{FIPS: fips, SecureBoot: secureBoot, BootDeviceLayout: layout}, nil
+}
+
+// FIPSEnabled returns whether the kernel of the host runs in FIPS mode
+func FIPSEnabled(hostCommandsExecutor ops.Execute) (bool, error) {
+	output, err := hostCommandsExecutor.Execute("cat", fipsEnabledFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the FIPS mode: %w", err)
+	}
+	return strings.TrimSpace(output) == "1", nil
+}
+
+// SecureBootEnabled returns whether the host booted with secure boot. A host booted in legacy BIOS mode has no secure
+// boot.
+func SecureBootEnabled(hostCommandsExecutor ops.Execute) (bool, error) {
+	output, err := hostCommandsExecutor.Execute("sh", "-c",
+		fmt.Sprintf("if [ -e %[1]s ]; then od -An -t u1 -j 4 -N 1 %[1]s; else echo 0; fi", secureBootVar))
+	if err != nil {
+		return false, fmt.Errorf("failed to read the secure boot state: %w", err)
+	}
+	return strings.TrimSpace(output) == "1", nil
+}
+
+// Check verifies the seed cluster info is compatible with the host:
+//   - the FIPS mode must be the same, the seed stateroot carrying the kernel arguments and crypto policy of the seed
+//     cluster
+//   - a host with secure boot requires a seed cluster with secure boot, whose kernel and modules are known to be signed
+//   - the boot device layout must be the same, the seed stateroot assembling the RAID arrays and multipath devices of
+//     the seed node to mount /boot
+//
+// The values not recorded by the lca-cli that created the seed image are not checked.
+func Check(seed *seedclusterinfo.SeedClusterInfo, host *HostInfo) error {
+	if seed.FIPS != nil && *seed.FIPS != host.FIPS {
+		return lcaerrors.Wrap(lcaerrors.ErrFIPSMismatch, fmt.Errorf(
+			"seed cluster FIPS mode (%t) does not match the FIPS mode of this host (%t), a seed image created from a "+
+				"cluster with the same FIPS mode is required", *seed.FIPS, host.FIPS))
+	}
+	if seed.SecureBoot != nil && host.SecureBoot && !*seed.SecureBoot {
+		return lcaerrors.Wrap(lcaerrors.ErrSecureBootMismatch, errors.New(
+			"this host boots with secure boot but the seed cluster does not, a seed image created from a cluster "+
+				"with secure boot enabled is required"))
+	}
+	if seed.BootDeviceLayout != "" && seed.BootDeviceLayout != host.BootDeviceLayout {
+		return lcaerrors.Wrap(lcaerrors.ErrBootDeviceLayoutMismatch, fmt.Errorf(
+			"seed cluster boot device layout (%s) does not match the boot device layout of this host (%s), a seed "+
+				"image created from a cluster with the same boot disk configuration is required",
+			seed.BootDeviceLayout, host.BootDeviceLayout))
+	}
+	return nil
+}
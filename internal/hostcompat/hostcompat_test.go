@@ -0,0 +1,83 @@
+package hostcompat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedclusterinfo"
+)
+
+func TestGather(t *testing.T) {
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	mockExec.EXPECT().Execute("cat", fipsEnabledFile).Return("1\n", nil)
+	mockExec.EXPECT().Execute("sh", "-c", gomock.Any()).Return("   1\n", nil)
+	mockExec.EXPECT().Execute("findmnt", "--noheadings", "--output", "SOURCE", "--target", "/boot").Return("/dev/md126", nil)
+	mockExec.EXPECT().Execute("lsblk", "--json", "--inverse", "--output", "KNAME,TYPE", "/dev/md126").
+		Return(`{"blockdevices":[{"kname":"md126","type":"raid1","children":[{"kname":"sda3","type":"part"}]}]}`, nil)
+
+	host, err := Gather(mockExec)
+	assert.NoError(t, err)
+	assert.Equal(t, &HostInfo{FIPS: true, SecureBoot: true, BootDeviceLayout: "raid1"}, host)
+
+	mockExec.EXPECT().Execute("cat", fipsEnabledFile).Return("", errors.New("exit status 1"))
+	_, err = Gather(mockExec)
+	assert.EqualError(t, err, "failed to read the FIPS mode: exit status 1")
+}
+
+func TestCheck(t *testing.T) {
+	host := &HostInfo{FIPS: false, SecureBoot: true, BootDeviceLayout: "single"}
+	testcases := []struct {
+		name        string
+		seed        *seedclusterinfo.SeedClusterInfo
+		expectedErr error
+		expectedMsg string
+	}{
+		{
+			name: "compatible",
+			seed: &seedclusterinfo.SeedClusterInfo{FIPS: lo.ToPtr(false), SecureBoot: lo.ToPtr(true), BootDeviceLayout: "single"},
+		},
+		{
+			name: "not recorded by the seed",
+			seed: &seedclusterinfo.SeedClusterInfo{},
+		},
+		{
+			name:        "FIPS mismatch",
+			seed:        &seedclusterinfo.SeedClusterInfo{FIPS: lo.ToPtr(true)},
+			expectedErr: lcaerrors.ErrFIPSMismatch,
+			expectedMsg: "seed cluster FIPS mode (true) does not match the FIPS mode of this host (false)",
+		},
+		{
+			name:        "seed without secure boot",
+			seed:        &seedclusterinfo.SeedClusterInfo{SecureBoot: lo.ToPtr(false)},
+			expectedErr: lcaerrors.ErrSecureBootMismatch,
+			expectedMsg: "this host boots with secure boot but the seed cluster does not",
+		},
+		{
+			name:        "boot device layout mismatch",
+			seed:        &seedclusterinfo.SeedClusterInfo{BootDeviceLayout: "raid1"},
+			expectedErr: lcaerrors.ErrBootDeviceLayoutMismatch,
+			expectedMsg: "seed cluster boot device layout (raid1) does not match the boot device layout of this host (single)",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Check(tc.seed, host)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.ErrorContains(t, err, tc.expectedMsg)
+		})
+	}
+
+	// A host without secure boot accepts a seed with secure boot
+	assert.NoError(t, Check(&seedclusterinfo.SeedClusterInfo{SecureBoot: lo.ToPtr(true)}, &HostInfo{BootDeviceLayout: "single"}))
+}
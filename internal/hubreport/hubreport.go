@@ -0,0 +1,185 @@
+package hubreport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+const (
+	// SecretName is the secret in the LCA namespace that enables hub reporting
+	SecretName = "ibu-hub-report"
+	// HubKubeconfigKey is the secret key holding the kubeconfig used to access the hub
+	HubKubeconfigKey = "hubKubeconfig"
+	// ClusterNameKey is the secret key holding the managed cluster name, used as the report namespace and name on the hub
+	ClusterNameKey = "clusterName"
+)
+
+// newHubClient creates the API client for hub requests, overridden in tests
+var newHubClient = func(hubKubeconfig []byte, scheme *runtime.Scheme) (client.Client, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(hubKubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get REST config from hub kubeconfig: %w", err)
+	}
+	hubClient, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hub client: %w", err)
+	}
+	return hubClient, nil
+}
+
+// Reporter mirrors a condensed IBU status into an ImageBasedUpgradeReport on the hub. Reporting is enabled by
+// creating the SecretName secret in the LCA namespace
+type Reporter struct {
+	Client client.Reader
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	hubClient     client.Client
+	hubKubeconfig []byte
+	lastReported  *lcav1alpha1.ImageBasedUpgradeReportStatus
+	batcher       utils.StatusBatcher
+}
+
+// BuildStatus returns the report status summarizing the given ibu
+func BuildStatus(ibu *lcav1alpha1.ImageBasedUpgrade, clusterName string, eta *time.Time) lcav1alpha1.ImageBasedUpgradeReportStatus {
+	status := lcav1alpha1.ImageBasedUpgradeReportStatus{
+		ClusterName:   clusterName,
+		Stage:         ibu.Spec.Stage,
+		SeedImage:     ibu.Spec.SeedImageRef.Image,
+		TargetVersion: ibu.Spec.SeedImageRef.Version,
+	}
+
+	if inProgressStage := utils.GetInProgressStage(ibu); inProgressStage != "" {
+		status.Stage = inProgressStage
+		status.State = lcav1alpha1.ReportStateInProgress
+		if condition := utils.GetInProgressCondition(ibu, inProgressStage); condition != nil {
+			status.Message = condition.Message
+		}
+		if eta != nil {
+			status.EstimatedCompletionTime = &metav1.Time{Time: *eta}
+		}
+	} else if ibu.Spec.Stage == lcav1alpha1.Stages.Idle {
+		status.State = lcav1alpha1.ReportStateIdle
+	} else if utils.IsStageFailed(ibu, ibu.Spec.Stage) {
+		status.State = lcav1alpha1.ReportStateFailed
+	} else if utils.IsStageCompleted(ibu, ibu.Spec.Stage) {
+		status.State = lcav1alpha1.ReportStateCompleted
+		if condition := utils.GetCompletedCondition(ibu, ibu.Spec.Stage); condition != nil {
+			status.Message = condition.Message
+		}
+	}
+
+	// The failure is reported until the IBU goes back to Idle, which resets the conditions
+	for _, stage := range []lcav1alpha1.ImageBasedUpgradeStage{
+		lcav1alpha1.Stages.Prep, lcav1alpha1.Stages.Upgrade, lcav1alpha1.Stages.Rollback} {
+		if !utils.IsStageFailed(ibu, stage) {
+			continue
+		}
+		if condition := utils.GetInProgressCondition(ibu, stage); condition != nil {
+			status.LastError = condition.Message
+			status.LastErrorReason = condition.Reason
+			if status.State == lcav1alpha1.ReportStateFailed {
+				status.Message = condition.Message
+			}
+		}
+	}
+
+	return status
+}
+
+// Publish creates or updates the ImageBasedUpgradeReport of this cluster on the hub, if hub reporting is enabled.
+// The report is only written when its content changes.
+func (r *Reporter) Publish(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade, eta *time.Time) error {
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: SecretName, Namespace: common.LcaNamespace}, secret); err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Hub reporting is not enabled
+			r.hubClient, r.hubKubeconfig, r.lastReported = nil, nil, nil
+			return nil
+		}
+		return fmt.Errorf("failed to get secret %s: %w", SecretName, err)
+	}
+
+	hubKubeconfig := secret.Data[HubKubeconfigKey]
+	clusterName := string(secret.Data[ClusterNameKey])
+	if len(hubKubeconfig) == 0 || clusterName == "" {
+		return fmt.Errorf("secret %s must contain both %s and %s", SecretName, HubKubeconfigKey, ClusterNameKey)
+	}
+
+	if r.hubClient == nil || !bytes.Equal(r.hubKubeconfig, hubKubeconfig) {
+		hubClient, err := newHubClient(hubKubeconfig, r.Scheme)
+		if err != nil {
+			return err
+		}
+		r.hubClient, r.hubKubeconfig, r.lastReported = hubClient, hubKubeconfig, nil
+	}
+
+	status := BuildStatus(ibu, clusterName, eta)
+	if r.lastReported != nil && equality.Semantic.DeepEqual(*r.lastReported, status) {
+		return nil
+	}
+	now := time.Now()
+	if r.lastReported != nil && isProgressOnlyChange(r.lastReported, &status) &&
+		!r.batcher.Due(utils.GetStatusUpdateInterval(ibu), now) {
+		return nil
+	}
+	reported := status
+	status.LastUpdateTime = metav1.Now()
+
+	report := &lcav1alpha1.ImageBasedUpgradeReport{}
+	err := r.hubClient.Get(ctx, types.NamespacedName{Name: clusterName, Namespace: clusterName}, report)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get ImageBasedUpgradeReport from hub: %w", err)
+		}
+		report = &lcav1alpha1.ImageBasedUpgradeReport{
+			ObjectMeta: metav1.ObjectMeta{Name: clusterName, Namespace: clusterName},
+			Status:     status,
+		}
+		if err := r.hubClient.Create(ctx, report); err != nil {
+			return fmt.Errorf("failed to create ImageBasedUpgradeReport on hub: %w", err)
+		}
+	} else {
+		report.Status = status
+		if err := r.hubClient.Update(ctx, report); err != nil {
+			return fmt.Errorf("failed to update ImageBasedUpgradeReport on hub: %w", err)
+		}
+	}
+
+	r.Log.Info("Published ImageBasedUpgradeReport to hub", "cluster", clusterName, "stage", status.Stage, "state", status.State)
+	r.lastReported = &reported
+	r.batcher.Written(now)
+	return nil
+}
+
+// isProgressOnlyChange tells whether the new report status only differs from the old one by the progress of the
+// stage in progress
+func isProgressOnlyChange(old, new *lcav1alpha1.ImageBasedUpgradeReportStatus) bool {
+	if old.State != lcav1alpha1.ReportStateInProgress {
+		return false
+	}
+	oldCopy, newCopy := old.DeepCopy(), new.DeepCopy()
+	for _, status := range []*lcav1alpha1.ImageBasedUpgradeReportStatus{oldCopy, newCopy} {
+		status.Message = ""
+		status.EstimatedCompletionTime = nil
+	}
+	return equality.Semantic.DeepEqual(oldCopy, newCopy)
+}
@@ -0,0 +1,128 @@
+package hubreport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/testutils"
+)
+
+func TestBuildStatus(t *testing.T) {
+	eta := time.Now().Add(time.Hour)
+
+	inProgress := &lcav1alpha1.ImageBasedUpgrade{Spec: lcav1alpha1.ImageBasedUpgradeSpec{Stage: lcav1alpha1.Stages.Prep}}
+	utils.SetPrepStatusInProgress(inProgress, "Precaching progress: total: 10 (pulled: 5, skipped: 0, failed: 0)")
+
+	testCases := []struct {
+		name     string
+		ibu      *lcav1alpha1.ImageBasedUpgrade
+		expected lcav1alpha1.ImageBasedUpgradeReportStatus
+	}{
+		{
+			name: "prep in progress",
+			ibu:  inProgress,
+			expected: lcav1alpha1.ImageBasedUpgradeReportStatus{
+				ClusterName:             "sno1",
+				Stage:                   lcav1alpha1.Stages.Prep,
+				State:                   lcav1alpha1.ReportStateInProgress,
+				Message:                 "Precaching progress: total: 10 (pulled: 5, skipped: 0, failed: 0)",
+				EstimatedCompletionTime: &metav1.Time{Time: eta},
+			},
+		},
+		{
+			name: "prep failed",
+			ibu:  testutils.PrepFailedIBU(assert.AnError),
+			expected: lcav1alpha1.ImageBasedUpgradeReportStatus{
+				ClusterName:     "sno1",
+				Stage:           lcav1alpha1.Stages.Prep,
+				State:           lcav1alpha1.ReportStateFailed,
+				SeedImage:       "quay.io/seed:4.15.0",
+				TargetVersion:   "4.15.0",
+				Message:         assert.AnError.Error(),
+				LastError:       assert.AnError.Error(),
+				LastErrorReason: string(utils.ConditionReasons.Failed),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, BuildStatus(tc.ibu, "sno1", &eta))
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	scheme := testutils.Scheme()
+	hubClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	origNewHubClient := newHubClient
+	defer func() {
+		newHubClient = origNewHubClient
+	}()
+	hubClientCreated := 0
+	newHubClient = func(hubKubeconfig []byte, scheme *runtime.Scheme) (client.Client, error) {
+		hubClientCreated++
+		return hubClient, nil
+	}
+
+	// Reporting is disabled without the secret
+	localClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	reporter := &Reporter{Client: localClient, Scheme: scheme, Log: logr.Discard()}
+	ibu := testutils.PrepFailedIBU(assert.AnError)
+	assert.NoError(t, reporter.Publish(context.Background(), ibu, nil))
+	assert.Equal(t, 0, hubClientCreated)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: SecretName, Namespace: common.LcaNamespace},
+		Data:       map[string][]byte{HubKubeconfigKey: []byte("kubeconfig"), ClusterNameKey: []byte("sno1")},
+	}
+	assert.NoError(t, localClient.Create(context.Background(), secret))
+
+	// The report is created on the hub
+	assert.NoError(t, reporter.Publish(context.Background(), ibu, nil))
+	report := &lcav1alpha1.ImageBasedUpgradeReport{}
+	assert.NoError(t, hubClient.Get(context.Background(), types.NamespacedName{Name: "sno1", Namespace: "sno1"}, report))
+	assert.Equal(t, lcav1alpha1.ReportStateFailed, report.Status.State)
+	version := report.ResourceVersion
+
+	// An unchanged status is not written again
+	assert.NoError(t, reporter.Publish(context.Background(), ibu, nil))
+	assert.NoError(t, hubClient.Get(context.Background(), types.NamespacedName{Name: "sno1", Namespace: "sno1"}, report))
+	assert.Equal(t, version, report.ResourceVersion)
+
+	// A new status updates the report
+	ibu.Spec.Stage = lcav1alpha1.Stages.Idle
+	utils.ResetStatusConditions(&ibu.Status.Conditions, ibu.Generation)
+	assert.NoError(t, reporter.Publish(context.Background(), ibu, nil))
+	assert.NoError(t, hubClient.Get(context.Background(), types.NamespacedName{Name: "sno1", Namespace: "sno1"}, report))
+	assert.Equal(t, lcav1alpha1.ReportStateIdle, report.Status.State)
+	assert.Empty(t, report.Status.LastError)
+	assert.Equal(t, 1, hubClientCreated)
+
+	// The progress is batched, unless the interval is disabled
+	ibu.Spec.Stage = lcav1alpha1.Stages.Prep
+	utils.SetPrepStatusInProgress(ibu, "Precaching 1/10")
+	assert.NoError(t, reporter.Publish(context.Background(), ibu, nil))
+	utils.SetPrepStatusInProgress(ibu, "Precaching 2/10")
+	assert.NoError(t, reporter.Publish(context.Background(), ibu, nil))
+	assert.NoError(t, hubClient.Get(context.Background(), types.NamespacedName{Name: "sno1", Namespace: "sno1"}, report))
+	assert.Equal(t, "Precaching 1/10", report.Status.Message)
+
+	ibu.SetAnnotations(map[string]string{utils.StatusUpdateIntervalAnnotation: "0s"})
+	assert.NoError(t, reporter.Publish(context.Background(), ibu, nil))
+	assert.NoError(t, hubClient.Get(context.Background(), types.NamespacedName{Name: "sno1", Namespace: "sno1"}, report))
+	assert.Equal(t, "Precaching 2/10", report.Status.Message)
+}
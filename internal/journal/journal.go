@@ -0,0 +1,70 @@
+// Package journal mirrors the events of the upgrade into the host systemd journal, so that node-level troubleshooting
+// without API access can correlate the OS events with the upgrade timeline.
+package journal
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+// Identifier is the syslog identifier of the entries written to the journal, e.g. for journalctl -t lca-audit
+const Identifier = "lca-audit"
+
+// EventRecorder records the events and writes them to the host journal as well. Failures to write to the journal
+// are logged only, as the events are still recorded in the cluster
+type EventRecorder struct {
+	record.EventRecorder
+	executor ops.Execute
+	log      logr.Logger
+}
+
+// NewEventRecorder wraps the recorder to forward its events to the journal, through the host commands executor
+func NewEventRecorder(recorder record.EventRecorder, executor ops.Execute, log logr.Logger) *EventRecorder {
+	return &EventRecorder{EventRecorder: recorder, executor: executor, log: log}
+}
+
+// Event records the event and writes it to the journal
+func (r *EventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.EventRecorder.Event(object, eventtype, reason, message)
+	r.write(object, eventtype, reason, message)
+}
+
+// Eventf records the event and writes it to the journal
+func (r *EventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	r.write(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf records the event and writes it to the journal, without its annotations
+func (r *EventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	r.write(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *EventRecorder) write(object runtime.Object, eventtype, reason, message string) {
+	if r.executor == nil {
+		return
+	}
+
+	priority := "user.info"
+	if eventtype == corev1.EventTypeWarning {
+		priority = "user.warning"
+	}
+	entry := fmt.Sprintf("%s: %s", reason, message)
+	if accessor, err := meta.Accessor(object); err == nil {
+		kind := reflect.Indirect(reflect.ValueOf(object)).Type().Name()
+		entry = fmt.Sprintf("%s/%s %s", kind, accessor.GetName(), entry)
+	}
+
+	if _, err := r.executor.Execute("logger", "-t", Identifier, "-p", priority, "--", entry); err != nil {
+		r.log.Error(err, "Failed to write event to the journal", "reason", reason)
+	}
+}
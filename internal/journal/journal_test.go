@@ -0,0 +1,39 @@
+package journal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+func TestEventRecorder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockExec := ops.NewMockExecute(ctrl)
+	fakeRecorder := record.NewFakeRecorder(10)
+	recorder := NewEventRecorder(fakeRecorder, mockExec, logr.Discard())
+	ibu := &lcav1alpha1.ImageBasedUpgrade{ObjectMeta: metav1.ObjectMeta{Name: "upgrade"}}
+
+	mockExec.EXPECT().Execute("logger", "-t", Identifier, "-p", "user.info", "--",
+		"ImageBasedUpgrade/upgrade PrepCompleted: Prep stage completed successfully").Return("", nil)
+	recorder.Event(ibu, corev1.EventTypeNormal, "PrepCompleted", "Prep stage completed successfully")
+	assert.Equal(t, "Normal PrepCompleted Prep stage completed successfully", <-fakeRecorder.Events)
+
+	mockExec.EXPECT().Execute("logger", "-t", Identifier, "-p", "user.warning", "--",
+		"ImageBasedUpgrade/upgrade UpgradeFailed: health checks failed after 3 attempts").Return("", nil)
+	recorder.Eventf(ibu, corev1.EventTypeWarning, "UpgradeFailed", "health checks failed after %d attempts", 3)
+	assert.Equal(t, "Warning UpgradeFailed health checks failed after 3 attempts", <-fakeRecorder.Events)
+
+	// The event is recorded even if the journal cannot be written
+	mockExec.EXPECT().Execute("logger", gomock.Any()).Return("", errors.New("logger not found"))
+	recorder.Event(ibu, corev1.EventTypeNormal, "Idle", "Idle")
+	assert.Equal(t, "Normal Idle Idle", <-fakeRecorder.Events)
+}
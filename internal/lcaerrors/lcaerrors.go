@@ -0,0 +1,95 @@
+// Package lcaerrors defines the sentinel errors used to classify stage failures.
+// Each sentinel maps to a condition reason, so automation can branch on the reason
+// of a failed condition instead of parsing its message.
+package lcaerrors
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInsufficientSpace = errors.New("insufficient space")
+	ErrSeedIncompatible  = errors.New("seed image incompatible")
+	ErrSeedPull          = errors.New("seed image pull failed")
+	ErrSeedDigest        = errors.New("seed image digest mismatch")
+	ErrRegistryAuth      = errors.New("registry authentication failed")
+	ErrOstreeDeploy      = errors.New("ostree deployment failed")
+	ErrPrecache          = errors.New("precaching failed")
+	ErrPrecacheMissing   = errors.New("precached images missing")
+	ErrPrecacheTimeout   = errors.New("precaching timed out")
+	ErrPrecacheStalled   = errors.New("precaching stalled")
+	ErrInvalidSpec       = errors.New("invalid spec")
+	ErrBackup            = errors.New("backup failed")
+	ErrRestore           = errors.New("restore failed")
+	ErrStorageBackend    = errors.New("storage backend unavailable")
+	ErrExtraManifests    = errors.New("extra manifests failed")
+	ErrHealthCheck       = errors.New("health check failed")
+	ErrReboot            = errors.New("reboot failed")
+	ErrPivotGate         = errors.New("pivot gate failed")
+
+	ErrFIPSMismatch             = errors.New("seed FIPS mode mismatch")
+	ErrSecureBootMismatch       = errors.New("seed secure boot mismatch")
+	ErrBootDeviceLayoutMismatch = errors.New("seed boot device layout mismatch")
+	ErrNodeNameMismatch         = errors.New("node name not preserved")
+)
+
+// Host command output patterns used to refine the classification of host errors
+const (
+	noSpaceLeftOnDevice    = "no space left on device"
+	registryUnauthorized   = "unauthorized"
+	registryAuthentication = "authentication required"
+)
+
+// Error classifies err with one of the sentinel errors, keeping the original message
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap allows errors.Is to match both the kind and the wrapped error chain
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Wrap classifies err with the given kind. It returns nil if err is nil, and keeps
+// an existing classification if err was already classified.
+func Wrap(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// WrapHostError classifies an error from a host command, detecting failures caused by
+// a full filesystem or a registry authentication failure from the command output
+func WrapHostError(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case strings.Contains(strings.ToLower(err.Error()), noSpaceLeftOnDevice):
+		return Wrap(ErrInsufficientSpace, err)
+	case IsRegistryAuthError(err):
+		return Wrap(ErrRegistryAuth, err)
+	}
+	return Wrap(kind, err)
+}
+
+// IsRegistryAuthError tells whether the error of a host command is a registry rejecting the credentials, or
+// requiring some, from the command output
+func IsRegistryAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, registryUnauthorized) || strings.Contains(msg, registryAuthentication)
+}
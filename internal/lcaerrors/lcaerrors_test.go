@@ -0,0 +1,73 @@
+package lcaerrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrap(t *testing.T) {
+	err := errors.New("ostree admin deploy failed")
+
+	wrapped := Wrap(ErrOstreeDeploy, err)
+	assert.True(t, errors.Is(wrapped, ErrOstreeDeploy))
+	assert.True(t, errors.Is(wrapped, err))
+	assert.Equal(t, err.Error(), wrapped.Error())
+
+	// classification is preserved through fmt.Errorf wrapping
+	outer := fmt.Errorf("failed to setup stateroot: %w", wrapped)
+	assert.True(t, errors.Is(outer, ErrOstreeDeploy))
+
+	// an existing classification is kept
+	rewrapped := Wrap(ErrPrecache, outer)
+	assert.True(t, errors.Is(rewrapped, ErrOstreeDeploy))
+	assert.False(t, errors.Is(rewrapped, ErrPrecache))
+
+	assert.Nil(t, Wrap(ErrPrecache, nil))
+}
+
+func TestWrapHostError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "no space left on device",
+			err:      errors.New("write /sysroot/ostree/repo: no space left on device"),
+			expected: ErrInsufficientSpace,
+		},
+		{
+			name:     "unauthorized",
+			err:      errors.New("reading manifest: unauthorized: access to the requested resource is not authorized"),
+			expected: ErrRegistryAuth,
+		},
+		{
+			name:     "authentication required",
+			err:      errors.New("Error: initializing source: Requesting bearer token: invalid status code from registry 401 (Authentication Required)"),
+			expected: ErrRegistryAuth,
+		},
+		{
+			name:     "other failure",
+			err:      errors.New("connection refused"),
+			expected: ErrSeedPull,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.True(t, errors.Is(WrapHostError(ErrSeedPull, tc.err), tc.expected))
+		})
+	}
+}
+
+func TestIsRegistryAuthError(t *testing.T) {
+	assert.True(t, IsRegistryAuthError(errors.New("failed podman with args [pull quay.io/org/app:v1]: "+
+		"Error: reading blob sha256:abc: fetching blob: StatusCode: 401, \"{\\\"errors\\\":[{\\\"code\\\":\\\"UNAUTHORIZED\\\"}]}\"")))
+	assert.True(t, IsRegistryAuthError(fmt.Errorf("pull failed: %w",
+		errors.New("Requesting bearer token: invalid status code from registry 401 (Authentication Required)"))))
+	assert.False(t, IsRegistryAuthError(errors.New("manifest unknown")))
+	assert.False(t, IsRegistryAuthError(nil))
+}
@@ -0,0 +1,199 @@
+// Package mcodrift finds the files managed by the machine-config-operator that were modified locally on the node,
+// comparing the files and systemd units of the current MachineConfig of the node with their on-disk content.
+package mcodrift
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const (
+	// CurrentConfigFile is the MachineConfig currently applied on the node, written by the machine-config-daemon
+	CurrentConfigFile = "/etc/machine-config-daemon/currentconfig"
+
+	systemdUnitsDir = "/etc/systemd/system"
+)
+
+// ignition holds the subset of the ignition config of a MachineConfig that lays down files on the node
+type ignition struct {
+	Storage struct {
+		Files []struct {
+			Path     string `json:"path"`
+			Mode     *int   `json:"mode,omitempty"`
+			Contents struct {
+				Source      *string `json:"source,omitempty"`
+				Compression *string `json:"compression,omitempty"`
+			} `json:"contents"`
+		} `json:"files"`
+	} `json:"storage"`
+	Systemd struct {
+		Units []struct {
+			Name     string  `json:"name"`
+			Contents *string `json:"contents,omitempty"`
+			Dropins  []struct {
+				Name     string  `json:"name"`
+				Contents *string `json:"contents,omitempty"`
+			} `json:"dropins,omitempty"`
+		} `json:"units"`
+	} `json:"systemd"`
+}
+
+// Drift is a MachineConfig-managed file whose on-disk state differs from the MachineConfig
+type Drift struct {
+	Path   string
+	Reason string
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s (%s)", d.Path, d.Reason)
+}
+
+// Detect compares the files and systemd units of the current MachineConfig of the node with the files under root,
+// the host filesystem, and returns the ones modified locally, sorted by path
+func Detect(root string) ([]Drift, error) {
+	ign, err := readIgnition(root)
+	if err != nil || ign == nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	for _, file := range ign.Storage.Files {
+		if file.Contents.Source == nil {
+			continue
+		}
+		expected, err := decodeSource(*file.Contents.Source, file.Contents.Compression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the contents of %s: %w", file.Path, err)
+		}
+		var mode *fs.FileMode
+		if file.Mode != nil {
+			m := fs.FileMode(*file.Mode)
+			mode = &m
+		}
+		if drift := compare(root, file.Path, expected, mode); drift != nil {
+			drifts = append(drifts, *drift)
+		}
+	}
+	for _, unit := range ign.Systemd.Units {
+		if unit.Contents != nil {
+			if drift := compare(root, filepath.Join(systemdUnitsDir, unit.Name), []byte(*unit.Contents), nil); drift != nil {
+				drifts = append(drifts, *drift)
+			}
+		}
+		for _, dropin := range unit.Dropins {
+			if dropin.Contents != nil {
+				dropinPath := filepath.Join(systemdUnitsDir, unit.Name+".d", dropin.Name)
+				if drift := compare(root, dropinPath, []byte(*dropin.Contents), nil); drift != nil {
+					drifts = append(drifts, *drift)
+				}
+			}
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].Path < drifts[j].Path })
+	return drifts, nil
+}
+
+// ManagedUnits returns the names of the systemd units of the current MachineConfig of the node, under root, the host
+// filesystem
+func ManagedUnits(root string) ([]string, error) {
+	ign, err := readIgnition(root)
+	if err != nil || ign == nil {
+		return nil, err
+	}
+	units := make([]string, 0, len(ign.Systemd.Units))
+	for _, unit := range ign.Systemd.Units {
+		units = append(units, unit.Name)
+	}
+	return units, nil
+}
+
+// readIgnition reads the ignition config of the current MachineConfig of the node, nil if it has none
+func readIgnition(root string) (*ignition, error) {
+	mc := &mcfgv1.MachineConfig{}
+	if err := utils.ReadYamlOrJSONFile(filepath.Join(root, CurrentConfigFile), mc); err != nil {
+		return nil, fmt.Errorf("failed to read the current machine config: %w", err)
+	}
+	if len(mc.Spec.Config.Raw) == 0 {
+		return nil, nil
+	}
+
+	ign := &ignition{}
+	if err := json.Unmarshal(mc.Spec.Config.Raw, ign); err != nil {
+		return nil, fmt.Errorf("failed to decode the ignition config of machine config %s: %w", mc.Name, err)
+	}
+	return ign, nil
+}
+
+func compare(root, path string, expected []byte, mode *fs.FileMode) *Drift {
+	hostPath := filepath.Join(root, path)
+	info, err := os.Stat(hostPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Drift{Path: path, Reason: "missing"}
+		}
+		return &Drift{Path: path, Reason: fmt.Sprintf("unreadable: %s", err)}
+	}
+	content, err := os.ReadFile(hostPath)
+	if err != nil {
+		return &Drift{Path: path, Reason: fmt.Sprintf("unreadable: %s", err)}
+	}
+	if !bytes.Equal(content, expected) {
+		return &Drift{Path: path, Reason: "content differs"}
+	}
+	if mode != nil && info.Mode().Perm() != mode.Perm() {
+		return &Drift{Path: path, Reason: fmt.Sprintf("mode %#o differs from %#o", info.Mode().Perm(), mode.Perm())}
+	}
+	return nil
+}
+
+// decodeSource decodes the data URL of an ignition file, the only scheme used by the rendered MachineConfigs
+func decodeSource(source string, compression *string) ([]byte, error) {
+	if !strings.HasPrefix(source, "data:") {
+		return nil, fmt.Errorf("unsupported source scheme: %s", strings.SplitN(source, ":", 2)[0])
+	}
+	mediaType, data, found := strings.Cut(strings.TrimPrefix(source, "data:"), ",")
+	if !found {
+		return nil, fmt.Errorf("invalid data url")
+	}
+
+	var decoded []byte
+	if strings.HasSuffix(mediaType, ";base64") {
+		var err error
+		if decoded, err = base64.StdEncoding.DecodeString(data); err != nil {
+			return nil, fmt.Errorf("invalid base64 data: %w", err)
+		}
+	} else {
+		unescaped, err := url.PathUnescape(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url-encoded data: %w", err)
+		}
+		decoded = []byte(unescaped)
+	}
+
+	if compression != nil && *compression == "gzip" {
+		reader, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		defer reader.Close()
+		if decoded, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+	}
+	return decoded, nil
+}
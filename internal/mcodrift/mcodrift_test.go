@@ -0,0 +1,75 @@
+package mcodrift
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func writeFile(t *testing.T, root, path, content string, mode os.FileMode) {
+	hostPath := filepath.Join(root, path)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(hostPath), 0o755))
+	assert.NoError(t, os.WriteFile(hostPath, []byte(content), mode))
+	assert.NoError(t, os.Chmod(hostPath, mode))
+}
+
+func TestDetect(t *testing.T) {
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	_, _ = w.Write([]byte("compressed\n"))
+	_ = w.Close()
+
+	ignitionConfig := map[string]any{
+		"ignition": map[string]any{"version": "3.2.0"},
+		"storage": map[string]any{"files": []any{
+			map[string]any{"path": "/etc/plain.conf", "mode": 420, "contents": map[string]any{"source": "data:,hello%20world%0A"}},
+			map[string]any{"path": "/etc/base64.conf", "mode": 420, "contents": map[string]any{"source": "data:text/plain;charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte("base64\n"))}},
+			map[string]any{"path": "/etc/gzip.conf", "contents": map[string]any{"source": "data:;base64," + base64.StdEncoding.EncodeToString(gzipped.Bytes()), "compression": "gzip"}},
+			map[string]any{"path": "/etc/mode.conf", "mode": 384, "contents": map[string]any{"source": "data:,mode"}},
+			map[string]any{"path": "/etc/missing.conf", "contents": map[string]any{"source": "data:,missing"}},
+		}},
+		"systemd": map[string]any{"units": []any{
+			map[string]any{"name": "kubelet.service", "contents": "[Unit]\n"},
+			map[string]any{"name": "crio.service", "dropins": []any{map[string]any{"name": "10-mco.conf", "contents": "[Service]\n"}}},
+			map[string]any{"name": "enabled-only.service", "enabled": true},
+		}},
+	}
+	raw, err := json.Marshal(ignitionConfig)
+	assert.NoError(t, err)
+
+	root := t.TempDir()
+	mc := &mcfgv1.MachineConfig{Spec: mcfgv1.MachineConfigSpec{Config: runtime.RawExtension{Raw: raw}}}
+	mcJSON, err := json.Marshal(mc)
+	assert.NoError(t, err)
+	writeFile(t, root, CurrentConfigFile, string(mcJSON), 0o644)
+
+	writeFile(t, root, "/etc/plain.conf", "hello world\n", 0o644)
+	writeFile(t, root, "/etc/base64.conf", "base64\n", 0o644)
+	writeFile(t, root, "/etc/gzip.conf", "compressed\n", 0o644)
+	writeFile(t, root, "/etc/mode.conf", "mode", 0o644)
+	writeFile(t, root, "/etc/systemd/system/kubelet.service", "[Unit]\n", 0o644)
+	writeFile(t, root, "/etc/systemd/system/crio.service.d/10-mco.conf", "[Service]\nEnvironment=LOCAL=1\n", 0o644)
+
+	drifts, err := Detect(root)
+	assert.NoError(t, err)
+	assert.Equal(t, []Drift{
+		{Path: "/etc/missing.conf", Reason: "missing"},
+		{Path: "/etc/mode.conf", Reason: "mode 0644 differs from 0600"},
+		{Path: "/etc/systemd/system/crio.service.d/10-mco.conf", Reason: "content differs"},
+	}, drifts)
+
+	units, err := ManagedUnits(root)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kubelet.service", "crio.service", "enabled-only.service"}, units)
+
+	_, err = Detect(t.TempDir())
+	assert.ErrorContains(t, err, "failed to read the current machine config")
+}
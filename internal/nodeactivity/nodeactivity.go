@@ -0,0 +1,184 @@
+package nodeactivity
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ProcStatFile holds the node-wide CPU counters
+	ProcStatFile = "/proc/stat"
+	// ProcNetDevFile holds the network counters of the host network namespace, as LCA runs with hostPID
+	ProcNetDevFile = "/proc/1/net/dev"
+	// SampleInterval is the minimum interval over which the node activity is measured
+	SampleInterval = 10 * time.Second
+	// MaxSampleAge is the maximum interval over which the node activity is measured, older counters being replaced
+	MaxSampleAge = 2 * time.Minute
+)
+
+// Usage is the node activity measured over a sample interval
+type Usage struct {
+	CPUPercent  float64
+	NetworkKBps float64
+}
+
+func (u *Usage) String() string {
+	return fmt.Sprintf("cpu: %.1f%%, network: %.1f KB/s", u.CPUPercent, u.NetworkKBps)
+}
+
+type sample struct {
+	cpuBusy  uint64
+	cpuTotal uint64
+	netBytes uint64
+	time     time.Time
+}
+
+// Sampler measures the node activity across calls, between the counters read by two calls, so that the caller is not
+// blocked for the sample interval. The zero value is ready to use
+type Sampler struct {
+	mu         sync.Mutex
+	statFile   string
+	netDevFile string
+	last       *sample
+}
+
+// Next reads the node counters and returns the activity since the counters read by a previous call, or nil if none
+// was read between SampleInterval and MaxSampleAge ago, the caller being expected to call again after SampleInterval
+func (s *Sampler) Next() (*Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statFile, netDevFile := s.statFile, s.netDevFile
+	if statFile == "" {
+		statFile, netDevFile = ProcStatFile, ProcNetDevFile
+	}
+	current, err := readSample(statFile, netDevFile)
+	if err != nil {
+		s.last = nil
+		return nil, err
+	}
+
+	if s.last == nil || current.time.Sub(s.last.time) > MaxSampleAge {
+		s.last = &current
+		return nil, nil
+	}
+	if current.time.Sub(s.last.time) < SampleInterval {
+		return nil, nil
+	}
+	usage := usageBetween(*s.last, current)
+	s.last = &current
+	return usage, nil
+}
+
+// CheckLowActivity returns an error describing the exceeded thresholds, if any. A threshold <= 0 is not checked
+func CheckLowActivity(usage *Usage, maxCPUPercent, maxNetworkKBps int) error {
+	var errs error
+	if maxCPUPercent > 0 && usage.CPUPercent > float64(maxCPUPercent) {
+		errs = errors.Join(errs, fmt.Errorf("cpu utilization %.1f%% is above %d%%", usage.CPUPercent, maxCPUPercent))
+	}
+	if maxNetworkKBps > 0 && usage.NetworkKBps > float64(maxNetworkKBps) {
+		errs = errors.Join(errs, fmt.Errorf("network throughput %.1f KB/s is above %d KB/s", usage.NetworkKBps, maxNetworkKBps))
+	}
+	return errs
+}
+
+func usageBetween(start, end sample) *Usage {
+	usage := &Usage{}
+	if total := end.cpuTotal - start.cpuTotal; end.cpuTotal > start.cpuTotal {
+		usage.CPUPercent = float64(end.cpuBusy-start.cpuBusy) * 100 / float64(total)
+	}
+	if elapsed := end.time.Sub(start.time).Seconds(); elapsed > 0 && end.netBytes >= start.netBytes {
+		usage.NetworkKBps = float64(end.netBytes-start.netBytes) / 1024 / elapsed
+	}
+	return usage
+}
+
+func readSample(statFile, netDevFile string) (sample, error) {
+	s := sample{time: time.Now()}
+
+	var err error
+	if s.cpuBusy, s.cpuTotal, err = readCPUCounters(statFile); err != nil {
+		return s, err
+	}
+	if s.netBytes, err = readNetworkBytes(netDevFile); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// readCPUCounters returns the busy and total jiffies from the aggregated cpu line of /proc/stat
+func readCPUCounters(statFile string) (busy, total uint64, err error) {
+	f, err := os.Open(statFile)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open %s: %w", statFile, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to parse cpu counters in %s: %w", statFile, err)
+			}
+			// guest and guest_nice are already accounted in user and nice
+			if i >= 8 {
+				break
+			}
+			total += value
+			// idle and iowait
+			if i != 3 && i != 4 {
+				busy += value
+			}
+		}
+		return busy, total, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read %s: %w", statFile, err)
+	}
+	return 0, 0, fmt.Errorf("no cpu counters found in %s", statFile)
+}
+
+// readNetworkBytes returns the bytes received and transmitted on all interfaces but loopback
+func readNetworkBytes(netDevFile string) (uint64, error) {
+	f, err := os.Open(netDevFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", netDevFile, err)
+	}
+	defer f.Close()
+
+	var bytes uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		iface, counters, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(iface) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		// receive bytes is the first field, transmit bytes the ninth
+		for _, field := range []string{fields[0], fields[8]} {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse network counters of %s in %s: %w", strings.TrimSpace(iface), netDevFile, err)
+			}
+			bytes += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", netDevFile, err)
+	}
+	return bytes, nil
+}
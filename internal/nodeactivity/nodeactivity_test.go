@@ -0,0 +1,123 @@
+package nodeactivity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const netDevHeader = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+`
+
+func writeSampleFiles(t *testing.T, cpuLine, netDev string) (string, string) {
+	dir := t.TempDir()
+	statFile := filepath.Join(dir, "stat")
+	netDevFile := filepath.Join(dir, "dev")
+	assert.NoError(t, os.WriteFile(statFile, []byte(cpuLine+"\ncpu0 1 2 3 4 5 6 7 8 0 0\nintr 12345\n"), 0o600))
+	assert.NoError(t, os.WriteFile(netDevFile, []byte(netDevHeader+netDev), 0o600))
+	return statFile, netDevFile
+}
+
+func TestReadSample(t *testing.T) {
+	statFile, netDevFile := writeSampleFiles(t,
+		"cpu  100 10 50 800 40 0 0 0 20 0",
+		"    lo: 5000 10 0 0 0 0 0 0 5000 10 0 0 0 0 0 0\n"+
+			"  eth0: 2048 20 0 0 0 0 0 0 1024 10 0 0 0 0 0 0\n"+
+			"br-ex: 1000 20 0 0 0 0 0 0 1000 10 0 0 0 0 0 0\n")
+
+	s, err := readSample(statFile, netDevFile)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(160), s.cpuBusy)
+	assert.Equal(t, uint64(1000), s.cpuTotal)
+	assert.Equal(t, uint64(5072), s.netBytes)
+
+	_, err = readSample(filepath.Join(t.TempDir(), "missing"), netDevFile)
+	assert.Error(t, err)
+}
+
+func TestUsageBetween(t *testing.T) {
+	now := time.Now()
+	start := sample{cpuBusy: 100, cpuTotal: 1000, netBytes: 0, time: now}
+	end := sample{cpuBusy: 350, cpuTotal: 2000, netBytes: 20480, time: now.Add(10 * time.Second)}
+
+	assert.Equal(t, &Usage{CPUPercent: 25, NetworkKBps: 2}, usageBetween(start, end))
+	// Counters reset, e.g. an interface was recreated
+	assert.Equal(t, &Usage{}, usageBetween(end, start))
+}
+
+func TestCheckLowActivity(t *testing.T) {
+	usage := &Usage{CPUPercent: 25, NetworkKBps: 2048}
+
+	testCases := []struct {
+		name           string
+		maxCPUPercent  int
+		maxNetworkKBps int
+		expectedErr    string
+	}{
+		{name: "no thresholds"},
+		{name: "below thresholds", maxCPUPercent: 30, maxNetworkKBps: 4096},
+		{name: "cpu above threshold", maxCPUPercent: 20, expectedErr: "cpu utilization 25.0% is above 20%"},
+		{
+			name:           "cpu and network above thresholds",
+			maxCPUPercent:  10,
+			maxNetworkKBps: 1024,
+			expectedErr:    "cpu utilization 25.0% is above 10%\nnetwork throughput 2048.0 KB/s is above 1024 KB/s",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckLowActivity(usage, tc.maxCPUPercent, tc.maxNetworkKBps)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestSamplerNext(t *testing.T) {
+	statFile, netDevFile := writeSampleFiles(t,
+		"cpu  100 0 0 900 0 0 0 0 0 0",
+		"  eth0: 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0\n")
+	sampler := &Sampler{statFile: statFile, netDevFile: netDevFile}
+
+	// The first counters read give no activity yet, nor those read too soon after
+	usage, err := sampler.Next()
+	assert.NoError(t, err)
+	assert.Nil(t, usage)
+	usage, err = sampler.Next()
+	assert.NoError(t, err)
+	assert.Nil(t, usage)
+
+	sampler.last.time = sampler.last.time.Add(-SampleInterval)
+	writeSampleFile(t, statFile, "cpu  350 0 0 1650 0 0 0 0 0 0")
+	writeSampleFile(t, netDevFile, netDevHeader+"  eth0: 10240 0 0 0 0 0 0 0 10240 0 0 0 0 0 0 0\n")
+	usage, err = sampler.Next()
+	assert.NoError(t, err)
+	if assert.NotNil(t, usage) {
+		assert.Equal(t, float64(25), usage.CPUPercent)
+		assert.InDelta(t, 2, usage.NetworkKBps, 0.1)
+	}
+
+	// Counters older than MaxSampleAge are replaced
+	sampler.last.time = sampler.last.time.Add(-MaxSampleAge - time.Second)
+	usage, err = sampler.Next()
+	assert.NoError(t, err)
+	assert.Nil(t, usage)
+
+	// Errors forget the previous counters
+	assert.NoError(t, os.Remove(statFile))
+	_, err = sampler.Next()
+	assert.Error(t, err)
+	assert.Nil(t, sampler.last)
+}
+
+func writeSampleFile(t *testing.T, name, content string) {
+	assert.NoError(t, os.WriteFile(name, []byte(content+"\n"), 0o600))
+}
@@ -0,0 +1,47 @@
+// Package nodeidentity checks the node keeps its name across the upgrade. After the pivot, the kubelet of the new
+// stateroot registers the node under the hostname set from the cluster configuration gathered before the pivot, and
+// recert renames the node of the seed cluster after it. A node registered under another name is a new node object,
+// orphaning the labels and taints of the node object of the cluster and breaking the scheduling of its workloads.
+package nodeidentity
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedclusterinfo"
+	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+// Check verifies the node keeps its name after the pivot:
+//   - the hostname of the node, the hostname of the new stateroot and the new hostname given to recert, must be a
+//     lowercase RFC 1123 subdomain, as the kubelet lowercases it for the node name and recert does not
+//   - the node must be named after its hostname, the new stateroot not carrying a hostname override of the kubelet
+//   - the node of the seed cluster must be named after its hostname, the only name recert renames
+//
+// The seed node name not recorded by the lca-cli that created the seed image is not checked.
+func Check(node *corev1.Node, seed *seedclusterinfo.SeedClusterInfo) error {
+	hostname, err := lcautils.GetNodeHostname(*node)
+	if err != nil {
+		return lcaerrors.Wrap(lcaerrors.ErrNodeNameMismatch, fmt.Errorf("node %s: %w", node.Name, err))
+	}
+	if problems := validation.IsDNS1123Subdomain(hostname); len(problems) != 0 {
+		return lcaerrors.Wrap(lcaerrors.ErrNodeNameMismatch, fmt.Errorf(
+			"hostname %q of node %s is not a valid node name after the upgrade: %s", hostname, node.Name,
+			strings.Join(problems, ", ")))
+	}
+	if hostname != node.Name {
+		return lcaerrors.Wrap(lcaerrors.ErrNodeNameMismatch, fmt.Errorf(
+			"node %s is not named after its hostname %s, it would be registered as a new node %s after the upgrade",
+			node.Name, hostname, hostname))
+	}
+	if seed.SNONodeName != "" && seed.SNONodeName != seed.SNOHostname {
+		return lcaerrors.Wrap(lcaerrors.ErrNodeNameMismatch, fmt.Errorf(
+			"seed cluster node %s is not named after its hostname %s, which recert renames, a seed image created "+
+				"from a cluster whose node is named after its hostname is required", seed.SNONodeName, seed.SNOHostname))
+	}
+	return nil
+}
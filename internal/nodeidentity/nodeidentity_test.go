@@ -0,0 +1,79 @@
+package nodeidentity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedclusterinfo"
+)
+
+func newNode(name, hostname string) *corev1.Node {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if hostname != "" {
+		node.Status.Addresses = []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "192.168.127.10"},
+			{Type: corev1.NodeHostName, Address: hostname},
+		}
+	}
+	return node
+}
+
+func TestCheck(t *testing.T) {
+	testcases := []struct {
+		name        string
+		node        *corev1.Node
+		seed        *seedclusterinfo.SeedClusterInfo
+		expectedMsg string
+	}{
+		{
+			name: "node name continuity",
+			node: newNode("sno.example.com", "sno.example.com"),
+			seed: &seedclusterinfo.SeedClusterInfo{SNOHostname: "seed", SNONodeName: "seed"},
+		},
+		{
+			name: "seed node name not recorded",
+			node: newNode("sno", "sno"),
+			seed: &seedclusterinfo.SeedClusterInfo{SNOHostname: "seed"},
+		},
+		{
+			name:        "no hostname",
+			node:        newNode("sno", ""),
+			seed:        &seedclusterinfo.SeedClusterInfo{},
+			expectedMsg: "node sno: failed to find node hostname",
+		},
+		{
+			name:        "uppercase hostname",
+			node:        newNode("sno", "SNO"),
+			seed:        &seedclusterinfo.SeedClusterInfo{},
+			expectedMsg: `hostname "SNO" of node sno is not a valid node name after the upgrade`,
+		},
+		{
+			name:        "node not named after its hostname",
+			node:        newNode("worker-0", "sno"),
+			seed:        &seedclusterinfo.SeedClusterInfo{},
+			expectedMsg: "node worker-0 is not named after its hostname sno",
+		},
+		{
+			name:        "seed node not named after its hostname",
+			node:        newNode("sno", "sno"),
+			seed:        &seedclusterinfo.SeedClusterInfo{SNOHostname: "seed", SNONodeName: "seed-node"},
+			expectedMsg: "seed cluster node seed-node is not named after its hostname seed",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Check(tc.node, tc.seed)
+			if tc.expectedMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, lcaerrors.ErrNodeNameMismatch)
+			assert.ErrorContains(t, err, tc.expectedMsg)
+		})
+	}
+}
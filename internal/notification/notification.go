@@ -0,0 +1,271 @@
+// Package notification sends the events of the IBU, such as the stage transitions, failures and completions, to an
+// external HTTP webhook, so that ticketing or NOC systems can track the upgrades without polling the API of every
+// cluster. It is enabled by creating the SecretName secret in the LCA namespace.
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+const (
+	// SecretName is the secret in the LCA namespace that enables the notifications
+	SecretName = "ibu-notification-webhook"
+	// URLKey is the secret key holding the http or https URL the notifications are posted to
+	URLKey = "url"
+	// TokenKey is the optional secret key holding the bearer token sent in the Authorization header
+	TokenKey = "token"
+	// CABundleKey is the optional secret key holding the PEM CA bundle trusted for an https URL, besides the system
+	// CAs
+	CABundleKey = "caBundle"
+	// ClusterNameKey is the optional secret key holding the name identifying the cluster in the notifications
+	ClusterNameKey = "clusterName"
+
+	// queueSize bounds the notifications waiting to be sent, the newer ones being dropped when the webhook is down
+	queueSize = 100
+	// maxAttempts is the number of attempts to send a notification, including the first one
+	maxAttempts = 5
+	// initialBackoff is the wait before the first retry, doubled before each following one
+	initialBackoff = 5 * time.Second
+	// requestTimeout bounds each attempt
+	requestTimeout = 30 * time.Second
+)
+
+// sleep is overridden by the tests
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Notification is the JSON body posted to the webhook for each event
+type Notification struct {
+	ClusterName string `json:"clusterName,omitempty"`
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	// Type is the type of the event, Normal or Warning
+	Type string `json:"type"`
+	// Reason is the reason of the event, e.g. PrepStarted, PrepCompleted, PrepFailed or Reboot
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	// Stage is the desired stage of the IBU
+	Stage string `json:"stage,omitempty"`
+	// FailureReason is the reason of the failed stage condition, e.g. SeedPullFailed
+	FailureReason string    `json:"failureReason,omitempty"`
+	SeedImage     string    `json:"seedImage,omitempty"`
+	TargetVersion string    `json:"targetVersion,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// Notifier sends the notifications queued by its event recorders to the webhook, with retries, in the background
+// once started by the manager. Failures are logged only, the events still being recorded in the cluster
+type Notifier struct {
+	client client.Reader
+	log    logr.Logger
+	queue  chan Notification
+}
+
+// NewNotifier returns a notifier reading its configuration from the SecretName secret with the client
+func NewNotifier(c client.Reader, log logr.Logger) *Notifier {
+	return &Notifier{client: c, log: log, queue: make(chan Notification, queueSize)}
+}
+
+// EventRecorder records the events and queues a notification for those of an IBU
+type EventRecorder struct {
+	record.EventRecorder
+	notifier *Notifier
+}
+
+// NewEventRecorder wraps the recorder to notify its events. A nil notifier leaves the recorder unchanged
+func (n *Notifier) NewEventRecorder(recorder record.EventRecorder) record.EventRecorder {
+	if n == nil {
+		return recorder
+	}
+	return &EventRecorder{EventRecorder: recorder, notifier: n}
+}
+
+// Event records the event and notifies it
+func (r *EventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.EventRecorder.Event(object, eventtype, reason, message)
+	r.notifier.notify(object, eventtype, reason, message)
+}
+
+// Eventf records the event and notifies it
+func (r *EventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	r.notifier.notify(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf records the event and notifies it, without its annotations
+func (r *EventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	r.notifier.notify(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// notify queues the notification of the event of an IBU, dropping it if the queue is full
+func (n *Notifier) notify(object runtime.Object, eventtype, reason, message string) {
+	ibu, ok := object.(*lcav1alpha1.ImageBasedUpgrade)
+	if !ok {
+		return
+	}
+	notification := newNotification(ibu, eventtype, reason, message)
+	select {
+	case n.queue <- notification:
+	default:
+		n.log.Info("Dropping notification, too many notifications are waiting to be sent", "reason", reason)
+	}
+}
+
+func newNotification(ibu *lcav1alpha1.ImageBasedUpgrade, eventtype, reason, message string) Notification {
+	notification := Notification{
+		Kind:          "ImageBasedUpgrade",
+		Name:          ibu.Name,
+		Type:          eventtype,
+		Reason:        reason,
+		Message:       message,
+		Stage:         string(ibu.Spec.Stage),
+		SeedImage:     ibu.Spec.SeedImageRef.Image,
+		TargetVersion: ibu.Spec.SeedImageRef.Version,
+		Time:          time.Now().UTC(),
+	}
+	if eventtype == corev1.EventTypeWarning && utils.IsStageFailed(ibu, ibu.Spec.Stage) {
+		if condition := utils.GetInProgressCondition(ibu, ibu.Spec.Stage); condition != nil {
+			notification.FailureReason = condition.Reason
+		}
+	}
+	return notification
+}
+
+// Start sends the queued notifications in order until the context is done, implementing the manager Runnable
+func (n *Notifier) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-n.queue:
+			if err := n.send(ctx, notification); err != nil {
+				n.log.Error(err, "Failed to send notification", "reason", notification.Reason)
+			}
+		}
+	}
+}
+
+// config is the webhook configuration of the SecretName secret
+type config struct {
+	url         string
+	token       string
+	clusterName string
+	client      *http.Client
+}
+
+// loadConfig returns the webhook configuration, nil if the notifications are not enabled
+func (n *Notifier) loadConfig(ctx context.Context) (*config, error) {
+	secret := &corev1.Secret{}
+	if err := n.client.Get(ctx, types.NamespacedName{Name: SecretName, Namespace: common.LcaNamespace}, secret); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get secret %s: %w", SecretName, err)
+	}
+
+	cfg := &config{
+		url:         string(secret.Data[URLKey]),
+		token:       string(secret.Data[TokenKey]),
+		clusterName: string(secret.Data[ClusterNameKey]),
+	}
+	if parsed, err := url.Parse(cfg.url); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("secret %s must contain an http or https URL in %s", SecretName, URLKey)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caBundle := secret.Data[CABundleKey]; len(caBundle) != 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("secret %s has no valid PEM certificate in %s", SecretName, CABundleKey)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	cfg.client = &http.Client{Transport: transport, Timeout: requestTimeout}
+	return cfg, nil
+}
+
+// send posts the notification to the webhook, retrying the connection failures and the server errors with a backoff
+func (n *Notifier) send(ctx context.Context, notification Notification) error {
+	cfg, err := n.loadConfig(ctx)
+	if err != nil || cfg == nil {
+		return err
+	}
+	notification.ClusterName = cfg.clusterName
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := post(ctx, cfg, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= maxAttempts {
+			return fmt.Errorf("failed to post notification to %s after %d attempt(s): %w", cfg.url, attempt, err)
+		}
+		n.log.Info("Retrying notification", "reason", notification.Reason, "attempt", attempt, "wait", backoff.String(),
+			"error", err.Error())
+		if sleepErr := sleep(ctx, backoff); sleepErr != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// post posts the body once, returning whether a failure is worth retrying
+func post(ctx context.Context, cfg *config, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.token)
+	}
+	resp, err := cfg.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("unexpected status %s", resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
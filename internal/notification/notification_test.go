@@ -0,0 +1,148 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
+	"github.com/openshift-kni/lifecycle-agent/internal/testutils"
+)
+
+func webhookSecret(url string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: SecretName, Namespace: common.LcaNamespace},
+		Data: map[string][]byte{
+			URLKey:         []byte(url),
+			TokenKey:       []byte("s3cr3t"),
+			ClusterNameKey: []byte("sno1"),
+		},
+	}
+}
+
+func TestEventRecorder(t *testing.T) {
+	n := NewNotifier(fake.NewClientBuilder().WithScheme(testutils.Scheme()).Build(), logr.Discard())
+	fakeRecorder := record.NewFakeRecorder(10)
+	recorder := n.NewEventRecorder(fakeRecorder)
+
+	ibu := testutils.PrepFailedIBU(lcaerrors.Wrap(lcaerrors.ErrSeedPull, errors.New("failed to pull seed image")))
+	recorder.Event(ibu, corev1.EventTypeWarning, "PrepFailed", "failed to pull seed image")
+	recorder.Eventf(&corev1.Pod{}, corev1.EventTypeNormal, "Started", "seed %s", "generation")
+
+	assert.Len(t, fakeRecorder.Events, 2)
+	assert.Len(t, n.queue, 1)
+	notification := <-n.queue
+	assert.WithinDuration(t, time.Now(), notification.Time, time.Minute)
+	notification.Time = time.Time{}
+	assert.Equal(t, Notification{
+		Kind:          "ImageBasedUpgrade",
+		Name:          utils.IBUName,
+		Type:          corev1.EventTypeWarning,
+		Reason:        "PrepFailed",
+		Message:       "failed to pull seed image",
+		Stage:         "Prep",
+		FailureReason: "SeedPullFailed",
+		SeedImage:     "quay.io/seed:4.15.0",
+		TargetVersion: "4.15.0",
+	}, notification)
+
+	// A nil notifier leaves the recorder unchanged
+	assert.Equal(t, record.EventRecorder(fakeRecorder), (*Notifier)(nil).NewEventRecorder(fakeRecorder))
+}
+
+func TestSend(t *testing.T) {
+	origSleep := sleep
+	defer func() { sleep = origSleep }()
+	var waits []time.Duration
+	sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	var attempts atomic.Int32
+	var received Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Bearer s3cr3t", req.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := fake.NewClientBuilder().WithScheme(testutils.Scheme()).WithObjects(webhookSecret(server.URL)).Build()
+	n := NewNotifier(c, logr.Discard())
+	notification := newNotification(testutils.PrepFailedIBU(lcaerrors.Wrap(lcaerrors.ErrSeedPull, errors.New("failed to pull seed image"))), corev1.EventTypeWarning, "PrepFailed", "failed to pull seed image")
+
+	// Retried on the server errors
+	assert.NoError(t, n.send(context.Background(), notification))
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Equal(t, []time.Duration{initialBackoff, 2 * initialBackoff}, waits)
+	assert.Equal(t, "sno1", received.ClusterName)
+	assert.Equal(t, "SeedPullFailed", received.FailureReason)
+
+	// Not retried on a client error
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+	c = fake.NewClientBuilder().WithScheme(testutils.Scheme()).WithObjects(webhookSecret(unauthorized.URL)).Build()
+	n = NewNotifier(c, logr.Discard())
+	waits = nil
+	assert.ErrorContains(t, n.send(context.Background(), notification), "after 1 attempt(s): unexpected status 401 Unauthorized")
+	assert.Empty(t, waits)
+
+	// Not enabled without the secret
+	n = NewNotifier(fake.NewClientBuilder().WithScheme(testutils.Scheme()).Build(), logr.Discard())
+	assert.NoError(t, n.send(context.Background(), notification))
+
+	// Invalid URL
+	c = fake.NewClientBuilder().WithScheme(testutils.Scheme()).WithObjects(webhookSecret("ftp://example.com")).Build()
+	n = NewNotifier(c, logr.Discard())
+	assert.EqualError(t, n.send(context.Background(), notification),
+		"secret ibu-notification-webhook must contain an http or https URL in url")
+}
+
+func TestStart(t *testing.T) {
+	received := make(chan Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var notification Notification
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&notification))
+		received <- notification
+	}))
+	defer server.Close()
+
+	c := fake.NewClientBuilder().WithScheme(testutils.Scheme()).WithObjects(webhookSecret(server.URL)).Build()
+	n := NewNotifier(c, logr.Discard())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- n.Start(ctx) }()
+
+	n.NewEventRecorder(record.NewFakeRecorder(1)).Event(testutils.PrepFailedIBU(lcaerrors.Wrap(lcaerrors.ErrSeedPull, errors.New("failed to pull seed image"))), corev1.EventTypeNormal, "PrepStarted", "In progress")
+	select {
+	case notification := <-received:
+		assert.Equal(t, "PrepStarted", notification.Reason)
+	case <-time.After(10 * time.Second):
+		t.Fatal("notification not received")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
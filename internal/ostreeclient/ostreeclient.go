@@ -1,10 +1,11 @@
 package ostreeclient
 
 import (
+	"context"
 	"fmt"
-	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
@@ -12,86 +13,99 @@ import (
 
 //go:generate mockgen -source=ostreeclient.go -package=ostreeclient -destination=mock_ostreeclient.go
 type IClient interface {
-	PullLocal(repoPath string) error
-	OSInit(osname string) error
-	Deploy(osname, refsepc string, kargs []string) error
-	Undeploy(ostreeIndex int) error
-	SetDefaultDeployment(index int) error
-	IsOstreeAdminSetDefaultFeatureEnabled() bool
-	GetDeployment(osname string) (string, error)
-	GetDeploymentDir(osname string) (string, error)
+	PullLocal(ctx context.Context, repoPath string) error
+	OSInit(ctx context.Context, osname string) error
+	Deploy(ctx context.Context, osname, refsepc string, kargs []string) error
+	Undeploy(ctx context.Context, ostreeIndex int) error
+	SetDefaultDeployment(ctx context.Context, index int) error
+	IsOstreeAdminSetDefaultFeatureEnabled(ctx context.Context) bool
+	GetDeployment(ctx context.Context, osname string) (string, error)
+	GetDeploymentDir(ctx context.Context, osname string) (string, error)
+	GetCommitMetadata(ctx context.Context, checksum string) (*CommitMetadata, error)
+}
+
+// CommitMetadata is the metadata of an ostree commit
+type CommitMetadata struct {
+	Checksum string
+	// Parent is the checksum of the parent commit, empty if it has none
+	Parent    string
+	Version   string
+	Timestamp time.Time
 }
 
 type Client struct {
-	executor ops.Execute
-	ibi      bool
+	executor        ops.Execute
+	ibi             bool
+	staterootLayout common.StaterootLayout
 }
 
-func NewClient(executor ops.Execute, ibi bool) IClient {
+func NewClient(executor ops.Execute, ibi bool, staterootLayout common.StaterootLayout) IClient {
 	return &Client{
-		executor: executor,
-		ibi:      ibi,
+		executor:        executor,
+		ibi:             ibi,
+		staterootLayout: staterootLayout,
 	}
 }
 
-func (c *Client) PullLocal(repoPath string) error {
-	args := []string{"pull-local"}
+// PullLocal pulls the objects of the repo at repoPath, verifying their checksums
+func (c *Client) PullLocal(ctx context.Context, repoPath string) error {
+	args := []string{"pull-local", "--untrusted"}
 	if c.ibi {
 		args = append(args, "--repo", "/mnt/ostree/repo")
 	}
-	if _, err := c.executor.Execute("ostree", append(args, repoPath)...); err != nil {
+	if _, err := c.executor.ExecuteWithContext(ctx, "ostree", append(args, repoPath)...); err != nil {
 		return fmt.Errorf("failed to pull local ostree with args %s, %w", args, err)
 	}
 
 	return nil
 }
 
-func (c *Client) OSInit(osname string) error {
+func (c *Client) OSInit(ctx context.Context, osname string) error {
 	args := []string{"admin", "os-init"}
 	if c.ibi {
 		args = append(args, "--sysroot", "/mnt")
 	}
 
-	if _, err := c.executor.Execute("ostree", append(args, osname)...); err != nil {
+	if _, err := c.executor.ExecuteWithContext(ctx, "ostree", append(args, osname)...); err != nil {
 		return fmt.Errorf("failed to run OSInit with args %s: %w", args, err)
 	}
 	return nil
 }
 
-func (c *Client) Deploy(osname, refsepc string, kargs []string) error {
+func (c *Client) Deploy(ctx context.Context, osname, refsepc string, kargs []string) error {
 	args := []string{"admin", "deploy", "--os", osname, "--no-prune"}
 	if c.ibi {
 		args = append(args, "--sysroot", "/mnt")
 	}
 	args = append(args, kargs...)
 	args = append(args, refsepc)
-	if !c.ibi && c.IsOstreeAdminSetDefaultFeatureEnabled() {
+	if !c.ibi && c.IsOstreeAdminSetDefaultFeatureEnabled(ctx) {
 		args = append(args, "--not-as-default")
 	}
 
 	// Run the command in bash to preserve the quoted kargs
 	args = append([]string{"ostree"}, args...)
-	if _, err := c.executor.Execute("bash", "-c", strings.Join(args, " ")); err != nil {
+	if _, err := c.executor.ExecuteWithContext(ctx, "bash", "-c", strings.Join(args, " ")); err != nil {
 		return fmt.Errorf("failed to run OSInit with args %s: %w", args, err)
 	}
 	return nil
 }
 
-func (c *Client) Undeploy(ostreeIndex int) error {
+func (c *Client) Undeploy(ctx context.Context, ostreeIndex int) error {
 	args := []string{"admin", "undeploy"}
 	if c.ibi {
 		args = append(args, "--sysroot", "/mnt")
 	}
 	args = append(args, fmt.Sprint(ostreeIndex))
-	if _, err := c.executor.Execute("ostree", args...); err != nil {
+	if _, err := c.executor.ExecuteWithContext(ctx, "ostree", args...); err != nil {
 		return fmt.Errorf("failed to run Undeploy with args %s: %w", args, err)
 	}
 	return nil
 }
 
-func (c *Client) IsOstreeAdminSetDefaultFeatureEnabled() bool {
+func (c *Client) IsOstreeAdminSetDefaultFeatureEnabled(ctx context.Context) bool {
 	// Quick check to see if the "ostree admin set-default" feature is available
-	output, err := c.executor.Execute("ostree", "admin", "--help")
+	output, err := c.executor.ExecuteWithContext(ctx, "ostree", "admin", "--help")
 	if err != nil {
 		return false
 	}
@@ -99,27 +113,27 @@ func (c *Client) IsOstreeAdminSetDefaultFeatureEnabled() bool {
 	return strings.Contains(output, "set-default")
 }
 
-func (c *Client) SetDefaultDeployment(index int) error {
+func (c *Client) SetDefaultDeployment(ctx context.Context, index int) error {
 	if index == 0 {
 		// Already set as default deployment
 		return nil
 	}
 
 	args := []string{"admin", "set-default", strconv.Itoa(index)}
-	if _, err := c.executor.Execute("ostree", args...); err != nil {
+	if _, err := c.executor.ExecuteWithContext(ctx, "ostree", args...); err != nil {
 		return fmt.Errorf("failed run ostree set-default with args %s: %w", args, err)
 	}
 
 	return nil
 }
 
-func (c *Client) GetDeployment(stateroot string) (string, error) {
+func (c *Client) GetDeployment(ctx context.Context, stateroot string) (string, error) {
 	args := []string{"admin", "status"}
 	if c.ibi {
 		args = append(args, "--sysroot", common.OstreeDeployPathPrefix)
 	}
 
-	output, err := c.executor.Execute("ostree", args...)
+	output, err := c.executor.ExecuteWithContext(ctx, "ostree", args...)
 	if err != nil {
 		return "", fmt.Errorf("unable to get deployment, ostree command failed: %w", err)
 	}
@@ -154,12 +168,65 @@ func (c *Client) GetDeployment(stateroot string) (string, error) {
 	return "", nil
 }
 
-func (c *Client) GetDeploymentDir(stateroot string) (string, error) {
-	deployment, err := c.GetDeployment(stateroot)
+func (c *Client) GetDeploymentDir(ctx context.Context, stateroot string) (string, error) {
+	deployment, err := c.GetDeployment(ctx, stateroot)
 	if err != nil {
 		return "", fmt.Errorf("unable to get determine deployment dir: %w", err)
 	}
 
-	deploymentDir := filepath.Join(common.GetStaterootPath(stateroot), "deploy", deployment)
+	deploymentDir := c.staterootLayout.DeploymentDir(stateroot, deployment)
 	return deploymentDir, nil
 }
+
+// commitDateLayout is the layout of the date of a commit printed by ostree show
+const commitDateLayout = "2006-01-02 15:04:05 -0700"
+
+// GetCommitMetadata returns the parent, version and timestamp of the commit
+func (c *Client) GetCommitMetadata(ctx context.Context, checksum string) (*CommitMetadata, error) {
+	args := []string{"show"}
+	if c.ibi {
+		args = append(args, "--repo", "/mnt/ostree/repo")
+	}
+	args = append(args, checksum)
+	output, err := c.executor.ExecuteWithContext(ctx, "ostree", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ostree show with args %s: %w", args, err)
+	}
+	return parseCommitMetadata(checksum, output)
+}
+
+// parseCommitMetadata parses the output of ostree show for the commit
+func parseCommitMetadata(checksum, output string) (*CommitMetadata, error) {
+	// Example output:
+	//   # ostree show 9455b99374197f10c453eb96f1b66cea884b3dc16ce4bc753bdb7263602bb722
+	//   commit 9455b99374197f10c453eb96f1b66cea884b3dc16ce4bc753bdb7263602bb722
+	//   Parent:  8ef186bc6407db2180726e32354c394c189c6e9be2c17839b313cf1fed3d5391
+	//   ContentChecksum:  2b1e2c1c3b6ff4fa0ec4bf27e0c0ac10e2f6a1c3d1f5b0b0a7c0a25f5d1e0e2a
+	//   Date:  2024-01-18 10:12:31 +0000
+	//   Version: 415.92.202401180912-0
+	//   (no subject)
+	metadata := &CommitMetadata{Checksum: checksum}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Parent":
+			metadata.Parent = value
+		case "Version":
+			metadata.Version = value
+		case "Date":
+			timestamp, err := time.Parse(commitDateLayout, value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse date of commit %s: %w", checksum, err)
+			}
+			metadata.Timestamp = timestamp
+		}
+	}
+	if metadata.Timestamp.IsZero() {
+		return nil, fmt.Errorf("no date in the metadata of commit %s", checksum)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,165 @@
+// Package podpolicy applies the scheduling and security requirements of the cluster, configured once in the
+// lca-pod-policy ConfigMap, to the pods of all the workloads created by the LCA, such as the precaching job, so that
+// they are enforced uniformly instead of per workload.
+package podpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+const (
+	// ConfigMapName is the ConfigMap in the LCA namespace configuring the pod policy
+	ConfigMapName = "lca-pod-policy"
+
+	// ConfigMap keys of the pod policy. The tolerations, affinity and seccomp profile are in YAML, as in a pod spec.
+	TolerationsKey      = "tolerations"
+	AffinityKey         = "affinity"
+	RuntimeClassNameKey = "runtimeClassName"
+	SeccompProfileKey   = "seccompProfile"
+)
+
+// Policy is the scheduling and security configuration of the pods created by the LCA
+type Policy struct {
+	// Tolerations are added to those of the pods, e.g. for the taints of a single node reserved to some workloads
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Affinity replaces the affinity of the pods
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// RuntimeClassName is the runtime class of the pods
+	RuntimeClassName string `json:"runtimeClassName,omitempty"`
+	// SeccompProfile is the seccomp profile of the pods, unless their containers set one
+	SeccompProfile *corev1.SeccompProfile `json:"seccompProfile,omitempty"`
+}
+
+// Load reads the pod policy from the ConfigMapName ConfigMap in the LCA namespace. It returns nil if the ConfigMap does
+// not exist, as no pod policy is configured.
+func Load(ctx context.Context, c client.Reader) (*Policy, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Name: ConfigMapName, Namespace: common.LcaNamespace}, cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get pod policy configmap %s: %w", ConfigMapName, err)
+	}
+	policy, err := Parse(cm.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod policy configmap %s: %w", ConfigMapName, err)
+	}
+	return policy, nil
+}
+
+// Parse returns the pod policy of the data of its ConfigMap, validated
+func Parse(data map[string]string) (*Policy, error) {
+	policy := &Policy{RuntimeClassName: strings.TrimSpace(data[RuntimeClassNameKey])}
+	if value := data[TolerationsKey]; value != "" {
+		if err := yaml.UnmarshalStrict([]byte(value), &policy.Tolerations); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", TolerationsKey, err)
+		}
+	}
+	if value := data[AffinityKey]; value != "" {
+		if err := yaml.UnmarshalStrict([]byte(value), &policy.Affinity); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", AffinityKey, err)
+		}
+	}
+	if value := data[SeccompProfileKey]; value != "" {
+		if err := yaml.UnmarshalStrict([]byte(value), &policy.SeccompProfile); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", SeccompProfileKey, err)
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Validate checks the values of the policy the API server would otherwise reject when creating the pods
+func (p *Policy) Validate() error {
+	var errs []string
+	for i, toleration := range p.Tolerations {
+		switch toleration.Operator {
+		case corev1.TolerationOpExists:
+			if toleration.Value != "" {
+				errs = append(errs, fmt.Sprintf("%s[%d] must not have a value with the Exists operator", TolerationsKey, i))
+			}
+		case corev1.TolerationOpEqual, "":
+			if toleration.Key == "" {
+				errs = append(errs, fmt.Sprintf("%s[%d] requires a key with the Equal operator", TolerationsKey, i))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("%s[%d] has an unknown operator %q", TolerationsKey, i, toleration.Operator))
+		}
+	}
+	if p.RuntimeClassName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(p.RuntimeClassName) {
+			errs = append(errs, fmt.Sprintf("%s %q is invalid: %s", RuntimeClassNameKey, p.RuntimeClassName, msg))
+		}
+	}
+	if p.SeccompProfile != nil {
+		switch p.SeccompProfile.Type {
+		case corev1.SeccompProfileTypeRuntimeDefault, corev1.SeccompProfileTypeUnconfined:
+			if p.SeccompProfile.LocalhostProfile != nil {
+				errs = append(errs, fmt.Sprintf("%s of type %s must not have a localhostProfile", SeccompProfileKey,
+					p.SeccompProfile.Type))
+			}
+		case corev1.SeccompProfileTypeLocalhost:
+			if p.SeccompProfile.LocalhostProfile == nil || *p.SeccompProfile.LocalhostProfile == "" {
+				errs = append(errs, fmt.Sprintf("%s of type %s requires a localhostProfile", SeccompProfileKey,
+					p.SeccompProfile.Type))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("%s has an unknown type %q", SeccompProfileKey, p.SeccompProfile.Type))
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Apply sets the policy on the pod spec. The tolerations are added to those of the pod spec, and the seccomp profile
+// is set at the pod level, the containers setting their own keeping it. A nil policy leaves the pod spec unchanged.
+func (p *Policy) Apply(spec *corev1.PodSpec) {
+	if p == nil {
+		return
+	}
+	for _, toleration := range p.Tolerations {
+		if !hasToleration(spec.Tolerations, toleration) {
+			spec.Tolerations = append(spec.Tolerations, toleration)
+		}
+	}
+	if p.Affinity != nil {
+		spec.Affinity = p.Affinity.DeepCopy()
+	}
+	if p.RuntimeClassName != "" {
+		runtimeClassName := p.RuntimeClassName
+		spec.RuntimeClassName = &runtimeClassName
+	}
+	if p.SeccompProfile != nil {
+		if spec.SecurityContext == nil {
+			spec.SecurityContext = &corev1.PodSecurityContext{}
+		}
+		spec.SecurityContext.SeccompProfile = p.SeccompProfile.DeepCopy()
+	}
+}
+
+func hasToleration(tolerations []corev1.Toleration, toleration corev1.Toleration) bool {
+	for i := range tolerations {
+		if equality.Semantic.DeepEqual(tolerations[i], toleration) {
+			return true
+		}
+	}
+	return false
+}
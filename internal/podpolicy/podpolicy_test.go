@@ -0,0 +1,150 @@
+package podpolicy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name        string
+		data        map[string]string
+		expected    *Policy
+		expectedErr string
+	}{
+		{
+			name:     "empty",
+			data:     map[string]string{},
+			expected: &Policy{},
+		},
+		{
+			name: "full",
+			data: map[string]string{
+				TolerationsKey: "- key: node-role.kubernetes.io/master\n  operator: Exists\n  effect: NoSchedule\n" +
+					"- key: dedicated\n  value: ran\n  effect: NoExecute\n",
+				AffinityKey: "nodeAffinity:\n  requiredDuringSchedulingIgnoredDuringExecution:\n    nodeSelectorTerms:\n" +
+					"    - matchExpressions:\n      - key: kubernetes.io/os\n        operator: In\n        values: [linux]\n",
+				RuntimeClassNameKey: "performance-ran\n",
+				SeccompProfileKey:   "type: RuntimeDefault\n",
+			},
+			expected: &Policy{
+				Tolerations: []corev1.Toleration{
+					{Key: "node-role.kubernetes.io/master", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+					{Key: "dedicated", Value: "ran", Effect: corev1.TaintEffectNoExecute},
+				},
+				Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "kubernetes.io/os", Operator: corev1.NodeSelectorOpIn, Values: []string{"linux"}},
+						},
+					}}},
+				}},
+				RuntimeClassName: "performance-ran",
+				SeccompProfile:   &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			},
+		},
+		{
+			name:        "unknown toleration field",
+			data:        map[string]string{TolerationsKey: "- key: dedicated\n  effects: NoSchedule\n"},
+			expectedErr: `failed to parse tolerations`,
+		},
+		{
+			name: "invalid values",
+			data: map[string]string{
+				TolerationsKey:      "- key: dedicated\n  operator: Exists\n  value: ran\n- operator: Equal\n- operator: In\n",
+				RuntimeClassNameKey: "Performance_RAN",
+				SeccompProfileKey:   "type: Localhost\n",
+			},
+			expectedErr: "tolerations[0] must not have a value with the Exists operator, " +
+				"tolerations[1] requires a key with the Equal operator, tolerations[2] has an unknown operator \"In\", " +
+				`runtimeClassName "Performance_RAN" is invalid`,
+		},
+		{
+			name:        "localhost seccomp profile without path",
+			data:        map[string]string{SeccompProfileKey: "type: Localhost\n"},
+			expectedErr: "seccompProfile of type Localhost requires a localhostProfile",
+		},
+		{
+			name:        "unknown seccomp profile type",
+			data:        map[string]string{SeccompProfileKey: "type: Default\n"},
+			expectedErr: `seccompProfile has an unknown type "Default"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := Parse(tc.data)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, policy)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	policy, err := Load(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+
+	c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: common.LcaNamespace},
+		Data:       map[string]string{RuntimeClassNameKey: "performance-ran"},
+	}).Build()
+	policy, err = Load(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, &Policy{RuntimeClassName: "performance-ran"}, policy)
+
+	c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: common.LcaNamespace},
+		Data:       map[string]string{SeccompProfileKey: "type: Default"},
+	}).Build()
+	_, err = Load(context.Background(), c)
+	assert.ErrorContains(t, err, "invalid pod policy configmap lca-pod-policy")
+}
+
+func TestApply(t *testing.T) {
+	existing := corev1.Toleration{Key: "dedicated", Value: "ran", Effect: corev1.TaintEffectNoExecute}
+	added := corev1.Toleration{Key: "node-role.kubernetes.io/master", Operator: corev1.TolerationOpExists}
+	affinity := &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{}}
+	policy := &Policy{
+		Tolerations:      []corev1.Toleration{existing, added},
+		Affinity:         affinity,
+		RuntimeClassName: "performance-ran",
+		SeccompProfile:   &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+
+	spec := &corev1.PodSpec{
+		Tolerations:     []corev1.Toleration{existing},
+		SecurityContext: &corev1.PodSecurityContext{RunAsUser: lo.ToPtr(int64(0))},
+	}
+	policy.Apply(spec)
+	assert.Equal(t, &corev1.PodSpec{
+		Tolerations:      []corev1.Toleration{existing, added},
+		Affinity:         affinity,
+		RuntimeClassName: lo.ToPtr("performance-ran"),
+		SecurityContext: &corev1.PodSecurityContext{RunAsUser: lo.ToPtr(int64(0)),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}},
+	}, spec)
+
+	// The pod spec does not share the values of the policy
+	spec.Affinity.PodAntiAffinity = nil
+	assert.NotNil(t, policy.Affinity.PodAntiAffinity)
+
+	// A nil policy changes nothing
+	spec = &corev1.PodSpec{}
+	(*Policy)(nil).Apply(spec)
+	assert.Equal(t, &corev1.PodSpec{}, spec)
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+// Cache records the images successfully pre-cached by previous precaching attempts of the same seed image digest
+// and image list, so that a retry, e.g. a Prep run again after an abort, only needs to pull the remaining images
+type Cache struct {
+	SeedImage string `json:"seed_image"`
+	// SeedDigest and ImageListHash identify the precaching the record belongs to
+	SeedDigest    string            `json:"seed_digest"`
+	ImageListHash string            `json:"image_list_hash"`
+	Images        map[string]string `json:"images"` // image -> digest
+	// ImageList is the whole list of images to precache, those pre-cached by previous attempts included, verified
+	// before the upgrade
+	ImageList []string `json:"image_list,omitempty"`
+	mux       sync.Mutex
+}
+
+// LoadCache reads the precache record from the given file, returning nil if the file does not exist
+func LoadCache(filename string) (*Cache, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read precache record %s: %w", filename, err)
+	}
+
+	cache := &Cache{}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("failed to parse precache record %s: %w", filename, err)
+	}
+	if cache.Images == nil {
+		cache.Images = make(map[string]string)
+	}
+	return cache, nil
+}
+
+// Record marks the image as successfully pre-cached with the given digest
+func (c *Cache) Record(image, digest string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.Images == nil {
+		c.Images = make(map[string]string)
+	}
+	c.Images[image] = digest
+}
+
+// Persist writes the precache record to the given file
+func (c *Cache) Persist(filename string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal precache record: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write precache record %s: %w", filename, err)
+	}
+	return nil
+}
+
+// PrepareCache loads the precache record from the given file and returns the images from imageList that were not
+// already pre-cached by a previous attempt. The record is reset if it belongs to a different seed image digest or
+// image list, and is persisted with imageList so the precaching workload can append to it. The images recorded as
+// pre-cached are only skipped while in the container storage of the host with their recorded digest, e.g. not
+// removed by the image garbage collection of the kubelet since.
+func PrepareCache(ctx context.Context, executor ops.Execute, filename, seedImage, seedDigest string,
+	imageList []string) ([]string, error) {
+	cache, err := LoadCache(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := imageListHash(imageList)
+	if cache == nil || seedDigest == "" || cache.SeedDigest != seedDigest || cache.ImageListHash != hash {
+		cache = &Cache{
+			SeedDigest:    seedDigest,
+			ImageListHash: hash,
+			Images:        make(map[string]string),
+		}
+	}
+
+	cache.SeedImage = seedImage
+	cache.ImageList = imageList
+
+	var stored map[string]*storedImage
+	for _, digest := range cache.Images {
+		if digest != "" {
+			if stored, err = listStoredImages(ctx, executor); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	remaining := make([]string, 0, len(imageList))
+	for _, image := range imageList {
+		if digest := cache.Images[image]; digest != "" {
+			if stored, found := stored[image]; found && (strings.Contains(image, "@") || stored.hasDigest(digest)) {
+				continue
+			}
+			// removed from the container storage since, or the tag was pulled again with another digest
+			delete(cache.Images, image)
+		}
+		remaining = append(remaining, image)
+	}
+
+	if err := cache.Persist(filename); err != nil {
+		return nil, err
+	}
+
+	return remaining, nil
+}
+
+// imageListHash returns the hash of the images of the list, whatever their order
+func imageListHash(imageList []string) string {
+	sorted := append([]string{}, imageList...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+func TestPrepareCache(t *testing.T) {
+	imageList := []string{"quay.io/image1:latest", "quay.io/image2:latest", "quay.io/image3@sha256:ccc"}
+	seedImage, seedDigest := "quay.io/seed:4.15", "sha256:5eed"
+	hash := imageListHash(imageList)
+	stored := `[{"Names": ["quay.io/image1:latest"], "Digest": "sha256:aaa"},
+		{"RepoDigests": ["quay.io/image3@sha256:ccc"], "Digest": "sha256:ccc"}]`
+
+	testCases := []struct {
+		name              string
+		previous          *Cache
+		seedDigest        string
+		stored            string
+		expectedRemaining []string
+		expectedCached    int
+	}{
+		{
+			name:              "no previous record",
+			previous:          nil,
+			seedDigest:        seedDigest,
+			expectedRemaining: imageList,
+			expectedCached:    0,
+		},
+		{
+			name: "previous record for same seed digest and image list, e.g. before an abort",
+			previous: &Cache{
+				SeedDigest:    seedDigest,
+				ImageListHash: hash,
+				Images:        map[string]string{"quay.io/image1:latest": "sha256:aaa", "quay.io/image3@sha256:ccc": "sha256:ccc"},
+			},
+			seedDigest:        seedDigest,
+			stored:            stored,
+			expectedRemaining: []string{"quay.io/image2:latest"},
+			expectedCached:    2,
+		},
+		{
+			name: "recorded images removed from the container storage or pulled again with another digest",
+			previous: &Cache{
+				SeedDigest:    seedDigest,
+				ImageListHash: hash,
+				Images:        map[string]string{"quay.io/image1:latest": "sha256:bbb", "quay.io/image3@sha256:ccc": "sha256:ccc"},
+			},
+			seedDigest:        seedDigest,
+			stored:            "[]",
+			expectedRemaining: imageList,
+			expectedCached:    0,
+		},
+		{
+			name: "previous record for another image list",
+			previous: &Cache{
+				SeedDigest:    seedDigest,
+				ImageListHash: imageListHash(imageList[:2]),
+				Images:        map[string]string{"quay.io/image1:latest": "sha256:aaa"},
+			},
+			seedDigest:        seedDigest,
+			expectedRemaining: imageList,
+			expectedCached:    0,
+		},
+		{
+			name: "previous record for another seed digest",
+			previous: &Cache{
+				SeedDigest:    "sha256:0ther",
+				ImageListHash: hash,
+				Images:        map[string]string{"quay.io/image1:latest": "sha256:aaa"},
+			},
+			seedDigest:        seedDigest,
+			expectedRemaining: imageList,
+			expectedCached:    0,
+		},
+		{
+			name: "images recorded without digest are pulled again",
+			previous: &Cache{
+				SeedDigest:    seedDigest,
+				ImageListHash: hash,
+				Images:        map[string]string{"quay.io/image1:latest": ""},
+			},
+			seedDigest:        seedDigest,
+			expectedRemaining: imageList,
+			expectedCached:    1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			executor := ops.NewMockExecute(gomock.NewController(t))
+			if tc.stored != "" {
+				executor.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "images", "--format", "json").
+					Return(tc.stored, nil)
+			}
+			cacheFile := filepath.Join(t.TempDir(), "precache_cache.json")
+			if tc.previous != nil {
+				assert.NoError(t, tc.previous.Persist(cacheFile))
+			}
+
+			remaining, err := PrepareCache(context.Background(), executor, cacheFile, seedImage, tc.seedDigest, imageList)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRemaining, remaining)
+
+			// The record is persisted for the workload to append to
+			cache, err := LoadCache(cacheFile)
+			assert.NoError(t, err)
+			assert.NotNil(t, cache)
+			assert.Equal(t, tc.seedDigest, cache.SeedDigest)
+			assert.Equal(t, hash, cache.ImageListHash)
+			assert.Equal(t, seedImage, cache.SeedImage)
+			assert.Equal(t, tc.expectedCached, len(cache.Images))
+			assert.Equal(t, imageList, cache.ImageList)
+		})
+	}
+}
+
+func TestImageListHash(t *testing.T) {
+	assert.Equal(t, imageListHash([]string{"quay.io/a:1", "quay.io/b:1"}), imageListHash([]string{"quay.io/b:1", "quay.io/a:1"}))
+	assert.NotEqual(t, imageListHash([]string{"quay.io/a:1", "quay.io/b:1"}), imageListHash([]string{"quay.io/a:1"}))
+}
+
+func TestLoadCacheMissingFile(t *testing.T) {
+	cache, err := LoadCache(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err)
+	assert.Nil(t, cache)
+}
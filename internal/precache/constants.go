@@ -18,8 +18,10 @@ package precache
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -28,6 +30,16 @@ const (
 	LcaPrecacheServiceAccount string = "lifecycle-agent-controller-manager"
 	LcaPrecacheJobName        string = "lca-precache-job"
 	LcaPrecacheConfigMapName  string = "lca-precache-cm"
+
+	LcaPrecacheNetworkPolicyName string = "lca-precache-egress"
+	PrecachePodLabelKey          string = "app.kubernetes.io/name"
+	PrecachePodLabelValue        string = "lifecycle-agent-precache"
+)
+
+// Egress restriction defaults
+const (
+	DefaultRegistryPort int    = 443
+	DockerHubRegistry   string = "docker.io"
 )
 
 // Image paths
@@ -39,12 +51,29 @@ const (
 // StatusFile is the filename for persisting the precaching progress tracker
 const StatusFile = utils.IBUWorkspacePath + "/precache_status.json"
 
+// CacheFile is the filename for persisting the images successfully pre-cached across precaching attempts. It is
+// outside of the IBU workspace, so that the Prep run again after an abort resumes from it
+const CacheFile = common.LCAConfigDir + "/precache_cache.json"
+
+// ConfigFile is the filename for persisting the config of the precaching job, to recreate the job if it is deleted
+const ConfigFile = utils.IBUWorkspacePath + "/precache_config.json"
+
 // Environment variable names
 const (
 	EnvLcaPrecacheImage   string = "PRECACHE_WORKLOAD_IMG"
 	EnvPrecacheSpecFile   string = "PRECACHE_SPEC_FILE"
 	EnvMaxPullThreads     string = "MAX_PULL_THREADS"
 	EnvPrecacheBestEffort string = "PRECACHE_BEST_EFFORT"
+	// EnvPullTimeout is the time limit in seconds of a single image pull
+	EnvPullTimeout string = "PULL_TIMEOUT"
+	// EnvOversizedImageThreshold is the size in bytes above which a pulled image is reported as oversized
+	EnvOversizedImageThreshold string = "OVERSIZED_IMAGE_THRESHOLD"
+	// EnvPartialPulls enables the partial pulls of the zstd:chunked layers when set to "TRUE"
+	EnvPartialPulls string = "PARTIAL_PULLS"
+	// EnvVerifyOnly makes the workload only report the images it would pull when set to "TRUE"
+	EnvVerifyOnly string = "VERIFY_ONLY"
+	// EnvMaxMbps caps the download bandwidth of the pulls, in megabits per second
+	EnvMaxMbps string = "MAX_MBPS"
 )
 
 // Precaching job specs
@@ -88,6 +117,14 @@ const (
 	DefaultNicePriority       int = 0
 	DefaultIoNiceClass            = IoNiceClassBestEffort
 	DefaultIoNicePriority     int = 4
+	// DefaultPullTimeout bounds the pull of an image, so a stalled pull does not consume the whole precaching window
+	DefaultPullTimeout = 30 * time.Minute
+	// DefaultOversizedImageThreshold is 2GiB, above which an image is better included in the seed image
+	DefaultOversizedImageThreshold int64 = 2 << 30
+	// DefaultRetryBackoff is the wait before the first retry of a failed image pull, doubled at each following retry
+	DefaultRetryBackoff = 10 * time.Second
+	// MaxRetryBackoff caps the wait between the retries of a failed image pull
+	MaxRetryBackoff = 5 * time.Minute
 )
 
 // Precache status
@@ -22,8 +22,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 
@@ -36,6 +38,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -153,6 +156,20 @@ func renderJob(config *Config, log logr.Logger) (*batchv1.Job, error) {
 		ioNicePriority = DefaultIoNicePriority
 	}
 
+	// Process pull timeout and oversized image threshold
+	pullTimeout := config.PullTimeout
+	if pullTimeout < time.Second {
+		log.Info("Precaching invalid configuration [pullTimeout], using default", "spec", pullTimeout,
+			"default", DefaultPullTimeout)
+		pullTimeout = DefaultPullTimeout
+	}
+	oversizedImageThreshold := config.OversizedImageThreshold
+	if oversizedImageThreshold < 1 {
+		log.Info("Precaching invalid configuration [oversizedImageThreshold], using default", "spec", oversizedImageThreshold,
+			"default", DefaultOversizedImageThreshold)
+		oversizedImageThreshold = DefaultOversizedImageThreshold
+	}
+
 	execPrecacheArgs := fmt.Sprintf("nice -n %d ionice -c %d -n %d precache",
 		nicePriority, ioNiceClass, ioNicePriority)
 
@@ -165,7 +182,41 @@ func renderJob(config *Config, log logr.Logger) (*batchv1.Job, error) {
 			Name:  EnvMaxPullThreads,
 			Value: strconv.Itoa(numConcurrentPulls),
 		},
+		{
+			Name:  EnvPullTimeout,
+			Value: strconv.Itoa(int(pullTimeout.Seconds())),
+		},
+		{
+			Name:  EnvOversizedImageThreshold,
+			Value: strconv.FormatInt(oversizedImageThreshold, 10),
+		},
 	}...)
+	if config.PartialPulls {
+		precacheEnvVars = append(precacheEnvVars, corev1.EnvVar{Name: EnvPartialPulls, Value: "TRUE"})
+	}
+	if config.VerifyOnly {
+		precacheEnvVars = append(precacheEnvVars, corev1.EnvVar{Name: EnvVerifyOnly, Value: "TRUE"})
+	}
+	if config.MaxMbps > 0 {
+		precacheEnvVars = append(precacheEnvVars, corev1.EnvVar{Name: EnvMaxMbps, Value: strconv.Itoa(config.MaxMbps)})
+	}
+	if config.BestEffort {
+		precacheEnvVars = append(precacheEnvVars, corev1.EnvVar{Name: EnvPrecacheBestEffort, Value: "TRUE"})
+	}
+	if config.ArtifactSource != nil {
+		artifactSourceEnvVars, err := config.ArtifactSource.EnvVars()
+		if err != nil {
+			return nil, err
+		}
+		precacheEnvVars = append(precacheEnvVars, artifactSourceEnvVars...)
+	}
+	if config.RegistryTLS != nil {
+		registryTLSEnvVars, err := config.RegistryTLS.EnvVars()
+		if err != nil {
+			return nil, err
+		}
+		precacheEnvVars = append(precacheEnvVars, registryTLSEnvVars...)
+	}
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -177,8 +228,22 @@ func renderJob(config *Config, log logr.Logger) (*batchv1.Job, error) {
 		},
 		Spec: batchv1.JobSpec{
 			BackoffLimit: &backOffLimit,
+			// A pod evicted, preempted or stopped by a node shutdown is replaced rather than failing the job, the new
+			// pod resuming from the images recorded in CacheFile
+			PodFailurePolicy: &batchv1.PodFailurePolicy{
+				Rules: []batchv1.PodFailurePolicyRule{{
+					Action: batchv1.PodFailurePolicyActionIgnore,
+					OnPodConditions: []batchv1.PodFailurePolicyOnPodConditionsPattern{{
+						Type:   corev1.DisruptionTarget,
+						Status: corev1.ConditionTrue,
+					}},
+				}},
+			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						PrecachePodLabelKey: PrecachePodLabelValue,
+					},
 					Annotations: map[string]string{
 						common.WorkloadManagementAnnotationKey: common.WorkloadManagementAnnotationValue,
 					},
@@ -206,12 +271,7 @@ func renderJob(config *Config, log logr.Logger) (*batchv1.Job, error) {
 									MountPath: PrecachingSpecFilepath,
 								},
 							},
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceCPU:    resource.MustParse(RequestResourceCPU),
-									corev1.ResourceMemory: resource.MustParse(RequestResourceMemory),
-								},
-							},
+							Resources: jobResources(config.Resources),
 						},
 					},
 					ServiceAccountName: LcaPrecacheServiceAccount,
@@ -242,10 +302,62 @@ func renderJob(config *Config, log logr.Logger) (*batchv1.Job, error) {
 			},
 		},
 	}
+	config.PodPolicy.Apply(&job.Spec.Template.Spec)
 
 	return job, nil
 }
 
+// ValidateResources checks the compute resources of the pre-caching job set in the IBU spec, rejecting a request
+// above the limit of its resource, which the job would be rejected for
+func ValidateResources(spec *corev1.ResourceRequirements) error {
+	if spec == nil {
+		return nil
+	}
+	var problems []string
+	for name, request := range spec.Requests {
+		if limit, ok := spec.Limits[name]; ok && request.Cmp(limit) > 0 {
+			problems = append(problems, fmt.Sprintf("%s request %s is above its limit %s", name, request.String(),
+				limit.String()))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("invalid precaching job resources: %s", strings.Join(problems, ", "))
+}
+
+// jobResources returns the compute resources of the pre-caching job, the default requests overridden by the requests
+// of the spec, and the limits of the spec. A default request above the limit of its resource is lowered to the limit,
+// as the job would be rejected otherwise.
+func jobResources(spec *corev1.ResourceRequirements) corev1.ResourceRequirements {
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(RequestResourceCPU),
+			corev1.ResourceMemory: resource.MustParse(RequestResourceMemory),
+		},
+	}
+	if spec == nil {
+		return resources
+	}
+
+	for name, quantity := range spec.Requests {
+		resources.Requests[name] = quantity.DeepCopy()
+	}
+	if len(spec.Limits) != 0 {
+		resources.Limits = spec.Limits.DeepCopy()
+	}
+	for name, limit := range resources.Limits {
+		if _, ok := spec.Requests[name]; ok {
+			continue
+		}
+		if request, ok := resources.Requests[name]; ok && request.Cmp(limit) > 0 {
+			resources.Requests[name] = limit.DeepCopy()
+		}
+	}
+	return resources
+}
+
 func generateDeleteOptions() *client.DeleteOptions {
 	propagationPolicy := metav1.DeletePropagationBackground
 
@@ -282,23 +394,61 @@ func deleteConfigMap(ctx context.Context, c client.Client, name, namespace strin
 	return nil
 }
 
+// jobTerminationTimeout bounds the wait for the precaching job and its pods to be deleted, polled every
+// jobTerminationInterval, overridden in tests
+var (
+	jobTerminationTimeout  = 2 * time.Minute
+	jobTerminationInterval = 2 * time.Second
+)
+
+// deleteJob deletes the job with a foreground propagation, so that its pods are deleted first, and waits for them and
+// the job to be gone, so that no pod keeps pulling images once the job is reported deleted
 func deleteJob(ctx context.Context, c client.Client, name, namespace string) error {
-	// Delete the Job
 	job, err := getJob(ctx, c, name, namespace)
 	if err != nil {
 		return err
 	}
 
-	if job == nil {
-		return nil
+	if job != nil && job.DeletionTimestamp == nil {
+		if err := c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationForeground)); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete job: %w", err)
+			}
+		}
 	}
 
-	if err := c.Delete(ctx, job, generateDeleteOptions()); err != nil {
-		if k8serrors.IsNotFound(err) {
-			return nil
+	return waitForJobTermination(ctx, c, name, namespace)
+}
+
+// waitForJobTermination waits for the job and its pods to be deleted, up to jobTerminationTimeout
+func waitForJobTermination(ctx context.Context, c client.Client, name, namespace string) error {
+	var remaining string
+	err := wait.PollUntilContextTimeout(ctx, jobTerminationInterval, jobTerminationTimeout, true,
+		func(ctx context.Context) (bool, error) {
+			job, err := getJob(ctx, c, name, namespace)
+			if err != nil {
+				return false, err
+			}
+			if job != nil {
+				remaining = "job " + name
+				return false, nil
+			}
+			pods := &corev1.PodList{}
+			if err := c.List(ctx, pods, client.InNamespace(namespace),
+				client.MatchingLabels{PrecachePodLabelKey: PrecachePodLabelValue}); err != nil {
+				return false, fmt.Errorf("failed to list precaching job pods: %w", err)
+			}
+			if len(pods.Items) != 0 {
+				remaining = fmt.Sprintf("%d pods of job %s", len(pods.Items), name)
+				return false, nil
+			}
+			return true, nil
+		})
+	if err != nil {
+		if remaining != "" && wait.Interrupted(err) {
+			return fmt.Errorf("%s still terminating after %s: %w", remaining, jobTerminationTimeout, err)
 		}
-		return fmt.Errorf("failed to job: %w", err)
+		return fmt.Errorf("failed to wait for the termination of job %s: %w", name, err)
 	}
-
 	return nil
 }
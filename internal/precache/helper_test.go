@@ -25,14 +25,17 @@ import (
 	"sort"
 	"strconv"
 	"testing"
+	"time"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/podpolicy"
 
 	"github.com/stretchr/testify/assert"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/diff"
@@ -194,8 +197,20 @@ func getExpectedBaseJob() *batchv1.Job {
 		},
 		Spec: batchv1.JobSpec{
 			BackoffLimit: &backOffLimit,
+			PodFailurePolicy: &batchv1.PodFailurePolicy{
+				Rules: []batchv1.PodFailurePolicyRule{{
+					Action: batchv1.PodFailurePolicyActionIgnore,
+					OnPodConditions: []batchv1.PodFailurePolicyOnPodConditionsPattern{{
+						Type:   corev1.DisruptionTarget,
+						Status: corev1.ConditionTrue,
+					}},
+				}},
+			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						PrecachePodLabelKey: PrecachePodLabelValue,
+					},
 					Annotations: map[string]string{
 						common.WorkloadManagementAnnotationKey: common.WorkloadManagementAnnotationValue,
 					},
@@ -274,8 +289,10 @@ func TestRenderJob(t *testing.T) {
 		expectedEnvVars []corev1.EnvVar
 	}{
 		{
-			name:          "Fully specified, valid precaching config",
-			config:        NewConfig([]string{}, []corev1.EnvVar{}, "NumConcurrentPulls", 1, "NicePriority", 1, "IoNiceClass", IoNiceClassRealTime, "IoNicePriority", 5),
+			name: "Fully specified, valid precaching config",
+			config: NewConfig([]string{}, []corev1.EnvVar{}, "NumConcurrentPulls", 1, "NicePriority", 1, "IoNiceClass", IoNiceClassRealTime, "IoNicePriority", 5,
+				"PullTimeout", 10*time.Minute, "OversizedImageThreshold", int64(1<<30), "PartialPulls", true, "VerifyOnly", true, "MaxMbps", 50,
+				"BestEffort", true),
 			expectedError: nil,
 			expectedArgs:  []string{fmt.Sprintf("nice -n 1 ionice -c %d -n 5 precache", IoNiceClassRealTime)},
 			expectedEnvVars: []corev1.EnvVar{
@@ -283,11 +300,36 @@ func TestRenderJob(t *testing.T) {
 					Name:  EnvMaxPullThreads,
 					Value: "1",
 				},
+				{
+					Name:  EnvPullTimeout,
+					Value: "600",
+				},
+				{
+					Name:  EnvOversizedImageThreshold,
+					Value: "1073741824",
+				},
+				{
+					Name:  EnvPartialPulls,
+					Value: "TRUE",
+				},
+				{
+					Name:  EnvVerifyOnly,
+					Value: "TRUE",
+				},
+				{
+					Name:  EnvMaxMbps,
+					Value: "50",
+				},
+				{
+					Name:  EnvPrecacheBestEffort,
+					Value: "TRUE",
+				},
 			},
 		},
 		{
-			name:          "Partially specified, with some invalid precaching config",
-			config:        NewConfig([]string{}, []corev1.EnvVar{}, "NumConcurrentPulls", 10, "NicePriority", 100, "IoNiceClass", IoNiceClassRealTime),
+			name: "Partially specified, with some invalid precaching config",
+			config: NewConfig([]string{}, []corev1.EnvVar{}, "NumConcurrentPulls", 10, "NicePriority", 100, "IoNiceClass", IoNiceClassRealTime,
+				"PullTimeout", time.Duration(0), "OversizedImageThreshold", int64(-1)),
 			expectedError: nil,
 			expectedArgs: []string{fmt.Sprintf("nice -n %d ionice -c %d -n %d precache",
 				DefaultNicePriority, IoNiceClassRealTime, DefaultIoNicePriority)},
@@ -296,6 +338,14 @@ func TestRenderJob(t *testing.T) {
 					Name:  EnvMaxPullThreads,
 					Value: "10",
 				},
+				{
+					Name:  EnvPullTimeout,
+					Value: "1800",
+				},
+				{
+					Name:  EnvOversizedImageThreshold,
+					Value: strconv.FormatInt(DefaultOversizedImageThreshold, 10),
+				},
 			},
 		},
 		{
@@ -309,6 +359,14 @@ func TestRenderJob(t *testing.T) {
 					Name:  EnvMaxPullThreads,
 					Value: strconv.Itoa(DefaultMaxConcurrentPulls),
 				},
+				{
+					Name:  EnvPullTimeout,
+					Value: strconv.Itoa(int(DefaultPullTimeout.Seconds())),
+				},
+				{
+					Name:  EnvOversizedImageThreshold,
+					Value: strconv.FormatInt(DefaultOversizedImageThreshold, 10),
+				},
 			},
 		},
 	}
@@ -342,3 +400,75 @@ func TestRenderJob(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderJobPodPolicy(t *testing.T) {
+	toleration := corev1.Toleration{Key: "dedicated", Value: "ran", Effect: corev1.TaintEffectNoSchedule}
+	config := NewConfig([]string{}, []corev1.EnvVar{}, "PodPolicy", &podpolicy.Policy{
+		Tolerations:      []corev1.Toleration{toleration},
+		RuntimeClassName: "performance-ran",
+		SeccompProfile:   &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	})
+	job, err := renderJob(config, ctrl.Log.WithName("Precache"))
+	assert.NoError(t, err)
+	spec := job.Spec.Template.Spec
+	assert.Equal(t, []corev1.Toleration{toleration}, spec.Tolerations)
+	assert.Equal(t, "performance-ran", *spec.RuntimeClassName)
+	assert.Equal(t, corev1.SeccompProfileTypeRuntimeDefault, spec.SecurityContext.SeccompProfile.Type)
+	// The container keeps running privileged
+	assert.True(t, *spec.Containers[0].SecurityContext.Privileged)
+}
+
+func TestRenderJobResources(t *testing.T) {
+	testCases := []struct {
+		name     string
+		spec     *corev1.ResourceRequirements
+		expected corev1.ResourceRequirements
+	}{
+		{
+			name: "default",
+			expected: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("10m"), corev1.ResourceMemory: resource.MustParse("512Mi")}},
+		},
+		{
+			name: "requests and limits",
+			spec: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+			expected: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("512Mi")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+		},
+		{
+			name: "limit below the default request",
+			spec: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+			},
+			expected: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m"), corev1.ResourceMemory: resource.MustParse("256Mi")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			job, err := renderJob(NewConfig([]string{}, []corev1.EnvVar{}, "Resources", tc.spec), ctrl.Log.WithName("Precache"))
+			assert.NoError(t, err)
+			assert.True(t, equality.Semantic.DeepEqual(tc.expected, job.Spec.Template.Spec.Containers[0].Resources),
+				"resources %v", job.Spec.Template.Spec.Containers[0].Resources)
+		})
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	assert.NoError(t, ValidateResources(nil))
+	assert.NoError(t, ValidateResources(&corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}))
+	assert.EqualError(t, ValidateResources(&corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("2Gi")},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}), "invalid precaching job resources: cpu request 2 is above its limit 1, memory request 2Gi is above its limit 1Gi")
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/internal/common/collections"
+)
+
+// ImageListSource returns the source of the images to precache of the IBU, Seed when unset
+func ImageListSource(ibu *v1alpha1.ImageBasedUpgrade) v1alpha1.PrecacheImageListSource {
+	if ibu.Spec.Precache == nil || ibu.Spec.Precache.ImageListSource == "" {
+		return v1alpha1.PrecacheImageListSourceSeed
+	}
+	return ibu.Spec.Precache.ImageListSource
+}
+
+// BuildImageList returns the images to precache from the source: the images listed by the seed image, the images of
+// the operators installed on this cluster and the recert image, or both lists
+func BuildImageList(ctx context.Context, c client.Reader, source v1alpha1.PrecacheImageListSource, seedImages []string,
+	recertImage string) ([]string, error) {
+	if source == v1alpha1.PrecacheImageListSourceSeed {
+		return seedImages, nil
+	}
+	clusterImages, err := ClusterOperatorImages(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if recertImage != "" {
+		clusterImages = append(clusterImages, recertImage)
+	}
+	switch source {
+	case v1alpha1.PrecacheImageListSourceCluster:
+		return collections.Unique(clusterImages), nil
+	case v1alpha1.PrecacheImageListSourceMerged:
+		return collections.Union(seedImages, clusterImages), nil
+	default:
+		return nil, fmt.Errorf("unknown precache image list source %q", source)
+	}
+}
+
+// ParseImageList returns the images listed in the data of a config map, one image per line of each entry, in the
+// order of the entries' keys. Blank lines and lines starting with # are ignored, and images listed twice kept once.
+func ParseImageList(data map[string]string) ([]string, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	images := &collections.OrderedSet[string]{}
+	for _, key := range keys {
+		for i, line := range strings.Split(data[key], "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if strings.ContainsAny(line, " \t") {
+				return nil, fmt.Errorf("invalid image %q at line %d of %s, expecting one image per line", line, i+1, key)
+			}
+			images.Add(line)
+		}
+	}
+	return images.Items(), nil
+}
+
+// +kubebuilder:rbac:groups=operators.coreos.com,resources=clusterserviceversions,verbs=list;watch
+
+// ClusterOperatorImages returns the images of the operators installed on this cluster: the related images and the
+// images of the deployments of their ClusterServiceVersions. The copies of the ClusterServiceVersions in the target
+// namespaces of the operators are skipped.
+func ClusterOperatorImages(ctx context.Context, c client.Reader) ([]string, error) {
+	csvs := &operatorsv1alpha1.ClusterServiceVersionList{}
+	if err := c.List(ctx, csvs); err != nil {
+		return nil, fmt.Errorf("failed to list ClusterServiceVersions: %w", err)
+	}
+	images := &collections.OrderedSet[string]{}
+	for _, csv := range csvs.Items {
+		if csv.Status.Reason == operatorsv1alpha1.CSVReasonCopied {
+			continue
+		}
+		for _, relatedImage := range csv.Spec.RelatedImages {
+			images.Add(relatedImage.Image)
+		}
+		for _, deployment := range csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs {
+			for _, container := range deployment.Spec.Template.Spec.InitContainers {
+				images.Add(container.Image)
+			}
+			for _, container := range deployment.Spec.Template.Spec.Containers {
+				images.Add(container.Image)
+			}
+		}
+	}
+	images.Remove("")
+	return images.Items(), nil
+}
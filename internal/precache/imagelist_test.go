@@ -0,0 +1,120 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"testing"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+)
+
+func newCSV(name, namespace string, reason operatorsv1alpha1.ConditionReason, relatedImages []string,
+	containerImages ...string) *operatorsv1alpha1.ClusterServiceVersion {
+	csv := &operatorsv1alpha1.ClusterServiceVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status:     operatorsv1alpha1.ClusterServiceVersionStatus{Reason: reason},
+	}
+	for _, image := range relatedImages {
+		csv.Spec.RelatedImages = append(csv.Spec.RelatedImages, operatorsv1alpha1.RelatedImage{Image: image})
+	}
+	var containers []corev1.Container
+	for _, image := range containerImages {
+		containers = append(containers, corev1.Container{Image: image})
+	}
+	csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs = []operatorsv1alpha1.StrategyDeploymentSpec{{
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: containers}}},
+	}}
+	return csv
+}
+
+func TestBuildImageList(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, operatorsv1alpha1.AddToScheme(s))
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+		newCSV("sriov.v4.15", "openshift-sriov-network-operator", "",
+			[]string{"quay.io/sriov/operator:v4.15", "quay.io/sriov/config-daemon:v4.15"}, "quay.io/sriov/operator:v4.15"),
+		newCSV("lca.v4.15", "openshift-lifecycle-agent", "", nil, "quay.io/lca/operator:v4.15", ""),
+		// Copies of a CSV installed in all namespaces
+		newCSV("global.v1", "default", operatorsv1alpha1.CSVReasonCopied, []string{"quay.io/global/copied:v1"}),
+	).Build()
+
+	seedImages := []string{"quay.io/ocp/release:4.15", "quay.io/sriov/operator:v4.15"}
+	recertImage := "quay.io/recert:v0"
+	testcases := []struct {
+		source   v1alpha1.PrecacheImageListSource
+		expected []string
+	}{
+		{
+			source:   v1alpha1.PrecacheImageListSourceSeed,
+			expected: seedImages,
+		},
+		{
+			source: v1alpha1.PrecacheImageListSourceCluster,
+			expected: []string{"quay.io/lca/operator:v4.15", "quay.io/sriov/operator:v4.15",
+				"quay.io/sriov/config-daemon:v4.15", recertImage},
+		},
+		{
+			source: v1alpha1.PrecacheImageListSourceMerged,
+			expected: []string{"quay.io/ocp/release:4.15", "quay.io/sriov/operator:v4.15", "quay.io/lca/operator:v4.15",
+				"quay.io/sriov/config-daemon:v4.15", recertImage},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(string(tc.source), func(t *testing.T) {
+			imageList, err := BuildImageList(context.Background(), c, tc.source, seedImages, recertImage)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, imageList)
+		})
+	}
+
+	_, err := BuildImageList(context.Background(), c, "Operators", seedImages, recertImage)
+	assert.ErrorContains(t, err, `unknown precache image list source "Operators"`)
+}
+
+func TestImageListSource(t *testing.T) {
+	ibu := &v1alpha1.ImageBasedUpgrade{}
+	assert.Equal(t, v1alpha1.PrecacheImageListSourceSeed, ImageListSource(ibu))
+	ibu.Spec.Precache = &v1alpha1.PrecacheConfig{}
+	assert.Equal(t, v1alpha1.PrecacheImageListSourceSeed, ImageListSource(ibu))
+	ibu.Spec.Precache.ImageListSource = v1alpha1.PrecacheImageListSourceMerged
+	assert.Equal(t, v1alpha1.PrecacheImageListSourceMerged, ImageListSource(ibu))
+}
+
+func TestParseImageList(t *testing.T) {
+	images, err := ParseImageList(map[string]string{
+		"b": "  quay.io/b:v1\r\n# comment\n\nquay.io/a:v1\n",
+		"a": "quay.io/a:v1\n",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"quay.io/a:v1", "quay.io/b:v1"}, images)
+
+	images, err = ParseImageList(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, images)
+
+	_, err = ParseImageList(map[string]string{"images": "quay.io/a:v1\nquay.io/b:v1 quay.io/c:v1"})
+	assert.EqualError(t, err, `invalid image "quay.io/b:v1 quay.io/c:v1" at line 2 of images, expecting one image per line`)
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=list;watch
+
+// Job controller event reason when it fails to create the job pod, e.g. when exceeding a ResourceQuota
+const jobFailedCreateReason = "FailedCreate"
+
+// getJobInterference returns why the precaching job cannot make progress, if its pod cannot be created because of a
+// ResourceQuota, cannot be scheduled, e.g. because of node pressure or insufficient resources, or was evicted or
+// preempted. It returns an empty string if no interference is found.
+func getJobInterference(ctx context.Context, c client.Client, job *batchv1.Job) (string, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(job.Namespace),
+		client.MatchingLabels{PrecachePodLabelKey: PrecachePodLabelValue}); err != nil {
+		return "", fmt.Errorf("failed to list precaching job pods: %w", err)
+	}
+
+	// A disrupted pod replaced by a pending or running one no longer blocks the job, which resumes the precaching
+	replaced := false
+	for i := range pods.Items {
+		if phase := pods.Items[i].Status.Phase; phase == corev1.PodPending || phase == corev1.PodRunning {
+			replaced = true
+		}
+	}
+	for i := range pods.Items {
+		if replaced && pods.Items[i].Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if msg := getPodInterference(&pods.Items[i]); msg != "" {
+			return msg, nil
+		}
+	}
+
+	if len(pods.Items) > 0 {
+		return "", nil
+	}
+
+	// No pod, look for the job controller failing to create it
+	events := &corev1.EventList{}
+	if err := c.List(ctx, events, client.InNamespace(job.Namespace)); err != nil {
+		return "", fmt.Errorf("failed to list precaching job events: %w", err)
+	}
+	var latest *corev1.Event
+	for i := range events.Items {
+		event := &events.Items[i]
+		if event.InvolvedObject.Kind != "Job" || event.InvolvedObject.Name != job.Name ||
+			event.InvolvedObject.UID != job.UID || event.Reason != jobFailedCreateReason {
+			continue
+		}
+		if latest == nil || latest.LastTimestamp.Before(&event.LastTimestamp) {
+			latest = event
+		}
+	}
+	if latest != nil {
+		return fmt.Sprintf("precaching pod cannot be created: %s", latest.Message), nil
+	}
+	return "", nil
+}
+
+func getPodInterference(pod *corev1.Pod) string {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.DisruptionTarget && condition.Status == corev1.ConditionTrue {
+			return fmt.Sprintf("precaching pod %s was disrupted (%s): %s", pod.Name, condition.Reason, condition.Message)
+		}
+	}
+
+	if pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == "Evicted" {
+		return fmt.Sprintf("precaching pod %s was evicted: %s", pod.Name, pod.Status.Message)
+	}
+
+	if pod.Status.Phase == corev1.PodPending {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+				return fmt.Sprintf("precaching pod %s cannot be scheduled (%s): %s", pod.Name, condition.Reason, condition.Message)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Precaching metrics, exported by the metrics endpoint of the manager for the dashboards and alerts on the upgrade
+// preparation of a fleet. They are derived from the progress of the precaching observed by the controller, the job
+// running in its own pod.
+var (
+	imagesMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lca_precache_images_total",
+		Help: "Number of images processed by precaching, by result: pulled, failed or skipped.",
+	}, []string{"result"})
+	pulledBytesMetric = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "lca_precache_pulled_bytes_total",
+		Help: "Size in bytes of the images pulled by precaching, in the container storage.",
+	})
+	durationMetric = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "lca_precache_duration_seconds",
+		Help: "Duration of the completed precaching, by status: Succeeded or Failed.",
+		// From a minute to about eight hours
+		Buckets: prometheus.ExponentialBuckets(60, 2, 10),
+	}, []string{"status"})
+	phaseMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lca_precache_phase",
+		Help: "Current phase of precaching, 1 for the current one of Active, Succeeded or Failed, 0 for the others.",
+	}, []string{"phase"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(imagesMetric, pulledBytesMetric, durationMetric, phaseMetric)
+}
+
+// precachePhases are the values of the phase label of phaseMetric
+var precachePhases = []string{Active, Succeeded, Failed}
+
+// progressCounts are the counters of a precaching progress exported as metrics
+type progressCounts struct {
+	pulled, failed, skipped int
+	pulledBytes             int64
+}
+
+// metricsRecorder turns the snapshots of the precaching progress into the increments of the metrics counters, as
+// the same progress is observed at each status query
+type metricsRecorder struct {
+	mu sync.Mutex
+	// processStart tells apart the runs started before the manager, counted by the previous manager process
+	processStart time.Time
+	// startTime identifies the observed run, last its counters already added to the metrics
+	startTime  time.Time
+	last       progressCounts
+	seenActive bool
+	completed  bool
+}
+
+var precacheMetrics = &metricsRecorder{processStart: time.Now()}
+
+// observe updates the metrics with the status of the precaching at the given time
+func (m *metricsRecorder) observe(status *Status, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := progressCounts{
+		pulled:      status.Progress.Pulled,
+		failed:      status.Progress.Failed,
+		skipped:     status.Progress.Skipped,
+		pulledBytes: status.Progress.PulledBytes,
+	}
+	if !status.StartTime.Equal(m.startTime) {
+		m.startTime = status.StartTime
+		m.last = progressCounts{}
+		m.seenActive, m.completed = false, false
+		if status.StartTime.Before(m.processStart) {
+			m.last = current
+		}
+	}
+
+	// A new pod of the job restarts its progress, its counters are then added from zero
+	add := func(result string, current, last int) {
+		if current > last {
+			imagesMetric.WithLabelValues(result).Add(float64(current - last))
+		} else if current < last {
+			imagesMetric.WithLabelValues(result).Add(float64(current))
+		}
+	}
+	if !status.Progress.VerifyOnly {
+		add("pulled", current.pulled, m.last.pulled)
+		add("failed", current.failed, m.last.failed)
+		add("skipped", current.skipped, m.last.skipped)
+		if current.pulledBytes > m.last.pulledBytes {
+			pulledBytesMetric.Add(float64(current.pulledBytes - m.last.pulledBytes))
+		} else if current.pulledBytes < m.last.pulledBytes {
+			pulledBytesMetric.Add(float64(current.pulledBytes))
+		}
+	}
+	m.last = current
+
+	for _, phase := range precachePhases {
+		value := 0.0
+		if phase == status.Status {
+			value = 1
+		}
+		phaseMetric.WithLabelValues(phase).Set(value)
+	}
+
+	// Only the runs seen in progress have their duration observed, once, e.g. not one completed before a restart
+	switch status.Status {
+	case Active:
+		m.seenActive = true
+	case Succeeded, Failed:
+		if m.seenActive && !m.completed && !status.StartTime.IsZero() {
+			durationMetric.WithLabelValues(status.Status).Observe(now.Sub(status.StartTime).Seconds())
+			m.completed = true
+		}
+	}
+}
+
+// reset clears the phase once the precaching resources are cleaned up
+func (m *metricsRecorder) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.startTime = time.Time{}
+	m.last = progressCounts{}
+	m.seenActive, m.completed = false, false
+	for _, phase := range precachePhases {
+		phaseMetric.WithLabelValues(phase).Set(0)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func metricValue(t *testing.T, collector prometheus.Collector) float64 {
+	ch := make(chan prometheus.Metric, 1)
+	collector.Collect(ch)
+	m := &dto.Metric{}
+	assert.NoError(t, (<-ch).Write(m))
+	switch {
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	case m.Histogram != nil:
+		return float64(m.Histogram.GetSampleCount())
+	}
+	return 0
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	now := time.Now()
+	recorder := &metricsRecorder{processStart: now.Add(-time.Hour)}
+	pulled, failed := imagesMetric.WithLabelValues("pulled"), imagesMetric.WithLabelValues("failed")
+	succeeded := durationMetric.WithLabelValues(Succeeded).(prometheus.Histogram)
+	basePulled, baseFailed := metricValue(t, pulled), metricValue(t, failed)
+	baseBytes, baseSucceeded := metricValue(t, pulledBytesMetric), metricValue(t, succeeded)
+
+	start := now.Add(-10 * time.Minute)
+	status := &Status{Status: Active, StartTime: start, Progress: Progress{Total: 10, Pulled: 3, PulledBytes: 300}}
+	recorder.observe(status, now)
+	// The same progress observed again is not counted twice
+	recorder.observe(status, now)
+	assert.Equal(t, basePulled+3, metricValue(t, pulled))
+	assert.Equal(t, baseBytes+300, metricValue(t, pulledBytesMetric))
+	assert.Equal(t, 1.0, metricValue(t, phaseMetric.WithLabelValues(Active)))
+
+	recorder.observe(&Status{Status: Succeeded, StartTime: start,
+		Progress: Progress{Total: 10, Pulled: 9, Failed: 1, PulledBytes: 900}}, now)
+	assert.Equal(t, basePulled+9, metricValue(t, pulled))
+	assert.Equal(t, baseFailed+1, metricValue(t, failed))
+	assert.Equal(t, baseBytes+900, metricValue(t, pulledBytesMetric))
+	assert.Equal(t, 0.0, metricValue(t, phaseMetric.WithLabelValues(Active)))
+	assert.Equal(t, 1.0, metricValue(t, phaseMetric.WithLabelValues(Succeeded)))
+	assert.Equal(t, baseSucceeded+1, metricValue(t, succeeded))
+
+	// The duration of a completed run is observed once
+	recorder.observe(&Status{Status: Succeeded, StartTime: start,
+		Progress: Progress{Total: 10, Pulled: 9, Failed: 1, PulledBytes: 900}}, now)
+	assert.Equal(t, baseSucceeded+1, metricValue(t, succeeded))
+
+	// A run started before the manager was counted by the previous manager process
+	recorder.observe(&Status{Status: Succeeded, StartTime: now.Add(-2 * time.Hour),
+		Progress: Progress{Total: 5, Pulled: 5, PulledBytes: 500}}, now)
+	assert.Equal(t, basePulled+9, metricValue(t, pulled))
+	assert.Equal(t, baseSucceeded+1, metricValue(t, succeeded))
+
+	recorder.reset()
+	assert.Equal(t, 0.0, metricValue(t, phaseMetric.WithLabelValues(Succeeded)))
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;delete
+
+// lookupIP resolves the egress endpoints, overridden in tests
+var lookupIP = net.LookupIP
+
+// egressEndpoint is a host and port the precaching job needs to reach
+type egressEndpoint struct {
+	host string
+	port int
+}
+
+// getEgressEndpoints returns the registries the images are pulled from, the additional allowed hosts, such as
+// mirror registries, and the proxies configured in the job environment
+func getEgressEndpoints(imageList, allowedHosts []string, envVars []corev1.EnvVar) ([]egressEndpoint, error) {
+	endpoints := map[egressEndpoint]bool{}
+
+	for _, host := range append(registriesFromImages(imageList), allowedHosts...) {
+		endpoint, err := parseHostPort(host, DefaultRegistryPort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid egress host %s: %w", host, err)
+		}
+		endpoints[endpoint] = true
+	}
+
+	for _, env := range envVars {
+		switch strings.ToUpper(env.Name) {
+		case "HTTP_PROXY", "HTTPS_PROXY":
+		default:
+			continue
+		}
+		if env.Value == "" {
+			continue
+		}
+		proxy, err := url.Parse(env.Value)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %s in %s", env.Value, env.Name)
+		}
+		defaultPort := 80
+		if proxy.Scheme == "https" {
+			defaultPort = 443
+		}
+		endpoint, err := parseHostPort(proxy.Host, defaultPort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s in %s: %w", env.Value, env.Name, err)
+		}
+		endpoints[endpoint] = true
+	}
+
+	result := make([]egressEndpoint, 0, len(endpoints))
+	for endpoint := range endpoints {
+		result = append(result, endpoint)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].host != result[j].host {
+			return result[i].host < result[j].host
+		}
+		return result[i].port < result[j].port
+	})
+	return result, nil
+}
+
+// registriesFromImages returns the registry of each image, defaulting to docker.io for short names
+func registriesFromImages(imageList []string) []string {
+	var registries []string
+	for _, image := range imageList {
+		if image == "" {
+			continue
+		}
+		registry := utils.ExtractRegistryFromImage(image)
+		// Short names, e.g. busybox or library/busybox, are pulled from docker.io
+		if registry == image || (!strings.ContainsAny(registry, ".:") && registry != "localhost") {
+			registry = DockerHubRegistry
+		}
+		registries = append(registries, registry)
+	}
+	return registries
+}
+
+func parseHostPort(hostport string, defaultPort int) (egressEndpoint, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		// No port specified
+		return egressEndpoint{host: strings.Trim(hostport, "[]"), port: defaultPort}, nil
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return egressEndpoint{}, fmt.Errorf("invalid port %s: %w", port, err)
+	}
+	return egressEndpoint{host: host, port: portNum}, nil
+}
+
+// renderNetworkPolicy renders the NetworkPolicy restricting the egress of the precaching job pod to DNS and the
+// resolved addresses of the given endpoints
+func renderNetworkPolicy(endpoints []egressEndpoint) (*networkingv1.NetworkPolicy, error) {
+	var (
+		udp = corev1.ProtocolUDP
+		tcp = corev1.ProtocolTCP
+	)
+
+	rules := []networkingv1.NetworkPolicyEgressRule{
+		{
+			// The job resolves the registries with the node DNS configuration, as it runs chrooted in the host
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: &udp, Port: &intstr.IntOrString{IntVal: 53}},
+				{Protocol: &tcp, Port: &intstr.IntOrString{IntVal: 53}},
+			},
+		},
+	}
+
+	for _, endpoint := range endpoints {
+		ips, err := lookupIP(endpoint.host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", endpoint.host, err)
+		}
+		rule := networkingv1.NetworkPolicyEgressRule{
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: &tcp, Port: &intstr.IntOrString{IntVal: int32(endpoint.port)}},
+			},
+		}
+		for _, ip := range ips {
+			cidr := ip.String() + "/32"
+			if ip.To4() == nil {
+				cidr = ip.String() + "/128"
+			}
+			rule.To = append(rule.To, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+		}
+		rules = append(rules, rule)
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      LcaPrecacheNetworkPolicyName,
+			Namespace: common.LcaNamespace,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{PrecachePodLabelKey: PrecachePodLabelValue},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      rules,
+		},
+	}, nil
+}
+
+// createOrUpdateNetworkPolicy applies the precaching job NetworkPolicy, replacing any left by a previous attempt
+func createOrUpdateNetworkPolicy(ctx context.Context, c client.Client, policy *networkingv1.NetworkPolicy) error {
+	existing := &networkingv1.NetworkPolicy{}
+	if err := c.Get(ctx, types.NamespacedName{Name: policy.Name, Namespace: policy.Namespace}, existing); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get precache network policy: %w", err)
+		}
+		if err := c.Create(ctx, policy); err != nil {
+			return fmt.Errorf("failed to create precache network policy: %w", err)
+		}
+		return nil
+	}
+
+	existing.Spec = policy.Spec
+	if err := c.Update(ctx, existing); err != nil {
+		return fmt.Errorf("failed to update precache network policy: %w", err)
+	}
+	return nil
+}
+
+func deleteNetworkPolicy(ctx context.Context, c client.Client, name, namespace string) error {
+	policy := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	if err := c.Delete(ctx, policy, generateDeleteOptions()); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete precache network policy: %w", err)
+	}
+	return nil
+}
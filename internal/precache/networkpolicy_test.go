@@ -0,0 +1,125 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+func fakeLookupIP(host string) ([]net.IP, error) {
+	switch host {
+	case "quay.io":
+		return []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, nil
+	case "mirror.example.com":
+		return []net.IP{net.ParseIP("fd00::1")}, nil
+	case "proxy.example.com":
+		return []net.IP{net.ParseIP("10.0.0.3")}, nil
+	}
+	return nil, fmt.Errorf("no such host %s", host)
+}
+
+func TestGetEgressEndpoints(t *testing.T) {
+	imageList := []string{
+		"quay.io/openshift-release-dev/ocp-release@sha256:1234",
+		"quay.io/openshift/origin:latest",
+		"mirror.example.com:5000/ocp/release:4.15",
+		"library/busybox",
+		"busybox:latest",
+		"",
+	}
+	envVars := []corev1.EnvVar{
+		{Name: "HTTPS_PROXY", Value: "http://proxy.example.com:3128"},
+		{Name: "no_proxy", Value: ".cluster.local"},
+	}
+
+	endpoints, err := getEgressEndpoints(imageList, []string{"cdn01.quay.io"}, envVars)
+	assert.NoError(t, err)
+	assert.Equal(t, []egressEndpoint{
+		{host: "cdn01.quay.io", port: 443},
+		{host: "docker.io", port: 443},
+		{host: "mirror.example.com", port: 5000},
+		{host: "proxy.example.com", port: 3128},
+		{host: "quay.io", port: 443},
+	}, endpoints)
+
+	_, err = getEgressEndpoints(nil, []string{"registry.example.com:port"}, nil)
+	assert.Error(t, err)
+}
+
+func TestRenderNetworkPolicy(t *testing.T) {
+	origLookupIP := lookupIP
+	defer func() {
+		lookupIP = origLookupIP
+	}()
+	lookupIP = fakeLookupIP
+
+	policy, err := renderNetworkPolicy([]egressEndpoint{{host: "mirror.example.com", port: 5000}, {host: "quay.io", port: 443}})
+	assert.NoError(t, err)
+	assert.Equal(t, LcaPrecacheNetworkPolicyName, policy.Name)
+	assert.Equal(t, map[string]string{PrecachePodLabelKey: PrecachePodLabelValue}, policy.Spec.PodSelector.MatchLabels)
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}, policy.Spec.PolicyTypes)
+
+	// DNS, then one rule per endpoint
+	assert.Len(t, policy.Spec.Egress, 3)
+	assert.Empty(t, policy.Spec.Egress[0].To)
+	assert.Equal(t, int32(53), policy.Spec.Egress[0].Ports[0].Port.IntVal)
+	assert.Equal(t, "fd00::1/128", policy.Spec.Egress[1].To[0].IPBlock.CIDR)
+	assert.Equal(t, int32(5000), policy.Spec.Egress[1].Ports[0].Port.IntVal)
+	assert.Equal(t, "10.0.0.1/32", policy.Spec.Egress[2].To[0].IPBlock.CIDR)
+	assert.Equal(t, "10.0.0.2/32", policy.Spec.Egress[2].To[1].IPBlock.CIDR)
+	assert.Equal(t, int32(443), policy.Spec.Egress[2].Ports[0].Port.IntVal)
+
+	_, err = renderNetworkPolicy([]egressEndpoint{{host: "unknown.example.com", port: 443}})
+	assert.Error(t, err)
+}
+
+func TestCreateJobWithRestrictedEgress(t *testing.T) {
+	origLookupIP := lookupIP
+	defer func() {
+		lookupIP = origLookupIP
+	}()
+	lookupIP = fakeLookupIP
+
+	fakeClient, err := getFakeClientFromObjects()
+	assert.NoError(t, err)
+	handler := &PHandler{Client: fakeClient, Log: ctrl.Log.WithName("Precache")}
+
+	config := NewConfig([]string{"quay.io/openshift/origin:latest"}, nil, "RestrictEgress", true,
+		"AllowedEgressHosts", []string{"mirror.example.com:5000"})
+	assert.NoError(t, handler.CreateJob(context.TODO(), config))
+
+	policy := &networkingv1.NetworkPolicy{}
+	key := types.NamespacedName{Name: LcaPrecacheNetworkPolicyName, Namespace: common.LcaNamespace}
+	assert.NoError(t, fakeClient.Get(context.TODO(), key, policy))
+	assert.Len(t, policy.Spec.Egress, 3)
+
+	assert.NoError(t, handler.Cleanup(context.TODO()))
+	err = fakeClient.Get(context.TODO(), key, policy)
+	assert.True(t, k8serrors.IsNotFound(err))
+}
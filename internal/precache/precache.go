@@ -19,14 +19,21 @@ package precache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/go-logr/logr"
 
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/openshift-kni/lifecycle-agent/internal/artifactsource"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/podpolicy"
+	"github.com/openshift-kni/lifecycle-agent/internal/registrytls"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -40,6 +47,20 @@ import (
 type PHandler struct {
 	client.Client
 	Log logr.Logger
+	// RunInProcess pre-caches the images of the config in the manager process, for the configs requesting it
+	RunInProcess func(ctx context.Context, config *Config, credentials *artifactsource.Credentials) error
+
+	mu  sync.Mutex
+	run *inProcessRun
+}
+
+// inProcessRun tracks the precaching run in the manager process
+type inProcessRun struct {
+	cancel    context.CancelFunc
+	done      chan struct{}
+	startTime time.Time
+	// err is the result of the run, set before done is closed
+	err error
 }
 
 // Config defines the configuration options for a pre-caching job.
@@ -55,8 +76,45 @@ type Config struct {
 	IoNiceClass    int // 0: none, 1: realtime, 2: best-effort, 3: idle
 	IoNicePriority int // priority (0..7) in the specified scheduling class, only for the realtime and best-effort classes
 
+	// To override the default compute resources of the pre-caching job, requests replacing the default ones by resource
+	Resources *corev1.ResourceRequirements
+
 	// Allow for environment variables to be passed in
 	EnvVars []corev1.EnvVar
+
+	// To restrict the egress of the pre-caching job to the image registries, the proxies and the allowed hosts,
+	// such as mirror registries or registry CDNs, with a NetworkPolicy
+	RestrictEgress     bool
+	AllowedEgressHosts []string
+
+	// To bound the pull of each image, and report the pulled images larger than the threshold, in bytes
+	PullTimeout             time.Duration
+	OversizedImageThreshold int64
+
+	// To pull the zstd:chunked layers partially, fetching only the file chunks missing from the local storage
+	PartialPulls bool
+
+	// To only report the images that would be pulled, checking their availability in the registry without pulling them
+	VerifyOnly bool
+
+	// To cap the download bandwidth of the pulls, in megabits per second, so that precaching does not starve the
+	// traffic of the workloads sharing the uplink. Unlimited if 0
+	MaxMbps int
+
+	// To complete the pre-caching job successfully when some images fail to be pulled, reporting them in the progress
+	BestEffort bool
+
+	// To download the images from a site-local artifact source instead of their registry
+	ArtifactSource *artifactsource.Config
+
+	// To override the TLS configuration of the registries of the images
+	RegistryTLS *registrytls.Config
+
+	// To schedule and secure the pre-caching job pod as the other pods created by the LCA
+	PodPolicy *podpolicy.Policy
+
+	// To pre-cache the images in the manager process instead of a pre-caching job
+	InProcess bool
 }
 
 // NewConfig creates a new Config instance with the provided imageList and optional configuration parameters.
@@ -66,18 +124,32 @@ type Config struct {
 //   - "NicePriority" (int): Nice priority for pre-caching.
 //   - "IoNiceClass" (int): I/O nice class for pre-caching.
 //   - "IoNicePriority" (int): I/O nice priority for pre-caching.
+//   - "RestrictEgress" (bool): Restrict the pre-caching job egress with a NetworkPolicy.
+//   - "AllowedEgressHosts" ([]string): Additional hosts allowed when restricting the egress.
+//   - "PullTimeout" (time.Duration): Time limit of each image pull.
+//   - "OversizedImageThreshold" (int64): Size in bytes above which a pulled image is reported as oversized.
+//   - "PartialPulls" (bool): Pull the zstd:chunked layers partially.
+//   - "VerifyOnly" (bool): Only report the images that would be pulled.
+//   - "MaxMbps" (int): Download bandwidth cap of the pulls, in megabits per second.
+//   - "BestEffort" (bool): Complete the pre-caching job successfully despite failed pulls.
+//   - "ArtifactSource" (*artifactsource.Config): Artifact source to download the images from.
+//   - "RegistryTLS" (*registrytls.Config): TLS overrides of the registries of the images.
+//   - "PodPolicy" (*podpolicy.Policy): Scheduling and security policy of the pre-caching job pod.
+//   - "InProcess" (bool): Pre-cache the images in the manager process instead of a pre-caching job.
 //
 // Example usage:
 //
 //	config := NewConfig(imageList, "NumConcurrentPulls", 10, "NicePriority", 5)
 func NewConfig(imageList []string, envVars []corev1.EnvVar, args ...any) *Config {
 	instance := &Config{
-		ImageList:          imageList,
-		NumConcurrentPulls: DefaultMaxConcurrentPulls,
-		NicePriority:       DefaultNicePriority,
-		IoNiceClass:        DefaultIoNiceClass,
-		IoNicePriority:     DefaultIoNicePriority,
-		EnvVars:            envVars,
+		ImageList:               imageList,
+		NumConcurrentPulls:      DefaultMaxConcurrentPulls,
+		NicePriority:            DefaultNicePriority,
+		IoNiceClass:             DefaultIoNiceClass,
+		IoNicePriority:          DefaultIoNicePriority,
+		EnvVars:                 envVars,
+		PullTimeout:             DefaultPullTimeout,
+		OversizedImageThreshold: DefaultOversizedImageThreshold,
 	}
 
 	for i := 0; i < len(args); i += 2 {
@@ -100,6 +172,58 @@ func NewConfig(imageList []string, envVars []corev1.EnvVar, args ...any) *Config
 			if IoNicePriority, ok := value.(int); ok {
 				instance.IoNicePriority = IoNicePriority
 			}
+		case "Resources":
+			if Resources, ok := value.(*corev1.ResourceRequirements); ok {
+				instance.Resources = Resources
+			}
+		case "RestrictEgress":
+			if RestrictEgress, ok := value.(bool); ok {
+				instance.RestrictEgress = RestrictEgress
+			}
+		case "AllowedEgressHosts":
+			if AllowedEgressHosts, ok := value.([]string); ok {
+				instance.AllowedEgressHosts = AllowedEgressHosts
+			}
+		case "PullTimeout":
+			if PullTimeout, ok := value.(time.Duration); ok {
+				instance.PullTimeout = PullTimeout
+			}
+		case "OversizedImageThreshold":
+			if OversizedImageThreshold, ok := value.(int64); ok {
+				instance.OversizedImageThreshold = OversizedImageThreshold
+			}
+		case "PartialPulls":
+			if PartialPulls, ok := value.(bool); ok {
+				instance.PartialPulls = PartialPulls
+			}
+		case "VerifyOnly":
+			if VerifyOnly, ok := value.(bool); ok {
+				instance.VerifyOnly = VerifyOnly
+			}
+		case "MaxMbps":
+			if MaxMbps, ok := value.(int); ok {
+				instance.MaxMbps = MaxMbps
+			}
+		case "BestEffort":
+			if BestEffort, ok := value.(bool); ok {
+				instance.BestEffort = BestEffort
+			}
+		case "ArtifactSource":
+			if ArtifactSource, ok := value.(*artifactsource.Config); ok {
+				instance.ArtifactSource = ArtifactSource
+			}
+		case "RegistryTLS":
+			if RegistryTLS, ok := value.(*registrytls.Config); ok {
+				instance.RegistryTLS = RegistryTLS
+			}
+		case "PodPolicy":
+			if PodPolicy, ok := value.(*podpolicy.Policy); ok {
+				instance.PodPolicy = PodPolicy
+			}
+		case "InProcess":
+			if InProcess, ok := value.(bool); ok {
+				instance.InProcess = InProcess
+			}
 		}
 	}
 
@@ -108,18 +232,42 @@ func NewConfig(imageList []string, envVars []corev1.EnvVar, args ...any) *Config
 
 // Status represents the status and progress information for the precaching job
 type Status struct {
-	Status   string
-	Message  string
-	Progress Progress
+	Status    string
+	Message   string
+	Progress  Progress
+	StartTime time.Time
+	// Blocked reports why the job cannot make progress, e.g. its pod is unschedulable or was evicted
+	Blocked string
+	// Recreated reports that the job was deleted while precaching, and recreated for the remaining images if any
+	Recreated string
+	// FailedImages are the images that failed to be pulled after their retries, sorted
+	FailedImages []FailedImage
 }
 
-// CreateJob creates a new precache job.
+// EstimatedCompletion extrapolates the completion time of the precaching job from the rate at which images have
+// been processed so far. It returns nil if the job is not active or has not processed any image yet.
+func (s *Status) EstimatedCompletion(now time.Time) *time.Time {
+	processed := s.Progress.Processed()
+	if s.Status != Active || s.StartTime.IsZero() || processed == 0 || s.Progress.Total < processed {
+		return nil
+	}
+	elapsed := now.Sub(s.StartTime)
+	remaining := time.Duration(int64(elapsed) / int64(processed) * int64(s.Progress.Total-processed))
+	eta := now.Add(remaining)
+	return &eta
+}
+
+// CreateJob creates a new precache job, or starts precaching in the manager process if the config requests it.
 func (h *PHandler) CreateJob(ctx context.Context, config *Config) error {
 
 	if err := validateJobConfig(ctx, h.Client, config.ImageList); err != nil {
 		return err
 	}
 
+	if config.InProcess {
+		return h.startInProcess(ctx, config)
+	}
+
 	// Generate ConfigMap for list of images to be pre-cached
 	cm := renderConfigMap(config.ImageList)
 	err := h.Client.Create(ctx, cm)
@@ -127,6 +275,21 @@ func (h *PHandler) CreateJob(ctx context.Context, config *Config) error {
 		return fmt.Errorf("failed to create configMap for precache: %w", err)
 	}
 
+	if config.RestrictEgress {
+		endpoints, err := getEgressEndpoints(config.ImageList, config.AllowedEgressHosts, config.EnvVars)
+		if err != nil {
+			return fmt.Errorf("failed to get precaching job egress endpoints: %w", err)
+		}
+		policy, err := renderNetworkPolicy(endpoints)
+		if err != nil {
+			return fmt.Errorf("failed to render precaching network policy: %w", err)
+		}
+		if err := createOrUpdateNetworkPolicy(ctx, h.Client, policy); err != nil {
+			return err
+		}
+		h.Log.Info("Precaching", "RestrictedEgress", policy.Name, "endpoints", len(endpoints))
+	}
+
 	job, err := renderJob(config, h.Log)
 	if err != nil {
 		return fmt.Errorf("failed to render precaching job manifest %w", err)
@@ -139,11 +302,196 @@ func (h *PHandler) CreateJob(ctx context.Context, config *Config) error {
 	// Log job details
 	h.Log.Info("Precaching", "CreatedJob", job.Name)
 
+	// The config is only needed to recreate the job if it is deleted, which is best effort
+	if err := persistConfig(config, configFile); err != nil {
+		h.Log.Error(err, "Failed to persist precaching config, the job will not be recreated if deleted")
+	}
+
+	return nil
+}
+
+// startInProcess starts precaching in the manager process, in the background, without a job nor its configmap
+func (h *PHandler) startInProcess(ctx context.Context, config *Config) error {
+	if h.RunInProcess == nil {
+		return errors.New("precaching in the manager process is not supported")
+	}
+	var credentials *artifactsource.Credentials
+	if config.ArtifactSource != nil {
+		var err error
+		if credentials, err = config.ArtifactSource.LoadCredentials(ctx, h.Client); err != nil {
+			return fmt.Errorf("failed to load artifact source credentials: %w", err)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.run != nil {
+		return errors.New("precaching already running in the manager process")
+	}
+	// The run outlives the reconcile, it is only stopped by Cleanup
+	runCtx, cancel := context.WithCancel(context.Background())
+	run := &inProcessRun{cancel: cancel, done: make(chan struct{}), startTime: time.Now()}
+	h.run = run
+	go func() {
+		defer close(run.done)
+		run.err = h.RunInProcess(runCtx, config, credentials)
+		if run.err != nil {
+			h.Log.Error(run.err, "Precaching in the manager process failed")
+		} else {
+			h.Log.Info("Precaching in the manager process succeeded")
+		}
+	}()
+	h.Log.Info("Starting in-process precaching", "images", len(config.ImageList))
+
+	// The config is only needed to restart precaching if the manager restarts, which is best effort
+	if err := persistConfig(config, configFile); err != nil {
+		h.Log.Error(err, "Failed to persist precaching config, precaching will not be restarted with the manager")
+	}
 	return nil
 }
 
-// QueryJobStatus retrieves the status of the precache job.
+// inProcessStatus returns the status of the precaching run in the manager process, or nil if there is none
+func (h *PHandler) inProcessStatus() *Status {
+	h.mu.Lock()
+	run := h.run
+	h.mu.Unlock()
+	if run == nil {
+		return nil
+	}
+
+	status := &Status{Status: Active, StartTime: run.startTime}
+	select {
+	case <-run.done:
+		if run.err != nil {
+			status.Status = Failed
+			status.Blocked = run.err.Error()
+		} else {
+			status.Status = Succeeded
+		}
+	default:
+	}
+	return status
+}
+
+// stopInProcess stops the precaching run in the manager process, if any, waiting for its pulls to be killed up to
+// jobTerminationTimeout
+func (h *PHandler) stopInProcess(ctx context.Context) error {
+	h.mu.Lock()
+	run := h.run
+	h.mu.Unlock()
+	if run == nil {
+		return nil
+	}
+
+	h.Log.Info("Stopping precaching in the manager process")
+	run.cancel()
+	timer := time.NewTimer(jobTerminationTimeout)
+	defer timer.Stop()
+	select {
+	case <-run.done:
+	case <-timer.C:
+		return errors.New("timed out waiting for the precaching in the manager process to stop")
+	case <-ctx.Done():
+		return fmt.Errorf("failed to wait for the precaching in the manager process to stop: %w", ctx.Err())
+	}
+
+	h.mu.Lock()
+	h.run = nil
+	h.mu.Unlock()
+	return nil
+}
+
+// configFile and cacheFile are the host paths of ConfigFile and CacheFile, overridden in tests
+var (
+	configFile = common.PathOutsideChroot(ConfigFile)
+	cacheFile  = common.PathOutsideChroot(CacheFile)
+)
+
+func persistConfig(config *Config, filename string) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal precaching config: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write precaching config %s: %w", filename, err)
+	}
+	return nil
+}
+
+// RecreateJob recreates the precaching job, if it was deleted while precaching, from the persisted config and for
+// the images not pre-cached yet. The precaching in the manager process, interrupted by a restart of the manager, is
+// restarted the same way. It returns nil if no precaching job was created. Only the Prep stage polling the precaching
+// is expected to call it, once QueryJobStatus reports no status.
+func (h *PHandler) RecreateJob(ctx context.Context) (*Status, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			h.Log.Info("Precaching job does not exist", "jobName", LcaPrecacheJobName)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read precaching config %s: %w", configFile, err)
+	}
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse precaching config %s: %w", configFile, err)
+	}
+
+	cache, err := LoadCache(cacheFile)
+	if err != nil {
+		return nil, err
+	}
+	var remaining []string
+	for _, image := range config.ImageList {
+		if cache == nil || cache.Images[image] == "" {
+			remaining = append(remaining, image)
+		}
+	}
+	stopped, recreated := "deleted", "recreated"
+	if config.InProcess {
+		stopped, recreated = "interrupted by a manager restart", "restarted"
+	}
+	if len(remaining) == 0 {
+		h.Log.Info(fmt.Sprintf("Precaching was %s after pre-caching all the images", stopped))
+		return &Status{Status: Succeeded, Recreated: stopped + " after pre-caching all the images"}, nil
+	}
+
+	h.Log.Info(fmt.Sprintf("Precaching was %s, %s for the remaining images", stopped, recreated),
+		"remaining", len(remaining), "total", len(config.ImageList))
+	if err := deleteConfigMap(ctx, h.Client, LcaPrecacheConfigMapName, common.LcaNamespace); err != nil {
+		return nil, err
+	}
+	remainingConfig := *config
+	remainingConfig.ImageList = remaining
+	if err := h.CreateJob(ctx, &remainingConfig); err != nil {
+		return nil, fmt.Errorf("failed to recreate precaching job: %w", err)
+	}
+	// Keep the full image list, in case the recreated job is deleted too
+	if err := persistConfig(config, configFile); err != nil {
+		h.Log.Error(err, "Failed to persist precaching config")
+	}
+
+	return &Status{
+		Status:    Active,
+		StartTime: time.Now(),
+		Recreated: fmt.Sprintf("%s while precaching, %s for the %d remaining images", stopped, recreated, len(remaining)),
+	}, nil
+}
+
+// QueryJobStatus retrieves the status of the precache job, or of the precaching in the manager process, and updates
+// the precaching metrics with it. It returns nil if there is neither, see RecreateJob.
 func (h *PHandler) QueryJobStatus(ctx context.Context) (*Status, error) {
+	status, err := h.queryStatus(ctx)
+	if err == nil && status != nil {
+		precacheMetrics.observe(status, time.Now())
+	}
+	return status, err
+}
+
+func (h *PHandler) queryStatus(ctx context.Context) (*Status, error) {
+	if status := h.inProcessStatus(); status != nil {
+		h.readProgress(status)
+		return status, nil
+	}
 
 	job, err := getJob(ctx, h.Client, LcaPrecacheJobName, common.LcaNamespace)
 	if err != nil {
@@ -157,6 +505,9 @@ func (h *PHandler) QueryJobStatus(ctx context.Context) (*Status, error) {
 	}
 
 	status := &Status{Message: ""}
+	if job.Status.StartTime != nil {
+		status.StartTime = job.Status.StartTime.Time
+	}
 	// Extract job status: active, successful, failed
 	if job.Status.Active > 0 {
 		status.Status = Active
@@ -169,8 +520,23 @@ func (h *PHandler) QueryJobStatus(ctx context.Context) (*Status, error) {
 		h.Log.Info("Precaching job failed", "name:", LcaPrecacheJobName)
 	}
 
-	// Get precaching progress summary from StatusFile
-	_, err = os.Stat(common.PathOutsideChroot(StatusFile))
+	if status.Status != Succeeded {
+		blocked, err := getJobInterference(ctx, h.Client, job)
+		if err != nil {
+			h.Log.Info("Unable to check precaching job interference", "error", err.Error())
+		} else if blocked != "" {
+			status.Blocked = blocked
+			h.Log.Info("Precaching job blocked", "reason", blocked)
+		}
+	}
+
+	h.readProgress(status)
+	return status, nil
+}
+
+// readProgress reads the precaching progress summary from StatusFile into the status
+func (h *PHandler) readProgress(status *Status) {
+	_, err := os.Stat(common.PathOutsideChroot(StatusFile))
 	if err == nil {
 		// in progress
 		var data []byte
@@ -181,19 +547,33 @@ func (h *PHandler) QueryJobStatus(ctx context.Context) (*Status, error) {
 			if err != nil {
 				h.Log.Error(err, "Failed to parse progress", "StatusFile", StatusFile)
 			} else {
-				status.Message = fmt.Sprintf("total: %d (pulled: %d, skipped: %d, failed: %d)",
-					status.Progress.Total, status.Progress.Pulled, status.Progress.Skipped, status.Progress.Failed)
+				status.Message = status.Progress.Summary()
+				if len(status.Progress.FailedImages) != 0 {
+					status.FailedImages = append([]FailedImage{}, status.Progress.FailedImages...)
+					sort.Slice(status.FailedImages, func(i, j int) bool {
+						return status.FailedImages[i].Image < status.FailedImages[j].Image
+					})
+					// The failed pulls are final once the job is no longer active
+					if status.Status != Active {
+						status.Message += "; " + FailedImagesSummary(status.FailedImages)
+					}
+				}
 			}
 		} else {
 			h.Log.Info("Unable to read precaching progress file", "StatusFile", StatusFile)
 		}
 	}
-	return status, nil
 }
 
-// Cleanup deletes the ConfigMap and Job precaching resources
+// Cleanup stops the precaching in the manager process and deletes the ConfigMap, Job and NetworkPolicy precaching
+// resources
 func (h *PHandler) Cleanup(ctx context.Context) error {
-	// Delete Job
+	if err := h.stopInProcess(ctx); err != nil {
+		return err
+	}
+
+	// Delete Job, waiting for its pods to terminate so that no image is pulled anymore
+	h.Log.Info("Deleting precaching job", "name", LcaPrecacheJobName)
 	if err := deleteJob(ctx, h.Client, LcaPrecacheJobName, common.LcaNamespace); err != nil {
 		h.Log.Info("Failed to delete precaching job", "name", LcaPrecacheJobName)
 		return err
@@ -203,6 +583,17 @@ func (h *PHandler) Cleanup(ctx context.Context) error {
 		h.Log.Info("Failed to delete precaching configmap", "name", LcaPrecacheConfigMapName)
 		return err
 	}
+	// Delete NetworkPolicy
+	if err := deleteNetworkPolicy(ctx, h.Client, LcaPrecacheNetworkPolicyName, common.LcaNamespace); err != nil {
+		h.Log.Info("Failed to delete precaching network policy", "name", LcaPrecacheNetworkPolicyName)
+		return err
+	}
+
+	// Delete the persisted config, so that the job is not recreated
+	if err := os.Remove(configFile); err != nil && !os.IsNotExist(err) {
+		h.Log.Error(err, "Failed to delete precaching config", "file", ConfigFile)
+	}
+	precacheMetrics.reset()
 
 	// Delete precaching progress tracker file
 	statusFile := common.PathOutsideChroot(StatusFile)
@@ -18,14 +18,19 @@ package precache
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 
 	"github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/internal/artifactsource"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
 
 	"github.com/stretchr/testify/assert"
@@ -210,6 +215,7 @@ func TestQueryJobStatus(t *testing.T) {
 		name           string
 		inputJobName   string
 		jobStatus      *batchv1.JobStatus
+		inputObjs      []client.Object
 		expectedError  error
 		expectedStatus *Status
 	}{
@@ -253,6 +259,108 @@ func TestQueryJobStatus(t *testing.T) {
 				Status: "Failed",
 			},
 		},
+		{
+			name:         "Active status, pod unschedulable",
+			inputJobName: LcaPrecacheJobName,
+			jobStatus: &batchv1.JobStatus{
+				Active: 1,
+			},
+			inputObjs: []client.Object{
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "lca-precache-job-abcde",
+						Namespace: common.LcaNamespace,
+						Labels:    map[string]string{PrecachePodLabelKey: PrecachePodLabelValue},
+					},
+					Status: corev1.PodStatus{
+						Phase: corev1.PodPending,
+						Conditions: []corev1.PodCondition{{
+							Type:    corev1.PodScheduled,
+							Status:  corev1.ConditionFalse,
+							Reason:  corev1.PodReasonUnschedulable,
+							Message: "0/1 nodes are available: 1 Insufficient memory.",
+						}},
+					},
+				},
+			},
+			expectedStatus: &Status{
+				Status:  "Active",
+				Blocked: "precaching pod lca-precache-job-abcde cannot be scheduled (Unschedulable): 0/1 nodes are available: 1 Insufficient memory.",
+			},
+		},
+		{
+			name:         "Failed status, pod evicted",
+			inputJobName: LcaPrecacheJobName,
+			jobStatus: &batchv1.JobStatus{
+				Failed: 1,
+			},
+			inputObjs: []client.Object{
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "lca-precache-job-abcde",
+						Namespace: common.LcaNamespace,
+						Labels:    map[string]string{PrecachePodLabelKey: PrecachePodLabelValue},
+					},
+					Status: corev1.PodStatus{
+						Phase:   corev1.PodFailed,
+						Reason:  "Evicted",
+						Message: "The node was low on resource: ephemeral-storage.",
+					},
+				},
+			},
+			expectedStatus: &Status{
+				Status:  "Failed",
+				Blocked: "precaching pod lca-precache-job-abcde was evicted: The node was low on resource: ephemeral-storage.",
+			},
+		},
+		{
+			name:         "Active status, evicted pod replaced",
+			inputJobName: LcaPrecacheJobName,
+			jobStatus: &batchv1.JobStatus{
+				Active: 1,
+			},
+			inputObjs: []client.Object{
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "lca-precache-job-abcde",
+						Namespace: common.LcaNamespace,
+						Labels:    map[string]string{PrecachePodLabelKey: PrecachePodLabelValue},
+					},
+					Status: corev1.PodStatus{
+						Phase:   corev1.PodFailed,
+						Reason:  "Evicted",
+						Message: "The node was low on resource: ephemeral-storage.",
+					},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "lca-precache-job-fghij",
+						Namespace: common.LcaNamespace,
+						Labels:    map[string]string{PrecachePodLabelKey: PrecachePodLabelValue},
+					},
+					Status: corev1.PodStatus{Phase: corev1.PodRunning},
+				},
+			},
+			expectedStatus: &Status{
+				Status: "Active",
+			},
+		},
+		{
+			name:         "No pod created, resource quota exceeded",
+			inputJobName: LcaPrecacheJobName,
+			jobStatus:    &batchv1.JobStatus{},
+			inputObjs: []client.Object{
+				&corev1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Name: "lca-precache-job.1", Namespace: common.LcaNamespace},
+					InvolvedObject: corev1.ObjectReference{Kind: "Job", Name: LcaPrecacheJobName, Namespace: common.LcaNamespace},
+					Reason:         "FailedCreate",
+					Message:        `Error creating: pods "lca-precache-job-abcde" is forbidden: exceeded quota: compute, requested: requests.memory=512Mi`,
+				},
+			},
+			expectedStatus: &Status{
+				Blocked: `precaching pod cannot be created: Error creating: pods "lca-precache-job-abcde" is forbidden: exceeded quota: compute, requested: requests.memory=512Mi`,
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -269,6 +377,7 @@ func TestQueryJobStatus(t *testing.T) {
 				job.Status = *tc.jobStatus
 				objs = append(objs, job)
 			}
+			objs = append(objs, tc.inputObjs...)
 
 			fakeClient, err := getFakeClientFromObjects(objs...)
 			if err != nil {
@@ -293,12 +402,98 @@ func TestQueryJobStatus(t *testing.T) {
 					assert.NotNil(t, status)
 
 					assert.Equal(t, tc.expectedStatus.Status, status.Status)
+					assert.Equal(t, tc.expectedStatus.Blocked, status.Blocked)
 				}
 			}
 		})
 	}
 }
 
+func TestRecreateDeletedJob(t *testing.T) {
+	imageList, _ := generateImageList()
+	testCases := []struct {
+		name           string
+		cachedImages   []string
+		expectedStatus *Status
+		expectedImages []string
+	}{
+		{
+			name:           "Recreated for the remaining images",
+			cachedImages:   imageList[:1],
+			expectedStatus: &Status{Status: Active, Recreated: "deleted while precaching, recreated for the 2 remaining images"},
+			expectedImages: imageList[1:],
+		},
+		{
+			name:           "All images already pre-cached",
+			cachedImages:   imageList,
+			expectedStatus: &Status{Status: Succeeded, Recreated: "deleted after pre-caching all the images"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			oldConfigFile, oldCacheFile := configFile, cacheFile
+			configFile, cacheFile = filepath.Join(tmpDir, "config.json"), filepath.Join(tmpDir, "cache.json")
+			defer func() { configFile, cacheFile = oldConfigFile, oldCacheFile }()
+
+			cache := &Cache{}
+			for _, image := range tc.cachedImages {
+				cache.Record(image, "sha256:digest")
+			}
+			assert.NoError(t, cache.Persist(cacheFile))
+
+			config := &Config{ImageList: imageList, NumConcurrentPulls: 5}
+			fakeClient, err := getFakeClientFromObjects()
+			assert.NoError(t, err)
+			handler := &PHandler{Client: fakeClient, Log: ctrl.Log.WithName("Precache")}
+
+			// The job is created, then deleted with the ConfigMap left behind
+			assert.NoError(t, handler.CreateJob(context.TODO(), config))
+			assert.NoError(t, deleteJob(context.TODO(), fakeClient, LcaPrecacheJobName, common.LcaNamespace))
+
+			// Querying the status does not recreate the job
+			status, err := handler.QueryJobStatus(context.TODO())
+			assert.NoError(t, err)
+			assert.Nil(t, status)
+			job, err := getJob(context.TODO(), fakeClient, LcaPrecacheJobName, common.LcaNamespace)
+			assert.NoError(t, err)
+			assert.Nil(t, job)
+
+			status, err = handler.RecreateJob(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus.Status, status.Status)
+			assert.Equal(t, tc.expectedStatus.Recreated, status.Recreated)
+
+			job, err = getJob(context.TODO(), fakeClient, LcaPrecacheJobName, common.LcaNamespace)
+			assert.NoError(t, err)
+			if tc.expectedImages == nil {
+				assert.Nil(t, job)
+				return
+			}
+			assert.NotNil(t, job)
+			cm, err := common.GetConfigMap(context.TODO(), fakeClient, v1alpha1.ConfigMapRef{
+				Name:      LcaPrecacheConfigMapName,
+				Namespace: common.LcaNamespace,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, renderConfigMap(tc.expectedImages).Data, cm.Data)
+
+			// The persisted config keeps the full image list
+			data, err := os.ReadFile(configFile)
+			assert.NoError(t, err)
+			persisted := &Config{}
+			assert.NoError(t, json.Unmarshal(data, persisted))
+			assert.Equal(t, imageList, persisted.ImageList)
+
+			// Cleanup removes the persisted config, so the job is not recreated anymore
+			assert.NoError(t, handler.Cleanup(context.TODO()))
+			status, err = handler.RecreateJob(context.TODO())
+			assert.NoError(t, err)
+			assert.Nil(t, status)
+		})
+	}
+}
+
 func TestCleanup(t *testing.T) {
 	imageList, _ := generateImageList()
 	config := &Config{ImageList: imageList}
@@ -374,3 +569,123 @@ func TestCleanup(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteJobWaitsForPods(t *testing.T) {
+	oldTimeout, oldInterval := jobTerminationTimeout, jobTerminationInterval
+	jobTerminationTimeout, jobTerminationInterval = time.Second, 10*time.Millisecond
+	defer func() { jobTerminationTimeout, jobTerminationInterval = oldTimeout, oldInterval }()
+
+	imageList, _ := generateImageList()
+	job, err := renderJob(&Config{ImageList: imageList}, ctrl.Log.WithName("Precache"))
+	assert.NoError(t, err)
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: LcaPrecacheJobName + "-abcde", Namespace: common.LcaNamespace,
+		Labels: map[string]string{PrecachePodLabelKey: PrecachePodLabelValue}}}
+
+	// The pod terminates while waiting
+	fakeClient, err := getFakeClientFromObjects(job, pod.DeepCopy())
+	assert.NoError(t, err)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, fakeClient.Delete(context.TODO(), pod.DeepCopy()))
+	}()
+	assert.NoError(t, deleteJob(context.TODO(), fakeClient, LcaPrecacheJobName, common.LcaNamespace))
+	podList := &corev1.PodList{}
+	assert.NoError(t, fakeClient.List(context.TODO(), podList))
+	assert.Empty(t, podList.Items)
+
+	// The pod never terminates
+	fakeClient, err = getFakeClientFromObjects(job, pod.DeepCopy())
+	assert.NoError(t, err)
+	err = deleteJob(context.TODO(), fakeClient, LcaPrecacheJobName, common.LcaNamespace)
+	assert.ErrorContains(t, err, "1 pods of job lca-precache-job still terminating after 1s")
+}
+
+func TestInProcessPrecaching(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldConfigFile := configFile
+	configFile = filepath.Join(tmpDir, "config.json")
+	defer func() { configFile = oldConfigFile }()
+
+	fakeClient, err := getFakeClientFromObjects()
+	assert.NoError(t, err)
+	release := make(chan error)
+	handler := &PHandler{
+		Client: fakeClient,
+		Log:    ctrl.Log.WithName("Precache"),
+		RunInProcess: func(ctx context.Context, config *Config, credentials *artifactsource.Credentials) error {
+			select {
+			case err := <-release:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+	imageList, _ := generateImageList()
+	assert.NoError(t, handler.CreateJob(context.TODO(), NewConfig(imageList, nil, "InProcess", true)))
+
+	// Neither the job nor its configmap are created
+	job, err := getJob(context.TODO(), fakeClient, LcaPrecacheJobName, common.LcaNamespace)
+	assert.NoError(t, err)
+	assert.Nil(t, job)
+	cms := &corev1.ConfigMapList{}
+	assert.NoError(t, fakeClient.List(context.TODO(), cms))
+	assert.Empty(t, cms.Items)
+
+	status, err := handler.QueryJobStatus(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, Active, status.Status)
+	assert.Error(t, handler.CreateJob(context.TODO(), NewConfig(imageList, nil, "InProcess", true)))
+
+	release <- errors.New("failed to pre-cache one or more images")
+	assert.Eventually(t, func() bool {
+		status, err = handler.QueryJobStatus(context.TODO())
+		return err == nil && status.Status == Failed
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "failed to pre-cache one or more images", status.Blocked)
+
+	// Cleanup forgets the run, so that Prep can precache again
+	assert.NoError(t, handler.Cleanup(context.TODO()))
+	assert.NoError(t, handler.CreateJob(context.TODO(), NewConfig(imageList, nil, "InProcess", true)))
+	release <- nil
+	assert.Eventually(t, func() bool {
+		status, err = handler.QueryJobStatus(context.TODO())
+		return err == nil && status.Status == Succeeded
+	}, time.Second, 10*time.Millisecond)
+
+	// Cleanup stops a running precaching
+	assert.NoError(t, handler.Cleanup(context.TODO()))
+	assert.NoError(t, handler.CreateJob(context.TODO(), NewConfig(imageList, nil, "InProcess", true)))
+	assert.NoError(t, handler.Cleanup(context.TODO()))
+	status, err = handler.QueryJobStatus(context.TODO())
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+}
+
+func TestEstimatedCompletion(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name     string
+		status   *Status
+		expected *time.Time
+	}{
+		{
+			name:   "no image processed yet",
+			status: &Status{Status: Active, StartTime: now.Add(-time.Minute), Progress: Progress{Total: 10}},
+		},
+		{
+			name:   "job not active",
+			status: &Status{Status: Succeeded, StartTime: now.Add(-time.Minute), Progress: Progress{Total: 10, Pulled: 10}},
+		},
+		{
+			name:     "half of the images processed",
+			status:   &Status{Status: Active, StartTime: now.Add(-time.Minute), Progress: Progress{Total: 10, Pulled: 3, Skipped: 1, Failed: 1}},
+			expected: func() *time.Time { eta := now.Add(time.Minute); return &eta }(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.status.EstimatedCompletion(now))
+		})
+	}
+}
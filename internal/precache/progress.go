@@ -18,7 +18,10 @@ package precache
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/sirupsen/logrus"
@@ -31,6 +34,24 @@ type Progress struct {
 	Failed         int      `json:"failed"`
 	Skipped        int      `json:"skipped"`
 	FailedPullList []string `json:"failed_pulls"`
+	// Resumed is the number of the skipped images pulled by a previous pod of the job, e.g. evicted or stopped by a
+	// node reboot
+	Resumed int `json:"resumed,omitempty"`
+	// PulledBytes is the size of the pulled images in the container storage
+	PulledBytes int64 `json:"pulled_bytes,omitempty"`
+	// FailedImages are the images that failed to be pulled after their retries, with the last error
+	FailedImages []FailedImage `json:"failed_images,omitempty"`
+	// TimedOutPullList lists the failed pulls that reached the pull time limit
+	TimedOutPullList []string `json:"timed_out_pulls,omitempty"`
+	// OversizedImages are the pulled images larger than the threshold, with their size in bytes
+	OversizedImages map[string]int64 `json:"oversized_images,omitempty"`
+	// BytesSaved are the bytes not downloaded by the partial pulls of the zstd:chunked images, per image
+	BytesSaved map[string]int64 `json:"bytes_saved,omitempty"`
+	// VerifyOnly is set when the images were only checked in their registry, the would pull counters then replacing
+	// the pulled one
+	VerifyOnly     bool  `json:"verify_only,omitempty"`
+	WouldPull      int   `json:"would_pull,omitempty"`
+	WouldPullBytes int64 `json:"would_pull_bytes,omitempty"`
 	mux            sync.Mutex
 }
 
@@ -49,14 +70,159 @@ func (p *Progress) Update(success bool, image string) {
 	}
 }
 
+// RecordFailure records an image that failed to be pulled after the given number of attempts, with the last error
+func (p *Progress) RecordFailure(image string, attempts int, err error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.FailedImages = append(p.FailedImages, FailedImage{Image: image, Attempts: attempts, Error: err.Error()})
+}
+
+// RecordPulledBytes records the size of a pulled image in the container storage
+func (p *Progress) RecordPulledBytes(size int64) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.PulledBytes += size
+}
+
+// RecordTimeout records that the pull of the image reached the pull time limit
+func (p *Progress) RecordTimeout(image string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.TimedOutPullList = append(p.TimedOutPullList, image)
+}
+
+// RecordOversized records a pulled image larger than the oversized image threshold
+func (p *Progress) RecordOversized(image string, size int64) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.OversizedImages == nil {
+		p.OversizedImages = make(map[string]int64)
+	}
+	p.OversizedImages[image] = size
+}
+
+// RecordWouldPull records an image available in its registry that would be pulled, with its compressed size in bytes
+func (p *Progress) RecordWouldPull(image string, size int64) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.WouldPull++
+	p.WouldPullBytes += size
+}
+
+// RecordBytesSaved records the bytes not downloaded by the partial pull of the image
+func (p *Progress) RecordBytesSaved(image string, saved int64) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.BytesSaved == nil {
+		p.BytesSaved = make(map[string]int64)
+	}
+	p.BytesSaved[image] = saved
+}
+
+// Processed returns the number of images pulled, or that would be pulled, skipped or failed
+func (p *Progress) Processed() int {
+	return p.Pulled + p.WouldPull + p.Skipped + p.Failed
+}
+
+// Percent returns the percentage of the images processed, 0 until the total is known
+func (p *Progress) Percent() int {
+	if p.Total <= 0 {
+		return 0
+	}
+	processed := p.Processed()
+	if processed > p.Total {
+		return 100
+	}
+	return processed * 100 / p.Total
+}
+
+// Summary returns the progress counters, with the timed out pulls, oversized images and bytes saved by the partial
+// pulls only when there are any
+func (p *Progress) Summary() string {
+	if p.VerifyOnly {
+		return fmt.Sprintf("total: %d (would pull: %d, %d bytes, skipped: %d, unavailable: %d)",
+			p.Total, p.WouldPull, p.WouldPullBytes, p.Skipped, p.Failed)
+	}
+	summary := fmt.Sprintf("total: %d (pulled: %d, skipped: %d, failed: %d", p.Total, p.Pulled, p.Skipped, p.Failed)
+	if p.Resumed != 0 {
+		summary += fmt.Sprintf(", resumed: %d", p.Resumed)
+	}
+	if len(p.TimedOutPullList) != 0 {
+		summary += fmt.Sprintf(", timed out: %d", len(p.TimedOutPullList))
+	}
+	if len(p.OversizedImages) != 0 {
+		summary += fmt.Sprintf(", oversized: %d", len(p.OversizedImages))
+	}
+	if len(p.BytesSaved) != 0 {
+		var saved int64
+		for _, bytes := range p.BytesSaved {
+			saved += bytes
+		}
+		summary += fmt.Sprintf(", saved by partial pulls: %d bytes", saved)
+	}
+	return summary + ")"
+}
+
+// FailedImage is an image that failed to be pulled after its retries
+type FailedImage struct {
+	Image    string `json:"image"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error"`
+}
+
+// FailedImagesSummary returns the images that failed to be pulled, sorted, with their attempts and last error, e.g.
+// "permanently failed pulls: quay.io/a/b:1 (5 attempts: manifest unknown)"
+func FailedImagesSummary(images []FailedImage) string {
+	sorted := append([]FailedImage{}, images...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Image < sorted[j].Image })
+	failures := make([]string, 0, len(sorted))
+	for _, image := range sorted {
+		failures = append(failures, fmt.Sprintf("%s (%d attempts: %s)", image.Image, image.Attempts, image.Error))
+	}
+	return "permanently failed pulls: " + strings.Join(failures, ", ")
+}
+
 func (p *Progress) Log() {
 	logrus.Infof("Total Images: %d", p.Total)
+	if p.VerifyOnly {
+		logrus.Infof("Images That Would Be Pulled: %d (%d bytes)", p.WouldPull, p.WouldPullBytes)
+		logrus.Infof("Images Skipped: %d", p.Skipped)
+		logrus.Infof("Images Unavailable: %d", p.Failed)
+		for _, img := range p.FailedPullList {
+			logrus.Infof("unavailable: %s", img)
+		}
+		return
+	}
 	logrus.Infof("Images Pulled Successfully: %d", p.Pulled)
 	logrus.Infof("Images Skipped: %d", p.Skipped)
+	if p.Resumed != 0 {
+		logrus.Infof("Images Pulled by a Previous Pod: %d", p.Resumed)
+	}
 	logrus.Infof("Images Failed to Pull: %d", p.Failed)
 	for _, img := range p.FailedPullList {
 		logrus.Infof("failed: %s", img)
 	}
+	for _, img := range p.FailedImages {
+		logrus.Infof("failed after %d attempts: %s: %s", img.Attempts, img.Image, img.Error)
+	}
+	for _, img := range p.TimedOutPullList {
+		logrus.Infof("timed out: %s", img)
+	}
+	if len(p.OversizedImages) > 0 {
+		logrus.Infof("Oversized images, consider including them in the seed image instead: %d", len(p.OversizedImages))
+		for img, size := range p.OversizedImages {
+			logrus.Infof("oversized: %s (%d bytes)", img, size)
+		}
+	}
+	for img, saved := range p.BytesSaved {
+		logrus.Infof("partially pulled: %s (%d bytes saved)", img, saved)
+	}
 }
 
 func (p *Progress) Persist(filename string) {
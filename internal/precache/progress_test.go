@@ -0,0 +1,89 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressSummary(t *testing.T) {
+	progress := &Progress{Total: 5, Pulled: 3, Skipped: 1}
+	assert.Equal(t, "total: 5 (pulled: 3, skipped: 1, failed: 0)", progress.Summary())
+
+	progress.Update(false, "registry.example.com/stalled:latest")
+	progress.RecordTimeout("registry.example.com/stalled:latest")
+	progress.RecordOversized("registry.example.com/large:latest", 3<<30)
+	assert.Equal(t, "total: 5 (pulled: 3, skipped: 1, failed: 1, timed out: 1, oversized: 1)", progress.Summary())
+	assert.Equal(t, int64(3<<30), progress.OversizedImages["registry.example.com/large:latest"])
+
+	progress.RecordBytesSaved("registry.example.com/chunked:v1", 1000)
+	progress.RecordBytesSaved("registry.example.com/chunked:v2", 500)
+	assert.Equal(t, "total: 5 (pulled: 3, skipped: 1, failed: 1, timed out: 1, oversized: 1, saved by partial pulls: 1500 bytes)", progress.Summary())
+}
+
+func TestProgressSummaryResumed(t *testing.T) {
+	progress := &Progress{Total: 5, Pulled: 2, Skipped: 3, Resumed: 2}
+	assert.Equal(t, "total: 5 (pulled: 2, skipped: 3, failed: 0, resumed: 2)", progress.Summary())
+	assert.Equal(t, 100, progress.Percent())
+}
+
+func TestProgressFailedImages(t *testing.T) {
+	progress := &Progress{Total: 3}
+	progress.RecordFailure("registry.example.com/flaky:v2", 5, errors.New("502 Bad Gateway"))
+	progress.RecordFailure("registry.example.com/flaky:v1", 1, errors.New("image pull timed out after 30m0s"))
+
+	data, err := json.Marshal(progress)
+	assert.NoError(t, err)
+	persisted := &Progress{}
+	assert.NoError(t, json.Unmarshal(data, persisted))
+	assert.Equal(t, progress.FailedImages, persisted.FailedImages)
+
+	assert.Equal(t, "permanently failed pulls: registry.example.com/flaky:v1 (1 attempts: image pull timed out after 30m0s), "+
+		"registry.example.com/flaky:v2 (5 attempts: 502 Bad Gateway)", FailedImagesSummary(progress.FailedImages))
+	// The summary does not reorder the failed images
+	assert.Equal(t, "registry.example.com/flaky:v2", progress.FailedImages[0].Image)
+}
+
+func TestProgressSummaryVerifyOnly(t *testing.T) {
+	progress := &Progress{Total: 4, Skipped: 1, VerifyOnly: true}
+	progress.RecordWouldPull("registry.example.com/a:v1", 1000)
+	progress.RecordWouldPull("registry.example.com/b:v1", 2000)
+	progress.Update(false, "registry.example.com/missing:v1")
+	assert.Equal(t, "total: 4 (would pull: 2, 3000 bytes, skipped: 1, unavailable: 1)", progress.Summary())
+	assert.Equal(t, 0, progress.Pulled)
+}
+
+func TestProgressPercent(t *testing.T) {
+	progress := &Progress{}
+	assert.Equal(t, 0, progress.Percent())
+
+	progress = &Progress{Total: 311, Pulled: 120, Skipped: 20}
+	progress.Update(false, "registry.example.com/missing:v1")
+	progress.RecordPulledBytes(1000)
+	progress.RecordPulledBytes(500)
+	assert.Equal(t, 141, progress.Processed())
+	assert.Equal(t, 45, progress.Percent())
+	assert.Equal(t, int64(1500), progress.PulledBytes)
+
+	progress = &Progress{Total: 4, Skipped: 1, VerifyOnly: true}
+	progress.RecordWouldPull("registry.example.com/a:v1", 1000)
+	assert.Equal(t, 50, progress.Percent())
+}
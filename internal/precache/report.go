@@ -0,0 +1,268 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/common/collections"
+)
+
+// Precaching report export, for CI pipelines to assert on the image pull results
+const (
+	LcaPrecacheReportConfigMapName string = "lca-precache-report"
+	// ReportJSONKey is the key of the JSON report in the report ConfigMap
+	ReportJSONKey string = "report.json"
+	// ReportJUnitKey is the key of the JUnit XML report in the report ConfigMap, when enabled
+	ReportJUnitKey string = "junit.xml"
+
+	// ReportFile and JUnitReportFile are the workspace files holding the reports
+	ReportFile      = utils.IBUWorkspacePath + "/precache_report.json"
+	JUnitReportFile = utils.IBUWorkspacePath + "/precache_report.xml"
+)
+
+// Image results of the precaching report
+const (
+	// ImagePrecached is an image present on the node, pulled by the job or already there
+	ImagePrecached string = "Precached"
+	ImageFailed    string = "Failed"
+	// ImageTimedOut is a failed pull that reached the pull time limit
+	ImageTimedOut string = "TimedOut"
+	// ImageNotPulled is an image the job did not get to pull before failing
+	ImageNotPulled string = "NotPulled"
+	// ImageAvailable and ImageUnavailable are the results of the images checked in their registry only
+	ImageAvailable   string = "Available"
+	ImageUnavailable string = "Unavailable"
+)
+
+// reportFile and junitReportFile are the host paths of ReportFile and JUnitReportFile, overridden in tests
+var (
+	reportFile      = common.PathOutsideChroot(ReportFile)
+	junitReportFile = common.PathOutsideChroot(JUnitReportFile)
+)
+
+// Report is the final precaching report, with the result of each image
+type Report struct {
+	Status     string        `json:"status"`
+	VerifyOnly bool          `json:"verifyOnly,omitempty"`
+	Summary    string        `json:"summary"`
+	Total      int           `json:"total"`
+	Pulled     int           `json:"pulled"`
+	Skipped    int           `json:"skipped"`
+	Failed     int           `json:"failed"`
+	Images     []ImageResult `json:"images"`
+}
+
+// ImageResult is the result of the precaching of an image
+type ImageResult struct {
+	Image  string `json:"image"`
+	Result string `json:"result"`
+	Digest string `json:"digest,omitempty"`
+	// SizeBytes is reported for the oversized images only
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+}
+
+// NewReport builds the report of the precaching job from its final status, the list of images to pre-cache and the
+// record of the pre-cached images, which may be nil
+func NewReport(status *Status, imageList []string, cache *Cache) *Report {
+	progress := &status.Progress
+	report := &Report{
+		Status:     status.Status,
+		VerifyOnly: progress.VerifyOnly,
+		Summary:    progress.Summary(),
+		Total:      progress.Total,
+		Pulled:     progress.Pulled,
+		Skipped:    progress.Skipped,
+		Failed:     progress.Failed,
+		Images:     make([]ImageResult, 0, len(imageList)),
+	}
+
+	failed := collections.NewOrderedSet(progress.FailedPullList...)
+	timedOut := collections.NewOrderedSet(progress.TimedOutPullList...)
+	for _, image := range imageList {
+		result := ImageResult{Image: image, SizeBytes: progress.OversizedImages[image]}
+		if cache != nil {
+			result.Digest = cache.Images[image]
+		}
+		switch {
+		case progress.VerifyOnly && failed.Contains(image):
+			result.Result = ImageUnavailable
+		case progress.VerifyOnly:
+			result.Result = ImageAvailable
+		case timedOut.Contains(image):
+			result.Result = ImageTimedOut
+		case failed.Contains(image):
+			result.Result = ImageFailed
+		case result.Digest != "" || status.Status == Succeeded:
+			result.Result = ImagePrecached
+		default:
+			result.Result = ImageNotPulled
+		}
+		report.Images = append(report.Images, result)
+	}
+
+	// The images pre-cached by a previous attempt are not in the list of the job
+	if cache != nil {
+		cached := make([]string, 0, len(cache.Images))
+		for image := range cache.Images {
+			cached = append(cached, image)
+		}
+		sort.Strings(cached)
+		for _, image := range collections.Difference(cached, imageList) {
+			report.Images = append(report.Images, ImageResult{Image: image, Result: ImagePrecached, Digest: cache.Images[image]})
+		}
+	}
+	return report
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// JUnit renders the report as JUnit XML, with a test case per image failing when the image was not pre-cached, or is
+// unavailable in its registry
+func (r *Report) JUnit() ([]byte, error) {
+	suite := junitTestSuite{Name: "precache", Tests: len(r.Images)}
+	for _, image := range r.Images {
+		testCase := junitTestCase{ClassName: "precache", Name: image.Image}
+		switch image.Result {
+		case ImageFailed, ImageTimedOut, ImageUnavailable:
+			testCase.Failure = &junitFailure{Message: image.Result}
+			suite.Failures++
+		case ImageNotPulled:
+			testCase.Skipped = &junitSkipped{Message: image.Result}
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	data, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal precaching JUnit report: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// ExportReport writes the final precaching report to the workspace and to the report ConfigMap, as JSON and as JUnit
+// XML if junit is set, for CI pipelines to assert on the image pull results
+func (h *PHandler) ExportReport(ctx context.Context, status *Status, junit bool) (*Report, error) {
+	var imageList []string
+	if data, err := os.ReadFile(configFile); err == nil {
+		config := &Config{}
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("failed to parse precaching config %s: %w", configFile, err)
+		}
+		imageList = config.ImageList
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read precaching config %s: %w", configFile, err)
+	}
+	cache, err := LoadCache(cacheFile)
+	if err != nil {
+		return nil, err
+	}
+	report := NewReport(status, imageList, cache)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal precaching report: %w", err)
+	}
+	reportData := map[string]string{ReportJSONKey: string(data)}
+	if err := os.WriteFile(reportFile, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write precaching report %s: %w", reportFile, err)
+	}
+	if junit {
+		junitData, err := report.JUnit()
+		if err != nil {
+			return nil, err
+		}
+		reportData[ReportJUnitKey] = string(junitData)
+		if err := os.WriteFile(junitReportFile, junitData, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write precaching JUnit report %s: %w", junitReportFile, err)
+		}
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := h.Client.Get(ctx, types.NamespacedName{Name: LcaPrecacheReportConfigMapName, Namespace: common.LcaNamespace}, cm); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get configmap %s: %w", LcaPrecacheReportConfigMapName, err)
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: LcaPrecacheReportConfigMapName, Namespace: common.LcaNamespace},
+			Data:       reportData,
+		}
+		if err := h.Client.Create(ctx, cm); err != nil {
+			return nil, fmt.Errorf("failed to create configmap %s: %w", LcaPrecacheReportConfigMapName, err)
+		}
+	} else {
+		cm.Data = reportData
+		if err := h.Client.Update(ctx, cm); err != nil {
+			return nil, fmt.Errorf("failed to update configmap %s: %w", LcaPrecacheReportConfigMapName, err)
+		}
+	}
+	h.Log.Info("Exported the precaching report", "configmap", LcaPrecacheReportConfigMapName, "junit", junit)
+
+	return report, nil
+}
+
+// DeleteReport deletes the report ConfigMap and files of a previous precaching
+func (h *PHandler) DeleteReport(ctx context.Context) error {
+	if err := deleteConfigMap(ctx, h.Client, LcaPrecacheReportConfigMapName, common.LcaNamespace); err != nil {
+		return err
+	}
+	for _, file := range []string{reportFile, junitReportFile} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete precaching report %s: %w", file, err)
+		}
+	}
+	return nil
+}
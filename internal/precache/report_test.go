@@ -0,0 +1,114 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+func TestNewReport(t *testing.T) {
+	cache := &Cache{}
+	cache.Record("quay.io/app:cached", "sha256:cached")
+	cache.Record("quay.io/app:pulled", "sha256:pulled")
+	imageList := []string{"quay.io/app:pulled", "quay.io/app:failed", "quay.io/app:slow", "quay.io/app:pending"}
+
+	status := &Status{Status: Failed, Progress: Progress{
+		Total: 4, Pulled: 1, Failed: 2,
+		FailedPullList:   []string{"quay.io/app:failed", "quay.io/app:slow"},
+		TimedOutPullList: []string{"quay.io/app:slow"},
+		OversizedImages:  map[string]int64{"quay.io/app:pulled": 3 << 30},
+	}}
+	report := NewReport(status, imageList, cache)
+	assert.Equal(t, Failed, report.Status)
+	assert.Equal(t, "total: 4 (pulled: 1, skipped: 0, failed: 2, timed out: 1, oversized: 1)", report.Summary)
+	assert.Equal(t, []ImageResult{
+		{Image: "quay.io/app:pulled", Result: ImagePrecached, Digest: "sha256:pulled", SizeBytes: 3 << 30},
+		{Image: "quay.io/app:failed", Result: ImageFailed},
+		{Image: "quay.io/app:slow", Result: ImageTimedOut},
+		{Image: "quay.io/app:pending", Result: ImageNotPulled},
+		{Image: "quay.io/app:cached", Result: ImagePrecached, Digest: "sha256:cached"},
+	}, report.Images)
+
+	junit, err := report.JUnit()
+	assert.NoError(t, err)
+	assert.Contains(t, string(junit), `<testsuite name="precache" tests="5" failures="2" skipped="1">`)
+	assert.Contains(t, string(junit), `<testcase classname="precache" name="quay.io/app:slow">
+      <failure message="TimedOut"></failure>`)
+
+	// Verify only
+	status = &Status{Status: Succeeded, Progress: Progress{Total: 2, VerifyOnly: true, WouldPull: 1, Failed: 1,
+		FailedPullList: []string{"quay.io/app:failed"}}}
+	report = NewReport(status, []string{"quay.io/app:pulled", "quay.io/app:failed"}, nil)
+	assert.Equal(t, []ImageResult{
+		{Image: "quay.io/app:pulled", Result: ImageAvailable},
+		{Image: "quay.io/app:failed", Result: ImageUnavailable},
+	}, report.Images)
+}
+
+func TestExportReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldConfigFile, oldCacheFile, oldReportFile, oldJUnitReportFile := configFile, cacheFile, reportFile, junitReportFile
+	configFile, cacheFile = filepath.Join(tmpDir, "config.json"), filepath.Join(tmpDir, "cache.json")
+	reportFile, junitReportFile = filepath.Join(tmpDir, "report.json"), filepath.Join(tmpDir, "report.xml")
+	defer func() {
+		configFile, cacheFile, reportFile, junitReportFile = oldConfigFile, oldCacheFile, oldReportFile, oldJUnitReportFile
+	}()
+	assert.NoError(t, persistConfig(&Config{ImageList: []string{"quay.io/app:1", "quay.io/app:2"}}, configFile))
+
+	fakeClient, err := getFakeClientFromObjects()
+	assert.NoError(t, err)
+	handler := &PHandler{Client: fakeClient, Log: ctrl.Log.WithName("Precache")}
+	status := &Status{Status: Succeeded, Progress: Progress{Total: 2, Pulled: 2}}
+
+	_, err = handler.ExportReport(context.Background(), status, false)
+	assert.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, fakeClient.Get(context.Background(),
+		types.NamespacedName{Name: LcaPrecacheReportConfigMapName, Namespace: common.LcaNamespace}, cm))
+	report := &Report{}
+	assert.NoError(t, json.Unmarshal([]byte(cm.Data[ReportJSONKey]), report))
+	assert.Len(t, report.Images, 2)
+	assert.Equal(t, ImagePrecached, report.Images[1].Result)
+	assert.NotContains(t, cm.Data, ReportJUnitKey)
+
+	// The JUnit report is added on request
+	_, err = handler.ExportReport(context.Background(), status, true)
+	assert.NoError(t, err)
+	assert.NoError(t, fakeClient.Get(context.Background(),
+		types.NamespacedName{Name: LcaPrecacheReportConfigMapName, Namespace: common.LcaNamespace}, cm))
+	assert.Contains(t, cm.Data[ReportJUnitKey], `<testsuite name="precache" tests="2" failures="0" skipped="0">`)
+	junit, err := os.ReadFile(junitReportFile)
+	assert.NoError(t, err)
+	assert.Equal(t, cm.Data[ReportJUnitKey], string(junit))
+
+	assert.NoError(t, handler.DeleteReport(context.Background()))
+	_, err = os.Stat(reportFile)
+	assert.True(t, os.IsNotExist(err))
+	assert.Error(t, fakeClient.Get(context.Background(),
+		types.NamespacedName{Name: LcaPrecacheReportConfigMapName, Namespace: common.LcaNamespace}, cm))
+}
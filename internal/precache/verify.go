@@ -0,0 +1,106 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+// storedImage is an image in the container storage of the host, as listed by podman images
+type storedImage struct {
+	// Names are the references the image is tagged with
+	Names []string `json:"Names"`
+	// RepoDigests are the references by digest of the image
+	RepoDigests []string `json:"RepoDigests"`
+	Digest      string   `json:"Digest"`
+}
+
+// hasDigest tells whether the image has the given digest, its own or the one of a manifest list it was pulled from
+func (i *storedImage) hasDigest(digest string) bool {
+	if i.Digest == digest {
+		return true
+	}
+	for _, repoDigest := range i.RepoDigests {
+		if strings.HasSuffix(repoDigest, "@"+digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyImages checks that the images of the precache list recorded in the given file are in the container storage
+// of the host, shared by the stateroots, with the digest they were pre-cached with. It returns the missing images,
+// in the order of the list, with the reason. In best-effort mode, the images not recorded as pre-cached, which failed
+// to precache, are not expected. Nothing is verified when the record has no image list, e.g. written by a previous
+// version, or belongs to another seed image.
+func VerifyImages(ctx context.Context, executor ops.Execute, filename, seedImage string, bestEffort bool) ([]string, error) {
+	cache, err := LoadCache(filename)
+	if err != nil {
+		return nil, err
+	}
+	if cache == nil || len(cache.ImageList) == 0 || cache.SeedImage != seedImage {
+		return nil, nil
+	}
+
+	byName, err := listStoredImages(ctx, executor)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, image := range cache.ImageList {
+		recorded := cache.Images[image]
+		stored, found := byName[image]
+		switch {
+		case !found && recorded == "" && bestEffort:
+			// failed to precache, reported when Prep completed, and pulled after the pivot
+			continue
+		case !found:
+			missing = append(missing, fmt.Sprintf("%s (not found)", image))
+		case recorded != "" && !strings.Contains(image, "@") && !stored.hasDigest(recorded):
+			// the tag was pulled again with another digest since precaching
+			missing = append(missing, fmt.Sprintf("%s (digest %s instead of %s)", image, stored.Digest, recorded))
+		}
+	}
+	return missing, nil
+}
+
+// listStoredImages returns the images in the container storage of the host by their names and references by digest
+func listStoredImages(ctx context.Context, executor ops.Execute) (map[string]*storedImage, error) {
+	output, err := executor.ExecuteWithContext(ctx, "podman", "images", "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	var images []storedImage
+	if output != "" {
+		if err := json.Unmarshal([]byte(output), &images); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal images output: %w", err)
+		}
+	}
+	byName := make(map[string]*storedImage)
+	for i := range images {
+		for _, name := range append(images[i].Names, images[i].RepoDigests...) {
+			byName[name] = &images[i]
+		}
+	}
+	return byName, nil
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package precache
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+func TestVerifyImages(t *testing.T) {
+	seedImage := "quay.io/seed:4.15"
+	podmanImages := `[
+		{"Names":["quay.io/a:1"],"RepoDigests":["quay.io/a@sha256:aaa"],"Digest":"sha256:aaa"},
+		{"Names":["quay.io/b:1"],"RepoDigests":["quay.io/b@sha256:b2","quay.io/b@sha256:blist"],"Digest":"sha256:b2"},
+		{"Names":[],"RepoDigests":["quay.io/c@sha256:ccc"],"Digest":"sha256:ccc"}
+	]`
+
+	testCases := []struct {
+		name            string
+		cache           *Cache
+		bestEffort      bool
+		podmanError     error
+		expectedMissing []string
+		expectedError   string
+	}{
+		{
+			name: "all images present",
+			cache: &Cache{SeedImage: seedImage,
+				Images:    map[string]string{"quay.io/a:1": "sha256:aaa", "quay.io/b:1": "sha256:blist"},
+				ImageList: []string{"quay.io/a:1", "quay.io/b:1", "quay.io/c@sha256:ccc"}},
+		},
+		{
+			name: "missing and retagged images",
+			cache: &Cache{SeedImage: seedImage,
+				Images:    map[string]string{"quay.io/a:1": "sha256:old", "quay.io/d:1": "sha256:ddd"},
+				ImageList: []string{"quay.io/a:1", "quay.io/d:1", "quay.io/e@sha256:eee"}},
+			expectedMissing: []string{"quay.io/a:1 (digest sha256:aaa instead of sha256:old)", "quay.io/d:1 (not found)",
+				"quay.io/e@sha256:eee (not found)"},
+		},
+		{
+			name: "images failed to precache in best-effort mode",
+			cache: &Cache{SeedImage: seedImage,
+				Images:    map[string]string{"quay.io/d:1": "sha256:ddd"},
+				ImageList: []string{"quay.io/a:1", "quay.io/d:1", "quay.io/e@sha256:eee"}},
+			bestEffort:      true,
+			expectedMissing: []string{"quay.io/d:1 (not found)"},
+		},
+		{
+			name:  "record of another seed image",
+			cache: &Cache{SeedImage: "quay.io/seed:4.14", ImageList: []string{"quay.io/d:1"}},
+		},
+		{
+			name:  "record without image list",
+			cache: &Cache{SeedImage: seedImage, Images: map[string]string{"quay.io/d:1": "sha256:ddd"}},
+		},
+		{
+			name:          "podman failure",
+			cache:         &Cache{SeedImage: seedImage, ImageList: []string{"quay.io/a:1"}},
+			podmanError:   errors.New("podman failed"),
+			expectedError: "failed to list images: podman failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockExec := ops.NewMockExecute(gomock.NewController(t))
+			cacheFile := filepath.Join(t.TempDir(), "precache_cache.json")
+			assert.NoError(t, tc.cache.Persist(cacheFile))
+			if len(tc.cache.ImageList) != 0 && tc.cache.SeedImage == seedImage {
+				mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "images", "--format", "json").
+					Return(podmanImages, tc.podmanError)
+			}
+
+			missing, err := VerifyImages(context.Background(), mockExec, cacheFile, seedImage, tc.bestEffort)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedMissing, missing)
+		})
+	}
+
+	// No record, e.g. when Prep completed with a previous version
+	missing, err := VerifyImages(context.Background(), nil, filepath.Join(t.TempDir(), "missing.json"), seedImage, false)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workload
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxAuthRefreshes is the max number of times the pull of an image waits for the credentials to be refreshed
+	// after its registry rejected them, on top of MaxRetries
+	MaxAuthRefreshes int = 3
+	// authRefreshWait bounds the wait for the auth file to be refreshed, before pulling again with new bearer tokens
+	authRefreshWait = 2 * time.Minute
+	// authFilePollInterval is the interval at which the auth file is checked for a refresh
+	authFilePollInterval = 5 * time.Second
+)
+
+// authRefresher waits for the registry credentials to be refreshed when a registry rejects them, e.g. when the
+// short-lived credentials of a cloud registry expire during a multi-hour precaching, instead of failing the remaining
+// images. Each podman pull reads the auth file again and requests new bearer tokens from the registry, so a pull is
+// retried as soon as the auth file is rewritten, e.g. by the MCO after a pull-secret rotation, or after the wait for
+// the registries whose tokens expired without the credentials changing. The concurrent pulls rejected together wait
+// for the same refresh.
+type authRefresher struct {
+	authFile     string
+	wait         time.Duration
+	pollInterval time.Duration
+
+	mux         sync.Mutex
+	modTime     time.Time
+	refreshedAt time.Time
+	// refreshing is closed once the wait in progress, if any, is over
+	refreshing chan struct{}
+}
+
+func newAuthRefresher(authFile string) *authRefresher {
+	a := &authRefresher{authFile: authFile, wait: authRefreshWait, pollInterval: authFilePollInterval}
+	if info, err := os.Stat(hostPath(authFile)); err == nil {
+		a.modTime = info.ModTime()
+	}
+	return a
+}
+
+// waitForRefresh waits for the auth file to be refreshed after the registry rejected the credentials of a pull started
+// at the given time, unless a refresh already happened since, or until the context is canceled. The first rejected pull
+// polls the auth file, the others wait for it to be done.
+func (a *authRefresher) waitForRefresh(ctx context.Context, image string, pullStart time.Time) error {
+	a.mux.Lock()
+	if a.refreshedAt.After(pullStart) {
+		a.mux.Unlock()
+		log.Infof("Credentials refreshed since the pull of %s started, pulling it again", image)
+		return nil
+	}
+	if refreshing := a.refreshing; refreshing != nil {
+		a.mux.Unlock()
+		log.Infof("Registry rejected the credentials for %s, waiting for the auth file refresh in progress", image)
+		select {
+		case <-ctx.Done():
+		case <-refreshing:
+		}
+		return ctx.Err()
+	}
+	refreshing := make(chan struct{})
+	a.refreshing = refreshing
+	modTime := a.modTime
+	a.mux.Unlock()
+
+	log.Infof("Registry rejected the credentials for %s, waiting up to %s for the auth file to be refreshed", image, a.wait)
+	modTime, err := a.poll(ctx, modTime)
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	a.refreshing = nil
+	close(refreshing)
+	if err != nil {
+		return err
+	}
+	a.modTime = modTime
+	a.refreshedAt = time.Now()
+	return nil
+}
+
+// poll checks the auth file until its modification time differs from the given one, the wait is over or the context is
+// canceled, returning its modification time
+func (a *authRefresher) poll(ctx context.Context, modTime time.Time) (time.Time, error) {
+	timeout := time.NewTimer(a.wait)
+	defer timeout.Stop()
+	ticker := time.NewTicker(a.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return modTime, ctx.Err()
+		case <-timeout.C:
+			return modTime, nil
+		case <-ticker.C:
+			if info, err := os.Stat(hostPath(a.authFile)); err == nil && !info.ModTime().Equal(modTime) {
+				log.Infof("Auth file %s refreshed", a.authFile)
+				return info.ModTime(), nil
+			}
+		}
+	}
+}
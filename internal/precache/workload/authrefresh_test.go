@@ -0,0 +1,102 @@
+package workload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestAuthRefresher returns an auth refresher of an auth file in a temporary HostRoot, polling it every millisecond
+func newTestAuthRefresher(t *testing.T, wait time.Duration) *authRefresher {
+	origHostRoot := HostRoot
+	t.Cleanup(func() { HostRoot = origHostRoot })
+	HostRoot = t.TempDir()
+
+	authFile := "/auth.json"
+	assert.NoError(t, os.WriteFile(hostPath(authFile), []byte("{}"), 0o600))
+	a := newAuthRefresher(authFile)
+	a.wait = wait
+	a.pollInterval = time.Millisecond
+	return a
+}
+
+func TestWaitForRefresh(t *testing.T) {
+	t.Run("refreshed since the pull started", func(t *testing.T) {
+		a := newTestAuthRefresher(t, time.Hour)
+		pullStart := time.Now()
+		a.refreshedAt = pullStart.Add(time.Second)
+
+		start := time.Now()
+		assert.NoError(t, a.waitForRefresh(context.Background(), "quay.io/a/b:1", pullStart))
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, pullStart.Add(time.Second), a.refreshedAt)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		a := newTestAuthRefresher(t, 50*time.Millisecond)
+		pullStart := time.Now()
+
+		start := time.Now()
+		assert.NoError(t, a.waitForRefresh(context.Background(), "quay.io/a/b:1", pullStart))
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.True(t, a.refreshedAt.After(pullStart))
+		assert.Nil(t, a.refreshing)
+
+		// a pull started before the refresh pulls again right away
+		start = time.Now()
+		assert.NoError(t, a.waitForRefresh(context.Background(), "quay.io/a/c:1", pullStart))
+		assert.Less(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("auth file refreshed", func(t *testing.T) {
+		a := newTestAuthRefresher(t, time.Hour)
+		modTime := time.Now().Add(time.Minute)
+		assert.NoError(t, os.Chtimes(filepath.Join(HostRoot, a.authFile), modTime, modTime))
+
+		assert.NoError(t, a.waitForRefresh(context.Background(), "quay.io/a/b:1", time.Now()))
+		assert.True(t, a.modTime.Equal(modTime))
+	})
+
+	t.Run("concurrent pulls wait for the same refresh", func(t *testing.T) {
+		a := newTestAuthRefresher(t, 50*time.Millisecond)
+		pullStart := time.Now()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, a.waitForRefresh(context.Background(), "quay.io/a/b:1", pullStart))
+			}()
+		}
+		start := time.Now()
+		wg.Wait()
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		a := newTestAuthRefresher(t, time.Hour)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// the pull waiting for the refresh in progress is not blocked by the lock
+		waiting := make(chan error)
+		go func() { waiting <- a.waitForRefresh(ctx, "quay.io/a/b:1", time.Now()) }()
+		assert.Eventually(t, func() bool {
+			a.mux.Lock()
+			defer a.mux.Unlock()
+			return a.refreshing != nil
+		}, time.Second, time.Millisecond)
+		go func() { waiting <- a.waitForRefresh(ctx, "quay.io/a/c:1", time.Now()) }()
+
+		cancel()
+		assert.ErrorIs(t, <-waiting, context.Canceled)
+		assert.ErrorIs(t, <-waiting, context.Canceled)
+		assert.True(t, a.refreshedAt.IsZero())
+		assert.Nil(t, a.refreshing)
+	})
+}
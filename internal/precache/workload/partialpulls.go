@@ -0,0 +1,187 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workload
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/precache"
+)
+
+const (
+	// storageConfFile is the containers-storage configuration of the host
+	storageConfFile = "/etc/containers/storage.conf"
+	// partialPullsStorageConfFile is the copy of the host storage configuration enabling the partial pulls, used by
+	// the podman commands of the precaching job only
+	partialPullsStorageConfFile = "/var/tmp/lca-precache-storage.conf"
+	// envStorageConf overrides the containers-storage configuration of podman
+	envStorageConf = "CONTAINERS_STORAGE_CONF"
+
+	pullOptionsTable    = "[storage.options.pull_options]"
+	enablePartialImages = `enable_partial_images = "true"`
+
+	// zstdChunkedAnnotation is set on the layers compressed with zstd:chunked, which can be partially pulled
+	zstdChunkedAnnotation = "io.github.containers.zstd-chunked.manifest-checksum"
+
+	netDevFile = "/proc/net/dev"
+)
+
+// enablePartialPulls enables the partial pulls of the zstd:chunked layers for the podman commands of the precaching
+// job, with a copy of the host storage configuration. Partial pulls are only supported by the overlay driver, the
+// images are then pulled in full as usual.
+func enablePartialPulls() error {
+	content, err := os.ReadFile(storageConfFile)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", storageConfFile, err)
+	}
+	if !usesOverlayDriver(string(content)) {
+		return fmt.Errorf("partial pulls require the overlay storage driver")
+	}
+	if err := os.WriteFile(partialPullsStorageConfFile, []byte(renderPartialPullsStorageConf(string(content))), 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", partialPullsStorageConfFile, err)
+	}
+	if err := os.Setenv(envStorageConf, partialPullsStorageConfFile); err != nil {
+		return fmt.Errorf("failed to set %s: %w", envStorageConf, err)
+	}
+	return nil
+}
+
+// usesOverlayDriver returns whether the storage configuration selects the overlay driver
+func usesOverlayDriver(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if found && strings.TrimSpace(key) == "driver" {
+			return strings.Trim(strings.TrimSpace(value), `"`) == "overlay"
+		}
+	}
+	return false
+}
+
+// renderPartialPullsStorageConf returns the storage configuration with the partial pulls enabled, overriding any
+// enable_partial_images setting of its pull options
+func renderPartialPullsStorageConf(content string) string {
+	var lines []string
+	inPullOptions, enabled := false, false
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			inPullOptions = trimmed == pullOptionsTable
+		}
+		if inPullOptions && strings.HasPrefix(trimmed, "enable_partial_images") {
+			continue
+		}
+		lines = append(lines, line)
+		if trimmed == pullOptionsTable {
+			lines = append(lines, enablePartialImages)
+			enabled = true
+		}
+	}
+	if !enabled {
+		lines = append(lines, "", pullOptionsTable, enablePartialImages)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// layerData is the subset of the layers data reported by skopeo inspect
+type layerData struct {
+	Size        int64             `json:"Size"`
+	Annotations map[string]string `json:"Annotations"`
+}
+
+// imageLayers returns the compressed size of the layers of the image in the registry, and whether any of them is
+// compressed with zstd:chunked
+func imageLayers(image, authFile string, tlsArgs []string) (int64, bool, error) {
+	args := []string{"inspect", "--format", "{{json .LayersData}}"}
+	if authFile != "" {
+		args = append(args, "--authfile", authFile)
+	}
+	args = append(args, tlsArgs...)
+	output, err := Executor.Execute("skopeo", append(args, "docker://"+image)...)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed skopeo inspect for %s: %w", image, err)
+	}
+	var layers []layerData
+	if err := json.Unmarshal([]byte(output), &layers); err != nil {
+		return 0, false, fmt.Errorf("failed to parse the layers of %s: %w", image, err)
+	}
+
+	var size int64
+	chunked := false
+	for _, layer := range layers {
+		size += layer.Size
+		if _, ok := layer.Annotations[zstdChunkedAnnotation]; ok {
+			chunked = true
+		}
+	}
+	return size, chunked, nil
+}
+
+// networkRxBytes returns the bytes received on the network interfaces of the precaching job, other than loopback
+func networkRxBytes() (int64, error) {
+	file, err := os.Open(netDevFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", netDevFile, err)
+	}
+	defer file.Close()
+
+	var rx int64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		iface, counters, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(iface) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) == 0 {
+			continue
+		}
+		bytes, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		rx += bytes
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", netDevFile, err)
+	}
+	return rx, nil
+}
+
+// recordBytesSaved reports the bytes not downloaded by the partial pull of the image, which are the compressed size
+// of its layers minus the bytes received while pulling it. The bytes received by the concurrent pulls are counted as
+// well, so the reported figure is a lower bound.
+func recordBytesSaved(image, authFile string, tlsArgs []string, received int64, progress *precache.Progress) {
+	size, chunked, err := imageLayers(image, authFile, tlsArgs)
+	if err != nil {
+		log.Infof("Unable to determine the layers of %s, not reporting its partial pull: %v", image, err)
+		return
+	}
+	if !chunked {
+		return
+	}
+	if saved := size - received; saved > 0 {
+		log.Infof("Partially pulled %s, saving %d of %d bytes", image, saved, size)
+		progress.RecordBytesSaved(image, saved)
+	}
+}
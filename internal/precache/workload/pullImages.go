@@ -17,21 +17,50 @@
 package workload
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/openshift-kni/lifecycle-agent/internal/artifactsource"
+	"github.com/openshift-kni/lifecycle-agent/internal/lcaerrors"
 	"github.com/openshift-kni/lifecycle-agent/internal/precache"
+	"github.com/openshift-kni/lifecycle-agent/internal/registrytls"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 )
 
 // MaxRetries is the max number of retries for pulling an image before marking it as failed
 const MaxRetries int = 5
 
+// initialRetryBackoff is the wait before the first retry of a failed image pull
+var initialRetryBackoff = precache.DefaultRetryBackoff
+
+// retryBackoff returns the wait before the retry following the given failed attempt, starting at
+// initialRetryBackoff and doubled at each attempt up to precache.MaxRetryBackoff
+func retryBackoff(attempt int) time.Duration {
+	backoff := initialRetryBackoff
+	for i := 1; i < attempt && backoff < precache.MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > precache.MaxRetryBackoff {
+		return precache.MaxRetryBackoff
+	}
+	return backoff
+}
+
+// timeoutExitCode is the exit code of the timeout command when the time limit is reached
+const timeoutExitCode = 124
+
+// errPullTimeout is returned when an image pull reaches the pull time limit, which is not retried
+var errPullTimeout = errors.New("image pull timed out")
+
 // Podman auth-file related constants
 const (
 	EnvAuthFile     string = "PULL_SECRET_PATH"
@@ -40,6 +69,16 @@ const (
 
 var Executor = ops.NewRegularExecutor(log.StandardLogger(), false)
 
+// HostRoot is the path of the host root filesystem, prefixing the host files accessed by the workload itself, such as
+// the progress, the precache record and the auth file. It is empty in the precaching job, chrooted to the host, and
+// set when precaching in the manager process, the commands being run on the host by Executor.
+var HostRoot = ""
+
+// hostPath returns the path of the given host file from the workload
+func hostPath(path string) string {
+	return filepath.Join(HostRoot, path)
+}
+
 // CheckPodman verifies that podman is running by checking the version of podman
 func CheckPodman() bool {
 	if _, err := Executor.ExecuteWithLiveLogger("podman", []string{"version"}...); err != nil {
@@ -56,40 +95,250 @@ func podmanImgExists(image string) bool {
 	return true
 }
 
-// podmanImgPull pulls the specified image via podman CLI
-func podmanImgPull(image, authFile string) error {
+// podmanImgPull pulls the specified image via podman CLI, within the time limit of the config, with the given TLS
+// arguments overriding the TLS configuration of its registry
+func podmanImgPull(ctx context.Context, image, authFile string, tlsArgs []string, config pullConfig) error {
 	args := []string{"pull", image}
 	if authFile != "" {
 		args = append(args, []string{"--authfile", authFile}...)
 	}
-	if _, err := Executor.ExecuteWithLiveLogger("podman", args...); err != nil {
-		return fmt.Errorf("failed podman pull with args %s: %w", args, err)
+	args = append(args, tlsArgs...)
+	return executeWithTimeout(ctx, config.timeout, config.wrapperArgs(), "podman", args...)
+}
+
+// executeWithTimeout runs the command within the given time limit, wrapped by the given commands such as nice,
+// returning errPullTimeout when it is reached. The command is killed when the context is canceled, if it can be, its
+// output only being logged live otherwise, as in the precaching job.
+func executeWithTimeout(ctx context.Context, timeout time.Duration, wrapperArgs []string, command string, args ...string) error {
+	timeoutArgs := append([]string{strconv.Itoa(int(timeout.Seconds()))}, wrapperArgs...)
+	timeoutArgs = append(append(timeoutArgs, command), args...)
+	var err error
+	if ctx.Done() == nil {
+		_, err = Executor.ExecuteWithLiveLogger("timeout", timeoutArgs...)
+	} else {
+		_, err = Executor.ExecuteWithContext(ctx, "timeout", timeoutArgs...)
+	}
+	if err != nil {
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == timeoutExitCode {
+			return fmt.Errorf("%w after %s", errPullTimeout, timeout)
+		}
+		return fmt.Errorf("failed %s with args %s: %w", command, args, err)
 	}
 	return nil
 }
 
-// pullImage attempts to pull an image via podman CLI
-func pullImage(image, authFile string, progress *precache.Progress) error {
+// artifactSourceImgPull downloads the specified image from the artifact source and copies it to the container
+// storage under its reference, within the time limit of the config
+func artifactSourceImgPull(parent context.Context, image, authFile string, config pullConfig) error {
+	source := config.artifactSource
+	deadline := time.Now().Add(config.timeout)
+	ctx, cancel := context.WithDeadline(parent, deadline)
+	defer cancel()
+
+	artifact, err := source.Fetch(ctx, image, hostPath(artifactsource.Dir))
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %s", errPullTimeout, config.timeout)
+		}
+		return fmt.Errorf("failed to fetch image from %s artifact source: %w", source.Type(), err)
+	}
+	defer func() {
+		if err := artifact.Remove(); err != nil {
+			log.Errorf("Failed to remove artifact of %s: %v", image, err)
+		}
+	}()
+
+	remaining := time.Until(deadline)
+	if remaining < time.Second {
+		return fmt.Errorf("%w after %s", errPullTimeout, config.timeout)
+	}
+	return executeWithTimeout(parent, remaining, config.wrapperArgs(), "skopeo",
+		artifactsource.CopyArgs(artifact.TransportReference(HostRoot), image, authFile)...)
+}
+
+// podmanImgInspect returns the digest and the size of the given image via podman CLI
+func podmanImgInspect(image string) (string, int64, error) {
+	output, err := Executor.Execute("podman", []string{"image", "inspect", "--format", "{{.Digest}} {{.Size}}", image}...)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed podman image inspect for %s: %w", image, err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return "", 0, fmt.Errorf("unexpected podman image inspect output for %s: %s", image, output)
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to parse size of %s: %w", image, err)
+	}
+	return fields[0], size, nil
+}
+
+// recordImage stamps the successfully pulled image with its digest in the precache record, adds its size to the
+// progress, and reports it in the progress if it is larger than the oversized image threshold
+func recordImage(image string, oversizedImageThreshold int64, progress *precache.Progress, cache *precache.Cache) {
+	digest, size, err := podmanImgInspect(image)
+	if err != nil || digest == "" {
+		log.Infof("Unable to determine digest of %s, not recording it as pre-cached: %v", image, err)
+		return
+	}
+	progress.RecordPulledBytes(size)
+	if size > oversizedImageThreshold {
+		log.Infof("Image %s is oversized (%d bytes), consider including it in the seed image", image, size)
+		progress.RecordOversized(image, size)
+	}
+	cache.Record(image, digest)
+	if err := cache.Persist(hostPath(precache.CacheFile)); err != nil {
+		log.Errorf("Failed to update precache record: %v", err)
+	}
+}
+
+// pullImage attempts to pull an image via podman CLI, until the context is canceled
+func pullImage(ctx context.Context, image, authFile string, config pullConfig, progress *precache.Progress,
+	cache *precache.Cache) error {
+
+	var rxBefore int64
+	if config.partialPulls {
+		rxBefore, _ = networkRxBytes()
+	}
 
 	var err error
+	attempts := 0
+	authRefreshes := 0
 	for i := 0; i < MaxRetries; i++ {
-		err = podmanImgPull(image, authFile)
+		attempts = i + 1
+		pullStart := time.Now()
+		if config.artifactSource != nil {
+			err = artifactSourceImgPull(ctx, image, authFile, config)
+		} else {
+			err = podmanImgPull(ctx, image, authFile, config.registryTLS.Args(image), config)
+		}
 		if err == nil {
 			log.Infof("Successfully pulled image: %s", image)
 			break
-		} else {
+		}
+		if ctx.Err() != nil {
+			log.Infof("Precaching stopped, not pulling %s anymore", image)
+			err = fmt.Errorf("precaching stopped: %w", ctx.Err())
+			break
+		}
+		if errors.Is(err, errPullTimeout) {
 			log.Infof("Attempt %d/%d: Failed to pull %s: %v", i+1, MaxRetries, image, err)
+			// a stalled or enormous image would consume the precaching window at each retry
+			progress.RecordTimeout(image)
+			break
+		}
+		if config.authRefresher != nil && authRefreshes < MaxAuthRefreshes && lcaerrors.IsRegistryAuthError(err) {
+			// expired credentials are not a failure of the pull, it does not consume its retries
+			authRefreshes++
+			log.Infof("Credentials rejected %d/%d: Failed to pull %s: %v", authRefreshes, MaxAuthRefreshes, image, err)
+			if err = config.authRefresher.waitForRefresh(ctx, image, pullStart); err != nil {
+				log.Infof("Precaching stopped, not pulling %s anymore", image)
+				err = fmt.Errorf("precaching stopped: %w", err)
+				break
+			}
+			i--
+			continue
+		}
+		log.Infof("Attempt %d/%d: Failed to pull %s: %v", i+1, MaxRetries, image, err)
+		if i+1 < MaxRetries {
+			// back off per image, a flaky registry failing the concurrent pulls at once
+			backoff := retryBackoff(i + 1)
+			log.Infof("Retrying to pull %s in %s", image, backoff)
+			select {
+			case <-ctx.Done():
+				log.Infof("Precaching stopped, not pulling %s anymore", image)
+				err = fmt.Errorf("precaching stopped: %w", ctx.Err())
+			case <-time.After(backoff):
+				continue
+			}
+			break
 		}
 	}
+	if err != nil {
+		progress.RecordFailure(image, attempts, err)
+	}
+	// record successful pulls so that a retry does not pull them again
+	if err == nil {
+		recordImage(image, config.oversizedImageThreshold, progress, cache)
+		if config.partialPulls && rxBefore > 0 {
+			if rxAfter, err := networkRxBytes(); err == nil {
+				recordBytesSaved(image, authFile, config.registryTLS.Args(image), rxAfter-rxBefore, progress)
+			}
+		}
+	}
+
 	// update precache progress tracker
 	progress.Update(err == nil, image)
 
 	// persist progress to file
-	progress.Persist(precache.StatusFile)
+	progress.Persist(hostPath(precache.StatusFile))
 
 	return err
 }
 
+// pullConfig bounds the pull of each image
+type pullConfig struct {
+	timeout                 time.Duration
+	oversizedImageThreshold int64
+	// partialPulls reports the bytes saved by the partial pulls of the zstd:chunked images
+	partialPulls bool
+	// verifyOnly only checks the images in their registry, without pulling them
+	verifyOnly bool
+	// artifactSource downloads the images from a site-local cache instead of their registry
+	artifactSource artifactsource.Source
+	// registryTLS overrides the TLS configuration of the registries of the images
+	registryTLS *registrytls.Config
+	// authRefresher waits for the credentials to be refreshed when a registry rejects them
+	authRefresher *authRefresher
+	// niceArgs run the pulls with the nice and ionice priorities when precaching in the manager process, the
+	// precaching job running with them
+	niceArgs []string
+	// maxMbps caps the download bandwidth of the pulls, in megabits per second, unlimited if 0
+	maxMbps int
+	// throttleArgs route the pulls through the throttling proxy capping their bandwidth, once it is started
+	throttleArgs []string
+}
+
+// wrapperArgs returns the commands wrapping the pull commands: nice and ionice, and env routing them through the
+// throttling proxy
+func (c pullConfig) wrapperArgs() []string {
+	return append(append([]string{}, c.niceArgs...), c.throttleArgs...)
+}
+
+// getPullConfig reads the pull time limit, oversized image threshold, partial pulls, verify only mode, bandwidth cap,
+// artifact source and registry TLS overrides set by the controller, using the defaults if unset
+func getPullConfig() pullConfig {
+	config := pullConfig{
+		timeout:                 precache.DefaultPullTimeout,
+		oversizedImageThreshold: precache.DefaultOversizedImageThreshold,
+	}
+	if seconds, err := strconv.Atoi(os.Getenv(precache.EnvPullTimeout)); err == nil && seconds > 0 {
+		config.timeout = time.Duration(seconds) * time.Second
+	}
+	if threshold, err := strconv.ParseInt(os.Getenv(precache.EnvOversizedImageThreshold), 10, 64); err == nil && threshold > 0 {
+		config.oversizedImageThreshold = threshold
+	}
+	config.partialPulls = os.Getenv(precache.EnvPartialPulls) == "TRUE"
+	config.verifyOnly = os.Getenv(precache.EnvVerifyOnly) == "TRUE"
+	if maxMbps, err := strconv.Atoi(os.Getenv(precache.EnvMaxMbps)); err == nil && maxMbps > 0 {
+		config.maxMbps = maxMbps
+	}
+	if sourceConfig, credentials, err := artifactsource.FromEnv(); err != nil {
+		log.Errorf("Pulling the images from their registry, invalid artifact source: %v", err)
+	} else if sourceConfig != nil {
+		if config.artifactSource, err = artifactsource.New(sourceConfig, credentials); err != nil {
+			log.Errorf("Pulling the images from their registry, invalid artifact source: %v", err)
+		}
+	}
+	registryTLS, err := registrytls.FromEnv()
+	if err != nil {
+		log.Errorf("Pulling the images with the TLS configuration of the host, invalid registry TLS overrides: %v", err)
+	}
+	config.registryTLS = registryTLS
+	return config
+}
+
 // getAuthFile returns the auth file for podman
 func GetAuthFile() (string, error) {
 	// Configure Podman auth file
@@ -99,7 +348,7 @@ func GetAuthFile() (string, error) {
 	}
 
 	// Check if authFile exists
-	if _, err := os.Stat(authFile); os.IsNotExist(err) {
+	if _, err := os.Stat(hostPath(authFile)); os.IsNotExist(err) {
 		return "", fmt.Errorf("failed to get authfile for podman: %w", err)
 	}
 	log.Info("Auth file for podman found.")
@@ -107,8 +356,19 @@ func GetAuthFile() (string, error) {
 	return authFile, nil
 }
 
-// PullImages pulls a list of images using podman
+// PullImages pulls a list of images using podman, configured by the environment of the precaching job
 func PullImages(precacheSpec []string, authFile string) *precache.Progress {
+	numThreads, err := strconv.Atoi(os.Getenv(precache.EnvMaxPullThreads))
+	if err != nil {
+		numThreads = precache.DefaultMaxConcurrentPulls
+	}
+	return pullImages(context.Background(), precacheSpec, authFile, numThreads, getPullConfig())
+}
+
+// pullImages pulls a list of images using podman with the given number of concurrent pulls, until the context is
+// canceled
+func pullImages(ctx context.Context, precacheSpec []string, authFile string, numThreads int,
+	config pullConfig) *precache.Progress {
 
 	// Initialize progress tracking
 	progress := &precache.Progress{
@@ -118,11 +378,28 @@ func PullImages(precacheSpec []string, authFile string) *precache.Progress {
 		Failed:  0,
 	}
 
+	// Load the record of images pre-cached by previous attempts, prepared by the controller
+	cache, err := precache.LoadCache(hostPath(precache.CacheFile))
+	if err != nil {
+		log.Errorf("Failed to load precache record, starting a new one: %v", err)
+	}
+	if cache == nil {
+		cache = &precache.Cache{}
+	}
+
 	var pullSpec = make([]string, 0, len(precacheSpec))
 	// Sift through image list to determine which images exist, and which need to be pulled
 	log.Infof("Checking the pre-cache spec file images to determine if they need to be pulled...")
 	var skip bool
 	for _, image := range precacheSpec {
+		// Resume from the images pulled by a previous pod of the job, e.g. evicted or stopped by a node reboot, tagged
+		// images included. The images recorded by the previous precaching attempts are not in the list.
+		if cache.Images[image] != "" && podmanImgExists(image) {
+			log.Infof("%s was pulled by a previous precaching pod, skipping it...", image)
+			progress.Skipped++
+			progress.Resumed++
+			continue
+		}
 		// Never skip tagged images, as the tagged image may have been updated
 		isUntagged := strings.Contains(image, "@sha")
 		skip = isUntagged && podmanImgExists(image)
@@ -137,23 +414,57 @@ func PullImages(precacheSpec []string, authFile string) *precache.Progress {
 
 	// Create wait group and pull images
 	var wg sync.WaitGroup
-	numThreads, err := strconv.Atoi(os.Getenv(precache.EnvMaxPullThreads))
-	if err != nil {
-		numThreads = precache.DefaultMaxConcurrentPulls
-	}
 	threads := make(chan struct{}, numThreads)
 	log.Infof("Configured precaching job to concurrently pull %d images.", numThreads)
+	if authFile != "" && config.artifactSource == nil {
+		config.authRefresher = newAuthRefresher(authFile)
+	}
+	log.Infof("Configured precaching job with a pull timeout of %s per image, reporting images larger than %d bytes.",
+		config.timeout, config.oversizedImageThreshold)
+	if config.artifactSource != nil {
+		log.Infof("Configured precaching job to pull the images from the %s artifact source.", config.artifactSource.Type())
+	}
+	if config.verifyOnly {
+		log.Info("Configured precaching job to only verify the images that would be pulled.")
+		verifyImages(pullSpec, authFile, config.registryTLS, numThreads, progress)
+		progress.Log()
+		progress.Persist(hostPath(precache.StatusFile))
+		return progress
+	}
+	if config.partialPulls {
+		if err := enablePartialPulls(); err != nil {
+			log.Infof("Pulling the images in full, unable to enable partial pulls: %v", err)
+			config.partialPulls = false
+		} else {
+			log.Info("Configured precaching job to partially pull the zstd:chunked images.")
+		}
+	}
+	if config.maxMbps > 0 {
+		proxy, err := startThrottlingProxy(config.maxMbps)
+		if err != nil {
+			log.Errorf("Pulling the images without bandwidth cap, unable to start the throttling proxy: %v", err)
+		} else {
+			defer proxy.stop()
+			config.throttleArgs = proxy.envArgs()
+			log.Infof("Configured precaching job to cap the download bandwidth at %d Mbps.", config.maxMbps)
+		}
+	}
 
 	// Start pulling images
 	for _, image := range pullSpec {
 		threads <- struct{}{}
+		if ctx.Err() != nil {
+			<-threads
+			log.Info("Precaching stopped, not pulling the remaining images")
+			break
+		}
 		wg.Add(1)
 		go func(image string) {
 			defer func() {
 				<-threads
 				wg.Done()
 			}()
-			err := pullImage(image, authFile, progress)
+			err := pullImage(ctx, image, authFile, config, progress, cache)
 
 			if err != nil {
 				log.Errorf("Failed to pull image: %s, error: %v", image, err)
@@ -170,11 +481,41 @@ func PullImages(precacheSpec []string, authFile string) *precache.Progress {
 	progress.Log()
 
 	// Store final precache progress report to file
-	progress.Persist(precache.StatusFile)
+	progress.Persist(hostPath(precache.StatusFile))
 
 	return progress
 }
 
+// verifyImages reports the images that would be pulled, with the compressed size of their layers, checking they are
+// available in their registry without pulling them
+func verifyImages(pullSpec []string, authFile string, registryTLS *registrytls.Config, numThreads int,
+	progress *precache.Progress) {
+	progress.VerifyOnly = true
+
+	var wg sync.WaitGroup
+	threads := make(chan struct{}, numThreads)
+	for _, image := range pullSpec {
+		threads <- struct{}{}
+		wg.Add(1)
+		go func(image string) {
+			defer func() {
+				<-threads
+				wg.Done()
+			}()
+			size, _, err := imageLayers(image, authFile, registryTLS.Args(image))
+			if err != nil {
+				log.Errorf("Image %s is not available: %v", image, err)
+				progress.Update(false, image)
+			} else {
+				log.Infof("Would pull image %s (%d bytes)", image, size)
+				progress.RecordWouldPull(image, size)
+			}
+			progress.Persist(hostPath(precache.StatusFile))
+		}(image)
+	}
+	wg.Wait()
+}
+
 func ValidatePrecache(status *precache.Progress, bestEffort bool) error {
 	// Check pre-caching execution status
 	if status.Failed != 0 {
@@ -196,6 +537,12 @@ func Precache(precacheSpec []string, authFile string, bestEffort bool) error {
 	status := PullImages(precacheSpec, authFile)
 	log.Info("Completed executing pre-caching")
 
+	if status.VerifyOnly {
+		// the unavailable images are part of the report, not a failure of the verification
+		log.Info("Verified the images to pre-cache, none pulled.")
+		return nil
+	}
+
 	if err := ValidatePrecache(status, bestEffort); err != nil {
 		return fmt.Errorf("failed to pre-cache one or more images")
 	}
@@ -203,3 +550,53 @@ func Precache(precacheSpec []string, authFile string, bestEffort bool) error {
 	log.Info("Pre-cached images successfully.")
 	return nil
 }
+
+// RunInProcess pre-caches the images of the config in the calling process instead of a precaching job, accessing the
+// host through HostRoot and Executor, until the context is canceled. The pulls are configured by the config rather
+// than the environment of the job, the artifact source with the given credentials.
+func RunInProcess(ctx context.Context, config *precache.Config, credentials *artifactsource.Credentials) error {
+	if !CheckPodman() {
+		return fmt.Errorf("failed to execute podman command")
+	}
+	authFile, err := GetAuthFile()
+	if err != nil {
+		return err
+	}
+
+	pull := pullConfig{
+		timeout:                 config.PullTimeout,
+		oversizedImageThreshold: config.OversizedImageThreshold,
+		verifyOnly:              config.VerifyOnly,
+		maxMbps:                 config.MaxMbps,
+		registryTLS:             config.RegistryTLS,
+		niceArgs: []string{
+			"nice", "-n", strconv.Itoa(config.NicePriority),
+			"ionice", "-c", strconv.Itoa(config.IoNiceClass), "-n", strconv.Itoa(config.IoNicePriority),
+		},
+	}
+	if pull.timeout <= 0 {
+		pull.timeout = precache.DefaultPullTimeout
+	}
+	if pull.oversizedImageThreshold <= 0 {
+		pull.oversizedImageThreshold = precache.DefaultOversizedImageThreshold
+	}
+	if config.ArtifactSource != nil {
+		if pull.artifactSource, err = artifactsource.New(config.ArtifactSource, credentials); err != nil {
+			return fmt.Errorf("failed to create artifact source: %w", err)
+		}
+	}
+	numThreads := config.NumConcurrentPulls
+	if numThreads < 1 {
+		numThreads = precache.DefaultMaxConcurrentPulls
+	}
+
+	status := pullImages(ctx, config.ImageList, authFile, numThreads, pull)
+	if ctx.Err() != nil {
+		return fmt.Errorf("precaching stopped: %w", ctx.Err())
+	}
+	if status.VerifyOnly {
+		log.Info("Verified the images to pre-cache, none pulled.")
+		return nil
+	}
+	return ValidatePrecache(status, config.BestEffort)
+}
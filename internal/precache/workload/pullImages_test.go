@@ -0,0 +1,235 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/precache"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+// exitError is the error of a command exiting with the given code
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return "exit status"
+}
+
+func (e *exitError) ExitCode() int {
+	return e.code
+}
+
+// setupWorkload mocks the executor of the workload, with its host files in a temporary HostRoot, and shortens the retry
+// backoff
+func setupWorkload(t *testing.T) *ops.MockExecute {
+	origExecutor, origHostRoot, origBackoff := Executor, HostRoot, initialRetryBackoff
+	t.Cleanup(func() {
+		Executor, HostRoot, initialRetryBackoff = origExecutor, origHostRoot, origBackoff
+	})
+
+	HostRoot = t.TempDir()
+	for _, file := range []string{precache.StatusFile, precache.CacheFile} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(hostPath(file)), 0o700))
+	}
+	initialRetryBackoff = time.Millisecond
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	Executor = mockExec
+	return mockExec
+}
+
+// expectPull expects the pull of the image within the one minute time limit of the tests
+func expectPull(mockExec *ops.MockExecute, image string) *gomock.Call {
+	return mockExec.EXPECT().ExecuteWithLiveLogger("timeout", "60", "podman", "pull", image, "--authfile", "/auth.json")
+}
+
+// expectInspect expects the inspection of the pulled image recording it
+func expectInspect(mockExec *ops.MockExecute, image, digest string) *gomock.Call {
+	return mockExec.EXPECT().Execute("podman", "image", "inspect", "--format", "{{.Digest}} {{.Size}}", image).
+		Return(digest+" 1024", nil)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, retryBackoff(1))
+	assert.Equal(t, 20*time.Second, retryBackoff(2))
+	assert.Equal(t, 160*time.Second, retryBackoff(5))
+	assert.Equal(t, precache.MaxRetryBackoff, retryBackoff(6))
+	assert.Equal(t, precache.MaxRetryBackoff, retryBackoff(20))
+}
+
+func TestPullImage(t *testing.T) {
+	image := "quay.io/org/image:v1"
+	config := pullConfig{timeout: time.Minute, oversizedImageThreshold: precache.DefaultOversizedImageThreshold}
+
+	t.Run("retry then success", func(t *testing.T) {
+		mockExec := setupWorkload(t)
+		gomock.InOrder(
+			expectPull(mockExec, image).Return("", errors.New("502 Bad Gateway")),
+			expectPull(mockExec, image).Return("", errors.New("502 Bad Gateway")),
+			expectPull(mockExec, image).Return("", nil),
+			expectInspect(mockExec, image, "sha256:1234"),
+		)
+		progress := &precache.Progress{Total: 1}
+		cache := &precache.Cache{}
+
+		assert.NoError(t, pullImage(context.Background(), image, "/auth.json", config, progress, cache))
+		assert.Equal(t, 1, progress.Pulled)
+		assert.Equal(t, 0, progress.Failed)
+		assert.Empty(t, progress.FailedImages)
+		assert.Equal(t, int64(1024), progress.PulledBytes)
+		assert.Equal(t, map[string]string{image: "sha256:1234"}, cache.Images)
+	})
+
+	t.Run("permanent failure", func(t *testing.T) {
+		mockExec := setupWorkload(t)
+		expectPull(mockExec, image).Return("", errors.New("manifest unknown")).Times(MaxRetries)
+		progress := &precache.Progress{Total: 1}
+		cache := &precache.Cache{}
+
+		assert.ErrorContains(t, pullImage(context.Background(), image, "/auth.json", config, progress, cache),
+			"manifest unknown")
+		assert.Equal(t, 1, progress.Failed)
+		assert.Equal(t, []string{image}, progress.FailedPullList)
+		assert.Equal(t, []precache.FailedImage{{Image: image, Attempts: MaxRetries,
+			Error: `failed podman with args [pull quay.io/org/image:v1 --authfile /auth.json]: manifest unknown`}},
+			progress.FailedImages)
+		assert.Equal(t, "permanently failed pulls: quay.io/org/image:v1 (5 attempts: failed podman with args "+
+			"[pull quay.io/org/image:v1 --authfile /auth.json]: manifest unknown)",
+			precache.FailedImagesSummary(progress.FailedImages))
+		assert.Empty(t, cache.Images)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		mockExec := setupWorkload(t)
+		expectPull(mockExec, image).Return("", &exitError{code: timeoutExitCode})
+		progress := &precache.Progress{Total: 1}
+
+		err := pullImage(context.Background(), image, "/auth.json", config, progress, &precache.Cache{})
+		assert.ErrorIs(t, err, errPullTimeout)
+		assert.Equal(t, []string{image}, progress.TimedOutPullList)
+		assert.Equal(t, 1, progress.Failed)
+		assert.Equal(t, 1, progress.FailedImages[0].Attempts)
+	})
+
+	t.Run("canceled during the backoff", func(t *testing.T) {
+		mockExec := setupWorkload(t)
+		initialRetryBackoff = time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+		mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "timeout", "60", "podman", "pull", image,
+			"--authfile", "/auth.json").DoAndReturn(func(context.Context, string, ...string) (string, error) {
+			time.AfterFunc(10*time.Millisecond, cancel)
+			return "", errors.New("502 Bad Gateway")
+		})
+		progress := &precache.Progress{Total: 1}
+
+		start := time.Now()
+		err := pullImage(ctx, image, "/auth.json", config, progress, &precache.Cache{})
+		assert.ErrorContains(t, err, "precaching stopped")
+		assert.Less(t, time.Since(start), time.Minute)
+		assert.Equal(t, 1, progress.Failed)
+	})
+}
+
+func TestPullImagesResume(t *testing.T) {
+	config := pullConfig{timeout: time.Minute, oversizedImageThreshold: precache.DefaultOversizedImageThreshold}
+	images := []string{"quay.io/org/a:v1", "quay.io/org/b:v1", "quay.io/org/c:v1"}
+
+	t.Run("completed images skipped", func(t *testing.T) {
+		mockExec := setupWorkload(t)
+		// a was pulled by a previous pod, b has no digest recorded and c is no longer in the container storage
+		assert.NoError(t, os.WriteFile(hostPath(precache.CacheFile),
+			[]byte(`{"images":{"quay.io/org/a:v1":"sha256:a","quay.io/org/b:v1":"",`+
+				`"quay.io/org/c:v1":"sha256:c"}}`), 0o600))
+		mockExec.EXPECT().Execute("podman", "image", "exists", "quay.io/org/a:v1").Return("", nil)
+		mockExec.EXPECT().Execute("podman", "image", "exists", "quay.io/org/c:v1").Return("", errors.New("exit status 1"))
+		for _, image := range images[1:] {
+			expectPull(mockExec, image).Return("", nil)
+			expectInspect(mockExec, image, "sha256:new")
+		}
+
+		progress := pullImages(context.Background(), images, "/auth.json", 1, config)
+		assert.Equal(t, 1, progress.Skipped)
+		assert.Equal(t, 1, progress.Resumed)
+		assert.Equal(t, 2, progress.Pulled)
+
+		cache, err := precache.LoadCache(hostPath(precache.CacheFile))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"quay.io/org/a:v1": "sha256:a", "quay.io/org/b:v1": "sha256:new",
+			"quay.io/org/c:v1": "sha256:new"}, cache.Images)
+	})
+
+	t.Run("corrupt record", func(t *testing.T) {
+		mockExec := setupWorkload(t)
+		assert.NoError(t, os.WriteFile(hostPath(precache.CacheFile), []byte(`{"images":{"quay.io/org/a:v1"`), 0o600))
+		for _, image := range images {
+			expectPull(mockExec, image).Return("", nil)
+			expectInspect(mockExec, image, "sha256:new")
+		}
+
+		progress := pullImages(context.Background(), images, "/auth.json", 1, config)
+		assert.Equal(t, 0, progress.Skipped)
+		assert.Equal(t, 0, progress.Resumed)
+		assert.Equal(t, 3, progress.Pulled)
+	})
+}
+
+func TestRunInProcess(t *testing.T) {
+	mockExec := setupWorkload(t)
+	t.Setenv(EnvAuthFile, "")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(hostPath(DefaultAuthFile)), 0o700))
+	assert.NoError(t, os.WriteFile(hostPath(DefaultAuthFile), []byte("{}"), 0o600))
+
+	handler := &precache.PHandler{Client: fake.NewClientBuilder().Build(), Log: logr.Discard(),
+		RunInProcess: RunInProcess}
+	image := "quay.io/org/a:v1"
+	config := precache.NewConfig([]string{image}, nil, "InProcess", true)
+	pullArgs := []string{"1800", "nice", "-n", "0", "ionice", "-c", "2", "-n", "4", "podman", "pull", image,
+		"--authfile", DefaultAuthFile}
+	queryStatus := func() *precache.Status {
+		status, err := handler.QueryJobStatus(context.Background())
+		assert.NoError(t, err)
+		return status
+	}
+
+	// a running precaching is active until stopped, its pulls being killed
+	started := make(chan struct{})
+	mockExec.EXPECT().ExecuteWithLiveLogger("podman", "version").Return("", nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "timeout", pullArgs).
+		DoAndReturn(func(ctx context.Context, _ string, _ ...string) (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+	assert.NoError(t, handler.CreateJob(context.Background(), config))
+	<-started
+	assert.Equal(t, precache.Active, queryStatus().Status)
+	assert.NoError(t, handler.Cleanup(context.Background()))
+	assert.Nil(t, queryStatus())
+
+	// a completed precaching reports its result
+	mockExec.EXPECT().ExecuteWithLiveLogger("podman", "version").Return("", nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "timeout", pullArgs).Return("", nil)
+	expectInspect(mockExec, image, "sha256:a")
+	assert.NoError(t, handler.CreateJob(context.Background(), config))
+	assert.Eventually(t, func() bool { return queryStatus().Status == precache.Succeeded }, 5*time.Second,
+		10*time.Millisecond)
+	assert.NoError(t, handler.Cleanup(context.Background()))
+
+	// a failed precaching reports its error
+	mockExec.EXPECT().ExecuteWithLiveLogger("podman", "version").Return("", errors.New("podman not found"))
+	assert.NoError(t, handler.CreateJob(context.Background(), config))
+	assert.Eventually(t, func() bool { return queryStatus().Status == precache.Failed }, 5*time.Second,
+		10*time.Millisecond)
+	assert.Equal(t, "failed to execute podman command", queryStatus().Blocked)
+	assert.NoError(t, handler.Cleanup(context.Background()))
+}
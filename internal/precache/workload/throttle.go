@@ -0,0 +1,329 @@
+/*
+ * Copyright 2023 Red Hat, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this inputFilePath except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workload
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// throttleChunkSize bounds the bytes read from the registries at once, so that the bandwidth is shared evenly by the
+// concurrent pulls
+const throttleChunkSize = 32 * 1024
+
+// throttleDialTimeout bounds the connection to a registry or to the upstream proxy
+const throttleDialTimeout = 30 * time.Second
+
+// bandwidthLimiter is a token bucket shared by the connections of the throttling proxy, refilled at the bandwidth cap
+type bandwidthLimiter struct {
+	mu sync.Mutex
+	// rate is the bandwidth cap, in bytes per second
+	rate float64
+	// burst is the number of bytes that can be read at once after an idle period
+	burst float64
+	// tokens are the bytes that can be read without waiting, negative when the reads are ahead of the cap
+	tokens float64
+	last   time.Time
+}
+
+// newBandwidthLimiter returns a limiter capping the bandwidth at the given megabits per second
+func newBandwidthLimiter(maxMbps int) *bandwidthLimiter {
+	rate := float64(maxMbps) * 1000 * 1000 / 8
+	// a tenth of a second of transfer, so that an idle period does not let a burst above the cap through
+	burst := rate / 10
+	if burst < throttleChunkSize {
+		burst = throttleChunkSize
+	}
+	return &bandwidthLimiter{rate: rate, burst: burst, last: time.Now()}
+}
+
+// wait accounts for the given number of bytes read, blocking until they fit in the cap or the context is canceled
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	// the bytes are reserved right away, the following reads waiting for them as well
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	l.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(deficit / l.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttledReader reads from the underlying reader within the bandwidth cap of the limiter. Not reading faster than
+// the cap makes the registry send slower, through the flow control of the connection.
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *bandwidthLimiter
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleChunkSize {
+		p = p[:throttleChunkSize]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err //nolint:wrapcheck
+}
+
+// throttlingProxy is a local HTTP proxy the pulls are routed through, capping the bandwidth of their downloads. The
+// HTTPS connections are tunneled, and the connections to the registries go through the proxy of the environment of the
+// workload, if any.
+type throttlingProxy struct {
+	limiter  *bandwidthLimiter
+	listener net.Listener
+	server   *http.Server
+	// transport forwards the plain HTTP requests, e.g. to an insecure registry
+	transport *http.Transport
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// startThrottlingProxy starts a throttling proxy on the loopback interface, capping the bandwidth at the given
+// megabits per second
+func startThrottlingProxy(maxMbps int) (*throttlingProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the throttling proxy: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &throttlingProxy{
+		limiter:  newBandwidthLimiter(maxMbps),
+		listener: listener,
+		transport: &http.Transport{
+			Proxy:       http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{Timeout: throttleDialTimeout}).DialContext,
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: throttleDialTimeout}
+	go func() {
+		if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Throttling proxy stopped: %v", err)
+		}
+	}()
+	return p, nil
+}
+
+// url returns the URL of the proxy
+func (p *throttlingProxy) url() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// envArgs returns the env command arguments routing the pulls of a command through the proxy, whatever the proxy
+// environment of the host, the upstream proxy being used by the throttling proxy itself
+func (p *throttlingProxy) envArgs() []string {
+	proxyURL := p.url()
+	return []string{"env",
+		"HTTPS_PROXY=" + proxyURL, "https_proxy=" + proxyURL,
+		"HTTP_PROXY=" + proxyURL, "http_proxy=" + proxyURL,
+		"NO_PROXY=", "no_proxy=",
+	}
+}
+
+// stop closes the proxy and its tunnels
+func (p *throttlingProxy) stop() {
+	p.cancel()
+	if err := p.server.Close(); err != nil {
+		log.Errorf("Failed to close the throttling proxy: %v", err)
+	}
+	p.transport.CloseIdleConnections()
+}
+
+func (p *throttlingProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		p.tunnel(w, req)
+		return
+	}
+	p.forward(w, req)
+}
+
+// tunnel connects the client to the requested host, throttling the data received from it
+func (p *throttlingProxy) tunnel(w http.ResponseWriter, req *http.Request) {
+	upstream, err := p.dial(req.Context(), req.Host)
+	if err != nil {
+		log.Infof("Throttling proxy failed to connect to %s: %v", req.Host, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		log.Infof("Throttling proxy failed to hijack the connection to %s: %v", req.Host, err)
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		// the client may have sent the start of the TLS handshake along with the CONNECT request
+		_, _ = io.Copy(upstream, buffered)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, &throttledReader{ctx: p.ctx, reader: upstream, limiter: p.limiter})
+		done <- struct{}{}
+	}()
+	// either side closing ends the tunnel
+	<-done
+	client.Close()
+	upstream.Close()
+	<-done
+}
+
+// dial connects to the given host and port, through the upstream proxy of the environment if any
+func (p *throttlingProxy) dial(ctx context.Context, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: throttleDialTimeout}
+	proxyURL, err := http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: address}})
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream proxy: %w", err)
+	}
+	if proxyURL == nil {
+		return dialer.DialContext(ctx, "tcp", address) //nolint:wrapcheck
+	}
+
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to upstream proxy %s: %w", proxyURL.Redacted(), err)
+	}
+	if proxyURL.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), MinVersion: tls.VersionTLS12})
+	}
+
+	connectReq := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		connectReq.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := connectReq.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT to upstream proxy %s: %w", proxyURL.Redacted(), err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, connectReq)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response of upstream proxy %s: %w", proxyURL.Redacted(), err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("upstream proxy %s refused to connect to %s: %s", proxyURL.Redacted(), address, resp.Status)
+	}
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
+
+// bufferedConn is a connection whose first bytes may have been buffered by the reader of the CONNECT response
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p) //nolint:wrapcheck
+}
+
+// hopHeaders are the headers of the connection to the proxy, not forwarded
+var hopHeaders = []string{"Connection", "Proxy-Connection", "Proxy-Authorization", "Keep-Alive", "Te", "Trailer",
+	"Transfer-Encoding", "Upgrade"}
+
+// forward sends a plain HTTP request to the requested host, throttling the response body
+func (p *throttlingProxy) forward(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Host == "" {
+		http.Error(w, "not a proxy request", http.StatusBadRequest)
+		return
+	}
+	outReq := req.Clone(req.Context())
+	outReq.RequestURI = ""
+	for _, header := range hopHeaders {
+		outReq.Header.Del(header)
+	}
+	resp, err := p.transport.RoundTrip(outReq)
+	if err != nil {
+		log.Infof("Throttling proxy failed to forward the request to %s: %v", req.URL.Host, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, header := range hopHeaders {
+		resp.Header.Del(header)
+	}
+	for name, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, &throttledReader{ctx: p.ctx, reader: resp.Body, limiter: p.limiter})
+}
@@ -0,0 +1,146 @@
+package workload
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBandwidthLimiter(t *testing.T) {
+	l := newBandwidthLimiter(80)
+	assert.Equal(t, float64(10*1000*1000), l.rate)
+	assert.Equal(t, float64(1000*1000), l.burst)
+
+	// the burst covers at least a chunk
+	l = newBandwidthLimiter(1)
+	assert.Equal(t, float64(125*1000), l.rate)
+	assert.Equal(t, float64(throttleChunkSize), l.burst)
+}
+
+func TestBandwidthLimiterWait(t *testing.T) {
+	l := newBandwidthLimiter(80)
+
+	// an idle period lets the burst through at once, not more
+	l.last = time.Now().Add(-time.Minute)
+	start := time.Now()
+	assert.NoError(t, l.wait(context.Background(), int(l.burst)))
+	assert.Less(t, time.Since(start), 10*time.Millisecond)
+
+	// the bytes beyond it wait for the rate, 200KB taking 20ms at 10MB/s
+	start = time.Now()
+	assert.NoError(t, l.wait(context.Background(), 200*1000))
+	assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the wait stops with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	assert.ErrorIs(t, l.wait(ctx, int(l.rate)*60), context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestThrottledReader(t *testing.T) {
+	// 8 Mbps is 1MB/s, the 300KB beyond the burst taking 300ms
+	l := newBandwidthLimiter(8)
+	l.last = time.Now().Add(-time.Minute)
+	data := bytes.Repeat([]byte("x"), int(l.burst)+300*1000)
+	reader := &throttledReader{ctx: context.Background(), reader: bytes.NewReader(data), limiter: l}
+
+	// a read is bounded by the chunk size
+	n, err := reader.Read(make([]byte, 2*throttleChunkSize))
+	assert.NoError(t, err)
+	assert.Equal(t, throttleChunkSize, n)
+
+	start := time.Now()
+	read, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data)-throttleChunkSize, len(read))
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// a canceled context stops the reads
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reader = &throttledReader{ctx: ctx, reader: bytes.NewReader(data), limiter: newBandwidthLimiter(8)}
+	_, err = io.ReadAll(reader)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// newProxiedClient returns an HTTP client routed through a throttling proxy
+func newProxiedClient(t *testing.T, transport *http.Transport) *http.Client {
+	proxy, err := startThrottlingProxy(1000)
+	assert.NoError(t, err)
+	t.Cleanup(proxy.stop)
+
+	proxyURL, err := url.Parse(proxy.url())
+	assert.NoError(t, err)
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return &http.Client{Transport: transport, Timeout: 10 * time.Second}
+}
+
+// closedAddress returns a local address nothing listens on
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+	return address
+}
+
+func TestThrottlingProxyTunnel(t *testing.T) {
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("blob"))
+	}))
+	defer registry.Close()
+	client := newProxiedClient(t, registry.Client().Transport.(*http.Transport).Clone())
+
+	resp, err := client.Get(registry.URL + "/v2/org/image/blobs/sha256:1234")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "blob", string(body))
+
+	// the registry being unreachable fails the CONNECT request
+	_, err = client.Get("https://" + closedAddress(t) + "/v2/")
+	assert.ErrorContains(t, err, "Bad Gateway")
+}
+
+func TestThrottlingProxyForward(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Proxy-Authorization"))
+		assert.Equal(t, "token", r.Header.Get("Authorization"))
+		w.Header().Set("Docker-Content-Digest", "sha256:1234")
+		_, _ = w.Write([]byte("blob"))
+	}))
+	defer registry.Close()
+	client := newProxiedClient(t, &http.Transport{})
+
+	req, err := http.NewRequest(http.MethodGet, registry.URL+"/v2/org/image/blobs/sha256:1234", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "token")
+	req.Header.Set("Proxy-Authorization", "Basic dXNlcjpwYXNz")
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "sha256:1234", resp.Header.Get("Docker-Content-Digest"))
+	assert.Equal(t, "blob", string(body))
+
+	// the registry being unreachable fails the request
+	resp, err = client.Get("http://" + closedAddress(t) + "/v2/")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
@@ -0,0 +1,81 @@
+// Package preflight runs the checks of the Prep and Upgrade stages ahead of the upgrade and summarizes their results
+// in a readiness score, so that the go or no-go decision is taken from a single status instead of the failures of
+// the stages, one at a time.
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+)
+
+// Check is a preflight check. Its function returns a description of what was checked when it passes, a Warning for an
+// issue to review that does not prevent the upgrade, or any other error for an issue the upgrade would fail on
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Warning is an issue found by a check that does not prevent the upgrade
+type Warning struct {
+	Message string
+}
+
+func (w *Warning) Error() string {
+	return w.Message
+}
+
+// Warnf returns a Warning with the formatted message
+func Warnf(format string, args ...any) error {
+	return &Warning{Message: fmt.Sprintf(format, args...)}
+}
+
+// Run runs the checks in order, each one whatever the results of the previous ones, and returns their summary
+func Run(ctx context.Context, log logr.Logger, checks []Check, now func() time.Time) *lcav1alpha1.PreflightStatus {
+	status := &lcav1alpha1.PreflightStatus{Result: lcav1alpha1.PreflightPass, Score: 100}
+	points := 0
+	for _, check := range checks {
+		result := lcav1alpha1.PreflightCheck{Name: check.Name, Result: lcav1alpha1.PreflightPass}
+		message, err := check.Run(ctx)
+		var warning *Warning
+		switch {
+		case err == nil:
+			result.Message = message
+			points += 2
+		case errors.As(err, &warning):
+			result.Result = lcav1alpha1.PreflightWarn
+			result.Message = err.Error()
+			points++
+		default:
+			result.Result = lcav1alpha1.PreflightFail
+			result.Message = err.Error()
+		}
+		log.Info("Preflight check done", "check", check.Name, "result", result.Result, "message", result.Message)
+		status.Checks = append(status.Checks, result)
+		status.Result = worst(status.Result, result.Result)
+	}
+	if len(checks) != 0 {
+		status.Score = points * 100 / (2 * len(checks))
+	}
+	status.CompletedAt = metav1.NewTime(now())
+	return status
+}
+
+var severity = map[lcav1alpha1.PreflightResult]int{
+	lcav1alpha1.PreflightPass: 0,
+	lcav1alpha1.PreflightWarn: 1,
+	lcav1alpha1.PreflightFail: 2,
+}
+
+func worst(a, b lcav1alpha1.PreflightResult) lcav1alpha1.PreflightResult {
+	if severity[b] > severity[a] {
+		return b
+	}
+	return a
+}
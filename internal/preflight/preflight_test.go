@@ -0,0 +1,97 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+)
+
+func result(message string, err error) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		return message, err
+	}
+}
+
+func TestRun(t *testing.T) {
+	completedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	now := func() time.Time { return completedAt }
+
+	testcases := []struct {
+		name     string
+		checks   []Check
+		expected *lcav1alpha1.PreflightStatus
+	}{
+		{
+			name: "all passed",
+			checks: []Check{
+				{Name: "DiskSpace", Run: result("enough space", nil)},
+				{Name: "ClusterHealth", Run: result("", nil)},
+			},
+			expected: &lcav1alpha1.PreflightStatus{
+				Result: lcav1alpha1.PreflightPass,
+				Score:  100,
+				Checks: []lcav1alpha1.PreflightCheck{
+					{Name: "DiskSpace", Result: lcav1alpha1.PreflightPass, Message: "enough space"},
+					{Name: "ClusterHealth", Result: lcav1alpha1.PreflightPass},
+				},
+				CompletedAt: metav1.NewTime(completedAt),
+			},
+		},
+		{
+			name: "warning",
+			checks: []Check{
+				{Name: "DiskSpace", Run: result("", Warnf("seed image size %s", "unknown"))},
+				{Name: "ClusterHealth", Run: result("", nil)},
+			},
+			expected: &lcav1alpha1.PreflightStatus{
+				Result: lcav1alpha1.PreflightWarn,
+				Score:  75,
+				Checks: []lcav1alpha1.PreflightCheck{
+					{Name: "DiskSpace", Result: lcav1alpha1.PreflightWarn, Message: "seed image size unknown"},
+					{Name: "ClusterHealth", Result: lcav1alpha1.PreflightPass},
+				},
+				CompletedAt: metav1.NewTime(completedAt),
+			},
+		},
+		{
+			name: "failure after a warning",
+			checks: []Check{
+				{Name: "SeedVersion", Run: result("", fmt.Errorf("skipped: %w", Warnf("not higher")))},
+				{Name: "ClusterHealth", Run: result("", errors.New("node not ready"))},
+				{Name: "Certificates", Run: result("", nil)},
+			},
+			expected: &lcav1alpha1.PreflightStatus{
+				Result: lcav1alpha1.PreflightFail,
+				Score:  50,
+				Checks: []lcav1alpha1.PreflightCheck{
+					{Name: "SeedVersion", Result: lcav1alpha1.PreflightWarn, Message: "skipped: not higher"},
+					{Name: "ClusterHealth", Result: lcav1alpha1.PreflightFail, Message: "node not ready"},
+					{Name: "Certificates", Result: lcav1alpha1.PreflightPass},
+				},
+				CompletedAt: metav1.NewTime(completedAt),
+			},
+		},
+		{
+			name: "no check",
+			expected: &lcav1alpha1.PreflightStatus{
+				Result:      lcav1alpha1.PreflightPass,
+				Score:       100,
+				CompletedAt: metav1.NewTime(completedAt),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Run(context.Background(), logr.Discard(), tc.checks, now))
+		})
+	}
+}
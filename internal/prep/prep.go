@@ -2,10 +2,12 @@ package prep
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/go-logr/logr"
@@ -22,6 +24,123 @@ import (
 // need this for unit tests
 var osReadFile = os.ReadFile
 
+const (
+	// maxExtractAttempts is the number of extractions of a seed image tar before its extracted files are reported as
+	// corrupted
+	maxExtractAttempts = 2
+	// maxReportedDifferences bounds the corrupted files listed in the error
+	maxReportedDifferences = 10
+)
+
+// StaterootSetupMarkerFile is written in the stateroot once its setup is complete, to allow reusing it
+const StaterootSetupMarkerFile = "lca-stateroot-setup.json"
+
+// staterootSetupMarker records the seed image a stateroot was set up from
+type staterootSetupMarker struct {
+	SeedImage    string `json:"seed_image"`
+	SeedDigest   string `json:"seed_digest"`
+	Deployment   string `json:"deployment"`
+	DeferVarData bool   `json:"defer_var_data,omitempty"`
+}
+
+func getStaterootSetupMarkerPath(layout common.StaterootLayout, osname string) string {
+	return common.PathOutsideChroot(filepath.Join(layout.Path(osname), StaterootSetupMarkerFile))
+}
+
+// getSeedImageDigest returns the digest of the local seed image
+func getSeedImageDigest(ctx context.Context, ops ops.Ops, seedImage string) (string, error) {
+	digest, err := ops.RunInHostNamespace(ctx, "podman", "image", "inspect", "--format", "{{.Digest}}", seedImage)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect seed image: %w", err)
+	}
+	return strings.TrimSpace(digest), nil
+}
+
+// isStaterootReusable checks whether the stateroot was completely set up from the same seed image digest, with the
+// same seed /var data mode, by a previous attempt, and that its deployment is still in place and deploys the commit
+// booted by the seed
+func isStaterootReusable(ctx context.Context, log logr.Logger, ostreeClient ostreeclient.IClient,
+	layout common.StaterootLayout, osname, seedDigest, seedBootedRef string, deferVarData bool) bool {
+	marker := &staterootSetupMarker{}
+	if err := utils.ReadYamlOrJSONFile(getStaterootSetupMarkerPath(layout, osname), marker); err != nil {
+		if !os.IsNotExist(err) {
+			log.Info("Ignoring unreadable stateroot setup marker", "stateroot", osname, "error", err.Error())
+		}
+		return false
+	}
+
+	if seedDigest == "" || marker.SeedDigest != seedDigest {
+		log.Info("Existing stateroot was set up from a different seed image", "stateroot", osname,
+			"seedDigest", marker.SeedDigest, "expectedDigest", seedDigest)
+		return false
+	}
+
+	deployment, err := ostreeClient.GetDeployment(ctx, osname)
+	if err != nil || deployment == "" || deployment != marker.Deployment {
+		log.Info("Existing stateroot deployment does not match the setup marker", "stateroot", osname,
+			"deployment", deployment, "expectedDeployment", marker.Deployment)
+		return false
+	}
+	if checksum := strings.Split(deployment, ".")[0]; checksum != seedBootedRef {
+		log.Info("Existing stateroot deployment is not the commit of the seed image", "stateroot", osname,
+			"checksum", checksum, "expectedChecksum", seedBootedRef)
+		return false
+	}
+
+	if marker.DeferVarData != deferVarData {
+		log.Info("Existing stateroot was set up with a different seed /var data mode", "stateroot", osname,
+			"deferVarData", marker.DeferVarData, "expectedDeferVarData", deferVarData)
+		return false
+	}
+	if deferVarData {
+		deferred := common.PathOutsideChroot(filepath.Join(layout.Path(osname), common.DeferredSeedVarDataFile))
+		if _, err := os.Stat(deferred); err != nil {
+			log.Info("Existing stateroot is missing the deferred seed /var data", "stateroot", osname,
+				"error", err.Error())
+			return false
+		}
+	}
+	return true
+}
+
+// GetStaterootSeedDigest returns the digest of the seed image the stateroot was set up from, recorded in its setup
+// marker
+func GetStaterootSeedDigest(layout common.StaterootLayout, osname string) (string, error) {
+	marker := &staterootSetupMarker{}
+	if err := utils.ReadYamlOrJSONFile(getStaterootSetupMarkerPath(layout, osname), marker); err != nil {
+		return "", fmt.Errorf("failed to read stateroot setup marker: %w", err)
+	}
+	return marker.SeedDigest, nil
+}
+
+// ParseStaterootSetupMarker returns the seed image and its digest the stateroot was set up from, recorded in the
+// content of its setup marker
+func ParseStaterootSetupMarker(data []byte) (seedImage, seedDigest string, err error) {
+	marker := &staterootSetupMarker{}
+	if err := json.Unmarshal(data, marker); err != nil {
+		return "", "", fmt.Errorf("failed to parse stateroot setup marker: %w", err)
+	}
+	return marker.SeedImage, marker.SeedDigest, nil
+}
+
+// writeStaterootSetupMarker marks the stateroot setup as complete
+func writeStaterootSetupMarker(ctx context.Context, ostreeClient ostreeclient.IClient, layout common.StaterootLayout,
+	osname, seedImage, seedDigest string, deferVarData bool) error {
+	deployment, err := ostreeClient.GetDeployment(ctx, osname)
+	if err != nil {
+		return fmt.Errorf("failed to get deployment: %w", err)
+	}
+	data, err := json.Marshal(&staterootSetupMarker{SeedImage: seedImage, SeedDigest: seedDigest, Deployment: deployment,
+		DeferVarData: deferVarData})
+	if err != nil {
+		return fmt.Errorf("failed to marshal stateroot setup marker: %w", err)
+	}
+	if err := os.WriteFile(getStaterootSetupMarkerPath(layout, osname), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write stateroot setup marker: %w", err)
+	}
+	return nil
+}
+
 // getBootedStaterootIDFromRPMOstreeJson reads rpm-ostree.json file from the seed image
 // and returns the deployment.ID of the booted stateroot
 func getBootedStaterootIDFromRPMOstreeJson(path string) (string, error) {
@@ -43,8 +162,8 @@ func getBootedStaterootIDFromRPMOstreeJson(path string) (string, error) {
 
 // getVersionFromSeedClusterInfoFile reads ClusterInfo file and returns the ocp version
 func getVersionFromSeedClusterInfoFile(path string) (string, error) {
-	ci := &seedclusterinfo.SeedClusterInfo{}
-	if err := utils.ReadYamlOrJSONFile(path, ci); err != nil {
+	ci, err := seedclusterinfo.ReadSeedClusterInfoFromFile(path)
+	if err != nil {
 		return "", fmt.Errorf("failed to read and decode ClusterInfo file: %w", err)
 	}
 	return ci.SeedClusterOCPVersion, nil
@@ -100,6 +219,55 @@ func removeETCDeletions(mountpoint, deploymentDir string) error {
 	return nil
 }
 
+// seedVarDataServiceUnit extracts the seed /var data at first boot of the new stateroot, before the services using /var
+const seedVarDataServiceUnit = `[Unit]
+Description=Extract the seed /var data deferred by the lifecycle agent
+DefaultDependencies=no
+RequiresMountsFor=/var
+After=ostree-remount.service
+Before=sysinit.target systemd-tmpfiles-setup.service systemd-journal-flush.service
+ConditionPathExists=` + common.DeferredSeedVarDataFile + `
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/usr/bin/tar xzf ` + common.DeferredSeedVarDataFile + ` -C / --selinux
+ExecStartPost=/usr/bin/rm -f ` + common.DeferredSeedVarDataFile + `
+
+[Install]
+WantedBy=sysinit.target
+`
+
+// deferSeedVarData stores the compressed seed /var data in the new stateroot, reflink-copied when the filesystems
+// support it, and enables a service extracting it at first boot. This saves the disk space of the extracted /var
+// data until the pivot, at the cost of a longer first boot.
+func deferSeedVarData(ctx context.Context, log logr.Logger, ops ops.Ops, layout common.StaterootLayout, mountpoint,
+	osname, deploymentDir string) error {
+	log.Info("Deferring the seed /var data extraction to first boot", "stateroot", osname)
+	if _, err := ops.RunInHostNamespace(ctx, "cp", "--reflink=auto",
+		filepath.Join(mountpoint, "var.tgz"),
+		filepath.Join(layout.Path(osname), common.DeferredSeedVarDataFile),
+	); err != nil {
+		return fmt.Errorf("failed to copy seed var data: %w", err)
+	}
+
+	unitDir := common.PathOutsideChroot(filepath.Join(deploymentDir, "etc/systemd/system"))
+	if err := os.MkdirAll(filepath.Join(unitDir, "sysinit.target.wants"), 0o755); err != nil {
+		return fmt.Errorf("failed to create systemd unit dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(unitDir, common.SeedVarDataService), []byte(seedVarDataServiceUnit), 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("failed to write %s: %w", common.SeedVarDataService, err)
+	}
+	link := filepath.Join(unitDir, "sysinit.target.wants", common.SeedVarDataService)
+	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", link, err)
+	}
+	if err := os.Symlink(filepath.Join("/etc/systemd/system", common.SeedVarDataService), link); err != nil {
+		return fmt.Errorf("failed to enable %s: %w", common.SeedVarDataService, err)
+	}
+	return nil
+}
+
 // split the deploymentID by '-' and return the last item
 // there should be at least one '-' in the deploymentID
 func getDeploymentFromDeploymentID(deploymentID string) (string, error) {
@@ -112,11 +280,12 @@ func getDeploymentFromDeploymentID(deploymentID string) (string, error) {
 	return splitted[len(splitted)-1], nil
 }
 
-func SetupStateroot(log logr.Logger, ops ops.Ops, ostreeClient ostreeclient.IClient,
-	rpmOstreeClient rpmostreeclient.IClient, seedImage, expectedVersion, imageListFile string, ibi bool) error {
+func SetupStateroot(ctx context.Context, log logr.Logger, ops ops.Ops, ostreeClient ostreeclient.IClient,
+	rpmOstreeClient rpmostreeclient.IClient, layout common.StaterootLayout, osname, seedImage, expectedVersion, imageListFile string,
+	ibi, deferVarData bool) (err error) {
 	log.Info("Start setupstateroot")
 
-	defer ops.UnmountAndRemoveImage(seedImage)
+	defer func() { releaseSeedImage(ops, seedImage, err) }()
 
 	workspaceOutsideChroot, err := os.MkdirTemp(common.PathOutsideChroot("/var/tmp"), "")
 	if err != nil {
@@ -136,26 +305,20 @@ func SetupStateroot(log logr.Logger, ops ops.Ops, ostreeClient ostreeclient.ICli
 	log.Info("workspace:" + workspace)
 
 	if !ibi {
-		if err = ops.RemountSysroot(); err != nil {
+		if err = ops.RemountSysroot(ctx); err != nil {
 			return fmt.Errorf("failed to remount /sysroot: %w", err)
 		}
 
 	}
 
-	mountpoint, err := ops.RunInHostNamespace("podman", "image", "mount", seedImage)
+	mountpoint, err := ops.RunInHostNamespace(ctx, "podman", "image", "mount", seedImage)
 	if err != nil {
 		return fmt.Errorf("failed to mount seed image: %w", err)
 	}
 
-	ostreeRepo := filepath.Join(workspace, "ostree")
-	if err = os.Mkdir(common.PathOutsideChroot(ostreeRepo), 0o700); err != nil {
-		return fmt.Errorf("failed to create ostree repo directory: %w", err)
-	}
-
-	if err := ops.ExtractTarWithSELinux(
-		fmt.Sprintf("%s/ostree.tgz", mountpoint), ostreeRepo,
-	); err != nil {
-		return fmt.Errorf("failed to extract ostree.tgz: %w", err)
+	seedDigest, err := getSeedImageDigest(ctx, ops, seedImage)
+	if err != nil {
+		return err
 	}
 
 	// example:
@@ -182,13 +345,32 @@ func SetupStateroot(log logr.Logger, ops ops.Ops, ostreeClient ostreeclient.ICli
 			version, expectedVersion)
 	}
 
-	osname := common.GetStaterootName(expectedVersion)
+	// Reuse the stateroot set up by a previous attempt from the same seed image, e.g. after a controller restart
+	if isStaterootReusable(ctx, log, ostreeClient, layout, osname, seedDigest, seedBootedRef, deferVarData) {
+		log.Info("Reusing existing stateroot set up from the same seed image", "stateroot", osname, "seedDigest", seedDigest)
+		if err := common.CopyOutsideChroot(filepath.Join(mountpoint, "containers.list"), imageListFile); err != nil {
+			return fmt.Errorf("failed to copy image list file: %w", err)
+		}
+		return nil
+	}
 
-	if err = ostreeClient.PullLocal(ostreeRepo); err != nil {
-		return fmt.Errorf("failed ostree pull-local: %w", err)
+	ostreeRepo := filepath.Join(workspace, "ostree")
+	if err = extractOstreeRepo(ctx, ops, mountpoint, ostreeRepo); err != nil {
+		return err
 	}
 
-	if err = ostreeClient.OSInit(osname); err != nil {
+	// The checksums of the objects are verified by the pull, the objects corrupted by the extraction are extracted again
+	if err = ostreeClient.PullLocal(ctx, ostreeRepo); err != nil {
+		log.Error(err, "Failed ostree pull-local, extracting the ostree repo again")
+		if err = extractOstreeRepo(ctx, ops, mountpoint, ostreeRepo); err != nil {
+			return err
+		}
+		if err = ostreeClient.PullLocal(ctx, ostreeRepo); err != nil {
+			return fmt.Errorf("failed ostree pull-local: %w", err)
+		}
+	}
+
+	if err = ostreeClient.OSInit(ctx, osname); err != nil {
 		return fmt.Errorf("failed ostree admin os-init: %w", err)
 	}
 
@@ -197,11 +379,11 @@ func SetupStateroot(log logr.Logger, ops ops.Ops, ostreeClient ostreeclient.ICli
 		return fmt.Errorf("failed to build kargs: %w", err)
 	}
 
-	if err = ostreeClient.Deploy(osname, seedBootedRef, kargs); err != nil {
+	if err = ostreeClient.Deploy(ctx, osname, seedBootedRef, kargs); err != nil {
 		return fmt.Errorf("failed ostree admin deploy: %w", err)
 	}
 
-	deploymentDir, err := ostreeClient.GetDeploymentDir(osname)
+	deploymentDir, err := ostreeClient.GetDeploymentDir(ctx, osname)
 	if err != nil {
 		return fmt.Errorf("failed to get deployment dir: %w", err)
 	}
@@ -213,20 +395,29 @@ func SetupStateroot(log logr.Logger, ops ops.Ops, ostreeClient ostreeclient.ICli
 		return fmt.Errorf("failed to restore origin file: %w", err)
 	}
 
-	if err = ops.ExtractTarWithSELinux(
-		filepath.Join(mountpoint, "var.tgz"),
-		common.GetStaterootPath(osname),
-	); err != nil {
-		return fmt.Errorf("failed to restore var directory: %w", err)
+	if !deferVarData {
+		if err = extractVerifiedTar(ctx, log, ops,
+			filepath.Join(mountpoint, "var.tgz"),
+			layout.Path(osname),
+		); err != nil {
+			return fmt.Errorf("failed to restore var directory: %w", err)
+		}
 	}
 
-	if err := ops.ExtractTarWithSELinux(
+	if err := extractVerifiedTar(ctx, log, ops,
 		filepath.Join(mountpoint, "etc.tgz"),
 		deploymentDir,
 	); err != nil {
 		return fmt.Errorf("failed to extract seed etc: %w", err)
 	}
 
+	if deferVarData {
+		// Written after the seed etc, so the service unit is not overwritten
+		if err := deferSeedVarData(ctx, log, ops, layout, mountpoint, osname, deploymentDir); err != nil {
+			return err
+		}
+	}
+
 	if err = removeETCDeletions(mountpoint, deploymentDir); err != nil {
 		return fmt.Errorf("failed to process etc.deletions: %w", err)
 	}
@@ -235,10 +426,70 @@ func SetupStateroot(log logr.Logger, ops ops.Ops, ostreeClient ostreeclient.ICli
 		return fmt.Errorf("failed to copy image list file: %w", err)
 	}
 
+	if err := writeStaterootSetupMarker(ctx, ostreeClient, layout, osname, seedImage, seedDigest, deferVarData); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func ReadPrecachingList(imageListFile, clusterRegistry, seedRegistry string, overrideSeedRegistry bool) (imageList []string, err error) {
+// extractOstreeRepo extracts the ostree repo of the seed image into an empty ostreeRepo directory
+func extractOstreeRepo(ctx context.Context, ops ops.Ops, mountpoint, ostreeRepo string) error {
+	if err := os.RemoveAll(common.PathOutsideChroot(ostreeRepo)); err != nil {
+		return fmt.Errorf("failed to remove ostree repo directory: %w", err)
+	}
+	if err := os.Mkdir(common.PathOutsideChroot(ostreeRepo), 0o700); err != nil {
+		return fmt.Errorf("failed to create ostree repo directory: %w", err)
+	}
+	if err := ops.ExtractTarWithSELinux(ctx, filepath.Join(mountpoint, "ostree.tgz"), ostreeRepo); err != nil {
+		return fmt.Errorf("failed to extract ostree.tgz: %w", err)
+	}
+	return nil
+}
+
+// extractVerifiedTar extracts the srcPath compressed tar of the seed image to destPath, and compares the extracted
+// files with the tar, extracting them again once if any is corrupted or missing. Extraction errors tar does not
+// detect would otherwise only show after the pivot, in the services of the new stateroot.
+func extractVerifiedTar(ctx context.Context, log logr.Logger, ops ops.Ops, srcPath, destPath string) error {
+	for attempt := 1; ; attempt++ {
+		if err := ops.ExtractTarWithSELinux(ctx, srcPath, destPath); err != nil {
+			return err //nolint:wrapcheck
+		}
+		differences, err := ops.CompareTar(ctx, srcPath, destPath)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		if len(differences) == 0 {
+			return nil
+		}
+		if attempt == maxExtractAttempts {
+			if len(differences) > maxReportedDifferences {
+				differences = append(differences[:maxReportedDifferences],
+					fmt.Sprintf("and %d more", len(differences)-maxReportedDifferences))
+			}
+			return fmt.Errorf("files extracted from %s are corrupted: %s", filepath.Base(srcPath),
+				strings.Join(differences, ", "))
+		}
+		log.Info("Files extracted from the seed image are corrupted, extracting them again",
+			"archive", filepath.Base(srcPath), "differences", len(differences), "first", differences[0])
+	}
+}
+
+// releaseSeedImage unmounts the seed image, and removes it once used successfully. The seed image is kept after a
+// failure, for a retried Prep to reuse it instead of pulling it again. It runs regardless of the cancellation of the
+// setup, which would leave the seed image mounted
+func releaseSeedImage(ops ops.Ops, seedImage string, err error) {
+	if err != nil {
+		_ = ops.UnmountImage(context.Background(), seedImage)
+		return
+	}
+	_ = ops.UnmountAndRemoveImage(context.Background(), seedImage)
+}
+
+// ReadPrecachingList reads the images to precache from the image list file, replacing the seed registry by the cluster
+// one if requested, and keeping the images selected by the filter, if any
+func ReadPrecachingList(imageListFile, clusterRegistry, seedRegistry string, overrideSeedRegistry bool,
+	filter *PrecacheFilter) (imageList []string, err error) {
 	var content []byte
 	content, err = os.ReadFile(common.PathOutsideChroot(imageListFile))
 	if err != nil {
@@ -261,5 +512,81 @@ func ReadPrecachingList(imageListFile, clusterRegistry, seedRegistry string, ove
 		imageList = append(imageList, image)
 	}
 
+	imageList, _ = filter.Apply(imageList)
 	return imageList, nil
 }
+
+// PrecacheFilter selects the images to precache with regular expressions matched against the image references, e.g.
+// to skip the images of the operators the cluster does not run
+type PrecacheFilter struct {
+	include  []*regexp.Regexp
+	exclude  []*regexp.Regexp
+	required map[string]bool
+}
+
+// NewPrecacheFilter compiles the include and exclude regular expressions. When include ones are set, only the images
+// matching one of them are kept, and the images matching an exclude one are removed. The required images, such as the
+// recert image run at first boot, are always kept. It returns nil without any regular expression.
+func NewPrecacheFilter(include, exclude []string, required ...string) (*PrecacheFilter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+
+	filter := &PrecacheFilter{required: make(map[string]bool)}
+	for _, expr := range include {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid precache include filter %q: %w", expr, err)
+		}
+		filter.include = append(filter.include, re)
+	}
+	for _, expr := range exclude {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid precache exclude filter %q: %w", expr, err)
+		}
+		filter.exclude = append(filter.exclude, re)
+	}
+	for _, image := range required {
+		if image != "" {
+			filter.required[image] = true
+		}
+	}
+	return filter, nil
+}
+
+// Match tells whether the image is kept by the filter. A nil filter keeps all the images.
+func (f *PrecacheFilter) Match(image string) bool {
+	if f == nil || f.required[image] {
+		return true
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(image) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(image) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the images kept by the filter, and those it removed
+func (f *PrecacheFilter) Apply(images []string) (kept, removed []string) {
+	if f == nil {
+		return images, nil
+	}
+	for _, image := range images {
+		if f.Match(image) {
+			kept = append(kept, image)
+		} else {
+			removed = append(removed, image)
+		}
+	}
+	return kept, removed
+}
@@ -1,12 +1,20 @@
 package prep
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 )
 
 func TestGetDeploymentFromDeploymentID(t *testing.T) {
@@ -112,3 +120,226 @@ func TestGetKernelArgumentsFromMCOFile(t *testing.T) {
 		})
 	}
 }
+
+func TestIsStaterootReusable(t *testing.T) {
+	const (
+		osname     = "rhcos_4.15.0"
+		seedRef    = "8ef186bc6407db2180726e32354c394c189c6e9be2c17839b313cf1fed3d5391"
+		deployment = seedRef + ".0"
+		seedDigest = "sha256:1234"
+	)
+
+	testcases := []struct {
+		name                string
+		writeMarker         bool
+		markerDeferVarData  bool
+		writeDeferredVar    bool
+		seedDigest          string
+		seedRef             string
+		deferVarData        bool
+		currentDeployment   string
+		expectGetDeployment bool
+		expect              bool
+	}{
+		{
+			name:       "no marker",
+			seedDigest: seedDigest,
+			seedRef:    seedRef,
+			expect:     false,
+		},
+		{
+			name:        "different seed digest",
+			writeMarker: true,
+			seedDigest:  "sha256:5678",
+			seedRef:     seedRef,
+			expect:      false,
+		},
+		{
+			name:                "deployment removed",
+			writeMarker:         true,
+			seedDigest:          seedDigest,
+			seedRef:             seedRef,
+			currentDeployment:   "",
+			expectGetDeployment: true,
+			expect:              false,
+		},
+		{
+			name:                "deployment of another commit than the seed",
+			writeMarker:         true,
+			seedDigest:          seedDigest,
+			seedRef:             "9455b99374197f10c453eb96f1b66cea884b3dc16ce4bc753bdb7263602bb722",
+			currentDeployment:   deployment,
+			expectGetDeployment: true,
+			expect:              false,
+		},
+		{
+			name:                "set up with the seed /var data extracted, deferred requested",
+			writeMarker:         true,
+			seedDigest:          seedDigest,
+			seedRef:             seedRef,
+			deferVarData:        true,
+			currentDeployment:   deployment,
+			expectGetDeployment: true,
+			expect:              false,
+		},
+		{
+			name:                "deferred seed /var data removed",
+			writeMarker:         true,
+			markerDeferVarData:  true,
+			seedDigest:          seedDigest,
+			seedRef:             seedRef,
+			deferVarData:        true,
+			currentDeployment:   deployment,
+			expectGetDeployment: true,
+			expect:              false,
+		},
+		{
+			name:                "same seed, deployment and deferred seed /var data",
+			writeMarker:         true,
+			markerDeferVarData:  true,
+			writeDeferredVar:    true,
+			seedDigest:          seedDigest,
+			seedRef:             seedRef,
+			deferVarData:        true,
+			currentDeployment:   deployment,
+			expectGetDeployment: true,
+			expect:              true,
+		},
+		{
+			name:                "same seed and deployment",
+			writeMarker:         true,
+			seedDigest:          seedDigest,
+			seedRef:             seedRef,
+			currentDeployment:   deployment,
+			expectGetDeployment: true,
+			expect:              true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ostreeClientMock := ostreeclient.NewMockIClient(ctrl)
+
+			origPrefix := common.OstreeDeployPathPrefix
+			defer func() {
+				common.OstreeDeployPathPrefix = origPrefix
+			}()
+			common.OstreeDeployPathPrefix = t.TempDir()
+			layout := common.CurrentStaterootLayout()
+			assert.NoError(t, os.MkdirAll(filepath.Join(layout.Path(osname), "var"), 0o700))
+
+			if tc.writeMarker {
+				ostreeClientMock.EXPECT().GetDeployment(gomock.Any(), osname).Return(deployment, nil)
+				assert.NoError(t, writeStaterootSetupMarker(context.Background(), ostreeClientMock, layout, osname, "quay.io/seed:4.15.0",
+					seedDigest, tc.markerDeferVarData))
+			}
+			if tc.writeDeferredVar {
+				assert.NoError(t, os.WriteFile(filepath.Join(layout.Path(osname), common.DeferredSeedVarDataFile), nil, 0o600))
+			}
+			if tc.expectGetDeployment {
+				ostreeClientMock.EXPECT().GetDeployment(gomock.Any(), osname).Return(tc.currentDeployment, nil)
+			}
+
+			assert.Equal(t, tc.expect, isStaterootReusable(context.Background(), logr.Discard(), ostreeClientMock, layout, osname,
+				tc.seedDigest, tc.seedRef, tc.deferVarData))
+		})
+	}
+}
+
+func TestDeferSeedVarData(t *testing.T) {
+	const osname = "rhcos_4.15.0"
+	ctrl := gomock.NewController(t)
+	opsMock := ops.NewMockOps(ctrl)
+
+	origPrefix := common.OstreeDeployPathPrefix
+	defer func() {
+		common.OstreeDeployPathPrefix = origPrefix
+	}()
+	common.OstreeDeployPathPrefix = t.TempDir()
+	deploymentDir := filepath.Join(common.CurrentStaterootLayout().Path(osname), "deploy", "abc.0")
+
+	opsMock.EXPECT().RunInHostNamespace(gomock.Any(), "cp", "--reflink=auto", "/mnt/seed/var.tgz",
+		filepath.Join(common.CurrentStaterootLayout().Path(osname), common.DeferredSeedVarDataFile)).Return("", nil).Times(2)
+
+	for i := 0; i < 2; i++ {
+		// a retried Prep rewrites the unit and its enablement link
+		assert.NoError(t, deferSeedVarData(context.Background(), logr.Discard(), opsMock, common.CurrentStaterootLayout(), "/mnt/seed", osname, deploymentDir))
+	}
+
+	unit, err := os.ReadFile(filepath.Join(deploymentDir, "etc/systemd/system", common.SeedVarDataService))
+	assert.NoError(t, err)
+	assert.Contains(t, string(unit), "ExecStart=/usr/bin/tar xzf /var/.lca-seed-var.tgz -C / --selinux")
+	target, err := os.Readlink(filepath.Join(deploymentDir, "etc/systemd/system/sysinit.target.wants", common.SeedVarDataService))
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/systemd/system/lca-seed-var.service", target)
+}
+
+func TestExtractVerifiedTar(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	opsMock := ops.NewMockOps(ctrl)
+
+	// Intact
+	gomock.InOrder(
+		opsMock.EXPECT().ExtractTarWithSELinux(gomock.Any(), "/mnt/seed/etc.tgz", "/deploy").Return(nil),
+		opsMock.EXPECT().CompareTar(gomock.Any(), "/mnt/seed/etc.tgz", "/deploy").Return(nil, nil),
+	)
+	assert.NoError(t, extractVerifiedTar(context.Background(), logr.Discard(), opsMock, "/mnt/seed/etc.tgz", "/deploy"))
+
+	// Corrupted once, extracted again
+	gomock.InOrder(
+		opsMock.EXPECT().ExtractTarWithSELinux(gomock.Any(), "/mnt/seed/var.tgz", "/var").Return(nil),
+		opsMock.EXPECT().CompareTar(gomock.Any(), "/mnt/seed/var.tgz", "/var").Return([]string{"var/lib/etcd/member/snap/db: Contents differ"}, nil),
+		opsMock.EXPECT().ExtractTarWithSELinux(gomock.Any(), "/mnt/seed/var.tgz", "/var").Return(nil),
+		opsMock.EXPECT().CompareTar(gomock.Any(), "/mnt/seed/var.tgz", "/var").Return(nil, nil),
+	)
+	assert.NoError(t, extractVerifiedTar(context.Background(), logr.Discard(), opsMock, "/mnt/seed/var.tgz", "/var"))
+
+	// Still corrupted, the differences are reported
+	var differences []string
+	for i := 0; i < 12; i++ {
+		differences = append(differences, fmt.Sprintf("var/lib/file%d: Size differs", i))
+	}
+	opsMock.EXPECT().ExtractTarWithSELinux(gomock.Any(), "/mnt/seed/var.tgz", "/var").Return(nil).Times(2)
+	opsMock.EXPECT().CompareTar(gomock.Any(), "/mnt/seed/var.tgz", "/var").Return(differences, nil).Times(2)
+	err := extractVerifiedTar(context.Background(), logr.Discard(), opsMock, "/mnt/seed/var.tgz", "/var")
+	assert.ErrorContains(t, err, "files extracted from var.tgz are corrupted: var/lib/file0: Size differs, ")
+	assert.ErrorContains(t, err, "var/lib/file9: Size differs, and 2 more")
+}
+
+func TestReadPrecachingListFilter(t *testing.T) {
+	imageListFile := filepath.Join(t.TempDir(), "containers.list")
+	images := []string{
+		"quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:aaa",
+		"registry.redhat.io/odf4/odf-operator-bundle@sha256:bbb",
+		"registry.redhat.io/rhacm2/klusterlet-rhel9@sha256:ccc",
+		"quay.io/edge-infrastructure/recert:v0",
+	}
+	assert.NoError(t, os.WriteFile(imageListFile, []byte(fmt.Sprintf("%s\n%s\n\n%s\n%s\n",
+		images[0], images[1], images[2], images[3])), 0o600))
+
+	imageList, err := ReadPrecachingList(imageListFile, "", "", false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, images, imageList)
+
+	// Exclude takes precedence over include, the required images are always kept
+	filter, err := NewPrecacheFilter([]string{`^registry\.redhat\.io/`, `ocp-v4\.0-art-dev`}, []string{`/odf4/`, `recert`},
+		images[3])
+	assert.NoError(t, err)
+	imageList, err = ReadPrecachingList(imageListFile, "", "", false, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{images[0], images[2], images[3]}, imageList)
+
+	// Exclude only
+	filter, err = NewPrecacheFilter(nil, []string{`rhacm2`})
+	assert.NoError(t, err)
+	kept, removed := filter.Apply(images)
+	assert.Equal(t, []string{images[0], images[1], images[3]}, kept)
+	assert.Equal(t, []string{images[2]}, removed)
+
+	filter, err = NewPrecacheFilter(nil, nil, images[3])
+	assert.NoError(t, err)
+	assert.Nil(t, filter)
+
+	_, err = NewPrecacheFilter([]string{`registry.redhat.io/(`}, nil)
+	assert.ErrorContains(t, err, "invalid precache include filter")
+}
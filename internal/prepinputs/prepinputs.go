@@ -0,0 +1,154 @@
+// Package prepinputs records the hashes of the inputs consumed by Prep, so that their changes can be detected before
+// the Upgrade starts from what Prep prepared.
+package prepinputs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+// InputsFile records the hashes of the inputs consumed by Prep, in the IBU workspace
+const InputsFile = utils.IBUWorkspacePath + "/prep_inputs.json"
+
+// The inputs consumed by Prep
+const (
+	// SeedImage is the seed image reference of the spec and the stateroot naming annotation, with the digest of the seed
+	// image the stateroot was set up from
+	SeedImage = "seedImage"
+	// OADPContent are the OADP ConfigMaps of the spec, validated at Prep
+	OADPContent = "oadpContent"
+	// ExtraManifests are the extra manifests ConfigMaps of the spec, validated at Prep
+	ExtraManifests = "extraManifests"
+	// PullSecret is the cluster pull secret, used to pull the seed image and pre-cache the images
+	PullSecret = "pullSecret"
+)
+
+// Hashes are the sha256 of each input consumed by Prep
+type Hashes map[string]string
+
+// Compute returns the hashes of the inputs consumed by Prep for the IBU, with the digest of its seed image. Missing
+// ConfigMaps are hashed as such, so that their deletion is detected as a change.
+func Compute(ctx context.Context, c client.Client, ibu *lcav1alpha1.ImageBasedUpgrade, seedDigest string) (Hashes, error) {
+	hashes := Hashes{}
+
+	h := sha256.New()
+	seedImageRef := ibu.Spec.SeedImageRef
+	fmt.Fprintf(h, "%s\n%s\n%s\n", seedImageRef.Image, seedImageRef.Version, seedDigest)
+	if seedImageRef.PullSecretRef != nil {
+		fmt.Fprintf(h, "%s\n", seedImageRef.PullSecretRef.Name)
+	}
+	// the annotation selects the stateroot set up by Prep
+	fmt.Fprintf(h, "%s\n", ibu.GetAnnotations()[common.StaterootSeedDigestSuffixAnnotation])
+	hashes[SeedImage] = hex.EncodeToString(h.Sum(nil))
+
+	oadpContent, err := hashConfigMaps(ctx, c, ibu.Spec.OADPContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash the oadp configmaps: %w", err)
+	}
+	hashes[OADPContent] = oadpContent
+
+	extraManifests, err := hashConfigMaps(ctx, c, ibu.Spec.ExtraManifests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash the extra manifests configmaps: %w", err)
+	}
+	hashes[ExtraManifests] = extraManifests
+
+	pullSecret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: common.PullSecretName, Namespace: common.OpenshiftConfigNamespace}, pullSecret); err != nil {
+		return nil, fmt.Errorf("failed to get the cluster pull secret: %w", err)
+	}
+	h = sha256.New()
+	h.Write(pullSecret.Data[corev1.DockerConfigJsonKey])
+	hashes[PullSecret] = hex.EncodeToString(h.Sum(nil))
+
+	return hashes, nil
+}
+
+// hashConfigMaps hashes the data of the referenced ConfigMaps, in the order of the references
+func hashConfigMaps(ctx context.Context, c client.Client, refs []lcav1alpha1.ConfigMapRef) (string, error) {
+	h := sha256.New()
+	for _, ref := range refs {
+		fmt.Fprintf(h, "%s/%s\n", ref.Namespace, ref.Name)
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, cm); err != nil {
+			if errors.IsNotFound(err) {
+				fmt.Fprintln(h, "missing")
+				continue
+			}
+			return "", fmt.Errorf("failed to get configmap %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+		writeSorted(h, cm.Data)
+		binaryData := make(map[string]string, len(cm.BinaryData))
+		for key, value := range cm.BinaryData {
+			binaryData[key] = string(value)
+		}
+		writeSorted(h, binaryData)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeSorted(h hash.Hash, data map[string]string) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%d:%s\n", key, len(data[key]), data[key])
+	}
+}
+
+// Changed returns the inputs whose hash differs from the recorded one, sorted by name
+func Changed(recorded, current Hashes) []string {
+	var changed []string
+	for input, hash := range current {
+		if recordedHash, ok := recorded[input]; ok && recordedHash != hash {
+			changed = append(changed, input)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Load reads the recorded hashes. It returns nil without error when no hashes were recorded.
+func Load(filename string) (Hashes, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read prep inputs record: %w", err)
+	}
+	hashes := Hashes{}
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return nil, fmt.Errorf("failed to parse prep inputs record: %w", err)
+	}
+	return hashes, nil
+}
+
+// Persist writes the hashes to the file
+func (h Hashes) Persist(filename string) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("failed to marshal prep inputs record: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write prep inputs record: %w", err)
+	}
+	return nil
+}
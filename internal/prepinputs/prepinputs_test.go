@@ -0,0 +1,61 @@
+package prepinputs
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+func TestCompute(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1.AddToScheme(scheme))
+	pullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: common.PullSecretName, Namespace: common.OpenshiftConfigNamespace},
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pullSecret).Build()
+
+	ibu := &lcav1alpha1.ImageBasedUpgrade{
+		Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+			SeedImageRef:   lcav1alpha1.SeedImageRef{Image: "quay.io/seed:4.16.0", Version: "4.16.0"},
+			ExtraManifests: []lcav1alpha1.ConfigMapRef{{Name: "extra", Namespace: "default"}},
+		},
+	}
+	missing, err := Compute(context.Background(), c, ibu, "sha256:0123")
+	assert.NoError(t, err)
+	assert.Len(t, missing, 4)
+
+	extra := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "extra", Namespace: "default"},
+		Data:       map[string]string{"cm.yaml": "data"},
+	}
+	assert.NoError(t, c.Create(context.Background(), extra))
+	created, err := Compute(context.Background(), c, ibu, "sha256:0123")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ExtraManifests}, Changed(missing, created))
+
+	other, err := Compute(context.Background(), c, ibu, "sha256:4567")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{SeedImage}, Changed(created, other))
+}
+
+func TestLoadAndPersist(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "prep_inputs.json")
+	hashes, err := Load(filename)
+	assert.NoError(t, err)
+	assert.Nil(t, hashes)
+
+	assert.NoError(t, Hashes{SeedImage: "abc"}.Persist(filename))
+	hashes, err = Load(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, Hashes{SeedImage: "abc"}, hashes)
+}
@@ -1,6 +1,7 @@
 package reboot
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -39,11 +40,11 @@ type IBUAutoRollbackConfig struct {
 type RebootIntf interface {
 	WriteIBUAutoRollbackConfigFile(ibu *lcav1alpha1.ImageBasedUpgrade) error
 	ReadIBUAutoRollbackConfigFile() (*IBUAutoRollbackConfig, error)
-	DisableInitMonitor() error
-	RebootToNewStateRoot(rationale string) error
-	IsOrigStaterootBooted(ibu *v1alpha1.ImageBasedUpgrade) (bool, error)
-	InitiateRollback(msg string) error
-	AutoRollbackIfEnabled(component, msg string)
+	DisableInitMonitor(ctx context.Context) error
+	RebootToNewStateRoot(ctx context.Context, rationale string) error
+	IsOrigStaterootBooted(ctx context.Context, ibu *v1alpha1.ImageBasedUpgrade) (bool, error)
+	InitiateRollback(ctx context.Context, msg string) error
+	AutoRollbackIfEnabled(ctx context.Context, component, msg string)
 }
 
 type RebootClient struct {
@@ -52,6 +53,7 @@ type RebootClient struct {
 	rpmOstreeClient      rpmostreeclient.IClient
 	ostreeClient         ostreeclient.IClient
 	ops                  ops.Ops
+	staterootLayout      common.StaterootLayout
 }
 
 // NewRebootClient creates and returns a RebootIntf interface for reboot and rollback commands
@@ -59,19 +61,21 @@ func NewRebootClient(log *logr.Logger,
 	hostCommandsExecutor ops.Execute,
 	rpmOstreeClient rpmostreeclient.IClient,
 	ostreeClient ostreeclient.IClient,
-	ops ops.Ops) RebootIntf {
+	ops ops.Ops,
+	staterootLayout common.StaterootLayout) RebootIntf {
 	return &RebootClient{
 		log:                  log,
 		hostCommandsExecutor: hostCommandsExecutor,
 		rpmOstreeClient:      rpmOstreeClient,
 		ostreeClient:         ostreeClient,
 		ops:                  ops,
+		staterootLayout:      staterootLayout,
 	}
 }
 
 func (c *RebootClient) WriteIBUAutoRollbackConfigFile(ibu *lcav1alpha1.ImageBasedUpgrade) error {
-	stateroot := common.GetStaterootName(ibu.Spec.SeedImageRef.Version)
-	staterootPath := common.GetStaterootPath(stateroot)
+	stateroot := c.staterootLayout.DesiredName(ibu)
+	staterootPath := c.staterootLayout.Path(stateroot)
 	cfgfile := common.PathOutsideChroot(filepath.Join(staterootPath, common.IBUAutoRollbackConfigFile))
 
 	cfgdir := filepath.Dir(cfgfile)
@@ -119,19 +123,19 @@ func (c *RebootClient) ReadIBUAutoRollbackConfigFile() (*IBUAutoRollbackConfig,
 	return rollbackCfg, nil
 }
 
-func (c *RebootClient) DisableInitMonitor() error {
+func (c *RebootClient) DisableInitMonitor(ctx context.Context) error {
 	// Check whether service-unit is active before stopping. The "stop" command will exit with 0 if already stopped,
 	// but would return a failure if the service-unit doesn't exist (for whatever reason).
-	if _, err := c.hostCommandsExecutor.Execute("systemctl", "is-active", common.IBUInitMonitorService); err == nil {
-		if _, err := c.hostCommandsExecutor.Execute("systemctl", "stop", common.IBUInitMonitorService); err != nil {
+	if _, err := c.hostCommandsExecutor.ExecuteWithContext(ctx, "systemctl", "is-active", common.IBUInitMonitorService); err == nil {
+		if _, err := c.hostCommandsExecutor.ExecuteWithContext(ctx, "systemctl", "stop", common.IBUInitMonitorService); err != nil {
 			return fmt.Errorf("failed to stop %s: %w", common.IBUInitMonitorService, err)
 		}
 	}
 
 	// Check whether service-unit is enabled before dsiabling. The "disable" command will exit with 0 if already disabled,
 	// but would return a failure if the service-unit doesn't exist (for whatever reason).
-	if _, err := c.hostCommandsExecutor.Execute("systemctl", "is-enabled", common.IBUInitMonitorService); err == nil {
-		if _, err := c.hostCommandsExecutor.Execute("systemctl", "disable", common.IBUInitMonitorService); err != nil {
+	if _, err := c.hostCommandsExecutor.ExecuteWithContext(ctx, "systemctl", "is-enabled", common.IBUInitMonitorService); err == nil {
+		if _, err := c.hostCommandsExecutor.ExecuteWithContext(ctx, "systemctl", "disable", common.IBUInitMonitorService); err != nil {
 			return fmt.Errorf("failed to disable %s: %w", common.IBUInitMonitorService, err)
 		}
 	}
@@ -140,17 +144,17 @@ func (c *RebootClient) DisableInitMonitor() error {
 		return fmt.Errorf("failed to delete %s: %w", common.IBUInitMonitorServiceFile, err)
 	}
 
-	if _, err := c.hostCommandsExecutor.Execute("systemctl", "daemon-reload"); err != nil {
+	if _, err := c.hostCommandsExecutor.ExecuteWithContext(ctx, "systemctl", "daemon-reload"); err != nil {
 		return fmt.Errorf("systemctl daemon-reload failed after deleting %s: %w", common.IBUInitMonitorServiceFile, err)
 	}
 
 	return nil
 }
 
-func (c *RebootClient) RebootToNewStateRoot(rationale string) error {
+func (c *RebootClient) RebootToNewStateRoot(ctx context.Context, rationale string) error {
 	c.log.Info(fmt.Sprintf("rebooting to a new stateroot: %s", rationale))
 
-	_, err := c.hostCommandsExecutor.Execute("systemd-run", "--unit", "lifecycle-agent-reboot",
+	_, err := c.hostCommandsExecutor.ExecuteWithContext(ctx, "systemd-run", "--unit", "lifecycle-agent-reboot",
 		"--description", fmt.Sprintf("\"lifecycle-agent: %s\"", rationale),
 		"systemctl", "--message=\"Image Based Upgrade\"", "reboot")
 	if err != nil {
@@ -163,31 +167,31 @@ func (c *RebootClient) RebootToNewStateRoot(rationale string) error {
 	return fmt.Errorf("failed to reboot. This should never happen! Please check the system")
 }
 
-func (c *RebootClient) IsOrigStaterootBooted(ibu *v1alpha1.ImageBasedUpgrade) (bool, error) {
-	currentStaterootName, err := c.rpmOstreeClient.GetCurrentStaterootName()
+func (c *RebootClient) IsOrigStaterootBooted(ctx context.Context, ibu *v1alpha1.ImageBasedUpgrade) (bool, error) {
+	currentStaterootName, err := c.rpmOstreeClient.GetCurrentStaterootName(ctx)
 	if err != nil {
 		return false, fmt.Errorf("failed to get current stateroot name: %w", err)
 	}
-	c.log.Info("stateroots", "current stateroot:", currentStaterootName, "desired stateroot", common.GetDesiredStaterootName(ibu))
-	return currentStaterootName != common.GetDesiredStaterootName(ibu), nil
+	c.log.Info("stateroots", "current stateroot:", currentStaterootName, "desired stateroot", c.staterootLayout.DesiredName(ibu))
+	return currentStaterootName != c.staterootLayout.DesiredName(ibu), nil
 }
 
-func (c *RebootClient) InitiateRollback(msg string) error {
-	if !c.ostreeClient.IsOstreeAdminSetDefaultFeatureEnabled() {
+func (c *RebootClient) InitiateRollback(ctx context.Context, msg string) error {
+	if !c.ostreeClient.IsOstreeAdminSetDefaultFeatureEnabled(ctx) {
 		return fmt.Errorf("automatic rollback not supported in this release")
 	}
 
 	c.log.Info("Updating saved IBU CR with status msg for rollback")
-	stateroot, err := c.rpmOstreeClient.GetUnbootedStaterootName()
+	stateroot, err := c.rpmOstreeClient.GetUnbootedStaterootName(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to determine stateroot path for rollback: %w", err)
 	}
 
-	if err := c.ops.RemountSysroot(); err != nil {
+	if err := c.ops.RemountSysroot(ctx); err != nil {
 		return fmt.Errorf("unable to remount sysroot: %w", err)
 	}
 
-	filePath := common.PathOutsideChroot(filepath.Join(common.GetStaterootPath(stateroot), utils.IBUFilePath))
+	filePath := common.PathOutsideChroot(filepath.Join(c.staterootLayout.Path(stateroot), utils.IBUFilePath))
 
 	savedIbu := &lcav1alpha1.ImageBasedUpgrade{}
 	if err := lcautils.ReadYamlOrJSONFile(filePath, savedIbu); err != nil {
@@ -202,16 +206,16 @@ func (c *RebootClient) InitiateRollback(msg string) error {
 
 	c.log.Info("Iniating rollback")
 
-	deploymentIndex, err := c.rpmOstreeClient.GetUnbootedDeploymentIndex()
+	deploymentIndex, err := c.rpmOstreeClient.GetUnbootedDeploymentIndex(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to get unbooted deployment for automatic rollback: %w", err)
 	}
 
-	if err = c.ostreeClient.SetDefaultDeployment(deploymentIndex); err != nil {
+	if err = c.ostreeClient.SetDefaultDeployment(ctx, deploymentIndex); err != nil {
 		return fmt.Errorf("unable to get set deployment for automatic rollback: %w", err)
 	}
 
-	if err = c.RebootToNewStateRoot("rollback"); err != nil {
+	if err = c.RebootToNewStateRoot(ctx, "rollback"); err != nil {
 		return fmt.Errorf("unable to get set deployment for automatic rollback: %w", err)
 	}
 
@@ -219,7 +223,7 @@ func (c *RebootClient) InitiateRollback(msg string) error {
 	return nil
 }
 
-func (c *RebootClient) AutoRollbackIfEnabled(component, msg string) {
+func (c *RebootClient) AutoRollbackIfEnabled(ctx context.Context, component, msg string) {
 	rollbackCfg, err := c.ReadIBUAutoRollbackConfigFile()
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -237,7 +241,7 @@ func (c *RebootClient) AutoRollbackIfEnabled(component, msg string) {
 	}
 
 	c.log.Info(fmt.Sprintf("Auto-rollback is enabled for component: %s", component))
-	if err = c.InitiateRollback(msg); err != nil {
+	if err = c.InitiateRollback(ctx, msg); err != nil {
 		c.log.Info(fmt.Sprintf("Unable to initiate rollback: %s", err))
 	}
 
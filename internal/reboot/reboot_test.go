@@ -1,10 +1,12 @@
 package reboot
 
 import (
+	"context"
 	"testing"
 
 	"github.com/go-logr/logr"
 	"github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
 	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 	rpmostreeclient "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
@@ -58,9 +60,9 @@ func TestIsOrigStaterootBooted(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rebootClient := NewRebootClient(&tt.args.log, tt.args.executor, tt.args.r, tt.args.ostreeClient, tt.args.ops)
-			mockRpmostreeclient.EXPECT().GetCurrentStaterootName().Return(tt.currentStateRoot, nil).Times(1)
-			got, err := rebootClient.IsOrigStaterootBooted(tt.args.ibu)
+			rebootClient := NewRebootClient(&tt.args.log, tt.args.executor, tt.args.r, tt.args.ostreeClient, tt.args.ops, common.CurrentStaterootLayout())
+			mockRpmostreeclient.EXPECT().GetCurrentStaterootName(gomock.Any()).Return(tt.currentStateRoot, nil).Times(1)
+			got, err := rebootClient.IsOrigStaterootBooted(context.Background(), tt.args.ibu)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("IsOrigStaterootBooted() error = %v, wantErr %v", err, tt.wantErr)
 				return
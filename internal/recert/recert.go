@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/openshift-kni/lifecycle-agent/api/seedreconfig"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
@@ -39,6 +40,9 @@ type RecertConfig struct {
 	CNSanReplaceRules     []string `json:"cn_san_replace_rules,omitempty"`
 	UseKeyRules           []string `json:"use_key_rules,omitempty"`
 	UseCertRules          []string `json:"use_cert_rules,omitempty"`
+	// NotBeforeBackdateSeconds moves the start of the validity of the regenerated certificates back in time. Only
+	// supported by the recert versions having the not_before_backdate_seconds configuration
+	NotBeforeBackdateSeconds int64 `json:"not_before_backdate_seconds,omitempty"`
 }
 
 // CreateRecertConfigFile function to create recert config file
@@ -74,6 +78,14 @@ func CreateRecertConfigFile(seedReconfig *seedreconfig.SeedReconfiguration, seed
 	}
 	config.KubeadminPasswordHash = seedReconfig.KubeadminPasswordHash
 
+	if protection := seedReconfig.ClockSkewProtection; protection != nil && protection.NotBeforeBackdate != "" {
+		backdate, err := time.ParseDuration(protection.NotBeforeBackdate)
+		if err != nil || backdate < 0 {
+			return fmt.Errorf("invalid certificate not-before backdate %q", protection.NotBeforeBackdate)
+		}
+		config.NotBeforeBackdateSeconds = int64(backdate.Seconds())
+	}
+
 	if _, err := os.Stat(cryptoDir); err == nil {
 		ingressFile, ingressCN, err := getIngressCNAndFile(cryptoDir)
 		if err != nil {
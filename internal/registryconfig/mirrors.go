@@ -0,0 +1,167 @@
+package registryconfig
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+// ConfFile is the registries configuration of the host, rendered by the MCO from the mirror sets of the cluster
+const ConfFile = "/etc/containers/registries.conf"
+
+// ReadMirrors returns the mirrors configured by the registries configuration under the root, registries.conf then its
+// drop-ins in the order they are applied. It covers the mirrors not declared by mirror sets, e.g. delivered by a
+// MachineConfig or by the registry config of the upgrade. Only the [[registry]] tables of the version 2 format are
+// read, the registries with a wildcard prefix being skipped.
+func ReadMirrors(root string) ([]utils.ImageMirror, error) {
+	files := []string{filepath.Join(root, ConfFile)}
+	dropIns, err := filepath.Glob(filepath.Join(root, DropInDir, "*.conf"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", DropInDir, err)
+	}
+	sort.Strings(dropIns)
+	files = append(files, dropIns...)
+
+	var imageMirrors []utils.ImageMirror
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		mirrors, err := parseMirrors(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		imageMirrors = append(imageMirrors, mirrors...)
+	}
+	return imageMirrors, nil
+}
+
+// registryTable is a [[registry]] table of a registries configuration
+type registryTable struct {
+	prefix   string
+	location string
+	blocked  bool
+	mirrors  []string
+}
+
+// parseMirrors reads the [[registry]] tables with mirrors of a registries configuration
+func parseMirrors(content []byte) ([]utils.ImageMirror, error) {
+	var registries []*registryTable
+	var current *registryTable
+	inMirror := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case line == "[[registry]]":
+			current = &registryTable{}
+			registries = append(registries, current)
+			inMirror = false
+			continue
+		case line == "[[registry.mirror]]":
+			if current == nil {
+				return nil, fmt.Errorf("line %d: mirror outside of a registry", lineNumber)
+			}
+			current.mirrors = append(current.mirrors, "")
+			inMirror = true
+			continue
+		case strings.HasPrefix(line, "["):
+			// another table, e.g. the aliases of short names
+			current = nil
+			inMirror = false
+			continue
+		case current == nil:
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			// a multi-line array of a key not read
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case inMirror && key == "location":
+			location, err := parseString(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			current.mirrors[len(current.mirrors)-1] = location
+		case !inMirror && (key == "prefix" || key == "location"):
+			s, err := parseString(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			if key == "prefix" {
+				current.prefix = s
+			} else {
+				current.location = s
+			}
+		case !inMirror && key == "blocked":
+			current.blocked = strings.HasPrefix(value, "true")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan registries configuration: %w", err)
+	}
+
+	var imageMirrors []utils.ImageMirror
+	for _, registry := range registries {
+		source := registry.prefix
+		if source == "" {
+			source = registry.location
+		}
+		imageMirror := utils.ImageMirror{Source: source, NeverContactSource: registry.blocked}
+		for _, mirror := range registry.mirrors {
+			if mirror != "" {
+				imageMirror.Mirrors = append(imageMirror.Mirrors, mirror)
+			}
+		}
+		if source == "" || strings.HasPrefix(source, "*") || len(imageMirror.Mirrors) == 0 {
+			continue
+		}
+		imageMirrors = append(imageMirrors, imageMirror)
+	}
+	return imageMirrors, nil
+}
+
+// parseString reads a TOML basic or literal string value, followed by an optional comment
+func parseString(value string) (string, error) {
+	if strings.HasPrefix(value, "'") {
+		end := strings.Index(value[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string %s", value)
+		}
+		return value[1 : end+1], nil
+	}
+	if !strings.HasPrefix(value, `"`) {
+		return "", fmt.Errorf("not a string: %s", value)
+	}
+	for end := 1; end < len(value); end++ {
+		switch value[end] {
+		case '\\':
+			end++
+		case '"':
+			s, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return "", fmt.Errorf("invalid string %s: %w", value, err)
+			}
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unterminated string %s", value)
+}
@@ -0,0 +1,66 @@
+package registryconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+func TestReadMirrors(t *testing.T) {
+	root := t.TempDir()
+	mirrors, err := ReadMirrors(root)
+	assert.NoError(t, err)
+	assert.Nil(t, mirrors)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, DropInDir), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, ConfFile), []byte(`unqualified-search-registries = ["registry.access.redhat.com", "docker.io"]
+short-name-mode = ""
+
+[[registry]]
+  prefix = ""
+  location = "quay.io/openshift-release-dev/ocp-v4.0-art-dev"
+  blocked = true
+
+  [[registry.mirror]]
+    location = "mirror.local:5000/ocp/art-dev" # local mirror
+    pull-from-mirror = "digest-only"
+
+  [[registry.mirror]]
+    location = 'backup.local/ocp/art-dev'
+
+[[registry]]
+  location = "insecure.local:5000"
+  insecure = true
+
+[[registry]]
+  prefix = "*.example.com"
+  location = "example.com"
+
+  [[registry.mirror]]
+    location = "mirror.local:5000/example"
+`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, DropInDir, "98-lca-ibu-mirrors.conf"), []byte(`[[registry]]
+prefix = "registry.redhat.io/rhel9"
+location = "registry.redhat.io/rhel9"
+[[registry.mirror]]
+location = "mirror.local:5000/rhel9"
+`), 0o644))
+
+	mirrors, err = ReadMirrors(root)
+	assert.NoError(t, err)
+	assert.Equal(t, []utils.ImageMirror{
+		{Source: "quay.io/openshift-release-dev/ocp-v4.0-art-dev",
+			Mirrors: []string{"mirror.local:5000/ocp/art-dev", "backup.local/ocp/art-dev"}, NeverContactSource: true},
+		{Source: "registry.redhat.io/rhel9", Mirrors: []string{"mirror.local:5000/rhel9"}},
+	}, mirrors)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(root, DropInDir, "99-invalid.conf"), []byte(`[[registry]]
+location = "quay.io
+`), 0o644))
+	_, err = ReadMirrors(root)
+	assert.ErrorContains(t, err, "99-invalid.conf: line 2: unterminated string")
+}
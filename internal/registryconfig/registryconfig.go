@@ -0,0 +1,190 @@
+// Package registryconfig installs the registries configuration and CA certificates delivered for an upgrade by the
+// Secret or ConfigMap referenced by the IBU, for the sites whose mirror changes between upgrades.
+package registryconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const (
+	// KindSecret and KindConfigMap are the supported kinds of the referenced object
+	KindSecret    = "Secret"
+	KindConfigMap = "ConfigMap"
+
+	// DropInDir holds the containers-registries.conf drop-ins
+	DropInDir = "/etc/containers/registries.conf.d"
+	// AnchorsDir holds the CA certificates added to the trusted CAs of the host
+	AnchorsDir = "/etc/pki/ca-trust/source/anchors"
+
+	// dropInPrefix and anchorPrefix name the installed files, to replace or remove them as a whole. The drop-ins
+	// are applied before the chunked registries configuration, which is the cluster wide one
+	dropInPrefix = "98-lca-ibu-"
+	anchorPrefix = "lca-ibu-"
+)
+
+// validKey matches the keys installed as files
+var validKey = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Config is the registries configuration of the upgrade, by file name
+type Config struct {
+	DropIns map[string][]byte
+	CACerts map[string][]byte
+}
+
+// Load reads and validates the registries configuration referenced by the IBU
+func Load(ctx context.Context, c client.Reader, ref *lcav1alpha1.RegistryConfigRef) (*Config, error) {
+	data := map[string][]byte{}
+	key := types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}
+	switch ref.Kind {
+	case "", KindSecret:
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, key, secret); err != nil {
+			return nil, fmt.Errorf("failed to get registry config secret %s: %w", key, err)
+		}
+		data = secret.Data
+	case KindConfigMap:
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, key, cm); err != nil {
+			return nil, fmt.Errorf("failed to get registry config configmap %s: %w", key, err)
+		}
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
+		}
+		for k, v := range cm.BinaryData {
+			data[k] = v
+		}
+	default:
+		return nil, fmt.Errorf("unsupported registry config kind %q, must be %s or %s", ref.Kind, KindSecret, KindConfigMap)
+	}
+
+	config := &Config{DropIns: map[string][]byte{}, CACerts: map[string][]byte{}}
+	for k, v := range data {
+		if !validKey.MatchString(k) {
+			return nil, fmt.Errorf("invalid key %q in registry config %s", k, key)
+		}
+		switch {
+		case strings.HasSuffix(k, ".conf"):
+			if len(bytes.TrimSpace(v)) == 0 {
+				return nil, fmt.Errorf("empty registries configuration %s in registry config %s", k, key)
+			}
+			config.DropIns[k] = v
+		case strings.HasSuffix(k, ".crt"), strings.HasSuffix(k, ".pem"):
+			if err := utils.ValidateCABundle(v); err != nil {
+				return nil, fmt.Errorf("invalid CA certificates %s in registry config %s: %w", k, key, err)
+			}
+			config.CACerts[k] = v
+		default:
+			return nil, fmt.Errorf("unsupported key %q in registry config %s, must end with .conf, .crt or .pem", k, key)
+		}
+	}
+	if len(config.DropIns) == 0 && len(config.CACerts) == 0 {
+		return nil, fmt.Errorf("registry config %s is empty", key)
+	}
+	return config, nil
+}
+
+// Install writes the registries configuration under the root, replacing the one previously installed
+func (c *Config) Install(root string) error {
+	if _, err := Remove(root); err != nil {
+		return err
+	}
+	if err := writeFiles(filepath.Join(root, DropInDir), dropInPrefix, c.DropIns); err != nil {
+		return err
+	}
+	return writeFiles(filepath.Join(root, AnchorsDir), anchorPrefix, c.CACerts)
+}
+
+// AppendCABundle appends the CA certificates to the extracted CA bundle, unless already there, for the new stateroot
+// whose CA bundle is only extracted again by the next update of its trusted CAs
+func (c *Config) AppendCABundle(bundleFile string) error {
+	if len(c.CACerts) == 0 {
+		return nil
+	}
+	content, err := os.ReadFile(bundleFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle %s: %w", bundleFile, err)
+	}
+	f, err := os.OpenFile(bundleFile, os.O_APPEND|os.O_WRONLY, 0o644) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", bundleFile, err)
+	}
+	defer f.Close()
+	for _, name := range sortedKeys(c.CACerts) {
+		if bytes.Contains(content, bytes.TrimSpace(c.CACerts[name])) {
+			continue
+		}
+		if _, err := f.Write(append([]byte("\n"), c.CACerts[name]...)); err != nil {
+			return fmt.Errorf("failed to append CA certificates %s to %s: %w", name, bundleFile, err)
+		}
+	}
+	return nil
+}
+
+// Remove deletes the registries configuration installed under the root, returning whether CA certificates were removed
+func Remove(root string) (bool, error) {
+	if _, err := removeFiles(filepath.Join(root, DropInDir), dropInPrefix); err != nil {
+		return false, err
+	}
+	return removeFiles(filepath.Join(root, AnchorsDir), anchorPrefix)
+}
+
+// UpdateCATrust extracts the CA bundle of the host again from its trusted CAs
+func UpdateCATrust(executor ops.Execute) error {
+	if _, err := executor.Execute("update-ca-trust", "extract"); err != nil {
+		return fmt.Errorf("failed to update the trusted CAs: %w", err)
+	}
+	return nil
+}
+
+func writeFiles(dir, prefix string, files map[string][]byte) error {
+	if len(files) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, prefix+name)
+		if err := os.WriteFile(filePath, content, 0o644); err != nil { //nolint:gosec
+			return fmt.Errorf("failed to write %s: %w", filePath, err)
+		}
+	}
+	return nil
+}
+
+func removeFiles(dir, prefix string) (bool, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, prefix+"*"))
+	if err != nil {
+		return false, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	for _, match := range matches {
+		if err := os.Remove(match); err != nil {
+			return false, fmt.Errorf("failed to remove %s: %w", match, err)
+		}
+	}
+	return len(matches) != 0, nil
+}
+
+func sortedKeys(files map[string][]byte) []string {
+	keys := make([]string, 0, len(files))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
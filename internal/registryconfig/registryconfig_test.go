@@ -0,0 +1,102 @@
+package registryconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/internal/testutils"
+)
+
+func TestLoad(t *testing.T) {
+	cert := testutils.CertificatePEM(t, "mirror-ca")
+	mirrorConf := "[[registry]]\nlocation = \"quay.io\"\n[[registry.mirror]]\nlocation = \"mirror.site.example.com:8443\"\n"
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "mirror", Namespace: "ztp-site"},
+			Data:       map[string][]byte{"mirror.conf": []byte(mirrorConf), "mirror-ca.crt": cert},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "mirror", Namespace: "ztp-site"},
+			Data:       map[string]string{"mirror-ca.pem": string(cert)},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid-cert", Namespace: "ztp-site"},
+			Data:       map[string]string{"mirror-ca.crt": "not a certificate"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "unsupported-key", Namespace: "ztp-site"},
+			Data:       map[string]string{"policy.json": "{}"},
+		},
+	).Build()
+
+	config, err := Load(context.Background(), c, &lcav1alpha1.RegistryConfigRef{Name: "mirror", Namespace: "ztp-site"})
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{
+		DropIns: map[string][]byte{"mirror.conf": []byte(mirrorConf)},
+		CACerts: map[string][]byte{"mirror-ca.crt": cert},
+	}, config)
+
+	config, err = Load(context.Background(), c,
+		&lcav1alpha1.RegistryConfigRef{Kind: KindConfigMap, Name: "mirror", Namespace: "ztp-site"})
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{DropIns: map[string][]byte{}, CACerts: map[string][]byte{"mirror-ca.pem": cert}}, config)
+
+	_, err = Load(context.Background(), c, &lcav1alpha1.RegistryConfigRef{Name: "missing", Namespace: "ztp-site"})
+	assert.ErrorContains(t, err, "failed to get registry config secret ztp-site/missing")
+
+	_, err = Load(context.Background(), c,
+		&lcav1alpha1.RegistryConfigRef{Kind: KindConfigMap, Name: "invalid-cert", Namespace: "ztp-site"})
+	assert.ErrorContains(t, err, "invalid CA certificates mirror-ca.crt")
+
+	_, err = Load(context.Background(), c,
+		&lcav1alpha1.RegistryConfigRef{Kind: KindConfigMap, Name: "unsupported-key", Namespace: "ztp-site"})
+	assert.ErrorContains(t, err, `unsupported key "policy.json"`)
+}
+
+func TestInstallAndRemove(t *testing.T) {
+	root := t.TempDir()
+	cert := testutils.CertificatePEM(t, "mirror-ca")
+	otherDropIn := filepath.Join(root, DropInDir, "99-lca-chunked.conf")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(otherDropIn), 0o755))
+	assert.NoError(t, os.WriteFile(otherDropIn, []byte("[[registry]]"), 0o600))
+
+	previous := &Config{DropIns: map[string][]byte{"old.conf": []byte("[[registry]]")}}
+	assert.NoError(t, previous.Install(root))
+	assert.FileExists(t, filepath.Join(root, DropInDir, "98-lca-ibu-old.conf"))
+
+	config := &Config{
+		DropIns: map[string][]byte{"mirror.conf": []byte("[[registry]]")},
+		CACerts: map[string][]byte{"mirror-ca.crt": cert},
+	}
+	assert.NoError(t, config.Install(root))
+	assert.NoFileExists(t, filepath.Join(root, DropInDir, "98-lca-ibu-old.conf"))
+	assert.FileExists(t, filepath.Join(root, DropInDir, "98-lca-ibu-mirror.conf"))
+	assert.FileExists(t, filepath.Join(root, AnchorsDir, "lca-ibu-mirror-ca.crt"))
+
+	bundleFile := filepath.Join(root, "tls-ca-bundle.pem")
+	assert.NoError(t, os.WriteFile(bundleFile, []byte("seed bundle\n"), 0o600))
+	assert.NoError(t, config.AppendCABundle(bundleFile))
+	assert.NoError(t, config.AppendCABundle(bundleFile))
+	content, err := os.ReadFile(bundleFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "seed bundle\n\n"+string(cert), string(content))
+
+	removed, err := Remove(root)
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	assert.NoFileExists(t, filepath.Join(root, DropInDir, "98-lca-ibu-mirror.conf"))
+	assert.NoFileExists(t, filepath.Join(root, AnchorsDir, "lca-ibu-mirror-ca.crt"))
+	assert.FileExists(t, otherDropIn)
+
+	removed, err = Remove(root)
+	assert.NoError(t, err)
+	assert.False(t, removed)
+}
@@ -0,0 +1,199 @@
+// Package registrytls renders the TLS overrides of the IBU for named registries, for lab registries with self-signed
+// certificates, into the arguments of the seed image and precaching pulls of their images and a temporary directory
+// of their CA certificates in the IBU workspace, instead of changing the containers configuration of the host.
+package registrytls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const (
+	// CertsDir is the workspace directory of the CA certificates of the registries, in a directory per registry
+	CertsDir = utils.IBUWorkspacePath + "/registry-certs.d"
+
+	// CABundleKey is the key of the CA certificates in the referenced ConfigMap
+	CABundleKey = "ca-bundle.crt"
+
+	// EnvRegistryTLS holds the JSON TLS overrides passed to the precaching job
+	EnvRegistryTLS = "REGISTRY_TLS"
+)
+
+// Registry is the TLS override of a registry
+type Registry struct {
+	Registry string `json:"registry"`
+	Insecure bool   `json:"insecure,omitempty"`
+	// CertDir holds the CA certificates of the registry, relative to the host root
+	CertDir string `json:"certDir,omitempty"`
+}
+
+// Config is the TLS overrides of the upgrade
+type Config struct {
+	Registries []Registry `json:"registries"`
+}
+
+// Validate checks the TLS overrides of the spec, without reading their CA certificates
+func Validate(spec *lcav1alpha1.ImageBasedUpgradeSpec) error {
+	seen := map[string]bool{}
+	for _, registryTLS := range spec.RegistryTLS {
+		if registryTLS.Registry == "" {
+			return fmt.Errorf("registryTLS entry without registry")
+		}
+		if lcautils.ExtractRegistryFromImage(registryTLS.Registry) != registryTLS.Registry {
+			return fmt.Errorf("invalid registry %q in registryTLS, must be a host with an optional port",
+				registryTLS.Registry)
+		}
+		if seen[registryTLS.Registry] {
+			return fmt.Errorf("registry %s listed more than once in registryTLS", registryTLS.Registry)
+		}
+		seen[registryTLS.Registry] = true
+		if !registryTLS.InsecureSkipTLSVerify && registryTLS.CABundle == nil {
+			return fmt.Errorf("registryTLS of registry %s sets neither insecureSkipTLSVerify nor caBundle",
+				registryTLS.Registry)
+		}
+	}
+	return nil
+}
+
+// Render renders the TLS overrides of the spec under the root, replacing the ones previously rendered, and returns
+// them, or nil if none
+func Render(ctx context.Context, c client.Reader, spec *lcav1alpha1.ImageBasedUpgradeSpec, root string) (*Config, error) {
+	if err := Remove(root); err != nil {
+		return nil, err
+	}
+	if err := Validate(spec); err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	for _, registryTLS := range spec.RegistryTLS {
+		registry := Registry{Registry: registryTLS.Registry, Insecure: registryTLS.InsecureSkipTLSVerify}
+		if registryTLS.CABundle != nil {
+			certDir, err := renderCABundle(ctx, c, registryTLS.Registry, registryTLS.CABundle, root)
+			if err != nil {
+				return nil, err
+			}
+			registry.CertDir = certDir
+		}
+		config.Registries = append(config.Registries, registry)
+	}
+
+	// The seed image flag is a shorthand for an insecure override of the seed image registry
+	if spec.SeedImageRef.InsecureSkipTLSVerify {
+		seedRegistry := lcautils.ExtractRegistryFromImage(spec.SeedImageRef.Image)
+		found := false
+		for i := range config.Registries {
+			if config.Registries[i].Registry == seedRegistry {
+				config.Registries[i].Insecure = true
+				found = true
+			}
+		}
+		if !found {
+			config.Registries = append(config.Registries, Registry{Registry: seedRegistry, Insecure: true})
+		}
+	}
+
+	if len(config.Registries) == 0 {
+		return nil, nil
+	}
+	return config, nil
+}
+
+func renderCABundle(ctx context.Context, c client.Reader, registry string, ref *lcav1alpha1.ConfigMapRef,
+	root string) (string, error) {
+	key := types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, key, cm); err != nil {
+		return "", fmt.Errorf("failed to get CA bundle configmap %s of registry %s: %w", key, registry, err)
+	}
+	caBundle, ok := cm.Data[CABundleKey]
+	if !ok {
+		return "", fmt.Errorf("missing %s in CA bundle configmap %s of registry %s", CABundleKey, key, registry)
+	}
+	if err := lcautils.ValidateCABundle([]byte(caBundle)); err != nil {
+		return "", fmt.Errorf("invalid CA bundle configmap %s of registry %s: %w", key, registry, err)
+	}
+
+	certDir := filepath.Join(CertsDir, registry)
+	if err := os.MkdirAll(filepath.Join(root, certDir), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", certDir, err)
+	}
+	caFile := filepath.Join(root, certDir, "ca.crt")
+	if err := os.WriteFile(caFile, []byte(caBundle), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", caFile, err)
+	}
+	return certDir, nil
+}
+
+// Remove deletes the CA certificates rendered under the root
+func Remove(root string) error {
+	if err := os.RemoveAll(filepath.Join(root, CertsDir)); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", CertsDir, err)
+	}
+	return nil
+}
+
+// Args returns the podman pull or skopeo arguments overriding the TLS configuration of the registry of the image, or
+// nil if none. The override also applies to the mirrors of the registry the image is pulled from
+func (c *Config) Args(image string) []string {
+	if c == nil {
+		return nil
+	}
+	registry := lcautils.ExtractRegistryFromImage(image)
+	for _, r := range c.Registries {
+		if r.Registry != registry {
+			continue
+		}
+		if r.Insecure {
+			return []string{"--tls-verify=false"}
+		}
+		if r.CertDir != "" {
+			return []string{"--cert-dir", r.CertDir}
+		}
+	}
+	return nil
+}
+
+// SourceArgs returns the skopeo copy arguments overriding the TLS configuration of the registry of the source image,
+// or nil if none
+func (c *Config) SourceArgs(image string) []string {
+	args := c.Args(image)
+	if len(args) != 0 {
+		args = append([]string{"--src-" + strings.TrimPrefix(args[0], "--")}, args[1:]...)
+	}
+	return args
+}
+
+// EnvVars returns the env vars passing the TLS overrides to the precaching job
+func (c *Config) EnvVars() ([]corev1.EnvVar, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal registry TLS config: %w", err)
+	}
+	return []corev1.EnvVar{{Name: EnvRegistryTLS, Value: string(data)}}, nil
+}
+
+// FromEnv returns the TLS overrides passed to the precaching job, or nil if none
+func FromEnv() (*Config, error) {
+	value := os.Getenv(EnvRegistryTLS)
+	if value == "" {
+		return nil, nil
+	}
+	config := &Config{}
+	if err := json.Unmarshal([]byte(value), config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", EnvRegistryTLS, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,156 @@
+package registrytls
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/internal/testutils"
+)
+
+func TestValidate(t *testing.T) {
+	caBundle := &lcav1alpha1.ConfigMapRef{Name: "lab-ca", Namespace: "ztp-site"}
+	testcases := []struct {
+		name        string
+		registryTLS []lcav1alpha1.RegistryTLS
+		expectedErr string
+	}{
+		{
+			name: "valid",
+			registryTLS: []lcav1alpha1.RegistryTLS{
+				{Registry: "registry.lab.example.com:5000", InsecureSkipTLSVerify: true},
+				{Registry: "mirror.lab.example.com", CABundle: caBundle},
+			},
+		},
+		{
+			name:        "missing registry",
+			registryTLS: []lcav1alpha1.RegistryTLS{{InsecureSkipTLSVerify: true}},
+			expectedErr: "registryTLS entry without registry",
+		},
+		{
+			name:        "repository instead of registry",
+			registryTLS: []lcav1alpha1.RegistryTLS{{Registry: "registry.lab.example.com/org", InsecureSkipTLSVerify: true}},
+			expectedErr: "must be a host with an optional port",
+		},
+		{
+			name: "duplicate registry",
+			registryTLS: []lcav1alpha1.RegistryTLS{
+				{Registry: "registry.lab.example.com", InsecureSkipTLSVerify: true},
+				{Registry: "registry.lab.example.com", CABundle: caBundle},
+			},
+			expectedErr: "listed more than once",
+		},
+		{
+			name:        "no override",
+			registryTLS: []lcav1alpha1.RegistryTLS{{Registry: "registry.lab.example.com"}},
+			expectedErr: "sets neither insecureSkipTLSVerify nor caBundle",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(&lcav1alpha1.ImageBasedUpgradeSpec{RegistryTLS: tc.registryTLS})
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	root := t.TempDir()
+	cert := testutils.CertificatePEM(t, "lab-registry-ca")
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "lab-ca", Namespace: "ztp-site"},
+			Data:       map[string]string{CABundleKey: string(cert)},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid-ca", Namespace: "ztp-site"},
+			Data:       map[string]string{CABundleKey: "not a certificate"},
+		},
+	).Build()
+
+	config, err := Render(context.Background(), c, &lcav1alpha1.ImageBasedUpgradeSpec{
+		SeedImageRef: lcav1alpha1.SeedImageRef{Image: "quay.io/org/seed:4.15.0"},
+	}, root)
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	spec := &lcav1alpha1.ImageBasedUpgradeSpec{
+		SeedImageRef: lcav1alpha1.SeedImageRef{Image: "registry.lab.example.com:5000/org/seed:4.15.0", InsecureSkipTLSVerify: true},
+		RegistryTLS: []lcav1alpha1.RegistryTLS{
+			{Registry: "mirror.lab.example.com", CABundle: &lcav1alpha1.ConfigMapRef{Name: "lab-ca", Namespace: "ztp-site"}},
+		},
+	}
+	config, err = Render(context.Background(), c, spec, root)
+	assert.NoError(t, err)
+	certDir := filepath.Join(CertsDir, "mirror.lab.example.com")
+	assert.Equal(t, &Config{Registries: []Registry{
+		{Registry: "mirror.lab.example.com", CertDir: certDir},
+		{Registry: "registry.lab.example.com:5000", Insecure: true},
+	}}, config)
+	content, err := os.ReadFile(filepath.Join(root, certDir, "ca.crt"))
+	assert.NoError(t, err)
+	assert.Equal(t, cert, content)
+
+	// The seed image flag applies to a registry also listed
+	spec.SeedImageRef.Image = "mirror.lab.example.com/org/seed:4.15.0"
+	config, err = Render(context.Background(), c, spec, root)
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{Registries: []Registry{
+		{Registry: "mirror.lab.example.com", Insecure: true, CertDir: certDir},
+	}}, config)
+
+	spec.RegistryTLS[0].CABundle.Name = "invalid-ca"
+	_, err = Render(context.Background(), c, spec, root)
+	assert.ErrorContains(t, err, "invalid CA bundle configmap ztp-site/invalid-ca")
+	assert.NoDirExists(t, filepath.Join(root, certDir))
+
+	assert.NoError(t, Remove(root))
+	assert.NoDirExists(t, filepath.Join(root, CertsDir))
+}
+
+func TestArgs(t *testing.T) {
+	var none *Config
+	assert.Nil(t, none.Args("quay.io/org/image:latest"))
+
+	config := &Config{Registries: []Registry{
+		{Registry: "registry.lab.example.com:5000", Insecure: true},
+		{Registry: "mirror.lab.example.com", CertDir: "/var/lib/lca/workspace/registry-certs.d/mirror.lab.example.com"},
+	}}
+	assert.Equal(t, []string{"--tls-verify=false"}, config.Args("registry.lab.example.com:5000/org/image:latest"))
+	assert.Equal(t, []string{"--cert-dir", "/var/lib/lca/workspace/registry-certs.d/mirror.lab.example.com"},
+		config.Args("mirror.lab.example.com/org/image@sha256:1234"))
+	assert.Nil(t, config.Args("registry.lab.example.com/org/image:latest"))
+
+	assert.Equal(t, []string{"--src-tls-verify=false"}, config.SourceArgs("registry.lab.example.com:5000/org/image:latest"))
+	assert.Equal(t, []string{"--src-cert-dir", "/var/lib/lca/workspace/registry-certs.d/mirror.lab.example.com"},
+		config.SourceArgs("mirror.lab.example.com/org/image@sha256:1234"))
+	assert.Nil(t, none.SourceArgs("quay.io/org/image:latest"))
+}
+
+func TestEnvVars(t *testing.T) {
+	config := &Config{Registries: []Registry{{Registry: "registry.lab.example.com:5000", Insecure: true}}}
+	envVars, err := config.EnvVars()
+	assert.NoError(t, err)
+	if assert.Len(t, envVars, 1) {
+		t.Setenv(envVars[0].Name, envVars[0].Value)
+	}
+	fromEnv, err := FromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, config, fromEnv)
+
+	t.Setenv(EnvRegistryTLS, "")
+	fromEnv, err = FromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, fromEnv)
+}
@@ -0,0 +1,74 @@
+package seedimage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+const (
+	// OCIArchivePrefix references a seed image as an OCI archive on the host, e.g. oci-archive:///var/tmp/seed.tar
+	OCIArchivePrefix = "oci-archive://"
+	// DirPrefix references a seed image as a directory of the dir transport on the host, e.g. dir:///var/tmp/seed
+	DirPrefix = "dir://"
+
+	// archiveRepository is the repository the seed images loaded from the host are named under in the container
+	// storage
+	archiveRepository = "localhost/lca-seed-archive"
+)
+
+// Archive is a seed image pre-placed on the host, for the disconnected sites without a registry
+type Archive struct {
+	// Transport is the transport of skopeo reading the archive, oci-archive or dir
+	Transport string
+	// Path is the absolute path of the archive on the host
+	Path string
+}
+
+// ParseArchive returns the archive referenced by the seed image reference, or nil for a registry reference. The path
+// of the archive must be absolute.
+func ParseArchive(image string) (*Archive, error) {
+	for prefix, transport := range map[string]string{OCIArchivePrefix: "oci-archive", DirPrefix: "dir"} {
+		path, found := strings.CutPrefix(image, prefix)
+		if !found {
+			continue
+		}
+		if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+			return nil, fmt.Errorf("invalid seed image %s, the path of the %s must be absolute and clean, e.g. %s/var/tmp/seed",
+				image, transport, prefix)
+		}
+		return &Archive{Transport: transport, Path: path}, nil
+	}
+	return nil, nil
+}
+
+// StorageName returns the name of the seed image in the container storage: a name derived from the reference for an
+// archive, as its reference is not a valid image name, or else the reference itself
+func StorageName(image string) string {
+	if archive, err := ParseArchive(image); err != nil || archive == nil {
+		return image
+	}
+	sum := sha256.Sum256([]byte(image))
+	return archiveRepository + ":" + hex.EncodeToString(sum[:])[:12]
+}
+
+// Load copies the seed image from its archive on the host into the container storage, under its StorageName
+func Load(ctx context.Context, executor ops.Execute, image string) error {
+	archive, err := ParseArchive(image)
+	if err != nil {
+		return err
+	}
+	if archive == nil {
+		return fmt.Errorf("seed image %s is not an archive", image)
+	}
+	if _, err := executor.ExecuteWithContext(ctx, "skopeo", "copy", archive.Transport+":"+archive.Path,
+		"containers-storage:"+StorageName(image)); err != nil {
+		return fmt.Errorf("failed to load seed image from %s: %w", archive.Path, err)
+	}
+	return nil
+}
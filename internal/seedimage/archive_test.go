@@ -0,0 +1,56 @@
+package seedimage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+func TestParseArchive(t *testing.T) {
+	for _, tc := range []struct {
+		image       string
+		expected    *Archive
+		expectedErr string
+	}{
+		{image: "quay.io/org/seed:4.15.0"},
+		{image: "oci-archive:///var/tmp/seed.tar", expected: &Archive{Transport: "oci-archive", Path: "/var/tmp/seed.tar"}},
+		{image: "dir:///var/tmp/seed", expected: &Archive{Transport: "dir", Path: "/var/tmp/seed"}},
+		{image: "oci-archive://seed.tar", expectedErr: "the path of the oci-archive must be absolute and clean"},
+		{image: "dir:///var/tmp/../seed", expectedErr: "the path of the dir must be absolute and clean"},
+	} {
+		t.Run(tc.image, func(t *testing.T) {
+			archive, err := ParseArchive(tc.image)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, archive)
+		})
+	}
+}
+
+func TestStorageName(t *testing.T) {
+	assert.Equal(t, "quay.io/org/seed:4.15.0", StorageName("quay.io/org/seed:4.15.0"))
+	name := StorageName("oci-archive:///var/tmp/seed.tar")
+	assert.True(t, strings.HasPrefix(name, "localhost/lca-seed-archive:"), name)
+	assert.Len(t, strings.TrimPrefix(name, "localhost/lca-seed-archive:"), 12)
+	assert.Equal(t, name, StorageName("oci-archive:///var/tmp/seed.tar"))
+	assert.NotEqual(t, name, StorageName("dir:///var/tmp/seed.tar"))
+}
+
+func TestLoad(t *testing.T) {
+	image := "oci-archive:///var/tmp/seed.tar"
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "skopeo", "copy", "oci-archive:/var/tmp/seed.tar",
+		"containers-storage:"+StorageName(image)).Return("", nil)
+	assert.NoError(t, Load(context.Background(), mockExec, image))
+
+	assert.ErrorContains(t, Load(context.Background(), mockExec, "quay.io/org/seed:4.15.0"),
+		"seed image quay.io/org/seed:4.15.0 is not an archive")
+}
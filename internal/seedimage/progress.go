@@ -0,0 +1,202 @@
+package seedimage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+// pullTmpDirGlob matches the directories of the host where podman downloads the blobs of an image, before committing
+// them to the container storage at the end of the pull
+const pullTmpDirGlob = "/var/tmp/container_images_storage*"
+
+var (
+	// progressInterval is the interval between two progress reports of a pull, overridden in tests
+	progressInterval = 10 * time.Second
+	// pullTmpDirs returns the download directories of the pulls, overridden in tests
+	pullTmpDirs = func() ([]string, error) {
+		return filepath.Glob(common.PathOutsideChroot(pullTmpDirGlob)) //nolint:wrapcheck
+	}
+)
+
+// Progress is the download progress of a pull. The totals are zero when the manifest of the image is unavailable.
+type Progress struct {
+	Layers           int
+	LayersDownloaded int
+	Bytes            int64
+	BytesDownloaded  int64
+}
+
+func (p Progress) String() string {
+	const mib = 1024 * 1024
+	if p.Bytes == 0 {
+		return fmt.Sprintf("%d layers, %.1f MiB downloaded", p.LayersDownloaded, float64(p.BytesDownloaded)/mib)
+	}
+	return fmt.Sprintf("%d of %d layers, %.1f of %.1f MiB downloaded (%d%%)", p.LayersDownloaded, p.Layers,
+		float64(p.BytesDownloaded)/mib, float64(p.Bytes)/mib, p.BytesDownloaded*100/p.Bytes)
+}
+
+// PullWithProgress pulls the image like Pull, reporting the download progress at regular intervals. The progress is
+// measured from the blobs downloaded by podman, against the layer sizes of the manifest of the image, as the podman
+// output only lists the blobs without their progress when not attached to a terminal.
+func PullWithProgress(ctx context.Context, executor ops.Execute, image, authFile, arch string, tlsArgs []string,
+	report func(Progress)) error {
+	progress := Progress{}
+	if layers, size, err := layerSizes(ctx, executor, image, authFile, arch, tlsArgs); err == nil {
+		progress.Layers, progress.Bytes = layers, size
+	}
+
+	start := time.Now()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				progress.LayersDownloaded, progress.BytesDownloaded = downloaded(start)
+				if progress.Layers != 0 && progress.LayersDownloaded > progress.Layers {
+					// the config blob is downloaded along with the layers
+					progress.LayersDownloaded = progress.Layers
+				}
+				if progress.Bytes != 0 && progress.BytesDownloaded > progress.Bytes {
+					progress.BytesDownloaded = progress.Bytes
+				}
+				report(progress)
+			}
+		}
+	}()
+
+	err := Pull(ctx, executor, image, authFile, arch, tlsArgs)
+	close(done)
+	wg.Wait()
+	return err
+}
+
+// downloaded returns the blobs downloaded and their size, from the download directories created since the start
+func downloaded(start time.Time) (blobs int, size int64) {
+	dirs, err := pullTmpDirs()
+	if err != nil {
+		return 0, 0
+	}
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() || info.ModTime().Before(start.Add(-time.Second)) {
+			continue
+		}
+		_ = filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.Type().IsRegular() {
+				// the blobs are removed at the end of the pull
+				return nil
+			}
+			if info, err := entry.Info(); err == nil {
+				blobs++
+				size += info.Size()
+			}
+			return nil
+		})
+	}
+	return blobs, size
+}
+
+// manifest holds the fields of an image manifest, or manifest list, needed for the layer sizes and the platforms
+type manifest struct {
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Layers []struct {
+		Size int64 `json:"size"`
+	} `json:"layers"`
+}
+
+// platformDigest returns the digest of the manifest of the linux image of the architecture in the manifest list, and
+// an ErrNoPlatformImage error listing the platforms of the manifest list if there is none
+func (m *manifest) platformDigest(image, arch string) (string, error) {
+	var platforms []string
+	for _, entry := range m.Manifests {
+		if entry.Platform.OS == "linux" && entry.Platform.Architecture == arch {
+			return entry.Digest, nil
+		}
+		platforms = append(platforms, entry.Platform.OS+"/"+entry.Platform.Architecture)
+	}
+	return "", fmt.Errorf("%w: manifest list %s has no image for linux/%s, only for %s", ErrNoPlatformImage, image,
+		arch, strings.Join(platforms, ", "))
+}
+
+// ErrNoPlatformImage is returned when a manifest list has no image for the platform of the node
+var ErrNoPlatformImage = errors.New("no image for the platform")
+
+// CheckPlatform checks the image has an image for linux on the given architecture when it is a manifest list,
+// returning an ErrNoPlatformImage error otherwise, authenticating with the given auth file, with the given TLS arguments
+// overriding the TLS configuration of its registry
+func CheckPlatform(ctx context.Context, executor ops.Execute, image, authFile, arch string, tlsArgs []string) error {
+	m, err := inspectManifest(ctx, executor, image, authFile, tlsArgs)
+	if err != nil {
+		return err
+	}
+	if len(m.Manifests) == 0 {
+		return nil
+	}
+	_, err = m.platformDigest(image, arch)
+	return err
+}
+
+// layerSizes returns the number of layers of the image and their total size, resolving the manifest of the given
+// architecture, or of the host if empty, when the image is a manifest list
+func layerSizes(ctx context.Context, executor ops.Execute, image, authFile, arch string, tlsArgs []string) (int, int64, error) {
+	m, err := inspectManifest(ctx, executor, image, authFile, tlsArgs)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(m.Manifests) != 0 {
+		if arch == "" {
+			arch = runtime.GOARCH
+		}
+		digest, err := m.platformDigest(image, arch)
+		if err != nil {
+			return 0, 0, err
+		}
+		if m, err = inspectManifest(ctx, executor, lcautils.ImageRepository(image)+"@"+digest, authFile, tlsArgs); err != nil {
+			return 0, 0, err
+		}
+	}
+	var size int64
+	for _, layer := range m.Layers {
+		size += layer.Size
+	}
+	return len(m.Layers), size, nil
+}
+
+func inspectManifest(ctx context.Context, executor ops.Execute, image, authFile string, tlsArgs []string) (*manifest, error) {
+	args := append([]string{"inspect", "--raw", "--retry-times", "3", "--authfile", authFile}, tlsArgs...)
+	output, err := executor.ExecuteWithContext(ctx, "skopeo", append(args, "docker://"+image)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect manifest of %s: %w", image, err)
+	}
+	m := &manifest{}
+	if err := json.Unmarshal([]byte(output), m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of %s: %w", image, err)
+	}
+	return m, nil
+}
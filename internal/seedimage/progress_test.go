@@ -0,0 +1,84 @@
+package seedimage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+func TestProgressString(t *testing.T) {
+	assert.Equal(t, "3 of 12 layers, 512.0 of 2048.0 MiB downloaded (25%)",
+		Progress{Layers: 12, LayersDownloaded: 3, Bytes: 2 << 30, BytesDownloaded: 512 << 20}.String())
+	assert.Equal(t, "3 layers, 512.0 MiB downloaded", Progress{LayersDownloaded: 3, BytesDownloaded: 512 << 20}.String())
+}
+
+func TestPullWithProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	origPullTmpDirs, origProgressInterval := pullTmpDirs, progressInterval
+	defer func() {
+		pullTmpDirs, progressInterval = origPullTmpDirs, origProgressInterval
+	}()
+	pullTmpDirs = func() ([]string, error) {
+		return filepath.Glob(filepath.Join(tmpDir, "container_images_storage*"))
+	}
+	progressInterval = 10 * time.Millisecond
+
+	const image = "quay.io/org/seed:4.15.0"
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "skopeo", "inspect", "--raw", "--retry-times", "3",
+		"--authfile", "/auth.json", "--tls-verify=false", "docker://"+image).
+		Return(fmt.Sprintf(`{"manifests":[{"digest":"sha256:1234","platform":{"architecture":%q,"os":"linux"}}]}`,
+			runtime.GOARCH), nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "skopeo", "inspect", "--raw", "--retry-times", "3",
+		"--authfile", "/auth.json", "--tls-verify=false", "docker://quay.io/org/seed@sha256:1234").
+		Return(`{"layers":[{"size":100},{"size":300}]}`, nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "pull", "--authfile", "/auth.json", "--tls-verify=false", image).
+		DoAndReturn(func(ctx context.Context, command string, args ...string) (string, error) {
+			dir := filepath.Join(tmpDir, "container_images_storage1234")
+			assert.NoError(t, os.Mkdir(dir, 0o700))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "1"), make([]byte, 100), 0o600))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "2"), make([]byte, 50), 0o600))
+			time.Sleep(100 * time.Millisecond)
+			return "", nil
+		})
+
+	var mutex sync.Mutex
+	var reports []Progress
+	assert.NoError(t, PullWithProgress(context.Background(), mockExec, image, "/auth.json", "", []string{"--tls-verify=false"},
+		func(progress Progress) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			reports = append(reports, progress)
+		}))
+	if assert.NotEmpty(t, reports) {
+		assert.Equal(t, Progress{Layers: 2, LayersDownloaded: 2, Bytes: 400, BytesDownloaded: 150}, reports[len(reports)-1])
+	}
+}
+
+func TestCheckPlatform(t *testing.T) {
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "skopeo", "inspect", "--raw", "--retry-times", "3",
+		"--authfile", "/auth.json", "docker://quay.io/org/seed:4.16.0").
+		Return(`{"manifests":[{"digest":"sha256:1234","platform":{"architecture":"amd64","os":"linux"}},`+
+			`{"digest":"sha256:5678","platform":{"architecture":"arm64","os":"linux"}}]}`, nil).Times(2)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "skopeo", "inspect", "--raw", "--retry-times", "3",
+		"--authfile", "/auth.json", "docker://quay.io/org/seed:4.15.0").
+		Return(`{"layers":[{"size":100}]}`, nil)
+
+	assert.NoError(t, CheckPlatform(context.Background(), mockExec, "quay.io/org/seed:4.16.0", "/auth.json", "arm64", nil))
+	err := CheckPlatform(context.Background(), mockExec, "quay.io/org/seed:4.16.0", "/auth.json", "ppc64le", nil)
+	assert.ErrorIs(t, err, ErrNoPlatformImage)
+	assert.ErrorContains(t, err, "manifest list quay.io/org/seed:4.16.0 has no image for linux/ppc64le, only for linux/amd64, linux/arm64")
+	// Not a manifest list
+	assert.NoError(t, CheckPlatform(context.Background(), mockExec, "quay.io/org/seed:4.15.0", "/auth.json", "ppc64le", nil))
+}
@@ -0,0 +1,82 @@
+package seedimage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+// StoredImage is a seed image in the container storage of the host, as listed by podman images
+type StoredImage struct {
+	ID string `json:"Id"`
+	// Names are the references the image is tagged with, empty for a dangling image
+	Names []string `json:"Names"`
+	// RepoDigests are the references by digest of the image
+	RepoDigests []string `json:"RepoDigests"`
+	Size        int64    `json:"Size"`
+}
+
+// List returns the seed images in the container storage of the host, identified by their seed format label
+func List(ctx context.Context, executor ops.Execute) ([]StoredImage, error) {
+	output, err := executor.ExecuteWithContext(ctx, "podman", "images", "--filter", "label="+common.SeedFormatOCILabel,
+		"--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list seed images: %w", err)
+	}
+	var images []StoredImage
+	if output == "" {
+		return images, nil
+	}
+	if err := json.Unmarshal([]byte(output), &images); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal images output: %w", err)
+	}
+	return images, nil
+}
+
+// Prune removes the seed images from the container storage of the host, but those referenced, by name or by digest,
+// by one of the images to keep, e.g. the seed image of the IBU kept for a retried Prep. It returns the removed images,
+// by name or by ID for the dangling ones, and removes as many of them as possible on failure.
+func Prune(ctx context.Context, executor ops.Execute, keep ...string) ([]string, error) {
+	images, err := List(ctx, executor)
+	if err != nil {
+		return nil, err
+	}
+	kept := map[string]bool{}
+	for _, image := range keep {
+		kept[StorageName(image)] = true
+	}
+
+	var removed []string
+	var errs []error
+	for _, image := range images {
+		name := image.ID
+		if len(image.Names) != 0 {
+			name = image.Names[0]
+		}
+		if isKept(image, kept) {
+			continue
+		}
+		// not forced, an image mounted or used by a container fails to be removed
+		if _, err := executor.ExecuteWithContext(ctx, "podman", "rmi", image.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove seed image %s: %w", name, err))
+			continue
+		}
+		removed = append(removed, name)
+	}
+	return removed, errors.Join(errs...)
+}
+
+func isKept(image StoredImage, kept map[string]bool) bool {
+	for _, names := range [][]string{image.Names, image.RepoDigests} {
+		for _, name := range names {
+			if kept[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package seedimage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+func TestPrune(t *testing.T) {
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "images", "--filter",
+		"label=com.openshift.lifecycle-agent.seed_format_version", "--format", "json").
+		Return(`[{"Id":"aaa","Names":["quay.io/org/seed:4.15.0"],"Size":1024},`+
+			`{"Id":"bbb","Names":["quay.io/org/seed:4.16.0"],"RepoDigests":["quay.io/org/seed@sha256:1234"],"Size":2048},`+
+			`{"Id":"ccc","Size":4096},`+
+			`{"Id":"ddd","Names":["quay.io/org/seed:4.16.1"],"Size":4096}]`, nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "rmi", "aaa").Return("", nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "rmi", "ccc").Return("", nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "rmi", "ddd").
+		Return("image is in use by a container", errors.New("exit status 2"))
+
+	removed, err := Prune(context.Background(), mockExec, "quay.io/org/seed@sha256:1234")
+	assert.Equal(t, []string{"quay.io/org/seed:4.15.0", "ccc"}, removed)
+	assert.EqualError(t, err, "failed to remove seed image quay.io/org/seed:4.16.1: exit status 2")
+
+	// No seed image
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "images", "--filter",
+		"label=com.openshift.lifecycle-agent.seed_format_version", "--format", "json").Return("[]", nil)
+	removed, err = Prune(context.Background(), mockExec)
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+}
@@ -0,0 +1,78 @@
+package seedimage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultAttempts is the number of attempts of a registry operation, including the first one
+	DefaultAttempts = 3
+	// DefaultBackoff is the wait before the first retry of a registry operation
+	DefaultBackoff = 10 * time.Second
+	// maxBackoff caps the doubling of the backoff
+	maxBackoff = 5 * time.Minute
+)
+
+// RetryPolicy retries the registry operations on the seed image, for a transient registry failure not to fail the
+// whole Prep stage. The backoff doubles after each failed attempt.
+type RetryPolicy struct {
+	Attempts int
+	Backoff  time.Duration
+	// MaxDuration bounds the time spent on the attempts, no retry being started past it. Zero is no limit.
+	MaxDuration time.Duration
+}
+
+// NewRetryPolicy returns the policy of the given values, the defaults replacing those <= 0
+func NewRetryPolicy(attempts int, backoff, maxDuration time.Duration) RetryPolicy {
+	policy := RetryPolicy{Attempts: attempts, Backoff: backoff, MaxDuration: maxDuration}
+	if policy.Attempts <= 0 {
+		policy.Attempts = DefaultAttempts
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = DefaultBackoff
+	}
+	if policy.MaxDuration < 0 {
+		policy.MaxDuration = 0
+	}
+	return policy
+}
+
+// sleep is overridden by the tests
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Retry calls fn until it succeeds, the attempts are exhausted, the next retry would start past the max duration or
+// the context is done, and returns the error of the last attempt. onRetry, if set, is called before waiting for each
+// retry, with the number of the failed attempt.
+func (p RetryPolicy) Retry(ctx context.Context, onRetry func(attempt int, err error, wait time.Duration), fn func() error) error {
+	start := time.Now()
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || ctx.Err() != nil {
+			return err
+		}
+		if p.MaxDuration > 0 && time.Since(start)+backoff > p.MaxDuration {
+			return fmt.Errorf("giving up after %d attempts, the next one would exceed %s: %w", attempt, p.MaxDuration, err)
+		}
+		if onRetry != nil {
+			onRetry(attempt, err, backoff)
+		}
+		if sleepErr := sleep(ctx, backoff); sleepErr != nil {
+			return err
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package seedimage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRetryPolicy(t *testing.T) {
+	assert.Equal(t, RetryPolicy{Attempts: DefaultAttempts, Backoff: DefaultBackoff}, NewRetryPolicy(0, -1, -1))
+	assert.Equal(t, RetryPolicy{Attempts: 5, Backoff: time.Second, MaxDuration: time.Minute},
+		NewRetryPolicy(5, time.Second, time.Minute))
+}
+
+func TestRetry(t *testing.T) {
+	testcases := []struct {
+		name          string
+		policy        RetryPolicy
+		failures      int
+		expectedCalls int
+		expectedWaits []time.Duration
+		expectedErr   string
+	}{
+		{
+			name:          "success",
+			policy:        NewRetryPolicy(3, time.Second, 0),
+			expectedCalls: 1,
+		},
+		{
+			name:          "transient failure",
+			policy:        NewRetryPolicy(3, time.Second, 0),
+			failures:      2,
+			expectedCalls: 3,
+			expectedWaits: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:          "attempts exhausted",
+			policy:        NewRetryPolicy(2, time.Second, 0),
+			failures:      5,
+			expectedCalls: 2,
+			expectedWaits: []time.Duration{time.Second},
+			expectedErr:   "registry unavailable",
+		},
+		{
+			name:          "backoff capped",
+			policy:        NewRetryPolicy(4, 4*time.Minute, 0),
+			failures:      5,
+			expectedCalls: 4,
+			expectedWaits: []time.Duration{4 * time.Minute, 5 * time.Minute, 5 * time.Minute},
+			expectedErr:   "registry unavailable",
+		},
+		{
+			name:          "max duration",
+			policy:        NewRetryPolicy(5, time.Minute, 30*time.Second),
+			failures:      5,
+			expectedCalls: 1,
+			expectedErr:   "giving up after 1 attempts, the next one would exceed 30s: registry unavailable",
+		},
+	}
+
+	origSleep := sleep
+	defer func() { sleep = origSleep }()
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var waits, reported []time.Duration
+			sleep = func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+			calls := 0
+			err := tc.policy.Retry(context.Background(),
+				func(attempt int, err error, wait time.Duration) {
+					assert.Equal(t, calls, attempt)
+					reported = append(reported, wait)
+				},
+				func() error {
+					calls++
+					if calls <= tc.failures {
+						return errors.New("registry unavailable")
+					}
+					return nil
+				})
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+			assert.Equal(t, tc.expectedCalls, calls)
+			assert.Equal(t, tc.expectedWaits, waits)
+			assert.Equal(t, tc.expectedWaits, reported)
+		})
+	}
+
+	// A cancelled wait returns the error of the last attempt
+	ctx, cancel := context.WithCancel(context.Background())
+	sleep = func(context.Context, time.Duration) error {
+		cancel()
+		return context.Canceled
+	}
+	calls := 0
+	err := NewRetryPolicy(3, time.Second, 0).Retry(ctx, nil, func() error {
+		calls++
+		return errors.New("registry unavailable")
+	})
+	assert.EqualError(t, err, "registry unavailable")
+	assert.Equal(t, 1, calls)
+}
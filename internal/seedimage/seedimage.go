@@ -0,0 +1,181 @@
+// Package seedimage pulls and inspects the seed image in the container storage of the host.
+//
+// The operations still run the podman CLI, as the containers/image library is not a dependency of LCA yet. Its
+// output is only parsed here, so that the callers depend on the typed API and the library can replace the CLI
+// without changing them.
+package seedimage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedsbom"
+	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+// digestPattern is the grammar of the supported image digests
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Image is the metadata of an image in the container storage of the host
+type Image struct {
+	ID     string `json:"Id"`
+	Digest string `json:"Digest"`
+	// RepoDigests are the references by digest of the image, including the digest of the manifest list it was
+	// pulled from, if any
+	RepoDigests []string          `json:"RepoDigests"`
+	Labels      map[string]string `json:"Labels"`
+	// Size is the size of the image in the container storage
+	Size int64 `json:"Size"`
+	// Architecture is the architecture of the image from its config, e.g. amd64 or arm64
+	Architecture string `json:"Architecture"`
+	// Created is the time the image was built, from its config
+	Created time.Time `json:"Created"`
+}
+
+// HasDigest returns whether the image has the digest, either as the digest of its manifest or of the manifest list it
+// was pulled from
+func (i *Image) HasDigest(digest string) bool {
+	if i.Digest == digest {
+		return true
+	}
+	for _, repoDigest := range i.RepoDigests {
+		if strings.HasSuffix(repoDigest, "@"+digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// ManifestListDigest returns the digest of the manifest list the image was pulled from for the platform of the host,
+// or an empty string for an image pulled from its own manifest
+func (i *Image) ManifestListDigest() string {
+	for _, repoDigest := range i.RepoDigests {
+		if _, digest, found := strings.Cut(repoDigest, "@"); found && digest != i.Digest {
+			return digest
+		}
+	}
+	return ""
+}
+
+// ReferenceDigest returns the digest of an image referenced by digest, e.g. quay.io/org/seed@sha256:..., or an empty
+// string for an image referenced by tag
+func ReferenceDigest(image string) (string, error) {
+	_, digest, found := strings.Cut(image, "@")
+	if !found {
+		return "", nil
+	}
+	if !digestPattern.MatchString(digest) {
+		return "", fmt.Errorf("invalid digest %q of image %s, must be sha256 followed by 64 lowercase hex characters",
+			digest, image)
+	}
+	return digest, nil
+}
+
+// Pull pulls the image into the container storage of the host, authenticating with the given auth file, with the
+// given TLS arguments overriding the TLS configuration of its registry. The image of a manifest list is the one of the
+// given architecture, or of the host if empty
+func Pull(ctx context.Context, executor ops.Execute, image, authFile, arch string, tlsArgs []string) error {
+	args := []string{"pull", "--authfile", authFile}
+	if arch != "" {
+		args = append(args, "--arch", arch)
+	}
+	args = append(args, tlsArgs...)
+	if _, err := executor.ExecuteWithContext(ctx, "podman", append(args, image)...); err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+	return nil
+}
+
+// Rename names the image pulled as source after target instead, as if it were pulled as target
+func Rename(ctx context.Context, executor ops.Execute, source, target string) error {
+	if _, err := executor.ExecuteWithContext(ctx, "podman", "tag", source, target); err != nil {
+		return fmt.Errorf("failed to tag image %s as %s: %w", source, target, err)
+	}
+	if _, err := executor.ExecuteWithContext(ctx, "podman", "untag", target, source); err != nil {
+		return fmt.Errorf("failed to untag image %s: %w", source, err)
+	}
+	return nil
+}
+
+// FetchSBOM copies the SBOM artifact attached by the seed creation to the seed image of the given digest into dir,
+// with the dir transport, authenticating with the given auth file, with the given TLS arguments of skopeo copy
+// overriding the TLS configuration of its registry
+func FetchSBOM(ctx context.Context, executor ops.Execute, image, digest, authFile string, tlsArgs []string, dir string) error {
+	source := "docker://" + lcautils.ImageRepository(image) + ":" + seedsbom.ReferrerTag(digest)
+	args := append([]string{"copy", "--retry-times", "3", "--src-authfile", authFile}, tlsArgs...)
+	if _, err := executor.ExecuteWithContext(ctx, "skopeo", append(args, source, "dir:"+dir)...); err != nil {
+		return fmt.Errorf("failed to copy SBOM %s: %w", source, err)
+	}
+	return nil
+}
+
+// RemoteImage is the metadata of an image in its registry
+type RemoteImage struct {
+	Digest string            `json:"Digest"`
+	Labels map[string]string `json:"Labels"`
+	// Architecture is the architecture of the image from its config. The image of a manifest list is the one of the
+	// inspected platform, if any
+	Architecture string `json:"Architecture"`
+	// LayersData are the layers of the image of the inspected platform, with their compressed size
+	LayersData []struct {
+		Size int64 `json:"Size"`
+	} `json:"LayersData"`
+}
+
+// Size returns the compressed size of the layers of the image
+func (i *RemoteImage) Size() int64 {
+	var size int64
+	for _, layer := range i.LayersData {
+		size += layer.Size
+	}
+	return size
+}
+
+// InspectRemote returns the metadata of the image in its registry, without pulling it, authenticating with the given
+// auth file, with the given TLS arguments overriding the TLS configuration of its registry. The image of a manifest list
+// is the one of the given architecture, or of the host if empty
+func InspectRemote(ctx context.Context, executor ops.Execute, image, authFile, arch string, tlsArgs []string) (*RemoteImage, error) {
+	args := []string{"inspect", "--retry-times", "3", "--authfile", authFile}
+	if arch != "" {
+		args = append(args, "--override-os", "linux", "--override-arch", arch)
+	}
+	args = append(args, tlsArgs...)
+	output, err := executor.ExecuteWithContext(ctx, "skopeo", append(args, "docker://"+image)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image %s in its registry: %w", image, err)
+	}
+	remote := &RemoteImage{}
+	if err := json.Unmarshal([]byte(output), remote); err != nil {
+		return nil, fmt.Errorf("failed to parse image %s inspect output: %w", image, err)
+	}
+	return remote, nil
+}
+
+// Inspect returns the metadata of the image in the container storage of the host
+func Inspect(ctx context.Context, executor ops.Execute, image string) (*Image, error) {
+	output, err := executor.ExecuteWithContext(ctx, "podman", "inspect", "--format", "json", image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+	if output == "" {
+		return nil, fmt.Errorf("failed to inspect image %s: empty inspect output", image)
+	}
+	return parseInspect(output)
+}
+
+// parseInspect parses the podman inspect output of a single image
+func parseInspect(output string) (*Image, error) {
+	var images []Image
+	if err := json.Unmarshal([]byte(output), &images); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal image inspect output: %w", err)
+	}
+	if len(images) != 1 {
+		return nil, fmt.Errorf("expected 1 image inspect result, got %d", len(images))
+	}
+	return &images[0], nil
+}
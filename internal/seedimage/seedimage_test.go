@@ -0,0 +1,103 @@
+package seedimage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+func TestInspect(t *testing.T) {
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "inspect", "--format", "json", "quay.io/org/seed:4.15.0").
+		Return(`[{"Id":"abc","Digest":"sha256:1234","Architecture":"amd64","Created":"2024-03-01T12:00:00.123456789Z",`+
+			`"Labels":{"com.openshift.lifecycle-agent.seed_format_version":"3"}}]`, nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "inspect", "--format", "json", "quay.io/org/missing:4.15.0").
+		Return("Error: no such object", errors.New("exit status 125"))
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "inspect", "--format", "json", "quay.io/org/ambiguous:4.15.0").
+		Return(`[]`, nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "podman", "inspect", "--format", "json", "quay.io/org/empty:4.15.0").
+		Return("", nil)
+
+	image, err := Inspect(context.Background(), mockExec, "quay.io/org/seed:4.15.0")
+	assert.NoError(t, err)
+	assert.Equal(t, &Image{ID: "abc", Digest: "sha256:1234", Architecture: "amd64",
+		Created: time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC),
+		Labels:  map[string]string{"com.openshift.lifecycle-agent.seed_format_version": "3"}}, image)
+
+	_, err = Inspect(context.Background(), mockExec, "quay.io/org/missing:4.15.0")
+	assert.ErrorContains(t, err, "failed to inspect image")
+
+	_, err = Inspect(context.Background(), mockExec, "quay.io/org/ambiguous:4.15.0")
+	assert.ErrorContains(t, err, "expected 1 image inspect result, got 0")
+
+	_, err = Inspect(context.Background(), mockExec, "quay.io/org/empty:4.15.0")
+	assert.EqualError(t, err, "failed to inspect image quay.io/org/empty:4.15.0: empty inspect output")
+}
+
+func TestInspectRemote(t *testing.T) {
+	mockExec := ops.NewMockExecute(gomock.NewController(t))
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "skopeo", "inspect", "--retry-times", "3", "--authfile", "/auth.json",
+		"--override-os", "linux", "--override-arch", "arm64", "--tls-verify=false", "docker://quay.io/org/seed:4.15.0").
+		Return(`{"Digest":"sha256:1234","Architecture":"arm64","Labels":{"com.openshift.lifecycle-agent.seed_precache_size":"3000"},`+
+			`"LayersData":[{"Size":1000},{"Size":24}]}`, nil)
+	mockExec.EXPECT().ExecuteWithContext(gomock.Any(), "skopeo", "inspect", "--retry-times", "3", "--authfile", "/auth.json",
+		"docker://quay.io/org/missing:4.15.0").
+		Return("manifest unknown", errors.New("exit status 1"))
+
+	image, err := InspectRemote(context.Background(), mockExec, "quay.io/org/seed:4.15.0", "/auth.json", "arm64",
+		[]string{"--tls-verify=false"})
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:1234", image.Digest)
+	assert.Equal(t, "3000", image.Labels["com.openshift.lifecycle-agent.seed_precache_size"])
+	assert.Equal(t, int64(1024), image.Size())
+	assert.Equal(t, "arm64", image.Architecture)
+
+	_, err = InspectRemote(context.Background(), mockExec, "quay.io/org/missing:4.15.0", "/auth.json", "", nil)
+	assert.ErrorContains(t, err, "failed to inspect image quay.io/org/missing:4.15.0 in its registry")
+}
+
+func TestReferenceDigest(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	for _, tc := range []struct {
+		image          string
+		expectedDigest string
+		expectedError  string
+	}{
+		{image: "quay.io/org/seed:4.15.0"},
+		{image: "quay.io/org/seed@" + digest, expectedDigest: digest},
+		{image: "quay.io/org/seed:4.15.0@" + digest, expectedDigest: digest},
+		{image: "quay.io/org/seed@sha256:1234", expectedError: `invalid digest "sha256:1234"`},
+		{image: "quay.io/org/seed@sha512:" + strings.Repeat("ab", 64), expectedError: "invalid digest"},
+	} {
+		t.Run(tc.image, func(t *testing.T) {
+			digest, err := ReferenceDigest(tc.image)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedDigest, digest)
+		})
+	}
+}
+
+func TestHasDigest(t *testing.T) {
+	image := &Image{Digest: "sha256:1234", RepoDigests: []string{"quay.io/org/seed@sha256:1234", "quay.io/org/seed@sha256:5678"}}
+	assert.True(t, image.HasDigest("sha256:1234"))
+	assert.True(t, image.HasDigest("sha256:5678"))
+	assert.False(t, image.HasDigest("sha256:9abc"))
+}
+
+func TestManifestListDigest(t *testing.T) {
+	image := &Image{Digest: "sha256:1234", RepoDigests: []string{"quay.io/org/seed@sha256:1234", "quay.io/org/seed@sha256:5678"}}
+	assert.Equal(t, "sha256:5678", image.ManifestListDigest())
+	image = &Image{Digest: "sha256:1234", RepoDigests: []string{"quay.io/org/seed@sha256:1234"}}
+	assert.Equal(t, "", image.ManifestListDigest())
+}
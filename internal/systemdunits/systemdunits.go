@@ -0,0 +1,162 @@
+// Package systemdunits carries the systemd units and timers created by the admins of the node in /etc/systemd/system
+// to the new stateroot, whose /etc comes from the seed image, e.g. the site maintenance timers.
+package systemdunits
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cp "github.com/otiai10/copy"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/mcodrift"
+)
+
+const unitsDir = "/etc/systemd/system"
+
+var (
+	// unitSuffixes are the kinds of the units carried
+	unitSuffixes = []string{".service", ".timer"}
+	// lcaUnits are the units of the lifecycle agent, set up in the new stateroot by the agent itself
+	lcaUnits = []string{common.IBUInitMonitorService, common.SeedVarDataService, "installation-configuration.service"}
+)
+
+// Report lists the admin units carried to the new stateroot, and the ones skipped with the reason
+type Report struct {
+	Carried []string
+	Skipped []string
+}
+
+// ParseAllowlist returns the comma separated shell patterns of the names of the units to carry, e.g. "site-*.timer"
+func ParseAllowlist(value string) ([]string, error) {
+	var allowlist []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid systemd unit pattern %q: %w", pattern, err)
+		}
+		allowlist = append(allowlist, pattern)
+	}
+	return allowlist, nil
+}
+
+// Carry copies the admin units of root, the host filesystem, matching the allowlist to the deployment dir of the new
+// stateroot, with their drop-ins and the symlinks enabling them. The admin units are the service and timer unit files
+// of /etc/systemd/system that are neither laid down by the current MachineConfig of the node nor by the lifecycle
+// agent. A unit already in the new stateroot is not replaced.
+func Carry(root, deploymentDir string, allowlist []string) (*Report, error) {
+	units, err := adminUnits(root)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	for _, unit := range units {
+		if !allowed(unit, allowlist) {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s (not in the allowlist)", unit))
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(deploymentDir, unitsDir, unit)); err == nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s (already in the new stateroot)", unit))
+			continue
+		}
+		if err := carryUnit(root, deploymentDir, unit); err != nil {
+			return nil, err
+		}
+		report.Carried = append(report.Carried, unit)
+	}
+	return report, nil
+}
+
+// adminUnits returns the names of the admin units of root, sorted
+func adminUnits(root string) ([]string, error) {
+	managed, err := mcodrift.ManagedUnits(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the systemd units of the machine config: %w", err)
+	}
+	excluded := map[string]bool{}
+	for _, unit := range append(managed, lcaUnits...) {
+		excluded[unit] = true
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, unitsDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", unitsDir, err)
+	}
+	var units []string
+	for _, entry := range entries {
+		// The symlinks are units masked or linked from elsewhere, not unit files created in /etc
+		if !entry.Type().IsRegular() || excluded[entry.Name()] || !hasUnitSuffix(entry.Name()) {
+			continue
+		}
+		units = append(units, entry.Name())
+	}
+	sort.Strings(units)
+	return units, nil
+}
+
+func hasUnitSuffix(name string) bool {
+	for _, suffix := range unitSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func allowed(unit string, allowlist []string) bool {
+	for _, pattern := range allowlist {
+		if match, _ := filepath.Match(pattern, unit); match {
+			return true
+		}
+	}
+	return false
+}
+
+// carryUnit copies the unit file and its drop-ins, and recreates the symlinks of the .wants and .requires dirs
+// enabling it
+func carryUnit(root, deploymentDir, unit string) error {
+	src := filepath.Join(root, unitsDir, unit)
+	dst := filepath.Join(deploymentDir, unitsDir, unit)
+	if err := cp.Copy(src, dst); err != nil {
+		return fmt.Errorf("failed to copy systemd unit %s: %w", unit, err)
+	}
+	if _, err := os.Stat(src + ".d"); err == nil {
+		if err := cp.Copy(src+".d", dst+".d"); err != nil {
+			return fmt.Errorf("failed to copy the drop-ins of systemd unit %s: %w", unit, err)
+		}
+	}
+
+	var links []string
+	for _, dir := range []string{"*.wants", "*.requires"} {
+		matches, err := filepath.Glob(filepath.Join(root, unitsDir, dir, unit))
+		if err != nil {
+			return fmt.Errorf("failed to find the symlinks enabling systemd unit %s: %w", unit, err)
+		}
+		links = append(links, matches...)
+	}
+	for _, link := range links {
+		target, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, link)
+		if err != nil {
+			return fmt.Errorf("failed to get the path of %s: %w", link, err)
+		}
+		newLink := filepath.Join(deploymentDir, rel)
+		if err := os.MkdirAll(filepath.Dir(newLink), 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(newLink), err)
+		}
+		if err := os.Symlink(target, newLink); err != nil && !os.IsExist(err) {
+			return fmt.Errorf("failed to enable systemd unit %s: %w", unit, err)
+		}
+	}
+	return nil
+}
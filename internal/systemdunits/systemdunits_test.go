@@ -0,0 +1,93 @@
+package systemdunits
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/mcodrift"
+)
+
+func writeFile(t *testing.T, root, path, content string) {
+	hostPath := filepath.Join(root, path)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(hostPath), 0o755))
+	assert.NoError(t, os.WriteFile(hostPath, []byte(content), 0o644))
+}
+
+func symlink(t *testing.T, root, target, path string) {
+	hostPath := filepath.Join(root, path)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(hostPath), 0o755))
+	assert.NoError(t, os.Symlink(target, hostPath))
+}
+
+func TestParseAllowlist(t *testing.T) {
+	allowlist, err := ParseAllowlist(" site-*.timer, site-*.service,,")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"site-*.timer", "site-*.service"}, allowlist)
+
+	allowlist, err = ParseAllowlist("")
+	assert.NoError(t, err)
+	assert.Empty(t, allowlist)
+
+	_, err = ParseAllowlist("site-[.timer")
+	assert.ErrorContains(t, err, `invalid systemd unit pattern "site-[.timer"`)
+}
+
+func TestCarry(t *testing.T) {
+	raw, err := json.Marshal(map[string]any{
+		"ignition": map[string]any{"version": "3.2.0"},
+		"systemd":  map[string]any{"units": []any{map[string]any{"name": "kubelet.service", "contents": "[Unit]\n"}}},
+	})
+	assert.NoError(t, err)
+	mcJSON, err := json.Marshal(&mcfgv1.MachineConfig{Spec: mcfgv1.MachineConfigSpec{Config: runtime.RawExtension{Raw: raw}}})
+	assert.NoError(t, err)
+
+	root := t.TempDir()
+	writeFile(t, root, mcodrift.CurrentConfigFile, string(mcJSON))
+	writeFile(t, root, "/etc/systemd/system/kubelet.service", "[Unit]\n")
+	writeFile(t, root, "/etc/systemd/system/lca-init-monitor.service", "[Unit]\n")
+	writeFile(t, root, "/etc/systemd/system/site-cleanup.timer", "[Timer]\nOnCalendar=daily\n")
+	writeFile(t, root, "/etc/systemd/system/site-cleanup.service", "[Service]\nExecStart=/usr/local/bin/cleanup\n")
+	writeFile(t, root, "/etc/systemd/system/site-cleanup.service.d/10-env.conf", "[Service]\nEnvironment=DAYS=7\n")
+	writeFile(t, root, "/etc/systemd/system/site-logs.timer", "[Timer]\n")
+	writeFile(t, root, "/etc/systemd/system/debug.service", "[Service]\n")
+	writeFile(t, root, "/etc/systemd/system/notes.txt", "not a unit\n")
+	symlink(t, root, "/dev/null", "/etc/systemd/system/masked.service")
+	symlink(t, root, "/etc/systemd/system/site-cleanup.timer", "/etc/systemd/system/timers.target.wants/site-cleanup.timer")
+
+	deploymentDir := t.TempDir()
+	writeFile(t, deploymentDir, "/etc/systemd/system/site-logs.timer", "[Timer]\nseed\n")
+
+	report, err := Carry(root, deploymentDir, []string{"site-*"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"site-cleanup.service", "site-cleanup.timer"}, report.Carried)
+	assert.Equal(t, []string{"debug.service (not in the allowlist)", "site-logs.timer (already in the new stateroot)"},
+		report.Skipped)
+
+	content, err := os.ReadFile(filepath.Join(deploymentDir, "/etc/systemd/system/site-cleanup.timer"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[Timer]\nOnCalendar=daily\n", string(content))
+	content, err = os.ReadFile(filepath.Join(deploymentDir, "/etc/systemd/system/site-cleanup.service.d/10-env.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[Service]\nEnvironment=DAYS=7\n", string(content))
+	target, err := os.Readlink(filepath.Join(deploymentDir, "/etc/systemd/system/timers.target.wants/site-cleanup.timer"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/systemd/system/site-cleanup.timer", target)
+	content, err = os.ReadFile(filepath.Join(deploymentDir, "/etc/systemd/system/site-logs.timer"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[Timer]\nseed\n", string(content))
+
+	// Nothing is carried without allowlist
+	report, err = Carry(root, t.TempDir(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Carried)
+	assert.Len(t, report.Skipped, 4)
+
+	_, err = Carry(t.TempDir(), deploymentDir, nil)
+	assert.ErrorContains(t, err, "failed to get the systemd units of the machine config")
+}
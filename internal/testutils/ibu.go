@@ -0,0 +1,31 @@
+package testutils
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+)
+
+// Scheme returns a scheme with the core and the IBU types, for the fake clients
+func Scheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(s)
+	_ = lcav1alpha1.AddToScheme(s)
+	return s
+}
+
+// PrepFailedIBU returns an IBU whose Prep failed with the given error
+func PrepFailedIBU(err error) *lcav1alpha1.ImageBasedUpgrade {
+	ibu := &lcav1alpha1.ImageBasedUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: utils.IBUName},
+		Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+			Stage:        lcav1alpha1.Stages.Prep,
+			SeedImageRef: lcav1alpha1.SeedImageRef{Image: "quay.io/seed:4.15.0", Version: "4.15.0"},
+		},
+	}
+	utils.SetPrepStatusFailedWithError(ibu, err)
+	return ibu
+}
@@ -0,0 +1,308 @@
+// Package upgradesummary summarizes the last upgrade attempts, with the timings of each of their stages, in a
+// ConfigMap with a stable JSON schema consumable by dashboards and the hub.
+package upgradesummary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/bootmetrics"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+const (
+	// ConfigMapName is the ConfigMap in the LCA namespace holding the summary
+	ConfigMapName = "lca-upgrade-summary"
+	// SummaryKey is the ConfigMap key holding the summary JSON
+	SummaryKey = "summary.json"
+	// SchemaVersion is the version of the summary JSON schema, changed on incompatible changes only
+	SchemaVersion = "v1"
+	// MaxAttempts is the number of upgrade attempts kept in the summary, the oldest ones being dropped
+	MaxAttempts = 10
+)
+
+// Result is the result of an upgrade attempt or of one of its stages
+type Result string
+
+const (
+	ResultInProgress Result = "InProgress"
+	ResultCompleted  Result = "Completed"
+	ResultFailed     Result = "Failed"
+	ResultAborted    Result = "Aborted"
+	// ResultSucceeded is set on the attempts finalized after a completed Upgrade stage
+	ResultSucceeded Result = "Succeeded"
+	// ResultRolledBack is set on the attempts finalized after a completed Rollback stage
+	ResultRolledBack Result = "RolledBack"
+)
+
+// Summary is the content of the SummaryKey of the ConfigMap
+type Summary struct {
+	SchemaVersion string `json:"schemaVersion"`
+	// Attempts are the last upgrade attempts, the oldest first
+	Attempts []Attempt `json:"attempts"`
+}
+
+// Attempt is an upgrade attempt, from the start of its first stage until the IBU is back to Idle
+type Attempt struct {
+	SeedImage       string       `json:"seedImage"`
+	TargetVersion   string       `json:"targetVersion"`
+	Result          Result       `json:"result"`
+	StartedAt       metav1.Time  `json:"startedAt"`
+	CompletedAt     *metav1.Time `json:"completedAt,omitempty"`
+	DurationSeconds int64        `json:"durationSeconds,omitempty"`
+	// Phases are the stages of the attempt, in order
+	Phases []Phase `json:"phases"`
+	// BootMetrics are the metrics of the first boot after the pivot, for the attempts that pivoted
+	BootMetrics *bootmetrics.Metrics `json:"bootMetrics,omitempty"`
+}
+
+// Phase is a Prep, Upgrade or Rollback stage of an upgrade attempt
+type Phase struct {
+	Stage           lcav1alpha1.ImageBasedUpgradeStage `json:"stage"`
+	Result          Result                             `json:"result"`
+	Reason          string                             `json:"reason,omitempty"`
+	StartedAt       metav1.Time                        `json:"startedAt"`
+	CompletedAt     *metav1.Time                       `json:"completedAt,omitempty"`
+	DurationSeconds int64                              `json:"durationSeconds,omitempty"`
+}
+
+// summaryFile persists the summary on the host, copied to the stateroot booted by the pivot and the rollback so that
+// the attempts survive them. Overridden in tests.
+var summaryFile = common.PathOutsideChroot(utils.UpgradeSummaryFile)
+
+// bootMetricsFile holds the metrics of the first boot after the pivot, recorded by the Upgrade stage. Overridden in
+// tests.
+var bootMetricsFile = common.PathOutsideChroot(utils.BootMetricsFile)
+
+// Recorder records the upgrade attempts of the IBU in the summary ConfigMap
+type Recorder struct {
+	Client client.Client
+	Log    logr.Logger
+}
+
+// Record updates the summary with the current status of the IBU, and publishes it in the ConfigMap when it differs
+func (r *Recorder) Record(ctx context.Context, ibu *lcav1alpha1.ImageBasedUpgrade) error {
+	summary, err := load(summaryFile)
+	if err != nil {
+		return err
+	}
+
+	metrics, err := bootmetrics.Load(bootMetricsFile)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	attached := AttachBootMetrics(summary, metrics)
+	if Update(summary, ibu) || attached {
+		if err := persist(summary, summaryFile); err != nil {
+			return err
+		}
+	}
+
+	return r.publish(ctx, summary)
+}
+
+func (r *Recorder) publish(ctx context.Context, summary *Summary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal upgrade summary: %w", err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: ConfigMapName, Namespace: common.LcaNamespace}, cm); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get configmap %s: %w", ConfigMapName, err)
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: common.LcaNamespace},
+			Data:       map[string]string{SummaryKey: string(data)},
+		}
+		if err := r.Client.Create(ctx, cm); err != nil {
+			return fmt.Errorf("failed to create configmap %s: %w", ConfigMapName, err)
+		}
+		r.Log.Info("Created the upgrade summary", "configmap", ConfigMapName)
+		return nil
+	}
+
+	if cm.Data[SummaryKey] == string(data) {
+		return nil
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[SummaryKey] = string(data)
+	if err := r.Client.Update(ctx, cm); err != nil {
+		return fmt.Errorf("failed to update configmap %s: %w", ConfigMapName, err)
+	}
+	return nil
+}
+
+// Update records the current status of the IBU in the summary. It opens an attempt when a stage starts while the IBU
+// was Idle, records the start and end of each stage from its conditions, and closes the attempt once the IBU is back
+// to Idle. It returns whether the summary changed.
+func Update(summary *Summary, ibu *lcav1alpha1.ImageBasedUpgrade) bool {
+	original, _ := json.Marshal(summary)
+
+	attempt := currentAttempt(summary)
+	for _, stage := range []lcav1alpha1.ImageBasedUpgradeStage{
+		lcav1alpha1.Stages.Prep, lcav1alpha1.Stages.Upgrade, lcav1alpha1.Stages.Rollback} {
+		if !utils.IsStageInProgress(ibu, stage) {
+			continue
+		}
+		startedAt := utils.GetInProgressCondition(ibu, stage).LastTransitionTime
+		if attempt == nil {
+			summary.Attempts = append(summary.Attempts, Attempt{
+				SeedImage:     ibu.Spec.SeedImageRef.Image,
+				TargetVersion: ibu.Spec.SeedImageRef.Version,
+				Result:        ResultInProgress,
+				StartedAt:     startedAt,
+				Phases:        []Phase{},
+			})
+			attempt = &summary.Attempts[len(summary.Attempts)-1]
+		}
+		if openPhase(attempt, stage) == nil {
+			attempt.Phases = append(attempt.Phases, Phase{Stage: stage, Result: ResultInProgress, StartedAt: startedAt})
+		}
+	}
+
+	if attempt != nil {
+		for i := range attempt.Phases {
+			phase := &attempt.Phases[i]
+			if phase.Result == ResultInProgress && !utils.IsStageInProgress(ibu, phase.Stage) {
+				closePhase(phase, ibu)
+			}
+		}
+		if idle := utils.GetCompletedCondition(ibu, lcav1alpha1.Stages.Idle); idle != nil && idle.Status == metav1.ConditionTrue {
+			closeAttempt(attempt, idle.LastTransitionTime)
+		}
+	}
+
+	if len(summary.Attempts) > MaxAttempts {
+		summary.Attempts = summary.Attempts[len(summary.Attempts)-MaxAttempts:]
+	}
+
+	updated, _ := json.Marshal(summary)
+	return string(original) != string(updated)
+}
+
+// AttachBootMetrics attaches the metrics of the first boot after the pivot to the attempt in progress, if the boot
+// happened during the attempt and no metrics were attached yet. It returns whether the summary changed.
+func AttachBootMetrics(summary *Summary, metrics *bootmetrics.Metrics) bool {
+	attempt := currentAttempt(summary)
+	if metrics == nil || attempt == nil || attempt.BootMetrics != nil || metrics.BootTime.Before(&attempt.StartedAt) {
+		return false
+	}
+	attempt.BootMetrics = metrics
+	return true
+}
+
+// currentAttempt returns the attempt in progress, if any
+func currentAttempt(summary *Summary) *Attempt {
+	if len(summary.Attempts) == 0 {
+		return nil
+	}
+	attempt := &summary.Attempts[len(summary.Attempts)-1]
+	if attempt.Result != ResultInProgress {
+		return nil
+	}
+	return attempt
+}
+
+// openPhase returns the phase of the stage in progress in the attempt, if any
+func openPhase(attempt *Attempt, stage lcav1alpha1.ImageBasedUpgradeStage) *Phase {
+	for i := range attempt.Phases {
+		if attempt.Phases[i].Stage == stage && attempt.Phases[i].Result == ResultInProgress {
+			return &attempt.Phases[i]
+		}
+	}
+	return nil
+}
+
+// closePhase records the end of the stage from its completed condition. The stage was aborted when its conditions were
+// reset by the transition to Idle.
+func closePhase(phase *Phase, ibu *lcav1alpha1.ImageBasedUpgrade) {
+	completed := utils.GetCompletedCondition(ibu, phase.Stage)
+	var completedAt metav1.Time
+	switch {
+	case completed != nil && completed.Status == metav1.ConditionTrue:
+		phase.Result = ResultCompleted
+		completedAt = completed.LastTransitionTime
+	case completed != nil:
+		phase.Result = ResultFailed
+		completedAt = completed.LastTransitionTime
+		if inProgress := utils.GetInProgressCondition(ibu, phase.Stage); inProgress != nil {
+			phase.Reason = inProgress.Reason
+		}
+	default:
+		phase.Result = ResultAborted
+		completedAt = metav1.Now()
+		if idle := utils.GetCompletedCondition(ibu, lcav1alpha1.Stages.Idle); idle != nil {
+			completedAt = idle.LastTransitionTime
+		}
+	}
+	phase.CompletedAt = &completedAt
+	phase.DurationSeconds = duration(phase.StartedAt, completedAt)
+}
+
+// closeAttempt records the end of the attempt, with its result derived from the results of its stages
+func closeAttempt(attempt *Attempt, completedAt metav1.Time) {
+	attempt.Result = ResultAborted
+	for _, phase := range attempt.Phases {
+		switch {
+		case phase.Stage == lcav1alpha1.Stages.Rollback && phase.Result == ResultCompleted:
+			attempt.Result = ResultRolledBack
+		case phase.Stage == lcav1alpha1.Stages.Upgrade && phase.Result == ResultCompleted && attempt.Result != ResultRolledBack:
+			attempt.Result = ResultSucceeded
+		case phase.Result == ResultFailed && attempt.Result == ResultAborted:
+			attempt.Result = ResultFailed
+		}
+	}
+	attempt.CompletedAt = &completedAt
+	attempt.DurationSeconds = duration(attempt.StartedAt, completedAt)
+}
+
+func duration(startedAt, completedAt metav1.Time) int64 {
+	if completedAt.Before(&startedAt) {
+		return 0
+	}
+	return int64(completedAt.Sub(startedAt.Time).Seconds())
+}
+
+// load reads the persisted summary, returning an empty one when none was persisted
+func load(filename string) (*Summary, error) {
+	summary := &Summary{SchemaVersion: SchemaVersion, Attempts: []Attempt{}}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return summary, nil
+		}
+		return nil, fmt.Errorf("failed to read upgrade summary: %w", err)
+	}
+	if err := json.Unmarshal(data, summary); err != nil {
+		return nil, fmt.Errorf("failed to parse upgrade summary: %w", err)
+	}
+	summary.SchemaVersion = SchemaVersion
+	return summary, nil
+}
+
+func persist(summary *Summary, filename string) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal upgrade summary: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write upgrade summary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package upgradesummary
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+	"github.com/openshift-kni/lifecycle-agent/internal/bootmetrics"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+var start = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// setTransitionTime sets the last transition time of the conditions, minutes after start
+func setTransitionTime(ibu *lcav1alpha1.ImageBasedUpgrade, minutes int, conditionTypes ...utils.ConditionType) {
+	for _, conditionType := range conditionTypes {
+		if condition := meta.FindStatusCondition(ibu.Status.Conditions, string(conditionType)); condition != nil {
+			condition.LastTransitionTime = metav1.NewTime(start.Add(time.Duration(minutes) * time.Minute))
+		}
+	}
+}
+
+func newIBU() *lcav1alpha1.ImageBasedUpgrade {
+	ibu := &lcav1alpha1.ImageBasedUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: utils.IBUName},
+		Spec: lcav1alpha1.ImageBasedUpgradeSpec{
+			SeedImageRef: lcav1alpha1.SeedImageRef{Image: "quay.io/seed:4.16.0", Version: "4.16.0"},
+		},
+	}
+	utils.ResetStatusConditions(&ibu.Status.Conditions, ibu.Generation)
+	return ibu
+}
+
+func startStage(ibu *lcav1alpha1.ImageBasedUpgrade, stage lcav1alpha1.ImageBasedUpgradeStage, minutes int) {
+	ibu.Spec.Stage = stage
+	utils.SetStatusCondition(&ibu.Status.Conditions, utils.ConditionTypes.Idle, utils.ConditionReasons.InProgress,
+		metav1.ConditionFalse, "In progress", ibu.Generation)
+	utils.SetStatusCondition(&ibu.Status.Conditions, utils.GetInProgressConditionType(stage), utils.ConditionReasons.InProgress,
+		metav1.ConditionTrue, "In progress", ibu.Generation)
+	setTransitionTime(ibu, minutes, utils.GetInProgressConditionType(stage))
+}
+
+func resetToIdle(ibu *lcav1alpha1.ImageBasedUpgrade, minutes int) {
+	ibu.Spec.Stage = lcav1alpha1.Stages.Idle
+	utils.ResetStatusConditions(&ibu.Status.Conditions, ibu.Generation)
+	setTransitionTime(ibu, minutes, utils.ConditionTypes.Idle)
+}
+
+func TestUpdate(t *testing.T) {
+	summary := &Summary{SchemaVersion: SchemaVersion, Attempts: []Attempt{}}
+	ibu := newIBU()
+	assert.False(t, Update(summary, ibu))
+	assert.Empty(t, summary.Attempts)
+
+	// Prep then Upgrade, finalized
+	startStage(ibu, lcav1alpha1.Stages.Prep, 0)
+	assert.True(t, Update(summary, ibu))
+	assert.Len(t, summary.Attempts, 1)
+	assert.Equal(t, ResultInProgress, summary.Attempts[0].Result)
+	assert.False(t, Update(summary, ibu))
+
+	utils.SetPrepStatusCompleted(ibu, "Prep completed")
+	setTransitionTime(ibu, 30, utils.ConditionTypes.PrepInProgress, utils.ConditionTypes.PrepCompleted)
+	startStage(ibu, lcav1alpha1.Stages.Upgrade, 40)
+	assert.True(t, Update(summary, ibu))
+	utils.SetUpgradeStatusCompleted(ibu, "Upgrade completed")
+	setTransitionTime(ibu, 100, utils.ConditionTypes.UpgradeInProgress, utils.ConditionTypes.UpgradeCompleted)
+	assert.True(t, Update(summary, ibu))
+	resetToIdle(ibu, 110)
+	assert.True(t, Update(summary, ibu))
+
+	attempt := summary.Attempts[0]
+	assert.Equal(t, ResultSucceeded, attempt.Result)
+	assert.Equal(t, "quay.io/seed:4.16.0", attempt.SeedImage)
+	assert.Equal(t, int64(110*60), attempt.DurationSeconds)
+	assert.Len(t, attempt.Phases, 2)
+	assert.Equal(t, Phase{
+		Stage:           lcav1alpha1.Stages.Prep,
+		Result:          ResultCompleted,
+		StartedAt:       metav1.NewTime(start),
+		CompletedAt:     &metav1.Time{Time: start.Add(30 * time.Minute)},
+		DurationSeconds: 30 * 60,
+	}, attempt.Phases[0])
+	assert.Equal(t, lcav1alpha1.Stages.Upgrade, attempt.Phases[1].Stage)
+	assert.Equal(t, int64(60*60), attempt.Phases[1].DurationSeconds)
+
+	// Prep failed, then aborted
+	startStage(ibu, lcav1alpha1.Stages.Prep, 200)
+	assert.True(t, Update(summary, ibu))
+	utils.SetPrepStatusFailed(ibu, "no space left")
+	setTransitionTime(ibu, 210, utils.ConditionTypes.PrepInProgress, utils.ConditionTypes.PrepCompleted)
+	assert.True(t, Update(summary, ibu))
+	resetToIdle(ibu, 220)
+	assert.True(t, Update(summary, ibu))
+	assert.Len(t, summary.Attempts, 2)
+	assert.Equal(t, ResultFailed, summary.Attempts[1].Phases[0].Result)
+	assert.Equal(t, ResultFailed, summary.Attempts[1].Result)
+
+	// Prep aborted while in progress
+	startStage(ibu, lcav1alpha1.Stages.Prep, 250)
+	assert.True(t, Update(summary, ibu))
+	resetToIdle(ibu, 260)
+	assert.True(t, Update(summary, ibu))
+	assert.Len(t, summary.Attempts, 3)
+	assert.Equal(t, ResultAborted, summary.Attempts[2].Phases[0].Result)
+	assert.Equal(t, int64(10*60), summary.Attempts[2].Phases[0].DurationSeconds)
+	assert.Equal(t, ResultAborted, summary.Attempts[2].Result)
+
+	// Upgrade rolled back
+	startStage(ibu, lcav1alpha1.Stages.Prep, 300)
+	assert.True(t, Update(summary, ibu))
+	utils.SetPrepStatusCompleted(ibu, "Prep completed")
+	startStage(ibu, lcav1alpha1.Stages.Upgrade, 310)
+	assert.True(t, Update(summary, ibu))
+	utils.SetUpgradeStatusFailed(ibu, "Rollback requested")
+	startStage(ibu, lcav1alpha1.Stages.Rollback, 320)
+	assert.True(t, Update(summary, ibu))
+	utils.SetRollbackStatusCompleted(ibu)
+	assert.True(t, Update(summary, ibu))
+	resetToIdle(ibu, 400)
+	assert.True(t, Update(summary, ibu))
+	attempt = summary.Attempts[3]
+	assert.Equal(t, ResultRolledBack, attempt.Result)
+	assert.Len(t, attempt.Phases, 3)
+	assert.Equal(t, ResultFailed, attempt.Phases[1].Result)
+	assert.Equal(t, string(utils.ConditionReasons.Failed), attempt.Phases[1].Reason)
+	assert.Equal(t, ResultCompleted, attempt.Phases[2].Result)
+
+	// Only the last attempts are kept
+	for i := 0; i < MaxAttempts; i++ {
+		startStage(ibu, lcav1alpha1.Stages.Prep, 500+i*10)
+		Update(summary, ibu)
+		resetToIdle(ibu, 505+i*10)
+		Update(summary, ibu)
+	}
+	assert.Len(t, summary.Attempts, MaxAttempts)
+	assert.Equal(t, metav1.NewTime(start.Add(500*time.Minute)), summary.Attempts[0].StartedAt)
+}
+
+func TestRecord(t *testing.T) {
+	origSummaryFile, origBootMetricsFile := summaryFile, bootMetricsFile
+	defer func() {
+		summaryFile, bootMetricsFile = origSummaryFile, origBootMetricsFile
+	}()
+	summaryFile = filepath.Join(t.TempDir(), "upgrade_summary.json")
+	bootMetricsFile = filepath.Join(t.TempDir(), "boot_metrics.json")
+
+	s := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(s))
+	c := fake.NewClientBuilder().WithScheme(s).Build()
+	recorder := &Recorder{Client: c, Log: logr.Discard()}
+
+	ibu := newIBU()
+	startStage(ibu, lcav1alpha1.Stages.Prep, 0)
+	assert.NoError(t, recorder.Record(context.Background(), ibu))
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: ConfigMapName, Namespace: common.LcaNamespace}, cm))
+	summary := &Summary{}
+	assert.NoError(t, json.Unmarshal([]byte(cm.Data[SummaryKey]), summary))
+	assert.Equal(t, SchemaVersion, summary.SchemaVersion)
+	assert.Len(t, summary.Attempts, 1)
+
+	// The summary is restored from the file, e.g. after the pivot, and the ConfigMap updated
+	utils.SetPrepStatusCompleted(ibu, "Prep completed")
+	recorder.Client = fake.NewClientBuilder().WithScheme(s).Build()
+	assert.NoError(t, recorder.Record(context.Background(), ibu))
+	assert.NoError(t, recorder.Client.Get(context.Background(), types.NamespacedName{Name: ConfigMapName, Namespace: common.LcaNamespace}, cm))
+	assert.NoError(t, json.Unmarshal([]byte(cm.Data[SummaryKey]), summary))
+	assert.Len(t, summary.Attempts, 1)
+	assert.Equal(t, ResultCompleted, summary.Attempts[0].Phases[0].Result)
+	assert.Nil(t, summary.Attempts[0].BootMetrics)
+
+	// The metrics of the first boot after the pivot are attached to the attempt
+	metrics := &bootmetrics.Metrics{BootTime: metav1.NewTime(start.Add(30 * time.Minute)), TimeToAPISeconds: 240}
+	assert.NoError(t, metrics.Persist(bootMetricsFile))
+	assert.NoError(t, recorder.Record(context.Background(), ibu))
+	assert.NoError(t, recorder.Client.Get(context.Background(), types.NamespacedName{Name: ConfigMapName, Namespace: common.LcaNamespace}, cm))
+	assert.NoError(t, json.Unmarshal([]byte(cm.Data[SummaryKey]), summary))
+	assert.Equal(t, int64(240), summary.Attempts[0].BootMetrics.TimeToAPISeconds)
+}
+
+func TestAttachBootMetrics(t *testing.T) {
+	summary := &Summary{Attempts: []Attempt{{Result: ResultInProgress, StartedAt: metav1.NewTime(start)}}}
+	assert.False(t, AttachBootMetrics(summary, nil))
+
+	// A boot before the attempt is not the first boot after its pivot
+	assert.False(t, AttachBootMetrics(summary, &bootmetrics.Metrics{BootTime: metav1.NewTime(start.Add(-time.Hour))}))
+
+	metrics := &bootmetrics.Metrics{BootTime: metav1.NewTime(start.Add(time.Hour))}
+	assert.True(t, AttachBootMetrics(summary, metrics))
+	assert.Equal(t, metrics, summary.Attempts[0].BootMetrics)
+	assert.False(t, AttachBootMetrics(summary, &bootmetrics.Metrics{BootTime: metav1.NewTime(start.Add(2 * time.Hour))}))
+
+	summary.Attempts[0].Result = ResultSucceeded
+	summary.Attempts[0].BootMetrics = nil
+	assert.False(t, AttachBootMetrics(summary, metrics))
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	v1 "github.com/openshift/api/config/v1"
@@ -34,6 +35,7 @@ import (
 	ostree "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedcreator"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedrestoration"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedsbom"
 )
 
 var (
@@ -50,6 +52,9 @@ var (
 	recertSkipValidation bool
 
 	skipCleanup bool
+
+	// sbomFormat is the format of the SBOM attached to the seed image, none if empty
+	sbomFormat string
 )
 
 func init() {
@@ -78,6 +83,8 @@ func init() {
 
 	// Add flags to create command
 	addCommonFlags(createCmd)
+	createCmd.Flags().StringVarP(&sbomFormat, "sbom-format", "", "",
+		"Attach an SBOM of the seed packages and images to the seed image, in spdx or cyclonedx format.")
 }
 
 func create() error {
@@ -85,6 +92,12 @@ func create() error {
 	var err error
 	log.Info("OCI image creation has started")
 
+	if sbomFormat != "" {
+		if sbomFormat, err = seedsbom.ParseFormat(sbomFormat); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
 	hostCommandsExecutor := ops.NewNsenterExecutor(log, true)
 	op := ops.NewOps(log, hostCommandsExecutor)
 	rpmOstreeClient := ostree.NewClient("lca-cli", hostCommandsExecutor)
@@ -92,7 +105,7 @@ func create() error {
 	if !skipCleanup {
 		defer func() {
 			if err = seedrestoration.NewSeedRestoration(log, op, common.BackupDir, containerRegistry,
-				authFile, recertContainerImage, recertSkipValidation).CleanupSeedCluster(); err != nil {
+				authFile, recertContainerImage, recertSkipValidation).CleanupSeedCluster(context.Background()); err != nil {
 				log.Fatalf("Failed to restore seed cluster: %v", err)
 			}
 			log.Info("Seed cluster restored successfully!")
@@ -109,8 +122,8 @@ func create() error {
 		return fmt.Errorf("failed to create runtime client: %w", err)
 	}
 
-	seedCreator := seedcreator.NewSeedCreator(client, log, op, rpmOstreeClient, common.BackupDir, common.KubeconfigFile,
-		containerRegistry, authFile, recertContainerImage, recertSkipValidation)
+	seedCreator := seedcreator.NewSeedCreator(client, log, op, hostCommandsExecutor, rpmOstreeClient, common.BackupDir, common.KubeconfigFile,
+		containerRegistry, authFile, recertContainerImage, recertSkipValidation, sbomFormat)
 	if err = seedCreator.CreateSeedImage(); err != nil {
 		err = fmt.Errorf("failed to create seed image: %w", err)
 		log.Errorf(err.Error())
@@ -0,0 +1,91 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/seedimage"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+)
+
+var (
+	// staterootMaxAgeDays is the age in days from which the unused stateroots are deleted, 0 to keep them
+	staterootMaxAgeDays int
+	// pruneSeedImages removes the seed images from the container storage
+	pruneSeedImages bool
+	// keepSeedImages are the seed images not to remove
+	keepSeedImages []string
+)
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove the seed images and the old stateroots left by previous upgrades.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := gc(); err != nil {
+			log.Fatalf("Error executing gc command: %v", err)
+		}
+	},
+}
+
+func init() {
+
+	// Add gc command
+	rootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().IntVarP(&staterootMaxAgeDays, "stateroot-max-age-days", "", 0,
+		"Delete the stateroots not booted, staged nor the default deployment, unmodified for this number of days. 0 keeps them.")
+	gcCmd.Flags().BoolVarP(&pruneSeedImages, "seed-images", "", false, "Remove the seed images from the container storage.")
+	gcCmd.Flags().StringSliceVarP(&keepSeedImages, "keep-seed-image", "", nil,
+		"A seed image not to remove with --seed-images, e.g. the seed image of an ImageBasedUpgrade to retry. Can be repeated.")
+}
+
+func gc() error {
+	if staterootMaxAgeDays < 0 {
+		return errors.New("--stateroot-max-age-days must not be negative")
+	}
+	if staterootMaxAgeDays == 0 && !pruneSeedImages {
+		return errors.New("nothing to remove, set --stateroot-max-age-days or --seed-images")
+	}
+
+	var errs []error
+	if pruneSeedImages {
+		removed, err := seedimage.Prune(context.Background(), ops.NewNsenterExecutor(log, verbose), keepSeedImages...)
+		for _, image := range removed {
+			log.Infof("Seed image %s removed", image)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if staterootMaxAgeDays > 0 {
+		deleted, err := newStaterootManager().DeleteOlderThan(context.Background(), time.Duration(staterootMaxAgeDays)*24*time.Hour)
+		for _, stateroot := range deleted {
+			log.Infof("Stateroot %s deleted", stateroot)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
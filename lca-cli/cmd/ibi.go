@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"github.com/spf13/cobra"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
@@ -62,11 +63,11 @@ func runIBI() {
 	log.Info("IBI preparation process has started")
 	hostCommandsExecutor := ops.NewChrootExecutor(log, true, common.Host)
 	rpmOstreeClient := ostree.NewClient("lca-cli", hostCommandsExecutor)
-	ostreeClient := ostreeclient.NewClient(hostCommandsExecutor, true)
+	ostreeClient := ostreeclient.NewClient(hostCommandsExecutor, true, common.CurrentStaterootLayout())
 
 	ibiRunner := ibipreparation.NewIBIPrepare(log, ops.NewOps(log, hostCommandsExecutor), rpmOstreeClient, ostreeClient,
 		seedImage, authFile, pullSecretFile, seedVersion, precacheBestEffort, precacheDisabled)
-	if err := ibiRunner.Run(); err != nil {
+	if err := ibiRunner.Run(context.Background()); err != nil {
 		log.Fatal(err)
 	}
 
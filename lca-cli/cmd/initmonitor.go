@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/initmonitor"
@@ -54,12 +55,12 @@ func initMonitor() error {
 
 	initMonitorRunner := initmonitor.NewInitMonitor(scheme, log, hostCommandsExecutor, ops.NewOps(log, hostCommandsExecutor), monitorSvcUnitComponentTag)
 	if launchMonitor {
-		if err := initMonitorRunner.RunInitMonitor(); err != nil {
+		if err := initMonitorRunner.RunInitMonitor(context.Background()); err != nil {
 			return fmt.Errorf("failed to run init monitor: %w", err)
 		}
 		return nil
 	} else if monitorSvcUnitComponentTag != "" {
-		if err := initMonitorRunner.RunExitStopPostCheck(); err != nil {
+		if err := initMonitorRunner.RunExitStopPostCheck(context.Background()); err != nil {
 			return fmt.Errorf("failed to run exit stop post check: %w", err)
 		}
 		return nil
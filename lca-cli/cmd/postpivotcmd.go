@@ -19,6 +19,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
 	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
@@ -40,36 +41,79 @@ var postPivotCmd = &cobra.Command{
 	},
 }
 
-var inContainer bool
+// postPivotRerunCmd represents the post-pivot rerun command
+var postPivotRerunCmd = &cobra.Command{
+	Use:   "rerun",
+	Short: "Rerun post pivot steps on a booted upgraded system",
+	Long: fmt.Sprintf("Rerun a post pivot step, or all the steps that can be rerun if none is given, to recover from a "+
+		"partial post pivot failure without a rollback. Valid steps are: %s", strings.Join(postpivot.RerunSteps, ", ")),
+	Run: func(cmd *cobra.Command, args []string) {
+		postPivotRerun()
+	},
+}
+
+var (
+	inContainer bool
+	rerunStep   string
+)
 
 func init() {
 
 	// Add create command
 	rootCmd.AddCommand(postPivotCmd)
 
-	postPivotCmd.Flags().BoolVarP(&inContainer, "in-container", "", false, "Use this flag if this command is being ran inside a container")
+	postPivotCmd.PersistentFlags().BoolVarP(&inContainer, "in-container", "", false, "Use this flag if this command is being ran inside a container")
+
+	// Add rerun subcommand
+	postPivotCmd.AddCommand(postPivotRerunCmd)
+
+	postPivotRerunCmd.Flags().StringVarP(&rerunStep, "step", "", "",
+		fmt.Sprintf("The post pivot step to rerun, one of: %s. All of them if not set", strings.Join(postpivot.RerunSteps, ", ")))
 }
 
-func postPivot() {
-	log.Info("Post pivot operation has started")
-	var hostCommandsExecutor ops.Execute
+func newPostPivotExecutor() ops.Execute {
 	if inContainer {
-		hostCommandsExecutor = ops.NewNsenterExecutor(log, true)
-	} else {
-		hostCommandsExecutor = ops.NewRegularExecutor(log, true)
+		return ops.NewNsenterExecutor(log, true)
 	}
+	return ops.NewRegularExecutor(log, true)
+}
+
+func postPivot() {
+	closeLogOutputs := postpivot.SetupLogging(log, common.IBUPostPivotLoggingConfigFile)
+	defer closeLogOutputs()
+
+	log.Info("Post pivot operation has started")
+	hostCommandsExecutor := newPostPivotExecutor()
 	opsClient := ops.NewOps(log, hostCommandsExecutor)
 	rpmOstreeClient := rpmostreeclient.NewClient("initmonitor", hostCommandsExecutor)
-	ostreeClient := ostreeclient.NewClient(hostCommandsExecutor, false)
-	rebootClient := reboot.NewRebootClient(&logr.Logger{}, hostCommandsExecutor, rpmOstreeClient, ostreeClient, opsClient)
+	ostreeClient := ostreeclient.NewClient(hostCommandsExecutor, false, common.CurrentStaterootLayout())
+	rebootClient := reboot.NewRebootClient(&logr.Logger{}, hostCommandsExecutor, rpmOstreeClient, ostreeClient, opsClient, common.CurrentStaterootLayout())
 
+	ctx := context.TODO()
 	postPivotRunner := postpivot.NewPostPivot(scheme, log, opsClient,
 		common.ImageRegistryAuthFile, common.OptOpenshift, common.KubeconfigFile)
-	if err := postPivotRunner.PostPivotConfiguration(context.TODO()); err != nil {
+	if err := postPivotRunner.PostPivotConfiguration(ctx); err != nil {
 		log.Error(err)
-		rebootClient.AutoRollbackIfEnabled(reboot.PostPivotComponent, fmt.Sprintf("Rollback due to postpivot failure: %s", err))
+		rebootClient.AutoRollbackIfEnabled(ctx, reboot.PostPivotComponent, fmt.Sprintf("Rollback due to postpivot failure: %s", err))
 		log.Fatal("Post pivot operation failed")
 	}
 
 	log.Info("Post pivot operation finished successfully!")
 }
+
+// postPivotRerun replays post pivot steps on behalf of the user recovering the upgraded system, so unlike the post
+// pivot configuration a failure does not trigger an automatic rollback
+func postPivotRerun() {
+	closeLogOutputs := postpivot.SetupLogging(log, common.IBUPostPivotLoggingConfigFile)
+	defer closeLogOutputs()
+
+	log.Info("Post pivot rerun has started")
+	opsClient := ops.NewOps(log, newPostPivotExecutor())
+	postPivotRunner := postpivot.NewPostPivot(scheme, log, opsClient,
+		common.ImageRegistryAuthFile, common.OptOpenshift, common.KubeconfigFile)
+	if err := postPivotRunner.Rerun(context.TODO(), rerunStep); err != nil {
+		log.Fatalf("Post pivot rerun failed: %v", err)
+	}
+
+	log.Info("Post pivot rerun finished successfully!")
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	ostree "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/stateroots"
+)
+
+// staterootCmd represents the stateroot command
+var staterootCmd = &cobra.Command{
+	Use:   "stateroot",
+	Short: "Manage the ostree stateroots of the node.",
+}
+
+// staterootListCmd represents the stateroot list command
+var staterootListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the stateroots with their size, deployment state and origin seed image.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listStateroots(); err != nil {
+			log.Fatalf("Error executing stateroot list command: %v", err)
+		}
+	},
+}
+
+// staterootDeleteCmd represents the stateroot delete command
+var staterootDeleteCmd = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "Delete a stateroot that is not booted, staged nor the default deployment.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := deleteStateroot(args[0]); err != nil {
+			log.Fatalf("Error executing stateroot delete command: %v", err)
+		}
+	},
+}
+
+func init() {
+
+	// Add stateroot commands
+	rootCmd.AddCommand(staterootCmd)
+	staterootCmd.AddCommand(staterootListCmd)
+	staterootCmd.AddCommand(staterootDeleteCmd)
+}
+
+func newStaterootManager() *stateroots.Manager {
+	hostCommandsExecutor := ops.NewNsenterExecutor(log, verbose)
+	return stateroots.NewManager(log, ops.NewOps(log, hostCommandsExecutor),
+		ostree.NewClient("lca-cli", hostCommandsExecutor),
+		ostreeclient.NewClient(hostCommandsExecutor, false, common.CurrentStaterootLayout()),
+		common.CurrentStaterootLayout())
+}
+
+func listStateroots() error {
+	list, err := newStaterootManager().List(context.Background())
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tDEPLOYMENTS\tSTATE\tSEED IMAGE")
+	for _, stateroot := range list {
+		var state []string
+		if stateroot.Booted {
+			state = append(state, "booted")
+		}
+		if stateroot.Staged {
+			state = append(state, "staged")
+		}
+		if stateroot.Default {
+			state = append(state, "default")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", stateroot.Name, formatSize(stateroot.Size), len(stateroot.Deployments),
+			valueOrNone(strings.Join(state, ",")), valueOrNone(stateroot.SeedImage))
+	}
+	return w.Flush() //nolint:wrapcheck
+}
+
+func deleteStateroot(name string) error {
+	if err := newStaterootManager().Delete(context.Background(), name); err != nil {
+		return err //nolint:wrapcheck
+	}
+	log.Infof("Stateroot %s deleted successfully!", name)
+	return nil
+}
+
+// formatSize returns the size in bytes with a binary unit, e.g. 1.5GiB
+func formatSize(size int64) string {
+	if size < 0 {
+		return "unknown"
+	}
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", value, "KMGTP"[exp])
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedprovenance"
+)
+
+// provenanceManifest is the path to the provenance manifest recorded when the seed image was created
+var provenanceManifest string
+
+// verifySeedCmd represents the verify-seed command
+var verifySeedCmd = &cobra.Command{
+	Use:   "verify-seed",
+	Short: "Verify a seed image corresponds to the source cluster recorded in its provenance manifest.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := verifySeed(); err != nil {
+			log.Fatalf("Error executing verify-seed command: %v", err)
+		}
+	},
+}
+
+func init() {
+
+	// Add verify-seed command
+	rootCmd.AddCommand(verifySeedCmd)
+
+	verifySeedCmd.Flags().StringVarP(&seedImage, "seed-image", "s", "", "Seed image to verify.")
+	verifySeedCmd.Flags().StringVarP(&provenanceManifest, "manifest", "m", "", "The path to the seed provenance manifest recorded on the source cluster.")
+	verifySeedCmd.Flags().StringVarP(&authFile, "authfile", "a", "", "The path to the authentication file of the container registry of seed image.")
+	verifySeedCmd.MarkFlagRequired("seed-image")
+	verifySeedCmd.MarkFlagRequired("manifest")
+}
+
+func verifySeed() error {
+	expected, err := seedprovenance.ReadManifest(provenanceManifest)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	executor := ops.NewRegularExecutor(log, verbose)
+
+	log.Infof("Pulling seed image %s", seedImage)
+	pullArgs := []string{"pull", seedImage}
+	if authFile != "" {
+		pullArgs = append(pullArgs, "--authfile", authFile)
+	}
+	if _, err := executor.Execute("podman", pullArgs...); err != nil {
+		return fmt.Errorf("failed to pull seed image: %w", err)
+	}
+
+	mountpoint, err := executor.Execute("podman", "image", "mount", seedImage)
+	if err != nil {
+		return fmt.Errorf("failed to mount seed image: %w", err)
+	}
+	defer func() {
+		if _, err := executor.Execute("podman", "image", "unmount", seedImage); err != nil {
+			log.Warnf("failed to unmount seed image: %v", err)
+		}
+	}()
+
+	log.Info("Verifying seed image content against the provenance manifest")
+	if err := seedprovenance.Verify(expected, mountpoint); err != nil {
+		return fmt.Errorf("seed image %s does not match the provenance manifest: %w", seedImage, err)
+	}
+
+	log.Infof("Seed image %s matches the provenance manifest: ostree commit %s, image list digest %s, config digest %s",
+		seedImage, expected.OstreeCommit, expected.ImageListDigest, expected.ConfigDigest)
+	return nil
+}
@@ -1,6 +1,7 @@
 package ibi_preparation
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -50,10 +51,10 @@ func NewIBIPrepare(log *logrus.Logger, ops ops.Ops, rpmostreeClient rpmostreecli
 	}
 }
 
-func (i *IBIPrepare) Run() error {
+func (i *IBIPrepare) Run(ctx context.Context) error {
 	// Pull seed image
 	i.log.Info("Pulling seed image")
-	if _, err := i.ops.RunInHostNamespace("podman", "pull", "--authfile", i.authFile, i.seedImage); err != nil {
+	if _, err := i.ops.RunInHostNamespace(ctx, "podman", "pull", "--authfile", i.authFile, i.seedImage); err != nil {
 		return fmt.Errorf("failed to pull image: %w", err)
 	}
 
@@ -61,8 +62,9 @@ func (i *IBIPrepare) Run() error {
 	log := logr.Logger{}
 	common.OstreeDeployPathPrefix = "/mnt/"
 	// Setup state root
-	if err := prep.SetupStateroot(log, i.ops, i.ostreeClient, i.rpmostreeClient,
-		i.seedImage, i.seedExpectedVersion, imageListFile, true); err != nil {
+	layout := common.CurrentStaterootLayout()
+	if err := prep.SetupStateroot(ctx, log, i.ops, i.ostreeClient, i.rpmostreeClient, layout, layout.Name(i.seedExpectedVersion, ""),
+		i.seedImage, i.seedExpectedVersion, imageListFile, true, false); err != nil {
 		return fmt.Errorf("failed to setup stateroot: %w", err)
 	}
 
@@ -75,7 +77,7 @@ func (i *IBIPrepare) Run() error {
 
 func (i *IBIPrepare) precacheFlow(imageListFile string) error {
 	// TODO: add support for mirror registry
-	imageList, err := prep.ReadPrecachingList(imageListFile, "", "", false)
+	imageList, err := prep.ReadPrecachingList(imageListFile, "", "", false, nil)
 	if err != nil {
 		err = fmt.Errorf("failed to read pre-caching image file: %s, %w", common.PathOutsideChroot(imageListFile), err)
 		return err
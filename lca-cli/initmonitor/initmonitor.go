@@ -1,6 +1,7 @@
 package initmonitor
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
 	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
 	"github.com/openshift-kni/lifecycle-agent/internal/reboot"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
@@ -29,8 +31,8 @@ type InitMonitor struct {
 
 func NewInitMonitor(scheme *runtime.Scheme, log *logrus.Logger, hostCommandsExecutor ops.Execute, ops ops.Ops, component string) *InitMonitor {
 	rpmOstreeClient := rpmostreeclient.NewClient("initmonitor", hostCommandsExecutor)
-	ostreeClient := ostreeclient.NewClient(hostCommandsExecutor, false)
-	rebootClient := reboot.NewRebootClient(&logr.Logger{}, hostCommandsExecutor, rpmOstreeClient, ostreeClient, ops)
+	ostreeClient := ostreeclient.NewClient(hostCommandsExecutor, false, common.CurrentStaterootLayout())
+	rebootClient := reboot.NewRebootClient(&logr.Logger{}, hostCommandsExecutor, rpmOstreeClient, ostreeClient, ops, common.CurrentStaterootLayout())
 	return &InitMonitor{
 		scheme:               scheme,
 		log:                  log,
@@ -43,7 +45,7 @@ func NewInitMonitor(scheme *runtime.Scheme, log *logrus.Logger, hostCommandsExec
 	}
 }
 
-func (m *InitMonitor) RunInitMonitor() error {
+func (m *InitMonitor) RunInitMonitor(ctx context.Context) error {
 	rollbackCfg, err := m.rebootClient.ReadIBUAutoRollbackConfigFile()
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -75,7 +77,7 @@ func (m *InitMonitor) RunInitMonitor() error {
 	msg := fmt.Sprintf("Rollback due to LCA Init Monitor timeout, after %s", timeout)
 	m.log.Info(msg)
 
-	if err := m.rebootClient.InitiateRollback(msg); err != nil {
+	if err := m.rebootClient.InitiateRollback(ctx, msg); err != nil {
 		return fmt.Errorf("unable to auto rollback: %w", err)
 	}
 
@@ -116,12 +118,12 @@ func (m *InitMonitor) checkSvcUnitRollbackNeeded() bool {
 	return true
 }
 
-func (m *InitMonitor) RunExitStopPostCheck() error {
+func (m *InitMonitor) RunExitStopPostCheck(ctx context.Context) error {
 	if m.checkSvcUnitRollbackNeeded() {
 		msg := fmt.Sprintf("Rollback due to service-unit failure: component %s", m.component)
 		m.log.Info(msg)
 
-		if err := m.rebootClient.InitiateRollback(msg); err != nil {
+		if err := m.rebootClient.InitiateRollback(ctx, msg); err != nil {
 			return fmt.Errorf("unable to auto rollback: %w", err)
 		}
 	}
@@ -0,0 +1,133 @@
+// Package nodekubeconfig refreshes the node-local admin kubeconfigs after the upgrade, so that a recovery SSH
+// session on the node has working credentials right away.
+package nodekubeconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// LocalhostKubeconfig reaches the API on the node loopback
+	LocalhostKubeconfig = "localhost.kubeconfig"
+	// LBExtKubeconfig reaches the API through its external name
+	LBExtKubeconfig = "lb-ext.kubeconfig"
+
+	// The bundle of the CAs signing every serving certificate of the API, maintained by the kube-apiserver operator
+	serverCAConfigMapName      = "kube-apiserver-server-ca"
+	serverCAConfigMapNamespace = "openshift-config-managed"
+	serverCAConfigMapKey       = "ca-bundle.crt"
+
+	verifyTimeout = 30 * time.Second
+)
+
+// Kubeconfigs are the node-local admin kubeconfigs refreshed after the upgrade
+var Kubeconfigs = []string{LocalhostKubeconfig, LBExtKubeconfig}
+
+// Verify checks that the kubeconfig authenticates against the API, listing namespaces requires an authenticated user
+var Verify = func(ctx context.Context, config *rest.Config) error {
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %w", err)
+	}
+	if _, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+		return fmt.Errorf("failed to authenticate: %w", err)
+	}
+	return nil
+}
+
+// Refresh patches the node-local admin kubeconfigs of kubeconfigDir with the current API serving CA bundle and,
+// when set in servers, with the API server URL. Each patched kubeconfig that authenticates replaces the original
+// one, and a copy of it is saved in validatedDir. A kubeconfig that does not authenticate is left untouched.
+func Refresh(ctx context.Context, c client.Reader, kubeconfigDir, validatedDir string, servers map[string]string) error {
+	caBundle, err := serverCABundle(ctx, c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(validatedDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", validatedDir, err)
+	}
+
+	var errs []error
+	for _, name := range Kubeconfigs {
+		if err := refresh(ctx, filepath.Join(kubeconfigDir, name), filepath.Join(validatedDir, name), caBundle, servers[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func refresh(ctx context.Context, kubeconfigPath, validatedPath string, caBundle []byte, server string) error {
+	original, err := os.ReadFile(kubeconfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	config, err := clientcmd.Load(original)
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	Patch(config, caBundle, server)
+
+	restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return fmt.Errorf("failed to build client config: %w", err)
+	}
+	if err := Verify(ctx, restConfig); err != nil {
+		return err
+	}
+
+	patched, err := clientcmd.Write(*config)
+	if err != nil {
+		return fmt.Errorf("failed to serialize kubeconfig: %w", err)
+	}
+	if !bytes.Equal(original, patched) {
+		if err := os.WriteFile(kubeconfigPath, patched, 0o600); err != nil {
+			return fmt.Errorf("failed to write kubeconfig: %w", err)
+		}
+	}
+	if err := os.WriteFile(validatedPath, patched, 0o600); err != nil {
+		return fmt.Errorf("failed to write validated kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// Patch sets the CA bundle and, if not empty, the server URL of every cluster of the kubeconfig
+func Patch(config *clientcmdapi.Config, caBundle []byte, server string) {
+	for _, cluster := range config.Clusters {
+		cluster.CertificateAuthority = ""
+		cluster.CertificateAuthorityData = caBundle
+		if server != "" {
+			cluster.Server = server
+		}
+	}
+}
+
+func serverCABundle(ctx context.Context, c client.Reader) ([]byte, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Name: serverCAConfigMapName, Namespace: serverCAConfigMapNamespace}, cm); err != nil {
+		return nil, fmt.Errorf("failed to get the API serving CA bundle: %w", err)
+	}
+	caBundle, ok := cm.Data[serverCAConfigMapKey]
+	if !ok || caBundle == "" {
+		return nil, fmt.Errorf("%s/%s has no %s", serverCAConfigMapNamespace, serverCAConfigMapName, serverCAConfigMapKey)
+	}
+	return []byte(caBundle), nil
+}
@@ -0,0 +1,71 @@
+package nodekubeconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func writeKubeconfig(t *testing.T, path, server string) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: server, CertificateAuthorityData: []byte("old-ca")}
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["admin"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "admin"}
+	config.CurrentContext = "admin"
+	assert.NoError(t, clientcmd.WriteToFile(*config, path))
+}
+
+func TestRefresh(t *testing.T) {
+	kubeconfigDir := t.TempDir()
+	validatedDir := filepath.Join(t.TempDir(), "kubeconfigs")
+	writeKubeconfig(t, filepath.Join(kubeconfigDir, LocalhostKubeconfig), "https://localhost:6443")
+	writeKubeconfig(t, filepath.Join(kubeconfigDir, LBExtKubeconfig), "https://api.seed.example.com:6443")
+
+	oldVerify := Verify
+	defer func() { Verify = oldVerify }()
+	Verify = func(ctx context.Context, config *rest.Config) error {
+		if config.Host == "https://localhost:6443" {
+			return errors.New("unauthorized")
+		}
+		return nil
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: serverCAConfigMapName, Namespace: serverCAConfigMapNamespace},
+		Data:       map[string]string{serverCAConfigMapKey: "new-ca"},
+	}
+	c := fake.NewClientBuilder().WithObjects(cm).Build()
+
+	err := Refresh(context.Background(), c, kubeconfigDir, validatedDir,
+		map[string]string{LBExtKubeconfig: "https://api.sno.example.com:6443"})
+	assert.ErrorContains(t, err, "localhost.kubeconfig: unauthorized")
+
+	// The kubeconfig that authenticates is patched and saved
+	for _, path := range []string{filepath.Join(kubeconfigDir, LBExtKubeconfig), filepath.Join(validatedDir, LBExtKubeconfig)} {
+		config, err := clientcmd.LoadFromFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://api.sno.example.com:6443", config.Clusters["cluster"].Server)
+		assert.Equal(t, []byte("new-ca"), config.Clusters["cluster"].CertificateAuthorityData)
+	}
+
+	// The one that does not is left untouched
+	config, err := clientcmd.LoadFromFile(filepath.Join(kubeconfigDir, LocalhostKubeconfig))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("old-ca"), config.Clusters["cluster"].CertificateAuthorityData)
+	_, err = os.Stat(filepath.Join(validatedDir, LocalhostKubeconfig))
+	assert.True(t, os.IsNotExist(err))
+
+	// Without the CA bundle nothing is refreshed
+	err = Refresh(context.Background(), fake.NewClientBuilder().Build(), kubeconfigDir, validatedDir, nil)
+	assert.ErrorContains(t, err, "failed to get the API serving CA bundle")
+}
@@ -2,11 +2,14 @@ package ops
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -17,16 +20,27 @@ import (
 type Execute interface {
 	Execute(command string, args ...string) (string, error)
 	ExecuteWithLiveLogger(command string, args ...string) (string, error)
+	// ExecuteWithContext executes the command like Execute, killing it along with the processes it spawned when the
+	// context is done
+	ExecuteWithContext(ctx context.Context, command string, args ...string) (string, error)
 }
 
+// killWaitDelay bounds the wait for the output of a killed command, held open by orphaned descendants
+const killWaitDelay = 10 * time.Second
+
 type executor struct {
 	log     *logrus.Logger
 	verbose bool
+	// env is added to the environment of the commands, see WithEnv
+	env []string
 }
 
-func (e *executor) execute(liveLogger io.Writer, root, command string, args ...string) (string, error) {
+func (e *executor) execute(ctx context.Context, liveLogger io.Writer, root, command string, args ...string) (string, error) {
 	e.log.Infof("Executing %s with args %s", command, args)
-	cmd := exec.Command(command, args...)
+	cmd := exec.CommandContext(ctx, command, args...)
+	if len(e.env) != 0 {
+		cmd.Env = append(os.Environ(), e.env...)
+	}
 	var stdoutBytes bytes.Buffer
 	if liveLogger != nil {
 		cmd.Stdout = io.MultiWriter(liveLogger, &stdoutBytes)
@@ -39,29 +53,69 @@ func (e *executor) execute(liveLogger io.Writer, root, command string, args ...s
 		cmd.SysProcAttr = &syscall.SysProcAttr{Chroot: root}
 		cmd.Dir = "/"
 	}
+	if ctx.Done() != nil {
+		// The command runs in its own process group, so that cancelling kills the processes it spawned as well,
+		// e.g. the command run by env or nsenter
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Setpgid = true
+		cmd.Cancel = func() error {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) //nolint:wrapcheck
+		}
+		cmd.WaitDelay = killWaitDelay
+	}
 
 	err := cmd.Run()
 	stdoutBytesTrimmed := strings.TrimSpace(stdoutBytes.String())
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return stdoutBytesTrimmed, fmt.Errorf("%s killed: %w", command, ctxErr)
+		}
 		return stdoutBytesTrimmed, fmt.Errorf("%s: %w", stdoutBytes.String(), err)
 	}
 	return stdoutBytesTrimmed, nil
 }
 
+// WithEnv returns a copy of the executor running its commands with the given environment variables, in the NAME=VALUE
+// form, added to its own. The variables are not logged, as they may hold credentials, e.g. those of a proxy. The
+// executors not created by this package, like mocks, are returned as is.
+func WithEnv(e Execute, env ...string) Execute {
+	switch executor := e.(type) {
+	case *regularExecutor:
+		c := *executor
+		c.env = append(append([]string{}, c.env...), env...)
+		return &c
+	case *nsenterExecutor:
+		c := *executor
+		c.env = append(append([]string{}, c.env...), env...)
+		return &c
+	case *chrootExecutor:
+		c := *executor
+		c.env = append(append([]string{}, c.env...), env...)
+		return &c
+	}
+	return e
+}
+
 type regularExecutor struct {
 	executor
 }
 
 func NewRegularExecutor(logger *logrus.Logger, verbose bool) Execute {
-	return &regularExecutor{executor: executor{logger, verbose}}
+	return &regularExecutor{executor: executor{log: logger, verbose: verbose}}
 }
 
 func (e *regularExecutor) Execute(command string, args ...string) (string, error) {
-	return e.executor.execute(nil, "", command, args...)
+	return e.executor.execute(context.Background(), nil, "", command, args...)
 }
 
 func (e *regularExecutor) ExecuteWithLiveLogger(command string, args ...string) (string, error) {
-	return e.executor.execute(e.executor.log.Writer(), "", command, args...)
+	return e.executor.execute(context.Background(), e.executor.log.Writer(), "", command, args...)
+}
+
+func (e *regularExecutor) ExecuteWithContext(ctx context.Context, command string, args ...string) (string, error) {
+	return e.executor.execute(ctx, nil, "", command, args...)
 }
 
 type nsenterExecutor struct {
@@ -69,14 +123,18 @@ type nsenterExecutor struct {
 }
 
 func NewNsenterExecutor(logger *logrus.Logger, verbose bool) Execute {
-	return &nsenterExecutor{executor: executor{logger, verbose}}
+	return &nsenterExecutor{executor: executor{log: logger, verbose: verbose}}
 }
 
 func (e *nsenterExecutor) ExecuteWithLiveLogger(command string, args ...string) (string, error) {
-	return e.baseExecute(e.executor.log.Writer(), command, args...)
+	return e.baseExecute(context.Background(), e.executor.log.Writer(), command, args...)
+}
+
+func (e *nsenterExecutor) ExecuteWithContext(ctx context.Context, command string, args ...string) (string, error) {
+	return e.baseExecute(ctx, nil, command, args...)
 }
 
-func (e *nsenterExecutor) baseExecute(writer io.Writer, command string, args ...string) (string, error) {
+func (e *nsenterExecutor) baseExecute(ctx context.Context, writer io.Writer, command string, args ...string) (string, error) {
 	// nsenter is used here to launch processes inside the container in a way that makes said processes feel
 	// and behave as if they're running on the host directly rather than inside the container
 	commandBase := "nsenter"
@@ -100,11 +158,11 @@ func (e *nsenterExecutor) baseExecute(writer io.Writer, command string, args ...
 	}
 
 	arguments = append(arguments, args...)
-	return e.executor.execute(writer, "", commandBase, arguments...)
+	return e.executor.execute(ctx, writer, "", commandBase, arguments...)
 }
 
 func (e *nsenterExecutor) Execute(command string, args ...string) (string, error) {
-	return e.baseExecute(nil, command, args...)
+	return e.baseExecute(context.Background(), nil, command, args...)
 }
 
 type chrootExecutor struct {
@@ -113,22 +171,26 @@ type chrootExecutor struct {
 }
 
 func NewChrootExecutor(logger *logrus.Logger, verbose bool, root string) Execute {
-	return &chrootExecutor{executor: executor{logger, verbose}, root: root}
+	return &chrootExecutor{executor: executor{log: logger, verbose: verbose}, root: root}
 }
 
 // Running a command with chroot using exec.Command runs into issues with exec.LookPath,
 // if an absolute path is not used for the "command", as it does not account for the chroot dir.
 // To workaround this issue, prefix the command with /usr/bin/env.
-func (e *chrootExecutor) baseExecute(writer io.Writer, command string, args ...string) (string, error) {
+func (e *chrootExecutor) baseExecute(ctx context.Context, writer io.Writer, command string, args ...string) (string, error) {
 	commandBase := "/usr/bin/env"
 	args = append([]string{"--", command}, args...)
-	return e.executor.execute(writer, e.root, commandBase, args...)
+	return e.executor.execute(ctx, writer, e.root, commandBase, args...)
 }
 
 func (e *chrootExecutor) Execute(command string, args ...string) (string, error) {
-	return e.baseExecute(nil, command, args...)
+	return e.baseExecute(context.Background(), nil, command, args...)
 }
 
 func (e *chrootExecutor) ExecuteWithLiveLogger(command string, args ...string) (string, error) {
-	return e.baseExecute(e.executor.log.Writer(), command, args...)
+	return e.baseExecute(context.Background(), e.executor.log.Writer(), command, args...)
+}
+
+func (e *chrootExecutor) ExecuteWithContext(ctx context.Context, command string, args ...string) (string, error) {
+	return e.baseExecute(ctx, nil, command, args...)
 }
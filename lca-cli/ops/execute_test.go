@@ -0,0 +1,44 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteWithContext(t *testing.T) {
+	executor := NewRegularExecutor(logrus.New(), false)
+
+	output, err := executor.ExecuteWithContext(context.Background(), "echo", "done")
+	assert.NoError(t, err)
+	assert.Equal(t, "done", output)
+
+	// The sleep spawned by the shell holds the output open, so it must be killed with the shell
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = executor.ExecuteWithContext(ctx, "sh", "-c", "sleep 30; echo done")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	assert.Less(t, time.Since(start), killWaitDelay)
+}
+
+func TestWithEnv(t *testing.T) {
+	executor := NewRegularExecutor(logrus.New(), false)
+	withEnv := WithEnv(executor, "LCA_TEST_PROXY=http://proxy:3128")
+
+	output, err := withEnv.ExecuteWithContext(context.Background(), "sh", "-c", "echo $LCA_TEST_PROXY")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://proxy:3128", output)
+
+	// The executor is left as is
+	output, err = executor.ExecuteWithContext(context.Background(), "sh", "-c", "echo ${LCA_TEST_PROXY:-none}")
+	assert.NoError(t, err)
+	assert.Equal(t, "none", output)
+
+	mock := &MockExecute{}
+	assert.Same(t, mock, WithEnv(mock, "LCA_TEST_PROXY=http://proxy:3128"))
+}
@@ -9,6 +9,7 @@
 package ops
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -57,6 +58,26 @@ func (mr *MockExecuteMockRecorder) Execute(command any, args ...any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Execute", reflect.TypeOf((*MockExecute)(nil).Execute), varargs...)
 }
 
+// ExecuteWithContext mocks base method.
+func (m *MockExecute) ExecuteWithContext(ctx context.Context, command string, args ...string) (string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, command}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecuteWithContext", varargs...)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecuteWithContext indicates an expected call of ExecuteWithContext.
+func (mr *MockExecuteMockRecorder) ExecuteWithContext(ctx, command any, args ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, command}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteWithContext", reflect.TypeOf((*MockExecute)(nil).ExecuteWithContext), varargs...)
+}
+
 // ExecuteWithLiveLogger mocks base method.
 func (m *MockExecute) ExecuteWithLiveLogger(command string, args ...string) (string, error) {
 	m.ctrl.T.Helper()
@@ -1,6 +1,7 @@
 package ops
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,24 +26,26 @@ var podmanRecertArgs = []string{
 //
 //go:generate mockgen -source=ops.go -package=ops -destination=mock_ops.go
 type Ops interface {
-	SystemctlAction(action string, args ...string) (string, error)
-	RunInHostNamespace(command string, args ...string) (string, error)
-	RunBashInHostNamespace(command string, args ...string) (string, error)
-	ForceExpireSeedCrypto(recertContainerImage, authFile string) error
-	RestoreOriginalSeedCrypto(recertContainerImage, authFile string) error
-	RunUnauthenticatedEtcdServer(authFile, name string) error
-	waitForEtcd(healthzEndpoint string) error
-	RunRecert(recertContainerImage, authFile, recertConfigFile string, additionalPodmanParams ...string) error
-	ExtractTarWithSELinux(srcPath, destPath string) error
-	RemountSysroot() error
-	ImageExists(img string) (bool, error)
-	IsImageMounted(img string) (bool, error)
-	UnmountAndRemoveImage(img string) error
-	RecertFullFlow(recertContainerImage, authFile, configFile string,
+	SystemctlAction(ctx context.Context, action string, args ...string) (string, error)
+	RunInHostNamespace(ctx context.Context, command string, args ...string) (string, error)
+	RunBashInHostNamespace(ctx context.Context, command string, args ...string) (string, error)
+	ForceExpireSeedCrypto(ctx context.Context, recertContainerImage, authFile string) error
+	RestoreOriginalSeedCrypto(ctx context.Context, recertContainerImage, authFile string) error
+	RunUnauthenticatedEtcdServer(ctx context.Context, authFile, name string) error
+	waitForEtcd(ctx context.Context, healthzEndpoint string) error
+	RunRecert(ctx context.Context, recertContainerImage, authFile, recertConfigFile string, additionalPodmanParams ...string) error
+	ExtractTarWithSELinux(ctx context.Context, srcPath, destPath string) error
+	CompareTar(ctx context.Context, srcPath, destPath string) ([]string, error)
+	RemountSysroot(ctx context.Context) error
+	ImageExists(ctx context.Context, img string) (bool, error)
+	IsImageMounted(ctx context.Context, img string) (bool, error)
+	UnmountImage(ctx context.Context, img string) error
+	UnmountAndRemoveImage(ctx context.Context, img string) error
+	RecertFullFlow(ctx context.Context, recertContainerImage, authFile, configFile string,
 		preRecertOperations func() error, postRecertOperations func() error, additionalPodmanParams ...string) error
-	ListBlockDevices() ([]BlockDevice, error)
-	Mount(deviceName, mountFolder string) error
-	Umount(deviceName string) error
+	ListBlockDevices(ctx context.Context) ([]BlockDevice, error)
+	Mount(ctx context.Context, deviceName, mountFolder string) error
+	Umount(ctx context.Context, deviceName string) error
 }
 
 type BlockDevice struct {
@@ -60,33 +63,33 @@ func NewOps(log *logrus.Logger, hostCommandsExecutor Execute) Ops {
 	return &ops{hostCommandsExecutor: hostCommandsExecutor, log: log}
 }
 
-func (o *ops) SystemctlAction(action string, args ...string) (string, error) {
+func (o *ops) SystemctlAction(ctx context.Context, action string, args ...string) (string, error) {
 	o.log.Infof("Running systemctl %s %s", action, args)
-	output, err := o.hostCommandsExecutor.Execute("systemctl", append([]string{action}, args...)...)
+	output, err := o.hostCommandsExecutor.ExecuteWithContext(ctx, "systemctl", append([]string{action}, args...)...)
 	if err != nil {
 		err = fmt.Errorf("failed executing systemctl %s %s: %w", action, args, err)
 	}
 	return output, err
 }
 
-func (o *ops) RunBashInHostNamespace(command string, args ...string) (string, error) {
+func (o *ops) RunBashInHostNamespace(ctx context.Context, command string, args ...string) (string, error) {
 	args = append([]string{command}, args...)
-	execute, err := o.hostCommandsExecutor.Execute("bash", "-c", strings.Join(args, " "))
+	execute, err := o.hostCommandsExecutor.ExecuteWithContext(ctx, "bash", "-c", strings.Join(args, " "))
 	if err != nil {
 		return "", fmt.Errorf("failed to run bash in host namespace with args %s: %w", args, err)
 	}
 	return execute, nil
 }
 
-func (o *ops) RunInHostNamespace(command string, args ...string) (string, error) {
-	execute, err := o.hostCommandsExecutor.Execute(command, args...)
+func (o *ops) RunInHostNamespace(ctx context.Context, command string, args ...string) (string, error) {
+	execute, err := o.hostCommandsExecutor.ExecuteWithContext(ctx, command, args...)
 	if err != nil {
 		return "", fmt.Errorf("failed to run in host namespace with args %s: %w", args, err)
 	}
 	return execute, nil
 }
 
-func (o *ops) ForceExpireSeedCrypto(recertContainerImage, authFile string) error {
+func (o *ops) ForceExpireSeedCrypto(ctx context.Context, recertContainerImage, authFile string) error {
 	o.log.Info("Running recert --force-expire tool and saving a summary without sensitive data")
 	// Run recert tool to force expiration of seed cluster certificates, and save a summary without sensitive data.
 	// This pre-check is also useful for validating that a cluster can be re-certified error-free before turning it
@@ -96,7 +99,7 @@ func (o *ops) ForceExpireSeedCrypto(recertContainerImage, authFile string) error
 	if err := recert.CreateRecertConfigFileForSeedCreation(recertConfigFile); err != nil {
 		return fmt.Errorf("failed to create %s file", recertConfigFile)
 	}
-	if err := o.RecertFullFlow(recertContainerImage, authFile, recertConfigFile, nil, nil); err != nil {
+	if err := o.RecertFullFlow(ctx, recertContainerImage, authFile, recertConfigFile, nil, nil); err != nil {
 		return err
 	}
 
@@ -104,7 +107,7 @@ func (o *ops) ForceExpireSeedCrypto(recertContainerImage, authFile string) error
 	return nil
 }
 
-func (o *ops) RestoreOriginalSeedCrypto(recertContainerImage, authFile string) error {
+func (o *ops) RestoreOriginalSeedCrypto(ctx context.Context, recertContainerImage, authFile string) error {
 	o.log.Info("Running recert --extend-expiration tool to restore original seed crypto")
 	o.log.Info("Run recert --extend-expiration tool")
 	recertConfigFile := path.Join(common.BackupCertsDir, recert.RecertConfigFile)
@@ -120,7 +123,7 @@ func (o *ops) RestoreOriginalSeedCrypto(recertContainerImage, authFile string) e
 		return nil
 	}
 
-	if err := o.RecertFullFlow(recertContainerImage, authFile, recertConfigFile, nil, postRecertOp); err != nil {
+	if err := o.RecertFullFlow(ctx, recertContainerImage, authFile, recertConfigFile, nil, postRecertOp); err != nil {
 		return err
 	}
 
@@ -131,7 +134,7 @@ func (o *ops) RestoreOriginalSeedCrypto(recertContainerImage, authFile string) e
 // RunUnauthenticatedEtcdServer Run unauthenticated etcd server for the recert tool.
 // This runs a small (fake) unauthenticated etcd server backed by the actual etcd database,
 // which is required before running the recert tool.
-func (o *ops) RunUnauthenticatedEtcdServer(authFile, name string) error {
+func (o *ops) RunUnauthenticatedEtcdServer(ctx context.Context, authFile, name string) error {
 	// Get etcdImage available for the current release, this is needed by recert to
 	// run an unauthenticated etcd server for running successfully.
 	o.log.Infof("Getting image from %s static pod file", common.EtcdStaticPodFile)
@@ -152,12 +155,12 @@ func (o *ops) RunUnauthenticatedEtcdServer(authFile, name string) error {
 		"--name", "editor", "--data-dir", "/store")
 
 	// Run the command and return an error if it occurs
-	if _, err := o.RunInHostNamespace(command, args...); err != nil {
+	if _, err := o.RunInHostNamespace(ctx, command, args...); err != nil {
 		return err
 	}
 
 	o.log.Info("Waiting for unauthenticated etcd start serving for recert tool")
-	if err := o.waitForEtcd("http://" + common.EtcdDefaultEndpoint + "/health"); err != nil {
+	if err := o.waitForEtcd(ctx, "http://"+common.EtcdDefaultEndpoint+"/health"); err != nil {
 		return fmt.Errorf("failed to wait for unauthenticated etcd server: %w", err)
 	}
 	o.log.Info("Unauthenticated etcd server for recert is up and running")
@@ -165,7 +168,7 @@ func (o *ops) RunUnauthenticatedEtcdServer(authFile, name string) error {
 	return nil
 }
 
-func (o *ops) waitForEtcd(healthzEndpoint string) error {
+func (o *ops) waitForEtcd(ctx context.Context, healthzEndpoint string) error {
 	timeout := time.After(1 * time.Minute)
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -192,7 +195,7 @@ func (o *ops) waitForEtcd(healthzEndpoint string) error {
 	}
 }
 
-func (o *ops) RunRecert(recertContainerImage, authFile, recertConfigFile string, additionalPodmanParams ...string) error {
+func (o *ops) RunRecert(ctx context.Context, recertContainerImage, authFile, recertConfigFile string, additionalPodmanParams ...string) error {
 	o.log.Info("Start running recert")
 	command := "podman"
 	args := append(podmanRecertArgs, "--name", "recert",
@@ -209,15 +212,15 @@ func (o *ops) RunRecert(recertContainerImage, authFile, recertConfigFile string,
 
 	args = append(args, additionalPodmanParams...)
 	args = append(args, recertContainerImage)
-	if _, err := o.hostCommandsExecutor.Execute(command, args...); err != nil {
+	if _, err := o.hostCommandsExecutor.ExecuteWithContext(ctx, command, args...); err != nil {
 		return fmt.Errorf("failed to run recert tool container: %w", err)
 	}
 
 	return nil
 }
 
-func (o *ops) ExtractTarWithSELinux(srcPath, destPath string) error {
-	if _, err := o.hostCommandsExecutor.Execute(
+func (o *ops) ExtractTarWithSELinux(ctx context.Context, srcPath, destPath string) error {
+	if _, err := o.hostCommandsExecutor.ExecuteWithContext(ctx,
 		"tar", "xzf", srcPath, "-C", destPath, "--selinux",
 	); err != nil {
 		return fmt.Errorf("failed to extract tar with SELinux with sourcePath %s and destPath %s: %w", srcPath, destPath, err)
@@ -225,15 +228,36 @@ func (o *ops) ExtractTarWithSELinux(srcPath, destPath string) error {
 	return nil
 }
 
-func (o *ops) RemountSysroot() error {
-	if _, err := o.hostCommandsExecutor.Execute("mount", "/sysroot", "-o", "remount,rw"); err != nil {
+// CompareTar compares the files extracted to destPath with those of the srcPath compressed tar, returning the
+// differences reported by tar, e.g. "var/lib/etcd/member/snap/db: Contents differ", none if the files are intact
+func (o *ops) CompareTar(ctx context.Context, srcPath, destPath string) ([]string, error) {
+	output, err := o.hostCommandsExecutor.ExecuteWithContext(ctx, "tar", "--compare", "--gzip", "--file", srcPath, "-C", destPath)
+	if err == nil {
+		return nil, nil
+	}
+	// tar exits with 1 when files differ, with 2 on a fatal error such as a corrupted archive
+	var exitError *exec.ExitError
+	if !errors.As(err, &exitError) || exitError.ExitCode() != 1 {
+		return nil, fmt.Errorf("failed to compare tar with sourcePath %s and destPath %s: %w", srcPath, destPath, err)
+	}
+	var differences []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(strings.TrimPrefix(line, "tar: ")); line != "" {
+			differences = append(differences, line)
+		}
+	}
+	return differences, nil
+}
+
+func (o *ops) RemountSysroot(ctx context.Context) error {
+	if _, err := o.hostCommandsExecutor.ExecuteWithContext(ctx, "mount", "/sysroot", "-o", "remount,rw"); err != nil {
 		return fmt.Errorf("failed to remount sysroot: %w", err)
 	}
 	return nil
 }
 
-func (o *ops) ImageExists(img string) (bool, error) {
-	_, err := o.hostCommandsExecutor.Execute("podman", "image", "exists", img)
+func (o *ops) ImageExists(ctx context.Context, img string) (bool, error) {
+	_, err := o.hostCommandsExecutor.ExecuteWithContext(ctx, "podman", "image", "exists", img)
 	if err != nil {
 		var exitError *exec.ExitError
 		if errors.As(err, &exitError) {
@@ -254,8 +278,8 @@ type PodmanImage struct {
 
 // IsImageMounted checkes whether certain image is mounted
 // pass in the full address with tag e.g: quay.io/openshift/lifecycle-agent-operator:latest
-func (o *ops) IsImageMounted(imgName string) (bool, error) {
-	output, err := o.hostCommandsExecutor.Execute("podman", "image", "mount", "--format", "json")
+func (o *ops) IsImageMounted(ctx context.Context, imgName string) (bool, error) {
+	output, err := o.hostCommandsExecutor.ExecuteWithContext(ctx, "podman", "image", "mount", "--format", "json")
 	if err != nil {
 		return false, fmt.Errorf("failed to mount podamn image: %w", err)
 	}
@@ -273,15 +297,15 @@ func (o *ops) IsImageMounted(imgName string) (bool, error) {
 	return false, nil
 }
 
-func (o *ops) removeImage(img string) error {
-	exist, err := o.ImageExists(img)
+func (o *ops) removeImage(ctx context.Context, img string) error {
+	exist, err := o.ImageExists(ctx, img)
 	if err != nil {
 		return fmt.Errorf("failed to check if image exist: %w", err)
 	}
 	if !exist {
 		return nil
 	}
-	if _, err := o.hostCommandsExecutor.Execute(
+	if _, err := o.hostCommandsExecutor.ExecuteWithContext(ctx,
 		"podman", "rmi", img,
 	); err != nil {
 		return fmt.Errorf("failed to remove image: %w", err)
@@ -289,29 +313,37 @@ func (o *ops) removeImage(img string) error {
 	return nil
 }
 
-func (o *ops) UnmountAndRemoveImage(img string) error {
-	if mounted, err := o.IsImageMounted(img); err != nil {
+// UnmountImage unmounts the image if it is mounted, keeping it in the container storage
+func (o *ops) UnmountImage(ctx context.Context, img string) error {
+	if mounted, err := o.IsImageMounted(ctx, img); err != nil {
 		return fmt.Errorf("failed to check if image is mounted: %w", err)
 	} else if mounted {
-		if _, err := o.hostCommandsExecutor.Execute(
+		if _, err := o.hostCommandsExecutor.ExecuteWithContext(ctx,
 			"podman", "image", "umount", img,
 		); err != nil {
 			return fmt.Errorf("failed to unmount image: %w", err)
 		}
 	}
+	return nil
+}
+
+func (o *ops) UnmountAndRemoveImage(ctx context.Context, img string) error {
+	if err := o.UnmountImage(ctx, img); err != nil {
+		return err
+	}
 
-	return o.removeImage(img)
+	return o.removeImage(ctx, img)
 }
 
-func (o *ops) RecertFullFlow(recertContainerImage, authFile, configFile string,
+func (o *ops) RecertFullFlow(ctx context.Context, recertContainerImage, authFile, configFile string,
 	preRecertOperations func() error, postRecertOperations func() error, additionalPodmanParams ...string) error {
-	if err := o.RunUnauthenticatedEtcdServer(authFile, common.EtcdContainerName); err != nil {
+	if err := o.RunUnauthenticatedEtcdServer(ctx, authFile, common.EtcdContainerName); err != nil {
 		return fmt.Errorf("failed to run etcd, err: %w", err)
 	}
 
 	defer func() {
 		o.log.Info("Killing the unauthenticated etcd server")
-		if _, err := o.RunInHostNamespace("podman", "stop", common.EtcdContainerName); err != nil {
+		if _, err := o.RunInHostNamespace(ctx, "podman", "stop", common.EtcdContainerName); err != nil {
 			o.log.WithError(err).Errorf("failed to kill %s container.", common.EtcdContainerName)
 		}
 	}()
@@ -322,7 +354,7 @@ func (o *ops) RecertFullFlow(recertContainerImage, authFile, configFile string,
 		}
 	}
 
-	if err := o.RunRecert(recertContainerImage, authFile, configFile,
+	if err := o.RunRecert(ctx, recertContainerImage, authFile, configFile,
 		additionalPodmanParams...); err != nil {
 		return err
 	}
@@ -338,9 +370,9 @@ func (o *ops) RecertFullFlow(recertContainerImage, authFile, configFile string,
 
 // ListBlockDevices runs lsblk command and not using go library cause
 // each library that i was looking into doesn't show label for block device and shows labels only for partitions
-func (o *ops) ListBlockDevices() ([]BlockDevice, error) {
+func (o *ops) ListBlockDevices(ctx context.Context) ([]BlockDevice, error) {
 	o.log.Info("Listing block devices")
-	lsblkOutput, err := o.RunInHostNamespace("lsblk", "-f",
+	lsblkOutput, err := o.RunInHostNamespace(ctx, "lsblk", "-f",
 		"--json", "--output", "NAME,LABEL")
 	if err != nil {
 		return nil, fmt.Errorf("failed to run lsblk, err: %w", err)
@@ -358,20 +390,20 @@ func (o *ops) ListBlockDevices() ([]BlockDevice, error) {
 	return blockDeviceList, nil
 }
 
-func (o *ops) Mount(deviceName, mountFolder string) error {
+func (o *ops) Mount(ctx context.Context, deviceName, mountFolder string) error {
 	o.log.Infof("Mounting %s into %s", deviceName, mountFolder)
 	if err := os.MkdirAll(mountFolder, 0o700); err != nil {
 		return fmt.Errorf("failed to create %s, err: %w", mountFolder, err)
 	}
-	if _, err := o.RunInHostNamespace("mount", fmt.Sprintf("/dev/%s", deviceName), mountFolder); err != nil {
+	if _, err := o.RunInHostNamespace(ctx, "mount", fmt.Sprintf("/dev/%s", deviceName), mountFolder); err != nil {
 		return fmt.Errorf("failed to mount %s into %s, err: %w", deviceName, mountFolder, err)
 	}
 	return nil
 }
 
-func (o *ops) Umount(deviceName string) error {
+func (o *ops) Umount(ctx context.Context, deviceName string) error {
 	o.log.Infof("Unmounting %s", deviceName)
-	if _, err := o.RunInHostNamespace("umount", fmt.Sprintf("/dev/%s", deviceName)); err != nil {
+	if _, err := o.RunInHostNamespace(ctx, "umount", fmt.Sprintf("/dev/%s", deviceName)); err != nil {
 		return fmt.Errorf("failed to unmount %s, err: %w", deviceName, err)
 	}
 	return nil
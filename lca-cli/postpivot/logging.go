@@ -0,0 +1,183 @@
+package postpivot
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const (
+	defaultLogMaxSizeMB = 10
+	defaultLogMaxFiles  = 3
+)
+
+// LoggingConfig is the post-pivot logging configuration, written to the new stateroot during Prep
+type LoggingConfig struct {
+	SerialConsole string `json:"serial_console,omitempty"`
+	LogFile       string `json:"log_file,omitempty"`
+	MaxSizeMB     int    `json:"max_size_mb,omitempty"`
+	MaxFiles      int    `json:"max_files,omitempty"`
+}
+
+// WriteLoggingConfigFile writes the post-pivot logging configuration from the IBU spec to the given stateroot.
+// Any configuration left by a previous attempt is removed if no additional output is requested.
+func WriteLoggingConfigFile(spec lcav1alpha1.PostPivotLogging, staterootPath string) error {
+	cfgfile := common.PathOutsideChroot(filepath.Join(staterootPath, common.IBUPostPivotLoggingConfigFile))
+	if spec.SerialConsole == "" && !spec.PersistentLog {
+		if err := os.Remove(cfgfile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove post-pivot logging config file %s: %w", cfgfile, err)
+		}
+		return nil
+	}
+
+	cfg := LoggingConfig{SerialConsole: spec.SerialConsole, MaxSizeMB: spec.MaxSizeMB, MaxFiles: spec.MaxFiles}
+	if spec.PersistentLog {
+		cfg.LogFile = common.PostPivotLogFile
+	}
+	if cfg.MaxSizeMB <= 0 {
+		cfg.MaxSizeMB = defaultLogMaxSizeMB
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = defaultLogMaxFiles
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfgfile), 0o700); err != nil {
+		return fmt.Errorf("unable to create config dir: %s: %w", filepath.Dir(cfgfile), err)
+	}
+	if err := utils.MarshalToFile(cfg, cfgfile); err != nil {
+		return fmt.Errorf("failed to write post-pivot logging config file in %s: %w", cfgfile, err)
+	}
+	return nil
+}
+
+// SetupLogging tees the logs to the serial console and the persistent log file set in the configuration file,
+// if any. Outputs that fail to open are skipped, so logging issues never block the post-pivot configuration.
+// The returned function closes the additional outputs.
+func SetupLogging(log *logrus.Logger, cfgfile string) func() {
+	noop := func() {}
+
+	if _, err := os.Stat(cfgfile); err != nil {
+		return noop
+	}
+	cfg := &LoggingConfig{}
+	if err := utils.ReadYamlOrJSONFile(cfgfile, cfg); err != nil {
+		log.Warnf("failed to read post-pivot logging config file %s: %s", cfgfile, err)
+		return noop
+	}
+
+	var outputs []io.WriteCloser
+	if cfg.SerialConsole != "" {
+		console := filepath.Join("/dev", cfg.SerialConsole)
+		if f, err := os.OpenFile(console, os.O_WRONLY|os.O_APPEND, 0); err != nil {
+			log.Warnf("unable to open serial console %s: %s", console, err)
+		} else {
+			outputs = append(outputs, f)
+		}
+	}
+	if cfg.LogFile != "" {
+		if f, err := newRotatingFile(cfg.LogFile, int64(cfg.MaxSizeMB)*1024*1024, cfg.MaxFiles); err != nil {
+			log.Warnf("unable to open log file %s: %s", cfg.LogFile, err)
+		} else {
+			outputs = append(outputs, f)
+		}
+	}
+	if len(outputs) == 0 {
+		return noop
+	}
+
+	log.SetOutput(&teeWriter{primary: log.Out, outputs: outputs})
+	return func() {
+		for _, output := range outputs {
+			output.Close()
+		}
+	}
+}
+
+// teeWriter writes to the primary writer, and on a best effort basis to the additional outputs
+type teeWriter struct {
+	primary io.Writer
+	outputs []io.WriteCloser
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	for _, output := range t.outputs {
+		_, _ = output.Write(p)
+	}
+	return t.primary.Write(p) //nolint:wrapcheck
+}
+
+// rotatingFile is a log file rotated to <path>.1 ... <path>.<maxFiles> when exceeding maxSize
+type rotatingFile struct {
+	mux      sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func newRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create log dir: %w", err)
+	}
+	r := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", r.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat %s: %w", r.path, err)
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+	for i := r.maxFiles - 1; i > 0; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if r.maxFiles > 0 {
+		_ = os.Rename(r.path, r.path+".1")
+	} else {
+		_ = os.Remove(r.path)
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err //nolint:wrapcheck
+}
+
+func (r *rotatingFile) Close() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.file.Close() //nolint:wrapcheck
+}
@@ -0,0 +1,72 @@
+package postpivot
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+func TestWriteLoggingConfigFile(t *testing.T) {
+	staterootPath := t.TempDir()
+	cfgfile := filepath.Join(staterootPath, common.IBUPostPivotLoggingConfigFile)
+
+	assert.NoError(t, WriteLoggingConfigFile(lcav1alpha1.PostPivotLogging{SerialConsole: "ttyS0", PersistentLog: true}, staterootPath))
+	data, err := os.ReadFile(cfgfile)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"serial_console":"ttyS0","log_file":"/var/log/lca/post-pivot.log","max_size_mb":10,"max_files":3}`, string(data))
+
+	// No additional output requested, the previous config is removed
+	assert.NoError(t, WriteLoggingConfigFile(lcav1alpha1.PostPivotLogging{}, staterootPath))
+	_, err = os.Stat(cfgfile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSetupLogging(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "post-pivot.log")
+	cfgfile := filepath.Join(dir, "config.json")
+	assert.NoError(t, os.WriteFile(cfgfile, []byte(`{"log_file":"`+logFile+`","max_size_mb":1,"max_files":2}`), 0o600))
+
+	primary := &bytes.Buffer{}
+	log := &logrus.Logger{Out: primary, Formatter: &logrus.TextFormatter{}, Level: logrus.InfoLevel}
+	closeLogOutputs := SetupLogging(log, cfgfile)
+	log.Info("post pivot started")
+	closeLogOutputs()
+
+	data, err := os.ReadFile(logFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "post pivot started")
+	assert.Contains(t, primary.String(), "post pivot started")
+
+	// Without config file, the output is left unchanged
+	log = &logrus.Logger{Out: primary}
+	SetupLogging(log, filepath.Join(dir, "missing.json"))()
+	assert.Equal(t, primary, log.Out)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "post-pivot.log")
+	r, err := newRotatingFile(path, 10, 2)
+	assert.NoError(t, err)
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		_, err := r.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, r.Close())
+
+	for file, expected := range map[string]string{path: "dddddddd\n", path + ".1": "cccccccc\n", path + ".2": "bbbbbbbb\n"} {
+		data, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(data))
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
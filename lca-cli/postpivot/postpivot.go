@@ -14,8 +14,10 @@ import (
 	clusterconfig_api "github.com/openshift-kni/lifecycle-agent/api/seedreconfig"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
 	"github.com/openshift-kni/lifecycle-agent/internal/recert"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/nodekubeconfig"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedclusterinfo"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedpackages"
 	"github.com/openshift-kni/lifecycle-agent/utils"
 	v1 "github.com/openshift/api/config/v1"
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
@@ -59,6 +61,8 @@ var (
 	hostnameFile       = "/etc/hostname"
 	nmConnectionFolder = common.NMConnectionFolder
 	nodeIpFile         = "/run/nodeip-configuration/primary-ip"
+	// clockSyncInterval is the interval between two checks of the clock synchronization before recert
+	clockSyncInterval = 5 * time.Second
 )
 
 const (
@@ -109,7 +113,7 @@ func (p *PostPivot) PostPivotConfiguration(ctx context.Context) error {
 
 	if err := utils.RunOnce("pull-secret", p.workingDir, p.log, p.createPullSecretFileAndManifest,
 		seedReconfiguration.PullSecret, common.ImageRegistryAuthFile, path.Join(p.workingDir, common.ClusterConfigDir,
-			common.ManifestsDir, pullSecretFileName)); err != nil {
+			common.ManifestsDir, pullSecretFileName), []string{seedReconfiguration.ReleaseRegistry}); err != nil {
 		return fmt.Errorf("failed to run once pull-secret for post pivot: %w", err)
 	}
 
@@ -130,23 +134,13 @@ func (p *PostPivot) PostPivotConfiguration(ctx context.Context) error {
 		return fmt.Errorf("failed to create k8s client, err: %w", err)
 	}
 
-	if _, err := p.ops.SystemctlAction("enable", "kubelet", "--now"); err != nil {
+	if _, err := p.ops.SystemctlAction(ctx, "enable", "kubelet", "--now"); err != nil {
 		return fmt.Errorf("failed to enable kubelet: %w", err)
 	}
 	p.waitForApi(ctx, client)
 
-	if err := p.deleteAllOldMirrorResources(ctx, client); err != nil {
-		return fmt.Errorf("failed to all old mirror resources: %w", err)
-	}
-
-	// We move back seed pull secret that we saved aside (if it exists), right before applying new PS secret
-	// in order for MCO not to be degraded and apply new rendered master machine config
-	if err := utils.MoveFileIfExists(common.ImageRegistryAuthFile+seedPullSecretSuffix,
-		common.ImageRegistryAuthFile); err != nil {
-		return fmt.Errorf("failed move back seed pull secret: %w", err)
-	}
-	if err := p.applyManifests(); err != nil {
-		return fmt.Errorf("failed apply manifests: %w", err)
+	if err := p.replaceClusterManifests(ctx, client); err != nil {
+		return err
 	}
 
 	if err := p.changeRegistryInCSVDeployment(ctx, client, seedReconfiguration, seedClusterInfo); err != nil {
@@ -162,7 +156,17 @@ func (p *PostPivot) PostPivotConfiguration(ctx context.Context) error {
 		return fmt.Errorf("failed to run once recover_lvm_devices for post pivot: %w", err)
 	}
 
-	if _, err = p.ops.SystemctlAction("disable", "installation-configuration.service"); err != nil {
+	// The package report is informative, it does not fail the upgrade
+	if err := p.reportPackageDrift(ctx); err != nil {
+		p.log.Warnf("failed to compare the installed packages with the seed: %s", err)
+	}
+
+	// The admin kubeconfigs are a recovery aid, failing to refresh them does not fail the upgrade
+	if err := p.refreshNodeKubeconfigs(ctx, client, seedReconfiguration); err != nil {
+		p.log.Warnf("failed to refresh the node admin kubeconfigs: %s", err)
+	}
+
+	if _, err = p.ops.SystemctlAction(ctx, "disable", "installation-configuration.service"); err != nil {
 		return fmt.Errorf("failed to disable installation-configuration.service, err: %w", err)
 	}
 
@@ -175,6 +179,10 @@ func (p *PostPivot) recert(ctx context.Context, seedReconfiguration *clusterconf
 			"In case you still want to rerun it please remove the file", recert.SummaryFile)
 	}
 
+	if err := p.waitForClockSync(ctx, seedReconfiguration.ClockSkewProtection); err != nil {
+		return err
+	}
+
 	p.log.Info("Create recert configuration file")
 	kubeconfigCryptoDir := path.Join(p.workingDir, common.KubeconfigCryptoDir)
 	if err := utils.SeedReconfigurationKubeconfigRetentionToCryptoDir(kubeconfigCryptoDir, &seedReconfiguration.KubeconfigCryptoRetention); err != nil {
@@ -190,14 +198,14 @@ func (p *PostPivot) recert(ctx context.Context, seedReconfiguration *clusterconf
 	defer cancel()
 	_ = wait.PollUntilContextCancel(ctxWithTimeout, time.Second, true, func(ctx context.Context) (bool, error) {
 		p.log.Info("pulling recert image")
-		if _, err := p.ops.RunInHostNamespace("podman", "pull", "--authfile", common.ImageRegistryAuthFile, seedClusterInfo.RecertImagePullSpec); err != nil {
+		if _, err := p.ops.RunInHostNamespace(ctx, "podman", "pull", "--authfile", common.ImageRegistryAuthFile, seedClusterInfo.RecertImagePullSpec); err != nil {
 			p.log.Warnf("failed to pull recert image, will retry, err: %s", err.Error())
 			return false, nil
 		}
 		return true, nil
 	})
 
-	err := p.ops.RecertFullFlow(seedClusterInfo.RecertImagePullSpec, p.authFile,
+	err := p.ops.RecertFullFlow(ctx, seedClusterInfo.RecertImagePullSpec, p.authFile,
 		path.Join(p.workingDir, recert.RecertConfigFile),
 		nil,
 		func() error { return p.postRecertCommands(ctx, seedReconfiguration, seedClusterInfo) },
@@ -209,6 +217,48 @@ func (p *PostPivot) recert(ctx context.Context, seedReconfiguration *clusterconf
 	return nil
 }
 
+// waitForClockSync waits for the time synchronization service of the node to synchronize its clock before recert, as
+// the certificates it regenerates while the clock is ahead would not be valid yet once the clock is corrected
+func (p *PostPivot) waitForClockSync(ctx context.Context, protection *clusterconfig_api.ClockSkewProtection) error {
+	if protection == nil || protection.ClockSyncTimeout == "" {
+		return nil
+	}
+	timeout, err := time.ParseDuration(protection.ClockSyncTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid clock sync timeout %q: %w", protection.ClockSyncTimeout, err)
+	}
+
+	p.log.Infof("Waiting up to %s for the clock to be synchronized before recert", timeout)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err = wait.PollUntilContextCancel(ctxWithTimeout, clockSyncInterval, true, func(ctx context.Context) (bool, error) {
+		output, err := p.ops.RunInHostNamespace(ctx, "timedatectl", "show", "--property=NTP", "--property=NTPSynchronized")
+		if err != nil {
+			p.log.Warnf("failed to get the clock synchronization status, will retry, err: %s", err.Error())
+			return false, nil
+		}
+		status := map[string]string{}
+		for _, line := range strings.Split(output, "\n") {
+			if key, value, found := strings.Cut(line, "="); found {
+				status[key] = value
+			}
+		}
+		if status["NTP"] != "yes" {
+			return false, fmt.Errorf("no time synchronization service is active on the node to validate its clock")
+		}
+		return status["NTPSynchronized"] == "yes", nil
+	})
+	if err != nil {
+		if ctx.Err() == nil && ctxWithTimeout.Err() != nil {
+			return fmt.Errorf("clock of the node is not synchronized after %s, not running recert as the certificates "+
+				"it regenerates may not be valid yet once the clock is corrected", timeout)
+		}
+		return fmt.Errorf("failed to wait for the clock synchronization: %w", err)
+	}
+	p.log.Info("Clock is synchronized")
+	return nil
+}
+
 func (p *PostPivot) etcdPostPivotOperations(ctx context.Context, reconfigurationInfo *clusterconfig_api.SeedReconfiguration) error {
 	p.log.Info("Start running etcd post pivot operations")
 	cli, err := etcdClient.New(etcdClient.Config{
@@ -244,7 +294,7 @@ func (p *PostPivot) postRecertCommands(ctx context.Context, clusterInfo *cluster
 	}
 
 	// changing seed ip to new ip in all static pod files
-	_, err := p.ops.RunBashInHostNamespace(fmt.Sprintf("find /etc/kubernetes/ -type f -print0 | xargs -0 sed -i \"s/%s/%s/g\"",
+	_, err := p.ops.RunBashInHostNamespace(ctx, fmt.Sprintf("find /etc/kubernetes/ -type f -print0 | xargs -0 sed -i \"s/%s/%s/g\"",
 		seedClusterInfo.NodeIP, clusterInfo.NodeIP))
 	if err != nil {
 		return fmt.Errorf("failed to change seed ip to new ip in /etc/kubernetes, err: %w", err)
@@ -265,7 +315,25 @@ func (p *PostPivot) waitForApi(ctx context.Context, client runtimeclient.Client)
 	})
 }
 
-func (p *PostPivot) applyManifests() error {
+// replaceClusterManifests deletes the mirror resources of the seed and applies the manifests of the cluster instead
+func (p *PostPivot) replaceClusterManifests(ctx context.Context, client runtimeclient.Client) error {
+	if err := p.deleteAllOldMirrorResources(ctx, client); err != nil {
+		return fmt.Errorf("failed to all old mirror resources: %w", err)
+	}
+
+	// We move back seed pull secret that we saved aside (if it exists), right before applying new PS secret
+	// in order for MCO not to be degraded and apply new rendered master machine config
+	if err := utils.MoveFileIfExists(common.ImageRegistryAuthFile+seedPullSecretSuffix,
+		common.ImageRegistryAuthFile); err != nil {
+		return fmt.Errorf("failed move back seed pull secret: %w", err)
+	}
+	if err := p.applyManifests(ctx); err != nil {
+		return fmt.Errorf("failed apply manifests: %w", err)
+	}
+	return nil
+}
+
+func (p *PostPivot) applyManifests(ctx context.Context) error {
 	p.log.Infof("Applying manifests from %s", path.Join(p.workingDir, common.ClusterConfigDir, common.ManifestsDir))
 	mPath := path.Join(p.workingDir, common.ClusterConfigDir, common.ManifestsDir)
 	dir, err := os.ReadDir(mPath)
@@ -279,14 +347,14 @@ func (p *PostPivot) applyManifests() error {
 
 	args := []string{"--kubeconfig", p.kubeconfig, "apply", "-f"}
 
-	_, err = p.ops.RunInHostNamespace("oc", append(args, path.Join(p.workingDir, common.ClusterConfigDir, common.ManifestsDir))...)
+	_, err = p.ops.RunInHostNamespace(ctx, "oc", append(args, path.Join(p.workingDir, common.ClusterConfigDir, common.ManifestsDir))...)
 	if err != nil {
 		return fmt.Errorf("failed to apply manifests, err: %w", err)
 	}
 
 	if _, err := os.Stat(path.Join(p.workingDir, common.ExtraManifestsDir)); err == nil {
 		p.log.Infof("Applying extra manifests")
-		_, err := p.ops.RunInHostNamespace("oc", append(args, path.Join(p.workingDir, common.ExtraManifestsDir))...)
+		_, err := p.ops.RunInHostNamespace(ctx, "oc", append(args, path.Join(p.workingDir, common.ExtraManifestsDir))...)
 		if err != nil {
 			return fmt.Errorf("failed to apply extra manifests, err: %w", err)
 		}
@@ -294,7 +362,7 @@ func (p *PostPivot) applyManifests() error {
 	return nil
 }
 
-func (p *PostPivot) recoverLvmDevices() error {
+func (p *PostPivot) recoverLvmDevices(ctx context.Context) error {
 	lvmConfigPath := path.Join(p.workingDir, common.LvmConfigDir)
 	lvmDevicesPath := path.Join(lvmConfigPath, path.Base(common.LvmDevicesPath))
 
@@ -306,7 +374,7 @@ func (p *PostPivot) recoverLvmDevices() error {
 	}
 
 	// Update the online record of PVs and activate all lvm devices in the VGs
-	_, err := p.ops.RunInHostNamespace("pvscan", "--cache", "--activate", "ay")
+	_, err := p.ops.RunInHostNamespace(ctx, "pvscan", "--cache", "--activate", "ay")
 	if err != nil {
 		return fmt.Errorf("failed to scan and active lvm devices, err: %w", err)
 	}
@@ -319,6 +387,43 @@ func (p *PostPivot) copyClusterConfigFiles() error {
 		common.CABundleFilePath); err != nil {
 		return fmt.Errorf("failed to copy cluster config file in %s: %w", common.ClusterConfigDir, err)
 	}
+	if err := installChunkedCABundle(path.Join(p.workingDir, common.ClusterConfigDir, common.ChunkedCABundleFileName),
+		common.ChunkedCABundleAnchorFile, common.CABundleFilePath); err != nil {
+		return err
+	}
+	if err := utils.CopyFileIfExists(path.Join(p.workingDir, common.ClusterConfigDir, common.ChunkedRegistriesConfFileName),
+		common.ChunkedRegistriesConfFile); err != nil {
+		return fmt.Errorf("failed to copy chunked registries configuration: %w", err)
+	}
+	return nil
+}
+
+// installChunkedCABundle adds the CA bundle reassembled from chunks, if any, to the trust anchors of the host, and to
+// its extracted CA bundle right away as the latter is copied from the original stateroot
+func installChunkedCABundle(chunkedCABundle, anchorFile, caBundleFile string) error {
+	content, err := os.ReadFile(chunkedCABundle)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read chunked CA bundle: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(anchorFile), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(anchorFile), err)
+	}
+	if err := os.WriteFile(anchorFile, content, 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("failed to write chunked CA bundle to %s: %w", anchorFile, err)
+	}
+
+	f, err := os.OpenFile(caBundleFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", caBundleFile, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append([]byte("\n"), content...)); err != nil {
+		return fmt.Errorf("failed to append chunked CA bundle to %s: %w", caBundleFile, err)
+	}
 	return nil
 }
 
@@ -405,6 +510,48 @@ func (p *PostPivot) changeRegistryInCSVDeployment(ctx context.Context, client ru
 	return nil
 }
 
+// reportPackageDrift compares the packages of the booted system with the packages recorded in the seed, before
+// the seed data is cleaned up, and saves the report for the lifecycle agent
+func (p *PostPivot) reportPackageDrift(ctx context.Context) error {
+	seedManifestFile := path.Join(common.SeedDataDir, seedpackages.PackagesFileName)
+	if _, err := os.Stat(seedManifestFile); os.IsNotExist(err) {
+		p.log.Info("The seed image has no package manifest, skipping the package report")
+		return nil
+	}
+	seedManifest, err := seedpackages.ReadManifest(seedManifestFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the seed packages: %w", err)
+	}
+	bootedManifest, err := seedpackages.Collect(func(command string, args ...string) (string, error) {
+		return p.ops.RunInHostNamespace(ctx, command, args...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to collect the booted system packages: %w", err)
+	}
+
+	report := seedpackages.Diff(seedManifest, bootedManifest)
+	p.log.Infof("Package report: %s", report.Summary())
+	if err := os.MkdirAll(common.LCAConfigDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", common.LCAConfigDir, err)
+	}
+	return seedpackages.WriteReport(report, common.PackageReportFile) //nolint:wrapcheck
+}
+
+// refreshNodeKubeconfigs patches the node admin kubeconfigs with the API serving CA bundle and name of the upgraded
+// cluster, and saves the ones that authenticate to common.ValidatedKubeconfigsDir for recovery SSH sessions
+func (p *PostPivot) refreshNodeKubeconfigs(ctx context.Context, client runtimeclient.Client,
+	seedReconfiguration *clusterconfig_api.SeedReconfiguration) error {
+	p.log.Info("Refreshing the node admin kubeconfigs")
+	servers := map[string]string{
+		nodekubeconfig.LBExtKubeconfig: fmt.Sprintf("https://api.%s.%s:6443", seedReconfiguration.ClusterName, seedReconfiguration.BaseDomain),
+	}
+	if err := nodekubeconfig.Refresh(ctx, client, common.NodeKubeconfigsDir, common.ValidatedKubeconfigsDir, servers); err != nil {
+		return fmt.Errorf("failed to refresh kubeconfigs: %w", err)
+	}
+	p.log.Infof("The node admin kubeconfigs are validated and saved to %s", common.ValidatedKubeconfigsDir)
+	return nil
+}
+
 func (p *PostPivot) cleanup() error {
 	p.log.Info("Cleaning up")
 	listOfDirs := []string{p.workingDir, common.SeedDataDir}
@@ -464,7 +611,7 @@ func (p *PostPivot) setNodeIPIfNotProvided(ctx context.Context,
 	}
 
 	if _, err := os.Stat(ipFile); err != nil {
-		_, err := p.ops.SystemctlAction("start", "nodeip-configuration")
+		_, err := p.ops.SystemctlAction(ctx, "start", "nodeip-configuration")
 		if err != nil {
 			return fmt.Errorf("failed to start nodeip-configuration service, err %w", err)
 		}
@@ -494,7 +641,7 @@ func (p *PostPivot) setNodeIPIfNotProvided(ctx context.Context,
 // setSSHKey  sets ssh public key provided by user in 2 operations:
 // 1. as file in order to give early access to the node
 // 2. creates 2 machine configs in manifests dir that will be applied when cluster is up
-func (p *PostPivot) setSSHKey(seedReconfiguration *clusterconfig_api.SeedReconfiguration, sshKeyFile string) error {
+func (p *PostPivot) setSSHKey(ctx context.Context, seedReconfiguration *clusterconfig_api.SeedReconfiguration, sshKeyFile string) error {
 	if seedReconfiguration.SSHKey == "" {
 		p.log.Infof("No ssh public key was provided, skipping")
 		return nil
@@ -506,7 +653,7 @@ func (p *PostPivot) setSSHKey(seedReconfiguration *clusterconfig_api.SeedReconfi
 	}
 
 	p.log.Infof("Setting %s user ownership on %s", userCore, sshKeyFile)
-	if _, err := p.ops.RunInHostNamespace("chown", userCore, sshKeyFile); err != nil {
+	if _, err := p.ops.RunInHostNamespace(ctx, "chown", userCore, sshKeyFile); err != nil {
 		return fmt.Errorf("failed to set %s user ownership on %s, err :%w", userCore, sshKeyFile, err)
 	}
 
@@ -556,7 +703,7 @@ func (p *PostPivot) createSSHKeyMachineConfigs(sshKey string) error {
 
 // applyNMStateConfiguration is applying nmstate yaml provided as string in seedReconfiguration.
 // It uses nmstatectl apply <file> command that will return error in case configuration is not successful
-func (p *PostPivot) applyNMStateConfiguration(seedReconfiguration *clusterconfig_api.SeedReconfiguration) error {
+func (p *PostPivot) applyNMStateConfiguration(ctx context.Context, seedReconfiguration *clusterconfig_api.SeedReconfiguration) error {
 	if seedReconfiguration.RawNMStateConfig == "" {
 		p.log.Infof("NMState config is empty, skipping")
 		return nil
@@ -566,7 +713,7 @@ func (p *PostPivot) applyNMStateConfiguration(seedReconfiguration *clusterconfig
 	if err := os.WriteFile(nmFile, []byte(seedReconfiguration.RawNMStateConfig), 0o600); err != nil {
 		return fmt.Errorf("failed to write nmstate config to %s, err %w", nmFile, err)
 	}
-	if _, err := p.ops.RunInHostNamespace("nmstatectl", "apply", nmFile); err != nil {
+	if _, err := p.ops.RunInHostNamespace(ctx, "nmstatectl", "apply", nmFile); err != nil {
 		return fmt.Errorf("failed to apply nmstate config %s, err: %w", seedReconfiguration.RawNMStateConfig, err)
 	}
 
@@ -574,13 +721,18 @@ func (p *PostPivot) applyNMStateConfiguration(seedReconfiguration *clusterconfig
 }
 
 // createPullSecretFile creates auth file on filesystem in order to be able to pull images
-// and runs createPullSecretManifest to write secret in manifests folder
-func (p *PostPivot) createPullSecretFileAndManifest(pullSecret, pullSecretFile, pullSecretManifest string) error {
+// and runs createPullSecretManifest to write secret in manifests folder, replacing the placeholder pull secret
+// baked in the seed image. The pull secret must have credentials for the required registries
+func (p *PostPivot) createPullSecretFileAndManifest(pullSecret, pullSecretFile, pullSecretManifest string,
+	requiredRegistries []string) error {
 	// TODO: Should return error in the future as cluster will not be operational without it
 	if pullSecret == "" {
 		p.log.Infof("Pull secret was not provided")
 		return nil
 	}
+	if err := utils.ValidatePullSecret([]byte(pullSecret), requiredRegistries...); err != nil {
+		return fmt.Errorf("failed to validate the provided pull secret: %w", err)
+	}
 	p.log.Infof("Move seed PS file aside")
 	if err := utils.MoveFileIfExists(pullSecretFile, pullSecretFile+seedPullSecretSuffix); err != nil {
 		return fmt.Errorf("failed to move seed PS file aside: %w", err)
@@ -626,7 +778,7 @@ func (p *PostPivot) waitForConfiguration(ctx context.Context, configFolder, bloc
 		if _, err := os.Stat(configFolder); err == nil {
 			return true, nil
 		}
-		blockDevices, err := p.ops.ListBlockDevices()
+		blockDevices, err := p.ops.ListBlockDevices(ctx)
 		if err != nil {
 			p.log.Infof("Failed to list block devices with error %s, will retry", err.Error())
 			return false, nil
@@ -635,7 +787,7 @@ func (p *PostPivot) waitForConfiguration(ctx context.Context, configFolder, bloc
 			// TODO: change after all the components will move to clusterconfig_api.BlockDeviceLabel
 			if lo.Contains([]string{clusterconfig_api.BlockDeviceLabel, OldblockDeviceLabel}, bd.Label) {
 				// in case of error while mounting device we exit wait and return the error
-				if err := p.setupConfigurationFolder(bd.Name, blockDeviceMountFolder, filepath.Dir(configFolder)); err != nil {
+				if err := p.setupConfigurationFolder(ctx, bd.Name, blockDeviceMountFolder, filepath.Dir(configFolder)); err != nil {
 					return true, err
 				}
 				return true, nil
@@ -652,17 +804,17 @@ func (p *PostPivot) waitForConfiguration(ctx context.Context, configFolder, bloc
 }
 
 // setupConfigurationFolder mounts device to mountFolder and copies everything to configFolder
-func (p *PostPivot) setupConfigurationFolder(deviceName, mountFolder, configFolder string) error {
+func (p *PostPivot) setupConfigurationFolder(ctx context.Context, deviceName, mountFolder, configFolder string) error {
 	p.log.Infof("Running setup of configuration folder")
 	if err := os.MkdirAll(configFolder, 0o700); err != nil {
 		return fmt.Errorf("failed to create %s, err: %w", configFolder, err)
 	}
 	defer os.RemoveAll(mountFolder)
 
-	if err := p.ops.Mount(deviceName, mountFolder); err != nil {
+	if err := p.ops.Mount(ctx, deviceName, mountFolder); err != nil {
 		return fmt.Errorf("failed to mount %s: %w", mountFolder, err)
 	}
-	defer p.ops.Umount(deviceName)
+	defer p.ops.Umount(ctx, deviceName)
 
 	if err := utils.CopyFileIfExists(mountFolder, configFolder); err != nil {
 		return fmt.Errorf("failed to copy contert of %s to %s, err: %w", mountFolder, configFolder, err)
@@ -705,7 +857,7 @@ func (p *PostPivot) networkConfiguration(ctx context.Context, seedReconfiguratio
 		return err
 	}
 
-	if err := p.applyNMStateConfiguration(seedReconfiguration); err != nil {
+	if err := p.applyNMStateConfiguration(ctx, seedReconfiguration); err != nil {
 		return err
 	}
 
@@ -721,11 +873,11 @@ func (p *PostPivot) networkConfiguration(ctx context.Context, seedReconfiguratio
 		return err
 	}
 
-	if _, err := p.ops.SystemctlAction("restart", nmService); err != nil {
+	if _, err := p.ops.SystemctlAction(ctx, "restart", nmService); err != nil {
 		return fmt.Errorf("failed to restart network manager service, err %w", err)
 	}
 
-	if _, err := p.ops.SystemctlAction("restart", dnsmasqService); err != nil {
+	if _, err := p.ops.SystemctlAction(ctx, "restart", dnsmasqService); err != nil {
 		return fmt.Errorf("failed to restart dnsmasq service, err %w", err)
 	}
 
@@ -7,6 +7,7 @@ import (
 	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -17,6 +18,7 @@ import (
 
 	clusterconfig_api "github.com/openshift-kni/lifecycle-agent/api/seedreconfig"
 
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 	"github.com/openshift-kni/lifecycle-agent/utils"
 )
@@ -85,7 +87,7 @@ func TestSetNodeIPIfNotProvided(t *testing.T) {
 			if tc.nodeipFileExists {
 				createNodeIpFile(t, ipFile, tc.ipToSet)
 			} else {
-				mockOps.EXPECT().SystemctlAction("start", "nodeip-configuration").Return("", nil).Do(func(any) {
+				mockOps.EXPECT().SystemctlAction(gomock.Any(), "start", "nodeip-configuration").Return("", nil).Do(func(any) {
 					createNodeIpFile(t, ipFile, tc.ipToSet)
 				})
 			}
@@ -217,12 +219,12 @@ interfaces:
 			nmstateFile := path.Join(tmpDir, "nmstate.yaml")
 			pp := NewPostPivot(nil, log, mockOps, "", tmpDir, "")
 			if tc.expectedError {
-				mockOps.EXPECT().RunInHostNamespace("nmstatectl", "apply", nmstateFile).Return("", fmt.Errorf("Dummy"))
+				mockOps.EXPECT().RunInHostNamespace(gomock.Any(), "nmstatectl", "apply", nmstateFile).Return("", fmt.Errorf("Dummy"))
 			} else {
-				mockOps.EXPECT().RunInHostNamespace("nmstatectl", "apply", nmstateFile).Return("", nil)
+				mockOps.EXPECT().RunInHostNamespace(gomock.Any(), "nmstatectl", "apply", nmstateFile).Return("", nil)
 			}
 
-			err := pp.applyNMStateConfiguration(tc.seedReconfiguration)
+			err := pp.applyNMStateConfiguration(context.Background(), tc.seedReconfiguration)
 			if !tc.expectedError {
 				if err != nil {
 					t.Errorf("unexpected error: %v", err)
@@ -250,18 +252,30 @@ func TestCreatePullSecretFile(t *testing.T) {
 		mockController.Finish()
 	}()
 
+	validPullSecret := `{"auths":{"quay.io":{"auth":"dXNlcjpwYXNz"}}}`
 	testcases := []struct {
-		name       string
-		pullSecret string
+		name          string
+		pullSecret    string
+		expectedError bool
 	}{
 		{
 			name:       "Happy flow, pull secret was set",
-			pullSecret: "pull-secret",
+			pullSecret: validPullSecret,
 		},
 		{
 			name:       "Pull secret was not set",
 			pullSecret: "",
 		},
+		{
+			name:          "Placeholder pull secret of the seed",
+			pullSecret:    common.PullSecretEmptyData,
+			expectedError: true,
+		},
+		{
+			name:          "No credentials for the release registry",
+			pullSecret:    `{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"}}}`,
+			expectedError: true,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -272,7 +286,14 @@ func TestCreatePullSecretFile(t *testing.T) {
 			pullSecretManifestFile := path.Join(tmpDir, pullSecretFileName)
 			clientgoscheme.AddToScheme(scheme)
 			pp := NewPostPivot(scheme, log, mockOps, "", tmpDir, "")
-			err := pp.createPullSecretFileAndManifest(tc.pullSecret, pullSecretFile, pullSecretManifestFile)
+			err := pp.createPullSecretFileAndManifest(tc.pullSecret, pullSecretFile, pullSecretManifestFile, []string{"quay.io"})
+			if tc.expectedError {
+				assert.Error(t, err)
+				if _, err := os.Stat(pullSecretFile); err == nil {
+					t.Errorf("expected no pull secret file to be created")
+				}
+				return
+			}
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
@@ -286,13 +307,13 @@ func TestCreatePullSecretFile(t *testing.T) {
 				if !ok {
 					t.Errorf("pull secret was not found: %v", err)
 				}
-				assert.Equal(t, "pull-secret", string(ps))
+				assert.Equal(t, tc.pullSecret, string(ps))
 
 				ps, err = os.ReadFile(pullSecretFile)
 				if err != nil {
 					t.Errorf("unexpected error while reading pull secret file: %v", err)
 				}
-				assert.Equal(t, "pull-secret", string(ps))
+				assert.Equal(t, tc.pullSecret, string(ps))
 
 			} else if _, err := os.Stat(pullSecretFile); err == nil {
 				t.Errorf("expected no pull secret file to be created")
@@ -388,16 +409,16 @@ func TestWaitForConfiguration(t *testing.T) {
 				}
 			}
 			if tc.listBlockDevicesSucceeds {
-				mockOps.EXPECT().ListBlockDevices().Return([]ops.BlockDevice{{Name: deviceName,
+				mockOps.EXPECT().ListBlockDevices(gomock.Any()).Return([]ops.BlockDevice{{Name: deviceName,
 					Label: clusterconfig_api.BlockDeviceLabel}}, nil).Times(1)
 				if tc.mountSucceeds {
-					mockOps.EXPECT().Mount(deviceName, gomock.Any()).Return(nil).Times(1)
-					mockOps.EXPECT().Umount(deviceName).Return(nil).Times(1)
+					mockOps.EXPECT().Mount(gomock.Any(), deviceName, gomock.Any()).Return(nil).Times(1)
+					mockOps.EXPECT().Umount(gomock.Any(), deviceName).Return(nil).Times(1)
 				} else {
-					mockOps.EXPECT().Mount(deviceName, gomock.Any()).Return(fmt.Errorf("dummy")).Times(1)
+					mockOps.EXPECT().Mount(gomock.Any(), deviceName, gomock.Any()).Return(fmt.Errorf("dummy")).Times(1)
 				}
 			} else if !tc.configurationFolderExists {
-				mockOps.EXPECT().ListBlockDevices().Return(nil, fmt.Errorf("dummy")).Do(cancel).Times(1)
+				mockOps.EXPECT().ListBlockDevices(gomock.Any()).Return(nil, fmt.Errorf("dummy")).Do(func(context.Context) { cancel() }).Times(1)
 			}
 
 			err := pp.waitForConfiguration(ctx, configFolder, configFolder)
@@ -450,14 +471,14 @@ func TestNetworkConfiguration(t *testing.T) {
 			dnsmasqOverrides = path.Join(tmpDir, "dnsmasqoverrides")
 
 			if tc.restartNMSuccess {
-				mockOps.EXPECT().SystemctlAction("restart", nmService).Return("", nil).Times(1)
+				mockOps.EXPECT().SystemctlAction(gomock.Any(), "restart", nmService).Return("", nil).Times(1)
 				if tc.restartDNSMASQSuccess {
-					mockOps.EXPECT().SystemctlAction("restart", dnsmasqService).Return("", nil).Times(1)
+					mockOps.EXPECT().SystemctlAction(gomock.Any(), "restart", dnsmasqService).Return("", nil).Times(1)
 				} else {
-					mockOps.EXPECT().SystemctlAction("restart", dnsmasqService).Return("", fmt.Errorf("dummy")).Times(1)
+					mockOps.EXPECT().SystemctlAction(gomock.Any(), "restart", dnsmasqService).Return("", fmt.Errorf("dummy")).Times(1)
 				}
 			} else {
-				mockOps.EXPECT().SystemctlAction("restart", nmService).Return("", fmt.Errorf("dummy")).Times(1)
+				mockOps.EXPECT().SystemctlAction(gomock.Any(), "restart", nmService).Return("", fmt.Errorf("dummy")).Times(1)
 			}
 
 			err := pp.networkConfiguration(context.TODO(), seedReconfiguration)
@@ -465,3 +486,105 @@ func TestNetworkConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestInstallChunkedCABundle(t *testing.T) {
+	tmpDir := t.TempDir()
+	chunkedCABundle := path.Join(tmpDir, common.ChunkedCABundleFileName)
+	anchorFile := path.Join(tmpDir, "anchors", "lca-chunked-ca-bundle.pem")
+	caBundleFile := path.Join(tmpDir, "tls-ca-bundle.pem")
+	assert.NoError(t, os.WriteFile(caBundleFile, []byte("original"), 0o600))
+
+	// Nothing is installed without chunked CA bundle
+	assert.NoError(t, installChunkedCABundle(chunkedCABundle, anchorFile, caBundleFile))
+	_, err := os.Stat(anchorFile)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, os.WriteFile(chunkedCABundle, []byte("chunked"), 0o600))
+	assert.NoError(t, installChunkedCABundle(chunkedCABundle, anchorFile, caBundleFile))
+	content, err := os.ReadFile(anchorFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "chunked", string(content))
+	content, err = os.ReadFile(caBundleFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "original\nchunked", string(content))
+}
+
+func TestWaitForClockSync(t *testing.T) {
+	origInterval := clockSyncInterval
+	defer func() { clockSyncInterval = origInterval }()
+	clockSyncInterval = 10 * time.Millisecond
+	timedatectl := []any{"show", "--property=NTP", "--property=NTPSynchronized"}
+
+	testcases := []struct {
+		name          string
+		protection    *clusterconfig_api.ClockSkewProtection
+		outputs       []string
+		neverSynced   bool
+		expectedError string
+	}{
+		{
+			name: "no protection",
+		},
+		{
+			name:       "no clock sync timeout",
+			protection: &clusterconfig_api.ClockSkewProtection{NotBeforeBackdate: "1h0m0s"},
+		},
+		{
+			name:       "synchronized",
+			protection: &clusterconfig_api.ClockSkewProtection{ClockSyncTimeout: "1m"},
+			outputs:    []string{"NTP=yes\nNTPSynchronized=no", "NTP=yes\nNTPSynchronized=yes"},
+		},
+		{
+			name:          "no time synchronization service",
+			protection:    &clusterconfig_api.ClockSkewProtection{ClockSyncTimeout: "1m"},
+			outputs:       []string{"NTP=no\nNTPSynchronized=no"},
+			expectedError: "no time synchronization service is active on the node",
+		},
+		{
+			name:          "not synchronized in time",
+			protection:    &clusterconfig_api.ClockSkewProtection{ClockSyncTimeout: "50ms"},
+			neverSynced:   true,
+			expectedError: "clock of the node is not synchronized after 50ms",
+		},
+		{
+			name:          "invalid timeout",
+			protection:    &clusterconfig_api.ClockSkewProtection{ClockSyncTimeout: "soon"},
+			expectedError: `invalid clock sync timeout "soon"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockOps := ops.NewMockOps(gomock.NewController(t))
+			for _, output := range tc.outputs {
+				mockOps.EXPECT().RunInHostNamespace(gomock.Any(), "timedatectl", timedatectl...).Return(output, nil)
+			}
+			if tc.neverSynced {
+				mockOps.EXPECT().RunInHostNamespace(gomock.Any(), "timedatectl", timedatectl...).
+					Return("NTP=yes\nNTPSynchronized=no", nil).MinTimes(1)
+			}
+			pp := NewPostPivot(nil, logrus.New(), mockOps, "", "", "")
+
+			err := pp.waitForClockSync(context.Background(), tc.protection)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestRerun(t *testing.T) {
+	pp := NewPostPivot(nil, logrus.New(), ops.NewMockOps(gomock.NewController(t)), "", t.TempDir(), "")
+
+	// Unknown steps are rejected before anything runs
+	err := pp.Rerun(context.Background(), "recert")
+	assert.ErrorContains(t, err, `unknown post pivot step "recert", valid steps are: manifests, registry, restore`)
+
+	// Nothing to rerun once the post pivot configuration is cleaned up
+	err = pp.Rerun(context.Background(), RerunStepManifests)
+	assert.ErrorContains(t, err, "no post pivot configuration found in")
+	err = pp.Rerun(context.Background(), "")
+	assert.ErrorContains(t, err, "no post pivot configuration found in")
+}
@@ -0,0 +1,83 @@
+package postpivot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedclusterinfo"
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+// Post pivot steps that can be rerun on a booted upgraded system
+const (
+	// RerunStepManifests deletes the mirror resources of the seed and applies the manifests of the cluster
+	RerunStepManifests = "manifests"
+	// RerunStepRegistry sets the release registry of the cluster in the CSV deployment of the lifecycle agent
+	RerunStepRegistry = "registry"
+	// RerunStepRestore restores the lvm devices of the cluster
+	RerunStepRestore = "restore"
+)
+
+// RerunSteps are the post pivot steps that can be rerun, in the order of the post pivot configuration
+var RerunSteps = []string{RerunStepManifests, RerunStepRegistry, RerunStepRestore}
+
+// Rerun replays the given post pivot step, or all the steps that can be rerun if none is given, on a booted upgraded
+// system, to recover from a partial post pivot failure without a rollback. The steps are idempotent, they run
+// regardless of the markers of the steps that already ran once, from the configuration left in the working dir by the
+// failed post pivot configuration.
+func (p *PostPivot) Rerun(ctx context.Context, step string) error {
+	steps := RerunSteps
+	if step != "" {
+		if !lo.Contains(RerunSteps, step) {
+			return fmt.Errorf("unknown post pivot step %q, valid steps are: %s", step, strings.Join(RerunSteps, ", "))
+		}
+		steps = []string{step}
+	}
+
+	seedReconfigurationFile := path.Join(p.workingDir, common.ClusterConfigDir, common.SeedClusterInfoFileName)
+	if _, err := os.Stat(seedReconfigurationFile); os.IsNotExist(err) {
+		return fmt.Errorf("no post pivot configuration found in %s, it is cleaned up once the post pivot "+
+			"configuration succeeds", p.workingDir)
+	}
+
+	p.log.Info("Reading seed reconfiguration info")
+	seedReconfiguration, err := utils.ReadSeedReconfigurationFromFile(seedReconfigurationFile)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster info from %s, err: %w", seedReconfigurationFile, err)
+	}
+
+	client, err := utils.CreateKubeClient(p.scheme, p.kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client, err: %w", err)
+	}
+	p.waitForApi(ctx, client)
+
+	for _, s := range steps {
+		p.log.Infof("Rerunning post pivot step %s", s)
+		switch s {
+		case RerunStepManifests:
+			err = p.replaceClusterManifests(ctx, client)
+		case RerunStepRegistry:
+			var seedClusterInfo *seedclusterinfo.SeedClusterInfo
+			seedClusterInfo, err = seedclusterinfo.ReadSeedClusterInfoFromFile(path.Join(common.SeedDataDir, common.SeedClusterInfoFileName))
+			if err != nil {
+				return fmt.Errorf("failed to get seed info from %s, err: %w", common.SeedDataDir, err)
+			}
+			err = p.changeRegistryInCSVDeployment(ctx, client, seedReconfiguration, seedClusterInfo)
+		case RerunStepRestore:
+			err = p.recoverLvmDevices(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to rerun post pivot step %s: %w", s, err)
+		}
+	}
+
+	p.log.Infof("Post pivot steps %s rerun successfully", strings.Join(steps, ", "))
+	return nil
+}
@@ -1,11 +1,46 @@
 package seedclusterinfo
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
 
 	"github.com/openshift-kni/lifecycle-agent/utils"
 )
 
+// CurrentVersion is the version of the seed cluster info written by this
+// lifecycle-agent. Renaming, removing or changing the meaning of a field
+// requires incrementing it and adding the migration of the previous version
+// to [migrations].
+const CurrentVersion = 4
+
+// migration converts the fields of a seed cluster info of one version to
+// those of the next version
+type migration func(fields map[string]json.RawMessage) error
+
+// migrations maps each version older than [CurrentVersion] to its migration
+var migrations = map[int]migration{
+	// Version 0 is the seed cluster info written before it was versioned,
+	// its fields are those of version 1
+	0: func(map[string]json.RawMessage) error { return nil },
+	// Version 1 does not record whether the seed cluster has a proxy, left
+	// unset
+	1: func(map[string]json.RawMessage) error { return nil },
+	// Version 2 does not record the FIPS mode, secure boot state and boot
+	// device layout of the seed cluster, left unset
+	2: func(map[string]json.RawMessage) error { return nil },
+	// Version 3 does not record the node name of the seed cluster, left
+	// unset
+	3: func(map[string]json.RawMessage) error { return nil },
+}
+
 // SeedClusterInfo is a struct that contains information about the seed cluster
 // that was used to create the seed image. It is meant to be serialized to a
 // file on the seed image. It has multiple purposes, see the documentation of
@@ -15,7 +50,12 @@ import (
 // backwards compatibilitiy with existing seed images. If you've made a
 // breaking change, you will need to increment the [SeedFormatVersion] constant
 // to avoid silently breakage and allow for backwards compatibility code.
+// Changes to the fields of the file itself are versioned by [CurrentVersion].
 type SeedClusterInfo struct {
+	// The version of the seed cluster info, see [CurrentVersion]. Seed
+	// cluster info files without a version are version 0.
+	Version int `json:"version,omitempty"`
+
 	// The OCP version of the seed cluster that was used to create this seed
 	// image. During an IBU, lifecycle-agent will compare the user's desired
 	// version with the seed cluster's version to ensure the image the user is
@@ -48,38 +88,159 @@ type SeedClusterInfo struct {
 	// TODO: Is this really necessary? Find a way to get rid of this
 	MirrorRegistryConfigured bool `json:"mirror_registry_configured,omitempty"`
 
+	// The mirror configuration of the seed cluster, from its
+	// ImageDigestMirrorSets and ImageContentSourcePolicies. Images pulled by
+	// the seed cluster through one of these mirrors are rewritten per
+	// repository on the target cluster, to the mirror configured there for the
+	// same source, or to the source itself. Seed images created before this
+	// field was added fall back to the ReleaseRegistry heuristic.
+	ImageMirrors []utils.ImageMirror `json:"image_mirrors,omitempty"`
+
 	// The hostname of the seed cluster's SNO node. This hostname is required
 	// when we ask recert to replace the original hostname in certificates, as
 	// recert needs both the original hostname and the new hostname to perform
 	// the replace.
 	SNOHostname string `json:"sno_hostname,omitempty"`
 
+	// The name of the seed cluster's SNO node. recert renames the node of
+	// the seed hostname, so the node of a seed cluster named after another
+	// name would be orphaned after the upgrade. Unset in the seed images
+	// created before this field was added.
+	SNONodeName string `json:"sno_node_name,omitempty"`
+
 	// The recert image pull-spec that was used by the seed cluster. This is
 	// used to run recert using the same version of recert that was used to
 	// create the seed image (the seed cluster runs recert to expire the
 	// certificates, so it has already proven to run successfully on the seed
 	// data).
 	RecertImagePullSpec string `json:"recert_image_pull_spec,omitempty"`
+
+	// Whether the seed cluster has a cluster-wide proxy. The seed image is
+	// labeled with it, for the Prep stage to reject a seed image whose proxy
+	// configuration differs from the one of the target cluster. Unset in the
+	// seed images created before this field was added.
+	HasProxy *bool `json:"has_proxy,omitempty"`
+
+	// Whether the seed cluster runs in FIPS mode, secure boot is enabled on
+	// its node, and the layout of the devices under its /boot, e.g. single
+	// or raid1. The Prep stage rejects a seed image whose stateroot would
+	// not boot or would change the security posture of the target host.
+	// Unset in the seed images created before these fields were added.
+	FIPS             *bool  `json:"fips,omitempty"`
+	SecureBoot       *bool  `json:"secure_boot,omitempty"`
+	BootDeviceLayout string `json:"boot_device_layout,omitempty"`
 }
 
 func NewFromClusterInfo(clusterInfo *utils.ClusterInfo, seedImagePullSpec string) *SeedClusterInfo {
+	hasProxy := clusterInfo.HasProxy
 	return &SeedClusterInfo{
+		Version:                  CurrentVersion,
 		SeedClusterOCPVersion:    clusterInfo.OCPVersion,
 		BaseDomain:               clusterInfo.BaseDomain,
 		ClusterName:              clusterInfo.ClusterName,
 		NodeIP:                   clusterInfo.NodeIP,
 		ReleaseRegistry:          clusterInfo.ReleaseRegistry,
 		SNOHostname:              clusterInfo.Hostname,
+		SNONodeName:              clusterInfo.NodeName,
 		MirrorRegistryConfigured: clusterInfo.MirrorRegistryConfigured,
+		ImageMirrors:             clusterInfo.ImageMirrors,
 		RecertImagePullSpec:      seedImagePullSpec,
+		HasProxy:                 &hasProxy,
 	}
 }
 
+// ReadSeedClusterInfoFromFile reads the seed cluster info of the file,
+// migrating it from older versions, and validates it. It fails on the fields
+// it does not know, so that a field renamed by a newer version is not
+// silently ignored.
 func ReadSeedClusterInfoFromFile(path string) (*SeedClusterInfo, error) {
-	data := &SeedClusterInfo{}
-	err := utils.ReadYamlOrJSONFile(path, data)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed cluster info file: %w", err)
+	}
+	info, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse seed cluster info file %s: %w", path, err)
+	}
+	return info, nil
+}
+
+// Parse decodes a seed cluster info in JSON or YAML, migrating it from older
+// versions, and validates it
+func Parse(data []byte) (*SeedClusterInfo, error) {
+	data, err := yaml.YAMLToJSON(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create seed cluster info from file %w", err)
+		return nil, fmt.Errorf("invalid seed cluster info: %w", err)
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid seed cluster info: %w", err)
+	}
+
+	version := 0
+	if raw, ok := fields["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("invalid seed cluster info version %s: %w", raw, err)
+		}
+	}
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("seed cluster info version %d is newer than the version %d supported by this "+
+			"lifecycle-agent, the seed image was created by a newer lifecycle-agent", version, CurrentVersion)
+	}
+	for ; version < CurrentVersion; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("seed cluster info version %d is no longer supported", version)
+		}
+		if err := migrate(fields); err != nil {
+			return nil, fmt.Errorf("failed to migrate seed cluster info from version %d: %w", version, err)
+		}
+	}
+	fields["version"] = json.RawMessage(fmt.Sprintf("%d", CurrentVersion))
+
+	migrated, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated seed cluster info: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(migrated))
+	decoder.DisallowUnknownFields()
+	info := &SeedClusterInfo{}
+	if err := decoder.Decode(info); err != nil {
+		return nil, fmt.Errorf("invalid seed cluster info version %d: %w", CurrentVersion, err)
+	}
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Validate checks the seed cluster info has the fields the upgrade and
+// installation rely on, returning an error describing all the invalid fields
+func (s *SeedClusterInfo) Validate() error {
+	var problems []string
+	for field, value := range map[string]string{
+		"seed_cluster_ocp_version": s.SeedClusterOCPVersion,
+		"base_domain":              s.BaseDomain,
+		"cluster_name":             s.ClusterName,
+		"node_ip":                  s.NodeIP,
+		"sno_hostname":             s.SNOHostname,
+		"recert_image_pull_spec":   s.RecertImagePullSpec,
+	} {
+		if value == "" {
+			problems = append(problems, fmt.Sprintf("%s is required", field))
+		}
+	}
+	if s.NodeIP != "" && net.ParseIP(s.NodeIP) == nil {
+		problems = append(problems, fmt.Sprintf("node_ip %q is not an IP address", s.NodeIP))
+	}
+	for i, mirror := range s.ImageMirrors {
+		if mirror.Source == "" {
+			problems = append(problems, fmt.Sprintf("image_mirrors[%d].source is required", i))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
 	}
-	return data, nil
+	sort.Strings(problems)
+	return errors.New("invalid seed cluster info: " + strings.Join(problems, ", "))
 }
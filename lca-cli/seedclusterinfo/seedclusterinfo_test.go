@@ -0,0 +1,136 @@
+package seedclusterinfo
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const legacySeedClusterInfo = `{"seed_cluster_ocp_version":"4.15.0","base_domain":"example.com","cluster_name":"seed",` +
+	`"node_ip":"192.168.127.10","release_registry":"quay.io","sno_hostname":"seed-sno",` +
+	`"recert_image_pull_spec":"quay.io/edge-infrastructure/recert:v0"}`
+
+func TestReadSeedClusterInfoFromFile(t *testing.T) {
+	info := NewFromClusterInfo(&utils.ClusterInfo{
+		OCPVersion:  "4.15.0",
+		BaseDomain:  "example.com",
+		ClusterName: "seed",
+		NodeIP:      "fd00::10",
+		Hostname:    "seed-sno",
+		NodeName:    "seed-sno",
+		ImageMirrors: []utils.ImageMirror{
+			{Source: "quay.io/openshift-release-dev", Mirrors: []string{"registry.example.com/ocp"}},
+		},
+	}, "quay.io/edge-infrastructure/recert:v0")
+	assert.Equal(t, CurrentVersion, info.Version)
+
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	assert.NoError(t, utils.MarshalToFile(info, path))
+	read, err := ReadSeedClusterInfoFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, info, read)
+
+	_, err = ReadSeedClusterInfoFromFile(filepath.Join(t.TempDir(), "manifest.json"))
+	assert.ErrorContains(t, err, "failed to read seed cluster info file")
+}
+
+func TestParse(t *testing.T) {
+	testcases := []struct {
+		name        string
+		data        string
+		hasProxy    *bool
+		expectedErr string
+	}{
+		{
+			name: "unversioned",
+			data: legacySeedClusterInfo,
+		},
+		{
+			name: "yaml",
+			data: "version: 4\nhas_proxy: true\nseed_cluster_ocp_version: 4.15.0\nbase_domain: example.com\ncluster_name: seed\n" +
+				"node_ip: 192.168.127.10\nsno_hostname: seed-sno\nrecert_image_pull_spec: quay.io/edge-infrastructure/recert:v0\n" +
+				"fips: true\nsecure_boot: false\nboot_device_layout: raid1\nsno_node_name: seed-sno\n",
+			hasProxy: lo.ToPtr(true),
+		},
+		{
+			name: "version 2",
+			data: `{"version":2,"has_proxy":false,"seed_cluster_ocp_version":"4.15.0","base_domain":"example.com",` +
+				`"cluster_name":"seed","node_ip":"192.168.127.10","sno_hostname":"seed-sno",` +
+				`"recert_image_pull_spec":"quay.io/edge-infrastructure/recert:v0"}`,
+			hasProxy: lo.ToPtr(false),
+		},
+		{
+			name:        "newer version",
+			data:        `{"version":5}`,
+			expectedErr: "seed cluster info version 5 is newer than the version 4 supported by this lifecycle-agent",
+		},
+		{
+			name:        "invalid version",
+			data:        `{"version":"v1"}`,
+			expectedErr: `invalid seed cluster info version "v1"`,
+		},
+		{
+			name:        "renamed field",
+			data:        `{"version":2,"ocp_version":"4.15.0"}`,
+			expectedErr: `unknown field "ocp_version"`,
+		},
+		{
+			name: "missing fields",
+			data: `{"version":2,"seed_cluster_ocp_version":"4.15.0","node_ip":"seed","image_mirrors":[{"mirrors":["a"]}]}`,
+			expectedErr: "invalid seed cluster info: base_domain is required, cluster_name is required, " +
+				`image_mirrors[0].source is required, node_ip "seed" is not an IP address, ` +
+				"recert_image_pull_spec is required, sno_hostname is required",
+		},
+		{
+			name:        "not an object",
+			data:        `["4.15.0"]`,
+			expectedErr: "invalid seed cluster info",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, err := Parse([]byte(tc.data))
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, CurrentVersion, info.Version)
+			assert.Equal(t, "4.15.0", info.SeedClusterOCPVersion)
+			assert.Equal(t, "seed-sno", info.SNOHostname)
+			assert.Equal(t, tc.hasProxy, info.HasProxy)
+		})
+	}
+}
+
+func TestParseMigrations(t *testing.T) {
+	origMigrations := migrations
+	defer func() { migrations = origMigrations }()
+
+	// A version 0 field renamed by version 1
+	migrations = map[int]migration{
+		0: func(fields map[string]json.RawMessage) error {
+			fields["sno_hostname"] = fields["hostname"]
+			delete(fields, "hostname")
+			return nil
+		},
+		1: origMigrations[1],
+		2: origMigrations[2],
+		3: origMigrations[3],
+	}
+	data := []byte(`{"seed_cluster_ocp_version":"4.15.0","base_domain":"example.com","cluster_name":"seed",` +
+		`"node_ip":"192.168.127.10","hostname":"seed-sno","recert_image_pull_spec":"quay.io/edge-infrastructure/recert:v0"}`)
+	info, err := Parse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "seed-sno", info.SNOHostname)
+
+	migrations = map[int]migration{1: origMigrations[1], 2: origMigrations[2], 3: origMigrations[3]}
+	_, err = Parse(data)
+	assert.ErrorContains(t, err, "seed cluster info version 0 is no longer supported")
+}
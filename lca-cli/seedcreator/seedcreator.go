@@ -1,7 +1,9 @@
 package seedcreator
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -21,9 +23,13 @@ import (
 	runtime "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/hostcompat"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 	ostree "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedclusterinfo"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedpackages"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedprovenance"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedsbom"
 	"github.com/openshift-kni/lifecycle-agent/utils"
 )
 
@@ -38,6 +44,7 @@ type SeedCreator struct {
 	client               runtime.Client
 	log                  *logrus.Logger
 	ops                  ops.Ops
+	hostCommandsExecutor ops.Execute
 	ostreeClient         *ostree.Client
 	backupDir            string
 	kubeconfig           string
@@ -45,16 +52,19 @@ type SeedCreator struct {
 	authFile             string
 	recertContainerImage string
 	recertSkipValidation bool
+	// sbomFormat is the format of the SBOM attached to the seed image, none if empty
+	sbomFormat string
 }
 
 // NewSeedCreator is a constructor function for SeedCreator
-func NewSeedCreator(client runtime.Client, log *logrus.Logger, ops ops.Ops, ostreeClient *ostree.Client, backupDir,
-	kubeconfig, containerRegistry, authFile, recertContainerImage string, recertSkipValidation bool) *SeedCreator {
+func NewSeedCreator(client runtime.Client, log *logrus.Logger, ops ops.Ops, hostCommandsExecutor ops.Execute,
+	ostreeClient *ostree.Client, backupDir, kubeconfig, containerRegistry, authFile, recertContainerImage string, recertSkipValidation bool, sbomFormat string) *SeedCreator {
 
 	return &SeedCreator{
 		client:               client,
 		log:                  log,
 		ops:                  ops,
+		hostCommandsExecutor: hostCommandsExecutor,
 		ostreeClient:         ostreeClient,
 		backupDir:            backupDir,
 		kubeconfig:           kubeconfig,
@@ -62,6 +72,7 @@ func NewSeedCreator(client runtime.Client, log *logrus.Logger, ops ops.Ops, ostr
 		authFile:             authFile,
 		recertContainerImage: recertContainerImage,
 		recertSkipValidation: recertSkipValidation,
+		sbomFormat:           sbomFormat,
 	}
 }
 
@@ -70,7 +81,7 @@ func (s *SeedCreator) CreateSeedImage() error {
 	s.log.Info("Creating seed image")
 	ctx := context.TODO()
 
-	if err := s.copyConfigurationFiles(); err != nil {
+	if err := s.copyConfigurationFiles(ctx); err != nil {
 		return fmt.Errorf("failed to add configuration files: %w", err)
 	}
 
@@ -97,17 +108,21 @@ func (s *SeedCreator) CreateSeedImage() error {
 		return fmt.Errorf("failed to run once gather_cluster_info: %w", err)
 	}
 
+	if err := utils.RunOnce("record_packages", common.BackupChecksDir, s.log, s.recordPackages); err != nil {
+		return fmt.Errorf("failed to run once record_packages: %w", err)
+	}
+
 	if s.recertSkipValidation {
 		s.log.Info("Skipping seed certificates backing up.")
 	} else {
 		s.log.Info("Backing up seed cluster certificates for recert tool")
-		if err := utils.BackupKubeconfigCrypto(ctx, s.client, common.BackupCertsDir); err != nil {
+		if err := utils.BackupKubeconfigCrypto(ctx, s.client, common.BackupCertsDir, nil); err != nil {
 			return fmt.Errorf("failed to backing up seed cluster certificates for recert tool: %w", err)
 		}
 		s.log.Info("Seed cluster certificates backed up successfully for recert tool")
 	}
 
-	if err := s.stopServices(); err != nil {
+	if err := s.stopServices(ctx); err != nil {
 		return fmt.Errorf("failed to stop services to create seed image: %w", err)
 	}
 
@@ -142,19 +157,23 @@ func (s *SeedCreator) CreateSeedImage() error {
 		return fmt.Errorf("failed to run once backup_mco_config: %w", err)
 	}
 
-	if err := s.createAndPushSeedImage(); err != nil {
+	if err := s.writeProvenanceManifest(); err != nil {
+		return fmt.Errorf("failed to write seed provenance manifest: %w", err)
+	}
+
+	if err := s.createAndPushSeedImage(ctx); err != nil {
 		return fmt.Errorf("failed to create and push seed image: %w", err)
 	}
 
 	return nil
 }
 
-func (s *SeedCreator) copyConfigurationFiles() error {
+func (s *SeedCreator) copyConfigurationFiles(ctx context.Context) error {
 
-	return s.handleServices()
+	return s.handleServices(ctx)
 }
 
-func (s *SeedCreator) handleServices() error {
+func (s *SeedCreator) handleServices(ctx context.Context) error {
 	dir := filepath.Join(common.InstallationConfigurationFilesDir, "services")
 	return utils.HandleFilesWithCallback(dir, func(path string) error { //nolint:wrapcheck
 		serviceName := filepath.Base(path)
@@ -165,7 +184,7 @@ func (s *SeedCreator) handleServices() error {
 		}
 
 		s.log.Infof("Enabling service %s", serviceName)
-		if _, err := s.ops.SystemctlAction("enable", serviceName); err != nil {
+		if _, err := s.ops.SystemctlAction(ctx, "enable", serviceName); err != nil {
 			return fmt.Errorf("failed to enabling service %s: %w", serviceName, err)
 		}
 		return nil
@@ -181,6 +200,15 @@ func (s *SeedCreator) gatherClusterInfo(ctx context.Context) error {
 
 	seedClusterInfo := seedclusterinfo.NewFromClusterInfo(clusterInfo, s.recertContainerImage)
 
+	// The stateroot of the seed image only boots on a host with the same configuration, let Prep check it
+	hostInfo, err := hostcompat.Gather(s.hostCommandsExecutor)
+	if err != nil {
+		return fmt.Errorf("failed to get host info: %w", err)
+	}
+	seedClusterInfo.FIPS = &hostInfo.FIPS
+	seedClusterInfo.SecureBoot = &hostInfo.SecureBoot
+	seedClusterInfo.BootDeviceLayout = hostInfo.BootDeviceLayout
+
 	if err := os.MkdirAll(common.SeedDataDir, os.ModePerm); err != nil {
 		return fmt.Errorf("error creating SeedDataDir %s: %w", common.SeedDataDir, err)
 	}
@@ -201,6 +229,30 @@ func (s *SeedCreator) gatherClusterInfo(ctx context.Context) error {
 	return nil
 }
 
+// recordPackages saves the installed packages of the seed cluster, for the upgraded cluster to be compared with
+func (s *SeedCreator) recordPackages(ctx context.Context) error {
+	s.log.Info("Saving the list of installed packages")
+	manifest, err := seedpackages.Collect(func(command string, args ...string) (string, error) {
+		return s.ops.RunInHostNamespace(ctx, command, args...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to collect installed packages: %w", err)
+	}
+
+	p := path.Join(common.SeedDataDir, seedpackages.PackagesFileName)
+	if err := seedpackages.WriteManifest(manifest, p); err != nil {
+		return fmt.Errorf("failed to save installed packages: %w", err)
+	}
+
+	// the var archive carries the manifest to the upgraded cluster, keep it in the seed metadata too
+	dest := path.Join(s.backupDir, seedpackages.PackagesFileName)
+	if err := cp.Copy(p, dest); err != nil {
+		return fmt.Errorf("error copying from %s to %s: %w", p, dest, err)
+	}
+	s.log.Infof("Saved %d installed packages", len(manifest.Packages))
+	return nil
+}
+
 func (s *SeedCreator) createContainerList(ctx context.Context) error {
 	s.log.Info("Saving list of running containers and catalogsources.")
 	containersListFileName := s.backupDir + "/containers.list"
@@ -208,7 +260,7 @@ func (s *SeedCreator) createContainerList(ctx context.Context) error {
 	// purge all unknown image if exists
 	s.log.Info("Cleaning image list")
 	// Don't ever add -a option as we don't want to delete unused images
-	if _, err := s.ops.RunBashInHostNamespace("podman", "image", "prune", "-f"); err != nil {
+	if _, err := s.ops.RunBashInHostNamespace(ctx, "podman", "image", "prune", "-f"); err != nil {
 		return fmt.Errorf("failed to prune with podmamn: %w", err)
 	}
 
@@ -217,7 +269,7 @@ func (s *SeedCreator) createContainerList(ctx context.Context) error {
 	args := []string{"images", "-o", "json", "|", "jq", "-r",
 		"'.images[] | if .repoTags | length > 0 then .repoTags[] else .repoDigests[] end'"}
 
-	output, err := s.ops.RunBashInHostNamespace("crictl", args...)
+	output, err := s.ops.RunBashInHostNamespace(ctx, "crictl", args...)
 	if err != nil {
 		return fmt.Errorf("failed to run crictl with args %s: %w", args, err)
 	}
@@ -239,21 +291,21 @@ func (s *SeedCreator) createContainerList(ctx context.Context) error {
 	return nil
 }
 
-func (s *SeedCreator) stopServices() error {
+func (s *SeedCreator) stopServices(ctx context.Context) error {
 	s.log.Info("Stop kubelet service")
-	_, err := s.ops.SystemctlAction("stop", "kubelet.service")
+	_, err := s.ops.SystemctlAction(ctx, "stop", "kubelet.service")
 	if err != nil {
 		return fmt.Errorf("failed to stop kubelet: %w", err)
 	}
 
 	s.log.Info("Disabling kubelet service")
-	_, err = s.ops.SystemctlAction("disable", "kubelet.service")
+	_, err = s.ops.SystemctlAction(ctx, "disable", "kubelet.service")
 	if err != nil {
 		return fmt.Errorf("failed to disable kubelet: %w", err)
 	}
 
 	s.log.Info("Stopping containers and CRI-O runtime.")
-	crioSystemdStatus, err := s.ops.SystemctlAction("is-active", "crio")
+	crioSystemdStatus, err := s.ops.SystemctlAction(ctx, "is-active", "crio")
 	var exitErr *exec.ExitError
 	// If ExitCode is 3, the command succeeded and told us that crio is down
 	if err != nil && errors.As(err, &exitErr) && exitErr.ExitCode() != 3 {
@@ -265,7 +317,7 @@ func (s *SeedCreator) stopServices() error {
 		_ = wait.PollUntilContextCancel(context.TODO(), time.Second, true, func(ctx context.Context) (done bool, err error) {
 			s.log.Info("Stop running containers")
 			args := []string{"ps", "-q", "|", "xargs", "--no-run-if-empty", "--max-args", "1", "--max-procs", "10", "crictl", "stop", "--timeout", "5"}
-			_, err = s.ops.RunBashInHostNamespace("crictl", args...)
+			_, err = s.ops.RunBashInHostNamespace(ctx, "crictl", args...)
 			if err != nil {
 				return false, fmt.Errorf("failed to stop running containers: %w", err)
 			}
@@ -274,7 +326,7 @@ func (s *SeedCreator) stopServices() error {
 
 		// Execute a D-Bus call to stop the CRI-O runtime
 		s.log.Debug("Stopping CRI-O engine")
-		_, err = s.ops.SystemctlAction("stop", "crio.service")
+		_, err = s.ops.SystemctlAction(ctx, "stop", "crio.service")
 		if err != nil {
 			return fmt.Errorf("failed to stop crio engine: %w", err)
 		}
@@ -286,7 +338,7 @@ func (s *SeedCreator) stopServices() error {
 	return nil
 }
 
-func (s *SeedCreator) backupVar() error {
+func (s *SeedCreator) backupVar(ctx context.Context) error {
 	varTarFile := path.Join(s.backupDir, "var.tgz")
 
 	// Define the 'exclude' patterns
@@ -311,7 +363,7 @@ func (s *SeedCreator) backupVar() error {
 	tarArgs = append(tarArgs, "--selinux", common.VarFolder)
 
 	// Run the tar command
-	_, err := s.ops.RunBashInHostNamespace("tar", tarArgs...)
+	_, err := s.ops.RunBashInHostNamespace(ctx, "tar", tarArgs...)
 	if err != nil {
 		return fmt.Errorf("failed to run tar for backupVar: %w", err)
 	}
@@ -320,7 +372,7 @@ func (s *SeedCreator) backupVar() error {
 	return nil
 }
 
-func (s *SeedCreator) backupEtc() error {
+func (s *SeedCreator) backupEtc(ctx context.Context) error {
 	s.log.Info("Backing up /etc")
 
 	// Execute 'ostree admin config-diff' command and backup etc.deletions
@@ -336,7 +388,7 @@ func (s *SeedCreator) backupEtc() error {
 
 	args := []string{"admin", "config-diff", "|", "awk", `'$1 == "D" {print "/etc/" $2}'`, ">",
 		path.Join(s.backupDir, "/etc.deletions")}
-	_, err := s.ops.RunBashInHostNamespace("ostree", args...)
+	_, err := s.ops.RunBashInHostNamespace(ctx, "ostree", args...)
 	if err != nil {
 		return fmt.Errorf("failed backing up /etc with args %s: %w", args, err)
 	}
@@ -345,7 +397,7 @@ func (s *SeedCreator) backupEtc() error {
 		"|", "awk", `'$1 != "D" {print "/etc/" $2}'`, "|"}
 	args = append(args, tarArgs...)
 
-	_, err = s.ops.RunBashInHostNamespace("ostree", args...)
+	_, err = s.ops.RunBashInHostNamespace(ctx, "ostree", args...)
 	if err != nil {
 		return fmt.Errorf("failed backing up /etc with args %s: %w", args, err)
 	}
@@ -354,49 +406,64 @@ func (s *SeedCreator) backupEtc() error {
 	return nil
 }
 
-func (s *SeedCreator) backupOstree() error {
+func (s *SeedCreator) backupOstree(ctx context.Context) error {
 	s.log.Info("Backing up ostree")
 	ostreeTar := s.backupDir + "/ostree.tgz"
 
 	// Execute 'tar' command and backup /etc
 	args := []string{"czf", ostreeTar, "--selinux", "-C", "/ostree/repo", "."}
-	if _, err := s.ops.RunBashInHostNamespace("tar", args...); err != nil {
+	if _, err := s.ops.RunBashInHostNamespace(ctx, "tar", args...); err != nil {
 		return fmt.Errorf("failed backing ostree with args %s: %w", args, err)
 	}
 
 	return nil
 }
 
-func (s *SeedCreator) backupRPMOstree() error {
+func (s *SeedCreator) backupRPMOstree(ctx context.Context) error {
 	rpmJSON := s.backupDir + "/rpm-ostree.json"
 	args := append([]string{"status", "-v", "--json"}, ">", rpmJSON)
-	if _, err := s.ops.RunBashInHostNamespace("rpm-ostree", args...); err != nil {
+	if _, err := s.ops.RunBashInHostNamespace(ctx, "rpm-ostree", args...); err != nil {
 		return fmt.Errorf("failed to run backup rpmostree with args %s: %w", args, err)
 	}
 	s.log.Info("Backup of rpm-ostree.json created successfully.")
 	return nil
 }
 
-func (s *SeedCreator) backupMCOConfig() error {
+func (s *SeedCreator) backupMCOConfig(ctx context.Context) error {
 	mcoJSON := s.backupDir + "/mco-currentconfig.json"
-	if _, err := s.ops.RunBashInHostNamespace("cp", "/etc/machine-config-daemon/currentconfig", mcoJSON); err != nil {
+	if _, err := s.ops.RunBashInHostNamespace(ctx, "cp", "/etc/machine-config-daemon/currentconfig", mcoJSON); err != nil {
 		return fmt.Errorf("failed to backup MCO config: %w", err)
 	}
 	s.log.Info("Backup of mco-currentconfig created successfully.")
 	return nil
 }
 
+// writeProvenanceManifest records the source cluster of the seed image outside the seed content, to be published
+// along with the seed image for verification with the verify-seed command
+func (s *SeedCreator) writeProvenanceManifest() error {
+	manifest, err := seedprovenance.Generate(s.backupDir)
+	if err != nil {
+		return fmt.Errorf("failed to generate seed provenance manifest: %w", err)
+	}
+	if err := utils.MarshalToFile(manifest, common.SeedProvenanceFile); err != nil {
+		return fmt.Errorf("failed to write %s: %w", common.SeedProvenanceFile, err)
+	}
+	s.log.Infof("Seed provenance manifest saved in %s: ostree commit %s, image list digest %s, config digest %s",
+		common.SeedProvenanceFile, manifest.OstreeCommit, manifest.ImageListDigest, manifest.ConfigDigest)
+	return nil
+}
+
 // Building and pushing OCI image
-func (s *SeedCreator) createAndPushSeedImage() error {
+func (s *SeedCreator) createAndPushSeedImage(ctx context.Context) error {
 	s.log.Info("Build and push OCI image to ", s.containerRegistry)
-	s.log.Debug(s.ostreeClient.RpmOstreeVersion()) // If verbose, also dump out current rpm-ostree version available
+	s.log.Debug(s.ostreeClient.RpmOstreeVersion(ctx)) // If verbose, also dump out current rpm-ostree version available
 
 	// Get the current status of rpm-ostree daemon in the host
-	statusRpmOstree, err := s.ostreeClient.QueryStatus()
+	statusRpmOstree, err := s.ostreeClient.QueryStatus(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to query ostree status: %w", err)
 	}
-	if err := s.backupOstreeOrigin(statusRpmOstree); err != nil {
+	if err := s.backupOstreeOrigin(ctx, statusRpmOstree); err != nil {
 		return err
 	}
 
@@ -420,25 +487,200 @@ func (s *SeedCreator) createAndPushSeedImage() error {
 		"--file", tmpfile.Name(),
 		"--tag", s.containerRegistry,
 		"--label", fmt.Sprintf("%s=%d", common.SeedFormatOCILabel, common.SeedFormatVersion),
-		s.backupDir,
 	}
-	_, err = s.ops.RunInHostNamespace(
-		"podman", podmanBuildArgs...)
+	if s.sbomFormat != "" {
+		podmanBuildArgs = append(podmanBuildArgs, "--label", fmt.Sprintf("%s=%s", seedsbom.FormatOCILabel, s.sbomFormat))
+	}
+	// The proxy configuration of the seed cluster lets the Prep stage check it against the one of the target cluster
+	// right after pulling the seed image
+	seedInfo, err := seedclusterinfo.ReadSeedClusterInfoFromFile(path.Join(s.backupDir, common.SeedClusterInfoFileName))
+	if err != nil {
+		return fmt.Errorf("failed to read seed cluster info: %w", err)
+	}
+	if seedInfo.HasProxy != nil {
+		podmanBuildArgs = append(podmanBuildArgs, "--label", fmt.Sprintf("%s=%t", common.SeedHasProxyOCILabel, *seedInfo.HasProxy))
+	}
+	// The base OS version and FIPS mode of the seed cluster are reported in the status of the IBU
+	for _, deployment := range statusRpmOstree.Deployments {
+		if deployment.Booted && deployment.Version != "" {
+			podmanBuildArgs = append(podmanBuildArgs, "--label", fmt.Sprintf("%s=%s", common.SeedBaseOSVersionOCILabel, deployment.Version))
+		}
+	}
+	if seedInfo.FIPS != nil {
+		podmanBuildArgs = append(podmanBuildArgs, "--label", fmt.Sprintf("%s=%t", common.SeedFIPSOCILabel, *seedInfo.FIPS))
+	}
+	// The size of the precached images lets the Prep stage check the disk space before pulling the seed image
+	if precacheSize, err := s.precacheSize(ctx); err != nil {
+		s.log.Warnf("Failed to compute the size of the images to precache, not labeling the seed image with it: %v", err)
+	} else {
+		podmanBuildArgs = append(podmanBuildArgs, "--label", fmt.Sprintf("%s=%d", common.SeedPrecacheSizeOCILabel, precacheSize))
+	}
+	// The image list and cluster info of the seed image let the Idle stage audit the precaching without pulling it
+	if imageList, clusterInfo, err := s.precacheAuditLabels(); err != nil {
+		s.log.Warnf("Failed to read the precaching inputs, not labeling the seed image with them: %v", err)
+	} else {
+		podmanBuildArgs = append(podmanBuildArgs,
+			"--label", fmt.Sprintf("%s=%s", common.SeedPrecacheListOCILabel, imageList),
+			"--label", fmt.Sprintf("%s=%s", common.SeedClusterInfoOCILabel, clusterInfo))
+	}
+	_, err = s.ops.RunInHostNamespace(ctx,
+		"podman", append(podmanBuildArgs, s.backupDir)...)
 	if err != nil {
 		return fmt.Errorf("failed to build seed image: %w", err)
 	}
 
-	// Push the created OCI image to user's repository
-	_, err = s.ops.RunInHostNamespace(
-		"podman", []string{"push", "--authfile", s.authFile, s.containerRegistry}...)
+	// Push the created OCI image to user's repository, recording its digest for the SBOM to refer to
+	digestFile := tmpfile.Name() + ".digest"
+	defer os.Remove(digestFile)
+	_, err = s.ops.RunInHostNamespace(ctx,
+		"podman", []string{"push", "--authfile", s.authFile, "--digestfile", digestFile, s.containerRegistry}...)
 	if err != nil {
 		return fmt.Errorf("failed to push seed image: %w", err)
 	}
 
+	if s.sbomFormat != "" {
+		digest, err := os.ReadFile(digestFile)
+		if err != nil {
+			return fmt.Errorf("failed to read seed image digest: %w", err)
+		}
+		if err := s.attachSBOM(ctx, strings.TrimSpace(string(digest))); err != nil {
+			return fmt.Errorf("failed to attach SBOM to seed image: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// precacheSize returns the size in the container storage of the images of the seed image list
+func (s *SeedCreator) precacheSize(ctx context.Context) (int64, error) {
+	containersList, err := os.ReadFile(path.Join(s.backupDir, seedprovenance.ContainersListFileName))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the seed image list: %w", err)
+	}
+	listed := map[string]bool{}
+	for _, image := range strings.Split(string(containersList), "\n") {
+		if image = strings.TrimSpace(image); image != "" {
+			listed[image] = true
+		}
+	}
+
+	output, err := s.ops.RunInHostNamespace(ctx, "podman", "images", "--format", "json")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list images: %w", err)
+	}
+	var images []struct {
+		ID          string   `json:"Id"`
+		Names       []string `json:"Names"`
+		RepoDigests []string `json:"RepoDigests"`
+		Size        int64    `json:"Size"`
+	}
+	if err := json.Unmarshal([]byte(output), &images); err != nil {
+		return 0, fmt.Errorf("failed to parse images: %w", err)
+	}
+
+	var size int64
+	for _, image := range images {
+		for _, name := range append(image.Names, image.RepoDigests...) {
+			if listed[name] {
+				size += image.Size
+				break
+			}
+		}
+	}
+	return size, nil
+}
+
+// precacheAuditLabels returns the comma-separated images of the seed image list and the compacted seed cluster info,
+// as labeled on the seed image
+func (s *SeedCreator) precacheAuditLabels() (string, string, error) {
+	containersList, err := os.ReadFile(path.Join(s.backupDir, seedprovenance.ContainersListFileName))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read the seed image list: %w", err)
+	}
+	var images []string
+	for _, image := range strings.Split(string(containersList), "\n") {
+		if image = strings.TrimSpace(image); image != "" {
+			images = append(images, image)
+		}
+	}
+	clusterInfo, err := os.ReadFile(path.Join(s.backupDir, common.SeedClusterInfoFileName))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read seed cluster info: %w", err)
+	}
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, clusterInfo); err != nil {
+		return "", "", fmt.Errorf("failed to compact seed cluster info: %w", err)
+	}
+	return strings.Join(images, ","), compacted.String(), nil
+}
+
+// attachSBOM generates the SBOM of the pushed seed image and pushes it to the seed image repository as an artifact
+// referring to the seed image
+func (s *SeedCreator) attachSBOM(ctx context.Context, digest string) error {
+	s.log.Infof("Generating %s SBOM of the seed image", s.sbomFormat)
+	packages, err := seedpackages.ReadManifest(path.Join(s.backupDir, seedpackages.PackagesFileName))
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	seedInfo, err := seedclusterinfo.ReadSeedClusterInfoFromFile(path.Join(s.backupDir, common.SeedClusterInfoFileName))
+	if err != nil {
+		return fmt.Errorf("failed to read seed cluster info: %w", err)
+	}
+	containersList, err := os.ReadFile(path.Join(s.backupDir, seedprovenance.ContainersListFileName))
+	if err != nil {
+		return fmt.Errorf("failed to read the seed image list: %w", err)
+	}
+	created := time.Now()
+	sbom, err := seedsbom.Generate(s.sbomFormat, &seedsbom.Inputs{
+		SeedImage:  s.containerRegistry,
+		OCPVersion: seedInfo.SeedClusterOCPVersion,
+		Packages:   packages,
+		Images:     strings.Split(string(containersList), "\n"),
+		Created:    created,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate SBOM: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp("/var/tmp", "seed-sbom-")
+	if err != nil {
+		return fmt.Errorf("failed to create SBOM work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// The raw manifest is saved to a file, as the command output is trimmed
+	repository := utils.ImageRepository(s.containerRegistry)
+	manifestFile := filepath.Join(workDir, "seed-manifest.json")
+	if _, err := s.ops.RunBashInHostNamespace(ctx, "skopeo", "inspect", "--raw", "--authfile", s.authFile,
+		"docker://"+repository+"@"+digest, ">", manifestFile); err != nil {
+		return fmt.Errorf("failed to inspect seed image manifest: %w", err)
+	}
+	rawManifest, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return fmt.Errorf("failed to read seed image manifest: %w", err)
+	}
+	subject, err := seedsbom.SubjectDescriptor(rawManifest)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	if subject.Digest != digest {
+		return fmt.Errorf("seed image manifest digest %s differs from the pushed digest %s", subject.Digest, digest)
+	}
+
+	layoutDir := filepath.Join(workDir, "layout")
+	if err := seedsbom.WriteArtifactLayout(layoutDir, s.sbomFormat, sbom, subject, created); err != nil {
+		return err //nolint:wrapcheck
+	}
+	destination := repository + ":" + seedsbom.ReferrerTag(digest)
+	if _, err := s.ops.RunInHostNamespace(ctx, "skopeo", "copy", "--preserve-digests", "--dest-authfile", s.authFile,
+		"oci:"+layoutDir, "docker://"+destination); err != nil {
+		return fmt.Errorf("failed to push SBOM: %w", err)
+	}
+	s.log.Infof("Attached %s SBOM of %d bytes to the seed image as %s", s.sbomFormat, len(sbom), destination)
 	return nil
 }
 
-func (s *SeedCreator) backupOstreeOrigin(statusRpmOstree *ostree.Status) error {
+func (s *SeedCreator) backupOstreeOrigin(ctx context.Context, statusRpmOstree *ostree.Status) error {
 
 	// Get OSName for booted ostree deployment
 	bootedOSName := statusRpmOstree.Deployments[0].OSName
@@ -454,7 +696,7 @@ func (s *SeedCreator) backupOstreeOrigin(statusRpmOstree *ostree.Status) error {
 		return fmt.Errorf("failed to get file info for %s: %w", originFileName, err)
 	}
 	// Execute 'copy' command and backup .origin file
-	_, err = s.ops.RunInHostNamespace(
+	_, err = s.ops.RunInHostNamespace(ctx,
 		"cp", []string{"/ostree/deploy/" + bootedOSName + "/deploy/" + bootedDeployment + ".origin", originFileName}...)
 	if err != nil {
 		return fmt.Errorf("failed 'copy' command to backup .origin file,: %w", err)
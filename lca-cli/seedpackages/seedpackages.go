@@ -0,0 +1,154 @@
+// Package seedpackages records the RPM packages of the seed cluster in the seed image and compares them, after the
+// upgrade, with the packages of the booted system, to catch content drift such as locally layered packages.
+package seedpackages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const (
+	// PackagesFileName is the package manifest of the seed cluster, in the seed data dir and the seed image
+	PackagesFileName = "packages.json"
+
+	// queryFormat prints a package per line as "name epoch:version-release.arch"
+	queryFormat = `%{NAME} %{EPOCHNUM}:%{VERSION}-%{RELEASE}.%{ARCH}\n`
+)
+
+// Manifest is the set of the installed RPM packages, indexed by name. A name maps to several versions when multiple
+// versions of a package are installed side by side, as for the kernel.
+type Manifest struct {
+	Packages map[string][]string `json:"packages"`
+}
+
+// PackageChange is a package installed with different versions in the seed and the booted system
+type PackageChange struct {
+	Name   string   `json:"name"`
+	Seed   []string `json:"seed"`
+	Booted []string `json:"booted"`
+}
+
+// Report is the difference between the packages of the seed and the packages of the booted system
+type Report struct {
+	// Added are the packages of the booted system missing from the seed
+	Added []string `json:"added,omitempty"`
+	// Removed are the packages of the seed missing from the booted system
+	Removed []string        `json:"removed,omitempty"`
+	Changed []PackageChange `json:"changed,omitempty"`
+}
+
+// Collect lists the packages installed on the host with rpm
+func Collect(runInHostNamespace func(command string, args ...string) (string, error)) (*Manifest, error) {
+	output, err := runInHostNamespace("rpm", "-qa", "--queryformat", queryFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the installed packages: %w", err)
+	}
+	return parse(output)
+}
+
+func parse(output string) (*Manifest, error) {
+	manifest := &Manifest{Packages: make(map[string][]string)}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected rpm output line: %q", line)
+		}
+		manifest.Packages[fields[0]] = append(manifest.Packages[fields[0]], fields[1])
+	}
+	for _, versions := range manifest.Packages {
+		sort.Strings(versions)
+	}
+	return manifest, nil
+}
+
+// WriteManifest writes the package manifest to a file
+func WriteManifest(manifest *Manifest, filePath string) error {
+	if err := utils.MarshalToFile(manifest, filePath); err != nil {
+		return fmt.Errorf("failed to write package manifest to %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// ReadManifest reads a package manifest from a file
+func ReadManifest(filePath string) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := utils.ReadYamlOrJSONFile(filePath, manifest); err != nil {
+		return nil, fmt.Errorf("failed to read package manifest %s: %w", filePath, err)
+	}
+	return manifest, nil
+}
+
+// Diff compares the packages of the seed with the packages of the booted system. The versions of both manifests
+// are sorted, as done when collecting them.
+func Diff(seed, booted *Manifest) *Report {
+	report := &Report{}
+	for name, seedVersions := range seed.Packages {
+		bootedVersions, ok := booted.Packages[name]
+		if !ok {
+			report.Removed = append(report.Removed, name)
+			continue
+		}
+		if strings.Join(seedVersions, ",") != strings.Join(bootedVersions, ",") {
+			report.Changed = append(report.Changed, PackageChange{Name: name, Seed: seedVersions, Booted: bootedVersions})
+		}
+	}
+	for name := range booted.Packages {
+		if _, ok := seed.Packages[name]; !ok {
+			report.Added = append(report.Added, name)
+		}
+	}
+	sort.Strings(report.Added)
+	sort.Strings(report.Removed)
+	sort.Slice(report.Changed, func(i, j int) bool { return report.Changed[i].Name < report.Changed[j].Name })
+	return report
+}
+
+// HasDrift tells whether the booted system packages differ from the seed
+func (r *Report) HasDrift() bool {
+	return len(r.Added) != 0 || len(r.Removed) != 0 || len(r.Changed) != 0
+}
+
+// Summary is a one line description of the drift
+func (r *Report) Summary() string {
+	if !r.HasDrift() {
+		return "the booted system packages match the seed"
+	}
+	var parts []string
+	if len(r.Added) != 0 {
+		parts = append(parts, fmt.Sprintf("added: %s", strings.Join(r.Added, ", ")))
+	}
+	if len(r.Removed) != 0 {
+		parts = append(parts, fmt.Sprintf("removed: %s", strings.Join(r.Removed, ", ")))
+	}
+	if len(r.Changed) != 0 {
+		parts = append(parts, fmt.Sprintf("changed: %s", strings.Join(lo.Map(r.Changed, func(c PackageChange, _ int) string {
+			return fmt.Sprintf("%s (%s -> %s)", c.Name, strings.Join(c.Seed, ","), strings.Join(c.Booted, ","))
+		}), ", ")))
+	}
+	return fmt.Sprintf("the booted system packages differ from the seed, %s", strings.Join(parts, "; "))
+}
+
+// WriteReport writes the package report to a file
+func WriteReport(report *Report, filePath string) error {
+	if err := utils.MarshalToFile(report, filePath); err != nil {
+		return fmt.Errorf("failed to write package report to %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// ReadReport reads a package report from a file
+func ReadReport(filePath string) (*Report, error) {
+	report := &Report{}
+	if err := utils.ReadYamlOrJSONFile(filePath, report); err != nil {
+		return nil, fmt.Errorf("failed to read package report %s: %w", filePath, err)
+	}
+	return report, nil
+}
@@ -0,0 +1,69 @@
+package seedpackages
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollect(t *testing.T) {
+	output := `bash 0:5.1.8-6.el9.x86_64
+kernel 0:5.14.0-284.40.1.el9_2.x86_64
+kernel 0:5.14.0-284.30.1.el9_2.x86_64
+`
+	var gotArgs []string
+	manifest, err := Collect(func(command string, args ...string) (string, error) {
+		gotArgs = append([]string{command}, args...)
+		return output, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rpm", "-qa", "--queryformat", queryFormat}, gotArgs)
+	assert.Equal(t, map[string][]string{
+		"bash":   {"0:5.1.8-6.el9.x86_64"},
+		"kernel": {"0:5.14.0-284.30.1.el9_2.x86_64", "0:5.14.0-284.40.1.el9_2.x86_64"},
+	}, manifest.Packages)
+
+	_, err = Collect(func(string, ...string) (string, error) { return "", errors.New("rpm failed") })
+	assert.ErrorContains(t, err, "rpm failed")
+
+	_, err = Collect(func(string, ...string) (string, error) { return "bash", nil })
+	assert.ErrorContains(t, err, "unexpected rpm output line")
+}
+
+func TestDiff(t *testing.T) {
+	seed := &Manifest{Packages: map[string][]string{
+		"bash":    {"0:5.1.8-6.el9.x86_64"},
+		"kernel":  {"0:5.14.0-284.40.1.el9_2.x86_64"},
+		"removed": {"0:1.0-1.el9.noarch"},
+	}}
+
+	report := Diff(seed, seed)
+	assert.False(t, report.HasDrift())
+	assert.Equal(t, "the booted system packages match the seed", report.Summary())
+
+	booted := &Manifest{Packages: map[string][]string{
+		"bash":    {"0:5.1.8-6.el9.x86_64"},
+		"kernel":  {"0:5.14.0-284.30.1.el9_2.x86_64", "0:5.14.0-284.40.1.el9_2.x86_64"},
+		"layered": {"0:2.0-1.el9.x86_64"},
+	}}
+	report = Diff(seed, booted)
+	assert.True(t, report.HasDrift())
+	assert.Equal(t, []string{"layered"}, report.Added)
+	assert.Equal(t, []string{"removed"}, report.Removed)
+	assert.Equal(t, []PackageChange{{
+		Name:   "kernel",
+		Seed:   []string{"0:5.14.0-284.40.1.el9_2.x86_64"},
+		Booted: []string{"0:5.14.0-284.30.1.el9_2.x86_64", "0:5.14.0-284.40.1.el9_2.x86_64"},
+	}}, report.Changed)
+	assert.Equal(t, "the booted system packages differ from the seed, added: layered; removed: removed; "+
+		"changed: kernel (0:5.14.0-284.40.1.el9_2.x86_64 -> 0:5.14.0-284.30.1.el9_2.x86_64,0:5.14.0-284.40.1.el9_2.x86_64)",
+		report.Summary())
+
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	assert.NoError(t, WriteReport(report, reportFile))
+	read, err := ReadReport(reportFile)
+	assert.NoError(t, err)
+	assert.Equal(t, report, read)
+}
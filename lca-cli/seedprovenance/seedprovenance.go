@@ -0,0 +1,186 @@
+package seedprovenance
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	rpmostreeclient "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedclusterinfo"
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const (
+	// ContainersListFileName is the image list to precache, in the seed image
+	ContainersListFileName = "containers.list"
+	// RPMOstreeStatusFileName is the rpm-ostree status of the seed cluster, in the seed image
+	RPMOstreeStatusFileName = "rpm-ostree.json"
+	// MCOConfigFileName is the current MachineConfig of the seed cluster, in the seed image
+	MCOConfigFileName = "mco-currentconfig.json"
+	// OstreeRepoArchiveFileName is the ostree repository of the seed cluster, in the seed image
+	OstreeRepoArchiveFileName = "ostree.tgz"
+)
+
+// configFiles are the seed cluster configuration files covered by the config digest
+var configFiles = []string{common.SeedClusterInfoFileName, MCOConfigFileName}
+
+// Manifest records the source cluster a seed image was generated from, so that a seed built by a third party can be
+// verified against it
+type Manifest struct {
+	SeedClusterOCPVersion string `json:"seed_cluster_ocp_version"`
+	// OstreeCommit is the ostree commit of the booted deployment of the seed cluster
+	OstreeCommit string `json:"ostree_commit"`
+	// ImageListDigest is the sha256 digest of the image list to precache
+	ImageListDigest string `json:"image_list_digest"`
+	// ConfigDigest is the sha256 digest of the seed cluster info and MachineConfig files
+	ConfigDigest string `json:"config_digest"`
+}
+
+// Generate computes the provenance manifest from the seed content in seedDir
+func Generate(seedDir string) (*Manifest, error) {
+	ci := &seedclusterinfo.SeedClusterInfo{}
+	if err := utils.ReadYamlOrJSONFile(filepath.Join(seedDir, common.SeedClusterInfoFileName), ci); err != nil {
+		return nil, fmt.Errorf("failed to read seed cluster info: %w", err)
+	}
+
+	commit, err := getBootedCommit(filepath.Join(seedDir, RPMOstreeStatusFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	imageListDigest, err := digestFiles(seedDir, ContainersListFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	configDigest, err := digestFiles(seedDir, configFiles...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manifest{
+		SeedClusterOCPVersion: ci.SeedClusterOCPVersion,
+		OstreeCommit:          commit,
+		ImageListDigest:       imageListDigest,
+		ConfigDigest:          configDigest,
+	}, nil
+}
+
+// Verify checks the seed content in seedDir corresponds to the expected manifest, including that the seed ostree
+// repository holds the recorded commit. All the mismatches are returned
+func Verify(expected *Manifest, seedDir string) error {
+	actual, err := Generate(seedDir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if actual.SeedClusterOCPVersion != expected.SeedClusterOCPVersion {
+		errs = append(errs, fmt.Errorf("seed cluster OCP version mismatch: expected %s, found %s",
+			expected.SeedClusterOCPVersion, actual.SeedClusterOCPVersion))
+	}
+	if actual.OstreeCommit != expected.OstreeCommit {
+		errs = append(errs, fmt.Errorf("ostree commit mismatch: expected %s, found %s", expected.OstreeCommit, actual.OstreeCommit))
+	} else if err := checkOstreeRepoHasCommit(filepath.Join(seedDir, OstreeRepoArchiveFileName), expected.OstreeCommit); err != nil {
+		errs = append(errs, err)
+	}
+	if actual.ImageListDigest != expected.ImageListDigest {
+		errs = append(errs, fmt.Errorf("image list digest mismatch: expected %s, found %s", expected.ImageListDigest, actual.ImageListDigest))
+	}
+	if actual.ConfigDigest != expected.ConfigDigest {
+		errs = append(errs, fmt.Errorf("config digest mismatch: expected %s, found %s", expected.ConfigDigest, actual.ConfigDigest))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ReadManifest reads a provenance manifest file
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed provenance manifest %s: %w", path, err)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode seed provenance manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+func getBootedCommit(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed reading %s: %w", path, err)
+	}
+	var status rpmostreeclient.Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return "", fmt.Errorf("failed unmarshalling %s: %w", path, err)
+	}
+	for _, deploy := range status.Deployments {
+		if deploy.Booted {
+			return deploy.Checksum, nil
+		}
+	}
+	return "", fmt.Errorf("failed finding booted deployment in %s", path)
+}
+
+// digestFiles returns the sha256 digest over the content of the given files, each prefixed by its name
+func digestFiles(dir string, names ...string) (string, error) {
+	h := sha256.New()
+	for _, name := range names {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		_, _ = io.WriteString(h, name+"\x00")
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkOstreeRepoHasCommit looks for the commit object in the ostree repository archive
+func checkOstreeRepoHasCommit(archive, commit string) error {
+	if len(commit) < 3 {
+		return fmt.Errorf("invalid ostree commit %q", commit)
+	}
+	commitObject := filepath.Join("objects", commit[:2], commit[2:]+".commit")
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", archive, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", archive, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("ostree commit %s not found in %s", commit, archive)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", archive, err)
+		}
+		if filepath.Clean(strings.TrimPrefix(hdr.Name, "./")) == commitObject {
+			return nil
+		}
+	}
+}
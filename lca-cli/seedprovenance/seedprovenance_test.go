@@ -0,0 +1,115 @@
+package seedprovenance
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+const testCommit = "ed4ab3244a76c6503a21441da650634b5abd25aba4255ca116782b2b3020519c"
+
+func writeOstreeArchive(t *testing.T, path string, names ...string) {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+	for _, name := range names {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: 0, Typeflag: tar.TypeReg}))
+	}
+}
+
+func writeSeedContent(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		common.SeedClusterInfoFileName: `{"seed_cluster_ocp_version":"4.15.0"}`,
+		RPMOstreeStatusFileName: `{"deployments":[{"id":"rhcos-other.0","checksum":"other","booted":false},` +
+			`{"id":"rhcos-` + testCommit + `.1","checksum":"` + testCommit + `","booted":true}]}`,
+		MCOConfigFileName:      `{"spec":{"kernelArguments":["nosmt"]}}`,
+		ContainersListFileName: "quay.io/openshift/a@sha256:1234\nquay.io/openshift/b:v1",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	writeOstreeArchive(t, filepath.Join(dir, OstreeRepoArchiveFileName),
+		"./config", "./objects/ed/"+testCommit[2:]+".commit", "./objects/ab/cdef.file")
+	return dir
+}
+
+func TestGenerate(t *testing.T) {
+	dir := writeSeedContent(t)
+
+	manifest, err := Generate(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "4.15.0", manifest.SeedClusterOCPVersion)
+	assert.Equal(t, testCommit, manifest.OstreeCommit)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", manifest.ImageListDigest)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", manifest.ConfigDigest)
+
+	// Generation is reproducible
+	again, err := Generate(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest, again)
+
+	_, err = Generate(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	testCases := []struct {
+		name        string
+		modify      func(dir string)
+		expectedErr string
+	}{
+		{
+			name: "seed matches manifest",
+		},
+		{
+			name: "image list changed",
+			modify: func(dir string) {
+				_ = os.WriteFile(filepath.Join(dir, ContainersListFileName), []byte("quay.io/openshift/c:v1"), 0o600)
+			},
+			expectedErr: "image list digest mismatch",
+		},
+		{
+			name: "config changed",
+			modify: func(dir string) {
+				_ = os.WriteFile(filepath.Join(dir, MCOConfigFileName), []byte(`{"spec":{}}`), 0o600)
+			},
+			expectedErr: "config digest mismatch",
+		},
+		{
+			name: "ostree repository without the commit",
+			modify: func(dir string) {
+				writeOstreeArchive(t, filepath.Join(dir, OstreeRepoArchiveFileName), "./config")
+			},
+			expectedErr: "ostree commit " + testCommit + " not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeSeedContent(t)
+			expected, err := Generate(dir)
+			assert.NoError(t, err)
+
+			if tc.modify != nil {
+				tc.modify(dir)
+			}
+			err = Verify(expected, dir)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package seedsbom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	mediaTypeOCIManifest = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex    = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIEmpty    = "application/vnd.oci.empty.v1+json"
+
+	// annotationTitle names the SBOM file of the artifact layer
+	annotationTitle   = "org.opencontainers.image.title"
+	annotationCreated = "org.opencontainers.image.created"
+
+	// referrerTagSuffix distinguishes the SBOM referrer tag from the other artifacts attached to the seed image
+	referrerTagSuffix = ".sbom"
+)
+
+// emptyConfig is the config blob of the OCI artifacts
+var emptyConfig = []byte("{}")
+
+// Descriptor is an OCI content descriptor
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest, carrying an artifact when its artifact type is set
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Subject       *Descriptor       `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// ReferrerTag returns the tag of the SBOM attached to the seed image of the given digest. Registries implementing the
+// OCI referrers API also list the SBOM as a referrer of the seed image, from the subject of its manifest
+func ReferrerTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + referrerTagSuffix
+}
+
+// SubjectDescriptor returns the descriptor of the raw manifest of the seed image, the subject of its SBOM
+func SubjectDescriptor(rawManifest []byte) (*Descriptor, error) {
+	m := &struct {
+		MediaType string `json:"mediaType"`
+	}{}
+	if err := json.Unmarshal(rawManifest, m); err != nil {
+		return nil, fmt.Errorf("failed to parse seed image manifest: %w", err)
+	}
+	mediaType := m.MediaType
+	if mediaType == "" {
+		mediaType = mediaTypeOCIManifest
+	}
+	return &Descriptor{MediaType: mediaType, Digest: digestOf(rawManifest), Size: int64(len(rawManifest))}, nil
+}
+
+// WriteArtifactLayout writes the SBOM as an OCI artifact referring to the subject, in an OCI image layout in dir, to
+// be copied to the registry of the seed image
+func WriteArtifactLayout(dir, format string, sbom []byte, subject *Descriptor, created time.Time) error {
+	for _, d := range []string{dir, filepath.Join(dir, "blobs", "sha256")} {
+		if err := os.MkdirAll(d, 0o700); err != nil {
+			return fmt.Errorf("failed to create %s: %w", d, err)
+		}
+	}
+
+	mediaType := MediaType(format)
+	manifest := &Manifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeOCIManifest,
+		ArtifactType:  mediaType,
+		Config:        Descriptor{MediaType: mediaTypeOCIEmpty, Digest: digestOf(emptyConfig), Size: int64(len(emptyConfig))},
+		Layers: []Descriptor{{
+			MediaType:   mediaType,
+			Digest:      digestOf(sbom),
+			Size:        int64(len(sbom)),
+			Annotations: map[string]string{annotationTitle: FileName(format)},
+		}},
+		Subject:     subject,
+		Annotations: map[string]string{annotationCreated: created.UTC().Format(time.RFC3339)},
+	}
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SBOM artifact manifest: %w", err)
+	}
+
+	for _, blob := range [][]byte{emptyConfig, sbom, manifestData} {
+		if err := writeBlob(dir, blob); err != nil {
+			return err
+		}
+	}
+	index, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     mediaTypeOCIIndex,
+		"manifests": []Descriptor{{
+			MediaType: mediaTypeOCIManifest, Digest: digestOf(manifestData), Size: int64(len(manifestData)),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal SBOM artifact index: %w", err)
+	}
+	for name, content := range map[string][]byte{
+		"oci-layout": []byte(`{"imageLayoutVersion":"1.0.0"}`),
+		"index.json": index,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ReadArtifact returns the SBOM and its format from an artifact copied to dir with the dir transport of skopeo,
+// which names the blobs by the hex of their digest
+func ReadArtifact(dir string) ([]byte, string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read SBOM artifact manifest: %w", err)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, "", fmt.Errorf("failed to parse SBOM artifact manifest: %w", err)
+	}
+	for _, layer := range manifest.Layers {
+		format := ""
+		switch layer.MediaType {
+		case MediaTypeSPDX:
+			format = FormatSPDX
+		case MediaTypeCycloneDX:
+			format = FormatCycloneDX
+		default:
+			continue
+		}
+		sbom, err := os.ReadFile(filepath.Join(dir, strings.TrimPrefix(layer.Digest, "sha256:")))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read SBOM blob: %w", err)
+		}
+		if digestOf(sbom) != layer.Digest {
+			return nil, "", fmt.Errorf("SBOM blob does not match its digest %s", layer.Digest)
+		}
+		return sbom, format, nil
+	}
+	return nil, "", fmt.Errorf("no SBOM layer in the artifact")
+}
+
+func writeBlob(dir string, blob []byte) error {
+	blobPath := filepath.Join(dir, "blobs", "sha256", strings.TrimPrefix(digestOf(blob), "sha256:"))
+	if err := os.WriteFile(blobPath, blob, 0o600); err != nil {
+		return fmt.Errorf("failed to write blob %s: %w", blobPath, err)
+	}
+	return nil
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,308 @@
+// Package seedsbom generates the Software Bill of Materials of a seed image, listing the RPM packages of the seed
+// cluster and the container images embedded for precaching, in SPDX or CycloneDX JSON. The SBOM is attached to the
+// seed image in its registry as a referrer artifact, which the Prep stage fetches for compliance scanning.
+package seedsbom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedpackages"
+	"github.com/openshift-kni/lifecycle-agent/utils"
+)
+
+const (
+	// FormatSPDX and FormatCycloneDX are the supported SBOM formats
+	FormatSPDX      = "spdx"
+	FormatCycloneDX = "cyclonedx"
+
+	// MediaTypeSPDX and MediaTypeCycloneDX are the media types of the SBOM artifacts
+	MediaTypeSPDX      = "application/spdx+json"
+	MediaTypeCycloneDX = "application/vnd.cyclonedx+json"
+
+	// FormatOCILabel is the seed image label naming the format of the SBOM attached to the seed image
+	FormatOCILabel = "com.openshift.lifecycle-agent.seed_sbom_format"
+
+	// rpmNamespace is the purl namespace of the RHCOS packages
+	rpmNamespace = "redhat"
+	// toolName is the creator of the SBOM
+	toolName = "lca-cli"
+)
+
+// Inputs is the content of the seed image described by its SBOM
+type Inputs struct {
+	// SeedImage is the reference of the seed image
+	SeedImage string
+	// OCPVersion is the OCP version of the seed cluster
+	OCPVersion string
+	Packages   *seedpackages.Manifest
+	// Images are the container images embedded in the seed image for precaching
+	Images []string
+	// Created is the creation time of the SBOM
+	Created time.Time
+}
+
+// ParseFormat returns the SBOM format of the given name, case insensitive
+func ParseFormat(name string) (string, error) {
+	switch format := strings.ToLower(name); format {
+	case FormatSPDX, FormatCycloneDX:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported SBOM format %q, must be %s or %s", name, FormatSPDX, FormatCycloneDX)
+	}
+}
+
+// MediaType returns the media type of the SBOM format
+func MediaType(format string) string {
+	if format == FormatCycloneDX {
+		return MediaTypeCycloneDX
+	}
+	return MediaTypeSPDX
+}
+
+// FileName returns the file name of the SBOM format
+func FileName(format string) string {
+	if format == FormatCycloneDX {
+		return "sbom.cdx.json"
+	}
+	return "sbom.spdx.json"
+}
+
+// Generate renders the SBOM of the seed image in the given format
+func Generate(format string, inputs *Inputs) ([]byte, error) {
+	var document any
+	switch format {
+	case FormatSPDX:
+		document = spdxDocument(inputs)
+	case FormatCycloneDX:
+		document = cycloneDXDocument(inputs)
+	default:
+		return nil, fmt.Errorf("unsupported SBOM format %q", format)
+	}
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s SBOM: %w", format, err)
+	}
+	return data, nil
+}
+
+// component is a package or an image of the seed image, in the terms shared by both formats
+type component struct {
+	name    string
+	version string
+	purl    string
+	image   bool
+}
+
+// components lists the packages, sorted by name and version, then the images, sorted by reference
+func components(inputs *Inputs) []component {
+	var result []component
+	if inputs.Packages != nil {
+		names := make([]string, 0, len(inputs.Packages.Packages))
+		for name := range inputs.Packages.Packages {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, evra := range inputs.Packages.Packages[name] {
+				version, purl := rpmPurl(name, evra)
+				result = append(result, component{name: name, version: version, purl: purl})
+			}
+		}
+	}
+	images := append([]string{}, inputs.Images...)
+	sort.Strings(images)
+	for _, image := range images {
+		if image = strings.TrimSpace(image); image == "" {
+			continue
+		}
+		version, purl := ociPurl(image)
+		result = append(result, component{name: image, version: version, purl: purl, image: true})
+	}
+	return result
+}
+
+// rpmPurl returns the version and the package URL of a package recorded as epoch:version-release.arch
+func rpmPurl(name, evra string) (string, string) {
+	epoch, vra, found := strings.Cut(evra, ":")
+	if !found {
+		epoch, vra = "0", evra
+	}
+	version, arch := vra, ""
+	if i := strings.LastIndex(vra, "."); i >= 0 {
+		version, arch = vra[:i], vra[i+1:]
+	}
+
+	qualifiers := url.Values{}
+	if arch != "" && arch != "(none)" {
+		qualifiers.Set("arch", arch)
+	}
+	if epoch != "0" {
+		qualifiers.Set("epoch", epoch)
+	}
+	purl := fmt.Sprintf("pkg:rpm/%s/%s@%s", rpmNamespace, url.PathEscape(name), url.PathEscape(version))
+	if len(qualifiers) != 0 {
+		purl += "?" + qualifiers.Encode()
+	}
+	return version, purl
+}
+
+// ociPurl returns the version, the digest or tag, and the package URL of an image reference
+func ociPurl(image string) (string, string) {
+	repository := utils.ImageRepository(image)
+	name := repository[strings.LastIndex(repository, "/")+1:]
+	qualifiers := url.Values{}
+	qualifiers.Set("repository_url", repository)
+
+	purl := "pkg:oci/" + url.PathEscape(name)
+	if _, digest, found := strings.Cut(image, "@"); found {
+		purl += "@" + url.PathEscape(digest)
+		return digest, purl + "?" + qualifiers.Encode()
+	}
+	tag := strings.TrimPrefix(strings.TrimPrefix(image, repository), ":")
+	if tag != "" {
+		qualifiers.Set("tag", tag)
+	}
+	return tag, purl + "?" + qualifiers.Encode()
+}
+
+type spdxDoc struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	PrimaryPurpose   string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func spdxDocument(inputs *Inputs) *spdxDoc {
+	const seedID = "SPDXRef-Seed"
+	doc := &spdxDoc{
+		SPDXVersion: "SPDX-2.3",
+		DataLicense: "CC0-1.0",
+		SPDXID:      "SPDXRef-DOCUMENT",
+		Name:        inputs.SeedImage,
+		CreationInfo: spdxCreationInfo{
+			Created:  inputs.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + toolName},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:           seedID,
+			Name:             inputs.SeedImage,
+			VersionInfo:      inputs.OCPVersion,
+			DownloadLocation: "NOASSERTION",
+			PrimaryPurpose:   "CONTAINER",
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: seedID,
+		}},
+	}
+	for i, c := range components(inputs) {
+		id := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		pkg := spdxPackage{
+			SPDXID:           id,
+			Name:             c.name,
+			VersionInfo:      c.version,
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: c.purl,
+			}},
+		}
+		if c.image {
+			pkg.PrimaryPurpose = "CONTAINER"
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID: seedID, RelationshipType: "CONTAINS", RelatedSPDXElement: id,
+		})
+	}
+	// The namespace only has to be unique to the document, its content makes it so
+	packages, _ := json.Marshal(doc.Packages)
+	sum := sha256.Sum256(append([]byte(inputs.SeedImage+" "+doc.CreationInfo.Created+" "), packages...))
+	doc.DocumentNamespace = fmt.Sprintf("https://github.com/openshift-kni/lifecycle-agent/spdx/%s-%s",
+		url.PathEscape(inputs.SeedImage), hex.EncodeToString(sum[:8]))
+	return doc
+}
+
+type cycloneDXDoc struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    cycloneDXMetadata    `json:"metadata"`
+	Components  []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     cycloneDXTools     `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Purl    string `json:"purl,omitempty"`
+}
+
+func cycloneDXDocument(inputs *Inputs) *cycloneDXDoc {
+	doc := &cycloneDXDoc{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: inputs.Created.UTC().Format(time.RFC3339),
+			Tools:     cycloneDXTools{Components: []cycloneDXComponent{{Type: "application", Name: toolName}}},
+			Component: cycloneDXComponent{Type: "container", Name: inputs.SeedImage, Version: inputs.OCPVersion},
+		},
+		Components: []cycloneDXComponent{},
+	}
+	for _, c := range components(inputs) {
+		componentType := "library"
+		if c.image {
+			componentType = "container"
+		}
+		doc.Components = append(doc.Components, cycloneDXComponent{
+			Type: componentType, Name: c.name, Version: c.version, Purl: c.purl,
+		})
+	}
+	return doc
+}
@@ -0,0 +1,146 @@
+package seedsbom
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/seedpackages"
+)
+
+func testInputs() *Inputs {
+	return &Inputs{
+		SeedImage:  "quay.io/org/seed:4.15.0",
+		OCPVersion: "4.15.0",
+		Packages: &seedpackages.Manifest{Packages: map[string][]string{
+			"bash":       {"0:5.1.8-6.el9.x86_64"},
+			"kernel":     {"0:5.14.0-284.el9.x86_64", "0:5.14.0-362.el9.x86_64"},
+			"gpg-pubkey": {"0:fd431d51-4ae0493b.(none)"},
+			"shadow":     {"2:4.9-6.el9.x86_64"},
+		}},
+		Images: []string{
+			"quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:1234",
+			"registry.example.com:5000/org/recert:latest",
+			"",
+		},
+		Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("SPDX")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatSPDX, format)
+	format, err = ParseFormat("CycloneDX")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCycloneDX, format)
+	_, err = ParseFormat("syft")
+	assert.ErrorContains(t, err, `unsupported SBOM format "syft"`)
+}
+
+func TestPurls(t *testing.T) {
+	version, purl := rpmPurl("shadow", "2:4.9-6.el9.x86_64")
+	assert.Equal(t, "4.9-6.el9", version)
+	assert.Equal(t, "pkg:rpm/redhat/shadow@4.9-6.el9?arch=x86_64&epoch=2", purl)
+	_, purl = rpmPurl("gpg-pubkey", "0:fd431d51-4ae0493b.(none)")
+	assert.Equal(t, "pkg:rpm/redhat/gpg-pubkey@fd431d51-4ae0493b", purl)
+
+	version, purl = ociPurl("quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:1234")
+	assert.Equal(t, "sha256:1234", version)
+	assert.Equal(t,
+		"pkg:oci/ocp-v4.0-art-dev@sha256:1234?repository_url=quay.io%2Fopenshift-release-dev%2Focp-v4.0-art-dev", purl)
+	version, purl = ociPurl("registry.example.com:5000/org/recert:latest")
+	assert.Equal(t, "latest", version)
+	assert.Equal(t, "pkg:oci/recert?repository_url=registry.example.com%3A5000%2Forg%2Frecert&tag=latest", purl)
+}
+
+func TestGenerateSPDX(t *testing.T) {
+	data, err := Generate(FormatSPDX, testInputs())
+	assert.NoError(t, err)
+
+	doc := &spdxDoc{}
+	assert.NoError(t, json.Unmarshal(data, doc))
+	assert.Equal(t, "SPDX-2.3", doc.SPDXVersion)
+	assert.Equal(t, "2024-01-02T03:04:05Z", doc.CreationInfo.Created)
+	assert.True(t, strings.HasPrefix(doc.DocumentNamespace, "https://github.com/openshift-kni/lifecycle-agent/spdx/"))
+	// the seed, 5 packages and 2 images
+	if assert.Len(t, doc.Packages, 8) {
+		assert.Equal(t, "SPDXRef-Seed", doc.Packages[0].SPDXID)
+		assert.Equal(t, "bash", doc.Packages[1].Name)
+		assert.Equal(t, "pkg:rpm/redhat/bash@5.1.8-6.el9?arch=x86_64", doc.Packages[1].ExternalRefs[0].ReferenceLocator)
+		assert.Equal(t, "CONTAINER", doc.Packages[7].PrimaryPurpose)
+	}
+	assert.Len(t, doc.Relationships, 8)
+
+	again, err := Generate(FormatSPDX, testInputs())
+	assert.NoError(t, err)
+	assert.Equal(t, data, again)
+}
+
+func TestGenerateCycloneDX(t *testing.T) {
+	data, err := Generate(FormatCycloneDX, testInputs())
+	assert.NoError(t, err)
+
+	doc := &cycloneDXDoc{}
+	assert.NoError(t, json.Unmarshal(data, doc))
+	assert.Equal(t, "CycloneDX", doc.BOMFormat)
+	assert.Equal(t, cycloneDXComponent{Type: "container", Name: "quay.io/org/seed:4.15.0", Version: "4.15.0"},
+		doc.Metadata.Component)
+	if assert.Len(t, doc.Components, 7) {
+		assert.Equal(t, cycloneDXComponent{
+			Type: "library", Name: "kernel", Version: "5.14.0-284.el9", Purl: "pkg:rpm/redhat/kernel@5.14.0-284.el9?arch=x86_64",
+		}, doc.Components[2])
+		assert.Equal(t, "container", doc.Components[6].Type)
+	}
+
+	_, err = Generate("syft", testInputs())
+	assert.Error(t, err)
+}
+
+func TestArtifact(t *testing.T) {
+	assert.Equal(t, "sha256-1234.sbom", ReferrerTag("sha256:1234"))
+
+	rawManifest := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json"}`)
+	subject, err := SubjectDescriptor(rawManifest)
+	assert.NoError(t, err)
+	assert.Equal(t, &Descriptor{MediaType: mediaTypeOCIManifest, Digest: digestOf(rawManifest), Size: int64(len(rawManifest))}, subject)
+
+	sbom, err := Generate(FormatCycloneDX, testInputs())
+	assert.NoError(t, err)
+	layout := t.TempDir()
+	assert.NoError(t, WriteArtifactLayout(layout, FormatCycloneDX, sbom, subject, testInputs().Created))
+
+	index := &struct {
+		Manifests []Descriptor `json:"manifests"`
+	}{}
+	data, err := os.ReadFile(filepath.Join(layout, "index.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, index))
+	if !assert.Len(t, index.Manifests, 1) {
+		return
+	}
+	manifestData, err := os.ReadFile(filepath.Join(layout, "blobs", "sha256", strings.TrimPrefix(index.Manifests[0].Digest, "sha256:")))
+	assert.NoError(t, err)
+	manifest := &Manifest{}
+	assert.NoError(t, json.Unmarshal(manifestData, manifest))
+	assert.Equal(t, MediaTypeCycloneDX, manifest.ArtifactType)
+	assert.Equal(t, subject, manifest.Subject)
+
+	// The dir transport stores the manifest and the blobs named by the hex of their digest
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), manifestData, 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, strings.TrimPrefix(manifest.Layers[0].Digest, "sha256:")), sbom, 0o600))
+	read, format, err := ReadArtifact(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCycloneDX, format)
+	assert.Equal(t, sbom, read)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, strings.TrimPrefix(manifest.Layers[0].Digest, "sha256:")), []byte("{}"), 0o600))
+	_, _, err = ReadArtifact(dir)
+	assert.ErrorContains(t, err, "does not match its digest")
+}
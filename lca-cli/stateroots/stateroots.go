@@ -0,0 +1,246 @@
+// Package stateroots lists and deletes the ostree stateroots of the host, for the housekeeping of the nodes that
+// accumulated the stateroots of several upgrades.
+package stateroots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
+	"github.com/openshift-kni/lifecycle-agent/internal/prep"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	rpmostreeclient "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
+)
+
+// now is overridden by the tests
+var now = time.Now
+
+// Stateroot is a stateroot of the host, with or without deployments
+type Stateroot struct {
+	Name string
+	// Size is the disk usage in bytes of the stateroot directory, the files shared with the ostree repository
+	// included, or -1 when it could not be computed
+	Size int64
+	// Modified is the last modification time of the stateroot directory, which is set up once and then only changed
+	// by its deployments, or the zero time when it could not be read
+	Modified time.Time
+	// Deployments are the indexes of the deployments of the stateroot in the rpm-ostree status
+	Deployments []int
+	Booted      bool
+	Staged      bool
+	// Default is set when a deployment of the stateroot is the default one, booted by the next reboot
+	Default bool
+	// SeedImage and SeedDigest are the seed image the stateroot was set up from by the LCA, if any
+	SeedImage  string
+	SeedDigest string
+}
+
+// InUse returns why the stateroot cannot be deleted, or an empty string
+func (s *Stateroot) InUse() string {
+	switch {
+	case s.Booted:
+		return "booted"
+	case s.Staged:
+		return "staged"
+	case s.Default:
+		return "the default deployment"
+	}
+	return ""
+}
+
+// Manager lists and deletes the stateroots of the host
+type Manager struct {
+	log             *logrus.Logger
+	ops             ops.Ops
+	rpmOstreeClient rpmostreeclient.IClient
+	ostreeClient    ostreeclient.IClient
+	layout          common.StaterootLayout
+}
+
+// NewManager returns a manager of the stateroots of the host
+func NewManager(log *logrus.Logger, hostOps ops.Ops, rpmOstreeClient rpmostreeclient.IClient,
+	ostreeClient ostreeclient.IClient, layout common.StaterootLayout) *Manager {
+	return &Manager{
+		log:             log,
+		ops:             hostOps,
+		rpmOstreeClient: rpmOstreeClient,
+		ostreeClient:    ostreeClient,
+		layout:          layout,
+	}
+}
+
+// List returns the stateroots of the host, those of the rpm-ostree deployments in their order followed by the
+// stateroot directories without deployment, e.g. left by a failed stateroot setup
+func (m *Manager) List(ctx context.Context) ([]Stateroot, error) {
+	status, err := m.rpmOstreeClient.QueryStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rpm-ostree status: %w", err)
+	}
+
+	var stateroots []Stateroot
+	index := map[string]int{}
+	add := func(name string) *Stateroot {
+		if i, ok := index[name]; ok {
+			return &stateroots[i]
+		}
+		index[name] = len(stateroots)
+		stateroots = append(stateroots, Stateroot{Name: name})
+		return &stateroots[len(stateroots)-1]
+	}
+	for i, deployment := range status.Deployments {
+		stateroot := add(deployment.OSName)
+		stateroot.Deployments = append(stateroot.Deployments, i)
+		stateroot.Booted = stateroot.Booted || deployment.Booted
+		stateroot.Staged = stateroot.Staged || deployment.Staged
+		stateroot.Default = stateroot.Default || i == 0
+	}
+
+	deployDir := filepath.Dir(m.layout.Path("stateroot"))
+	output, err := m.ops.RunInHostNamespace(ctx, "find", deployDir, "-mindepth", "1", "-maxdepth", "1", "-type", "d",
+		"-printf", "%f\\n")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the stateroots in %s: %w", deployDir, err)
+	}
+	for _, name := range strings.Split(output, "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			add(name)
+		}
+	}
+
+	for i := range stateroots {
+		stateroot := &stateroots[i]
+		stateroot.Size = m.size(ctx, stateroot.Name)
+		stateroot.Modified = m.modified(ctx, stateroot.Name)
+		stateroot.SeedImage, stateroot.SeedDigest = m.seed(ctx, stateroot.Name)
+	}
+	return stateroots, nil
+}
+
+// size returns the disk usage of the stateroot directory, or -1 on failure
+func (m *Manager) size(ctx context.Context, name string) int64 {
+	path := m.layout.Path(name)
+	output, err := m.ops.RunInHostNamespace(ctx, "du", "--summarize", "--one-file-system", "--block-size=1", path)
+	if err != nil {
+		m.log.Warnf("Failed to compute the size of stateroot %s: %v", name, err)
+		return -1
+	}
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		m.log.Warnf("Unexpected disk usage output for stateroot %s: %q", name, output)
+		return -1
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		m.log.Warnf("Unexpected disk usage output for stateroot %s: %q", name, output)
+		return -1
+	}
+	return size
+}
+
+// modified returns the last modification time of the stateroot directory, or the zero time on failure
+func (m *Manager) modified(ctx context.Context, name string) time.Time {
+	output, err := m.ops.RunInHostNamespace(ctx, "stat", "--format", "%Y", m.layout.Path(name))
+	if err != nil {
+		m.log.Warnf("Failed to get the modification time of stateroot %s: %v", name, err)
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		m.log.Warnf("Unexpected modification time output for stateroot %s: %q", name, output)
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// seed returns the seed image the stateroot was set up from, recorded by the LCA, or empty strings for the stateroots
+// not set up by the LCA
+func (m *Manager) seed(ctx context.Context, name string) (string, string) {
+	output, err := m.ops.RunInHostNamespace(ctx, "cat", filepath.Join(m.layout.Path(name), prep.StaterootSetupMarkerFile))
+	if err != nil {
+		m.log.Debugf("No stateroot setup marker in stateroot %s: %v", name, err)
+		return "", ""
+	}
+	seedImage, seedDigest, err := prep.ParseStaterootSetupMarker([]byte(output))
+	if err != nil {
+		m.log.Warnf("Ignoring the setup marker of stateroot %s: %v", name, err)
+		return "", ""
+	}
+	return seedImage, seedDigest
+}
+
+// Delete undeploys the deployments of the stateroot and removes its directory. It refuses to delete the stateroot of
+// the booted, staged or default deployment.
+func (m *Manager) Delete(ctx context.Context, name string) error {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return fmt.Errorf("invalid stateroot name %q", name)
+	}
+	stateroots, err := m.List(ctx)
+	if err != nil {
+		return err
+	}
+	var stateroot *Stateroot
+	for i := range stateroots {
+		if stateroots[i].Name == name {
+			stateroot = &stateroots[i]
+			break
+		}
+	}
+	if stateroot == nil {
+		return fmt.Errorf("stateroot %s not found", name)
+	}
+	if inUse := stateroot.InUse(); inUse != "" {
+		return fmt.Errorf("refusing to delete stateroot %s: it is %s", name, inUse)
+	}
+
+	// since undeploy shifts the order, undeploy in the reverse order
+	for i := len(stateroot.Deployments) - 1; i >= 0; i-- {
+		m.log.Infof("Undeploying deployment %d of stateroot %s", stateroot.Deployments[i], name)
+		if err := m.ostreeClient.Undeploy(ctx, stateroot.Deployments[i]); err != nil {
+			return fmt.Errorf("failed to undeploy stateroot %s: %w", name, err)
+		}
+	}
+
+	m.log.Infof("Removing stateroot %s", name)
+	if _, err := m.ops.RunBashInHostNamespace(ctx, "unshare", "-m", "/bin/sh", "-c",
+		fmt.Sprintf("\"mount -o remount,rw /sysroot && rm -rf %s\"", m.layout.Path(name))); err != nil {
+		return fmt.Errorf("failed to remove stateroot %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteOlderThan deletes the stateroots not modified for maxAge, but those in use and those listed to keep. It
+// returns the deleted stateroots, and deletes as many of them as possible on failure.
+func (m *Manager) DeleteOlderThan(ctx context.Context, maxAge time.Duration, keep ...string) ([]string, error) {
+	stateroots, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	var errs []error
+	for _, stateroot := range stateroots {
+		switch {
+		case stateroot.InUse() != "", lo.Contains(keep, stateroot.Name):
+			continue
+		case stateroot.Modified.IsZero():
+			m.log.Warnf("Keeping stateroot %s, its age is unknown", stateroot.Name)
+			continue
+		case now().Sub(stateroot.Modified) < maxAge:
+			continue
+		}
+		if err := m.Delete(ctx, stateroot.Name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted = append(deleted, stateroot.Name)
+	}
+	return deleted, errors.Join(errs...)
+}
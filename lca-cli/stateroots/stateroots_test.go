@@ -0,0 +1,133 @@
+package stateroots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
+	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
+	rpmostreeclient "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
+)
+
+// status has the upgraded stateroot booted and default, the original stateroot as rollback and a stateroot left by
+// a previous upgrade generation
+var status = &rpmostreeclient.Status{Deployments: []rpmostreeclient.Deployment{
+	{OSName: "rhcos_4.16.1", Booted: true},
+	{OSName: "rhcos"},
+	{OSName: "rhcos_4.15.2"},
+	{OSName: "rhcos_4.15.2"},
+}}
+
+var modifiedTimes = map[string]time.Time{
+	"/ostree/deploy/rhcos_4.16.1": time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	"/ostree/deploy/rhcos":        time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	"/ostree/deploy/rhcos_4.15.2": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func newTestManager(t *testing.T) (*Manager, *ops.MockOps, *ostreeclient.MockIClient) {
+	ctrl := gomock.NewController(t)
+	mockOps := ops.NewMockOps(ctrl)
+	mockRpmOstree := rpmostreeclient.NewMockIClient(ctrl)
+	mockOstree := ostreeclient.NewMockIClient(ctrl)
+	mockRpmOstree.EXPECT().QueryStatus(gomock.Any()).Return(status, nil).AnyTimes()
+	mockOps.EXPECT().RunInHostNamespace(gomock.Any(), "find", "/ostree/deploy", "-mindepth", "1", "-maxdepth", "1", "-type", "d",
+		"-printf", "%f\\n").Return("rhcos\nrhcos_4.16.1\nrhcos_4.15.2\nrhcos_4.14.0\n", nil).AnyTimes()
+	mockOps.EXPECT().RunInHostNamespace(gomock.Any(), "du", "--summarize", "--one-file-system", "--block-size=1", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, args ...string) (string, error) {
+			if args[3] == "/ostree/deploy/rhcos_4.14.0" {
+				return "", errors.New("exit status 1")
+			}
+			return "2048\t" + args[3], nil
+		}).AnyTimes()
+	mockOps.EXPECT().RunInHostNamespace(gomock.Any(), "stat", "--format", "%Y", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, args ...string) (string, error) {
+			if modified, ok := modifiedTimes[args[2]]; ok {
+				return fmt.Sprintf("%d\n", modified.Unix()), nil
+			}
+			return "", errors.New("exit status 1")
+		}).AnyTimes()
+	mockOps.EXPECT().RunInHostNamespace(gomock.Any(), "cat", gomock.Any()).DoAndReturn(func(_ context.Context, _ string, args ...string) (string, error) {
+		switch args[0] {
+		case "/ostree/deploy/rhcos_4.16.1/lca-stateroot-setup.json":
+			return `{"seed_image":"quay.io/org/seed:4.16.1","seed_digest":"sha256:1234","deployment":"abc.0"}`, nil
+		case "/ostree/deploy/rhcos_4.15.2/lca-stateroot-setup.json":
+			return `{"seed_image":"quay.io/org/seed:4.15.2","seed_digest":"sha256:5678","deployment":"def.0"}`, nil
+		}
+		return "", errors.New("no such file")
+	}).AnyTimes()
+	return NewManager(logrus.New(), mockOps, mockRpmOstree, mockOstree, common.CurrentStaterootLayout()),
+		mockOps, mockOstree
+}
+
+func TestList(t *testing.T) {
+	m, _, _ := newTestManager(t)
+	list, err := m.List(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Stateroot{
+		{Name: "rhcos_4.16.1", Size: 2048, Modified: time.Unix(modifiedTimes["/ostree/deploy/rhcos_4.16.1"].Unix(), 0),
+			Deployments: []int{0}, Booted: true, Default: true,
+			SeedImage: "quay.io/org/seed:4.16.1", SeedDigest: "sha256:1234"},
+		{Name: "rhcos", Size: 2048, Modified: time.Unix(modifiedTimes["/ostree/deploy/rhcos"].Unix(), 0),
+			Deployments: []int{1}},
+		{Name: "rhcos_4.15.2", Size: 2048, Modified: time.Unix(modifiedTimes["/ostree/deploy/rhcos_4.15.2"].Unix(), 0),
+			Deployments: []int{2, 3}, SeedImage: "quay.io/org/seed:4.15.2", SeedDigest: "sha256:5678"},
+		{Name: "rhcos_4.14.0", Size: -1},
+	}, list)
+}
+
+func TestDelete(t *testing.T) {
+	m, mockOps, mockOstree := newTestManager(t)
+	gomock.InOrder(
+		mockOstree.EXPECT().Undeploy(gomock.Any(), 3).Return(nil),
+		mockOstree.EXPECT().Undeploy(gomock.Any(), 2).Return(nil),
+		mockOps.EXPECT().RunBashInHostNamespace(gomock.Any(), "unshare", "-m", "/bin/sh", "-c",
+			"\"mount -o remount,rw /sysroot && rm -rf /ostree/deploy/rhcos_4.15.2\"").Return("", nil),
+	)
+	assert.NoError(t, m.Delete(context.Background(), "rhcos_4.15.2"))
+
+	// A stateroot without deployment is only removed
+	mockOps.EXPECT().RunBashInHostNamespace(gomock.Any(), "unshare", "-m", "/bin/sh", "-c",
+		"\"mount -o remount,rw /sysroot && rm -rf /ostree/deploy/rhcos_4.14.0\"").Return("", nil)
+	assert.NoError(t, m.Delete(context.Background(), "rhcos_4.14.0"))
+
+	assert.EqualError(t, m.Delete(context.Background(), "rhcos_4.16.1"), "refusing to delete stateroot rhcos_4.16.1: it is booted")
+	assert.EqualError(t, m.Delete(context.Background(), "rhcos_4.13.0"), "stateroot rhcos_4.13.0 not found")
+	assert.EqualError(t, m.Delete(context.Background(), "../rhcos"), `invalid stateroot name "../rhcos"`)
+}
+
+func TestDeleteOlderThan(t *testing.T) {
+	origNow := now
+	defer func() { now = origNow }()
+	now = func() time.Time { return time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC) }
+
+	m, mockOps, mockOstree := newTestManager(t)
+	gomock.InOrder(
+		mockOstree.EXPECT().Undeploy(gomock.Any(), 3).Return(nil),
+		mockOstree.EXPECT().Undeploy(gomock.Any(), 2).Return(nil),
+		mockOps.EXPECT().RunBashInHostNamespace(gomock.Any(), "unshare", "-m", "/bin/sh", "-c",
+			"\"mount -o remount,rw /sysroot && rm -rf /ostree/deploy/rhcos_4.15.2\"").Return("", nil),
+	)
+	// rhcos_4.16.1 is booted, rhcos is kept and the age of rhcos_4.14.0 is unknown
+	deleted, err := m.DeleteOlderThan(context.Background(), 30*24*time.Hour, "rhcos")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rhcos_4.15.2"}, deleted)
+
+	// Nothing is old enough
+	deleted, err = m.DeleteOlderThan(context.Background(), 10*365*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted)
+}
+
+func TestInUse(t *testing.T) {
+	assert.Equal(t, "", (&Stateroot{}).InUse())
+	assert.Equal(t, "staged", (&Stateroot{Staged: true}).InUse())
+	assert.Equal(t, "the default deployment", (&Stateroot{Default: true}).InUse())
+}
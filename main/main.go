@@ -20,6 +20,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"sync"
 
@@ -64,9 +65,14 @@ import (
 	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
 	"github.com/openshift-kni/lifecycle-agent/internal/backuprestore"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/hubreport"
+	"github.com/openshift-kni/lifecycle-agent/internal/journal"
+	"github.com/openshift-kni/lifecycle-agent/internal/notification"
 	"github.com/openshift-kni/lifecycle-agent/internal/ostreeclient"
 	"github.com/openshift-kni/lifecycle-agent/internal/precache"
+	"github.com/openshift-kni/lifecycle-agent/internal/precache/workload"
 	"github.com/openshift-kni/lifecycle-agent/internal/reboot"
+	"github.com/openshift-kni/lifecycle-agent/internal/upgradesummary"
 	"github.com/openshift-kni/lifecycle-agent/lca-cli/ops"
 	rpmostreeclient "github.com/openshift-kni/lifecycle-agent/lca-cli/ostreeclient"
 	lcautils "github.com/openshift-kni/lifecycle-agent/utils"
@@ -163,14 +169,20 @@ func main() {
 	executor := ops.NewChrootExecutor(newLogger, true, common.Host)
 	op := ops.NewOps(newLogger, executor)
 	rpmOstreeClient := rpmostreeclient.NewClient("ibu-controller", executor)
-	ostreeClient := ostreeclient.NewClient(executor, false)
-	rebootClient := reboot.NewRebootClient(&log, executor, rpmOstreeClient, ostreeClient, op)
+	staterootLayout := common.CurrentStaterootLayout()
+	ostreeClient := ostreeclient.NewClient(executor, false, staterootLayout)
+	rebootClient := reboot.NewRebootClient(&log, executor, rpmOstreeClient, ostreeClient, op, staterootLayout)
 
 	if err := lcautils.InitIBU(context.TODO(), mgr.GetClient(), &setupLog); err != nil {
 		setupLog.Error(err, "unable to initialize IBU CR")
 		os.Exit(1)
 	}
 
+	if err := lcautils.InitUpgradePlan(context.TODO(), mgr.GetClient(), &setupLog); err != nil {
+		setupLog.Error(err, "unable to restore UpgradePlan CR")
+		os.Exit(1)
+	}
+
 	if err := initSeedGen(context.TODO(), mgr.GetClient(), &setupLog); err != nil {
 		setupLog.Error(err, "unable to initialize SeedGenerator CR")
 		os.Exit(1)
@@ -197,11 +209,25 @@ func main() {
 	backupRestore := &backuprestore.BRHandler{
 		Client: mgr.GetClient(), DynamicClient: dynamicClient, Log: log.WithName("BackupRestore")}
 
+	notifier := notification.NewNotifier(mgr.GetClient(), log.WithName("Notification"))
+	if err := mgr.Add(notifier); err != nil {
+		setupLog.Error(err, "unable to add notifier")
+		os.Exit(1)
+	}
+	upgradeRecorder := notifier.NewEventRecorder(
+		journal.NewEventRecorder(mgr.GetEventRecorderFor("ImageBasedUpgrade"), executor, log.WithName("Journal")))
+
+	// Precaching in the manager process pulls the images on the host as the precaching job does
+	workload.Executor = executor
+	workload.HostRoot = common.Host
+
+	statusBatcher := &utils.StatusBatcher{}
 	if err = (&controllers.ImageBasedUpgradeReconciler{
-		Client:          mgr.GetClient(),
-		Log:             log,
-		Scheme:          mgr.GetScheme(),
-		Precache:        &precache.PHandler{Client: mgr.GetClient(), Log: log.WithName("Precache")},
+		Client: mgr.GetClient(),
+		Log:    log,
+		Scheme: mgr.GetScheme(),
+		Precache: &precache.PHandler{Client: mgr.GetClient(), Log: log.WithName("Precache"),
+			RunInProcess: workload.RunInProcess},
 		RPMOstreeClient: rpmOstreeClient,
 		Executor:        executor,
 		OstreeClient:    ostreeClient,
@@ -209,6 +235,9 @@ func main() {
 		RebootClient:    rebootClient,
 		BackupRestore:   backupRestore,
 		PrepTask:        &controllers.Task{Active: false, Success: false, Cancel: nil, Progress: ""},
+		HubReporter:     &hubreport.Reporter{Client: mgr.GetClient(), Scheme: mgr.GetScheme(), Log: log.WithName("HubReport")},
+		Notifier:        notifier,
+		UpgradeSummary:  &upgradesummary.Recorder{Client: mgr.GetClient(), Log: log.WithName("UpgradeSummary")},
 		UpgradeHandler: &controllers.UpgHandler{
 			Client:          mgr.GetClient(),
 			Log:             log.WithName("UpgradeHandler"),
@@ -217,12 +246,16 @@ func main() {
 			ClusterConfig:   &clusterconfig.UpgradeClusterConfigGather{Client: mgr.GetClient(), Scheme: mgr.GetScheme(), Log: log},
 			Executor:        executor,
 			Ops:             op,
-			Recorder:        mgr.GetEventRecorderFor("ImageBasedUpgrade"),
+			Recorder:        upgradeRecorder,
 			RPMOstreeClient: rpmOstreeClient,
 			OstreeClient:    ostreeClient,
 			RebootClient:    rebootClient,
+			StaterootLayout: staterootLayout,
+			StatusBatcher:   statusBatcher,
 		},
-		Mux: mux,
+		StaterootLayout: staterootLayout,
+		StatusBatcher:   statusBatcher,
+		Mux:             mux,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ImageBasedUpgrade")
 		os.Exit(1)
@@ -240,8 +273,29 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "SeedGenerator")
 		os.Exit(1)
 	}
+
+	if err = (&controllers.UpgradePlanReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("UpgradePlan"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "UpgradePlan")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&controllers.SeedGeneratorValidator{
+			Reader:         mgr.GetClient(),
+			Log:            ctrl.Log.WithName("webhooks").WithName("SeedGenerator"),
+			HTTPClient:     &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+			ServiceAccount: fmt.Sprintf("system:serviceaccount:%s:%s", common.LcaNamespace, precache.LcaPrecacheServiceAccount),
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SeedGenerator")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -0,0 +1,181 @@
+// Package ibuclient provides helpers for external consumers of the ImageBasedUpgrade API, such as cluster lifecycle
+// operators, GitOps tooling and test frameworks, to drive the IBU stages and interpret its status conditions
+// without re-implementing the condition parsing done by the lifecycle agent.
+package ibuclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+)
+
+// DefaultPollInterval is the interval used by WaitForStage when none is given
+const DefaultPollInterval = 10 * time.Second
+
+var (
+	// ErrInvalidTransition is returned by SetStage when the requested stage is not a valid next stage
+	ErrInvalidTransition = errors.New("invalid stage transition")
+	// ErrStageFailed is returned by WaitForStage when the awaited stage fails
+	ErrStageFailed = errors.New("stage failed")
+)
+
+// StageStatus is the state of an IBU stage as reported by its status conditions
+type StageStatus struct {
+	Stage      lcav1alpha1.ImageBasedUpgradeStage
+	InProgress bool
+	Completed  bool
+	Failed     bool
+	// Reason and Message are taken from the completed condition of the stage once it is completed or failed,
+	// otherwise from its in progress condition
+	Reason  string
+	Message string
+}
+
+// Client wraps a controller-runtime client to manage the ImageBasedUpgrade CR
+type Client struct {
+	client.Client
+}
+
+// New returns a Client using the given controller-runtime client, whose scheme must include lcav1alpha1
+func New(c client.Client) *Client {
+	return &Client{Client: c}
+}
+
+// NewForConfig returns a Client for the given rest config
+func NewForConfig(config *rest.Config) (*Client, error) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(lcav1alpha1.AddToScheme(scheme))
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return New(c), nil
+}
+
+// Get returns the ImageBasedUpgrade CR
+func (c *Client) Get(ctx context.Context) (*lcav1alpha1.ImageBasedUpgrade, error) {
+	ibu := &lcav1alpha1.ImageBasedUpgrade{}
+	if err := c.Client.Get(ctx, types.NamespacedName{Name: utils.IBUName}, ibu); err != nil {
+		return nil, fmt.Errorf("failed to get ImageBasedUpgrade %s: %w", utils.IBUName, err)
+	}
+	return ibu, nil
+}
+
+// SetStage requests the transition to the given stage, after validating it against the valid next stages reported
+// in the IBU status. Requesting the current stage is a no-op.
+func (c *Client) SetStage(ctx context.Context, stage lcav1alpha1.ImageBasedUpgradeStage) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error { //nolint:wrapcheck
+		ibu, err := c.Get(ctx)
+		if err != nil {
+			return err
+		}
+		if ibu.Spec.Stage == stage {
+			return nil
+		}
+		if !lo.Contains(ibu.Status.ValidNextStages, stage) {
+			return fmt.Errorf("%w: %s to %s, valid next stages: %v", ErrInvalidTransition, ibu.Spec.Stage, stage, ibu.Status.ValidNextStages)
+		}
+
+		ibu.Spec.Stage = stage
+		if err := c.Update(ctx, ibu); err != nil {
+			return fmt.Errorf("failed to update ImageBasedUpgrade stage to %s: %w", stage, err)
+		}
+		return nil
+	})
+}
+
+// WaitForStage polls the IBU every interval until the given stage is completed, and returns the IBU at that point.
+// It returns ErrStageFailed wrapped with the condition message if the stage fails, or the context error on timeout.
+func (c *Client) WaitForStage(ctx context.Context, stage lcav1alpha1.ImageBasedUpgradeStage, interval time.Duration) (*lcav1alpha1.ImageBasedUpgrade, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	var ibu *lcav1alpha1.ImageBasedUpgrade
+	var stageErr error
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		current, err := c.Get(ctx)
+		if err != nil {
+			// transient API errors are retried until the context is done
+			return false, nil
+		}
+		ibu = current
+
+		status := ParseConditions(ibu, stage)
+		if status.Failed {
+			stageErr = fmt.Errorf("%w: %s: %s", ErrStageFailed, stage, status.Message)
+			return true, nil
+		}
+		return status.Completed, nil
+	})
+	if stageErr != nil {
+		return ibu, stageErr
+	}
+	if err != nil {
+		return ibu, fmt.Errorf("timed out waiting for stage %s: %w", stage, err)
+	}
+	return ibu, nil
+}
+
+// ParseConditions returns the state of the given stage from the IBU status conditions
+func ParseConditions(ibu *lcav1alpha1.ImageBasedUpgrade, stage lcav1alpha1.ImageBasedUpgradeStage) StageStatus {
+	status := StageStatus{Stage: stage}
+
+	if stage == lcav1alpha1.Stages.Idle {
+		// the Idle condition is false while any other stage is in progress, so only the abort and finalize
+		// reasons are relevant to the Idle stage itself
+		condition := meta.FindStatusCondition(ibu.Status.Conditions, string(utils.ConditionTypes.Idle))
+		if condition == nil {
+			return status
+		}
+		status.Reason, status.Message = condition.Reason, condition.Message
+		switch {
+		case condition.Status == metav1.ConditionTrue:
+			status.Completed = true
+		case condition.Reason == string(utils.ConditionReasons.AbortFailed) || condition.Reason == string(utils.ConditionReasons.FinalizeFailed):
+			status.Failed = true
+		default:
+			status.InProgress = utils.IsStageInProgress(ibu, stage)
+		}
+		return status
+	}
+
+	status.InProgress = utils.IsStageInProgress(ibu, stage)
+	status.Completed = utils.IsStageCompleted(ibu, stage)
+	status.Failed = utils.IsStageFailed(ibu, stage)
+
+	condition := utils.GetCompletedCondition(ibu, stage)
+	if status.InProgress || condition == nil {
+		condition = utils.GetInProgressCondition(ibu, stage)
+	}
+	if condition != nil {
+		status.Reason, status.Message = condition.Reason, condition.Message
+	}
+	return status
+}
+
+// CurrentStage returns the state of the stage currently in progress, or of the stage requested in the spec when no
+// stage is in progress
+func CurrentStage(ibu *lcav1alpha1.ImageBasedUpgrade) StageStatus {
+	stage := utils.GetInProgressStage(ibu)
+	if stage == "" {
+		stage = ibu.Spec.Stage
+	}
+	return ParseConditions(ibu, stage)
+}
@@ -0,0 +1,169 @@
+package ibuclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lcav1alpha1 "github.com/openshift-kni/lifecycle-agent/api/v1alpha1"
+	"github.com/openshift-kni/lifecycle-agent/controllers/utils"
+)
+
+func newIBU(stage lcav1alpha1.ImageBasedUpgradeStage, validNextStages []lcav1alpha1.ImageBasedUpgradeStage, conditions ...metav1.Condition) *lcav1alpha1.ImageBasedUpgrade {
+	return &lcav1alpha1.ImageBasedUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: utils.IBUName},
+		Spec:       lcav1alpha1.ImageBasedUpgradeSpec{Stage: stage},
+		Status:     lcav1alpha1.ImageBasedUpgradeStatus{Conditions: conditions, ValidNextStages: validNextStages},
+	}
+}
+
+func newTestClient(t *testing.T, ibu *lcav1alpha1.ImageBasedUpgrade) *Client {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, lcav1alpha1.AddToScheme(scheme))
+	return New(fake.NewClientBuilder().WithScheme(scheme).WithObjects(ibu).Build())
+}
+
+func condition(conditionType utils.ConditionType, status metav1.ConditionStatus, reason utils.ConditionReason, msg string) metav1.Condition {
+	return metav1.Condition{Type: string(conditionType), Status: status, Reason: string(reason), Message: msg}
+}
+
+func TestSetStage(t *testing.T) {
+	idle := condition(utils.ConditionTypes.Idle, metav1.ConditionTrue, utils.ConditionReasons.Idle, "Idle")
+	c := newTestClient(t, newIBU(lcav1alpha1.Stages.Idle, []lcav1alpha1.ImageBasedUpgradeStage{lcav1alpha1.Stages.Prep}, idle))
+
+	err := c.SetStage(context.Background(), lcav1alpha1.Stages.Upgrade)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	assert.NoError(t, c.SetStage(context.Background(), lcav1alpha1.Stages.Prep))
+	ibu, err := c.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, lcav1alpha1.Stages.Prep, ibu.Spec.Stage)
+
+	// requesting the current stage again is a no-op
+	assert.NoError(t, c.SetStage(context.Background(), lcav1alpha1.Stages.Prep))
+}
+
+func TestWaitForStage(t *testing.T) {
+	c := newTestClient(t, newIBU(lcav1alpha1.Stages.Prep, nil,
+		condition(utils.ConditionTypes.PrepInProgress, metav1.ConditionFalse, utils.ConditionReasons.Completed, "Prep completed"),
+		condition(utils.ConditionTypes.PrepCompleted, metav1.ConditionTrue, utils.ConditionReasons.Completed, "Prep completed"),
+	))
+	ibu, err := c.WaitForStage(context.Background(), lcav1alpha1.Stages.Prep, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, lcav1alpha1.Stages.Prep, ibu.Spec.Stage)
+
+	c = newTestClient(t, newIBU(lcav1alpha1.Stages.Prep, nil,
+		condition(utils.ConditionTypes.PrepInProgress, metav1.ConditionFalse, utils.ConditionReasons.Failed, "Prep failed"),
+		condition(utils.ConditionTypes.PrepCompleted, metav1.ConditionFalse, utils.ConditionReasons.PrecacheFailed, "precaching failed"),
+	))
+	_, err = c.WaitForStage(context.Background(), lcav1alpha1.Stages.Prep, time.Millisecond)
+	assert.ErrorIs(t, err, ErrStageFailed)
+	assert.ErrorContains(t, err, "precaching failed")
+
+	c = newTestClient(t, newIBU(lcav1alpha1.Stages.Prep, nil,
+		condition(utils.ConditionTypes.PrepInProgress, metav1.ConditionTrue, utils.ConditionReasons.InProgress, "Precaching progress"),
+	))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = c.WaitForStage(ctx, lcav1alpha1.Stages.Prep, time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestParseConditions(t *testing.T) {
+	testcases := []struct {
+		name     string
+		ibu      *lcav1alpha1.ImageBasedUpgrade
+		stage    lcav1alpha1.ImageBasedUpgradeStage
+		expected StageStatus
+	}{
+		{
+			name: "upgrade in progress",
+			ibu: newIBU(lcav1alpha1.Stages.Upgrade, nil,
+				condition(utils.ConditionTypes.Idle, metav1.ConditionFalse, utils.ConditionReasons.InProgress, "In progress"),
+				condition(utils.ConditionTypes.PrepCompleted, metav1.ConditionTrue, utils.ConditionReasons.Completed, "Prep completed"),
+				condition(utils.ConditionTypes.UpgradeInProgress, metav1.ConditionTrue, utils.ConditionReasons.InProgress, "Waiting for system to stabilize"),
+			),
+			stage: lcav1alpha1.Stages.Upgrade,
+			expected: StageStatus{
+				Stage: lcav1alpha1.Stages.Upgrade, InProgress: true,
+				Reason: string(utils.ConditionReasons.InProgress), Message: "Waiting for system to stabilize",
+			},
+		},
+		{
+			name: "prep completed",
+			ibu: newIBU(lcav1alpha1.Stages.Upgrade, nil,
+				condition(utils.ConditionTypes.PrepInProgress, metav1.ConditionFalse, utils.ConditionReasons.Completed, "Prep completed"),
+				condition(utils.ConditionTypes.PrepCompleted, metav1.ConditionTrue, utils.ConditionReasons.Completed, "Prep stage completed successfully"),
+			),
+			stage: lcav1alpha1.Stages.Prep,
+			expected: StageStatus{
+				Stage: lcav1alpha1.Stages.Prep, Completed: true,
+				Reason: string(utils.ConditionReasons.Completed), Message: "Prep stage completed successfully",
+			},
+		},
+		{
+			name: "abort in progress",
+			ibu: newIBU(lcav1alpha1.Stages.Idle, nil,
+				condition(utils.ConditionTypes.Idle, metav1.ConditionFalse, utils.ConditionReasons.Aborting, "Aborting"),
+			),
+			stage: lcav1alpha1.Stages.Idle,
+			expected: StageStatus{
+				Stage: lcav1alpha1.Stages.Idle, InProgress: true,
+				Reason: string(utils.ConditionReasons.Aborting), Message: "Aborting",
+			},
+		},
+		{
+			name: "abort failed",
+			ibu: newIBU(lcav1alpha1.Stages.Idle, nil,
+				condition(utils.ConditionTypes.Idle, metav1.ConditionFalse, utils.ConditionReasons.AbortFailed, "failed to cleanup stateroot"),
+			),
+			stage: lcav1alpha1.Stages.Idle,
+			expected: StageStatus{
+				Stage: lcav1alpha1.Stages.Idle, Failed: true,
+				Reason: string(utils.ConditionReasons.AbortFailed), Message: "failed to cleanup stateroot",
+			},
+		},
+		{
+			name: "idle not reached while another stage is in progress",
+			ibu: newIBU(lcav1alpha1.Stages.Prep, nil,
+				condition(utils.ConditionTypes.Idle, metav1.ConditionFalse, utils.ConditionReasons.InProgress, "In progress"),
+			),
+			stage: lcav1alpha1.Stages.Idle,
+			expected: StageStatus{
+				Stage:  lcav1alpha1.Stages.Idle,
+				Reason: string(utils.ConditionReasons.InProgress), Message: "In progress",
+			},
+		},
+		{
+			name:     "no conditions",
+			ibu:      newIBU(lcav1alpha1.Stages.Idle, nil),
+			stage:    lcav1alpha1.Stages.Rollback,
+			expected: StageStatus{Stage: lcav1alpha1.Stages.Rollback},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ParseConditions(tc.ibu, tc.stage))
+		})
+	}
+}
+
+func TestCurrentStage(t *testing.T) {
+	ibu := newIBU(lcav1alpha1.Stages.Upgrade, nil,
+		condition(utils.ConditionTypes.UpgradeInProgress, metav1.ConditionTrue, utils.ConditionReasons.InProgress, "In progress"),
+	)
+	assert.Equal(t, lcav1alpha1.Stages.Upgrade, CurrentStage(ibu).Stage)
+	assert.True(t, CurrentStage(ibu).InProgress)
+
+	ibu = newIBU(lcav1alpha1.Stages.Prep, nil,
+		condition(utils.ConditionTypes.PrepCompleted, metav1.ConditionTrue, utils.ConditionReasons.Completed, "Prep completed"),
+	)
+	assert.Equal(t, lcav1alpha1.Stages.Prep, CurrentStage(ibu).Stage)
+	assert.True(t, CurrentStage(ibu).Completed)
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ProgressFunc reports the progress of a long running operation with a human readable message
+type ProgressFunc func(msg string)
+
+// BackupItem is a named item of a backup
+type BackupItem struct {
+	Name   string
+	Backup func() error
+}
+
+// BackupItemsError aggregates the errors of the failed items of a backup
+type BackupItemsError struct {
+	What   string
+	Total  int
+	Failed []string
+	Errs   []error
+}
+
+func (e *BackupItemsError) Error() string {
+	failures := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		failures[i] = fmt.Sprintf("%s: %s", e.Failed[i], err)
+	}
+	return fmt.Sprintf("failed to backup %d of %d %s items: %s", len(e.Errs), e.Total, e.What, strings.Join(failures, "; "))
+}
+
+// Unwrap allows errors.Is and errors.As to match the errors of the failed items
+func (e *BackupItemsError) Unwrap() []error {
+	return e.Errs
+}
+
+// RunBackupItems backs up every item, reporting the progress before each of them if progress is not nil.
+// A failed item does not stop the backup of the following ones, so a single error reports all the failed items.
+func RunBackupItems(what string, items []BackupItem, progress ProgressFunc) error {
+	backupErr := &BackupItemsError{What: what, Total: len(items)}
+	for i, item := range items {
+		if progress != nil {
+			progress(fmt.Sprintf("Backing up %s: %s (%d/%d)", what, item.Name, i+1, len(items)))
+		}
+		if err := item.Backup(); err != nil {
+			backupErr.Failed = append(backupErr.Failed, item.Name)
+			backupErr.Errs = append(backupErr.Errs, err)
+		}
+	}
+	if len(backupErr.Errs) > 0 {
+		return backupErr
+	}
+	return nil
+}
+
+// RunBackupItemsParallel backs up the items like RunBackupItems, running up to parallelism of them at a time. The
+// progress is reported as each item starts, and the failed items are listed in the order of the items.
+func RunBackupItemsParallel(what string, items []BackupItem, parallelism int, progress ProgressFunc) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		started int
+	)
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, parallelism)
+	for i := range items {
+		i := i
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if progress != nil {
+				mu.Lock()
+				started++
+				progress(fmt.Sprintf("Backing up %s: %s (%d/%d)", what, items[i].Name, started, len(items)))
+				mu.Unlock()
+			}
+			errs[i] = items[i].Backup()
+		}()
+	}
+	wg.Wait()
+
+	backupErr := &BackupItemsError{What: what, Total: len(items)}
+	for i, err := range errs {
+		if err != nil {
+			backupErr.Failed = append(backupErr.Failed, items[i].Name)
+			backupErr.Errs = append(backupErr.Errs, err)
+		}
+	}
+	if len(backupErr.Errs) > 0 {
+		return backupErr
+	}
+	return nil
+}
@@ -4,15 +4,19 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/openshift-kni/lifecycle-agent/api/seedreconfig"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	"github.com/openshift-kni/lifecycle-agent/internal/common/collections"
 	ocp_config_v1 "github.com/openshift/api/config/v1"
 	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 	"github.com/samber/lo"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/yaml"
@@ -73,7 +77,20 @@ type ClusterInfo struct {
 	NodeIP                   string
 	ReleaseRegistry          string
 	Hostname                 string
+	NodeName                 string
 	MirrorRegistryConfigured bool
+	ImageMirrors             []ImageMirror
+	HasProxy                 bool
+}
+
+// ImageMirror is a source repository and its mirrors, as configured by an ImageDigestMirrorSet or an
+// ImageContentSourcePolicy
+type ImageMirror struct {
+	Source  string   `json:"source"`
+	Mirrors []string `json:"mirrors,omitempty"`
+	// NeverContactSource is set for the ImageDigestMirrorSets never contacting the source, whose images can then only
+	// be pulled by digest from the mirrors, unless an ImageTagMirrorSet mirrors their tags
+	NeverContactSource bool `json:"never_contact_source,omitempty"`
 }
 
 func GetClusterInfo(ctx context.Context, client runtimeclient.Client) (*ClusterInfo, error) {
@@ -100,7 +117,7 @@ func GetClusterInfo(ctx context.Context, client runtimeclient.Client) (*ClusterI
 	if err != nil {
 		return nil, err
 	}
-	hostname, err := getNodeHostname(*node)
+	hostname, err := GetNodeHostname(*node)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +127,12 @@ func GetClusterInfo(ctx context.Context, client runtimeclient.Client) (*ClusterI
 		return nil, err
 	}
 
-	mirrorRegistrySources, err := GetMirrorRegistrySourceRegistries(ctx, client)
+	imageMirrors, err := GetImageMirrors(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyEnv, err := GetProxyEnv(ctx, client)
 	if err != nil {
 		return nil, err
 	}
@@ -123,10 +145,73 @@ func GetClusterInfo(ctx context.Context, client runtimeclient.Client) (*ClusterI
 		NodeIP:                   ip,
 		ReleaseRegistry:          releaseRegistry,
 		Hostname:                 hostname,
-		MirrorRegistryConfigured: len(mirrorRegistrySources) > 0,
+		NodeName:                 node.Name,
+		MirrorRegistryConfigured: len(imageMirrors) > 0,
+		ImageMirrors:             imageMirrors,
+		HasProxy:                 len(proxyEnv) != 0,
 	}, nil
 }
 
+// GetProxyEnv returns the proxy environment variables of the cluster-wide proxy, nil when the cluster has no proxy
+func GetProxyEnv(ctx context.Context, client runtimeclient.Client) ([]string, error) {
+	proxy := &ocp_config_v1.Proxy{}
+	if err := client.Get(ctx, types.NamespacedName{Name: "cluster"}, proxy); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get proxy: %w", err)
+	}
+	return ProxyEnv(proxy), nil
+}
+
+// GetOperatorUpgrade describes the OLM upgrade of the operators of the namespace in progress, from their
+// ClusterServiceVersions: the new one replacing a previous one until it succeeds, and the previous one until deleted.
+// It returns an empty string when no upgrade is in progress.
+func GetOperatorUpgrade(ctx context.Context, client runtimeclient.Client, namespace string) (string, error) {
+	csvs := &operatorsv1alpha1.ClusterServiceVersionList{}
+	if err := client.List(ctx, csvs, runtimeclient.InNamespace(namespace)); err != nil {
+		return "", fmt.Errorf("failed to list ClusterServiceVersions of %s: %w", namespace, err)
+	}
+	var upgrades []string
+	for _, csv := range csvs.Items {
+		switch {
+		case csv.Status.Reason == operatorsv1alpha1.CSVReasonCopied:
+			continue
+		case csv.Status.Phase == operatorsv1alpha1.CSVPhaseReplacing || csv.Status.Phase == operatorsv1alpha1.CSVPhaseDeleting:
+			upgrades = append(upgrades, fmt.Sprintf("%s is %s", csv.Name, csv.Status.Phase))
+		case csv.Spec.Replaces != "" && csv.Status.Phase != operatorsv1alpha1.CSVPhaseSucceeded:
+			phase := csv.Status.Phase
+			if phase == operatorsv1alpha1.CSVPhaseNone {
+				phase = operatorsv1alpha1.CSVPhasePending
+			}
+			upgrades = append(upgrades, fmt.Sprintf("%s replacing %s is %s", csv.Name, csv.Spec.Replaces, phase))
+		}
+	}
+	sort.Strings(upgrades)
+	return strings.Join(upgrades, ", "), nil
+}
+
+// ProxyEnv returns the proxy environment variables, preferring the status of the proxy, which holds the
+// values rendered by the cluster network operator, over its spec
+func ProxyEnv(proxy *ocp_config_v1.Proxy) []string {
+	httpProxy, httpsProxy, noProxy := proxy.Status.HTTPProxy, proxy.Status.HTTPSProxy, proxy.Status.NoProxy
+	if httpProxy == "" && httpsProxy == "" {
+		httpProxy, httpsProxy, noProxy = proxy.Spec.HTTPProxy, proxy.Spec.HTTPSProxy, proxy.Spec.NoProxy
+	}
+
+	var env []string
+	if httpProxy != "" {
+		env = append(env, "HTTP_PROXY="+httpProxy)
+	}
+	if httpsProxy != "" {
+		env = append(env, "HTTPS_PROXY="+httpsProxy)
+	}
+	if len(env) != 0 && noProxy != "" {
+		env = append(env, "NO_PROXY="+noProxy)
+	}
+	return env
+}
+
 // TODO: add dual stuck support
 func getNodeInternalIP(node corev1.Node) (string, error) {
 	for _, addr := range node.Status.Addresses {
@@ -137,7 +222,9 @@ func getNodeInternalIP(node corev1.Node) (string, error) {
 	return "", fmt.Errorf("failed to find node internal ip address")
 }
 
-func getNodeHostname(node corev1.Node) (string, error) {
+// GetNodeHostname returns the hostname address the kubelet reports for the node, which the upgrade sets as the hostname
+// of the new stateroot
+func GetNodeHostname(node corev1.Node) (string, error) {
 	for _, addr := range node.Status.Addresses {
 		if addr.Type == corev1.NodeHostName {
 			return addr.Address, nil
@@ -218,8 +305,10 @@ func ExtractRegistryFromImage(image string) string {
 	return strings.Split(image, "/")[0]
 }
 
-func GetMirrorRegistrySourceRegistries(ctx context.Context, client runtimeclient.Client) ([]string, error) {
-	var sourceRegistries []string
+// GetImageMirrors returns the mirror configuration of the cluster, from both ImageContentSourcePolicies and
+// ImageDigestMirrorSets
+func GetImageMirrors(ctx context.Context, client runtimeclient.Client) ([]ImageMirror, error) {
+	var imageMirrors []ImageMirror
 	allNamespaces := runtimeclient.ListOptions{Namespace: metav1.NamespaceAll}
 	currentIcps := &operatorv1alpha1.ImageContentSourcePolicyList{}
 	if err := client.List(ctx, currentIcps, &allNamespaces); err != nil {
@@ -227,7 +316,7 @@ func GetMirrorRegistrySourceRegistries(ctx context.Context, client runtimeclient
 	}
 	for _, icsp := range currentIcps.Items {
 		for _, rdp := range icsp.Spec.RepositoryDigestMirrors {
-			sourceRegistries = append(sourceRegistries, ExtractRegistryFromImage(rdp.Source))
+			imageMirrors = append(imageMirrors, ImageMirror{Source: rdp.Source, Mirrors: rdp.Mirrors})
 		}
 	}
 	currentIdms := ocp_config_v1.ImageDigestMirrorSetList{}
@@ -237,12 +326,215 @@ func GetMirrorRegistrySourceRegistries(ctx context.Context, client runtimeclient
 
 	for _, idms := range currentIdms.Items {
 		for _, idm := range idms.Spec.ImageDigestMirrors {
-			sourceRegistries = append(sourceRegistries, ExtractRegistryFromImage(idm.Source))
+			imageMirror := ImageMirror{Source: idm.Source,
+				NeverContactSource: idm.MirrorSourcePolicy == ocp_config_v1.NeverContactSource}
+			for _, mirror := range idm.Mirrors {
+				imageMirror.Mirrors = append(imageMirror.Mirrors, string(mirror))
+			}
+			imageMirrors = append(imageMirrors, imageMirror)
+		}
+	}
+	return imageMirrors, nil
+}
+
+// GetTagMirrors returns the mirror configuration of the cluster for the images referenced by tag, from the
+// ImageTagMirrorSets
+func GetTagMirrors(ctx context.Context, client runtimeclient.Client) ([]ImageMirror, error) {
+	itmsList := ocp_config_v1.ImageTagMirrorSetList{}
+	if err := client.List(ctx, &itmsList); err != nil {
+		return nil, fmt.Errorf("failed to list ImageTagMirrorSet: %w", err)
+	}
+	var imageMirrors []ImageMirror
+	for _, itms := range itmsList.Items {
+		for _, itm := range itms.Spec.ImageTagMirrors {
+			imageMirror := ImageMirror{Source: itm.Source,
+				NeverContactSource: itm.MirrorSourcePolicy == ocp_config_v1.NeverContactSource}
+			for _, mirror := range itm.Mirrors {
+				imageMirror.Mirrors = append(imageMirror.Mirrors, string(mirror))
+			}
+			imageMirrors = append(imageMirrors, imageMirror)
+		}
+	}
+	return imageMirrors, nil
+}
+
+// GetTagMirrorSources returns the source repositories mirrored by tag by the ImageTagMirrorSets
+func GetTagMirrorSources(ctx context.Context, client runtimeclient.Client) ([]string, error) {
+	tagMirrors, err := GetTagMirrors(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	var sources []string
+	for _, tagMirror := range tagMirrors {
+		sources = append(sources, tagMirror.Source)
+	}
+	return sources, nil
+}
+
+// DigestOnlyMirrors returns the image mirrors whose images can only be pulled by digest: the ones never contacting
+// their source, for the sources not mirrored by tag
+func DigestOnlyMirrors(imageMirrors []ImageMirror, tagMirrorSources []string) []ImageMirror {
+	var digestOnly []ImageMirror
+	for _, imageMirror := range imageMirrors {
+		if !imageMirror.NeverContactSource {
+			continue
+		}
+		mirroredByTag := false
+		for _, source := range tagMirrorSources {
+			if isImageInRepository(imageMirror.Source, source) {
+				mirroredByTag = true
+				break
+			}
+		}
+		if !mirroredByTag {
+			digestOnly = append(digestOnly, imageMirror)
+		}
+	}
+	return digestOnly
+}
+
+// MatchImageMirror returns the image mirror with the longest source repository matching the image, or nil
+func MatchImageMirror(image string, imageMirrors []ImageMirror) *ImageMirror {
+	var longest *ImageMirror
+	for i := range imageMirrors {
+		if isImageInRepository(image, imageMirrors[i].Source) &&
+			(longest == nil || len(imageMirrors[i].Source) > len(longest.Source)) {
+			longest = &imageMirrors[i]
 		}
 	}
+	return longest
+}
+
+// MirroredReferences returns the references of the image in the mirrors of the image mirrors with the longest source
+// repository matching it, in the order they are configured, without duplicates
+func MirroredReferences(image string, imageMirrors []ImageMirror) []string {
+	match := MatchImageMirror(image, imageMirrors)
+	if match == nil {
+		return nil
+	}
+	references := &collections.OrderedSet[string]{}
+	for _, imageMirror := range imageMirrors {
+		if imageMirror.Source != match.Source {
+			continue
+		}
+		for _, mirror := range imageMirror.Mirrors {
+			references.Add(mirror + strings.TrimPrefix(image, match.Source))
+		}
+	}
+	return references.Items()
+}
+
+// IsDigestReference tells whether the image is referenced by digest
+func IsDigestReference(image string) bool {
+	return strings.Contains(image, "@")
+}
+
+// ImageRepository returns the repository of the image, without its tag or digest
+func ImageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+func GetMirrorRegistrySourceRegistries(ctx context.Context, client runtimeclient.Client) ([]string, error) {
+	imageMirrors, err := GetImageMirrors(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	var sourceRegistries []string
+	for _, imageMirror := range imageMirrors {
+		sourceRegistries = append(sourceRegistries, ExtractRegistryFromImage(imageMirror.Source))
+	}
 	return sourceRegistries, nil
 }
 
+// BuildMirrorRewriteMap maps each repository the seed cluster pulled from through a mirror to the repository to use
+// on this cluster for the same source: the first mirror configured on this cluster for it, or the source itself if
+// this cluster does not mirror it. Images referenced by their source repository on the seed need no rewrite, as they
+// are resolved through the mirror configuration of this cluster.
+func BuildMirrorRewriteMap(seedMirrors, clusterMirrors []ImageMirror) map[string]string {
+	clusterMirrorBySource := make(map[string]string)
+	for _, imageMirror := range clusterMirrors {
+		if _, exists := clusterMirrorBySource[imageMirror.Source]; !exists && len(imageMirror.Mirrors) > 0 {
+			clusterMirrorBySource[imageMirror.Source] = imageMirror.Mirrors[0]
+		}
+	}
+
+	rewrites := make(map[string]string)
+	for _, imageMirror := range seedMirrors {
+		target, exists := clusterMirrorBySource[imageMirror.Source]
+		if !exists {
+			target = imageMirror.Source
+		}
+		for _, mirror := range imageMirror.Mirrors {
+			if _, exists := rewrites[mirror]; !exists && mirror != target {
+				rewrites[mirror] = target
+			}
+		}
+	}
+	return rewrites
+}
+
+// RewriteImageRepository replaces the longest repository of rewrites matching image with its rewrite
+func RewriteImageRepository(image string, rewrites map[string]string) string {
+	longest := ""
+	for repository := range rewrites {
+		if len(repository) > len(longest) && isImageInRepository(image, repository) {
+			longest = repository
+		}
+	}
+	if longest == "" {
+		return image
+	}
+	return rewrites[longest] + strings.TrimPrefix(image, longest)
+}
+
+func isImageInRepository(image, repository string) bool {
+	if !strings.HasPrefix(image, repository) {
+		return false
+	}
+	rest := strings.TrimPrefix(image, repository)
+	switch {
+	case rest == "", rest[0] == '/', rest[0] == '@':
+		return true
+	case rest[0] == ':':
+		// a tag, rather than the port of a longer registry host
+		return !strings.Contains(rest, "/")
+	}
+	return false
+}
+
+// ResolveSeedImage returns the reference to pre-cache an image of the seed image list with. Unlike
+// ShouldOverrideSeedRegistry, the decision is made per image, so that a cluster mirroring some repositories locally and
+// pulling the others from their source pre-caches both: an image of the seed release registry is kept when this
+// cluster mirrors its repository, or already references one of its mirrors, and is moved to the release registry of
+// this cluster otherwise.
+func ResolveSeedImage(image, clusterRegistry, seedRegistry string, clusterMirrors []ImageMirror) (string, error) {
+	if ExtractRegistryFromImage(image) != seedRegistry || isImageMirrored(image, clusterMirrors) {
+		return image, nil
+	}
+	return ReplaceImageRegistry(image, clusterRegistry, seedRegistry)
+}
+
+// isImageMirrored tells whether the image is in a source repository of the image mirrors, or in one of their mirrors
+func isImageMirrored(image string, imageMirrors []ImageMirror) bool {
+	if MatchImageMirror(image, imageMirrors) != nil {
+		return true
+	}
+	for _, imageMirror := range imageMirrors {
+		for _, mirror := range imageMirror.Mirrors {
+			if isImageInRepository(image, mirror) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func ShouldOverrideSeedRegistry(ctx context.Context, client runtimeclient.Client, mirrorRegistryConfigured bool, releaseRegistry string) (bool, error) {
 	mirroredRegistries, err := GetMirrorRegistrySourceRegistries(ctx, client)
 	if err != nil {
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetOperatorUpgrade(t *testing.T) {
+	csv := func(name, namespace, replaces string, phase operatorsv1alpha1.ClusterServiceVersionPhase,
+		reason operatorsv1alpha1.ConditionReason) client.Object {
+		return &operatorsv1alpha1.ClusterServiceVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       operatorsv1alpha1.ClusterServiceVersionSpec{Replaces: replaces},
+			Status:     operatorsv1alpha1.ClusterServiceVersionStatus{Phase: phase, Reason: reason},
+		}
+	}
+	testcases := []struct {
+		name     string
+		csvs     []client.Object
+		expected string
+	}{
+		{
+			name: "installed",
+			csvs: []client.Object{csv("lca.v2", "lca", "lca.v1", operatorsv1alpha1.CSVPhaseSucceeded, "")},
+		},
+		{
+			name: "upgrading",
+			csvs: []client.Object{
+				csv("lca.v1", "lca", "", operatorsv1alpha1.CSVPhaseReplacing, ""),
+				csv("lca.v2", "lca", "lca.v1", operatorsv1alpha1.CSVPhaseNone, ""),
+			},
+			expected: "lca.v1 is Replacing, lca.v2 replacing lca.v1 is Pending",
+		},
+		{
+			name: "previous version deleted once the upgrade succeeded",
+			csvs: []client.Object{
+				csv("lca.v1", "lca", "", operatorsv1alpha1.CSVPhaseDeleting, ""),
+				csv("lca.v2", "lca", "lca.v1", operatorsv1alpha1.CSVPhaseSucceeded, ""),
+			},
+			expected: "lca.v1 is Deleting",
+		},
+		{
+			name: "other namespace and copied operators",
+			csvs: []client.Object{
+				csv("lca.v2", "other", "lca.v1", operatorsv1alpha1.CSVPhaseInstalling, ""),
+				csv("global.v2", "lca", "global.v1", operatorsv1alpha1.CSVPhaseInstalling, operatorsv1alpha1.CSVReasonCopied),
+			},
+		},
+	}
+
+	s := runtime.NewScheme()
+	assert.NoError(t, operatorsv1alpha1.AddToScheme(s))
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(tc.csvs...).Build()
+			upgrade, err := GetOperatorUpgrade(context.Background(), c, "lca")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, upgrade)
+		})
+	}
+}
@@ -2,17 +2,31 @@ package utils
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path"
+	"sort"
+	"strings"
 
 	"github.com/openshift-kni/lifecycle-agent/api/seedreconfig"
 	"github.com/openshift-kni/lifecycle-agent/internal/common"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	cryptoDirMode = 0o600
+
+	// KubeconfigCryptoLayoutVersion is the version of the layout of the kubeconfig crypto backup, recorded in its
+	// manifest. Version 2 adds the etcd and service account signing crypto in subdirectories.
+	KubeconfigCryptoLayoutVersion = 2
+	// KubeconfigCryptoManifestFile is the manifest of the kubeconfig crypto backup, in the backup directory
+	KubeconfigCryptoManifestFile = "manifest.json"
+	// KubeconfigCryptoParallelism is the number of kubeconfig crypto items copied at a time
+	KubeconfigCryptoParallelism = 4
 )
 
 func SeedReconfigurationKubeconfigRetentionToCryptoDir(cryptoDir string, kubeconfigCryptoRetention *seedreconfig.KubeConfigCryptoRetention) error {
@@ -85,38 +99,190 @@ func SeedReconfigurationKubeconfigRetentionFromCluster(ctx context.Context, clie
 	return &kubeconfigCryptoRetention, nil
 }
 
-func BackupKubeconfigCrypto(ctx context.Context, client runtimeclient.Client, cryptoDir string) error {
+// kubeconfigCryptoItem is a key of a ConfigMap or Secret backed up as a file of the kubeconfig crypto backup. When
+// the key is empty, every key of the object is backed up as a file of the directory.
+type kubeconfigCryptoItem struct {
+	configMap bool
+	namespace string
+	name      string
+	key       string
+	file      string
+}
+
+func (i kubeconfigCryptoItem) String() string {
+	kind := "secret"
+	if i.configMap {
+		kind = "configmap"
+	}
+	if i.key == "" {
+		return fmt.Sprintf("%s %s/%s", kind, i.namespace, i.name)
+	}
+	return fmt.Sprintf("%s %s/%s %s", kind, i.namespace, i.name, i.key)
+}
+
+// kubeconfigCryptoItems are the required items of the kubeconfig crypto backup. The files at the top of the backup
+// directory are the ones of the first layout version, used by the recert rules.
+var kubeconfigCryptoItems = func() []kubeconfigCryptoItem {
+	items := []kubeconfigCryptoItem{
+		{configMap: true, namespace: "openshift-config", name: "admin-kubeconfig-client-ca", key: "ca-bundle.crt",
+			file: "admin-kubeconfig-client-ca.crt"},
+	}
+	for _, cert := range common.CertPrefixes {
+		items = append(items, kubeconfigCryptoItem{namespace: "openshift-kube-apiserver-operator", name: cert, key: "tls.key",
+			file: cert + ".key"})
+	}
+	return append(items,
+		kubeconfigCryptoItem{namespace: "openshift-ingress-operator", name: "router-ca", key: "tls.key",
+			file: "ingresskey-ingress-operator.key"},
+		kubeconfigCryptoItem{namespace: "openshift-config", name: "etcd-signer", key: "tls.crt", file: "etcd/etcd-signer.crt"},
+		kubeconfigCryptoItem{namespace: "openshift-config", name: "etcd-signer", key: "tls.key", file: "etcd/etcd-signer.key"},
+		kubeconfigCryptoItem{namespace: "openshift-config", name: "etcd-metric-signer", key: "tls.crt",
+			file: "etcd/etcd-metric-signer.crt"},
+		kubeconfigCryptoItem{namespace: "openshift-config", name: "etcd-metric-signer", key: "tls.key",
+			file: "etcd/etcd-metric-signer.key"},
+		kubeconfigCryptoItem{namespace: "openshift-etcd", name: "etcd-all-certs", file: "etcd/etcd-all-certs"},
+		kubeconfigCryptoItem{namespace: "openshift-kube-controller-manager", name: "service-account-private-key",
+			key: "service-account.key", file: "service-account/service-account-private.key"},
+		kubeconfigCryptoItem{namespace: "openshift-kube-controller-manager", name: "service-account-private-key",
+			key: "service-account.pub", file: "service-account/service-account-private.pub"},
+		kubeconfigCryptoItem{namespace: "openshift-kube-apiserver", name: "bound-service-account-signing-key",
+			key: "service-account.key", file: "service-account/bound-service-account-signing.key"},
+		kubeconfigCryptoItem{namespace: "openshift-kube-apiserver", name: "bound-service-account-signing-key",
+			key: "service-account.pub", file: "service-account/bound-service-account-signing.pub"},
+	)
+}()
+
+// KubeconfigCryptoManifest lists the files of the kubeconfig crypto backup, with their checksum
+type KubeconfigCryptoManifest struct {
+	Version int                            `json:"version"`
+	Items   []KubeconfigCryptoManifestItem `json:"items"`
+}
+
+// KubeconfigCryptoManifestItem is a file of the kubeconfig crypto backup, relative to the backup directory
+type KubeconfigCryptoManifestItem struct {
+	Source string `json:"source"`
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupKubeconfigCrypto writes the kubeconfig crypto of the cluster into cryptoDir, one file per item, along with a
+// manifest listing every file, and checks the backup is complete against the manifest. The items are copied in
+// parallel, all of them are attempted and the error lists every failed item. Progress is reported as each item starts
+// if progress is not nil.
+func BackupKubeconfigCrypto(ctx context.Context, client runtimeclient.Client, cryptoDir string, progress ProgressFunc) error {
 	if err := os.MkdirAll(cryptoDir, os.ModePerm); err != nil {
 		return fmt.Errorf("error creating %s: %w", cryptoDir, err)
 	}
 
-	adminKubeConfigClientCA, err := GetConfigMapData(ctx, "admin-kubeconfig-client-ca", "openshift-config", "ca-bundle.crt", client)
-	if err != nil {
-		return fmt.Errorf("failed to get configMap data with adminKubeConfigClientCA: %w", err)
+	files := make([][]KubeconfigCryptoManifestItem, len(kubeconfigCryptoItems))
+	items := make([]BackupItem, len(kubeconfigCryptoItems))
+	for i, cryptoItem := range kubeconfigCryptoItems {
+		i, cryptoItem := i, cryptoItem
+		items[i] = BackupItem{Name: cryptoItem.file, Backup: func() error {
+			written, err := backupKubeconfigCryptoItem(ctx, client, cryptoDir, cryptoItem)
+			files[i] = written
+			return err
+		}}
 	}
-	p := path.Join(cryptoDir, "admin-kubeconfig-client-ca.crt")
-	if err := os.WriteFile(p, []byte(adminKubeConfigClientCA), cryptoDirMode); err != nil {
-		return fmt.Errorf("failed to admin-kubeconfig-client-ca.crt to path %s: %w", p, err)
+	if err := RunBackupItemsParallel("kubeconfig crypto", items, KubeconfigCryptoParallelism, progress); err != nil {
+		return err
 	}
 
-	for _, cert := range common.CertPrefixes {
-		servingSignerKey, err := GetSecretData(ctx, cert, "openshift-kube-apiserver-operator", "tls.key", client)
-		if err != nil {
-			return fmt.Errorf("failed to get secret data with servingSignerKey: %w", err)
+	manifest := KubeconfigCryptoManifest{Version: KubeconfigCryptoLayoutVersion}
+	for _, written := range files {
+		manifest.Items = append(manifest.Items, written...)
+	}
+	if err := MarshalToFile(manifest, path.Join(cryptoDir, KubeconfigCryptoManifestFile)); err != nil {
+		return fmt.Errorf("failed to write kubeconfig crypto manifest: %w", err)
+	}
+
+	return VerifyKubeconfigCrypto(cryptoDir)
+}
+
+// backupKubeconfigCryptoItem writes the data of the item into its file, or into a file per key in its directory
+func backupKubeconfigCryptoItem(ctx context.Context, client runtimeclient.Client, cryptoDir string,
+	item kubeconfigCryptoItem) ([]KubeconfigCryptoManifestItem, error) {
+	data := map[string][]byte{}
+	if item.configMap {
+		cm := &corev1.ConfigMap{}
+		if err := client.Get(ctx, types.NamespacedName{Name: item.name, Namespace: item.namespace}, cm); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", item, err)
+		}
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
 		}
-		curP := path.Join(cryptoDir, cert+".key")
-		if err := os.WriteFile(curP, []byte(servingSignerKey), cryptoDirMode); err != nil {
-			return fmt.Errorf("failed write to .key file to path %s: %w", curP, err)
+	} else {
+		secret := &corev1.Secret{}
+		if err := client.Get(ctx, types.NamespacedName{Name: item.name, Namespace: item.namespace}, secret); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", item, err)
 		}
+		data = secret.Data
 	}
 
-	ingressOperatorKey, err := GetSecretData(ctx, "router-ca", "openshift-ingress-operator", "tls.key", client)
-	if err != nil {
-		return fmt.Errorf("failed to get secret data with ingressOperatorKey: %w", err)
+	files := map[string][]byte{}
+	if item.key == "" {
+		for k, v := range data {
+			files[path.Join(item.file, k)] = v
+		}
+	} else if v, ok := data[item.key]; ok {
+		files[item.file] = v
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no data found in %s", item)
 	}
-	p = path.Join(cryptoDir, "ingresskey-ingress-operator.key")
-	if err := os.WriteFile(p, []byte(ingressOperatorKey), cryptoDirMode); err != nil {
-		return fmt.Errorf("failed to ingresskey-ingress-operator.key to path %s: %w", p, err)
+
+	var written []KubeconfigCryptoManifestItem
+	for file, content := range files {
+		p := path.Join(cryptoDir, file)
+		if err := os.MkdirAll(path.Dir(p), 0o700); err != nil {
+			return nil, fmt.Errorf("error creating %s: %w", path.Dir(p), err)
+		}
+		if err := os.WriteFile(p, content, cryptoDirMode); err != nil {
+			return nil, fmt.Errorf("failed to write %s to path %s: %w", item, p, err)
+		}
+		written = append(written, KubeconfigCryptoManifestItem{Source: item.String(), File: file, SHA256: sha256Hex(content)})
+	}
+	sort.Slice(written, func(i, j int) bool { return written[i].File < written[j].File })
+	return written, nil
+}
+
+// VerifyKubeconfigCrypto checks the kubeconfig crypto backup in cryptoDir is complete: its manifest has the current
+// layout version and lists every required item, and each listed file is present with the recorded checksum
+func VerifyKubeconfigCrypto(cryptoDir string) error {
+	manifest := &KubeconfigCryptoManifest{}
+	if err := ReadYamlOrJSONFile(path.Join(cryptoDir, KubeconfigCryptoManifestFile), manifest); err != nil {
+		return fmt.Errorf("failed to read kubeconfig crypto manifest: %w", err)
+	}
+	if manifest.Version != KubeconfigCryptoLayoutVersion {
+		return fmt.Errorf("unsupported kubeconfig crypto layout version %d, expected %d", manifest.Version, KubeconfigCryptoLayoutVersion)
+	}
+
+	var missing []string
+	for _, item := range kubeconfigCryptoItems {
+		found := false
+		for _, listed := range manifest.Items {
+			if listed.Source == item.String() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, item.String())
+		}
+	}
+	for _, listed := range manifest.Items {
+		content, err := os.ReadFile(path.Join(cryptoDir, listed.File))
+		if err != nil || sha256Hex(content) != listed.SHA256 {
+			missing = append(missing, listed.File)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("incomplete kubeconfig crypto backup in %s, missing or modified: %s", cryptoDir, strings.Join(missing, ", "))
 	}
 	return nil
 }
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestBackupKubeconfigCrypto(t *testing.T) {
+	var objs []client.Object
+	for _, item := range kubeconfigCryptoItems {
+		objMeta := metav1.ObjectMeta{Name: item.name, Namespace: item.namespace}
+		key := item.key
+		if key == "" {
+			key = "etcd-serving-sno.crt"
+		}
+		if item.configMap {
+			objs = append(objs, &corev1.ConfigMap{ObjectMeta: objMeta, Data: map[string]string{key: item.String()}})
+			continue
+		}
+		found := false
+		for _, obj := range objs {
+			if secret, ok := obj.(*corev1.Secret); ok && secret.Name == item.name && secret.Namespace == item.namespace {
+				secret.Data[key] = []byte(item.String())
+				found = true
+			}
+		}
+		if !found {
+			objs = append(objs, &corev1.Secret{ObjectMeta: objMeta, Data: map[string][]byte{key: []byte(item.String())}})
+		}
+	}
+	s := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(s))
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+
+	cryptoDir := t.TempDir()
+	var progress []string
+	assert.NoError(t, BackupKubeconfigCrypto(context.Background(), c, cryptoDir, func(msg string) { progress = append(progress, msg) }))
+	assert.Len(t, progress, len(kubeconfigCryptoItems))
+
+	// The files of the first layout version are kept at the top of the directory, for the recert rules
+	content, err := os.ReadFile(path.Join(cryptoDir, "localhost-serving-signer.key"))
+	assert.NoError(t, err)
+	assert.Equal(t, "secret openshift-kube-apiserver-operator/localhost-serving-signer tls.key", string(content))
+	content, err = os.ReadFile(path.Join(cryptoDir, "etcd", "etcd-all-certs", "etcd-serving-sno.crt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "secret openshift-etcd/etcd-all-certs", string(content))
+
+	manifest := &KubeconfigCryptoManifest{}
+	assert.NoError(t, ReadYamlOrJSONFile(path.Join(cryptoDir, KubeconfigCryptoManifestFile), manifest))
+	assert.Equal(t, KubeconfigCryptoLayoutVersion, manifest.Version)
+	assert.Len(t, manifest.Items, len(kubeconfigCryptoItems))
+
+	// A modified file is detected
+	assert.NoError(t, os.WriteFile(path.Join(cryptoDir, "etcd", "etcd-signer.key"), []byte("modified"), 0o600))
+	assert.ErrorContains(t, VerifyKubeconfigCrypto(cryptoDir), "missing or modified: etcd/etcd-signer.key")
+
+	// A missing item fails the backup, after attempting all the others
+	assert.NoError(t, c.Delete(context.Background(), objs[len(objs)-1]))
+	err = BackupKubeconfigCrypto(context.Background(), c, t.TempDir(), nil)
+	assert.ErrorContains(t, err, "failed to backup 2 of")
+	assert.ErrorContains(t, err, "service-account/bound-service-account-signing.key: failed to get secret openshift-kube-apiserver/bound-service-account-signing-key")
+}
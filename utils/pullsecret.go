@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
+)
+
+// placeholderCredentials are the credentials of common.PullSecretEmptyData, the pull secret baked in the seed image
+const placeholderCredentials = "empty:empty"
+
+// dockerConfigJSON is the format of the pull secrets
+type dockerConfigJSON struct {
+	Auths map[string]dockerAuth `json:"auths"`
+}
+
+type dockerAuth struct {
+	Auth     string `json:"auth,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+func (a dockerAuth) credentials() string {
+	if a.Auth != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(a.Auth); err == nil {
+			return string(decoded)
+		}
+		return a.Auth
+	}
+	return a.Username + ":" + a.Password
+}
+
+// HasPlaceholderCredentials returns whether the pull secret still holds the placeholder credentials baked in the
+// seed image
+func HasPlaceholderCredentials(pullSecret []byte) bool {
+	if strings.TrimSpace(string(pullSecret)) == strings.TrimSpace(common.PullSecretEmptyData) {
+		return true
+	}
+	config := &dockerConfigJSON{}
+	if err := json.Unmarshal(pullSecret, config); err != nil {
+		return false
+	}
+	for _, auth := range config.Auths {
+		if auth.credentials() == placeholderCredentials {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePullSecret checks that the pull secret is a docker config JSON without the placeholder credentials of the
+// seed image, with credentials for each of the required registries
+func ValidatePullSecret(pullSecret []byte, requiredRegistries ...string) error {
+	config := &dockerConfigJSON{}
+	if err := json.Unmarshal(pullSecret, config); err != nil {
+		return fmt.Errorf("invalid pull secret: %w", err)
+	}
+	if len(config.Auths) == 0 {
+		return fmt.Errorf("invalid pull secret: no auths")
+	}
+	if HasPlaceholderCredentials(pullSecret) {
+		return fmt.Errorf("pull secret holds the placeholder credentials of the seed image")
+	}
+
+	var missing []string
+	for _, registry := range requiredRegistries {
+		if registry != "" && !hasRegistryCredentials(config, registry) {
+			missing = append(missing, registry)
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("pull secret has no credentials for the required registries: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// hasRegistryCredentials returns whether the pull secret has credentials for the registry, or for a repository of it
+func hasRegistryCredentials(config *dockerConfigJSON, registry string) bool {
+	for key := range config.Auths {
+		key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+		if key == registry || strings.HasPrefix(key, registry+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ErrPushDenied is returned when the registry rejects the push credentials
+var ErrPushDenied = errors.New("registry denied push access")
+
+var (
+	// imageReferencePattern is the grammar of the fully qualified image references, with the registry host
+	imageReferencePattern = regexp.MustCompile(`^` +
+		// registry host, with an optional port
+		`((?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?)` +
+		// repository path components
+		`((?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)+)` +
+		// optional tag
+		`(?::[\w][\w.-]{0,127})?` +
+		// optional digest
+		`(?:@[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,})?$`)
+	// challengeParam matches the parameters of a WWW-Authenticate challenge, e.g. realm="https://auth.example.com"
+	challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+// ValidateImageReference checks that the image is a fully qualified reference, with its registry host
+func ValidateImageReference(image string) error {
+	if len(image) > 255 {
+		return fmt.Errorf("invalid image reference %q: longer than 255 characters", image)
+	}
+	match := imageReferencePattern.FindStringSubmatch(image)
+	if match == nil {
+		return fmt.Errorf("invalid image reference %q", image)
+	}
+	registry := match[1]
+	if !strings.ContainsAny(registry, ".:") && registry != "localhost" {
+		return fmt.Errorf("invalid image reference %q: the registry host is required", image)
+	}
+	return nil
+}
+
+// RegistryCredentials returns the credentials of the pull secret for the repository of the image, preferring the
+// longest matching key, or false if it has none
+func RegistryCredentials(pullSecret []byte, image string) (username, password string, found bool, err error) {
+	config := &dockerConfigJSON{}
+	if err := json.Unmarshal(pullSecret, config); err != nil {
+		return "", "", false, fmt.Errorf("invalid pull secret: %w", err)
+	}
+	repository := ImageRepository(image)
+	longest := ""
+	for key, auth := range config.Auths {
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+		if (repository == trimmed || strings.HasPrefix(repository, trimmed+"/")) && len(trimmed) > len(longest) {
+			longest = trimmed
+			username, password, _ = strings.Cut(auth.credentials(), ":")
+			found = true
+		}
+	}
+	return username, password, found, nil
+}
+
+// CheckPushAccess checks with the registry that the pull secret grants push access to the repository of the image,
+// without pushing anything: the credentials are checked against the registry authentication, and the access granted
+// by its token service is checked when the token lists it. ErrPushDenied is returned when the registry rejects the
+// credentials, other errors when the check is inconclusive, e.g. the registry is unreachable.
+func CheckPushAccess(ctx context.Context, httpClient *http.Client, image string, pullSecret []byte) error {
+	username, password, found, err := RegistryCredentials(pullSecret, image)
+	if err != nil {
+		return err
+	}
+	registry := ExtractRegistryFromImage(image)
+	repository := strings.TrimPrefix(ImageRepository(image), registry+"/")
+	if !found {
+		return fmt.Errorf("%w: no credentials for %s", ErrPushDenied, registry)
+	}
+
+	resp, err := registryGet(ctx, httpClient, "https://"+registry+"/v2/", username, password, false)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// the registry requires no authentication
+		return nil
+	case http.StatusUnauthorized:
+	default:
+		return fmt.Errorf("unexpected status %s from registry %s", resp.Status, registry)
+	}
+
+	scheme, params := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+	switch scheme {
+	case "basic":
+		resp, err := registryGet(ctx, httpClient, "https://"+registry+"/v2/", username, password, true)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return checkAuthStatus(resp, registry)
+	case "bearer":
+		return checkTokenPushAccess(ctx, httpClient, params, registry, repository, username, password)
+	}
+	return fmt.Errorf("unsupported authentication challenge %q from registry %s", resp.Header.Get("WWW-Authenticate"), registry)
+}
+
+// checkTokenPushAccess requests a token with the push scope of the repository from the token service of the registry
+func checkTokenPushAccess(ctx context.Context, httpClient *http.Client, params map[string]string,
+	registry, repository, username, password string) error {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return fmt.Errorf("invalid token realm %q from registry %s", params["realm"], registry)
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull,push", repository))
+	realm.RawQuery = query.Encode()
+
+	resp, err := registryGet(ctx, httpClient, realm.String(), username, password, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkAuthStatus(resp, registry); err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read token from registry %s: %w", registry, err)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return fmt.Errorf("failed to parse token from registry %s: %w", registry, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if granted, known := tokenGrantsPush(token.Token, repository); known && !granted {
+		return fmt.Errorf("%w: token for %s lacks push access to %s", ErrPushDenied, registry, repository)
+	}
+	return nil
+}
+
+// tokenGrantsPush returns whether the access claim of the JWT token grants push to the repository, and whether the
+// token lists its access at all, as the tokens may be opaque
+func tokenGrantsPush(token, repository string) (granted, known bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false, false
+	}
+	var claims struct {
+		Access *[]struct {
+			Type    string   `json:"type"`
+			Name    string   `json:"name"`
+			Actions []string `json:"actions"`
+		} `json:"access"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Access == nil {
+		return false, false
+	}
+	for _, access := range *claims.Access {
+		if access.Type != "repository" || access.Name != repository {
+			continue
+		}
+		for _, action := range access.Actions {
+			if action == "push" || action == "*" {
+				return true, true
+			}
+		}
+	}
+	return false, true
+}
+
+func registryGet(ctx context.Context, httpClient *http.Client, target, username, password string,
+	authenticate bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request to %s: %w", target, err)
+	}
+	if authenticate {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach %s: %w", target, err)
+	}
+	return resp, nil
+}
+
+func checkAuthStatus(resp *http.Response, registry string) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s from registry %s", ErrPushDenied, resp.Status, registry)
+	}
+	return fmt.Errorf("unexpected status %s from registry %s", resp.Status, registry)
+}
+
+// parseChallenge returns the lowercase scheme and the parameters of a WWW-Authenticate challenge
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for _, match := range challengeParam.FindAllStringSubmatch(rest, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	return strings.ToLower(scheme), params
+}
+
+// ValidateCABundle checks that the bundle only holds PEM encoded certificates
+func ValidateCABundle(data []byte) error {
+	count := 0
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			if len(bytes.TrimSpace(rest)) != 0 {
+				return fmt.Errorf("unexpected content after %d certificates", count)
+			}
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("unexpected PEM block %s after %d certificates", block.Type, count)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("failed to parse certificate %d: %w", count+1, err)
+		}
+		count++
+	}
+	if count == 0 {
+		return fmt.Errorf("no certificate found")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateImageReference(t *testing.T) {
+	for _, image := range []string{
+		"quay.io/org/seed:4.15.0",
+		"mirror.example.com:5000/ocp/seed",
+		"localhost/seed:latest",
+		"quay.io/org/seed@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	} {
+		assert.NoError(t, ValidateImageReference(image), image)
+	}
+	for _, image := range []string{
+		"",
+		"seed:4.15.0",
+		"org/seed:4.15.0",
+		"quay.io/Org/seed:4.15.0",
+		"quay.io/org/seed:",
+		"quay.io/org/seed@sha256:1234",
+		"https://quay.io/org/seed",
+	} {
+		assert.Error(t, ValidateImageReference(image), image)
+	}
+}
+
+func TestRegistryCredentials(t *testing.T) {
+	pullSecret := []byte(`{"auths":{"quay.io":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("user:pass")) + `"},` +
+		`"quay.io/org":{"username":"robot","password":"token"}}}`)
+
+	username, password, found, err := RegistryCredentials(pullSecret, "quay.io/org/seed:4.15.0")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "robot", username)
+	assert.Equal(t, "token", password)
+
+	username, _, found, err = RegistryCredentials(pullSecret, "quay.io/other/seed:4.15.0")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "user", username)
+
+	_, _, found, err = RegistryCredentials(pullSecret, "registry.example.com/org/seed:4.15.0")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func testToken(t *testing.T, actions []string) string {
+	claims, err := json.Marshal(map[string]any{
+		"access": []map[string]any{{"type": "repository", "name": "org/seed", "actions": actions}},
+	})
+	assert.NoError(t, err)
+	return "e30." + base64.RawURLEncoding.EncodeToString(claims) + ".c2ln"
+}
+
+func TestCheckPushAccess(t *testing.T) {
+	var tokenActions []string
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/token":
+			if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, "registry", r.URL.Query().Get("service"))
+			assert.Equal(t, "repository:org/seed:pull,push", r.URL.Query().Get("scope"))
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": testToken(t, tokenActions)})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	registry := strings.TrimPrefix(server.URL, "https://")
+	image := registry + "/org/seed:4.15.0"
+	pullSecret := func(user, password string) []byte {
+		return []byte(`{"auths":{"` + registry + `":{"username":"` + user + `","password":"` + password + `"}}}`)
+	}
+
+	tokenActions = []string{"pull", "push"}
+	assert.NoError(t, CheckPushAccess(context.Background(), server.Client(), image, pullSecret("user", "secret")))
+
+	tokenActions = []string{"pull"}
+	err := CheckPushAccess(context.Background(), server.Client(), image, pullSecret("user", "secret"))
+	assert.True(t, errors.Is(err, ErrPushDenied), "unexpected error: %v", err)
+
+	err = CheckPushAccess(context.Background(), server.Client(), image, pullSecret("user", "wrong"))
+	assert.True(t, errors.Is(err, ErrPushDenied), "unexpected error: %v", err)
+
+	err = CheckPushAccess(context.Background(), server.Client(), "registry.example.com/org/seed:4.15.0", pullSecret("user", "secret"))
+	assert.True(t, errors.Is(err, ErrPushDenied), "unexpected error: %v", err)
+
+	// a registry with a CA unknown to the client is inconclusive
+	err = CheckPushAccess(context.Background(), http.DefaultClient, image, pullSecret("user", "secret"))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrPushDenied))
+}
@@ -295,6 +295,50 @@ func InitIBU(ctx context.Context, c client.Client, log *logr.Logger) error {
 	return nil
 }
 
+// InitUpgradePlan restores the UpgradePlan CR saved to the new stateroot before the pivot, if any
+func InitUpgradePlan(ctx context.Context, c client.Client, log *logr.Logger) error {
+	plan := &lcav1alpha1.UpgradePlan{}
+	filePath := common.PathOutsideChroot(utils.UpgradePlanFilePath)
+	if err := ReadYamlOrJSONFile(filePath, plan); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	// Strip the ResourceVersion, otherwise the restore fails
+	plan.SetResourceVersion("")
+
+	log.Info("Saved UpgradePlan CR found, restoring ...")
+	if err := common.RetryOnConflictOrRetriable(retry.DefaultBackoff, func() error {
+		return client.IgnoreNotFound(c.Delete(ctx, plan)) //nolint:wrapcheck
+	}); err != nil {
+		return fmt.Errorf("failed to delete UpgradePlan during restore: %w", err)
+	}
+
+	// Save status as the plan structure gets over-written by the create call
+	// with the result which has no status
+	status := plan.Status
+	if err := common.RetryOnConflictOrRetriable(retry.DefaultBackoff, func() error {
+		return c.Create(ctx, plan) //nolint:wrapcheck
+	}); err != nil {
+		return fmt.Errorf("failed to create UpgradePlan to restore: %w", err)
+	}
+
+	plan.Status = status
+	if err := common.RetryOnConflictOrRetriable(retry.DefaultBackoff, func() error {
+		return c.Status().Update(ctx, plan) //nolint:wrapcheck
+	}); err != nil {
+		return fmt.Errorf("failed to update UpgradePlan during restore: %w", err)
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("failed to remove UpgradePlan in %s: %w", filePath, err)
+	}
+	log.Info("Restore successful and saved UpgradePlan CR removed")
+	return nil
+}
+
 func ConvertToRawExtension(config any) (runtime.RawExtension, error) {
 	rawIgnConfig, err := json.Marshal(config)
 	if err != nil {
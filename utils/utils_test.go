@@ -1,12 +1,16 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift-kni/lifecycle-agent/internal/common"
 )
 
 func TestIsIpv6(t *testing.T) {
@@ -111,3 +115,232 @@ func TestCopyReplaceMirrorRegistry(t *testing.T) {
 		})
 	}
 }
+
+func TestRewriteImageRepository(t *testing.T) {
+	seedMirrors := []ImageMirror{
+		{Source: "quay.io/openshift-release-dev/ocp-v4.0-art-dev", Mirrors: []string{"seed-mirror.io:5000/ocp/art-dev"}},
+		{Source: "quay.io/openshift-release-dev/ocp-release", Mirrors: []string{"seed-mirror.io:5000/ocp/release"}},
+		{Source: "registry.redhat.io", Mirrors: []string{"seed-mirror.io:5000"}},
+	}
+	clusterMirrors := []ImageMirror{
+		{Source: "quay.io/openshift-release-dev/ocp-v4.0-art-dev", Mirrors: []string{"target-mirror.io/art-dev"}},
+	}
+	rewrites := BuildMirrorRewriteMap(seedMirrors, clusterMirrors)
+
+	testcases := []struct {
+		name     string
+		image    string
+		expected string
+	}{
+		{
+			name:     "mirrored on both clusters",
+			image:    "seed-mirror.io:5000/ocp/art-dev@sha256:1234",
+			expected: "target-mirror.io/art-dev@sha256:1234",
+		},
+		{
+			name:     "not mirrored on this cluster",
+			image:    "seed-mirror.io:5000/ocp/release:4.15.0-x86_64",
+			expected: "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+		},
+		{
+			name:     "registry wide mirror",
+			image:    "seed-mirror.io:5000/rhel9/support-tools@sha256:5678",
+			expected: "registry.redhat.io/rhel9/support-tools@sha256:5678",
+		},
+		{
+			name:     "source image is not rewritten",
+			image:    "quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:1234",
+			expected: "quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:1234",
+		},
+		{
+			name:     "repository prefix of another repository",
+			image:    "seed-mirror.io:5000/ocp/art-dev-extra@sha256:1234",
+			expected: "registry.redhat.io/ocp/art-dev-extra@sha256:1234",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, RewriteImageRepository(tc.image, rewrites))
+		})
+	}
+}
+
+func TestResolveSeedImage(t *testing.T) {
+	clusterMirrors := []ImageMirror{
+		{Source: "quay.io/openshift-release-dev/ocp-v4.0-art-dev", Mirrors: []string{"mirror.local:5000/ocp/art-dev"}},
+	}
+
+	testcases := []struct {
+		name            string
+		image           string
+		clusterRegistry string
+		expected        string
+	}{
+		{
+			name:            "mirrored on this cluster",
+			image:           "quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:1234",
+			clusterRegistry: "mirror.local:5000",
+			expected:        "quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:1234",
+		},
+		{
+			name:            "not mirrored on this cluster",
+			image:           "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+			clusterRegistry: "mirror.local:5000",
+			expected:        "mirror.local:5000/openshift-release-dev/ocp-release:4.15.0-x86_64",
+		},
+		{
+			name:            "not mirrored, pulled from the same registry",
+			image:           "quay.io/org/operator@sha256:5678",
+			clusterRegistry: "quay.io",
+			expected:        "quay.io/org/operator@sha256:5678",
+		},
+		{
+			name:            "not in the seed release registry",
+			image:           "registry.redhat.io/rhel9/support-tools@sha256:5678",
+			clusterRegistry: "mirror.local:5000",
+			expected:        "registry.redhat.io/rhel9/support-tools@sha256:5678",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			image, err := ResolveSeedImage(tc.image, tc.clusterRegistry, "quay.io", clusterMirrors)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, image)
+		})
+	}
+
+	// An image referencing a mirror of this cluster is pulled as is
+	image, err := ResolveSeedImage("mirror.local:5000/ocp/art-dev@sha256:1234", "quay.io", "mirror.local:5000", clusterMirrors)
+	assert.NoError(t, err)
+	assert.Equal(t, "mirror.local:5000/ocp/art-dev@sha256:1234", image)
+	image, err = ResolveSeedImage("mirror.local:5000/ocp/release:4.15.0", "quay.io", "mirror.local:5000", clusterMirrors)
+	assert.NoError(t, err)
+	assert.Equal(t, "quay.io/ocp/release:4.15.0", image)
+}
+
+func TestDigestOnlyMirrors(t *testing.T) {
+	imageMirrors := []ImageMirror{
+		{Source: "quay.io/openshift-release-dev/ocp-v4.0-art-dev", Mirrors: []string{"mirror.io/art-dev"}, NeverContactSource: true},
+		{Source: "quay.io/openshift-release-dev/ocp-release", Mirrors: []string{"mirror.io/release"}},
+		{Source: "registry.redhat.io", Mirrors: []string{"mirror.io"}, NeverContactSource: true},
+		{Source: "registry.redhat.io/rhel9", Mirrors: []string{"mirror.io/rhel9"}, NeverContactSource: true},
+	}
+	digestOnly := DigestOnlyMirrors(imageMirrors, []string{"registry.redhat.io"})
+	assert.Equal(t, []ImageMirror{imageMirrors[0]}, digestOnly)
+
+	digestOnly = DigestOnlyMirrors(imageMirrors, nil)
+	assert.Equal(t, []ImageMirror{imageMirrors[0], imageMirrors[2], imageMirrors[3]}, digestOnly)
+
+	assert.Equal(t, &digestOnly[2], MatchImageMirror("registry.redhat.io/rhel9/support-tools:latest", digestOnly))
+	assert.Equal(t, &digestOnly[1], MatchImageMirror("registry.redhat.io/ubi9:latest", digestOnly))
+	assert.Nil(t, MatchImageMirror("quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64", digestOnly))
+}
+
+func TestMirroredReferences(t *testing.T) {
+	imageMirrors := []ImageMirror{
+		{Source: "quay.io/org", Mirrors: []string{"mirror.io/org"}},
+		{Source: "quay.io/org/seed", Mirrors: []string{"mirror.io:5000/seed", "backup.io/seed"}},
+		{Source: "quay.io/org/seed", Mirrors: []string{"backup.io/seed", "other.io/seed"}, NeverContactSource: true},
+	}
+	assert.Equal(t, []string{"mirror.io:5000/seed:4.15.0", "backup.io/seed:4.15.0", "other.io/seed:4.15.0"},
+		MirroredReferences("quay.io/org/seed:4.15.0", imageMirrors))
+	assert.Equal(t, []string{"mirror.io/org/recert@sha256:1234"},
+		MirroredReferences("quay.io/org/recert@sha256:1234", imageMirrors))
+	assert.Nil(t, MirroredReferences("quay.io/other/seed:4.15.0", imageMirrors))
+}
+
+func TestImageRepository(t *testing.T) {
+	assert.Equal(t, "mirror.io:5000/ocp/release", ImageRepository("mirror.io:5000/ocp/release:4.15.0"))
+	assert.Equal(t, "mirror.io:5000/ocp/release", ImageRepository("mirror.io:5000/ocp/release@sha256:1234"))
+	assert.Equal(t, "mirror.io:5000/ocp/release", ImageRepository("mirror.io:5000/ocp/release"))
+	assert.True(t, IsDigestReference("mirror.io:5000/ocp/release@sha256:1234"))
+	assert.False(t, IsDigestReference("mirror.io:5000/ocp/release:4.15.0"))
+}
+
+func TestRunBackupItems(t *testing.T) {
+	errNotFound := errors.New("not found")
+	var progress []string
+	var backedUp []string
+	items := []BackupItem{
+		{Name: "first", Backup: func() error { backedUp = append(backedUp, "first"); return nil }},
+		{Name: "second", Backup: func() error { return errNotFound }},
+		{Name: "third", Backup: func() error { backedUp = append(backedUp, "third"); return nil }},
+	}
+
+	err := RunBackupItems("test", items, func(msg string) { progress = append(progress, msg) })
+	assert.EqualError(t, err, "failed to backup 1 of 3 test items: second: not found")
+	assert.ErrorIs(t, err, errNotFound)
+	assert.Equal(t, []string{"first", "third"}, backedUp)
+	assert.Equal(t, []string{"Backing up test: first (1/3)", "Backing up test: second (2/3)", "Backing up test: third (3/3)"}, progress)
+
+	assert.NoError(t, RunBackupItems("test", items[:1], nil))
+}
+
+func TestRunBackupItemsParallel(t *testing.T) {
+	errNotFound := errors.New("not found")
+	var progress []string
+	items := []BackupItem{
+		{Name: "first", Backup: func() error { return nil }},
+		{Name: "second", Backup: func() error { return errNotFound }},
+		{Name: "third", Backup: func() error { return errors.New("forbidden") }},
+		{Name: "fourth", Backup: func() error { return nil }},
+	}
+
+	err := RunBackupItemsParallel("test", items, 2, func(msg string) { progress = append(progress, msg) })
+	assert.EqualError(t, err, "failed to backup 2 of 4 test items: second: not found; third: forbidden")
+	assert.ErrorIs(t, err, errNotFound)
+	assert.Len(t, progress, 4)
+	for i, msg := range progress {
+		assert.True(t, strings.HasSuffix(msg, fmt.Sprintf("(%d/4)", i+1)), msg)
+	}
+
+	assert.NoError(t, RunBackupItemsParallel("test", items[:1], 0, nil))
+}
+
+func TestValidatePullSecret(t *testing.T) {
+	testcases := []struct {
+		name          string
+		pullSecret    string
+		registries    []string
+		expectedError string
+	}{
+		{
+			name:       "valid pull secret",
+			pullSecret: `{"auths":{"quay.io":{"auth":"dXNlcjpwYXNz"},"registry.example.com:5000/ocp":{"username":"user","password":"pass"}}}`,
+			registries: []string{"quay.io", "registry.example.com:5000", ""},
+		},
+		{
+			name:          "invalid json",
+			pullSecret:    "pull-secret",
+			expectedError: "invalid pull secret",
+		},
+		{
+			name:          "placeholder pull secret of the seed",
+			pullSecret:    common.PullSecretEmptyData,
+			expectedError: "placeholder credentials",
+		},
+		{
+			name:          "placeholder credentials left",
+			pullSecret:    `{"auths":{"quay.io":{"auth":"dXNlcjpwYXNz"},"registry.connect.redhat.com":{"auth":"ZW1wdHk6ZW1wdHk="}}}`,
+			expectedError: "placeholder credentials",
+		},
+		{
+			name:          "missing required registry",
+			pullSecret:    `{"auths":{"quay.io":{"auth":"dXNlcjpwYXNz"}}}`,
+			registries:    []string{"registry.example.com"},
+			expectedError: "no credentials for the required registries: registry.example.com",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidatePullSecret([]byte(tc.pullSecret), tc.registries...)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.expectedError)
+			}
+		})
+	}
+}